@@ -0,0 +1,332 @@
+// Package adf converts documents to and from the JSON of the Atlassian Document Format,
+// used by Jira and Confluence.
+//
+// ADF is close to the JSON of ProseMirror documents, with nodes and marks named after
+// the schema.ADFSpec schema. The parser reads the subset of ADF in a schema, and
+// keeps what it can of the rest:
+//   - unknown inline nodes become their text, like the text attribute of an emoji or
+//     the url of an inline card, which also gets a link mark when the schema has one.
+//   - unknown block nodes with inline content, like decision items, become paragraphs,
+//     and other unknown blocks, like expands or layouts, are replaced by their content.
+//   - unknown marks, unknown attributes and media are left out.
+//   - heading levels outside of 1 to 6 are brought back in range.
+package adf
+
+import (
+	"fmt"
+	"maps"
+	"math"
+
+	"github.com/go-json-experiment/json"
+
+	"github.com/karitham/prosemirror"
+)
+
+// Version is the version of ADF written in documents.
+const Version = 1
+
+type adfNode struct {
+	Type    string         `json:"type"`
+	Version int            `json:"version,omitzero"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+	Content []adfNode      `json:"content,omitempty"`
+	Text    string         `json:"text,omitempty"`
+	Marks   []adfMark      `json:"marks,omitempty"`
+}
+
+type adfMark struct {
+	Type  string         `json:"type"`
+	Attrs map[string]any `json:"attrs,omitempty"`
+}
+
+// Warning reports content of an ADF document left out of a parsed document, because
+// the schema cannot represent it. Its source is the type of the ADF node or mark
+// holding the content.
+type Warning = prosemirror.ParseWarning
+
+// Parser reads ADF documents into documents of a schema.
+type Parser struct {
+	Schema prosemirror.Schema
+
+	// The type of the blocks unknown nodes with inline content become.
+	Paragraph prosemirror.NodeTypeName
+
+	// The mark of the text of inline cards.
+	Link prosemirror.MarkTypeName
+}
+
+// NewParser creates a parser for the given schema, using the names of the schema.ADFSpec schema.
+func NewParser(s prosemirror.Schema) Parser {
+	return Parser{Schema: s, Paragraph: "paragraph", Link: "link"}
+}
+
+// Parse reads an ADF document from its JSON.
+func (p Parser) Parse(data []byte) (prosemirror.Node, []Warning, error) {
+	var root adfNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		return prosemirror.Node{}, nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+
+	top := *p.Schema.TopNodeType
+	if root.Type != string(top.Name) {
+		return prosemirror.Node{}, nil, fmt.Errorf("expected a %s node, got %q", top.Name, root.Type)
+	}
+
+	s := &parseState{parser: p}
+	s.open(top, s.attrs(top, root.Attrs), nil)
+	s.addContent(root.Content)
+
+	doc, err := s.stack[0].Node()
+	if err != nil {
+		return prosemirror.Node{}, s.warnings, fmt.Errorf("failed to create document: %w", err)
+	}
+
+	return doc, s.warnings, nil
+}
+
+type parseState struct {
+	parser   Parser
+	stack    []*prosemirror.ContentBuilder
+	warnings prosemirror.ParseWarnings
+}
+
+func (s *parseState) top() *prosemirror.ContentBuilder {
+	return s.stack[len(s.stack)-1]
+}
+
+func (s *parseState) open(typ prosemirror.NodeType, attrs map[string]any, marks []prosemirror.Mark) {
+	s.stack = append(s.stack, prosemirror.NewContentBuilder(typ, attrs, marks))
+}
+
+func (s *parseState) close(name string) {
+	node, err := s.top().Node()
+	s.stack = s.stack[:len(s.stack)-1]
+	if err != nil {
+		s.warnings.Warn(name, "%s", err)
+		return
+	}
+
+	s.addNode(name, node)
+}
+
+func (s *parseState) addNode(name string, node prosemirror.Node) {
+	if b := s.top(); !b.Push(node) {
+		s.warnings.Warn(name, "%s is not allowed in %s", node.Type.Name, b.Type.Name)
+	}
+}
+
+func (s *parseState) addContent(content []adfNode) {
+	for _, n := range content {
+		s.addADFNode(n)
+	}
+}
+
+func (s *parseState) addADFNode(n adfNode) {
+	sch := s.parser.Schema
+	typ, ok := sch.Nodes[prosemirror.NodeTypeName(n.Type)]
+	if n.Type == "text" || (ok && typ.IsText()) {
+		s.addText(n.Text, n.Marks)
+		return
+	}
+
+	if !ok {
+		s.unknown(n)
+		return
+	}
+
+	marks := s.marks(s.top().Type, n.Marks)
+	attrs := s.attrs(typ, n.Attrs)
+	if n.Type == "heading" {
+		s.headingLevel(attrs)
+	}
+
+	if typ.IsLeaf() {
+		node, err := typ.Create(attrs, marks)
+		if err != nil {
+			s.warnings.Warn(n.Type, "%s", err)
+			return
+		}
+
+		s.addNode(n.Type, node)
+		return
+	}
+
+	s.open(typ, attrs, marks)
+	s.addContent(n.Content)
+	s.close(n.Type)
+}
+
+// headingLevel keeps the level of a heading between 1 and 6, the levels ADF allows.
+func (s *parseState) headingLevel(attrs map[string]any) {
+	v, ok := attrs["level"]
+	if !ok {
+		return
+	}
+
+	level, ok := prosemirror.IntAttr(v)
+	switch {
+	case !ok:
+		s.warnings.Warn("heading", "level %v is not a number, replaced by 1", v)
+		attrs["level"] = 1
+	case level < 1 || level > 6:
+		s.warnings.Warn("heading", "level %d is out of range, replaced by %d", level, max(1, min(level, 6)))
+		attrs["level"] = max(1, min(level, 6))
+	default:
+		attrs["level"] = level
+	}
+}
+
+// unknown keeps what it can of a node that isn't in the schema.
+func (s *parseState) unknown(n adfNode) {
+	sch := s.parser.Schema
+	text, _ := n.Attrs["text"].(string)
+	if n.Type == "emoji" && text == "" {
+		text, _ = n.Attrs["shortName"].(string)
+	}
+
+	switch {
+	case n.Type == "mediaSingle" || n.Type == "mediaGroup" || n.Type == "mediaInline" || n.Type == "media":
+		s.warnings.Warn(n.Type, "dropped, media is not supported")
+	case n.Type == "inlineCard" || n.Type == "blockCard" || n.Type == "embedCard":
+		url, _ := n.Attrs["url"].(string)
+		if url == "" {
+			s.warnings.Warn(n.Type, "dropped, cards without url are not supported")
+			return
+		}
+
+		var marks []adfMark
+		if _, ok := sch.Marks[s.parser.Link]; ok {
+			marks = []adfMark{{Type: string(s.parser.Link), Attrs: map[string]any{"href": url}}}
+		}
+
+		s.warnings.Warn(n.Type, "replaced by its url")
+		if n.Type != "inlineCard" {
+			s.paragraph(n.Type, []adfNode{{Type: "text", Text: url, Marks: marks}})
+			return
+		}
+
+		s.addText(url, append(marks, n.Marks...))
+	case text != "":
+		s.warnings.Warn(n.Type, "replaced by its text")
+		s.addText(text, n.Marks)
+	case len(n.Content) > 0 && isInline(n.Content):
+		s.warnings.Warn(n.Type, "replaced by a paragraph")
+		s.paragraph(n.Type, n.Content)
+	case len(n.Content) > 0:
+		s.warnings.Warn(n.Type, "replaced by its content")
+		s.addContent(n.Content)
+	default:
+		s.warnings.Warn(n.Type, "unsupported node")
+	}
+}
+
+// paragraph adds a paragraph with the given inline content, in place of a node of another type.
+func (s *parseState) paragraph(name string, content []adfNode) {
+	typ, ok := s.parser.Schema.Nodes[s.parser.Paragraph]
+	if !ok {
+		s.warnings.Warn(name, "dropped, paragraph %s is not in the schema", s.parser.Paragraph)
+		return
+	}
+
+	s.open(typ, nil, nil)
+	s.addContent(content)
+	s.close(name)
+}
+
+// isInline reports whether ADF content is made of inline nodes, which
+// are the nodes with text or without content.
+func isInline(content []adfNode) bool {
+	for _, n := range content {
+		if n.Type != "text" && len(n.Content) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *parseState) addText(text string, marks []adfMark) {
+	if text == "" {
+		return
+	}
+
+	node := s.parser.Schema.Text(text, s.marks(s.top().Type, marks)...)
+	s.addNode("text", node)
+}
+
+// marks returns the marks of the schema allowed in a node of the given type.
+func (s *parseState) marks(parent prosemirror.NodeType, marks []adfMark) []prosemirror.Mark {
+	var set []prosemirror.Mark
+	for _, m := range marks {
+		typ, ok := s.parser.Schema.Marks[prosemirror.MarkTypeName(m.Type)]
+		if !ok {
+			s.warnings.Warn(m.Type, "unsupported mark")
+			continue
+		}
+
+		if !parent.AllowsMarkType(typ) {
+			s.warnings.Warn(m.Type, "mark is not allowed in %s", parent.Name)
+			continue
+		}
+
+		set = typ.Create(filterAttrs(typ.Attrs, m.Attrs)).AddToSet(set)
+	}
+
+	return set
+}
+
+// attrs returns the attributes of a node of the given type, leaving out unknown ones.
+func (s *parseState) attrs(typ prosemirror.NodeType, attrs map[string]any) map[string]any {
+	return filterAttrs(typ.Attrs, attrs)
+}
+
+// filterAttrs keeps the attributes in the spec, with whole numbers decoded as ints.
+func filterAttrs(spec prosemirror.Attrs, attrs map[string]any) map[string]any {
+	out := map[string]any{}
+	for name := range spec {
+		v, ok := attrs[name]
+		if !ok {
+			continue
+		}
+
+		if f, ok := v.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			v = int(f)
+		}
+		out[name] = v
+	}
+
+	return out
+}
+
+// Serialize writes a document of the schema.ADFSpec schema as ADF JSON.
+// Attributes without a value are left out.
+func Serialize(doc prosemirror.Node) ([]byte, error) {
+	root := toADF(doc)
+	root.Version = Version
+
+	data, err := json.Marshal(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode document: %w", err)
+	}
+
+	return data, nil
+}
+
+func toADF(n prosemirror.Node) adfNode {
+	out := adfNode{Type: string(n.Type.Name), Attrs: withoutNil(n.Attrs), Text: n.Text}
+	for _, m := range n.Marks {
+		out.Marks = append(out.Marks, adfMark{Type: string(m.Type.Name), Attrs: withoutNil(m.Attrs)})
+	}
+
+	for _, child := range n.Content.Content {
+		out.Content = append(out.Content, toADF(child))
+	}
+
+	return out
+}
+
+func withoutNil(attrs map[string]any) map[string]any {
+	attrs = maps.Clone(attrs)
+	maps.DeleteFunc(attrs, func(_ string, v any) bool { return v == nil })
+	return attrs
+}
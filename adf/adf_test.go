@@ -0,0 +1,148 @@
+package adf_test
+
+import (
+	"testing"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/adf"
+	"github.com/karitham/prosemirror/builder"
+	"github.com/karitham/prosemirror/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestADF(t *testing.T) {
+	s := p.Must(p.NewSchema(schema.ADFSpec))
+
+	b := builder.New(s)
+	para := b.Node("paragraph")
+
+	t.Run("round trip", func(t *testing.T) {
+		data := `{"type":"doc","version":1,"content":[
+			{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Title"}]},
+			{"type":"panel","attrs":{"panelType":"warning"},"content":[{"type":"paragraph","content":[
+				{"type":"text","text":"hi "},
+				{"type":"mention","attrs":{"id":"abc","text":"@Ann"}},
+				{"type":"text","text":" "},
+				{"type":"status","attrs":{"text":"DONE","color":"green"}},
+				{"type":"emoji","attrs":{"shortName":":smile:","text":"😄"}},
+				{"type":"text","text":"bold","marks":[{"type":"link","attrs":{"href":"https://x.org"}},{"type":"strong"}]}
+			]}]},
+			{"type":"table","attrs":{"isNumberColumnEnabled":false,"layout":"default"},"content":[
+				{"type":"tableRow","content":[
+					{"type":"tableHeader","attrs":{"colspan":1,"rowspan":1,"colwidth":[120]},"content":[{"type":"paragraph","content":[{"type":"text","text":"a"}]}]},
+					{"type":"tableCell","attrs":{"colspan":2,"rowspan":1},"content":[{"type":"paragraph"}]}
+				]}
+			]},
+			{"type":"orderedList","attrs":{"order":3},"content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"x"}]}]}]},
+			{"type":"codeBlock","attrs":{"language":"go"},"content":[{"type":"text","text":"a\nb"}]}
+		]}`
+
+		doc, warnings, err := adf.NewParser(s).Parse([]byte(data))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Empty(t, warnings)
+		assert.Equal(t, "hi @Ann DONE😄bold", doc.Child(1).TextContent())
+		assert.Equal(t, 2, doc.Child(2).Child(0).Child(1).Attrs["colspan"])
+
+		out, err := adf.Serialize(doc)
+		if assert.NoError(t, err) {
+			assert.JSONEq(t, data, string(out))
+		}
+	})
+
+	t.Run("unsupported content", func(t *testing.T) {
+		data := `{"type":"doc","version":1,"content":[
+			{"type":"expand","attrs":{"title":"more"},"content":[{"type":"paragraph","content":[{"type":"text","text":"a"}]}]},
+			{"type":"decisionList","content":[{"type":"decisionItem","attrs":{"state":"DECIDED"},"content":[{"type":"text","text":"b"}]}]},
+			{"type":"mediaSingle","content":[{"type":"media","attrs":{"id":"1","type":"file"}}]},
+			{"type":"paragraph","marks":[{"type":"alignment","attrs":{"align":"center"}}],"content":[
+				{"type":"text","text":"c","marks":[{"type":"annotation","attrs":{"id":"1"}},{"type":"em"}]},
+				{"type":"placeholder","attrs":{"text":"d"}},
+				{"type":"inlineCard","attrs":{"url":"https://x.org"}}
+			]}
+		]}`
+
+		doc, warnings, err := adf.NewParser(s).Parse([]byte(data))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		want := b.Node("doc")(
+			para("a"),
+			para("b"),
+			para(b.Mark("em")("c"), "d", b.Node("inlineCard")(map[string]any{"url": "https://x.org"})),
+		)
+		assert.Equal(t, want.String(), doc.String())
+		assert.True(t, want.Eq(doc), "attributes differ")
+		assert.Equal(t, []adf.Warning{
+			{Source: "expand", Reason: "replaced by its content"},
+			{Source: "decisionList", Reason: "replaced by its content"},
+			{Source: "decisionItem", Reason: "replaced by a paragraph"},
+			{Source: "mediaSingle", Reason: "dropped, media is not supported"},
+			{Source: "alignment", Reason: "unsupported mark"},
+			{Source: "annotation", Reason: "unsupported mark"},
+			{Source: "placeholder", Reason: "replaced by its text"},
+		}, warnings)
+	})
+
+	t.Run("heading levels", func(t *testing.T) {
+		data := `{"type":"doc","version":1,"content":[
+			{"type":"heading","attrs":{"level":7},"content":[{"type":"text","text":"a"}]},
+			{"type":"heading","attrs":{"level":0},"content":[{"type":"text","text":"b"}]},
+			{"type":"heading","attrs":{"level":"x"},"content":[{"type":"text","text":"c"}]},
+			{"type":"heading","attrs":{"level":3},"content":[{"type":"text","text":"d"}]}
+		]}`
+
+		doc, warnings, err := adf.NewParser(s).Parse([]byte(data))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var levels []any
+		for i := 0; i < doc.ChildCount(); i++ {
+			levels = append(levels, doc.Child(i).Attrs["level"])
+		}
+
+		assert.Equal(t, []any{6, 1, 1, 3}, levels)
+		assert.Equal(t, []adf.Warning{
+			{Source: "heading", Reason: "level 7 is out of range, replaced by 6"},
+			{Source: "heading", Reason: "level 0 is out of range, replaced by 1"},
+			{Source: "heading", Reason: "level x is not a number, replaced by 1"},
+		}, warnings)
+	})
+
+	t.Run("other schemas", func(t *testing.T) {
+		basic := p.Must(p.NewSchema(schema.DefaultSpec))
+		data := `{"type":"doc","version":1,"content":[{"type":"paragraph","content":[
+			{"type":"inlineCard","attrs":{"url":"https://x.org"}},
+			{"type":"emoji","attrs":{"shortName":":custom:"}},
+			{"type":"date","attrs":{"timestamp":"1"}}
+		]}]}`
+
+		doc, warnings, err := adf.NewParser(basic).Parse([]byte(data))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		want := p.Must(basic.Nodes["doc"].CreateUnchecked(nil, nil, p.Must(basic.Nodes["paragraph"].CreateUnchecked(nil, nil,
+			basic.Text("https://x.org", basic.Mark("link", map[string]any{"href": "https://x.org"})),
+			basic.Text(":custom:"),
+		))))
+		assert.Equal(t, want.String(), doc.String())
+		assert.True(t, want.Eq(doc), "attributes differ")
+		assert.Equal(t, []adf.Warning{
+			{Source: "inlineCard", Reason: "replaced by its url"},
+			{Source: "emoji", Reason: "replaced by its text"},
+			{Source: "date", Reason: "unsupported node"},
+		}, warnings)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, _, err := adf.NewParser(s).Parse([]byte(`{"type":"paragraph"}`))
+		assert.Error(t, err)
+		_, _, err = adf.NewParser(s).Parse([]byte(`not json`))
+		assert.Error(t, err)
+	})
+}
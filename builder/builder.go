@@ -1,7 +1,42 @@
+// Package builder builds documents of a schema for tests, like prosemirror-test-builder.
+//
+// Builders take their attributes and content as arguments: a map[string]any holds
+// attributes, strings become text, and nodes and the content of marks are added
+// as they are. Text may hold tags like "<a>", which are removed from it and record
+// their position, relative to the start of the built node's content:
+//
+//	b := builder.New(s)
+//	doc, p, em := b.Node("doc"), b.Node("paragraph"), b.Mark("em")
+//	d := doc(p("one<a>"), p("t", em("w<b>"), "o"))
+//	d.Tags // map[a:4 b:8]
+//
+// Builders panic when content doesn't fit their schema, to keep tests short.
 package builder
 
-import p "github.com/karitham/prosemirror"
+import (
+	"fmt"
+	"maps"
+	"regexp"
 
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/schema"
+)
+
+// Spec returns the spec of the schema tests usually build documents of: the
+// default schema, with ordered_list, bullet_list and list_item nodes. It doesn't
+// register its types, and can be changed by the caller.
+func Spec() p.SchemaSpec {
+	spec := schema.AddListNodes(schema.DefaultSpec, "paragraph block*", "block")
+	spec.DontRegister = true
+	return spec
+}
+
+// Schema returns the schema of Spec.
+func Schema() p.Schema {
+	return p.Must(p.NewSchema(Spec()))
+}
+
+// Builder creates node and mark builders for a schema.
 type Builder struct {
 	schema p.Schema
 }
@@ -10,16 +45,181 @@ func New(schema p.Schema) *Builder {
 	return &Builder{schema: schema}
 }
 
+// Node is a built node, with the positions of the tags in its content.
+type Node struct {
+	p.Node
+
+	// The positions of the tags, relative to the start of the node's content,
+	// which are document positions for the top node.
+	Tags map[string]int
+}
+
+// Content is the inline content built by a mark builder, with the positions of its tags.
+type Content struct {
+	Nodes []p.Node
+
+	// The positions of the tags, relative to the start of the content.
+	Tags map[string]int
+}
+
+// NodeBuilder builds nodes of a type from their attributes and content.
+type NodeBuilder func(args ...any) Node
+
+// With returns a builder creating nodes with the given attributes by default.
+func (nb NodeBuilder) With(attrs map[string]any) NodeBuilder {
+	return func(args ...any) Node {
+		return nb(append([]any{attrs}, args...)...)
+	}
+}
+
+// MarkBuilder adds a mark of a type to content, from its attributes and the content.
+type MarkBuilder func(args ...any) Content
+
+// With returns a builder creating marks with the given attributes by default.
+func (mb MarkBuilder) With(attrs map[string]any) MarkBuilder {
+	return func(args ...any) Content {
+		return mb(append([]any{attrs}, args...)...)
+	}
+}
+
+// Node returns the builder of the node type with the given name.
+func (b *Builder) Node(name p.NodeTypeName) NodeBuilder {
+	typ, ok := b.schema.Nodes[name]
+	if !ok {
+		panic(fmt.Sprintf("no node type %s in schema", name))
+	}
+
+	return func(args ...any) Node {
+		attrs, content, tags := b.flatten(args, nil)
+		node, err := typ.Create(attrs, nil, content...)
+		if err != nil {
+			panic(fmt.Sprintf("failed to build %s: %s", name, err))
+		}
+
+		return Node{Node: node, Tags: tags}
+	}
+}
+
+// Mark returns the builder of the mark type with the given name.
+func (b *Builder) Mark(name p.MarkTypeName) MarkBuilder {
+	typ, ok := b.schema.Marks[name]
+	if !ok {
+		panic(fmt.Sprintf("no mark type %s in schema", name))
+	}
+
+	return func(args ...any) Content {
+		_, content, tags := b.flatten(args, func(n p.Node, attrs map[string]any) p.Node {
+			return n.Mark(typ.Create(attrs).AddToSet(n.Marks))
+		})
+
+		return Content{Nodes: content, Tags: tags}
+	}
+}
+
+// Nodes returns the builders of the node types of the schema, but text.
+func (b *Builder) Nodes() map[p.NodeTypeName]NodeBuilder {
+	builders := map[p.NodeTypeName]NodeBuilder{}
+	for name, typ := range b.schema.Nodes {
+		if !typ.IsText() {
+			builders[name] = b.Node(name)
+		}
+	}
+
+	return builders
+}
+
+// Marks returns the builders of the mark types of the schema.
+func (b *Builder) Marks() map[p.MarkTypeName]MarkBuilder {
+	builders := map[p.MarkTypeName]MarkBuilder{}
+	for name := range b.schema.Marks {
+		builders[name] = b.Mark(name)
+	}
+
+	return builders
+}
+
+var tagRe = regexp.MustCompile(`<(\w+)>`)
+
+// flatten reads the attributes and content of a builder's arguments, with the
+// positions of their tags. When set, mark is applied to the nodes of the content,
+// with the attributes read.
+func (b *Builder) flatten(args []any, mark func(n p.Node, attrs map[string]any) p.Node) (map[string]any, []p.Node, map[string]int) {
+	var attrs map[string]any
+	var nodes []p.Node
+	tags := map[string]int{}
+	pos := 0
+
+	for _, arg := range args {
+		if a, ok := arg.(map[string]any); ok {
+			if attrs == nil {
+				attrs = map[string]any{}
+			}
+
+			maps.Copy(attrs, a)
+		}
+	}
+
+	add := func(n p.Node) {
+		if mark != nil {
+			n = mark(n, attrs)
+		}
+
+		if last := len(nodes) - 1; last >= 0 && n.IsText() && nodes[last].IsText() && p.SameMarkSet(n.Marks, nodes[last].Marks) {
+			nodes[last] = b.schema.Text(nodes[last].Text+n.Text, n.Marks...)
+		} else {
+			nodes = append(nodes, n)
+		}
+
+		pos += n.NodeSize()
+	}
+
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case map[string]any:
+		case string:
+			text, at := "", 0
+			for _, m := range tagRe.FindAllStringSubmatchIndex(arg, -1) {
+				text += arg[at:m[0]]
+				tags[arg[m[2]:m[3]]] = pos + p.UTF16Len(text)
+				at = m[1]
+			}
+
+			if text += arg[at:]; text != "" {
+				add(b.schema.Text(text))
+			}
+		case p.Node:
+			add(arg)
+		case Node:
+			for name, tag := range arg.Tags {
+				tags[name] = pos + 1 + tag
+			}
+			add(arg.Node)
+		case Content:
+			for name, tag := range arg.Tags {
+				tags[name] = pos + tag
+			}
+
+			for _, n := range arg.Nodes {
+				add(n)
+			}
+		default:
+			panic(fmt.Sprintf("unexpected builder argument of type %T", arg))
+		}
+	}
+
+	return attrs, nodes, tags
+}
+
 func (b *Builder) Doc(content ...p.Node) p.Node {
-	return b.schema.Node("doc", nil, p.NewFragment(content...))
+	return b.Node("doc")(nodeArgs(content)...).Node
 }
 
 func (b *Builder) P(content ...p.Node) p.Node {
-	return b.schema.Node("paragraph", nil, p.NewFragment(content...))
+	return b.Node("paragraph")(nodeArgs(content)...).Node
 }
 
 func (b *Builder) PText(text string) p.Node {
-	return b.schema.Node("paragraph", nil, p.NewFragment(b.Text(text)))
+	return b.P(b.Text(text))
 }
 
 func (b *Builder) Text(text string) p.Node {
@@ -32,3 +232,12 @@ func (b *Builder) Em(content string) p.Node {
 		b.schema.Mark("em", nil),
 	)
 }
+
+func nodeArgs(nodes []p.Node) []any {
+	args := make([]any, len(nodes))
+	for i, n := range nodes {
+		args[i] = n
+	}
+
+	return args
+}
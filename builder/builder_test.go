@@ -0,0 +1,73 @@
+package builder_test
+
+import (
+	"testing"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/builder"
+	"github.com/karitham/prosemirror/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	s := p.Must(p.NewSchema(schema.DefaultSpec))
+	b := builder.New(s)
+	doc, para, em, strong := b.Node("doc"), b.Node("paragraph"), b.Mark("em"), b.Mark("strong")
+
+	t.Run("tags", func(t *testing.T) {
+		d := doc(para("one<a>"), para("t", em("w<b>"), "o"), b.Node("horizontal_rule")(), para("<c>😀<d>"))
+		assert.Equal(t, map[string]int{"a": 4, "b": 8, "c": 12, "d": 14}, d.Tags)
+
+		want := p.Must(s.Nodes["doc"].Create(nil, nil,
+			p.Must(s.Nodes["paragraph"].Create(nil, nil, s.Text("one"))),
+			p.Must(s.Nodes["paragraph"].Create(nil, nil, s.Text("t"), s.Text("w", s.Mark("em", nil)), s.Text("o"))),
+			p.Must(s.Nodes["horizontal_rule"].Create(nil, nil)),
+			p.Must(s.Nodes["paragraph"].Create(nil, nil, s.Text("😀"))),
+		))
+		assert.True(t, want.Eq(d.Node), "got %s", d.Node)
+
+		rp, err := d.Resolve(d.Tags["b"])
+		if assert.NoError(t, err) {
+			assert.Equal(t, "tw", rp.Parent().TextContent()[:rp.ParentOffset])
+		}
+	})
+
+	t.Run("attributes", func(t *testing.T) {
+		h2 := b.Node("heading").With(map[string]any{"level": 2})
+		link := b.Mark("link").With(map[string]any{"href": "https://x.org"})
+
+		d := doc(h2("a"), b.Node("heading")(map[string]any{"level": 3}, link("b", strong("c"))))
+		assert.Equal(t, 2, d.Child(0).Attrs["level"])
+		assert.Equal(t, 3, d.Child(1).Attrs["level"])
+
+		c := d.Child(1).Child(1)
+		assert.Equal(t, "c", c.Text)
+		assert.Len(t, c.Marks, 2)
+		assert.Equal(t, "https://x.org", c.Marks[0].Attrs["href"])
+	})
+
+	t.Run("merges text", func(t *testing.T) {
+		d := doc(para("a", em("b"), em("c"), s.Text("d"), "e"))
+		assert.Equal(t, 3, d.Child(0).ChildCount())
+		assert.Equal(t, "bc", d.Child(0).Child(1).Text)
+	})
+
+	t.Run("all builders", func(t *testing.T) {
+		nodes, marks := b.Nodes(), b.Marks()
+		assert.Contains(t, nodes, p.NodeTypeName("code_block"))
+		assert.NotContains(t, nodes, p.NodeTypeName("text"))
+		assert.Contains(t, marks, p.MarkTypeName("code"))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.Panics(t, func() { b.Node("table") })
+		assert.Panics(t, func() { para(42) })
+		assert.Panics(t, func() { b.Node("text")("a") })
+		assert.Panics(t, func() { doc("raw text in doc") })
+		assert.Panics(t, func() { b.Node("horizontal_rule")("inside hr") })
+	})
+
+	t.Run("shorthands", func(t *testing.T) {
+		assert.True(t, doc(para("a", em("b"))).Eq(b.Doc(b.P(b.Text("a"), b.Em("b")))))
+	})
+}
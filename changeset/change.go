@@ -0,0 +1,271 @@
+package changeset
+
+import (
+	"math"
+	"reflect"
+	"slices"
+)
+
+// Span is a stretch of deleted or inserted content, with the data
+// (usually the author or the commit) it was attributed to.
+type Span[T any] struct {
+	Length int
+	Data   T
+}
+
+func (s Span[T]) cut(length int) Span[T] {
+	return Span[T]{Length: length, Data: s.Data}
+}
+
+// Combine merges the data of two adjacent spans. It returns false when the data
+// can't be merged, in which case the spans are kept separate.
+type Combine[T any] func(a, b T) (T, bool)
+
+// DefaultCombine merges the data of two spans when it is deeply equal.
+func DefaultCombine[T any](a, b T) (T, bool) {
+	return a, reflect.DeepEqual(a, b)
+}
+
+func sliceSpans[T any](spans []Span[T], from, to int) []Span[T] {
+	if from == to {
+		return nil
+	}
+
+	if from == 0 && to == spansLen(spans) {
+		return spans
+	}
+
+	var result []Span[T]
+	for i, off := 0, 0; off < to; i++ {
+		span := spans[i]
+		end := off + span.Length
+		if overlap := min(to, end) - max(from, off); overlap > 0 {
+			result = append(result, span.cut(overlap))
+		}
+
+		off = end
+	}
+
+	return result
+}
+
+func joinSpans[T any](a, b []Span[T], combine Combine[T]) []Span[T] {
+	if len(a) == 0 {
+		return b
+	}
+
+	if len(b) == 0 {
+		return a
+	}
+
+	last := a[len(a)-1]
+	combined, ok := combine(last.Data, b[0].Data)
+	if !ok {
+		return append(slices.Clip(a), b...)
+	}
+
+	result := append(slices.Clone(a[:len(a)-1]), Span[T]{Length: last.Length + b[0].Length, Data: combined})
+	return append(result, b[1:]...)
+}
+
+func spansLen[T any](spans []Span[T]) int {
+	l := 0
+	for _, s := range spans {
+		l += s.Length
+	}
+
+	return l
+}
+
+// Change is a replaced range with attribution information. `FromA` and `ToA` point
+// into the old document, and `FromB` and `ToB` into the new one.
+type Change[T any] struct {
+	// The start and end of the range in the old document.
+	FromA, ToA int
+
+	// The start and end of the range in the new document.
+	FromB, ToB int
+
+	// Data associated with the deleted content. The length of these spans adds up to `ToA - FromA`.
+	Deleted []Span[T]
+
+	// Data associated with the inserted content. The length of these spans adds up to `ToB - FromB`.
+	Inserted []Span[T]
+}
+
+// LenA is the length of the change in the old document.
+func (c Change[T]) LenA() int {
+	return c.ToA - c.FromA
+}
+
+// LenB is the length of the change in the new document.
+func (c Change[T]) LenB() int {
+	return c.ToB - c.FromB
+}
+
+// Slice returns the part of this change covering the given offsets,
+// relative to its start in the old and new documents.
+func (c Change[T]) Slice(startA, endA, startB, endB int) Change[T] {
+	if startA == 0 && startB == 0 && endA == c.LenA() && endB == c.LenB() {
+		return c
+	}
+
+	return Change[T]{
+		FromA:    c.FromA + startA,
+		ToA:      c.FromA + endA,
+		FromB:    c.FromB + startB,
+		ToB:      c.FromB + endB,
+		Deleted:  sliceSpans(c.Deleted, startA, endA),
+		Inserted: sliceSpans(c.Inserted, startB, endB),
+	}
+}
+
+// MergeChanges merges two sets of changes, where the new document of `x` is the
+// old document of `y`. Deletions from `x` and insertions from `y` are kept,
+// insertions from `x` that are deleted by `y` disappear.
+func MergeChanges[T any](x, y []Change[T], combine Combine[T]) []Change[T] {
+	if len(x) == 0 {
+		return y
+	}
+
+	if len(y) == 0 {
+		return x
+	}
+
+	// the offset of the end of the previous change of a set.
+	offset := func(changes []Change[T], i int) int {
+		if i == 0 {
+			return 0
+		}
+
+		return changes[i-1].ToB - changes[i-1].ToA
+	}
+
+	var result []Change[T]
+
+	// iterate over both sets in parallel, using the middle coordinate
+	// system (B in x, A in y) to synchronize.
+	iX, iY := 0, 0
+	curX, curY := &x[0], &y[0]
+	nextX := func() {
+		iX++
+		curX = nil
+		if iX < len(x) {
+			curX = &x[iX]
+		}
+	}
+	nextY := func() {
+		iY++
+		curY = nil
+		if iY < len(y) {
+			curY = &y[iY]
+		}
+	}
+
+	for curX != nil || curY != nil {
+		switch {
+		case curX != nil && (curY == nil || curX.ToB < curY.FromA):
+			// curX is entirely in front of curY
+			c := *curX
+			if off := offset(y, iY); off != 0 {
+				c.FromB, c.ToB = c.FromB+off, c.ToB+off
+			}
+
+			result = append(result, c)
+			nextX()
+		case curY != nil && (curX == nil || curY.ToA < curX.FromB):
+			// curY is entirely in front of curX
+			c := *curY
+			if off := offset(x, iX); off != 0 {
+				c.FromA, c.ToA = c.FromA-off, c.ToA-off
+			}
+
+			result = append(result, c)
+			nextY()
+		default:
+			// The changes touch and need to be merged. Areas of the middle document
+			// covered by x but not by y are insertions from x that are kept, and
+			// areas covered by y but not x are deletions from y that are added.
+			pos := min(curX.FromB, curY.FromA)
+			fromA := min(curX.FromA, curY.FromA-offset(x, iX))
+			fromB := min(curY.FromB, curX.FromB+offset(y, iY))
+			toA, toB := fromA, fromB
+			var deleted, inserted []Span[T]
+
+			// prevent adding the ranges of the same change twice
+			enteredX, enteredY := false, false
+
+			// any number of further changes might be touching this group.
+			for {
+				endX, endY := math.MaxInt, math.MaxInt
+				if curX != nil {
+					endX = curX.FromB
+					if pos >= curX.FromB {
+						endX = curX.ToB
+					}
+				}
+
+				if curY != nil {
+					endY = curY.FromA
+					if pos >= curY.FromA {
+						endY = curY.ToA
+					}
+				}
+
+				next := min(endX, endY)
+				inX := curX != nil && pos >= curX.FromB
+				inY := curY != nil && pos >= curY.FromA
+				if !inX && !inY {
+					break
+				}
+
+				if inX && pos == curX.FromB && !enteredX {
+					deleted = joinSpans(deleted, curX.Deleted, combine)
+					toA += curX.LenA()
+					enteredX = true
+				}
+
+				if inX && !inY {
+					inserted = joinSpans(inserted, sliceSpans(curX.Inserted, pos-curX.FromB, next-curX.FromB), combine)
+					toB += next - pos
+				}
+
+				if inY && pos == curY.FromA && !enteredY {
+					inserted = joinSpans(inserted, curY.Inserted, combine)
+					toB += curY.LenB()
+					enteredY = true
+				}
+
+				if inY && !inX {
+					deleted = joinSpans(deleted, sliceSpans(curY.Deleted, pos-curY.FromA, next-curY.FromA), combine)
+					toA += next - pos
+				}
+
+				if inX && next == curX.ToB {
+					nextX()
+					enteredX = false
+				}
+
+				if inY && next == curY.ToA {
+					nextY()
+					enteredY = false
+				}
+
+				pos = next
+			}
+
+			if fromA < toA || fromB < toB {
+				result = append(result, Change[T]{
+					FromA:    fromA,
+					ToA:      toA,
+					FromB:    fromB,
+					ToB:      toB,
+					Deleted:  deleted,
+					Inserted: inserted,
+				})
+			}
+		}
+	}
+
+	return result
+}
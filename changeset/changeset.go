@@ -0,0 +1,137 @@
+// Package changeset tracks the ranges changed by a series of steps, with
+// attribution, like prosemirror-changeset.
+package changeset
+
+import (
+	"slices"
+
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/transform"
+)
+
+// ChangeSet keeps track of the changes made to a document by a series of steps,
+// as a set of replaced ranges in the start document and the current document.
+// Each deleted and inserted piece of content is attributed to the data it was added with.
+type ChangeSet[T any] struct {
+	doc     prosemirror.Node
+	combine Combine[T]
+	encoder TokenEncoder
+
+	// The replaced ranges, ordered by position.
+	Changes []Change[T]
+}
+
+// Create a changeset with the given base document. `combine` decides whether
+// the data of adjacent spans is merged, and defaults to DefaultCombine. `encoder`
+// decides how content is compared when minimizing changes, and defaults to DefaultEncoder.
+func Create[T any](doc prosemirror.Node, combine Combine[T], encoder TokenEncoder) *ChangeSet[T] {
+	if combine == nil {
+		combine = DefaultCombine[T]
+	}
+
+	if encoder == nil {
+		encoder = DefaultEncoder
+	}
+
+	return &ChangeSet[T]{doc: doc, combine: combine, encoder: encoder}
+}
+
+// StartDoc returns the starting document of the changeset.
+func (cs *ChangeSet[T]) StartDoc() prosemirror.Node {
+	return cs.doc
+}
+
+// AddSteps computes a new changeset by adding the given step maps, which lead
+// to `newDoc`. `data` is attributed to the changes of the steps: either a single
+// value for all of them, or one value per map.
+func (cs *ChangeSet[T]) AddSteps(newDoc prosemirror.Node, maps []transform.StepMap, data ...T) *ChangeSet[T] {
+	var stepChanges []Change[T]
+	for i, m := range maps {
+		var d T
+		switch {
+		case len(data) == 1:
+			d = data[0]
+		case i < len(data):
+			d = data[i]
+		}
+
+		// every range of a map is treated as its own step, in the document
+		// produced by the ranges before it.
+		off := 0
+		m.ForEach(func(fromA, toA, fromB, toB int) {
+			c := Change[T]{FromA: fromA + off, ToA: toA + off, FromB: fromB, ToB: toB}
+			if fromA != toA {
+				c.Deleted = []Span[T]{{Length: toA - fromA, Data: d}}
+			}
+
+			if fromB != toB {
+				c.Inserted = []Span[T]{{Length: toB - fromB, Data: d}}
+			}
+
+			stepChanges = append(stepChanges, c)
+			off = toB - toA
+		})
+	}
+
+	if len(stepChanges) == 0 {
+		return cs
+	}
+
+	newChanges := cs.mergeAll(stepChanges)
+	changes := MergeChanges(cs.Changes, newChanges, cs.combine)
+	updated, cloned := changes, false
+
+	// minimize the changes that touch the new ones.
+	for i := 0; i < len(updated); i++ {
+		change := updated[i]
+		if change.FromA == change.ToA || change.FromB == change.ToB ||
+			!slices.ContainsFunc(newChanges, func(r Change[T]) bool { return r.ToB > change.FromB && r.FromB < change.ToB }) {
+			continue
+		}
+
+		diff := computeDiff(cs.doc.Content, newDoc.Content, change, cs.encoder)
+
+		// if they are completely different, there is nothing to minimize.
+		if len(diff) == 1 && diff[0].FromB == change.FromB && diff[0].ToB == change.ToB {
+			continue
+		}
+
+		if !cloned {
+			updated, cloned = slices.Clone(changes), true
+		}
+
+		updated = slices.Replace(updated, i, i+1, diff...)
+		i += len(diff) - 1
+	}
+
+	return &ChangeSet[T]{doc: cs.doc, combine: cs.combine, encoder: cs.encoder, Changes: updated}
+}
+
+func (cs *ChangeSet[T]) mergeAll(changes []Change[T]) []Change[T] {
+	if len(changes) == 1 {
+		return changes
+	}
+
+	mid := len(changes) / 2
+	return MergeChanges(cs.mergeAll(changes[:mid]), cs.mergeAll(changes[mid:]), cs.combine)
+}
+
+// Map returns a changeset with the data of every span replaced by the result of `f`.
+func (cs *ChangeSet[T]) Map(f func(data T) T) *ChangeSet[T] {
+	mapSpans := func(spans []Span[T]) []Span[T] {
+		result := make([]Span[T], len(spans))
+		for i, s := range spans {
+			result[i] = Span[T]{Length: s.Length, Data: f(s.Data)}
+		}
+
+		return result
+	}
+
+	changes := make([]Change[T], len(cs.Changes))
+	for i, c := range cs.Changes {
+		c.Deleted, c.Inserted = mapSpans(c.Deleted), mapSpans(c.Inserted)
+		changes[i] = c
+	}
+
+	return &ChangeSet[T]{doc: cs.doc, combine: cs.combine, encoder: cs.encoder, Changes: changes}
+}
@@ -0,0 +1,151 @@
+package changeset_test
+
+import (
+	"testing"
+
+	"github.com/go-json-experiment/json"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/changeset"
+	"github.com/karitham/prosemirror/transform"
+
+	// for side effects
+	_ "github.com/karitham/prosemirror/schema"
+)
+
+func fromJSON[T any](s string) T {
+	var v T
+	_ = json.Unmarshal([]byte(s), &v)
+	return v
+}
+
+func TestAddSteps(t *testing.T) {
+	type edit struct {
+		author string
+		apply  func(tr *transform.Transform) error
+	}
+
+	type tt struct {
+		name  string
+		doc   string
+		edits []edit
+		want  []changeset.Change[string]
+	}
+
+	text := func(tr *transform.Transform, s string) prosemirror.Node {
+		return tr.Doc.Type.Schema.Text(s)
+	}
+
+	tests := []tt{
+		{
+			name: "insertion",
+			doc:  `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"hello world"}]}]}`,
+			edits: []edit{
+				{"alice", func(tr *transform.Transform) error { return tr.Insert(6, text(tr, " big")) }},
+			},
+			want: []changeset.Change[string]{
+				{FromA: 6, ToA: 6, FromB: 6, ToB: 10, Inserted: []changeset.Span[string]{{Length: 4, Data: "alice"}}},
+			},
+		},
+		{
+			name: "adjacent insertions by the same author are merged",
+			doc:  `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"hello"}]}]}`,
+			edits: []edit{
+				{"alice", func(tr *transform.Transform) error { return tr.Insert(6, text(tr, " wo")) }},
+				{"alice", func(tr *transform.Transform) error { return tr.Insert(9, text(tr, "rld")) }},
+			},
+			want: []changeset.Change[string]{
+				{FromA: 6, ToA: 6, FromB: 6, ToB: 12, Inserted: []changeset.Span[string]{{Length: 6, Data: "alice"}}},
+			},
+		},
+		{
+			name: "insertions by different authors keep their attribution",
+			doc:  `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"hello"}]}]}`,
+			edits: []edit{
+				{"alice", func(tr *transform.Transform) error { return tr.Insert(6, text(tr, " wo")) }},
+				{"bob", func(tr *transform.Transform) error { return tr.Insert(9, text(tr, "rld")) }},
+			},
+			want: []changeset.Change[string]{
+				{FromA: 6, ToA: 6, FromB: 6, ToB: 12, Inserted: []changeset.Span[string]{{Length: 3, Data: "alice"}, {Length: 3, Data: "bob"}}},
+			},
+		},
+		{
+			name: "deleting inserted content cancels it",
+			doc:  `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"hello"}]}]}`,
+			edits: []edit{
+				{"alice", func(tr *transform.Transform) error { return tr.Insert(6, text(tr, " world")) }},
+				{"bob", func(tr *transform.Transform) error { return tr.Delete(6, 12) }},
+			},
+			want: nil,
+		},
+		{
+			name: "replacement is minimized",
+			doc:  `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"the cat sat on the mat"}]}]}`,
+			edits: []edit{
+				{"alice", func(tr *transform.Transform) error { return tr.ReplaceWith(1, 23, text(tr, "the dog sat on the mat")) }},
+			},
+			want: []changeset.Change[string]{
+				{
+					FromA: 5, ToA: 8, FromB: 5, ToB: 8,
+					Deleted:  []changeset.Span[string]{{Length: 3, Data: "alice"}},
+					Inserted: []changeset.Span[string]{{Length: 3, Data: "alice"}},
+				},
+			},
+		},
+		{
+			name: "deletion",
+			doc:  `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"hello world"}]}]}`,
+			edits: []edit{
+				{"bob", func(tr *transform.Transform) error { return tr.Delete(1, 7) }},
+			},
+			want: []changeset.Change[string]{
+				{FromA: 1, ToA: 7, FromB: 1, ToB: 1, Deleted: []changeset.Span[string]{{Length: 6, Data: "bob"}}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := fromJSON[prosemirror.Node](tc.doc)
+			cs := changeset.Create[string](doc, nil, nil)
+
+			for _, e := range tc.edits {
+				tr := transform.New(doc)
+				if !assert.NoError(t, e.apply(tr)) {
+					return
+				}
+
+				doc = tr.Doc
+				cs = cs.AddSteps(doc, tr.Mapping.Maps, e.author)
+			}
+
+			assert.Equal(t, tc.want, cs.Changes)
+		})
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	doc := fromJSON[prosemirror.Node](`{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"hello world"}]}]}`)
+	cs := changeset.Create[string](doc, nil, nil)
+
+	tr := transform.New(doc)
+	if !assert.NoError(t, tr.ReplaceWith(8, 10, tr.Doc.Type.Schema.Text("xy"))) {
+		return
+	}
+
+	cs = cs.AddSteps(tr.Doc, tr.Mapping.Maps, "alice")
+	if !assert.Len(t, cs.Changes, 1) {
+		return
+	}
+
+	// the change inside "world" is expanded to the whole word.
+	simplified := changeset.Simplify(cs.Changes, tr.Doc)
+	assert.Equal(t, []changeset.Change[string]{
+		{
+			FromA: 7, ToA: 12, FromB: 7, ToB: 12,
+			Deleted:  []changeset.Span[string]{{Length: 5, Data: "alice"}},
+			Inserted: []changeset.Span[string]{{Length: 5, Data: "alice"}},
+		},
+	}, simplified)
+}
@@ -0,0 +1,216 @@
+package changeset
+
+import (
+	"unicode/utf16"
+
+	"github.com/karitham/prosemirror"
+)
+
+// TokenEncoder turns document content into tokens, which are compared to
+// find the minimal changes between two versions of a changed range.
+type TokenEncoder interface {
+	// EncodeCharacter encodes a single UTF-16 code unit of text with the given marks.
+	EncodeCharacter(char uint16, marks []prosemirror.Mark) any
+
+	// EncodeNodeStart encodes the start of a node, or a whole leaf node.
+	EncodeNodeStart(node prosemirror.Node) any
+
+	// EncodeNodeEnd encodes the end of a node.
+	EncodeNodeEnd(node prosemirror.Node) any
+
+	// CompareTokens reports whether two tokens are equal.
+	CompareTokens(a, b any) bool
+}
+
+// DefaultEncoder compares characters by their code, and nodes by their type name.
+// It ignores marks and attributes.
+var DefaultEncoder TokenEncoder = defaultEncoder{}
+
+type defaultEncoder struct{}
+
+func (defaultEncoder) EncodeCharacter(char uint16, _ []prosemirror.Mark) any {
+	return int(char)
+}
+
+func (defaultEncoder) EncodeNodeStart(node prosemirror.Node) any {
+	return string(node.Type.Name)
+}
+
+func (defaultEncoder) EncodeNodeEnd(prosemirror.Node) any {
+	return -1
+}
+
+func (defaultEncoder) CompareTokens(a, b any) bool {
+	return a == b
+}
+
+// tokens converts the content of a fragment between `start` and `end` into tokens,
+// one per position.
+func tokens(f prosemirror.Fragment, encoder TokenEncoder, start, end int, target []any) []any {
+	off := 0
+	for i := range f.Content {
+		child := &f.Content[i]
+		endOff := off + child.NodeSize()
+		from, to := max(off, start), min(endOff, end)
+		if from < to {
+			switch {
+			case child.IsText():
+				text := utf16.Encode([]rune(child.Text))
+				for j := from; j < to; j++ {
+					target = append(target, encoder.EncodeCharacter(text[j-off], child.Marks))
+				}
+			case child.IsLeaf():
+				target = append(target, encoder.EncodeNodeStart(*child))
+			default:
+				if from == off {
+					target = append(target, encoder.EncodeNodeStart(*child))
+				}
+
+				target = tokens(child.Content, encoder, max(off+1, from)-off-1, min(endOff-1, to)-off-1, target)
+				if to == endOff {
+					target = append(target, encoder.EncodeNodeEnd(*child))
+				}
+			}
+		}
+
+		off = endOff
+	}
+
+	return target
+}
+
+// maxDiffSize is the maximum amount of edits looked for when diffing a range.
+// Ranges that differ more are reported as a single change.
+const maxDiffSize = 2000
+
+// minUnchanged is the minimum amount of unchanged tokens that keeps two changes apart.
+func minUnchanged(sizeA, sizeB int) int {
+	return min(15, max(2, max(sizeA, sizeB)/10))
+}
+
+// computeDiff finds the minimal set of changes covering the differences between
+// the old and new content of the given change.
+func computeDiff[T any](fragA, fragB prosemirror.Fragment, r Change[T], encoder TokenEncoder) []Change[T] {
+	tokA := tokens(fragA, encoder, r.FromA, r.ToA, nil)
+	tokB := tokens(fragB, encoder, r.FromB, r.ToB, nil)
+
+	// scan from both sides to cheaply eliminate work
+	start, endA, endB := 0, len(tokA), len(tokB)
+	for start < len(tokA) && start < len(tokB) && encoder.CompareTokens(tokA[start], tokB[start]) {
+		start++
+	}
+
+	if start == len(tokA) && start == len(tokB) {
+		return nil
+	}
+
+	for endA > start && endB > start && encoder.CompareTokens(tokA[endA-1], tokB[endB-1]) {
+		endA, endB = endA-1, endB-1
+	}
+
+	whole := []Change[T]{r.Slice(start, endA, start, endB)}
+	if endA == start || endB == start || (endA == endB && endA == start+1) {
+		return whole
+	}
+
+	edits, ok := myers(tokA[start:endA], tokB[start:endB], encoder.CompareTokens)
+	if !ok {
+		return whole
+	}
+
+	// edits come back to front, changes closer than minSpan are joined.
+	minSpan := minUnchanged(endA-start, endB-start)
+
+	var diff []Change[T]
+	cur := edits[0]
+	for _, e := range edits[1:] {
+		if cur.fromA-e.toA < minSpan {
+			cur.fromA, cur.fromB = e.fromA, e.fromB
+			continue
+		}
+
+		diff = append(diff, r.Slice(start+cur.fromA, start+cur.toA, start+cur.fromB, start+cur.toB))
+		cur = e
+	}
+
+	diff = append(diff, r.Slice(start+cur.fromA, start+cur.toA, start+cur.fromB, start+cur.toB))
+
+	for i, j := 0, len(diff)-1; i < j; i, j = i+1, j-1 {
+		diff[i], diff[j] = diff[j], diff[i]
+	}
+
+	return diff
+}
+
+type edit struct {
+	fromA, toA int
+	fromB, toB int
+}
+
+// myers implements Myers' diff algorithm, returning the single-token edits
+// that turn `a` into `b`, from back to front. It gives up when more than
+// maxDiffSize edits are needed.
+func myers(a, b []any, eq func(a, b any) bool) ([]edit, bool) {
+	n, m := len(a), len(b)
+	maxD := min(maxDiffSize, n+m)
+	offset := maxD + 1
+
+	v := make([]int, 2*maxD+3)
+
+	// trace[d] holds the part of v that iteration d reads from.
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && eq(a[x], b[y]) {
+				x, y = x+1, y+1
+			}
+
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m), true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func backtrack(trace [][]int, x, y int) []edit {
+	var edits []edit
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+		}
+
+		if x == prevX {
+			edits = append(edits, edit{fromA: x, toA: x, fromB: prevY, toB: prevY + 1})
+		} else {
+			edits = append(edits, edit{fromA: prevX, toA: prevX + 1, fromB: y, toB: y})
+		}
+
+		x, y = prevX, prevY
+	}
+
+	return edits
+}
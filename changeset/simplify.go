@@ -0,0 +1,206 @@
+package changeset
+
+import (
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/karitham/prosemirror"
+)
+
+// maxSimplifyDistance is the distance changes must be apart to not be
+// considered candidates for merging.
+const maxSimplifyDistance = 30
+
+func isLetter(code uint16) bool {
+	r := rune(code)
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// getText converts a range of the document into UTF-16 code units, so that characters
+// can be accessed by position. Non-text tokens are treated as spaces so that they
+// aren't considered part of a word.
+func getText(f prosemirror.Fragment, start, end int) []uint16 {
+	var out []uint16
+
+	var convert func(f prosemirror.Fragment, start, end int)
+	convert = func(f prosemirror.Fragment, start, end int) {
+		off := 0
+		for i := range f.Content {
+			child := &f.Content[i]
+			endOff := off + child.NodeSize()
+			from, to := max(off, start), min(endOff, end)
+			if from < to {
+				switch {
+				case child.IsText():
+					out = append(out, utf16.Encode([]rune(child.Text))[from-off:to-off]...)
+				case child.IsLeaf():
+					out = append(out, ' ')
+				default:
+					if from == off {
+						out = append(out, ' ')
+					}
+
+					convert(child.Content, max(0, from-off-1), min(child.Content.Size, end-off-1))
+					if to == endOff {
+						out = append(out, ' ')
+					}
+				}
+			}
+
+			off = endOff
+		}
+	}
+
+	convert(f, start, end)
+	return out
+}
+
+// Simplify simplifies a set of changes for presentation. This makes the
+// assumption that having both insertions and deletions within a word
+// is confusing, and, when such changes occur without a word boundary
+// between them, they should be expanded to cover the entire set of
+// words (in the new document) they touch. An exception is made for
+// single-character replacements.
+func Simplify[T any](changes []Change[T], doc prosemirror.Node) []Change[T] {
+	var result []Change[T]
+	for i := 0; i < len(changes); i++ {
+		end, start := changes[i].ToB, i
+		for i < len(changes)-1 && changes[i+1].FromB <= end+maxSimplifyDistance {
+			i++
+			end = changes[i].ToB
+		}
+
+		result = simplifyAdjacentChanges(changes, start, i+1, doc, result)
+	}
+
+	return result
+}
+
+func simplifyAdjacentChanges[T any](changes []Change[T], from, to int, doc prosemirror.Node, target []Change[T]) []Change[T] {
+	start := max(0, changes[from].FromB-maxSimplifyDistance)
+	end := min(doc.Content.Size, changes[to-1].ToB+maxSimplifyDistance)
+	text := getText(doc.Content, start, end)
+	letterAt := func(pos int) bool {
+		return pos >= start && pos < end && pos-start < len(text) && isLetter(text[pos-start])
+	}
+
+	for i := from; i < to; i++ {
+		startI, last := i, changes[i]
+		deleted, inserted := last.LenA(), last.LenB()
+		for i < to-1 {
+			next, boundary := changes[i+1], false
+			prevLetter := last.ToB != end && letterAt(last.ToB-1)
+			for pos := last.ToB; !boundary && pos < next.FromB; pos++ {
+				nextLetter := pos != end && letterAt(pos)
+				if (!prevLetter || !nextLetter) && prevLetter != nextLetter {
+					boundary = true
+				}
+
+				prevLetter = nextLetter
+			}
+
+			if boundary {
+				break
+			}
+
+			deleted += next.LenA()
+			inserted += next.LenB()
+			last = next
+			i++
+		}
+
+		if inserted > 0 && deleted > 0 && !(inserted == 1 && deleted == 1) {
+			from, to := changes[startI].FromB, changes[i].ToB
+			if from < end && letterAt(from) {
+				for from > start && letterAt(from-1) {
+					from--
+				}
+			}
+
+			if to > start && letterAt(to-1) {
+				for to < end && letterAt(to) {
+					to++
+				}
+			}
+
+			joined := fillChange(changes[startI:i+1], from, to)
+			if n := len(target); n > 0 && target[n-1].ToA == joined.FromA {
+				prev := target[n-1]
+				target[n-1] = Change[T]{
+					FromA:    prev.FromA,
+					ToA:      joined.ToA,
+					FromB:    prev.FromB,
+					ToB:      joined.ToB,
+					Deleted:  append(append([]Span[T](nil), prev.Deleted...), joined.Deleted...),
+					Inserted: append(append([]Span[T](nil), prev.Inserted...), joined.Inserted...),
+				}
+			} else {
+				target = append(target, joined)
+			}
+		} else {
+			target = append(target, changes[startI:i+1]...)
+		}
+	}
+
+	return target
+}
+
+// fillChange creates a single change covering the given changes and the
+// unchanged content between them, from `fromB` to `toB` in the new document.
+func fillChange[T any](changes []Change[T], fromB, toB int) Change[T] {
+	first, last := changes[0], changes[len(changes)-1]
+	fromA := first.FromA - (first.FromB - fromB)
+	toA := last.ToA + (toB - last.ToB)
+
+	delData, insData := spanData(first.Deleted, first.Inserted), spanData(first.Inserted, first.Deleted)
+
+	var deleted, inserted []Span[T]
+	posA, posB := fromA, fromB
+	for i := 0; ; i++ {
+		endA, endB := toA, toB
+		if i < len(changes) {
+			endA, endB = changes[i].FromA, changes[i].FromB
+		}
+
+		if endA > posA {
+			deleted = joinSpans(deleted, []Span[T]{{Length: endA - posA, Data: delData}}, DefaultCombine[T])
+		}
+
+		if endB > posB {
+			inserted = joinSpans(inserted, []Span[T]{{Length: endB - posB, Data: insData}}, DefaultCombine[T])
+		}
+
+		if i == len(changes) {
+			break
+		}
+
+		next := changes[i]
+		deleted = joinSpans(deleted, next.Deleted, DefaultCombine[T])
+		inserted = joinSpans(inserted, next.Inserted, DefaultCombine[T])
+		if len(deleted) > 0 {
+			delData = deleted[len(deleted)-1].Data
+		}
+
+		if len(inserted) > 0 {
+			insData = inserted[len(inserted)-1].Data
+		}
+
+		posA, posB = next.ToA, next.ToB
+	}
+
+	return Change[T]{FromA: fromA, ToA: toA, FromB: fromB, ToB: toB, Deleted: deleted, Inserted: inserted}
+}
+
+// spanData returns the data of the first span of `spans`, or of `fallback` when it is empty.
+func spanData[T any](spans, fallback []Span[T]) T {
+	if len(spans) == 0 {
+		spans = fallback
+	}
+
+	var data T
+	if len(spans) > 0 {
+		data = spans[0].Data
+	}
+
+	return data
+}
@@ -0,0 +1,96 @@
+// Command pmcompat compares two versions of a schema spec, in JSON or YAML,
+// and writes the report of their changes as JSON. It exits with status 1 when
+// some change is breaking, to gate deployments in CI:
+//
+//	pmcompat schema.old.json schema.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+	"gopkg.in/yaml.v3"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/compat"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: pmcompat [flags] old new")
+		flag.PrintDefaults()
+	}
+	breakingOnly := flag.Bool("breaking", false, "only report breaking changes")
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	report, err := run(flag.Arg(0), flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "pmcompat:", err)
+		os.Exit(2)
+	}
+
+	if *breakingOnly {
+		report.Changes = report.BreakingChanges()
+	}
+
+	if report.Changes == nil {
+		report.Changes = []compat.Change{}
+	}
+
+	if err := json.MarshalWrite(os.Stdout, report, jsontext.WithIndent("  ")); err != nil {
+		fmt.Fprintln(os.Stderr, "pmcompat:", err)
+		os.Exit(2)
+	}
+	fmt.Println()
+
+	if report.Breaking {
+		os.Exit(1)
+	}
+}
+
+func run(oldPath, newPath string) (compat.Report, error) {
+	before, err := load(oldPath)
+	if err != nil {
+		return compat.Report{}, err
+	}
+
+	after, err := load(newPath)
+	if err != nil {
+		return compat.Report{}, err
+	}
+
+	return compat.Check(before, after), nil
+}
+
+// load reads and compiles a schema spec, in YAML when its file has a YAML
+// extension, and JSON otherwise.
+func load(path string) (p.Schema, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return p.Schema{}, err
+	}
+
+	var spec p.SchemaSpec
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &spec)
+	default:
+		err = json.Unmarshal(b, &spec)
+	}
+
+	if err != nil {
+		return p.Schema{}, fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	spec.DontRegister = true
+	return p.NewSchema(spec)
+}
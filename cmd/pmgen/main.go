@@ -0,0 +1,74 @@
+// Command pmgen generates typed constructors and attribute accessors for the
+// node and mark types of a schema spec in JSON, for use with go:generate:
+//
+//	//go:generate go run github.com/karitham/prosemirror/cmd/pmgen -spec schema.json -pkg docs -o schema_gen.go
+//
+// The generated code creates nodes and marks of the schema held by the
+// -schema expression, which the package declares itself.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-json-experiment/json"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/codegen"
+)
+
+func main() {
+	specPath := flag.String("spec", "", "path of the JSON schema spec")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package of the generated file")
+	schema := flag.String("schema", "Schema", "Go expression of the compiled schema")
+	imports := flag.String("imports", "", "comma separated imports needed by the schema expression")
+	out := flag.String("o", "", "path of the generated file, defaults to stdout")
+	flag.Parse()
+
+	if err := run(*specPath, *out, codegen.Options{
+		Package: *pkg,
+		Schema:  *schema,
+		Imports: splitList(*imports),
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "pmgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(specPath, out string, opts codegen.Options) error {
+	if specPath == "" {
+		return fmt.Errorf("no spec given")
+	}
+
+	b, err := os.ReadFile(specPath)
+	if err != nil {
+		return err
+	}
+
+	var spec p.SchemaSpec
+	if err := json.Unmarshal(b, &spec); err != nil {
+		return fmt.Errorf("error reading spec %s: %w", specPath, err)
+	}
+
+	src, err := codegen.Generate(spec, opts)
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+
+	return os.WriteFile(out, src, 0o644)
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,345 @@
+// Package codegen generates typed Go constructors and attribute accessors for
+// the node and mark types of a schema, so that attribute names and types are
+// checked at compile time.
+//
+// For a heading node with a level attribute, it generates:
+//
+//	type HeadingAttrs struct{ Level int }
+//	func (a HeadingAttrs) Map() map[string]any
+//	func HeadingAttrsOf(n p.Node) HeadingAttrs
+//	func NewHeading(level int, content ...p.Node) (p.Node, error)
+//
+// Marks get the same, with a Mark suffix: LinkMarkAttrs, LinkMarkAttrsOf
+// and NewLinkMark. The type of an attribute is the type of its default, or
+// any when it has none.
+//
+// The cmd/pmgen command generates code from a spec in JSON. Specs written in
+// Go are generated by a small program calling Generate, run by go:generate.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	p "github.com/karitham/prosemirror"
+)
+
+// Options configures the generated code.
+type Options struct {
+	// The package of the generated file.
+	Package string
+
+	// The Go expression of the compiled schema the constructors create nodes
+	// and marks of, like "Schema" or "docs.Schema". Defaults to "Schema".
+	Schema string
+
+	// Additional imports of the generated file, needed by the Schema expression.
+	Imports []string
+}
+
+// Generate returns the Go source of the constructors and accessors of the node
+// and mark types of spec, in schema order. The text node type is left out.
+func Generate(spec p.SchemaSpec, opts Options) ([]byte, error) {
+	if opts.Package == "" {
+		return nil, fmt.Errorf("no package given")
+	}
+
+	if opts.Schema == "" {
+		opts.Schema = "Schema"
+	}
+
+	spec.DontRegister = true
+	s, err := p.NewSchema(spec)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling schema: %w", err)
+	}
+
+	g := &generator{opts: opts, helpers: map[string]bool{}}
+	for _, typ := range s.NodeTypes() {
+		if !typ.IsText() {
+			g.node(typ)
+		}
+	}
+
+	for _, typ := range s.MarkTypes() {
+		g.mark(typ)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by pmgen. DO NOT EDIT.\n\npackage %s\n\n", opts.Package)
+	fmt.Fprintf(&out, "import (\n\tp %q\n", "github.com/karitham/prosemirror")
+	for _, imp := range opts.Imports {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	fmt.Fprintf(&out, ")\n")
+
+	out.Write(g.buf.Bytes())
+	for _, name := range helperNames {
+		if g.helpers[name] {
+			out.WriteString(helpers[name])
+		}
+	}
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated code: %w", err)
+	}
+
+	return src, nil
+}
+
+type generator struct {
+	opts    Options
+	buf     bytes.Buffer
+	helpers map[string]bool
+}
+
+// attr is an attribute of a type, with the Go names of its field and parameter.
+type attr struct {
+	name  string
+	field string
+	param string
+	typ   string
+}
+
+func (g *generator) attrs(spec map[string]p.Attribute) []attr {
+	names := make([]string, 0, len(spec))
+	for name := range spec {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	attrs := make([]attr, len(names))
+	for i, name := range names {
+		field := exportedName(name)
+		if field == "Map" {
+			field += "Attr"
+		}
+
+		attrs[i] = attr{
+			name:  name,
+			field: field,
+			param: paramName(field),
+			typ:   goType(spec[name].Default),
+		}
+	}
+
+	return attrs
+}
+
+func (g *generator) node(typ p.NodeType) {
+	name := exportedName(string(typ.Name))
+	attrs := g.attrs(typ.Spec.Attrs)
+
+	params := make([]string, 0, len(attrs)+1)
+	for _, a := range attrs {
+		params = append(params, a.param+" "+a.typ)
+	}
+
+	if !typ.IsLeaf() {
+		params = append(params, "content ...p.Node")
+	}
+
+	fmt.Fprintf(&g.buf, "\n// New%s creates a node of type %s.\n", name, typ.Name)
+	fmt.Fprintf(&g.buf, "func New%s(%s) (p.Node, error) {\n", name, strings.Join(params, ", "))
+
+	content := ""
+	if !typ.IsLeaf() {
+		content = ", content..."
+	}
+
+	if len(attrs) == 0 {
+		fmt.Fprintf(&g.buf, "\treturn %s.Nodes[%q].Create(nil, nil%s)\n}\n", g.opts.Schema, typ.Name, content)
+		return
+	}
+
+	fmt.Fprintf(&g.buf, "\treturn %s.Nodes[%q].Create(%s.Map(), nil%s)\n}\n", g.opts.Schema, typ.Name, literal(name+"Attrs", attrs), content)
+	g.attrsType(name+"Attrs", string(typ.Name)+" nodes", "p.Node", "n", attrs)
+}
+
+func (g *generator) mark(typ p.MarkType) {
+	name := exportedName(string(typ.Name)) + "Mark"
+	attrs := g.attrs(typ.Spec.Attrs)
+
+	params := make([]string, len(attrs))
+	for i, a := range attrs {
+		params[i] = a.param + " " + a.typ
+	}
+
+	fmt.Fprintf(&g.buf, "\n// New%s creates a mark of type %s.\n", name, typ.Name)
+	fmt.Fprintf(&g.buf, "func New%s(%s) p.Mark {\n", name, strings.Join(params, ", "))
+	if len(attrs) == 0 {
+		fmt.Fprintf(&g.buf, "\treturn %s.Marks[%q].Create(nil)\n}\n", g.opts.Schema, typ.Name)
+		return
+	}
+
+	fmt.Fprintf(&g.buf, "\treturn %s.Marks[%q].Create(%s.Map())\n}\n", g.opts.Schema, typ.Name, literal(name+"Attrs", attrs))
+	g.attrsType(name+"Attrs", string(typ.Name)+" marks", "p.Mark", "m", attrs)
+}
+
+// attrsType writes the attributes struct of a type, converting to and from
+// the attribute maps of its nodes or marks.
+func (g *generator) attrsType(name, of, holder, recv string, attrs []attr) {
+	fmt.Fprintf(&g.buf, "\n// %s holds the attributes of %s.\ntype %s struct {\n", name, of, name)
+	for _, a := range attrs {
+		fmt.Fprintf(&g.buf, "\t%s %s `json:%q`\n", a.field, a.typ, a.name)
+	}
+	fmt.Fprintf(&g.buf, "}\n")
+
+	fmt.Fprintf(&g.buf, "\n// Map returns the attributes as an attribute map.\nfunc (a %s) Map() map[string]any {\n\treturn map[string]any{\n", name)
+	for _, a := range attrs {
+		fmt.Fprintf(&g.buf, "\t\t%q: a.%s,\n", a.name, a.field)
+	}
+	fmt.Fprintf(&g.buf, "\t}\n}\n")
+
+	fmt.Fprintf(&g.buf, "\n// %sOf reads the attributes of %s.\nfunc %sOf(%s %s) %s {\n\treturn %s{\n", name, of, name, recv, holder, name, name)
+	for _, a := range attrs {
+		helper := attrHelper(a.typ)
+		g.helpers[helper] = true
+		if helper == "attrOf" {
+			helper += "[" + a.typ + "]"
+		}
+
+		fmt.Fprintf(&g.buf, "\t\t%s: %s(%s.Attrs, %q),\n", a.field, helper, recv, a.name)
+	}
+	fmt.Fprintf(&g.buf, "\t}\n}\n")
+}
+
+// literal returns a composite literal of an attributes struct, built from
+// the constructor's parameters.
+func literal(typ string, attrs []attr) string {
+	fields := make([]string, len(attrs))
+	for i, a := range attrs {
+		fields[i] = a.field + ": " + a.param
+	}
+
+	return typ + "{" + strings.Join(fields, ", ") + "}"
+}
+
+// goType returns the Go type of an attribute with the given default.
+func goType(def any) string {
+	switch def.(type) {
+	case nil:
+		return "any"
+	case int, float64, string, bool:
+		return reflect.TypeOf(def).String()
+	}
+
+	// other defaults are kept as they are when they have a type of their own
+	if t := reflect.TypeOf(def); t.PkgPath() == "" && t.Kind() == reflect.Slice && t.Elem().PkgPath() == "" && t.Elem().Kind() != reflect.Interface {
+		return t.String()
+	}
+
+	return "any"
+}
+
+// attrHelper returns the helper reading attributes of the given type.
+// Numbers are converted, since documents read from JSON hold float64s.
+func attrHelper(typ string) string {
+	switch typ {
+	case "any":
+		return "attrAny"
+	case "int":
+		return "attrInt"
+	case "float64":
+		return "attrFloat"
+	}
+
+	return "attrOf"
+}
+
+var helperNames = []string{"attrAny", "attrInt", "attrFloat", "attrOf"}
+
+var helpers = map[string]string{
+	"attrAny": `
+func attrAny(attrs map[string]any, name string) any {
+	return attrs[name]
+}
+`,
+	"attrInt": `
+func attrInt(attrs map[string]any, name string) int {
+	switch v := attrs[name].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+
+	return 0
+}
+`,
+	"attrFloat": `
+func attrFloat(attrs map[string]any, name string) float64 {
+	switch v := attrs[name].(type) {
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	}
+
+	return 0
+}
+`,
+	"attrOf": `
+func attrOf[T any](attrs map[string]any, name string) T {
+	v, _ := attrs[name].(T)
+	return v
+}
+`,
+}
+
+// initialisms are written in upper case in Go names.
+var initialisms = map[string]bool{
+	"id": true, "url": true, "uri": true, "html": true, "json": true, "api": true, "http": true, "css": true,
+}
+
+// exportedName converts a type or attribute name, like bullet_list or
+// data-id, to an exported Go name, like BulletList or DataID.
+func exportedName(name string) string {
+	var b strings.Builder
+	words := strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for _, w := range words {
+		if initialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+
+	out := b.String()
+	if out == "" || !unicode.IsLetter([]rune(out)[0]) {
+		out = "X" + out
+	}
+
+	return out
+}
+
+// paramName converts an exported name to a parameter name, avoiding
+// keywords and the content parameter.
+func paramName(exported string) string {
+	r := []rune(exported)
+	i := 0
+	for i+1 < len(r) && unicode.IsUpper(r[i+1]) {
+		i++
+	}
+
+	if i == 0 || i+1 == len(r) {
+		i++
+	}
+
+	param := strings.ToLower(string(r[:i])) + string(r[i:])
+	if token.IsKeyword(param) || param == "content" || param == "p" {
+		param += "Attr"
+	}
+
+	return param
+}
@@ -0,0 +1,85 @@
+package codegen_test
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/codegen"
+	"github.com/karitham/prosemirror/schema"
+)
+
+func TestGenerate(t *testing.T) {
+	spec := schema.DefaultSpec
+	spec.Nodes = map[p.NodeTypeName]p.NodeSpec{
+		"doc":       {Content: "block+"},
+		"paragraph": {Content: "inline*", Group: "block"},
+		"heading": {Content: "inline*", Group: "block", Attrs: map[string]p.Attribute{
+			"level": {Default: 1},
+			"type":  {Default: "title"},
+		}},
+		"table_cell": {Content: "paragraph+", Group: "block", Attrs: map[string]p.Attribute{
+			"colwidth": {Default: []int{}},
+			"data-id":  {Optional: true},
+		}},
+		"text":  {Group: "inline"},
+		"image": {Inline: true, Group: "inline", Attrs: map[string]p.Attribute{"src": {}}},
+	}
+
+	src, err := codegen.Generate(spec, codegen.Options{Package: "docs"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	code := string(src)
+	for _, want := range []string{
+		"// Code generated by pmgen. DO NOT EDIT.",
+		"func NewDoc(content ...p.Node) (p.Node, error) {",
+		"func NewHeading(level int, typeAttr string, content ...p.Node) (p.Node, error) {",
+		"return Schema.Nodes[\"heading\"].Create(HeadingAttrs{Level: level, Type: typeAttr}.Map(), nil, content...)",
+		"Level int    `json:\"level\"`",
+		"func HeadingAttrsOf(n p.Node) HeadingAttrs {",
+		"Level: attrInt(n.Attrs, \"level\"),",
+		"Type:  attrOf[string](n.Attrs, \"type\"),",
+		"func NewTableCell(colwidth []int, dataID any, content ...p.Node) (p.Node, error) {",
+		"func NewImage(src any) (p.Node, error) {",
+		"func NewLinkMark(href any, title any) p.Mark {",
+		"func LinkMarkAttrsOf(m p.Mark) LinkMarkAttrs {",
+		"func NewEmMark() p.Mark {",
+	} {
+		assert.Contains(t, code, want)
+	}
+	assert.NotContains(t, code, "NewText")
+	assert.NotContains(t, code, "attrFloat", "unused helpers are left out")
+
+	// the generated code type checks against the package
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "schema_gen.go", src, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	schemaDecl, err := parser.ParseFile(fset, "schema.go", "package docs\nimport p \"github.com/karitham/prosemirror\"\nvar Schema p.Schema", 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check("docs", fset, []*ast.File{f, schemaDecl}, nil)
+	assert.NoError(t, err)
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := codegen.Generate(spec, codegen.Options{})
+		assert.Error(t, err)
+
+		spec.TopNode = "missing"
+		_, err = codegen.Generate(spec, codegen.Options{Package: "docs"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,1216 @@
+// Package commands implements the basic editing commands of prosemirror-commands,
+// operating on an editor state without a view.
+package commands
+
+import (
+	"regexp"
+
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/state"
+	"github.com/karitham/prosemirror/transform"
+)
+
+// Command is a function that tries to perform an editing action on the given state.
+//
+// When the command is not applicable, it returns false. When it is, it returns true,
+// and if dispatch is not nil, it calls dispatch with the transaction performing the action.
+// Calling a command with a nil dispatch can be used to check whether it applies.
+type Command func(s *state.EditorState, dispatch func(tr *state.Transaction)) bool
+
+// DeleteSelection deletes the selection, if there is one.
+func DeleteSelection(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	if s.Selection.Empty() {
+		return false
+	}
+
+	return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+		return tr.DeleteSelection()
+	})
+}
+
+func atBlockStart(s *state.EditorState) *prosemirror.ResolvedPos {
+	sel, ok := s.Selection.(*state.TextSelection)
+	if !ok {
+		return nil
+	}
+
+	cursor := sel.Cursor()
+	if cursor == nil || cursor.ParentOffset > 0 {
+		return nil
+	}
+
+	return cursor
+}
+
+// JoinBackward, if the selection is empty and at the start of a textblock,
+// tries to reduce the distance between that block and the one before it: if
+// there's a block directly before it that can be joined, join them. If not,
+// try to move the selected block closer to the next one in the document
+// structure by lifting it out of its parent or moving it into a parent of the
+// previous block.
+func JoinBackward(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	cursor := atBlockStart(s)
+	if cursor == nil {
+		return false
+	}
+
+	cut := findCutBefore(*cursor)
+
+	// If there is no node before this, try to lift
+	if cut == nil {
+		r := cursor.BlockRange(*cursor, nil)
+		if r == nil {
+			return false
+		}
+
+		target := transform.LiftTarget(*r)
+		if target < 0 {
+			return false
+		}
+
+		return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+			return tr.Lift(*r, target)
+		})
+	}
+
+	before := cut.NodeBefore()
+
+	// Apply the joining algorithm
+	if deleteBarrier(s, *cut, dispatch, -1) {
+		return true
+	}
+
+	// If the node below has no content and the node above is
+	// selectable, delete the node below, selecting the one above.
+	if cursor.Parent().Content.Size == 0 && (textblockAt(*before, false, false) || state.IsSelectable(*before)) {
+		for depth := cursor.Depth; ; depth-- {
+			delStep, err := transform.ReplaceStepFor(s.Doc, cursor.Before(depth), cursor.After(depth), prosemirror.Slice{})
+			if err == nil && shrinks(delStep) {
+				return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+					if err := tr.Step(delStep); err != nil {
+						return err
+					}
+
+					if textblockAt(*before, false, false) {
+						rp, err := tr.Doc.Resolve(tr.Mapping.Map(cut.Pos, -1))
+						if err != nil {
+							return err
+						}
+
+						if sel := state.FindSelectionFrom(rp, -1, false); sel != nil {
+							tr.SetSelection(sel)
+						}
+
+						return nil
+					}
+
+					sel, err := state.CreateNodeSelection(tr.Doc, cut.Pos-before.NodeSize())
+					if err != nil {
+						return err
+					}
+
+					tr.SetSelection(sel)
+					return nil
+				})
+			}
+
+			if depth == 1 || cursor.Node(depth-1).ChildCount() > 1 {
+				break
+			}
+		}
+	}
+
+	// If the node before is an atom, delete it
+	if before.IsAtom() && cut.Depth == cursor.Depth-1 {
+		return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+			return tr.Delete(cut.Pos-before.NodeSize(), cut.Pos)
+		})
+	}
+
+	return false
+}
+
+// shrinks reports whether the given step is a replace step that removes more than it inserts.
+func shrinks(step transform.Applier) bool {
+	rs, ok := step.(*transform.ReplaceStep)
+	return ok && rs.Slice.Size() < rs.To-rs.From
+}
+
+// JoinTextblockBackward is a more limited form of JoinBackward that only tries
+// to join the current textblock to the one before it, if the cursor is at the
+// start of a textblock.
+func JoinTextblockBackward(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	cursor := atBlockStart(s)
+	if cursor == nil {
+		return false
+	}
+
+	cut := findCutBefore(*cursor)
+	return cut != nil && joinTextblocksAround(s, *cut, dispatch)
+}
+
+// JoinTextblockForward is a more limited form of JoinForward that only tries
+// to join the current textblock to the one after it, if the cursor is at the
+// end of a textblock.
+func JoinTextblockForward(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	cursor := atBlockEnd(s)
+	if cursor == nil {
+		return false
+	}
+
+	cut := findCutAfter(*cursor)
+	return cut != nil && joinTextblocksAround(s, *cut, dispatch)
+}
+
+func joinTextblocksAround(s *state.EditorState, cut prosemirror.ResolvedPos, dispatch func(tr *state.Transaction)) bool {
+	beforeText, beforePos := cut.NodeBefore(), cut.Pos-1
+	for ; !beforeText.IsTextblock(); beforePos-- {
+		if beforeText.Type.Spec.Isolating {
+			return false
+		}
+
+		beforeText = beforeText.LastChild()
+		if beforeText == nil {
+			return false
+		}
+	}
+
+	afterText, afterPos := cut.NodeAfter(), cut.Pos+1
+	for ; !afterText.IsTextblock(); afterPos++ {
+		if afterText.Type.Spec.Isolating {
+			return false
+		}
+
+		afterText = afterText.FirstChild()
+		if afterText == nil {
+			return false
+		}
+	}
+
+	step, err := transform.ReplaceStepFor(s.Doc, beforePos, afterPos, prosemirror.Slice{})
+	if err != nil || step == nil {
+		return false
+	}
+
+	switch st := step.(type) {
+	case *transform.ReplaceStep:
+		if st.From != beforePos || st.Slice.Size() >= afterPos-beforePos {
+			return false
+		}
+	case *transform.ReplaceAroundStep:
+		if st.From != beforePos {
+			return false
+		}
+	}
+
+	return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+		if err := tr.Step(step); err != nil {
+			return err
+		}
+
+		sel, err := state.CreateTextSelection(tr.Doc, beforePos, beforePos)
+		if err != nil {
+			return err
+		}
+
+		tr.SetSelection(sel)
+		return nil
+	})
+}
+
+// textblockAt reports whether a textblock is found along the start (when `start`
+// is true) or the end of the given node. When `only` is true, every node on the way
+// must have a single child.
+func textblockAt(node prosemirror.Node, start bool, only bool) bool {
+	for scan := &node; scan != nil; {
+		if scan.IsTextblock() {
+			return true
+		}
+
+		if only && scan.ChildCount() != 1 {
+			return false
+		}
+
+		if start {
+			scan = scan.FirstChild()
+		} else {
+			scan = scan.LastChild()
+		}
+	}
+
+	return false
+}
+
+// SelectNodeBackward, when the selection is empty and at the start of a
+// textblock, selects the node before that textblock, if possible. This is
+// intended to be bound to keys like backspace, after JoinBackward or other
+// deleting commands, as a fall-back behavior when the schema doesn't allow
+// deletion at the selected point.
+func SelectNodeBackward(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	sel := s.Selection
+	if !sel.Empty() {
+		return false
+	}
+
+	head := sel.Head()
+	cut := &head
+	if head.Parent().IsTextblock() {
+		if head.ParentOffset > 0 {
+			return false
+		}
+
+		cut = findCutBefore(head)
+	}
+
+	if cut == nil {
+		return false
+	}
+
+	node := cut.NodeBefore()
+	if node == nil || !state.IsSelectable(*node) {
+		return false
+	}
+
+	return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+		sel, err := state.CreateNodeSelection(s.Doc, cut.Pos-node.NodeSize())
+		if err != nil {
+			return err
+		}
+
+		tr.SetSelection(sel)
+		return nil
+	})
+}
+
+func findCutBefore(pos prosemirror.ResolvedPos) *prosemirror.ResolvedPos {
+	if pos.Parent().Type.Spec.Isolating {
+		return nil
+	}
+
+	for i := pos.Depth - 1; i >= 0; i-- {
+		if pos.Index(i) > 0 {
+			cut, err := pos.Doc().Resolve(pos.Before(i + 1))
+			if err != nil {
+				return nil
+			}
+
+			return &cut
+		}
+
+		if pos.Node(i).Type.Spec.Isolating {
+			break
+		}
+	}
+
+	return nil
+}
+
+func atBlockEnd(s *state.EditorState) *prosemirror.ResolvedPos {
+	sel, ok := s.Selection.(*state.TextSelection)
+	if !ok {
+		return nil
+	}
+
+	cursor := sel.Cursor()
+	if cursor == nil || cursor.ParentOffset < cursor.Parent().Content.Size {
+		return nil
+	}
+
+	return cursor
+}
+
+// JoinForward, if the selection is empty and the cursor is at the end of a
+// textblock, tries to reduce or remove the boundary between that block and
+// the one after it, either by joining them or by moving the other block
+// closer to this one in the tree structure.
+func JoinForward(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	cursor := atBlockEnd(s)
+	if cursor == nil {
+		return false
+	}
+
+	cut := findCutAfter(*cursor)
+
+	// If there is no node after this, there's nothing to do
+	if cut == nil {
+		return false
+	}
+
+	after := cut.NodeAfter()
+
+	// Try the joining algorithm
+	if deleteBarrier(s, *cut, dispatch, 1) {
+		return true
+	}
+
+	// If the node above has no content and the node below is
+	// selectable, delete the node above, selecting the one below.
+	if cursor.Parent().Content.Size == 0 && (textblockAt(*after, true, false) || state.IsSelectable(*after)) {
+		delStep, err := transform.ReplaceStepFor(s.Doc, cursor.Before(cursor.Depth), cursor.After(cursor.Depth), prosemirror.Slice{})
+		if err == nil && shrinks(delStep) {
+			return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+				if err := tr.Step(delStep); err != nil {
+					return err
+				}
+
+				if textblockAt(*after, true, false) {
+					rp, err := tr.Doc.Resolve(tr.Mapping.Map(cut.Pos, 1))
+					if err != nil {
+						return err
+					}
+
+					if sel := state.FindSelectionFrom(rp, 1, false); sel != nil {
+						tr.SetSelection(sel)
+					}
+
+					return nil
+				}
+
+				sel, err := state.CreateNodeSelection(tr.Doc, tr.Mapping.Map(cut.Pos, 1))
+				if err != nil {
+					return err
+				}
+
+				tr.SetSelection(sel)
+				return nil
+			})
+		}
+	}
+
+	// If the next node is an atom, delete it
+	if after.IsAtom() && cut.Depth == cursor.Depth-1 {
+		return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+			return tr.Delete(cut.Pos, cut.Pos+after.NodeSize())
+		})
+	}
+
+	return false
+}
+
+// SelectNodeForward, when the selection is empty and at the end of a textblock,
+// selects the node coming after that textblock, if possible. This is intended
+// to be bound to keys like delete, after JoinForward and similar deleting
+// commands, to provide a fall-back behavior when the schema doesn't allow
+// deletion at the selected point.
+func SelectNodeForward(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	sel := s.Selection
+	if !sel.Empty() {
+		return false
+	}
+
+	head := sel.Head()
+	cut := &head
+	if head.Parent().IsTextblock() {
+		if head.ParentOffset < head.Parent().Content.Size {
+			return false
+		}
+
+		cut = findCutAfter(head)
+	}
+
+	if cut == nil {
+		return false
+	}
+
+	node := cut.NodeAfter()
+	if node == nil || !state.IsSelectable(*node) {
+		return false
+	}
+
+	return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+		sel, err := state.CreateNodeSelection(s.Doc, cut.Pos)
+		if err != nil {
+			return err
+		}
+
+		tr.SetSelection(sel)
+		return nil
+	})
+}
+
+func findCutAfter(pos prosemirror.ResolvedPos) *prosemirror.ResolvedPos {
+	if pos.Parent().Type.Spec.Isolating {
+		return nil
+	}
+
+	for i := pos.Depth - 1; i >= 0; i-- {
+		parent := pos.Node(i)
+		if pos.Index(i)+1 < parent.ChildCount() {
+			cut, err := pos.Doc().Resolve(pos.After(i + 1))
+			if err != nil {
+				return nil
+			}
+
+			return &cut
+		}
+
+		if parent.Type.Spec.Isolating {
+			break
+		}
+	}
+
+	return nil
+}
+
+// JoinUp joins the selected block or, if there is a text selection, the
+// closest ancestor block of the selection that can be joined, with the
+// sibling above it.
+func JoinUp(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	sel := s.Selection
+	nodeSel, isNodeSel := sel.(*state.NodeSelection)
+
+	var point int
+	if isNodeSel {
+		if nodeSel.Node.IsTextblock() || !transform.CanJoin(s.Doc, sel.From().Pos) {
+			return false
+		}
+
+		point = sel.From().Pos
+	} else {
+		point = transform.JoinPoint(s.Doc, sel.From().Pos, -1)
+		if point < 0 {
+			return false
+		}
+	}
+
+	return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+		if err := tr.Join(point, 1); err != nil {
+			return err
+		}
+
+		if isNodeSel {
+			rp, err := s.Doc.Resolve(point)
+			if err != nil {
+				return err
+			}
+
+			sel, err := state.CreateNodeSelection(tr.Doc, point-rp.NodeBefore().NodeSize())
+			if err != nil {
+				return err
+			}
+
+			tr.SetSelection(sel)
+		}
+
+		return nil
+	})
+}
+
+// JoinDown joins the selected block, or the closest ancestor of the selection
+// that can be joined, with the sibling after it.
+func JoinDown(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	sel := s.Selection
+
+	var point int
+	if nodeSel, ok := sel.(*state.NodeSelection); ok {
+		if nodeSel.Node.IsTextblock() || !transform.CanJoin(s.Doc, sel.To().Pos) {
+			return false
+		}
+
+		point = sel.To().Pos
+	} else {
+		point = transform.JoinPoint(s.Doc, sel.To().Pos, 1)
+		if point < 0 {
+			return false
+		}
+	}
+
+	return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+		return tr.Join(point, 1)
+	})
+}
+
+// Lift the selected block, or the closest ancestor block of the selection
+// that can be lifted, out of its parent node.
+func Lift(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	from, to := s.Selection.From(), s.Selection.To()
+
+	r := from.BlockRange(to, nil)
+	if r == nil {
+		return false
+	}
+
+	target := transform.LiftTarget(*r)
+	if target < 0 {
+		return false
+	}
+
+	return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+		return tr.Lift(*r, target)
+	})
+}
+
+// NewlineInCode, if the selection is in a node whose type has a truthy
+// Code property in its spec, replaces the selection with a newline character.
+func NewlineInCode(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	head, anchor := s.Selection.Head(), s.Selection.Anchor()
+	if !head.Parent().Type.Spec.Code || !head.SameParent(anchor) {
+		return false
+	}
+
+	return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+		return tr.InsertText("\n")
+	})
+}
+
+func defaultBlockAt(match *prosemirror.ContentMatch) *prosemirror.NodeType {
+	if match == nil {
+		return nil
+	}
+
+	for i := 0; i < match.EdgeCount(); i++ {
+		typ, _ := match.Edge(i)
+		if typ.IsTextblock() && !typ.HasRequiredAttrs() {
+			return &typ
+		}
+	}
+
+	return nil
+}
+
+// ExitCode, when the selection is in a node with a truthy Code property in its
+// spec, creates a default block after the code block, and moves the cursor there.
+func ExitCode(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	head, anchor := s.Selection.Head(), s.Selection.Anchor()
+	if !head.Parent().Type.Spec.Code || !head.SameParent(anchor) {
+		return false
+	}
+
+	above, after := head.Node(-1), head.IndexAfter(-1)
+	typ := defaultBlockAt(above.ContentMatchAt(after))
+	if typ == nil || !above.CanReplaceWith(after, after, *typ, nil) {
+		return false
+	}
+
+	return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+		pos := head.After(head.Depth)
+
+		node, err := typ.CreateAndFill(nil, nil)
+		if err != nil {
+			return err
+		}
+
+		if err := tr.ReplaceWith(pos, pos, node); err != nil {
+			return err
+		}
+
+		rp, err := tr.Doc.Resolve(pos)
+		if err != nil {
+			return err
+		}
+
+		tr.SetSelection(state.SelectionNear(rp, 1))
+		return nil
+	})
+}
+
+// CreateParagraphNear, if a block node is selected, creates an empty paragraph
+// before (if it is its parent's first child) or after it.
+func CreateParagraphNear(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	sel := s.Selection
+	from, to := sel.From(), sel.To()
+	if _, ok := sel.(*state.AllSelection); ok || from.Parent().InlineContent() || to.Parent().InlineContent() {
+		return false
+	}
+
+	typ := defaultBlockAt(to.Parent().ContentMatchAt(to.IndexAfter(to.Depth)))
+	if typ == nil || !typ.IsTextblock() {
+		return false
+	}
+
+	return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+		side := to.Pos
+		if from.ParentOffset == 0 && to.Index(to.Depth) < to.Parent().ChildCount() {
+			side = from.Pos
+		}
+
+		node, err := typ.CreateAndFill(nil, nil)
+		if err != nil {
+			return err
+		}
+
+		if err := tr.Insert(side, node); err != nil {
+			return err
+		}
+
+		sel, err := state.CreateTextSelection(tr.Doc, side+1, side+1)
+		if err != nil {
+			return err
+		}
+
+		tr.SetSelection(sel)
+		return nil
+	})
+}
+
+// LiftEmptyBlock, if the cursor is in an empty textblock that can be lifted,
+// lifts the block.
+func LiftEmptyBlock(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	sel, ok := s.Selection.(*state.TextSelection)
+	if !ok {
+		return false
+	}
+
+	cursor := sel.Cursor()
+	if cursor == nil || cursor.Parent().Content.Size > 0 {
+		return false
+	}
+
+	if cursor.Depth > 1 && cursor.After(cursor.Depth) != cursor.End(-1) {
+		before := cursor.Before(cursor.Depth)
+		if transform.CanSplit(s.Doc, before, 1, nil) {
+			return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+				return tr.Split(before, 1, nil)
+			})
+		}
+	}
+
+	r := cursor.BlockRange(*cursor, nil)
+	if r == nil {
+		return false
+	}
+
+	target := transform.LiftTarget(*r)
+	if target < 0 {
+		return false
+	}
+
+	return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+		return tr.Lift(*r, target)
+	})
+}
+
+// SplitBlockAs creates a variant of SplitBlock that uses a custom function to
+// determine the type of the newly split off block. The function gets the node
+// being split, whether the cursor is at its end, and the start of the selection,
+// and returns nil to use the default type.
+func SplitBlockAs(splitNode func(node prosemirror.Node, atEnd bool, from prosemirror.ResolvedPos) *transform.Wrapper) Command {
+	return func(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+		from, to := s.Selection.From(), s.Selection.To()
+
+		if sel, ok := s.Selection.(*state.NodeSelection); ok && sel.Node.IsBlock() {
+			if from.ParentOffset == 0 || !transform.CanSplit(s.Doc, from.Pos, 1, nil) {
+				return false
+			}
+
+			return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+				return tr.Split(from.Pos, 1, nil)
+			})
+		}
+
+		if from.Depth == 0 {
+			return false
+		}
+
+		var types []*transform.Wrapper
+		var deflt *prosemirror.NodeType
+		splitDepth, atEnd, atStart := 0, false, false
+		for d := from.Depth; ; d-- {
+			node := from.Node(d)
+			if node.IsBlock() {
+				atEnd = from.End(d) == from.Pos+(from.Depth-d)
+				atStart = from.Start(d) == from.Pos-(from.Depth-d)
+				deflt = defaultBlockAt(from.Node(d - 1).ContentMatchAt(from.IndexAfter(d - 1)))
+
+				var splitType *transform.Wrapper
+				if splitNode != nil {
+					splitType = splitNode(to.Parent(), atEnd, from)
+				}
+
+				if splitType == nil && atEnd && deflt != nil {
+					splitType = &transform.Wrapper{Type: *deflt}
+				}
+
+				types = append([]*transform.Wrapper{splitType}, types...)
+				splitDepth = d
+				break
+			}
+
+			if d == 1 {
+				return false
+			}
+
+			types = append([]*transform.Wrapper{nil}, types...)
+		}
+
+		tr := s.Tr()
+		switch s.Selection.(type) {
+		case *state.TextSelection, *state.AllSelection:
+			if err := tr.DeleteSelection(); err != nil {
+				return false
+			}
+		}
+
+		splitPos := tr.Mapping.Map(from.Pos, 1)
+		can := transform.CanSplit(tr.Doc, splitPos, len(types), types)
+		if !can {
+			types[0] = nil
+			if deflt != nil {
+				types[0] = &transform.Wrapper{Type: *deflt}
+			}
+
+			can = transform.CanSplit(tr.Doc, splitPos, len(types), types)
+		}
+
+		if !can {
+			return false
+		}
+
+		if err := tr.Split(splitPos, len(types), types); err != nil {
+			return false
+		}
+
+		if !atEnd && atStart && deflt != nil && !from.Node(splitDepth).Type.Eq(*deflt) {
+			first := tr.Mapping.Map(from.Before(splitDepth), 1)
+			rFirst, err := tr.Doc.Resolve(first)
+			if err != nil {
+				return false
+			}
+
+			if from.Node(splitDepth-1).CanReplaceWith(rFirst.Index(rFirst.Depth), rFirst.Index(rFirst.Depth)+1, *deflt, nil) {
+				if err := tr.SetNodeMarkup(first, deflt, nil, nil); err != nil {
+					return false
+				}
+			}
+		}
+
+		if dispatch != nil {
+			dispatch(tr)
+		}
+
+		return true
+	}
+}
+
+// SplitBlock splits the parent block of the selection. If the selection is a
+// text selection, also deletes its content.
+var SplitBlock = SplitBlockAs(nil)
+
+// SplitBlockKeepMarks acts like SplitBlock, but without resetting the set of
+// active marks at the cursor.
+func SplitBlockKeepMarks(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	if dispatch == nil {
+		return SplitBlock(s, nil)
+	}
+
+	return SplitBlock(s, func(tr *state.Transaction) {
+		marks := s.StoredMarks
+		if marks == nil && s.Selection.To().ParentOffset > 0 {
+			marks = s.Selection.From().Marks()
+		}
+
+		if marks != nil {
+			tr.EnsureMarks(marks)
+		}
+
+		dispatch(tr)
+	})
+}
+
+// SelectParentNode moves the selection to the node wrapping the current
+// selection, if any. (Will not select the document node.)
+func SelectParentNode(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	from, to := s.Selection.From(), s.Selection.To()
+
+	same := from.SharedDepth(to.Pos)
+	if same == 0 {
+		return false
+	}
+
+	pos := from.Before(same)
+	return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+		sel, err := state.CreateNodeSelection(s.Doc, pos)
+		if err != nil {
+			return err
+		}
+
+		tr.SetSelection(sel)
+		return nil
+	})
+}
+
+// SelectAll selects the whole document.
+func SelectAll(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+	if dispatch != nil {
+		dispatch(s.Tr().SetSelection(state.NewAllSelection(s.Doc)))
+	}
+
+	return true
+}
+
+func joinMaybeClear(s *state.EditorState, pos prosemirror.ResolvedPos, dispatch func(tr *state.Transaction)) bool {
+	before, after, index := pos.NodeBefore(), pos.NodeAfter(), pos.Index(pos.Depth)
+	if before == nil || after == nil || !before.Type.CompatibleContent(after.Type) {
+		return false
+	}
+
+	if before.Content.Size == 0 && pos.Parent().CanReplace(index-1, index, prosemirror.Fragment{}) {
+		return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+			return tr.Delete(pos.Pos-before.NodeSize(), pos.Pos)
+		})
+	}
+
+	if !pos.Parent().CanReplace(index, index+1, prosemirror.Fragment{}) || !(after.IsTextblock() || transform.CanJoin(s.Doc, pos.Pos)) {
+		return false
+	}
+
+	return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+		return tr.Join(pos.Pos, 1)
+	})
+}
+
+func deleteBarrier(s *state.EditorState, cut prosemirror.ResolvedPos, dispatch func(tr *state.Transaction), dir int) bool {
+	before, after := cut.NodeBefore(), cut.NodeAfter()
+	isolated := before.Type.Spec.Isolating || after.Type.Spec.Isolating
+	if !isolated && joinMaybeClear(s, cut, dispatch) {
+		return true
+	}
+
+	index := cut.Index(cut.Depth)
+	canDelAfter := !isolated && cut.Parent().CanReplace(index, index+1, prosemirror.Fragment{})
+	if canDelAfter {
+		match := before.ContentMatchAt(before.ChildCount())
+		if conn := match.FindWrapping(after.Type); conn != nil {
+			first := after.Type
+			if len(conn) > 0 {
+				first = conn[0]
+			}
+
+			if m := match.MatchType(first); m != nil && m.ValidEnd {
+				return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+					end := cut.Pos + after.NodeSize()
+
+					wrap := prosemirror.Fragment{}
+					for i := len(conn) - 1; i >= 0; i-- {
+						node, err := conn[i].CreateUnchecked(nil, nil, wrap.Content...)
+						if err != nil {
+							return err
+						}
+
+						wrap = prosemirror.NewFragment(node)
+					}
+
+					wrap = prosemirror.NewFragment(before.Copy(wrap))
+					step := transform.NewReplaceAroundStep(cut.Pos-1, end, cut.Pos, end, prosemirror.NewSlice(wrap, 1, 0), len(conn), true)
+					if err := tr.Step(step); err != nil {
+						return err
+					}
+
+					joinAt, err := tr.Doc.Resolve(end + 2*len(conn))
+					if err != nil {
+						return err
+					}
+
+					if next := joinAt.NodeAfter(); next != nil && next.Type.Eq(before.Type) && transform.CanJoin(tr.Doc, joinAt.Pos) {
+						return tr.Join(joinAt.Pos, 1)
+					}
+
+					return nil
+				})
+			}
+		}
+	}
+
+	var selAfter state.Selection
+	if !after.Type.Spec.Isolating && !(dir > 0 && isolated) {
+		selAfter = state.FindSelectionFrom(cut, 1, false)
+	}
+
+	if selAfter != nil {
+		if r := selAfter.From().BlockRange(selAfter.To(), nil); r != nil {
+			if target := transform.LiftTarget(*r); target >= 0 && target >= cut.Depth {
+				return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+					return tr.Lift(*r, target)
+				})
+			}
+		}
+	}
+
+	if canDelAfter && textblockAt(*after, true, true) && textblockAt(*before, false, false) {
+		at := *before
+		wrap := []prosemirror.Node{}
+		for {
+			wrap = append(wrap, at)
+			if at.IsTextblock() {
+				break
+			}
+
+			at = *at.LastChild()
+		}
+
+		afterText, afterDepth := *after, 1
+		for ; !afterText.IsTextblock(); afterText = *afterText.FirstChild() {
+			afterDepth++
+		}
+
+		if at.CanReplace(at.ChildCount(), at.ChildCount(), afterText.Content) {
+			return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+				end := prosemirror.Fragment{}
+				for i := len(wrap) - 1; i >= 0; i-- {
+					end = prosemirror.NewFragment(wrap[i].Copy(end))
+				}
+
+				return tr.Step(transform.NewReplaceAroundStep(
+					cut.Pos-len(wrap), cut.Pos+after.NodeSize(),
+					cut.Pos+afterDepth, cut.Pos+after.NodeSize()-afterDepth,
+					prosemirror.NewSlice(end, len(wrap), 0), 0, true,
+				))
+			})
+		}
+	}
+
+	return false
+}
+
+func selectTextblockSide(side int) Command {
+	return func(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+		pos := s.Selection.To()
+		if side < 0 {
+			pos = s.Selection.From()
+		}
+
+		depth := pos.Depth
+		for pos.Node(depth).IsInline() {
+			if depth == 0 {
+				return false
+			}
+
+			depth--
+		}
+
+		if !pos.Node(depth).IsTextblock() {
+			return false
+		}
+
+		return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+			at := pos.End(depth)
+			if side < 0 {
+				at = pos.Start(depth)
+			}
+
+			sel, err := state.CreateTextSelection(s.Doc, at, at)
+			if err != nil {
+				return err
+			}
+
+			tr.SetSelection(sel)
+			return nil
+		})
+	}
+}
+
+// SelectTextblockStart moves the cursor to the start of current text block.
+var SelectTextblockStart = selectTextblockSide(-1)
+
+// SelectTextblockEnd moves the cursor to the end of current text block.
+var SelectTextblockEnd = selectTextblockSide(1)
+
+// WrapIn wraps the selection in a node of the given type with the given
+// attributes.
+func WrapIn(nodeType prosemirror.NodeType, attrs map[string]any) Command {
+	return func(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+		from, to := s.Selection.From(), s.Selection.To()
+
+		r := from.BlockRange(to, nil)
+		if r == nil {
+			return false
+		}
+
+		wrapping := transform.FindWrapping(*r, nodeType, attrs, nil)
+		if wrapping == nil {
+			return false
+		}
+
+		return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+			return tr.Wrap(*r, wrapping)
+		})
+	}
+}
+
+// SetBlockType returns a command that tries to set the selected textblocks to
+// the given node type with the given attributes.
+func SetBlockType(nodeType prosemirror.NodeType, attrs map[string]any) Command {
+	return func(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+		from, to := s.Selection.From().Pos, s.Selection.To().Pos
+
+		applicable := false
+		s.Doc.NodesBetween(from, to, func(node prosemirror.Node, pos int, _ *prosemirror.Node, _ int) bool {
+			if applicable {
+				return false
+			}
+
+			if !node.IsTextblock() || node.HasMarkup(nodeType, attrs, node.Marks) {
+				return true
+			}
+
+			if node.Type.Eq(nodeType) {
+				applicable = true
+				return true
+			}
+
+			rp, err := s.Doc.Resolve(pos)
+			if err != nil {
+				return false
+			}
+
+			index := rp.Index(rp.Depth)
+			applicable = rp.Parent().CanReplaceWith(index, index+1, nodeType, nil)
+			return true
+		})
+
+		if !applicable {
+			return false
+		}
+
+		return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+			return tr.SetBlockType(from, to, nodeType, attrs)
+		})
+	}
+}
+
+func markApplies(doc prosemirror.Node, from, to prosemirror.ResolvedPos, typ prosemirror.MarkType) bool {
+	can := from.Depth == 0 && doc.InlineContent() && doc.Type.AllowsMarkType(typ)
+
+	doc.NodesBetween(from.Pos, to.Pos, func(node prosemirror.Node, _ int, _ *prosemirror.Node, _ int) bool {
+		if can {
+			return false
+		}
+
+		can = node.InlineContent() && node.Type.AllowsMarkType(typ)
+		return true
+	})
+
+	return can
+}
+
+var (
+	leadingSpace  = regexp.MustCompile(`^\s*`)
+	trailingSpace = regexp.MustCompile(`\s*$`)
+)
+
+// ToggleMark creates a command function that toggles the mark with the given
+// type and attributes. If there is any content in the selection with the mark,
+// it is removed, otherwise the mark is added to the selection. For cursor
+// selections, this toggles the stored marks instead of changing the document.
+//
+// Whitespace at the edges of the selection is left out when the mark is added.
+func ToggleMark(markType prosemirror.MarkType, attrs map[string]any) Command {
+	return func(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+		sel := s.Selection
+
+		var cursor *prosemirror.ResolvedPos
+		if ts, ok := sel.(*state.TextSelection); ok {
+			cursor = ts.Cursor()
+		}
+
+		if (sel.Empty() && cursor == nil) || !markApplies(s.Doc, sel.From(), sel.To(), markType) {
+			return false
+		}
+
+		tr := s.Tr()
+		if cursor != nil {
+			marks := s.StoredMarks
+			if marks == nil {
+				marks = cursor.Marks()
+			}
+
+			if markType.IsInSet(marks) != nil {
+				tr.RemoveStoredMarkType(markType)
+			} else {
+				tr.AddStoredMark(markType.Create(attrs))
+			}
+
+			if dispatch != nil {
+				dispatch(tr)
+			}
+
+			return true
+		}
+
+		from, to := sel.From(), sel.To()
+		if s.Doc.RangeHasMark(from.Pos, to.Pos, markType) {
+			if err := tr.RemoveMarkType(from.Pos, to.Pos, markType); err != nil {
+				return false
+			}
+		} else {
+			start, end := from.Pos, to.Pos
+
+			spaceStart, spaceEnd := 0, 0
+			if n := from.NodeAfter(); n != nil && n.IsText() {
+				spaceStart = prosemirror.UTF16Len(leadingSpace.FindString(n.Text))
+			}
+
+			if n := to.NodeBefore(); n != nil && n.IsText() {
+				spaceEnd = prosemirror.UTF16Len(trailingSpace.FindString(n.Text))
+			}
+
+			if start+spaceStart < end {
+				start += spaceStart
+				end -= spaceEnd
+			}
+
+			if err := tr.AddMark(start, end, markType.Create(attrs)); err != nil {
+				return false
+			}
+		}
+
+		if dispatch != nil {
+			dispatch(tr)
+		}
+
+		return true
+	}
+}
+
+// ChainCommands combines a number of command functions into a single function
+// (which calls them one by one until one returns true).
+func ChainCommands(commands ...Command) Command {
+	return func(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+		for _, cmd := range commands {
+			if cmd(s, dispatch) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// dispatchWith builds a transaction with the given function and dispatches it
+// when dispatch is not nil. A failure while building the transaction makes the
+// command not apply, so the transaction is built even when only checking
+// whether the command applies.
+func dispatchWith(s *state.EditorState, dispatch func(tr *state.Transaction), build func(tr *state.Transaction) error) bool {
+	tr := s.Tr()
+	if err := build(tr); err != nil {
+		return false
+	}
+
+	if dispatch != nil {
+		dispatch(tr)
+	}
+
+	return true
+}
+
+// Backspace is the default command chain for the backspace key.
+var Backspace = ChainCommands(DeleteSelection, JoinBackward, SelectNodeBackward)
+
+// Delete is the default command chain for the delete key.
+var Delete = ChainCommands(DeleteSelection, JoinForward, SelectNodeForward)
+
+// Enter is the default command chain for the enter key.
+var Enter = ChainCommands(NewlineInCode, CreateParagraphNear, LiftEmptyBlock, SplitBlock)
+
+// BaseKeymap maps key names to the basic editing commands, like
+// prosemirror-commands' pcBaseKeymap.
+var BaseKeymap = map[string]Command{
+	"Enter":           Enter,
+	"Mod-Enter":       ExitCode,
+	"Backspace":       Backspace,
+	"Mod-Backspace":   Backspace,
+	"Shift-Backspace": Backspace,
+	"Delete":          Delete,
+	"Mod-Delete":      Delete,
+	"Mod-a":           SelectAll,
+}
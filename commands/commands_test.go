@@ -0,0 +1,269 @@
+package commands_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/commands"
+	"github.com/karitham/prosemirror/state"
+	"github.com/stretchr/testify/assert"
+
+	// for side effects
+	_ "github.com/karitham/prosemirror/schema"
+)
+
+func TestCommands(t *testing.T) {
+	const twoParas = `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}`
+
+	type tt struct {
+		name   string
+		doc    string
+		anchor int
+		head   int
+		cmd    func(s prosemirror.Schema) commands.Command
+		want   string
+		wantOK bool
+
+		// select the node at anchor instead of creating a text selection.
+		nodeSel bool
+	}
+
+	tests := []tt{
+		{
+			name:   "delete selection",
+			doc:    twoParas,
+			anchor: 2,
+			head:   7,
+			cmd:    func(prosemirror.Schema) commands.Command { return commands.DeleteSelection },
+			want:   `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"owo"}]}]}`,
+			wantOK: true,
+		},
+		{
+			name:   "delete empty selection",
+			doc:    twoParas,
+			anchor: 2,
+			head:   2,
+			cmd:    func(prosemirror.Schema) commands.Command { return commands.DeleteSelection },
+			wantOK: false,
+		},
+		{
+			name:   "join backward",
+			doc:    twoParas,
+			anchor: 6,
+			head:   6,
+			cmd:    func(prosemirror.Schema) commands.Command { return commands.JoinBackward },
+			want:   `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"onetwo"}]}]}`,
+			wantOK: true,
+		},
+		{
+			name:   "join backward not at start",
+			doc:    twoParas,
+			anchor: 7,
+			head:   7,
+			cmd:    func(prosemirror.Schema) commands.Command { return commands.JoinBackward },
+			wantOK: false,
+		},
+		{
+			name:   "join forward",
+			doc:    twoParas,
+			anchor: 4,
+			head:   4,
+			cmd:    func(prosemirror.Schema) commands.Command { return commands.JoinForward },
+			want:   `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"onetwo"}]}]}`,
+			wantOK: true,
+		},
+		{
+			name:   "join backward out of blockquote",
+			doc:    `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]},{"type":"blockquote","content":[{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}]}`,
+			anchor: 7,
+			head:   7,
+			cmd:    func(prosemirror.Schema) commands.Command { return commands.JoinBackward },
+			want:   `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}`,
+			wantOK: true,
+		},
+		{
+			name:   "split block",
+			doc:    twoParas,
+			anchor: 2,
+			head:   2,
+			cmd:    func(prosemirror.Schema) commands.Command { return commands.SplitBlock },
+			want:   `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"o"}]},{"type":"paragraph","content":[{"type":"text","text":"ne"}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}`,
+			wantOK: true,
+		},
+		{
+			name:   "split heading at end creates paragraph",
+			doc:    `{"type":"doc","content":[{"type":"heading","attrs":{"level":1},"content":[{"type":"text","text":"title"}]}]}`,
+			anchor: 6,
+			head:   6,
+			cmd:    func(prosemirror.Schema) commands.Command { return commands.SplitBlock },
+			want:   `{"type":"doc","content":[{"type":"heading","attrs":{"level":1},"content":[{"type":"text","text":"title"}]},{"type":"paragraph"}]}`,
+			wantOK: true,
+		},
+		{
+			name:   "lift",
+			doc:    `{"type":"doc","content":[{"type":"blockquote","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]}]}]}`,
+			anchor: 3,
+			head:   3,
+			cmd:    func(prosemirror.Schema) commands.Command { return commands.Lift },
+			want:   `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]}]}`,
+			wantOK: true,
+		},
+		{
+			name:   "wrap in blockquote",
+			doc:    twoParas,
+			anchor: 2,
+			head:   7,
+			cmd: func(s prosemirror.Schema) commands.Command {
+				return commands.WrapIn(s.Nodes["blockquote"], nil)
+			},
+			want:   `{"type":"doc","content":[{"type":"blockquote","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}]}`,
+			wantOK: true,
+		},
+		{
+			name:   "set block type",
+			doc:    twoParas,
+			anchor: 2,
+			head:   2,
+			cmd: func(s prosemirror.Schema) commands.Command {
+				return commands.SetBlockType(s.Nodes["heading"], map[string]any{"level": 2})
+			},
+			want:   `{"type":"doc","content":[{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"one"}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}`,
+			wantOK: true,
+		},
+		{
+			name:   "toggle mark on",
+			doc:    twoParas,
+			anchor: 1,
+			head:   3,
+			cmd: func(s prosemirror.Schema) commands.Command {
+				return commands.ToggleMark(s.Marks["strong"], nil)
+			},
+			want:   `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","marks":[{"type":"strong"}],"text":"on"},{"type":"text","text":"e"}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}`,
+			wantOK: true,
+		},
+		{
+			name:   "toggle mark off",
+			doc:    `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","marks":[{"type":"strong"}],"text":"one"}]}]}`,
+			anchor: 1,
+			head:   4,
+			cmd: func(s prosemirror.Schema) commands.Command {
+				return commands.ToggleMark(s.Marks["strong"], nil)
+			},
+			want:   `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]}]}`,
+			wantOK: true,
+		},
+		{
+			name:    "create paragraph near",
+			doc:     `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]},{"type":"horizontal_rule"}]}`,
+			anchor:  5,
+			nodeSel: true,
+			cmd:     func(prosemirror.Schema) commands.Command { return commands.CreateParagraphNear },
+			want:    `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]},{"type":"horizontal_rule"},{"type":"paragraph"}]}`,
+			wantOK:  true,
+		},
+		{
+			name:   "newline in code",
+			doc:    `{"type":"doc","content":[{"type":"code_block","content":[{"type":"text","text":"ab"}]}]}`,
+			anchor: 2,
+			head:   2,
+			cmd:    func(prosemirror.Schema) commands.Command { return commands.NewlineInCode },
+			want:   `{"type":"doc","content":[{"type":"code_block","content":[{"type":"text","text":"a\nb"}]}]}`,
+			wantOK: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newState(t, tc.doc, tc.anchor, tc.head)
+			if s == nil {
+				return
+			}
+
+			if tc.nodeSel {
+				sel, err := state.CreateNodeSelection(s.Doc, tc.anchor)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				s = state.Create(state.Config{Doc: s.Doc, Selection: sel})
+			}
+
+			var got *state.Transaction
+			ok := tc.cmd(s.Schema())(s, func(tr *state.Transaction) { got = tr })
+			assert.Equal(t, ok, tc.cmd(s.Schema())(s, nil), "dry run should agree")
+			if !assert.Equal(t, tc.wantOK, ok) || !ok {
+				return
+			}
+
+			if !assert.NotNil(t, got) {
+				return
+			}
+
+			assert.JSONEq(t, tc.want, toJSON(t, got.Doc))
+		})
+	}
+}
+
+func TestToggleMarkCursor(t *testing.T) {
+	s := newState(t, `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]}]}`, 2, 2)
+	if s == nil {
+		return
+	}
+
+	strong := s.Schema().Marks["strong"]
+
+	var tr *state.Transaction
+	if !assert.True(t, commands.ToggleMark(strong, nil)(s, func(t *state.Transaction) { tr = t })) {
+		return
+	}
+
+	s = s.Apply(tr)
+	assert.NotNil(t, strong.IsInSet(s.StoredMarks))
+
+	if !assert.True(t, commands.ToggleMark(strong, nil)(s, func(t *state.Transaction) { tr = t })) {
+		return
+	}
+
+	s = s.Apply(tr)
+	assert.Nil(t, strong.IsInSet(s.StoredMarks))
+}
+
+func TestSelectAll(t *testing.T) {
+	s := newState(t, `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]}]}`, 2, 2)
+	if s == nil {
+		return
+	}
+
+	var tr *state.Transaction
+	assert.True(t, commands.SelectAll(s, func(t *state.Transaction) { tr = t }))
+
+	sel := s.Apply(tr).Selection
+	assert.IsType(t, &state.AllSelection{}, sel)
+	assert.Equal(t, 0, sel.From().Pos)
+	assert.Equal(t, 5, sel.To().Pos)
+}
+
+func newState(t *testing.T, doc string, anchor, head int) *state.EditorState {
+	t.Helper()
+
+	var node prosemirror.Node
+	if !assert.NoError(t, json.Unmarshal([]byte(doc), &node)) {
+		return nil
+	}
+
+	sel, err := state.CreateTextSelection(node, anchor, head)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+
+	return state.Create(state.Config{Doc: node, Selection: sel})
+}
+
+func toJSON(t *testing.T, node prosemirror.Node) string {
+	t.Helper()
+
+	b, err := json.Marshal(node)
+	assert.NoError(t, err)
+	return string(b)
+}
@@ -0,0 +1,490 @@
+package commands
+
+import (
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/state"
+	"github.com/karitham/prosemirror/transform"
+)
+
+// WrapInList returns a command that wraps the selection in a list with the
+// given type and attributes. If dispatch is nil, it only returns a value to
+// indicate whether this is possible, but does not actually perform the change.
+func WrapInList(listType prosemirror.NodeType, attrs map[string]any) Command {
+	return func(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+		from, to := s.Selection.From(), s.Selection.To()
+
+		r := from.BlockRange(to, nil)
+		if r == nil {
+			return false
+		}
+
+		if dispatch == nil {
+			return WrapRangeInList(nil, *r, listType, attrs)
+		}
+
+		tr := s.Tr()
+		if !WrapRangeInList(tr.Transform, *r, listType, attrs) {
+			return false
+		}
+
+		dispatch(tr)
+		return true
+	}
+}
+
+// WrapRangeInList tries to wrap the given node range in a list of the given type,
+// reporting whether it is possible. When tr is not nil, the wrapping is added to it.
+func WrapRangeInList(tr *transform.Transform, r prosemirror.NodeRange, listType prosemirror.NodeType, attrs map[string]any) bool {
+	doJoin, outerRange := false, r
+	doc := r.From.Doc()
+
+	// this is at the top of an existing list item
+	if r.Depth >= 2 && r.From.Node(r.Depth-1).Type.CompatibleContent(listType) && r.StartIndex() == 0 {
+		// don't do anything if this is the top of the list
+		if r.From.Index(r.Depth-1) == 0 {
+			return false
+		}
+
+		insert, err := doc.Resolve(r.Start() - 2)
+		if err != nil {
+			return false
+		}
+
+		outerRange = prosemirror.NodeRange{From: insert, To: insert, Depth: r.Depth}
+		if r.EndIndex() < r.Parent().ChildCount() {
+			end, err := doc.Resolve(r.To.End(r.Depth))
+			if err != nil {
+				return false
+			}
+
+			r = prosemirror.NodeRange{From: r.From, To: end, Depth: r.Depth}
+		}
+
+		doJoin = true
+	}
+
+	wrap := transform.FindWrapping(outerRange, listType, attrs, &r)
+	if wrap == nil {
+		return false
+	}
+
+	if tr != nil {
+		return doWrapInList(tr, r, wrap, doJoin, listType) == nil
+	}
+
+	return true
+}
+
+func doWrapInList(tr *transform.Transform, r prosemirror.NodeRange, wrappers []transform.Wrapper, joinBefore bool, listType prosemirror.NodeType) error {
+	content := prosemirror.NewFragment()
+	for i := len(wrappers) - 1; i >= 0; i-- {
+		node, err := wrappers[i].Type.CreateUnchecked(wrappers[i].Attrs, nil, content.Content...)
+		if err != nil {
+			return err
+		}
+
+		content = prosemirror.NewFragment(node)
+	}
+
+	join := 0
+	if joinBefore {
+		join = 2
+	}
+
+	step := transform.NewReplaceAroundStep(r.Start()-join, r.End(), r.Start(), r.End(), prosemirror.NewSlice(content, 0, 0), len(wrappers), true)
+	if err := tr.Step(step); err != nil {
+		return err
+	}
+
+	found := 0
+	for i, w := range wrappers {
+		if w.Type.Eq(listType) {
+			found = i + 1
+		}
+	}
+	splitDepth := len(wrappers) - found
+
+	splitPos, parent := r.Start()+len(wrappers)-join, r.Parent()
+	for i := r.StartIndex(); i < r.EndIndex(); i++ {
+		if i > r.StartIndex() && transform.CanSplit(tr.Doc, splitPos, splitDepth, nil) {
+			if err := tr.Split(splitPos, splitDepth, nil); err != nil {
+				return err
+			}
+
+			splitPos += 2 * splitDepth
+		}
+
+		splitPos += parent.Child(i).NodeSize()
+	}
+
+	return nil
+}
+
+// SplitListItem builds a command that splits a non-empty textblock at the top
+// level of a list item by also splitting that list item. itemAttrs, when not
+// nil, are the attributes of the new list item.
+func SplitListItem(itemType prosemirror.NodeType, itemAttrs map[string]any) Command {
+	return func(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+		from, to := s.Selection.From(), s.Selection.To()
+		if sel, ok := s.Selection.(*state.NodeSelection); (ok && sel.Node.IsBlock()) || from.Depth < 2 || !from.SameParent(to) {
+			return false
+		}
+
+		grandParent := from.Node(from.Depth - 1)
+		if !grandParent.Type.Eq(itemType) {
+			return false
+		}
+
+		if from.Parent().Content.Size == 0 && from.Node(from.Depth-1).ChildCount() == from.IndexAfter(from.Depth-1) {
+			// In an empty block. If this is a nested list, the wrapping
+			// list item should be split. Otherwise, bail out and let next
+			// command handle lifting.
+			if from.Depth == 3 || !from.Node(from.Depth-3).Type.Eq(itemType) ||
+				from.Index(from.Depth-2) != from.Node(from.Depth-2).ChildCount()-1 {
+				return false
+			}
+
+			tr, err := splitNestedListItem(s, from, itemType)
+			if err != nil {
+				return false
+			}
+
+			if dispatch != nil {
+				dispatch(tr)
+			}
+
+			return true
+		}
+
+		var nextType *prosemirror.NodeType
+		if to.Pos == from.End(from.Depth) {
+			match := grandParent.ContentMatchAt(0)
+			nextType = match.DefaultType()
+		}
+
+		tr := s.Tr()
+		if err := tr.Delete(from.Pos, to.Pos); err != nil {
+			return false
+		}
+
+		var types []*transform.Wrapper
+		if nextType != nil {
+			var item *transform.Wrapper
+			if itemAttrs != nil {
+				item = &transform.Wrapper{Type: itemType, Attrs: itemAttrs}
+			}
+
+			types = []*transform.Wrapper{item, {Type: *nextType}}
+		}
+
+		if !transform.CanSplit(tr.Doc, from.Pos, 2, types) {
+			return false
+		}
+
+		if err := tr.Split(from.Pos, 2, types); err != nil {
+			return false
+		}
+
+		if dispatch != nil {
+			dispatch(tr)
+		}
+
+		return true
+	}
+}
+
+// splitNestedListItem splits the list item wrapping the nested list of an empty
+// last item, moving the cursor into a new item after it.
+func splitNestedListItem(s *state.EditorState, from prosemirror.ResolvedPos, itemType prosemirror.NodeType) (*state.Transaction, error) {
+	depthBefore := 3
+	switch {
+	case from.Index(from.Depth-1) > 0:
+		depthBefore = 1
+	case from.Index(from.Depth-2) > 0:
+		depthBefore = 2
+	}
+
+	// build a fragment containing empty versions of the structure
+	// from the outer list item to the parent node of the cursor
+	wrap := prosemirror.NewFragment()
+	for d := from.Depth - depthBefore; d >= from.Depth-3; d-- {
+		wrap = prosemirror.NewFragment(from.Node(d).Copy(wrap))
+	}
+
+	depthAfter := 3
+	switch {
+	case from.IndexAfter(from.Depth-1) < from.Node(from.Depth-2).ChildCount():
+		depthAfter = 1
+	case from.IndexAfter(from.Depth-2) < from.Node(from.Depth-3).ChildCount():
+		depthAfter = 2
+	}
+
+	// add a second list item with an empty default start node
+	item, err := itemType.CreateAndFill(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	wrap = wrap.Append(prosemirror.NewFragment(item))
+
+	start := from.Before(from.Depth - (depthBefore - 1))
+	tr := s.Tr()
+	if err := tr.Replace(start, from.After(from.Depth-depthAfter), prosemirror.NewSlice(wrap, 4-depthBefore, 0)); err != nil {
+		return nil, err
+	}
+
+	sel := -1
+	tr.Doc.NodesBetween(start, tr.Doc.Content.Size, func(node prosemirror.Node, pos int, _ *prosemirror.Node, _ int) bool {
+		if sel > -1 {
+			return false
+		}
+
+		if node.IsTextblock() && node.Content.Size == 0 {
+			sel = pos + 1
+		}
+
+		return true
+	})
+
+	if sel > -1 {
+		rp, err := tr.Doc.Resolve(sel)
+		if err != nil {
+			return nil, err
+		}
+
+		tr.SetSelection(state.SelectionNear(rp, 1))
+	}
+
+	return tr, nil
+}
+
+// SplitListItemKeepMarks acts like SplitListItem, but without resetting the set
+// of active marks at the cursor.
+func SplitListItemKeepMarks(itemType prosemirror.NodeType, itemAttrs map[string]any) Command {
+	split := SplitListItem(itemType, itemAttrs)
+	return func(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+		if dispatch == nil {
+			return split(s, nil)
+		}
+
+		return split(s, func(tr *state.Transaction) {
+			marks := s.StoredMarks
+			if marks == nil && s.Selection.To().ParentOffset > 0 {
+				marks = s.Selection.From().Marks()
+			}
+
+			if marks != nil {
+				tr.EnsureMarks(marks)
+			}
+
+			dispatch(tr)
+		})
+	}
+}
+
+// itemRange returns the range of list items around the selection.
+func itemRange(s *state.EditorState, itemType prosemirror.NodeType) *prosemirror.NodeRange {
+	from, to := s.Selection.From(), s.Selection.To()
+	return from.BlockRange(to, func(node prosemirror.Node) bool {
+		return node.ChildCount() > 0 && node.FirstChild().Type.Eq(itemType)
+	})
+}
+
+// LiftListItem creates a command to lift the list item around the selection
+// up into a wrapping list.
+func LiftListItem(itemType prosemirror.NodeType) Command {
+	return func(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+		r := itemRange(s, itemType)
+		if r == nil {
+			return false
+		}
+
+		var tr *state.Transaction
+		var err error
+		if r.From.Node(r.Depth - 1).Type.Eq(itemType) {
+			// inside a parent list
+			tr, err = liftToOuterList(s, itemType, *r)
+		} else {
+			// outer list node
+			tr, err = liftOutOfList(s, *r)
+		}
+
+		if err != nil || tr == nil {
+			return false
+		}
+
+		if dispatch != nil {
+			dispatch(tr)
+		}
+
+		return true
+	}
+}
+
+func liftToOuterList(s *state.EditorState, itemType prosemirror.NodeType, r prosemirror.NodeRange) (*state.Transaction, error) {
+	tr := s.Tr()
+	end, endOfList := r.End(), r.To.End(r.Depth)
+	if end < endOfList {
+		// there are siblings after the lifted items, which must become
+		// children of the last item
+		item, err := itemType.CreateUnchecked(nil, nil, r.Parent().Copy(prosemirror.NewFragment()))
+		if err != nil {
+			return nil, err
+		}
+
+		step := transform.NewReplaceAroundStep(end-1, endOfList, end, endOfList, prosemirror.NewSlice(prosemirror.NewFragment(item), 1, 0), 1, true)
+		if err := tr.Step(step); err != nil {
+			return nil, err
+		}
+
+		from, err := tr.Doc.Resolve(r.From.Pos)
+		if err != nil {
+			return nil, err
+		}
+
+		to, err := tr.Doc.Resolve(endOfList)
+		if err != nil {
+			return nil, err
+		}
+
+		r = prosemirror.NodeRange{From: from, To: to, Depth: r.Depth}
+	}
+
+	target := transform.LiftTarget(r)
+	if target < 0 {
+		return nil, nil
+	}
+
+	if err := tr.Lift(r, target); err != nil {
+		return nil, err
+	}
+
+	after, err := tr.Doc.Resolve(tr.Mapping.Map(end, -1) - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if transform.CanJoin(tr.Doc, after.Pos) && after.NodeBefore().Type.Eq(after.NodeAfter().Type) {
+		if err := tr.Join(after.Pos, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	return tr, nil
+}
+
+func liftOutOfList(s *state.EditorState, r prosemirror.NodeRange) (*state.Transaction, error) {
+	tr, list := s.Tr(), r.Parent()
+
+	// merge the list items into a single big item
+	pos := r.End()
+	for i := r.EndIndex() - 1; i > r.StartIndex(); i-- {
+		pos -= list.Child(i).NodeSize()
+		if err := tr.Delete(pos-1, pos+1); err != nil {
+			return nil, err
+		}
+	}
+
+	start, err := tr.Doc.Resolve(r.Start())
+	if err != nil {
+		return nil, err
+	}
+
+	item := start.NodeAfter()
+	if item == nil || tr.Mapping.Map(r.End(), 1) != r.Start()+item.NodeSize() {
+		return nil, nil
+	}
+
+	atStart, atEnd := r.StartIndex() == 0, r.EndIndex() == list.ChildCount()
+	parent, indexBefore := start.Node(start.Depth-1), start.Index(start.Depth-1)
+
+	replacement := item.Content
+	if !atEnd {
+		replacement = replacement.Append(prosemirror.NewFragment(list))
+	}
+
+	replaceFrom := indexBefore + 1
+	if atStart {
+		replaceFrom = indexBefore
+	}
+
+	if !parent.CanReplace(replaceFrom, indexBefore+1, replacement) {
+		return nil, nil
+	}
+
+	// Strip off the surrounding list. At the sides where we're not at
+	// the end of the list, the existing list is closed. At sides where
+	// this is the end, it is overwritten to its end.
+	startPos, endPos := start.Pos, start.Pos+item.NodeSize()
+	emptyList := prosemirror.NewFragment(list.Copy(prosemirror.NewFragment()))
+	from, to, content := startPos, endPos, prosemirror.NewFragment()
+	openStart, openEnd := 0, 0
+	if atStart {
+		from--
+	} else {
+		content, openStart = emptyList, 1
+	}
+	if atEnd {
+		to++
+	} else {
+		content, openEnd = content.Append(emptyList), 1
+	}
+
+	step := transform.NewReplaceAroundStep(from, to, startPos+1, endPos-1, prosemirror.NewSlice(content, openStart, openEnd), openStart, true)
+	if err := tr.Step(step); err != nil {
+		return nil, err
+	}
+
+	return tr, nil
+}
+
+// SinkListItem creates a command to sink the list item around the selection
+// down into an inner list.
+func SinkListItem(itemType prosemirror.NodeType) Command {
+	return func(s *state.EditorState, dispatch func(tr *state.Transaction)) bool {
+		r := itemRange(s, itemType)
+		if r == nil {
+			return false
+		}
+
+		startIndex := r.StartIndex()
+		if startIndex == 0 {
+			return false
+		}
+
+		parent := r.Parent()
+		nodeBefore := parent.Child(startIndex - 1)
+		if !nodeBefore.Type.Eq(itemType) {
+			return false
+		}
+
+		return dispatchWith(s, dispatch, func(tr *state.Transaction) error {
+			last := nodeBefore.LastChild()
+			nestedBefore := last != nil && last.Type.Eq(parent.Type)
+
+			var inner []prosemirror.Node
+			depth := 1
+			if nestedBefore {
+				item, err := itemType.CreateUnchecked(nil, nil)
+				if err != nil {
+					return err
+				}
+
+				inner, depth = []prosemirror.Node{item}, 3
+			}
+
+			list, err := parent.Type.CreateUnchecked(nil, nil, inner...)
+			if err != nil {
+				return err
+			}
+
+			item, err := itemType.CreateUnchecked(nil, nil, list)
+			if err != nil {
+				return err
+			}
+
+			before, after := r.Start(), r.End()
+			return tr.Step(transform.NewReplaceAroundStep(before-depth, after, before, after,
+				prosemirror.NewSlice(prosemirror.NewFragment(item), depth, 0), 1, true))
+		})
+	}
+}
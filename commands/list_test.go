@@ -0,0 +1,175 @@
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/karitham/prosemirror/builder"
+	"github.com/karitham/prosemirror/commands"
+	"github.com/karitham/prosemirror/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListCommands(t *testing.T) {
+	s := builder.Schema()
+	b := builder.New(s)
+	doc, p, ul, li := b.Node("doc"), b.Node("paragraph"), b.Node("bullet_list"), b.Node("list_item")
+
+	bulletList, listItem := s.Nodes["bullet_list"], s.Nodes["list_item"]
+
+	type tt struct {
+		name   string
+		doc    builder.Node
+		anchor int
+		head   int
+		cmd    commands.Command
+		want   builder.Node
+		wantOK bool
+	}
+
+	tests := []tt{
+		{
+			name:   "wrap in list",
+			doc:    doc(p("one"), p("two")),
+			anchor: 2,
+			head:   7,
+			cmd:    commands.WrapInList(bulletList, nil),
+			want:   doc(ul(li(p("one")), li(p("two")))),
+			wantOK: true,
+		},
+		{
+			name:   "wrap in nested list",
+			doc:    doc(ul(li(p("a")), li(p("b")))),
+			anchor: 8,
+			head:   8,
+			cmd:    commands.WrapInList(bulletList, nil),
+			want:   doc(ul(li(p("a"), ul(li(p("b")))))),
+			wantOK: true,
+		},
+		{
+			name:   "wrap at the top of a list",
+			doc:    doc(ul(li(p("a")))),
+			anchor: 3,
+			head:   3,
+			cmd:    commands.WrapInList(bulletList, nil),
+			wantOK: false,
+		},
+		{
+			name:   "split list item",
+			doc:    doc(ul(li(p("ab")))),
+			anchor: 4,
+			head:   4,
+			cmd:    commands.SplitListItem(listItem, nil),
+			want:   doc(ul(li(p("a")), li(p("b")))),
+			wantOK: true,
+		},
+		{
+			name:   "split at the end of a list item",
+			doc:    doc(ul(li(p("ab")))),
+			anchor: 5,
+			head:   5,
+			cmd:    commands.SplitListItem(listItem, nil),
+			want:   doc(ul(li(p("ab")), li(p()))),
+			wantOK: true,
+		},
+		{
+			name:   "split empty list item",
+			doc:    doc(ul(li(p("a")), li(p()))),
+			anchor: 8,
+			head:   8,
+			cmd:    commands.SplitListItem(listItem, nil),
+			wantOK: false,
+		},
+		{
+			name:   "split empty nested list item",
+			doc:    doc(ul(li(p("a"), ul(li(p()))))),
+			anchor: 8,
+			head:   8,
+			cmd:    commands.SplitListItem(listItem, nil),
+			want:   doc(ul(li(p("a")), li(p()))),
+			wantOK: true,
+		},
+		{
+			name:   "lift out of list",
+			doc:    doc(ul(li(p("a")), li(p("b")))),
+			anchor: 8,
+			head:   8,
+			cmd:    commands.LiftListItem(listItem),
+			want:   doc(ul(li(p("a"))), p("b")),
+			wantOK: true,
+		},
+		{
+			name:   "lift into outer list",
+			doc:    doc(ul(li(p("a"), ul(li(p("b")))))),
+			anchor: 8,
+			head:   8,
+			cmd:    commands.LiftListItem(listItem),
+			want:   doc(ul(li(p("a")), li(p("b")))),
+			wantOK: true,
+		},
+		{
+			name:   "lift outside of a list",
+			doc:    doc(p("a")),
+			anchor: 1,
+			head:   1,
+			cmd:    commands.LiftListItem(listItem),
+			wantOK: false,
+		},
+		{
+			name:   "sink list item",
+			doc:    doc(ul(li(p("a")), li(p("b")))),
+			anchor: 8,
+			head:   8,
+			cmd:    commands.SinkListItem(listItem),
+			want:   doc(ul(li(p("a"), ul(li(p("b")))))),
+			wantOK: true,
+		},
+		{
+			name:   "sink into existing nested list",
+			doc:    doc(ul(li(p("a"), ul(li(p("b")))), li(p("c")))),
+			anchor: 15,
+			head:   15,
+			cmd:    commands.SinkListItem(listItem),
+			want:   doc(ul(li(p("a"), ul(li(p("b")), li(p("c")))))),
+			wantOK: true,
+		},
+		{
+			name:   "sink first list item",
+			doc:    doc(ul(li(p("a")))),
+			anchor: 3,
+			head:   3,
+			cmd:    commands.SinkListItem(listItem),
+			wantOK: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sel, err := state.CreateTextSelection(tc.doc.Node, tc.anchor, tc.head)
+			if !assert.NoError(t, err) {
+				return
+			}
+			st := state.Create(state.Config{Doc: tc.doc.Node, Selection: sel})
+
+			var got *state.Transaction
+			ok := tc.cmd(st, func(tr *state.Transaction) { got = tr })
+			assert.Equal(t, ok, tc.cmd(st, nil), "dry run should agree")
+			if !assert.Equal(t, tc.wantOK, ok) || !ok {
+				return
+			}
+
+			if !assert.NotNil(t, got) {
+				return
+			}
+			assert.Equal(t, tc.want.String(), got.Doc.String())
+
+			// the steps replay on the original document
+			replayed := tc.doc.Node
+			for _, step := range got.Steps {
+				if replayed, err = step.Apply(replayed); !assert.NoError(t, err) {
+					return
+				}
+			}
+			assert.Equal(t, tc.want.String(), replayed.String())
+		})
+	}
+}
@@ -0,0 +1,346 @@
+// Package compat compares two versions of a schema, and reports the changes
+// which may leave documents of the old version invalid in the new one.
+//
+// Content expressions are compared on the languages of their compiled
+// automata: a content expression is narrowed when some sequence of children
+// it accepts isn't accepted by its new version, which is given as an example.
+// Reports marshal to JSON for CI checks, see cmd/pmcompat.
+package compat
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	p "github.com/karitham/prosemirror"
+)
+
+// Kind is the kind of a change between two schemas.
+type Kind string
+
+const (
+	NodeRemoved     Kind = "node_removed"
+	NodeAdded       Kind = "node_added"
+	MarkRemoved     Kind = "mark_removed"
+	MarkAdded       Kind = "mark_added"
+	TopNodeChanged  Kind = "top_node_changed"
+	ContentNarrowed Kind = "content_narrowed"
+	ContentWidened  Kind = "content_widened"
+	AttrRequired    Kind = "attr_required"
+	AttrAdded       Kind = "attr_added"
+	AttrRemoved     Kind = "attr_removed"
+	MarksNarrowed   Kind = "marks_narrowed"
+	ExcludesWidened Kind = "excludes_widened"
+)
+
+// Change is a change between two schemas, on a node or mark type.
+type Change struct {
+	Kind Kind           `json:"kind"`
+	Node p.NodeTypeName `json:"node,omitempty"`
+	Mark p.MarkTypeName `json:"mark,omitempty"`
+	Attr string         `json:"attr,omitempty"`
+
+	// Whether documents of the old schema may be invalid in the new one.
+	Breaking bool   `json:"breaking"`
+	Message  string `json:"message"`
+
+	// The children of a node accepted by the old content expression but not
+	// the new one, for narrowed content.
+	Example []p.NodeTypeName `json:"example,omitempty"`
+}
+
+// Report lists the changes between two schemas, in schema order.
+type Report struct {
+	// Whether some change is breaking.
+	Breaking bool     `json:"breaking"`
+	Changes  []Change `json:"changes"`
+}
+
+// BreakingChanges returns the breaking changes of the report.
+func (r Report) BreakingChanges() []Change {
+	var out []Change
+	for _, c := range r.Changes {
+		if c.Breaking {
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
+
+// Check compares the old and new versions of a schema, before and after.
+func Check(before, after p.Schema) Report {
+	c := &checker{}
+
+	if before.TopNodeType != nil && after.TopNodeType != nil && before.TopNodeType.Name != after.TopNodeType.Name {
+		c.add(Change{
+			Kind:     TopNodeChanged,
+			Node:     after.TopNodeType.Name,
+			Breaking: true,
+			Message:  fmt.Sprintf("top node changed from %s to %s", before.TopNodeType.Name, after.TopNodeType.Name),
+		})
+	}
+
+	for _, typ := range before.NodeTypes() {
+		newType, ok := after.Nodes[typ.Name]
+		if !ok {
+			c.add(Change{Kind: NodeRemoved, Node: typ.Name, Breaking: true, Message: fmt.Sprintf("node type %s was removed", typ.Name)})
+			continue
+		}
+
+		c.node(typ, newType)
+	}
+
+	for _, typ := range after.NodeTypes() {
+		if _, ok := before.Nodes[typ.Name]; !ok {
+			c.add(Change{Kind: NodeAdded, Node: typ.Name, Message: fmt.Sprintf("node type %s was added", typ.Name)})
+		}
+	}
+
+	for _, typ := range before.MarkTypes() {
+		newType, ok := after.Marks[typ.Name]
+		if !ok {
+			c.add(Change{Kind: MarkRemoved, Mark: typ.Name, Breaking: true, Message: fmt.Sprintf("mark type %s was removed", typ.Name)})
+			continue
+		}
+
+		c.mark(typ, newType)
+	}
+
+	for _, typ := range after.MarkTypes() {
+		if _, ok := before.Marks[typ.Name]; !ok {
+			c.add(Change{Kind: MarkAdded, Mark: typ.Name, Message: fmt.Sprintf("mark type %s was added", typ.Name)})
+		}
+	}
+
+	return c.report
+}
+
+type checker struct {
+	report Report
+}
+
+func (c *checker) add(change Change) {
+	c.report.Changes = append(c.report.Changes, change)
+	c.report.Breaking = c.report.Breaking || change.Breaking
+}
+
+func (c *checker) node(before, after p.NodeType) {
+	oldMatch, newMatch := before.ContentMatch, after.ContentMatch
+	if example, ok := included(&oldMatch, &newMatch); !ok {
+		c.add(Change{
+			Kind:     ContentNarrowed,
+			Node:     before.Name,
+			Breaking: true,
+			Message:  contentMessage(before, after, "narrowed"),
+			Example:  example,
+		})
+	} else if _, ok := included(&newMatch, &oldMatch); !ok {
+		c.add(Change{
+			Kind:    ContentWidened,
+			Node:    before.Name,
+			Message: contentMessage(before, after, "widened"),
+		})
+	}
+
+	c.attrs(before.Spec.Attrs, after.Spec.Attrs, Change{Node: before.Name}, "node "+string(before.Name))
+
+	if removed := removedMarks(before, after); len(removed) > 0 {
+		c.add(Change{
+			Kind:     MarksNarrowed,
+			Node:     before.Name,
+			Breaking: true,
+			Message:  fmt.Sprintf("marks %s are no longer allowed in %s", strings.Join(removed, ", "), before.Name),
+		})
+	}
+}
+
+// contentMessage describes a change of content, which may come from a change of
+// the groups of its expression only.
+func contentMessage(before, after p.NodeType, change string) string {
+	if before.Spec.Content == after.Spec.Content {
+		return fmt.Sprintf("content %q of %s %s", before.Spec.Content, before.Name, change)
+	}
+
+	return fmt.Sprintf("content of %s %s from %q to %q", before.Name, change, before.Spec.Content, after.Spec.Content)
+}
+
+func (c *checker) mark(before, after p.MarkType) {
+	c.attrs(before.Spec.Attrs, after.Spec.Attrs, Change{Mark: before.Name}, "mark "+string(before.Name))
+
+	var excluded []string
+	for _, other := range after.Excluded {
+		if other.Name == before.Name {
+			continue
+		}
+
+		if oldOther, ok := before.Schema.Marks[other.Name]; ok && !before.Excludes(oldOther) {
+			excluded = append(excluded, string(other.Name))
+		}
+	}
+
+	if len(excluded) > 0 {
+		c.add(Change{
+			Kind:     ExcludesWidened,
+			Mark:     before.Name,
+			Breaking: true,
+			Message:  fmt.Sprintf("mark %s now excludes %s", before.Name, strings.Join(excluded, ", ")),
+		})
+	}
+}
+
+// attrs compares the attributes of a type, described by of, adding changes
+// on the type of base.
+func (c *checker) attrs(before, after map[string]p.Attribute, base Change, of string) {
+	for _, name := range sortedKeys(after) {
+		attr := after[name]
+		oldAttr, existed := before[name]
+		required := attr.Default == nil && !attr.Optional
+
+		change := base
+		change.Attr = name
+		switch {
+		case !existed && required:
+			change.Kind, change.Breaking = AttrRequired, true
+			change.Message = fmt.Sprintf("required attribute %s was added to %s", name, of)
+		case !existed:
+			change.Kind = AttrAdded
+			change.Message = fmt.Sprintf("attribute %s was added to %s", name, of)
+		case required && oldAttr.Default == nil && oldAttr.Optional:
+			// stored documents may hold null values, which are no longer allowed
+			change.Kind, change.Breaking = AttrRequired, true
+			change.Message = fmt.Sprintf("attribute %s of %s is no longer optional", name, of)
+		default:
+			continue
+		}
+
+		c.add(change)
+	}
+
+	for _, name := range sortedKeys(before) {
+		if _, ok := after[name]; !ok {
+			change := base
+			change.Kind, change.Attr = AttrRemoved, name
+			change.Message = fmt.Sprintf("attribute %s was removed from %s", name, of)
+			c.add(change)
+		}
+	}
+}
+
+// removedMarks returns the names of the marks allowed before, but not after.
+func removedMarks(before, after p.NodeType) []string {
+	var removed []string
+	for _, mark := range before.Schema.MarkTypes() {
+		if !before.AllowsMarkType(mark) {
+			continue
+		}
+
+		newMark, ok := after.Schema.Marks[mark.Name]
+		if !ok {
+			// reported as a removed mark type
+			continue
+		}
+
+		if !after.AllowsMarkType(newMark) {
+			removed = append(removed, string(mark.Name))
+		}
+	}
+
+	return removed
+}
+
+// included reports whether the language of the automaton a is included in
+// the one of b, comparing node types by name. When it isn't, it returns the
+// shortest sequence accepted by a but not b.
+func included(a, b *p.ContentMatch) ([]p.NodeTypeName, bool) {
+	type pair struct{ a, b *p.ContentMatch }
+	type step struct {
+		prev *step
+		name p.NodeTypeName
+	}
+
+	path := func(s *step) []p.NodeTypeName {
+		var names []p.NodeTypeName
+		for ; s != nil; s = s.prev {
+			names = append(names, s.name)
+		}
+
+		slices.Reverse(names)
+		return names
+	}
+
+	start := pair{a, b}
+	seen := map[pair]bool{start: true}
+	queue := []pair{start}
+	steps := map[pair]*step{start: nil}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		if cur.b == nil || (cur.a.ValidEnd && !cur.b.ValidEnd) {
+			// a can always complete its sequence, since compiled automata have no dead ends
+			return append(path(steps[cur]), completion(cur.a)...), false
+		}
+
+		for i := 0; i < cur.a.EdgeCount(); i++ {
+			typ, next := cur.a.Edge(i)
+			var nextB *p.ContentMatch
+			for j := 0; j < cur.b.EdgeCount(); j++ {
+				if t, n := cur.b.Edge(j); t.Name == typ.Name {
+					nextB = n
+					break
+				}
+			}
+
+			to := pair{next, nextB}
+			if seen[to] {
+				continue
+			}
+
+			seen[to] = true
+			steps[to] = &step{prev: steps[cur], name: typ.Name}
+			queue = append(queue, to)
+		}
+	}
+
+	return nil, true
+}
+
+// completion returns the shortest sequence of types leading from m to a valid end.
+func completion(m *p.ContentMatch) []p.NodeTypeName {
+	type entry struct {
+		m     *p.ContentMatch
+		names []p.NodeTypeName
+	}
+
+	seen := map[*p.ContentMatch]bool{m: true}
+	queue := []entry{{m, nil}}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur.m.ValidEnd {
+			return cur.names
+		}
+
+		for i := 0; i < cur.m.EdgeCount(); i++ {
+			typ, next := cur.m.Edge(i)
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, entry{next, append(slices.Clone(cur.names), typ.Name)})
+			}
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys(m map[string]p.Attribute) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+	return keys
+}
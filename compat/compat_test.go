@@ -0,0 +1,161 @@
+package compat_test
+
+import (
+	"testing"
+
+	"github.com/go-json-experiment/json"
+	"github.com/stretchr/testify/assert"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/compat"
+)
+
+func schema(t *testing.T, spec string) p.Schema {
+	t.Helper()
+
+	var s p.SchemaSpec
+	if err := json.Unmarshal([]byte(spec), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	s.DontRegister = true
+	return p.Must(p.NewSchema(s))
+}
+
+const base = `{
+	"nodes": {
+		"doc": {"content": "block+"},
+		"paragraph": {"content": "inline*", "group": "block"},
+		"heading": {"content": "inline*", "group": "block", "attrs": {"level": {"default": 1}, "id": {"default": null}}},
+		"text": {"group": "inline"}
+	},
+	"marks": {"em": {}, "strong": {}, "link": {"attrs": {"href": {}}}}
+}`
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		after    string
+		want     []compat.Change
+		breaking bool
+	}{
+		{
+			name:  "unchanged",
+			after: base,
+		},
+		{
+			name: "removed and added types",
+			after: `{
+				"nodes": {
+					"doc": {"content": "block+"},
+					"paragraph": {"content": "inline*", "group": "block"},
+					"heading": {"content": "inline*", "group": "block", "attrs": {"level": {"default": 1}, "id": {"default": null}}},
+					"blockquote": {"content": "block+", "group": "block"},
+					"text": {"group": "inline"}
+				},
+				"marks": {"em": {}, "link": {"attrs": {"href": {}}}, "code": {}}
+			}`,
+			want: []compat.Change{
+				// the block group has a new member
+				{Kind: compat.ContentWidened, Node: "doc", Message: `content "block+" of doc widened`},
+				{Kind: compat.NodeAdded, Node: "blockquote", Message: "node type blockquote was added"},
+				{Kind: compat.MarkRemoved, Mark: "strong", Breaking: true, Message: "mark type strong was removed"},
+				{Kind: compat.MarkAdded, Mark: "code", Message: "mark type code was added"},
+			},
+			breaking: true,
+		},
+		{
+			name: "narrowed content",
+			after: `{
+				"nodes": {
+					"doc": {"content": "heading block*"},
+					"paragraph": {"content": "inline*", "group": "block"},
+					"heading": {"content": "text{0,10}", "group": "block", "attrs": {"level": {"default": 1}, "id": {"default": null}}},
+					"text": {"group": "inline"}
+				},
+				"marks": {"em": {}, "strong": {}, "link": {"attrs": {"href": {}}}}
+			}`,
+			want: []compat.Change{
+				{Kind: compat.ContentNarrowed, Node: "doc", Breaking: true, Message: `content of doc narrowed from "block+" to "heading block*"`, Example: []p.NodeTypeName{"paragraph"}},
+				{Kind: compat.ContentNarrowed, Node: "heading", Breaking: true, Message: `content of heading narrowed from "inline*" to "text{0,10}"`, Example: []p.NodeTypeName{"text", "text", "text", "text", "text", "text", "text", "text", "text", "text", "text"}},
+			},
+			breaking: true,
+		},
+		{
+			name: "widened content",
+			after: `{
+				"nodes": {
+					"doc": {"content": "block*"},
+					"paragraph": {"content": "inline*", "group": "block"},
+					"heading": {"content": "inline*", "group": "block", "attrs": {"level": {"default": 1}, "id": {"default": null}}},
+					"text": {"group": "inline"}
+				},
+				"marks": {"em": {}, "strong": {}, "link": {"attrs": {"href": {}}}}
+			}`,
+			want: []compat.Change{
+				{Kind: compat.ContentWidened, Node: "doc", Message: `content of doc widened from "block+" to "block*"`},
+			},
+		},
+		{
+			name: "attributes",
+			after: `{
+				"nodes": {
+					"doc": {"content": "block+"},
+					"paragraph": {"content": "inline*", "group": "block", "attrs": {"align": {"default": "left"}}},
+					"heading": {"content": "inline*", "group": "block", "attrs": {"id": {}, "anchor": {}}},
+					"text": {"group": "inline"}
+				},
+				"marks": {"em": {}, "strong": {}, "link": {"attrs": {"href": {}, "title": {"default": null}}}}
+			}`,
+			want: []compat.Change{
+				{Kind: compat.AttrAdded, Node: "paragraph", Attr: "align", Message: "attribute align was added to node paragraph"},
+				{Kind: compat.AttrRequired, Node: "heading", Attr: "anchor", Breaking: true, Message: "required attribute anchor was added to node heading"},
+				{Kind: compat.AttrRequired, Node: "heading", Attr: "id", Breaking: true, Message: "attribute id of node heading is no longer optional"},
+				{Kind: compat.AttrRemoved, Node: "heading", Attr: "level", Message: "attribute level was removed from node heading"},
+				{Kind: compat.AttrAdded, Mark: "link", Attr: "title", Message: "attribute title was added to mark link"},
+			},
+			breaking: true,
+		},
+		{
+			name: "marks",
+			after: `{
+				"nodes": {
+					"doc": {"content": "block+"},
+					"paragraph": {"content": "inline*", "group": "block", "marks": "em link"},
+					"heading": {"content": "inline*", "group": "block", "attrs": {"level": {"default": 1}, "id": {"default": null}}},
+					"text": {"group": "inline"}
+				},
+				"marks": {"em": {"excludes": "em strong"}, "strong": {}, "link": {"attrs": {"href": {}}}}
+			}`,
+			want: []compat.Change{
+				{Kind: compat.MarksNarrowed, Node: "paragraph", Breaking: true, Message: "marks strong are no longer allowed in paragraph"},
+				{Kind: compat.ExcludesWidened, Mark: "em", Breaking: true, Message: "mark em now excludes strong"},
+			},
+			breaking: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := compat.Check(schema(t, base), schema(t, tc.after))
+			assert.Equal(t, tc.want, got.Changes)
+			assert.Equal(t, tc.breaking, got.Breaking)
+		})
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	after := `{"nodes": {"doc": {"content": "paragraph"}, "paragraph": {"content": "text*"}, "text": {}}}`
+	report := compat.Check(schema(t, base), schema(t, after))
+
+	b, err := json.Marshal(report.BreakingChanges()[0])
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{
+			"kind": "content_narrowed",
+			"node": "doc",
+			"breaking": true,
+			"message": "content of doc narrowed from \"block+\" to \"paragraph\"",
+			"example": ["heading"]
+		}`, string(b))
+	}
+}
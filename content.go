@@ -2,11 +2,10 @@ package prosemirror
 
 import (
 	"fmt"
-	"regexp"
 	"slices"
-	"sort"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 type contentMatchNode struct {
@@ -14,6 +13,10 @@ type contentMatchNode struct {
 	Next *ContentMatch
 }
 
+// ContentMatch represents a match state of a node type's content
+// expression, and can be used to find out whether further content
+// matches here, and whether a given position is a valid end of the
+// node.
 type ContentMatch struct {
 	Next     []contentMatchNode
 	ValidEnd bool
@@ -35,137 +38,9 @@ func (c ContentMatch) String() string {
 	return fmt.Sprint(c)
 }
 
+// InlineContent reports whether this match state represents inline content.
 func (c *ContentMatch) InlineContent() bool {
-	return len(c.Next) == 0 || !c.Next[0].Type.isInline()
-}
-
-// Javascript writes a regex parser
-// https://github.com/ProseMirror/prosemirror-model/blob/a37b6b3adeb548dc9822211b680ce9d31be65842/src/content.ts#L169
-//
-// We have one at home.
-// https://regex101.com/r/5RPdEf/1
-var contentMatchRegex = regexp.MustCompile(`\s*([\w\-]+)(\?|\*|\+|\{\d?,?\d\}|\|)?\s*`)
-
-// TODO: handle `seq` properly
-type Expr struct {
-	Type string
-	// for `choice` and `seq`
-	Exprs []Expr
-
-	Value NodeType
-
-	// for `choice`, `seq`, `+`, `*`, `range` and `?`
-	Expr *Expr
-
-	// for `+`, `range` as well as `*` and `?`
-	Min int
-	Max int
-}
-
-// currently doesn't allow subchoices/seqs
-func parseNodespecContent(s string, types map[NodeTypeName]NodeType) (Expr, error) {
-	if s == "" {
-		return Expr{}, nil
-	}
-
-	if match := contentMatchRegex.FindString(s); len(match) != len(s) {
-		return Expr{}, fmt.Errorf("invalid content expression: %s", s)
-	}
-
-	matches := contentMatchRegex.FindStringSubmatch(s)
-	if len(matches) != 3 {
-		return Expr{}, fmt.Errorf("invalid content expression: %s", s)
-	}
-
-	nameExpr := parseAtom(matches[1], types)
-	name, minQ, maxQ, err := parseQuantifier(matches[2])
-	if err != nil {
-		return Expr{}, fmt.Errorf("invalid content expression: %s", s)
-	}
-
-	return Expr{
-		Type: name,
-		Expr: &nameExpr,
-		Min:  minQ,
-		Max:  maxQ,
-	}, nil
-}
-
-func parseAtom(name string, types map[NodeTypeName]NodeType) Expr {
-	exprs := []Expr{}
-	for _, v := range resolveName(name, types) {
-		exprs = append(exprs, Expr{
-			Type:  nameExpr,
-			Value: v,
-		})
-	}
-
-	if len(exprs) > 1 {
-		return Expr{
-			Exprs: exprs,
-			Type:  choiceExpr,
-		}
-	}
-
-	return exprs[0]
-}
-
-func parseQuantifier(m string) (string, int, int, error) {
-	if m == "" {
-		return "", 1, 1, nil
-	}
-
-	if m == "?" {
-		return optExpr, 0, 1, nil
-	}
-
-	if m == "*" {
-		return starExpr, 0, -1, nil
-	}
-
-	if m == "+" {
-		return plusExpr, 1, -1, nil
-	}
-
-	// we know len(m) > 0 since it's != ""
-	if m[0] == '{' {
-		if m[len(m)-1] == '}' {
-			m = m[1 : len(m)-1]
-		} else {
-			return rangeExpr, 0, 0, fmt.Errorf("invalid quantifier: %s", m)
-		}
-	} else {
-		return "", 0, 0, fmt.Errorf("invalid quantifier: %s", m)
-	}
-
-	minQs, maxQs, ok := strings.Cut(m, ",")
-
-	minQ, err := strconv.Atoi(minQs)
-	if err != nil {
-		return "", 0, 0, fmt.Errorf("invalid quantifier: %s", m)
-	}
-
-	if !ok {
-		return rangeExpr, minQ, minQ, nil
-	}
-
-	if maxQs == "" {
-		return rangeExpr, minQ, -1, nil
-	}
-
-	maxQ, err := strconv.Atoi(maxQs)
-	if err != nil {
-		return "", 0, 0, fmt.Errorf("invalid quantifier: %s", m)
-	}
-
-	if maxQ == 0 {
-		return "", 0, 0, fmt.Errorf("invalid quantifier: %s", m)
-	}
-
-	if minQ > maxQ {
-		return "", 0, 0, fmt.Errorf("invalid quantifier: %s", m)
-	}
-	return rangeExpr, minQ, maxQ, nil
+	return len(c.Next) != 0 && c.Next[0].Type.IsInline()
 }
 
 func EmptyContentMatch() *ContentMatch {
@@ -175,8 +50,16 @@ func EmptyContentMatch() *ContentMatch {
 	}
 }
 
+// Empty reports whether this match state accepts no content at all,
+// which is the case for leaf node types.
 func (c ContentMatch) Empty() bool {
-	return c.Next == nil && !c.ValidEnd
+	return len(c.Next) == 0
+}
+
+// MatchType matches a node type, returning a match after that node
+// if successful.
+func (c *ContentMatch) MatchType(t NodeType) *ContentMatch {
+	return c.matchType(t)
 }
 
 func (c *ContentMatch) matchType(t NodeType) *ContentMatch {
@@ -190,6 +73,12 @@ func (c *ContentMatch) matchType(t NodeType) *ContentMatch {
 	return nil
 }
 
+// MatchFragment tries to match a fragment. Returns the resulting match when successful.
+// `start` and `end` may be -1 to match the whole fragment.
+func (c *ContentMatch) MatchFragment(frag Fragment, start, end int) *ContentMatch {
+	return c.matchFragment(frag, start, end)
+}
+
 func (c *ContentMatch) matchFragment(frag Fragment, start, end int) *ContentMatch {
 	if start == -1 {
 		start = 0
@@ -206,11 +95,13 @@ func (c *ContentMatch) matchFragment(frag Fragment, start, end int) *ContentMatc
 	return cur
 }
 
-func (c *ContentMatch) defaultType() *NodeType {
+// DefaultType gets the first matching node type at this match position
+// that can be generated.
+func (c *ContentMatch) DefaultType() *NodeType {
 	for _, m := range c.Next {
-		typ := m.Type
-		if typ.isText() && !typ.hasRequiredAttrs() {
-			return nil
+		typ := m.Type.schemaType()
+		if !typ.IsText() && !typ.hasRequiredAttrs() {
+			return &typ
 		}
 	}
 
@@ -230,120 +121,181 @@ func (c *ContentMatch) compatible(other ContentMatch) bool {
 	return false
 }
 
-type Edge struct {
-	Term *NodeType
-	To   *int
-}
-
-func dfa(nfa [][]Edge) *ContentMatch {
-	type edgeTuple struct {
-		Term NodeType
-		Set  []int
-	}
-
-	labeled := make(map[string]*ContentMatch)
+// FillBefore tries to match the given fragment, and if that fails, see if it can
+// be made to match by inserting nodes in front of it. When
+// successful, return a fragment of inserted nodes (which may be
+// empty if nothing had to be inserted). When `toEnd` is true, only
+// return a fragment if the resulting match goes to the end of the
+// content expression.
+func (c *ContentMatch) FillBefore(after Fragment, toEnd bool, startIndex int) *Fragment {
+	seen := []*ContentMatch{c}
+
+	var search func(match *ContentMatch, types []NodeType) *Fragment
+	search = func(match *ContentMatch, types []NodeType) *Fragment {
+		finished := match.matchFragment(after, startIndex, -1)
+		if finished != nil && (!toEnd || finished.ValidEnd) {
+			nodes := make([]Node, 0, len(types))
+			for _, t := range types {
+				n, err := t.CreateAndFill(nil, nil)
+				if err != nil {
+					return nil
+				}
+				nodes = append(nodes, n)
+			}
 
-	var explore func(states []int) *ContentMatch
-	explore = func(states []int) *ContentMatch {
-		out := make([]edgeTuple, 0)
+			f := NewFragment(nodes...)
+			return &f
+		}
 
-		for _, node := range states {
-			for _, edge := range nfa[node] {
-				term := edge.Term
-				if term == nil {
-					continue
+		for _, m := range match.Next {
+			typ := m.Type.schemaType()
+			if !typ.IsText() && !typ.hasRequiredAttrs() && !slices.Contains(seen, m.Next) {
+				seen = append(seen, m.Next)
+				if found := search(m.Next, append(slices.Clone(types), typ)); found != nil {
+					return found
 				}
+			}
+		}
 
-				var set []int
+		return nil
+	}
 
-				for i := range out {
-					if edge.Term.Name == out[i].Term.Name {
-						set = out[i].Set
-						break
-					}
-				}
+	return search(c, nil)
+}
 
-				for _, node := range nullFrom(nfa, edge.To) {
-					if set == nil {
-						out = append(out, edgeTuple{*edge.Term, []int{}})
-					}
-					if !slices.Contains(set, node) {
-						set = append(set, node)
-					}
-				}
+// FindWrapping finds a set of wrapping node types that would allow a node
+// of the given type to appear at this position. The result may be
+// empty (when it fits directly) and will be nil when no such wrapping exists.
+func (c *ContentMatch) FindWrapping(target NodeType) []NodeType {
+	type active struct {
+		match *ContentMatch
+		typ   *NodeType
+		via   *active
+	}
+
+	seen := map[NodeTypeName]bool{}
+	queue := []*active{{match: c}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if current.match.matchType(target) != nil {
+			result := []NodeType{}
+			for obj := current; obj.typ != nil; obj = obj.via {
+				result = append(result, *obj.typ)
 			}
+
+			slices.Reverse(result)
+			return result
 		}
 
-		labeledKey := join(states, ",")
-		state, ok := labeled[labeledKey]
-		if !ok {
-			state = &ContentMatch{
-				// TODO: we can be invalid more often than not, but I'm not sure I understand the original code's intent here.
-				ValidEnd: true,
+		for _, m := range current.match.Next {
+			typ := m.Type.schemaType()
+			if !typ.IsLeaf() && !typ.hasRequiredAttrs() && !seen[typ.Name] && (current.typ == nil || m.Next.ValidEnd) {
+				match := typ.ContentMatch
+				queue = append(queue, &active{match: &match, typ: &typ, via: current})
+				seen[typ.Name] = true
 			}
-			labeled[labeledKey] = state
 		}
+	}
 
-		for _, item := range out {
-			sort.Ints(item.Set)
-
-			next, ok := labeled[labeledKey]
-			if !ok {
-				next = explore(states)
-			}
+	return nil
+}
 
-			state.Next = append(state.Next, contentMatchNode{Type: item.Term, Next: next})
-		}
+// EdgeCount returns the number of outgoing edges this node has in the finite
+// automaton that describes the content expression.
+func (c *ContentMatch) EdgeCount() int {
+	return len(c.Next)
+}
 
-		return state
+// Edge gets the _n_th outgoing edge from this node in the finite
+// automaton that describes the content expression.
+func (c *ContentMatch) Edge(n int) (NodeType, *ContentMatch) {
+	if n >= len(c.Next) {
+		panic(fmt.Sprintf("there's no %dth edge in this content match", n))
 	}
 
-	return explore(nullFrom(nfa, nil))
+	return c.Next[n].Type.schemaType(), c.Next[n].Next
 }
 
-func nullFrom(nfa [][]Edge, n *int) []int {
-	var result []int
+// Javascript splits the expression with a lookahead regex
+// https://github.com/ProseMirror/prosemirror-model/blob/a37b6b3adeb548dc9822211b680ce9d31be65842/src/content.ts#L169
+//
+// We have a tokenizer at home.
+type tokenStream struct {
+	str    string
+	tokens []string
+	pos    int
+	types  map[NodeTypeName]NodeType
+}
 
-	var scan func(node int)
-	scan = func(node int) {
-		edges := nfa[node]
-		if len(edges) == 1 && edges[0].Term == nil {
-			scan(*edges[0].To)
-			return
-		}
-		result = append(result, node)
-		for i := range edges {
-			edge := edges[i]
-			if edge.Term == nil && edge.To != nil && !slices.Contains(result, *edge.To) {
-				scan(*edge.To)
+func newTokenStream(str string, types map[NodeTypeName]NodeType) *tokenStream {
+	ts := &tokenStream{str: str, types: types}
+
+	isWord := func(r rune) bool {
+		return r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	runes := []rune(str)
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case isWord(r):
+			j := i
+			for j < len(runes) && isWord(runes[j]) {
+				j++
 			}
+			ts.tokens = append(ts.tokens, string(runes[i:j]))
+			i = j
+		default:
+			ts.tokens = append(ts.tokens, string(r))
+			i++
 		}
 	}
 
-	node := 0
-	if n != nil {
-		node = *n
+	return ts
+}
+
+func (ts *tokenStream) next() string {
+	if ts.pos >= len(ts.tokens) {
+		return ""
 	}
 
-	scan(node)
-	sort.Ints(result)
-	return result
+	return ts.tokens[ts.pos]
 }
 
-func join(ints []int, sep string) string {
-	s := &strings.Builder{}
-	for i := range ints {
-		if i > 0 {
-			s.WriteString(sep)
-		}
-		s.WriteString(strconv.Itoa(ints[i]))
+func (ts *tokenStream) eat(tok string) bool {
+	if ts.next() == tok {
+		ts.pos++
+		return true
 	}
 
-	return s.String()
+	return false
+}
+
+func (ts *tokenStream) err(format string, args ...any) error {
+	return fmt.Errorf("%s (in content expression %q)", fmt.Sprintf(format, args...), ts.str)
+}
+
+type Expr struct {
+	Type string
+	// for `choice` and `seq`
+	Exprs []Expr
+
+	Value NodeType
+
+	// for `+`, `*`, `range` and `?`
+	Expr *Expr
+
+	// for `range`, -1 meaning unbounded
+	Min int
+	Max int
 }
 
 const (
 	choiceExpr = "choice"
+	seqExpr    = "seq"
 	starExpr   = "star"
 	plusExpr   = "plus"
 	optExpr    = "opt"
@@ -351,52 +303,250 @@ const (
 	rangeExpr  = "range"
 )
 
-func nfa(expr Expr) [][]Edge {
-	var nfa [][]Edge
+func parseNodespecContent(s string, types map[NodeTypeName]NodeType) (Expr, error) {
+	ts := newTokenStream(s, types)
+	if ts.next() == "" {
+		return Expr{}, nil
+	}
+
+	expr, err := parseExpr(ts)
+	if err != nil {
+		return Expr{}, err
+	}
+
+	if ts.next() != "" {
+		return Expr{}, ts.err("unexpected trailing text")
+	}
+
+	return expr, nil
+}
+
+func parseExpr(ts *tokenStream) (Expr, error) {
+	exprs := []Expr{}
+	for {
+		e, err := parseExprSeq(ts)
+		if err != nil {
+			return Expr{}, err
+		}
+		exprs = append(exprs, e)
+
+		if !ts.eat("|") {
+			break
+		}
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+
+	return Expr{Type: choiceExpr, Exprs: exprs}, nil
+}
+
+func parseExprSeq(ts *tokenStream) (Expr, error) {
+	exprs := []Expr{}
+	for ts.next() != "" && ts.next() != ")" && ts.next() != "|" {
+		e, err := parseExprSubscript(ts)
+		if err != nil {
+			return Expr{}, err
+		}
+		exprs = append(exprs, e)
+	}
+
+	switch len(exprs) {
+	case 0:
+		return Expr{}, ts.err("unexpected token %q", ts.next())
+	case 1:
+		return exprs[0], nil
+	}
+
+	return Expr{Type: seqExpr, Exprs: exprs}, nil
+}
+
+func parseExprSubscript(ts *tokenStream) (Expr, error) {
+	expr, err := parseExprAtom(ts)
+	if err != nil {
+		return Expr{}, err
+	}
+
+	for {
+		inner := expr
+		switch {
+		case ts.eat("+"):
+			expr = Expr{Type: plusExpr, Expr: &inner}
+		case ts.eat("*"):
+			expr = Expr{Type: starExpr, Expr: &inner}
+		case ts.eat("?"):
+			expr = Expr{Type: optExpr, Expr: &inner}
+		case ts.eat("{"):
+			expr, err = parseExprRange(ts, inner)
+			if err != nil {
+				return Expr{}, err
+			}
+		default:
+			return expr, nil
+		}
+	}
+}
+
+func parseNum(ts *tokenStream) (int, error) {
+	n, err := strconv.Atoi(ts.next())
+	if err != nil {
+		return 0, ts.err("expected number, got %q", ts.next())
+	}
+
+	ts.pos++
+	return n, nil
+}
+
+func parseExprRange(ts *tokenStream, expr Expr) (Expr, error) {
+	minQ, err := parseNum(ts)
+	if err != nil {
+		return Expr{}, err
+	}
+
+	maxQ := minQ
+	if ts.eat(",") {
+		if ts.next() != "}" {
+			maxQ, err = parseNum(ts)
+			if err != nil {
+				return Expr{}, err
+			}
+		} else {
+			maxQ = -1
+		}
+	}
+
+	if !ts.eat("}") {
+		return Expr{}, ts.err("unclosed braced range")
+	}
+
+	if maxQ != -1 && minQ > maxQ {
+		return Expr{}, ts.err("invalid range {%d,%d}", minQ, maxQ)
+	}
+
+	return Expr{Type: rangeExpr, Min: minQ, Max: maxQ, Expr: &expr}, nil
+}
+
+func parseExprAtom(ts *tokenStream) (Expr, error) {
+	if ts.eat("(") {
+		expr, err := parseExpr(ts)
+		if err != nil {
+			return Expr{}, err
+		}
+
+		if !ts.eat(")") {
+			return Expr{}, ts.err("missing closing paren")
+		}
+
+		return expr, nil
+	}
+
+	next := ts.next()
+	if next == "" || !unicode.IsLetter([]rune(next)[0]) && !strings.ContainsAny(next[:1], "_-0123456789") {
+		return Expr{}, ts.err("unexpected token %q", next)
+	}
+
+	types := resolveName(next, ts.types)
+	if len(types) == 0 {
+		return Expr{}, ts.err("no node type or group %q found", next)
+	}
+
+	exprs := make([]Expr, 0, len(types))
+	for _, typ := range types {
+		exprs = append(exprs, Expr{Type: nameExpr, Value: typ})
+	}
+
+	ts.pos++
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+
+	return Expr{Type: choiceExpr, Exprs: exprs}, nil
+}
+
+// resolveName returns the node type with the given name, or every node
+// type in the group with that name, in schema order.
+func resolveName(name string, types map[NodeTypeName]NodeType) []NodeType {
+	if typ, ok := types[NodeTypeName(name)]; ok {
+		return []NodeType{typ}
+	}
+
+	results := []NodeType{}
+	for _, typ := range types {
+		if slices.Contains(typ.Groups, name) {
+			results = append(results, typ)
+		}
+	}
+
+	slices.SortFunc(results, compareNodeTypes)
+	return results
+}
+
+// nfaEdge is an edge of the non-deterministic automaton. A nil term
+// means an epsilon transition, a negative to means it isn't connected yet.
+type nfaEdge struct {
+	term *NodeType
+	to   int
+}
+
+type edgeRef struct {
+	from, index int
+}
+
+// Construct an NFA from an expression as returned by the parser. The
+// NFA is represented as an array of states, which are themselves
+// arrays of edges.
+func nfa(expr Expr) [][]nfaEdge {
+	automaton := [][]nfaEdge{{}}
 
-	nfa = append(nfa, []Edge{})
 	node := func() int {
-		n := len(nfa)
-		nfa = append(nfa, []Edge{})
-		return n - 1
+		automaton = append(automaton, []nfaEdge{})
+		return len(automaton) - 1
 	}
 
-	edge := func(from int, to *int, term *NodeType) Edge {
-		e := Edge{term, to}
-		nfa[from] = append(nfa[from], e)
-		return e
+	edge := func(from int, to int, term *NodeType) edgeRef {
+		automaton[from] = append(automaton[from], nfaEdge{term: term, to: to})
+		return edgeRef{from, len(automaton[from]) - 1}
 	}
 
-	connect := func(edges []Edge, to int) {
-		for i := range edges {
-			edges[i].To = &to
+	connect := func(edges []edgeRef, to int) {
+		for _, e := range edges {
+			automaton[e.from][e.index].to = to
 		}
 	}
 
-	var compile func(expr Expr, from int) []Edge
-	compile = func(expr Expr, from int) []Edge {
+	var compile func(expr Expr, from int) []edgeRef
+	compile = func(expr Expr, from int) []edgeRef {
 		switch expr.Type {
 		case choiceExpr:
-			var edges []Edge
+			var edges []edgeRef
 			for _, expr := range expr.Exprs {
 				edges = append(edges, compile(expr, from)...)
 			}
 			return edges
+		case seqExpr:
+			for i := 0; ; i++ {
+				next := compile(expr.Exprs[i], from)
+				if i == len(expr.Exprs)-1 {
+					return next
+				}
+				from = node()
+				connect(next, from)
+			}
 		case starExpr:
 			loop := node()
-			edge(from, &loop, nil)
+			edge(from, loop, nil)
 			connect(compile(*expr.Expr, loop), loop)
-			return []Edge{edge(loop, nil, nil)}
+			return []edgeRef{edge(loop, -1, nil)}
 		case plusExpr:
 			loop := node()
 			connect(compile(*expr.Expr, from), loop)
 			connect(compile(*expr.Expr, loop), loop)
-			return []Edge{edge(loop, nil, nil)}
+			return []edgeRef{edge(loop, -1, nil)}
 		case optExpr:
-			return append([]Edge{edge(from, nil, nil)}, compile(*expr.Expr, from)...)
+			return append([]edgeRef{edge(from, -1, nil)}, compile(*expr.Expr, from)...)
 		case rangeExpr:
 			cur := from
-
 			for i := 0; i < expr.Min; i++ {
 				next := node()
 				connect(compile(*expr.Expr, cur), next)
@@ -405,39 +555,176 @@ func nfa(expr Expr) [][]Edge {
 
 			if expr.Max == -1 {
 				connect(compile(*expr.Expr, cur), cur)
-				return []Edge{edge(cur, nil, nil)}
-			}
-
-			for i := expr.Min; i < expr.Max; i++ {
-				next := node()
-				edge(cur, &next, nil)
-				connect(compile(*expr.Expr, cur), next)
-				cur = next
+			} else {
+				for i := expr.Min; i < expr.Max; i++ {
+					next := node()
+					edge(cur, next, nil)
+					connect(compile(*expr.Expr, cur), next)
+					cur = next
+				}
 			}
 
-			return []Edge{edge(cur, nil, nil)}
+			return []edgeRef{edge(cur, -1, nil)}
 		case nameExpr:
-			return []Edge{edge(from, nil, &expr.Value)}
+			value := expr.Value
+			return []edgeRef{edge(from, -1, &value)}
 		}
 
 		panic(fmt.Sprintf("invalid expression type: %s", expr.Type))
 	}
 
 	connect(compile(expr, 0), node())
-	return nfa
+	return automaton
 }
 
-func resolveName(name string, types map[NodeTypeName]NodeType) []NodeType {
-	if typ, ok := types[NodeTypeName(name)]; ok {
-		return []NodeType{typ}
+func cmpInt(a, b int) int { return a - b }
+
+// Get the set of nodes reachable by null edges from `node`. Omit
+// nodes with only a single null-out-edge, since they may lead to
+// needless duplicated nodes.
+func nullFrom(automaton [][]nfaEdge, node int) []int {
+	var result []int
+
+	var scan func(node int)
+	scan = func(node int) {
+		edges := automaton[node]
+		if len(edges) == 1 && edges[0].term == nil {
+			scan(edges[0].to)
+			return
+		}
+
+		result = append(result, node)
+		for _, edge := range edges {
+			if edge.term == nil && !slices.Contains(result, edge.to) {
+				scan(edge.to)
+			}
+		}
 	}
 
-	results := []NodeType{}
-	for _, typ := range types {
-		if slices.Contains(typ.Groups, name) {
-			results = append(results, typ)
+	scan(node)
+	slices.SortFunc(result, cmpInt)
+	return result
+}
+
+// Compiles an NFA as produced by `nfa` into a DFA, modeled as a set
+// of state objects (`ContentMatch` instances) with transitions
+// between them.
+func dfa(automaton [][]nfaEdge) *ContentMatch {
+	type edgeTuple struct {
+		term NodeType
+		set  []int
+	}
+
+	labeled := map[string]*ContentMatch{}
+
+	var explore func(states []int) *ContentMatch
+	explore = func(states []int) *ContentMatch {
+		out := []*edgeTuple{}
+
+		for _, node := range states {
+			for _, edge := range automaton[node] {
+				if edge.term == nil {
+					continue
+				}
+
+				var set *edgeTuple
+				for _, o := range out {
+					if o.term.Name == edge.term.Name {
+						set = o
+					}
+				}
+
+				for _, node := range nullFrom(automaton, edge.to) {
+					if set == nil {
+						set = &edgeTuple{term: *edge.term}
+						out = append(out, set)
+					}
+
+					if !slices.Contains(set.set, node) {
+						set.set = append(set.set, node)
+					}
+				}
+			}
+		}
+
+		state := &ContentMatch{
+			Next:     []contentMatchNode{},
+			ValidEnd: slices.Contains(states, len(automaton)-1),
 		}
+		labeled[join(states, ",")] = state
+
+		for _, item := range out {
+			slices.SortFunc(item.set, cmpInt)
+
+			next, ok := labeled[join(item.set, ",")]
+			if !ok {
+				next = explore(item.set)
+			}
+
+			state.Next = append(state.Next, contentMatchNode{Type: item.term, Next: next})
+		}
+
+		return state
 	}
 
-	return results
+	return explore(nullFrom(automaton, 0))
+}
+
+// checkForDeadEnds makes sure every non-accepting state of the automaton
+// can be left by generating a node, so that content can always be filled.
+func checkForDeadEnds(match *ContentMatch, ts *tokenStream) error {
+	work := []*ContentMatch{match}
+	for i := 0; i < len(work); i++ {
+		state := work[i]
+		dead := !state.ValidEnd
+		nodes := []string{}
+
+		for _, m := range state.Next {
+			nodes = append(nodes, string(m.Type.Name))
+			if dead && !(m.Type.IsText() || m.Type.hasRequiredAttrs()) {
+				dead = false
+			}
+
+			if !slices.Contains(work, m.Next) {
+				work = append(work, m.Next)
+			}
+		}
+
+		if dead {
+			return ts.err("only non-generatable nodes (%s) in a required position", strings.Join(nodes, ", "))
+		}
+	}
+
+	return nil
+}
+
+// parseContentMatch parses a content expression into a content match automaton.
+func parseContentMatch(s string, types map[NodeTypeName]NodeType) (*ContentMatch, error) {
+	expr, err := parseNodespecContent(s, types)
+	if err != nil {
+		return nil, err
+	}
+
+	if expr.Type == "" {
+		return EmptyContentMatch(), nil
+	}
+
+	match := dfa(nfa(expr))
+	if err := checkForDeadEnds(match, newTokenStream(s, types)); err != nil {
+		return nil, err
+	}
+
+	return match, nil
+}
+
+func join(ints []int, sep string) string {
+	s := &strings.Builder{}
+	for i := range ints {
+		if i > 0 {
+			s.WriteString(sep)
+		}
+		s.WriteString(strconv.Itoa(ints[i]))
+	}
+
+	return s.String()
 }
@@ -0,0 +1,180 @@
+package prosemirror
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func contentTestSchema(t *testing.T) Schema {
+	t.Helper()
+
+	s, err := NewSchema(SchemaSpec{
+		Nodes: map[NodeTypeName]NodeSpec{
+			"doc":             {Content: "block+"},
+			"paragraph":       {Content: "inline*", Group: "block"},
+			"heading":         {Content: "inline*", Group: "block", Attrs: map[string]Attribute{"level": {Default: 1}}},
+			"blockquote":      {Content: "block+", Group: "block"},
+			"horizontal_rule": {Group: "block"},
+			"bullet_list":     {Content: "list_item+", Group: "block"},
+			"list_item":       {Content: "paragraph block*"},
+			"image":           {Inline: true, Group: "inline", Attrs: map[string]Attribute{"src": {}}},
+			"text":            {Group: "inline"},
+		},
+		NodeOrder:    []NodeTypeName{"doc", "paragraph", "heading", "blockquote", "horizontal_rule", "bullet_list", "list_item", "image", "text"},
+		TopNode:      "doc",
+		DontRegister: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestContentMatch(t *testing.T) {
+	s := contentTestSchema(t)
+
+	tests := []struct {
+		expr  string
+		types []NodeTypeName
+		valid bool
+	}{
+		{expr: "", valid: true},
+		{expr: "", types: []NodeTypeName{"image"}, valid: false},
+		{expr: "image*", valid: true},
+		{expr: "image*", types: []NodeTypeName{"image", "image"}, valid: true},
+		{expr: "image*", types: []NodeTypeName{"image", "text"}, valid: false},
+		{expr: "inline*", types: []NodeTypeName{"image", "text"}, valid: true},
+		{expr: "paragraph+", valid: false},
+		{expr: "paragraph+", types: []NodeTypeName{"paragraph", "paragraph"}, valid: true},
+		{expr: "paragraph?", types: []NodeTypeName{"paragraph", "paragraph"}, valid: false},
+		{expr: "heading paragraph*", types: []NodeTypeName{"heading", "paragraph"}, valid: true},
+		{expr: "heading paragraph*", types: []NodeTypeName{"paragraph"}, valid: false},
+		{expr: "(heading | paragraph)+", types: []NodeTypeName{"paragraph", "heading", "paragraph"}, valid: true},
+		{expr: "(heading paragraph)+", types: []NodeTypeName{"heading", "paragraph", "heading"}, valid: false},
+		{expr: "paragraph{2}", types: []NodeTypeName{"paragraph"}, valid: false},
+		{expr: "paragraph{2}", types: []NodeTypeName{"paragraph", "paragraph"}, valid: true},
+		{expr: "paragraph{2,}", types: []NodeTypeName{"paragraph", "paragraph", "paragraph"}, valid: true},
+		{expr: "paragraph{1,2}", types: []NodeTypeName{"paragraph", "paragraph", "paragraph"}, valid: false},
+		{expr: "heading? (paragraph | horizontal_rule)*", types: []NodeTypeName{"horizontal_rule", "paragraph"}, valid: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			match, err := parseContentMatch(tc.expr, s.Nodes)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			for _, name := range tc.types {
+				if match = match.MatchType(s.Nodes[name]); match == nil {
+					break
+				}
+			}
+
+			assert.Equal(t, tc.valid, match != nil && match.ValidEnd)
+		})
+	}
+}
+
+func TestContentMatchErrors(t *testing.T) {
+	s := contentTestSchema(t)
+
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{expr: "paragraph | ", err: "unexpected token"},
+		{expr: "(paragraph", err: "missing closing paren"},
+		{expr: "paragraph{2", err: "unclosed braced range"},
+		{expr: "paragraph{3,1}", err: "invalid range {3,1}"},
+		{expr: "foo", err: `no node type or group "foo" found`},
+		{expr: "image", err: "only non-generatable nodes (image) in a required position"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := parseContentMatch(tc.expr, s.Nodes)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.err)
+			}
+		})
+	}
+}
+
+func TestContentMatchFillBefore(t *testing.T) {
+	s := contentTestSchema(t)
+	p := s.Node("paragraph", nil, Fragment{})
+
+	tests := []struct {
+		name  string
+		expr  string
+		after Fragment
+		toEnd bool
+		want  []NodeTypeName
+	}{
+		{name: "nothing needed", expr: "paragraph*", after: NewFragment(p), want: []NodeTypeName{}},
+		{name: "required start", expr: "heading paragraph*", after: NewFragment(p), want: []NodeTypeName{"heading"}},
+		{name: "to end", expr: "paragraph horizontal_rule", toEnd: true, want: []NodeTypeName{"paragraph", "horizontal_rule"}},
+		{name: "impossible", expr: "horizontal_rule", after: NewFragment(p)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			match, err := parseContentMatch(tc.expr, s.Nodes)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			got := match.FillBefore(tc.after, tc.toEnd, 0)
+			if tc.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+
+			if assert.NotNil(t, got) {
+				names := []NodeTypeName{}
+				for _, n := range got.Content {
+					names = append(names, n.Type.Name)
+				}
+
+				assert.Equal(t, tc.want, names)
+			}
+		})
+	}
+}
+
+func TestContentMatchFindWrapping(t *testing.T) {
+	s := contentTestSchema(t)
+
+	tests := []struct {
+		name   string
+		parent NodeTypeName
+		target NodeTypeName
+		want   []NodeTypeName
+	}{
+		{name: "fits", parent: "doc", target: "paragraph", want: []NodeTypeName{}},
+		{name: "list item", parent: "doc", target: "list_item", want: []NodeTypeName{"bullet_list"}},
+		{name: "text", parent: "blockquote", target: "text", want: []NodeTypeName{"paragraph"}},
+		{name: "none", parent: "paragraph", target: "heading"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			match := s.Nodes[tc.parent].ContentMatch
+			got := match.FindWrapping(s.Nodes[tc.target])
+			if tc.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+
+			names := []NodeTypeName{}
+			for _, typ := range got {
+				names = append(names, typ.Name)
+			}
+
+			assert.Equal(t, tc.want, names)
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package prosemirror
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-json-experiment/json"
+)
+
+// wrapMarks wraps the debug representation of a node in its marks.
+func wrapMarks(marks []Mark, str string) string {
+	for i := len(marks) - 1; i >= 0; i-- {
+		str = string(marks[i].Type.Name) + "(" + str + ")"
+	}
+
+	return str
+}
+
+// quoteJS quotes a string like JSON.stringify does in JavaScript.
+func quoteJS(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+// ParseDebugString reads a node from its debug representation, as written by
+// Node.String and by node.toString() in prosemirror-model, using the types of
+// the schema. The representation leaves out attributes, so nodes and marks get
+// the default values of theirs, like headings getting level 1, and types with
+// required attributes, like images and links, fail to parse.
+func ParseDebugString(s Schema, text string) (Node, error) {
+	p := &debugParser{schema: s, text: text}
+	nodes, err := p.item(nil)
+	if err != nil {
+		return Node{}, err
+	}
+
+	if p.skipSpace(); p.pos < len(p.text) {
+		return Node{}, p.errorf("unexpected %q after node", p.text[p.pos:])
+	}
+
+	if len(nodes) != 1 {
+		return Node{}, p.errorf("expected a single node, got %d", len(nodes))
+	}
+
+	return nodes[0], nil
+}
+
+type debugParser struct {
+	schema Schema
+	text   string
+	pos    int
+}
+
+func (p *debugParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *debugParser) skipSpace() {
+	for p.pos < len(p.text) && strings.ContainsRune(" \t\r\n", rune(p.text[p.pos])) {
+		p.pos++
+	}
+}
+
+// eat skips the given byte, reporting whether it was next.
+func (p *debugParser) eat(c byte) bool {
+	if p.skipSpace(); p.pos < len(p.text) && p.text[p.pos] == c {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+// item reads a node, or the nodes wrapped in a mark, with the given marks.
+func (p *debugParser) item(marks []Mark) ([]Node, error) {
+	if p.skipSpace(); p.pos >= len(p.text) {
+		return nil, p.errorf("unexpected end of input")
+	}
+
+	if p.text[p.pos] == '"' {
+		text, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+
+		if text == "" {
+			return nil, p.errorf("empty text nodes are not allowed")
+		}
+
+		return []Node{p.schema.Text(text, marks...)}, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.text) && !strings.ContainsRune(" \t\r\n(),\"<>", rune(p.text[p.pos])) {
+		p.pos++
+	}
+
+	name := p.text[start:p.pos]
+	if name == "" {
+		return nil, p.errorf("expected a node, got %q", p.text[p.pos:p.pos+1])
+	}
+
+	if typ, ok := p.schema.Nodes[NodeTypeName(name)]; ok {
+		if attr, ok := requiredAttr(typ.Attrs); ok {
+			return nil, p.errorf("node type %s requires attribute %s, which debug strings can't hold", name, attr)
+		}
+
+		var content []Node
+		if p.eat('(') {
+			var err error
+			if content, err = p.items(nil); err != nil {
+				return nil, err
+			}
+		}
+
+		node, err := typ.Create(nil, marks, content...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", name, err)
+		}
+
+		return []Node{node}, nil
+	}
+
+	if typ, ok := p.schema.Marks[MarkTypeName(name)]; ok {
+		if attr, ok := requiredAttr(typ.Attrs); ok {
+			return nil, p.errorf("mark type %s requires attribute %s, which debug strings can't hold", name, attr)
+		}
+
+		if !p.eat('(') {
+			return nil, p.errorf("expected the content of mark %s", name)
+		}
+
+		return p.items(typ.Create(nil).AddToSet(marks))
+	}
+
+	return nil, p.errorf("unknown node or mark type %q", name)
+}
+
+// requiredAttr returns the first required attribute, by name, if any.
+func requiredAttr(attrs Attrs) (string, bool) {
+	var names []string
+	for name, attr := range attrs {
+		if attr.isRequired() {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return "", false
+	}
+
+	slices.Sort(names)
+	return names[0], true
+}
+
+// items reads comma-separated items up to a closing parenthesis.
+func (p *debugParser) items(marks []Mark) ([]Node, error) {
+	var nodes []Node
+	for {
+		item, err := p.item(marks)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, item...)
+
+		if p.eat(')') {
+			return nodes, nil
+		}
+
+		if !p.eat(',') {
+			return nil, p.errorf("expected ',' or ')'")
+		}
+	}
+}
+
+// quoted reads a JSON string.
+func (p *debugParser) quoted() (string, error) {
+	start := p.pos
+	for p.pos++; p.pos < len(p.text); p.pos++ {
+		switch p.text[p.pos] {
+		case '\\':
+			p.pos++
+		case '"':
+			p.pos++
+
+			var text string
+			if err := json.Unmarshal([]byte(p.text[start:p.pos]), &text); err != nil {
+				return "", fmt.Errorf("invalid string at offset %d: %w", start, err)
+			}
+
+			return text, nil
+		}
+	}
+
+	return "", fmt.Errorf("unterminated string at offset %d", start)
+}
@@ -0,0 +1,107 @@
+package prosemirror
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugString(t *testing.T) {
+	s := Must(NewSchema(SchemaSpec{
+		Nodes: map[NodeTypeName]NodeSpec{
+			"doc": {
+				Content: "block+",
+			},
+			"paragraph": {
+				Content: "inline*",
+				Group:   "block",
+			},
+			"heading": {
+				Content: "inline*",
+				Group:   "block",
+				Attrs:   map[string]Attribute{"level": {Default: 1}},
+			},
+			"horizontal_rule": {
+				Group: "block",
+			},
+			"text": {
+				Group: "inline",
+			},
+			"image": {
+				Inline: true,
+				Group:  "inline",
+				Attrs:  map[string]Attribute{"src": {}},
+			},
+		},
+		Marks: map[MarkTypeName]MarkSpec{
+			"em":     {},
+			"strong": {},
+			"link":   {Attrs: map[string]Attribute{"href": {}}},
+		},
+		MarkOrder:    []MarkTypeName{"link", "em", "strong"},
+		TopNode:      "doc",
+		DontRegister: true,
+	}))
+
+	p := func(content ...Node) Node { return Must(s.Nodes["paragraph"].Create(nil, nil, content...)) }
+	em, strong := s.Mark("em", nil), s.Mark("strong", nil)
+
+	doc := Must(s.Nodes["doc"].Create(nil, nil,
+		p(s.Text("hi "), s.Text("there", em, strong), s.Text(" \"you\"\n\x01")),
+		Must(s.Nodes["heading"].Create(map[string]any{"level": 2}, nil)),
+		Must(s.Nodes["horizontal_rule"].Create(nil, nil)),
+	))
+
+	const str = `doc(paragraph("hi ", em(strong("there")), " \"you\"\n\u0001"), heading, horizontal_rule)`
+	assert.Equal(t, str, doc.String())
+	assert.Equal(t, `<heading, horizontal_rule>`, doc.Content.Cut(18, 21).String())
+	assert.Equal(t, `<em(strong("there"))>(0,0)`, Must(doc.Slice(4, 9, false)).String())
+
+	rp, err := doc.Resolve(5)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "paragraph_0:4", rp.String())
+	}
+
+	t.Run("parse", func(t *testing.T) {
+		got, err := ParseDebugString(s, str)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, str, got.String())
+		assert.Equal(t, 1, got.Child(1).Attrs["level"], "attributes get their defaults")
+
+		got, err = ParseDebugString(s, "doc(paragraph(em(\"a\", strong(\"b\")), \"c\"))")
+		if assert.NoError(t, err) {
+			want := Must(s.Nodes["doc"].Create(nil, nil, p(s.Text("a", em), s.Text("b", em, strong), s.Text("c"))))
+			assert.True(t, want.Eq(got), "got %s", got)
+		}
+	})
+
+	t.Run("required attributes", func(t *testing.T) {
+		_, err := ParseDebugString(s, `doc(paragraph(image))`)
+		assert.EqualError(t, err, "at offset 19: node type image requires attribute src, which debug strings can't hold")
+
+		_, err = ParseDebugString(s, `doc(paragraph(link("a")))`)
+		assert.EqualError(t, err, "at offset 18: mark type link requires attribute href, which debug strings can't hold")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, text := range []string{
+			`doc(paragraph("a")`,
+			`doc(paragraph("a")) x`,
+			`doc(paragraph("a) )`,
+			`doc(paragraph(""))`,
+			`doc(unknown)`,
+			`doc(paragraph(image))`,
+			`doc(paragraph(link("a")))`,
+			`doc(paragraph(em))`,
+			`doc(paragraph("a") paragraph)`,
+			`doc("a")`,
+			`doc(paragraph(horizontal_rule))`,
+		} {
+			_, err := ParseDebugString(s, text)
+			assert.Error(t, err, text)
+		}
+	})
+}
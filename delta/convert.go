@@ -0,0 +1,321 @@
+package delta
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/transform"
+)
+
+// The line attributes of list items, holding the key of their ListFormat and their nesting level.
+const (
+	listAttr   = "list"
+	indentAttr = "indent"
+)
+
+// LineFormat describes the textblock lines with a line attribute become, like headers.
+type LineFormat struct {
+	// The type of the textblock.
+	Node prosemirror.NodeTypeName
+
+	// Computes the attributes of the node from the value of the line attribute.
+	// The node gets its default attributes when nil.
+	Attrs func(value any) map[string]any
+
+	// Computes the value of the line attribute from the node. The value is true when nil.
+	Value func(node prosemirror.Node) any
+}
+
+// ListFormat describes the list lines with a value of the "list" line attribute become.
+type ListFormat struct {
+	// The types of the list and its items.
+	List, Item prosemirror.NodeTypeName
+
+	// The attributes of the items, like the state of a checklist item.
+	// Items match the format when their attributes have these values.
+	ItemAttrs map[string]any
+}
+
+// MarkFormat describes the mark inline content with an attribute gets, like bold.
+type MarkFormat struct {
+	// The type of the mark.
+	Mark prosemirror.MarkTypeName
+
+	// Computes the attributes of the mark from the value of the attribute.
+	// The mark gets its default attributes when nil.
+	Attrs func(value any) map[string]any
+
+	// Computes the value of the attribute from the mark. The value is true when nil.
+	Value func(mark prosemirror.Mark) any
+}
+
+// EmbedFormat describes the node an embed becomes, like an image. Embeds of inline
+// nodes are part of lines, while embeds of block nodes are lines of their own.
+type EmbedFormat struct {
+	// The type of the node.
+	Node prosemirror.NodeTypeName
+
+	// Computes the attributes of the node from the value of the embed and its attributes.
+	// The node gets its default attributes when nil.
+	Attrs func(value any, attributes map[string]any) map[string]any
+
+	// Computes the value of the embed and its attributes from the node. The value is true when nil.
+	Value func(node prosemirror.Node) (value any, attributes map[string]any)
+}
+
+// Warning reports content of a delta left out of a document, because the
+// converter or the schema cannot represent it. Its source is the attribute or
+// embed holding the content.
+type Warning = prosemirror.ParseWarning
+
+// Converter converts documents of a schema to and from deltas.
+//
+// Every textblock is a line, and blocks wrapping lines, like blockquotes and lists,
+// are line attributes of their lines. Deltas cannot represent several textblocks in
+// a list item: each of them becomes an item of its own when converted back.
+type Converter struct {
+	Schema prosemirror.Schema
+
+	// The type of lines without line attributes.
+	Paragraph prosemirror.NodeTypeName
+
+	// The textblocks lines become, by line attribute.
+	Lines map[string]LineFormat
+
+	// The types of the nodes wrapping consecutive lines, by line attribute, like blockquotes.
+	Wraps map[string]prosemirror.NodeTypeName
+
+	// The lists and items lines become, by value of their "list" attribute.
+	// Their "indent" attribute holds their nesting level.
+	Lists map[string]ListFormat
+
+	// The marks of inline content, by attribute.
+	Marks map[string]MarkFormat
+
+	// The nodes embeds become, by embed type.
+	Embeds map[string]EmbedFormat
+}
+
+// ToDelta returns the delta inserting the content of doc.
+func (c Converter) ToDelta(doc prosemirror.Node) (Delta, error) {
+	s := &deltaState{converter: c}
+	if err := s.blocks(doc.Content, nil, 0); err != nil {
+		return Delta{}, err
+	}
+
+	return s.delta, nil
+}
+
+type deltaState struct {
+	converter Converter
+	delta     Delta
+}
+
+// blocks writes block content, whose lines get the given attributes.
+// level is the nesting level of lists in the content.
+func (s *deltaState) blocks(content prosemirror.Fragment, attrs map[string]any, level int) error {
+	c := s.converter
+	for _, node := range content.Content {
+		switch {
+		case node.IsTextblock():
+			if err := s.textblock(node, attrs); err != nil {
+				return err
+			}
+		case node.IsLeaf():
+			key, format, ok := findKey(c.Embeds, func(f EmbedFormat) bool { return f.Node == node.Type.Name })
+			if !ok {
+				return fmt.Errorf("no embed format for node %s", node.Type.Name)
+			}
+
+			s.embed(key, format, node)
+		default:
+			if key, _, ok := findKey(c.Wraps, func(typ prosemirror.NodeTypeName) bool { return typ == node.Type.Name }); ok {
+				if err := s.blocks(node.Content, withAttr(attrs, key, true), level); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if _, _, ok := findKey(c.Lists, func(f ListFormat) bool { return f.List == node.Type.Name }); !ok {
+				return fmt.Errorf("no format for node %s", node.Type.Name)
+			}
+
+			if err := s.list(node, attrs, level); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *deltaState) list(list prosemirror.Node, attrs map[string]any, level int) error {
+	for _, item := range list.Content.Content {
+		key, _, ok := findKey(s.converter.Lists, func(f ListFormat) bool {
+			return f.List == list.Type.Name && f.Item == item.Type.Name && hasAttrs(item, f.ItemAttrs)
+		})
+		if !ok {
+			return fmt.Errorf("no list format for %s in %s", item.Type.Name, list.Type.Name)
+		}
+
+		itemAttrs := withAttr(attrs, listAttr, key)
+		if level > 0 {
+			itemAttrs[indentAttr] = level
+		}
+
+		for _, child := range item.Content.Content {
+			var err error
+			if _, _, nested := findKey(s.converter.Lists, func(f ListFormat) bool { return f.List == child.Type.Name }); nested {
+				err = s.list(child, attrs, level+1)
+			} else {
+				err = s.blocks(prosemirror.NewFragment(child), itemAttrs, level)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *deltaState) textblock(node prosemirror.Node, attrs map[string]any) error {
+	c := s.converter
+	lineAttrs := maps.Clone(attrs)
+	if node.Type.Name != c.Paragraph {
+		key, format, ok := findKey(c.Lines, func(f LineFormat) bool { return f.Node == node.Type.Name })
+		if !ok {
+			return fmt.Errorf("no line format for node %s", node.Type.Name)
+		}
+
+		lineAttrs = withAttr(attrs, key, value(format.Value, node))
+	}
+
+	// code blocks are made of one line per line of text
+	if node.Type.IsCode() {
+		for _, line := range strings.Split(node.TextContent(), "\n") {
+			s.delta.push(Op{Insert: line})
+			s.delta.push(Op{Insert: "\n", Attributes: lineAttrs})
+		}
+
+		return nil
+	}
+
+	for _, child := range node.Content.Content {
+		if !child.IsText() {
+			key, format, ok := findKey(c.Embeds, func(f EmbedFormat) bool { return f.Node == child.Type.Name })
+			if !ok {
+				return fmt.Errorf("no embed format for node %s", child.Type.Name)
+			}
+
+			s.embed(key, format, child)
+			continue
+		}
+
+		var inline map[string]any
+		for _, m := range child.Marks {
+			key, format, ok := findKey(c.Marks, func(f MarkFormat) bool { return f.Mark == m.Type.Name })
+			if !ok {
+				return fmt.Errorf("no format for mark %s", m.Type.Name)
+			}
+
+			inline = withAttr(inline, key, value(format.Value, m))
+		}
+
+		s.delta.push(Op{Insert: child.Text, Attributes: inline})
+	}
+
+	s.delta.push(Op{Insert: "\n", Attributes: lineAttrs})
+	return nil
+}
+
+func (s *deltaState) embed(key string, format EmbedFormat, node prosemirror.Node) {
+	var v any = true
+	var attrs map[string]any
+	if format.Value != nil {
+		v, attrs = format.Value(node)
+	}
+
+	s.delta.push(Op{Insert: map[string]any{key: v}, Attributes: attrs})
+}
+
+// value computes the value of an attribute, which is true without a function.
+func value[T any](f func(T) any, v T) any {
+	if f == nil {
+		return true
+	}
+
+	return f(v)
+}
+
+// findKey returns the first key of m, in sorted order, whose value matches.
+func findKey[T any](m map[string]T, match func(T) bool) (string, T, bool) {
+	for _, k := range sortedKeys(m) {
+		if match(m[k]) {
+			return k, m[k], true
+		}
+	}
+
+	var zero T
+	return "", zero, false
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+	return keys
+}
+
+// withAttr returns a copy of attrs with an attribute set.
+func withAttr(attrs map[string]any, key string, v any) map[string]any {
+	attrs = maps.Clone(attrs)
+	if attrs == nil {
+		attrs = map[string]any{}
+	}
+
+	attrs[key] = v
+	return attrs
+}
+
+// hasAttrs reports whether the node's attributes have the given values.
+func hasAttrs(node prosemirror.Node, attrs map[string]any) bool {
+	for k, v := range attrs {
+		if !reflect.DeepEqual(node.Attrs[k], v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Steps converts a change to the delta of doc into the steps applying it to doc.
+//
+// The changed delta is converted to a document, which is diffed with doc. Content
+// the delta cannot represent is normalized the way FromDelta builds it.
+func (c Converter) Steps(doc prosemirror.Node, change Delta) ([]transform.Step, []Warning, error) {
+	d, err := c.ToDelta(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next, warnings, err := c.FromDelta(d.Compose(change))
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	steps, err := transform.Diff(doc, next)
+	if err != nil {
+		return nil, warnings, fmt.Errorf("failed to diff documents: %w", err)
+	}
+
+	return steps, warnings, nil
+}
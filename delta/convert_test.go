@@ -0,0 +1,148 @@
+package delta_test
+
+import (
+	"testing"
+
+	"github.com/go-json-experiment/json"
+	"github.com/karitham/prosemirror/builder"
+	"github.com/karitham/prosemirror/delta"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConverter(t *testing.T) {
+	s := builder.Schema()
+	c := delta.NewConverter(s)
+
+	b := builder.New(s)
+	doc, para, heading, blockquote, codeBlock, hr := b.Node("doc"), b.Node("paragraph"), b.Node("heading"), b.Node("blockquote"), b.Node("code_block"), b.Node("horizontal_rule")
+	br, img, ul, ol, li := b.Node("hard_break"), b.Node("image"), b.Node("bullet_list"), b.Node("ordered_list"), b.Node("list_item")
+	em, strong := b.Mark("em"), b.Mark("strong")
+	link := b.Mark("link").With(map[string]any{"href": "https://x.org"})
+
+	t.Run("round trip", func(t *testing.T) {
+		want := doc(
+			heading(map[string]any{"level": 2}, "Title"),
+			para(em(strong("a")), link("b"), br(), img(map[string]any{"src": "a.png", "alt": "alt"})),
+			blockquote(para("q"), para("r")),
+			codeBlock(map[string]any{"language": "go"}, "a\n\nb"),
+			hr(),
+			ul(
+				li(para("a"), ol(li(para("b")), li(para("c")))),
+				li(para("d")),
+			),
+			para(),
+		)
+
+		d, err := c.ToDelta(want.Node)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		data, err := json.Marshal(d)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.JSONEq(t, `{"ops":[
+			{"insert":"Title"},{"insert":"\n","attributes":{"header":2}},
+			{"insert":"a","attributes":{"bold":true,"italic":true}},
+			{"insert":"b","attributes":{"link":"https://x.org"}},
+			{"insert":{"break":true}},
+			{"insert":{"image":"a.png"},"attributes":{"alt":"alt"}},
+			{"insert":"\nq"},{"insert":"\n","attributes":{"blockquote":true}},
+			{"insert":"r"},{"insert":"\n","attributes":{"blockquote":true}},
+			{"insert":"a"},{"insert":"\n\n","attributes":{"code-block":"go"}},
+			{"insert":"b"},{"insert":"\n","attributes":{"code-block":"go"}},
+			{"insert":{"divider":true}},
+			{"insert":"a"},{"insert":"\n","attributes":{"list":"bullet"}},
+			{"insert":"b"},{"insert":"\n","attributes":{"list":"ordered","indent":1}},
+			{"insert":"c"},{"insert":"\n","attributes":{"list":"ordered","indent":1}},
+			{"insert":"d"},{"insert":"\n","attributes":{"list":"bullet"}},
+			{"insert":"\n"}
+		]}`, string(data))
+
+		var decoded delta.Delta
+		if !assert.NoError(t, json.Unmarshal(data, &decoded)) {
+			return
+		}
+
+		got, warnings, err := c.FromDelta(decoded)
+		if assert.NoError(t, err) {
+			assert.Empty(t, warnings)
+			assert.Equal(t, want.String(), got.String())
+			assert.True(t, want.Eq(got), "attributes differ")
+		}
+	})
+
+	t.Run("unsupported formats", func(t *testing.T) {
+		d := delta.Delta{}.
+			Insert("a", map[string]any{"bold": true, "color": "red"}).
+			InsertEmbed(map[string]any{"video": "v.mp4"}, nil).
+			Insert("\n", map[string]any{"align": "center", "indent": 1}).
+			Insert("b", map[string]any{"bold": true}).
+			Insert("\n", map[string]any{"code-block": true, "header": 1}).
+			Insert("c", nil).
+			Insert("\n", map[string]any{"list": "checked"}).
+			Insert("d", nil)
+
+		got, warnings, err := c.FromDelta(d)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		want := doc(
+			para(strong("a")),
+			codeBlock("b"),
+			para("c"),
+			para("d"),
+		)
+		assert.Equal(t, want.String(), got.String())
+		assert.True(t, want.Eq(got), "attributes differ")
+		assert.Equal(t, []delta.Warning{
+			{Source: "align", Reason: "unsupported line format"},
+			{Source: "indent", Reason: "dropped, only list items can be indented"},
+			{Source: "color", Reason: "unsupported format"},
+			{Source: "video", Reason: "unsupported embed"},
+			{Source: "header", Reason: "dropped, the line is already a code-block"},
+			{Source: "bold", Reason: "mark strong is not allowed in code_block"},
+			{Source: "list", Reason: "unsupported list checked"},
+		}, warnings)
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		before := doc(para("hello"), para("world")).Node
+
+		// make "hello" a bold header and insert a list item after it
+		change := delta.Delta{}.Retain(5, map[string]any{"bold": true}).Retain(1, map[string]any{"header": 1}).Insert("item\n", map[string]any{"list": "bullet"})
+		steps, warnings, err := c.Steps(before, change)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Empty(t, warnings)
+
+		got := before
+		for _, step := range steps {
+			if got, err = step.Apply(got); !assert.NoError(t, err) {
+				return
+			}
+		}
+
+		want := doc(
+			heading(map[string]any{"level": 1}, strong("hello")),
+			ul(li(para("item"))),
+			para("world"),
+		)
+		assert.Equal(t, want.String(), got.String())
+		assert.True(t, want.Eq(got), "attributes differ")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, _, err := c.FromDelta(delta.Delta{}.Retain(1, nil))
+		assert.Error(t, err)
+
+		basic := delta.NewConverter(s)
+		basic.Marks = nil
+		_, err = basic.ToDelta(doc(para(link("a"))).Node)
+		assert.EqualError(t, err, "no format for mark link")
+	})
+}
@@ -0,0 +1,111 @@
+package delta
+
+import "github.com/karitham/prosemirror"
+
+// NewConverter creates a converter for the given schema, using Quill's formats and the
+// node and mark names of the schema package's default schema, along with bullet_list,
+// ordered_list and list_item nodes.
+func NewConverter(s prosemirror.Schema) Converter {
+	return Converter{
+		Schema:    s,
+		Paragraph: "paragraph",
+		Lines:     DefaultLines(),
+		Wraps:     map[string]prosemirror.NodeTypeName{"blockquote": "blockquote"},
+		Lists:     DefaultLists(),
+		Marks:     DefaultMarks(),
+		Embeds:    DefaultEmbeds(),
+	}
+}
+
+// DefaultLines returns the formats of headers and code blocks, to be extended for other schemas.
+// The language of code blocks is the value of their "code-block" attribute, as in Quill's syntax module.
+func DefaultLines() map[string]LineFormat {
+	return map[string]LineFormat{
+		"header": {
+			Node: "heading",
+			Attrs: func(v any) map[string]any {
+				level, ok := prosemirror.IntAttr(v)
+				if !ok {
+					return nil
+				}
+
+				return map[string]any{"level": max(1, min(level, 6))}
+			},
+			Value: func(node prosemirror.Node) any {
+				level, _ := prosemirror.IntAttr(node.Attrs["level"])
+				return level
+			},
+		},
+		"code-block": {
+			Node: "code_block",
+			Attrs: func(v any) map[string]any {
+				if lang, ok := v.(string); ok && lang != "plain" {
+					return map[string]any{"language": lang}
+				}
+
+				return nil
+			},
+			Value: func(node prosemirror.Node) any {
+				if lang, ok := node.Attrs["language"].(string); ok && lang != "" {
+					return lang
+				}
+
+				return true
+			},
+		},
+	}
+}
+
+// DefaultLists returns the formats of bullet and ordered lists, to be extended for other schemas.
+func DefaultLists() map[string]ListFormat {
+	return map[string]ListFormat{
+		"bullet":  {List: "bullet_list", Item: "list_item"},
+		"ordered": {List: "ordered_list", Item: "list_item"},
+	}
+}
+
+// DefaultMarks returns the formats of the default marks, to be extended for other schemas.
+func DefaultMarks() map[string]MarkFormat {
+	return map[string]MarkFormat{
+		"bold":   {Mark: "strong"},
+		"italic": {Mark: "em"},
+		"code":   {Mark: "code"},
+		"link": {
+			Mark:  "link",
+			Attrs: func(v any) map[string]any { return map[string]any{"href": v} },
+			Value: func(mark prosemirror.Mark) any { return mark.Attrs["href"] },
+		},
+	}
+}
+
+// DefaultEmbeds returns the formats of images, dividers and line breaks, to be extended for other schemas.
+// Quill has no line break embed, those are written as {"break": true}.
+func DefaultEmbeds() map[string]EmbedFormat {
+	return map[string]EmbedFormat{
+		"image": {
+			Node: "image",
+			Attrs: func(v any, attributes map[string]any) map[string]any {
+				attrs := map[string]any{"src": v}
+				for _, k := range []string{"alt", "title"} {
+					if a, ok := attributes[k].(string); ok {
+						attrs[k] = a
+					}
+				}
+
+				return attrs
+			},
+			Value: func(node prosemirror.Node) (any, map[string]any) {
+				var attributes map[string]any
+				for _, k := range []string{"alt", "title"} {
+					if a, ok := node.Attrs[k].(string); ok && a != "" {
+						attributes = withAttr(attributes, k, a)
+					}
+				}
+
+				return node.Attrs["src"], attributes
+			},
+		},
+		"divider": {Node: "horizontal_rule"},
+		"break":   {Node: "hard_break"},
+	}
+}
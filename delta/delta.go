@@ -0,0 +1,263 @@
+// Package delta converts documents to and from Quill Deltas.
+//
+// A Delta describing a document is a list of insert operations. Text inserts carry
+// inline attributes, like bold or link, and each line ends with a "\n" insert holding
+// the attributes of the line, like header or list. Embeds, like images, are inserted
+// as objects of length 1.
+package delta
+
+import (
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"unicode/utf16"
+
+	"github.com/karitham/prosemirror"
+)
+
+// Op is an operation of a Delta. Exactly one of Insert, Delete and Retain is set.
+type Op struct {
+	// The inserted content, a string or an embed like {"image": "a.png"}.
+	Insert any `json:"insert,omitempty"`
+
+	// The number of deleted characters.
+	Delete int `json:"delete,omitzero"`
+
+	// The number of kept characters, whose attributes are updated with Attributes.
+	Retain int `json:"retain,omitzero"`
+
+	// The attributes of inserted content, or the changed attributes of kept content,
+	// where nil values remove the attribute.
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// Len returns the length of the operation, in UTF-16 code units. Embeds have a length of 1.
+func (o Op) Len() int {
+	switch {
+	case o.Delete > 0:
+		return o.Delete
+	case o.Retain > 0:
+		return o.Retain
+	}
+
+	if text, ok := o.Insert.(string); ok {
+		return prosemirror.UTF16Len(text)
+	}
+
+	return 1
+}
+
+func (o Op) isInsert() bool {
+	return o.Insert != nil
+}
+
+// Delta is a list of operations, describing either a document or a change to one.
+type Delta struct {
+	Ops []Op `json:"ops"`
+}
+
+// Insert returns the delta with some text inserted at its end.
+func (d Delta) Insert(text string, attrs map[string]any) Delta {
+	return d.with(Op{Insert: text, Attributes: attrs})
+}
+
+// InsertEmbed returns the delta with an embed, like {"image": "a.png"}, inserted at its end.
+func (d Delta) InsertEmbed(embed map[string]any, attrs map[string]any) Delta {
+	return d.with(Op{Insert: embed, Attributes: attrs})
+}
+
+// Retain returns the delta keeping n more characters, updating their attributes with attrs.
+func (d Delta) Retain(n int, attrs map[string]any) Delta {
+	return d.with(Op{Retain: n, Attributes: attrs})
+}
+
+// Delete returns the delta deleting n more characters.
+func (d Delta) Delete(n int) Delta {
+	return d.with(Op{Delete: n})
+}
+
+// Len returns the length of the delta, in UTF-16 code units.
+func (d Delta) Len() int {
+	l := 0
+	for _, op := range d.Ops {
+		l += op.Len()
+	}
+
+	return l
+}
+
+func (d Delta) with(op Op) Delta {
+	d.Ops = slices.Clone(d.Ops)
+	d.push(op)
+	return d
+}
+
+// push adds an operation at the end of the delta, merging it with the previous one when possible.
+// Inserts are placed before deletes, which has the same effect.
+func (d *Delta) push(op Op) {
+	if (op.Delete <= 0 && op.Retain <= 0 && !op.isInsert()) || op.Insert == "" {
+		return
+	}
+
+	if len(op.Attributes) == 0 {
+		op.Attributes = nil
+	}
+
+	index := len(d.Ops)
+	if index > 0 {
+		last := &d.Ops[index-1]
+		if op.Delete > 0 && last.Delete > 0 {
+			last.Delete += op.Delete
+			return
+		}
+
+		if last.Delete > 0 && op.isInsert() {
+			index--
+			if index == 0 {
+				d.Ops = slices.Insert(d.Ops, 0, op)
+				return
+			}
+			last = &d.Ops[index-1]
+		}
+
+		if reflect.DeepEqual(op.Attributes, last.Attributes) {
+			lastText, lastIsText := last.Insert.(string)
+			text, isText := op.Insert.(string)
+			switch {
+			case lastIsText && isText:
+				last.Insert = lastText + text
+				return
+			case last.Retain > 0 && op.Retain > 0:
+				last.Retain += op.Retain
+				return
+			}
+		}
+	}
+
+	d.Ops = slices.Insert(d.Ops, index, op)
+}
+
+// chop removes a trailing retain without attributes, which has no effect.
+func (d *Delta) chop() {
+	if last := len(d.Ops) - 1; last >= 0 && d.Ops[last].Retain > 0 && d.Ops[last].Attributes == nil {
+		d.Ops = d.Ops[:last]
+	}
+}
+
+// Compose returns the delta equivalent to applying d, then other.
+func (d Delta) Compose(other Delta) Delta {
+	a, b := &opIterator{ops: d.Ops}, &opIterator{ops: other.Ops}
+
+	var res Delta
+	for a.hasNext() || b.hasNext() {
+		switch {
+		case b.peek().isInsert():
+			res.push(b.next(math.MaxInt))
+		case a.peek().Delete > 0:
+			res.push(a.next(math.MaxInt))
+		default:
+			length := min(a.peekLength(), b.peekLength())
+			op, change := a.next(length), b.next(length)
+			switch {
+			case change.Retain > 0:
+				next := Op{Retain: op.Retain, Insert: op.Insert}
+				next.Attributes = composeAttributes(op.Attributes, change.Attributes, op.Retain > 0)
+				res.push(next)
+			case change.Delete > 0 && op.Retain > 0:
+				res.push(change)
+			}
+			// inserted content deleted by the change disappears
+		}
+	}
+
+	res.chop()
+	return res
+}
+
+// composeAttributes returns the attributes of content with attributes a, updated with b.
+// Nil values remove attributes, and are kept in the result when keepNil is set, for retains.
+func composeAttributes(a, b map[string]any, keepNil bool) map[string]any {
+	attrs := maps.Clone(b)
+	if !keepNil {
+		maps.DeleteFunc(attrs, func(_ string, v any) bool { return v == nil })
+	}
+
+	for k, v := range a {
+		if _, ok := b[k]; !ok && v != nil {
+			if attrs == nil {
+				attrs = map[string]any{}
+			}
+			attrs[k] = v
+		}
+	}
+
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	return attrs
+}
+
+// opIterator walks the operations of a delta, splitting them at arbitrary lengths.
+type opIterator struct {
+	ops    []Op
+	index  int
+	offset int
+}
+
+func (it *opIterator) hasNext() bool {
+	return it.index < len(it.ops)
+}
+
+// peek returns the next operation, or an infinite retain when there are no more operations.
+func (it *opIterator) peek() Op {
+	if !it.hasNext() {
+		return Op{Retain: math.MaxInt}
+	}
+
+	return it.ops[it.index]
+}
+
+func (it *opIterator) peekLength() int {
+	if !it.hasNext() {
+		return math.MaxInt
+	}
+
+	return it.ops[it.index].Len() - it.offset
+}
+
+// next returns up to length characters of the next operation.
+func (it *opIterator) next(length int) Op {
+	if !it.hasNext() {
+		return Op{Retain: math.MaxInt}
+	}
+
+	op, offset := it.ops[it.index], it.offset
+	if rest := op.Len() - offset; length >= rest {
+		length = rest
+		it.index++
+		it.offset = 0
+	} else {
+		it.offset += length
+	}
+
+	switch {
+	case op.Delete > 0:
+		return Op{Delete: length}
+	case op.Retain > 0:
+		return Op{Retain: length, Attributes: op.Attributes}
+	}
+
+	if text, ok := op.Insert.(string); ok {
+		return Op{Insert: sliceText(text, offset, offset+length), Attributes: op.Attributes}
+	}
+
+	return Op{Insert: op.Insert, Attributes: op.Attributes}
+}
+
+// sliceText returns the part of text between the given UTF-16 offsets.
+func sliceText(text string, from, to int) string {
+	units := utf16.Encode([]rune(text))
+	return string(utf16.Decode(units[from:to]))
+}
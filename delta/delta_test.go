@@ -0,0 +1,96 @@
+package delta_test
+
+import (
+	"testing"
+
+	"github.com/go-json-experiment/json"
+	"github.com/karitham/prosemirror/delta"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelta(t *testing.T) {
+	bold := map[string]any{"bold": true}
+
+	t.Run("push merges operations", func(t *testing.T) {
+		d := delta.Delta{}.Insert("a", nil).Insert("b", nil).Insert("c", bold).Delete(1).Delete(2).Insert("d", bold)
+		assert.Equal(t, []delta.Op{
+			{Insert: "ab"},
+			{Insert: "cd", Attributes: bold},
+			{Delete: 3},
+		}, d.Ops)
+		assert.Equal(t, 7, d.Len())
+	})
+
+	t.Run("builders don't share operations", func(t *testing.T) {
+		base := delta.Delta{}.Insert("a", nil)
+		a, b := base.Insert("b", nil), base.Insert("c", nil)
+		assert.Equal(t, "a", base.Ops[0].Insert)
+		assert.Equal(t, "ab", a.Ops[0].Insert)
+		assert.Equal(t, "ac", b.Ops[0].Insert)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		d := delta.Delta{}.Insert("a😀", bold).InsertEmbed(map[string]any{"image": "a.png"}, nil).Retain(2, map[string]any{"bold": nil}).Delete(1)
+		data, err := json.Marshal(d)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, `{"ops":[{"insert":"a😀","attributes":{"bold":true}},{"insert":{"image":"a.png"}},{"retain":2,"attributes":{"bold":null}},{"delete":1}]}`, string(data))
+
+		var got delta.Delta
+		if assert.NoError(t, json.Unmarshal(data, &got)) {
+			assert.Equal(t, d, got)
+			assert.Equal(t, 7, got.Len())
+		}
+	})
+}
+
+func TestCompose(t *testing.T) {
+	bold, italic := map[string]any{"bold": true}, map[string]any{"italic": true}
+	image := map[string]any{"image": "a.png"}
+
+	tests := []struct {
+		name      string
+		a, change delta.Delta
+		want      delta.Delta
+	}{
+		{
+			name:   "insert",
+			a:      delta.Delta{}.Insert("ac\n", nil),
+			change: delta.Delta{}.Retain(1, nil).Insert("b", bold),
+			want:   delta.Delta{}.Insert("a", nil).Insert("b", bold).Insert("c\n", nil),
+		},
+		{
+			name:   "delete",
+			a:      delta.Delta{}.Insert("abc\n", nil),
+			change: delta.Delta{}.Delete(1).Retain(1, nil).Delete(1),
+			want:   delta.Delta{}.Insert("b\n", nil),
+		},
+		{
+			name:   "format",
+			a:      delta.Delta{}.Insert("ab", bold).InsertEmbed(image, nil),
+			change: delta.Delta{}.Retain(1, map[string]any{"bold": nil}).Retain(2, italic),
+			want: delta.Delta{}.Insert("a", nil).Insert("b", map[string]any{"bold": true, "italic": true}).
+				InsertEmbed(image, italic),
+		},
+		{
+			name:   "surrogate pairs",
+			a:      delta.Delta{}.Insert("😀😀", nil),
+			change: delta.Delta{}.Retain(2, nil).Insert("a", nil).Delete(2),
+			want:   delta.Delta{}.Insert("😀a", nil),
+		},
+		{
+			name:   "changes",
+			a:      delta.Delta{}.Retain(2, bold).Delete(1),
+			change: delta.Delta{}.Delete(1).Retain(1, map[string]any{"bold": nil}).Insert("a", nil),
+			want:   delta.Delta{}.Delete(1).Retain(1, map[string]any{"bold": nil}).Insert("a", nil).Delete(1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.a.Compose(tt.change))
+		})
+	}
+}
@@ -0,0 +1,413 @@
+package delta
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/karitham/prosemirror"
+)
+
+// FromDelta builds the document inserted by a delta. Attributes, embeds and nodes
+// that the converter or the schema cannot represent are left out, reported as warnings,
+// and their content kept when possible: lines with an unknown format become paragraphs.
+func (c Converter) FromDelta(d Delta) (prosemirror.Node, []Warning, error) {
+	s := &parseState{converter: c}
+	s.open(*c.Schema.TopNodeType, nil, "")
+
+	for _, op := range d.Ops {
+		switch {
+		case op.Delete > 0:
+			return prosemirror.Node{}, nil, fmt.Errorf("delta is not a document, it deletes %d characters", op.Delete)
+		case op.Retain > 0:
+			return prosemirror.Node{}, nil, fmt.Errorf("delta is not a document, it retains %d characters", op.Retain)
+		}
+
+		switch insert := op.Insert.(type) {
+		case string:
+			lines := strings.Split(insert, "\n")
+			for i, text := range lines {
+				if text != "" {
+					s.inline = append(s.inline, Op{Insert: text, Attributes: op.Attributes})
+				}
+
+				if i < len(lines)-1 {
+					s.line(op.Attributes)
+				}
+			}
+		case map[string]any:
+			s.embed(insert, op.Attributes)
+		default:
+			return prosemirror.Node{}, nil, fmt.Errorf("unsupported insert %v", op.Insert)
+		}
+	}
+
+	// Quill deltas end with a newline, be lenient with those that don't
+	if len(s.inline) > 0 {
+		s.line(nil)
+	}
+
+	s.align(nil)
+	doc, err := s.stack[0].Node()
+	if err != nil {
+		return prosemirror.Node{}, s.warnings, fmt.Errorf("failed to create document: %w", err)
+	}
+
+	return doc, s.warnings, nil
+}
+
+// parseFrame is a node being built, along with the line attribute it comes from.
+type parseFrame struct {
+	*prosemirror.ContentBuilder
+
+	// The line attribute the node comes from, listAttr for lists and items.
+	format string
+}
+
+// container is a node wrapping a line, one element of its path in the document.
+type container struct {
+	typ    prosemirror.NodeType
+	attrs  map[string]any
+	format string
+
+	// A fresh container is never shared with the previous line, like a new list item.
+	fresh bool
+
+	// A container matching any open list or list item, for the outer levels of nested lists.
+	anyList bool
+}
+
+type parseState struct {
+	converter Converter
+	stack     []*parseFrame
+
+	// The inline content of the current line.
+	inline []Op
+
+	warnings prosemirror.ParseWarnings
+}
+
+func (s *parseState) top() *parseFrame {
+	return s.stack[len(s.stack)-1]
+}
+
+func (s *parseState) nodeType(format string, name prosemirror.NodeTypeName) (prosemirror.NodeType, bool) {
+	typ, ok := s.converter.Schema.Nodes[name]
+	if !ok {
+		s.warnings.Warn(format, "node %s is not in the schema", name)
+	}
+
+	return typ, ok
+}
+
+func (s *parseState) open(typ prosemirror.NodeType, attrs map[string]any, format string) {
+	s.stack = append(s.stack, &parseFrame{ContentBuilder: prosemirror.NewContentBuilder(typ, attrs, nil), format: format})
+}
+
+func (s *parseState) close() {
+	f := s.top()
+	s.stack = s.stack[:len(s.stack)-1]
+
+	node, err := f.Node()
+	if err != nil {
+		s.warnings.Warn(f.format, "%s", err)
+		return
+	}
+
+	s.addNode(f.format, node)
+}
+
+func (s *parseState) addNode(format string, node prosemirror.Node) {
+	if f := s.top(); !f.Push(node) {
+		s.warnings.Warn(format, "%s is not allowed in %s", node.Type.Name, f.Type.Name)
+	}
+}
+
+// align closes and opens containers so that the stack holds the given path.
+func (s *parseState) align(path []container) {
+	shared := 0
+	for shared < len(path) && shared+1 < len(s.stack) && s.matches(s.stack[shared+1], path[shared]) {
+		shared++
+	}
+
+	for len(s.stack) > shared+1 {
+		s.close()
+	}
+
+	for _, c := range path[shared:] {
+		s.open(c.typ, c.attrs, c.format)
+	}
+}
+
+func (s *parseState) matches(f *parseFrame, c container) bool {
+	switch {
+	case c.fresh:
+		return false
+	case c.anyList:
+		return f.format == c.format && s.isList(f.Type) == s.isList(c.typ)
+	}
+
+	return f.format == c.format && f.Type.Name == c.typ.Name && reflect.DeepEqual(f.Attrs, c.attrs)
+}
+
+func (s *parseState) isList(typ prosemirror.NodeType) bool {
+	_, _, ok := findKey(s.converter.Lists, func(f ListFormat) bool { return f.List == typ.Name })
+	return ok
+}
+
+// line ends the current line, whose line attributes are attrs.
+func (s *parseState) line(attrs map[string]any) {
+	c := s.converter
+	inline := s.inline
+	s.inline = nil
+
+	typ, ok := s.nodeType("", c.Paragraph)
+	if !ok {
+		return
+	}
+
+	var nodeAttrs map[string]any
+	var format string
+	var path []container
+	for _, key := range sortedKeys(attrs) {
+		v := attrs[key]
+		if v == nil || v == false {
+			continue
+		}
+
+		if line, ok := c.Lines[key]; ok {
+			if format != "" {
+				s.warnings.Warn(key, "dropped, the line is already a %s", format)
+				continue
+			}
+
+			if t, ok := s.nodeType(key, line.Node); ok {
+				typ, format = t, key
+				if line.Attrs != nil {
+					nodeAttrs = line.Attrs(v)
+				}
+			}
+			continue
+		}
+
+		if wrap, ok := c.Wraps[key]; ok {
+			if t, ok := s.nodeType(key, wrap); ok {
+				path = append(path, container{typ: t, format: key})
+			}
+			continue
+		}
+
+		switch _, inline := c.Marks[key]; {
+		case key == listAttr:
+			path = append(path, s.listPath(v, attrs[indentAttr])...)
+		case key == indentAttr:
+			if _, ok := attrs[listAttr]; !ok {
+				s.warnings.Warn(key, "dropped, only list items can be indented")
+			}
+		case !inline:
+			// inline formats of newlines have no effect, as in Quill
+			s.warnings.Warn(key, "unsupported line format")
+		}
+	}
+
+	s.align(path)
+
+	content := s.inlineContent(typ, inline)
+	if typ.IsCode() {
+		var text strings.Builder
+		for _, n := range content {
+			text.WriteString(n.TextContent())
+		}
+
+		content = nil
+		if text.Len() > 0 {
+			content = []prosemirror.Node{c.Schema.Text(text.String())}
+		}
+
+		// consecutive code lines make a single code block
+		f := s.top()
+		if last := len(f.Content) - 1; last >= 0 && f.Content[last].Type.Name == typ.Name && reflect.DeepEqual(f.Content[last].Attrs, s.attrs(typ, nodeAttrs)) {
+			text := f.Content[last].TextContent() + "\n" + text.String()
+			if merged, err := typ.CreateAndFill(f.Content[last].Attrs, nil, c.Schema.Text(text)); err == nil {
+				f.Content[last] = merged
+				return
+			}
+		}
+	}
+
+	s.open(typ, nodeAttrs, format)
+	for _, n := range content {
+		s.addNode(format, n)
+	}
+	s.close()
+}
+
+// attrs returns the attributes of a node of the given type, including the defaults.
+func (s *parseState) attrs(typ prosemirror.NodeType, attrs map[string]any) map[string]any {
+	node, err := typ.Create(attrs, nil)
+	if err != nil {
+		return attrs
+	}
+
+	return node.Attrs
+}
+
+func (s *parseState) isLineFormat(key string) bool {
+	_, line := s.converter.Lines[key]
+	_, wrap := s.converter.Wraps[key]
+	return line || wrap || key == listAttr || key == indentAttr
+}
+
+// listPath returns the containers of a list line, one list and item per nesting level.
+func (s *parseState) listPath(v, indent any) []container {
+	key, _ := v.(string)
+	list, ok := s.converter.Lists[key]
+	if !ok {
+		s.warnings.Warn(listAttr, "unsupported list %v", v)
+		return nil
+	}
+
+	listType, ok := s.nodeType(listAttr, list.List)
+	if !ok {
+		return nil
+	}
+
+	itemType, ok := s.nodeType(listAttr, list.Item)
+	if !ok {
+		return nil
+	}
+
+	level, _ := prosemirror.IntAttr(indent)
+	var path []container
+	for i := 0; i <= max(level, 0); i++ {
+		outer := i < level
+		path = append(path,
+			container{typ: listType, format: listAttr, anyList: outer},
+			container{typ: itemType, attrs: list.ItemAttrs, format: listAttr, anyList: outer, fresh: !outer},
+		)
+	}
+
+	return path
+}
+
+// inlineContent builds the content of a textblock of the given type.
+func (s *parseState) inlineContent(typ prosemirror.NodeType, ops []Op) []prosemirror.Node {
+	c := s.converter
+	var content []prosemirror.Node
+	for _, op := range ops {
+		var node prosemirror.Node
+		if text, ok := op.Insert.(string); ok {
+			node = c.Schema.Text(text, s.marks(typ, op.Attributes)...)
+
+			if last := len(content) - 1; last >= 0 && content[last].IsText() && prosemirror.SameMarkSet(content[last].Marks, node.Marks) {
+				content[last] = c.Schema.Text(content[last].Text+text, node.Marks...)
+				continue
+			}
+		} else {
+			embed, ok := s.embedNode(op.Insert.(map[string]any), op.Attributes)
+			if !ok {
+				continue
+			}
+
+			node = embed
+		}
+
+		content = append(content, node)
+	}
+
+	return content
+}
+
+// marks returns the marks of inline content with the given attributes.
+func (s *parseState) marks(typ prosemirror.NodeType, attrs map[string]any) []prosemirror.Mark {
+	c := s.converter
+	var marks []prosemirror.Mark
+	for _, key := range sortedKeys(attrs) {
+		v := attrs[key]
+		if v == nil || v == false {
+			continue
+		}
+
+		format, ok := c.Marks[key]
+		if !ok {
+			// line formats of text have no effect, as in Quill
+			if !s.isLineFormat(key) {
+				s.warnings.Warn(key, "unsupported format")
+			}
+			continue
+		}
+
+		markType, ok := c.Schema.Marks[format.Mark]
+		if !ok {
+			s.warnings.Warn(key, "mark %s is not in the schema", format.Mark)
+			continue
+		}
+
+		if !typ.AllowsMarkType(markType) {
+			s.warnings.Warn(key, "mark %s is not allowed in %s", markType.Name, typ.Name)
+			continue
+		}
+
+		var markAttrs map[string]any
+		if format.Attrs != nil {
+			markAttrs = format.Attrs(v)
+		}
+
+		marks = markType.Create(markAttrs).AddToSet(marks)
+	}
+
+	return marks
+}
+
+// embedNode builds the node of an embed.
+func (s *parseState) embedNode(embed, attrs map[string]any) (prosemirror.Node, bool) {
+	for key, v := range embed {
+		format, ok := s.converter.Embeds[key]
+		if !ok {
+			s.warnings.Warn(key, "unsupported embed")
+			return prosemirror.Node{}, false
+		}
+
+		typ, ok := s.nodeType(key, format.Node)
+		if !ok {
+			return prosemirror.Node{}, false
+		}
+
+		var nodeAttrs map[string]any
+		if format.Attrs != nil {
+			nodeAttrs = format.Attrs(v, attrs)
+		}
+
+		node, err := typ.CreateAndFill(nodeAttrs, nil)
+		if err != nil {
+			s.warnings.Warn(key, "%s", err)
+			return prosemirror.Node{}, false
+		}
+
+		return node, true
+	}
+
+	s.warnings.Warn("", "empty embed")
+	return prosemirror.Node{}, false
+}
+
+// embed adds an embed, which is part of the current line when inline, or a line of its own.
+func (s *parseState) embed(embed, attrs map[string]any) {
+	for key := range embed {
+		if format, ok := s.converter.Embeds[key]; ok {
+			if typ, ok := s.converter.Schema.Nodes[format.Node]; ok && typ.IsBlock() {
+				if len(s.inline) > 0 {
+					s.line(nil)
+				}
+
+				if node, ok := s.embedNode(embed, attrs); ok {
+					s.align(nil)
+					s.addNode(key, node)
+				}
+				return
+			}
+		}
+	}
+
+	s.inline = append(s.inline, Op{Insert: embed, Attributes: attrs})
+}
@@ -0,0 +1,91 @@
+package prosemirror
+
+import "unicode/utf16"
+
+// FindDiffStart finds the first position at which this fragment and another
+// fragment differ, offset by `pos`. Returns false when they are the same.
+func (f Fragment) FindDiffStart(other Fragment, pos int) (int, bool) {
+	return findDiffStart(f, other, pos)
+}
+
+// FindDiffEnd finds the first position, searching from the end, at which this
+// fragment and the given fragment differ. Since that position will not be the
+// same in both fragments, it returns the position in this fragment and the
+// position in the other one, offset by `pos` and `otherPos`, which are
+// usually the sizes of the fragments. Returns false when they are the same.
+func (f Fragment) FindDiffEnd(other Fragment, pos, otherPos int) (int, int, bool) {
+	return findDiffEnd(f, other, pos, otherPos)
+}
+
+func findDiffStart(a, b Fragment, pos int) (int, bool) {
+	for i := 0; ; i++ {
+		if i == a.ChildCount() || i == b.ChildCount() {
+			return pos, a.ChildCount() != b.ChildCount()
+		}
+
+		childA, childB := a.Child(i), b.Child(i)
+		if childA.Eq(*childB) {
+			pos += childA.NodeSize()
+			continue
+		}
+
+		if !childA.SameMarkup(*childB) {
+			return pos, true
+		}
+
+		if childA.IsText() && childA.Text != childB.Text {
+			textA, textB := utf16.Encode([]rune(childA.Text)), utf16.Encode([]rune(childB.Text))
+			for j := 0; j < len(textA) && j < len(textB) && textA[j] == textB[j]; j++ {
+				pos++
+			}
+
+			return pos, true
+		}
+
+		if childA.Content.Size > 0 || childB.Content.Size > 0 {
+			if inner, ok := findDiffStart(childA.Content, childB.Content, pos+1); ok {
+				return inner, true
+			}
+		}
+
+		pos += childA.NodeSize()
+	}
+}
+
+func findDiffEnd(a, b Fragment, posA, posB int) (int, int, bool) {
+	for iA, iB := a.ChildCount(), b.ChildCount(); ; {
+		if iA == 0 || iB == 0 {
+			return posA, posB, iA != iB
+		}
+
+		iA, iB = iA-1, iB-1
+		childA, childB := a.Child(iA), b.Child(iB)
+		size := childA.NodeSize()
+		if childA.Eq(*childB) {
+			posA, posB = posA-size, posB-size
+			continue
+		}
+
+		if !childA.SameMarkup(*childB) {
+			return posA, posB, true
+		}
+
+		if childA.IsText() && childA.Text != childB.Text {
+			textA, textB := utf16.Encode([]rune(childA.Text)), utf16.Encode([]rune(childB.Text))
+			for same := 0; same < len(textA) && same < len(textB) &&
+				textA[len(textA)-same-1] == textB[len(textB)-same-1]; same++ {
+				posA, posB = posA-1, posB-1
+			}
+
+			return posA, posB, true
+		}
+
+		if childA.Content.Size > 0 || childB.Content.Size > 0 {
+			if innerA, innerB, ok := findDiffEnd(childA.Content, childB.Content, posA-1, posB-1); ok {
+				return innerA, innerB, true
+			}
+		}
+
+		posA, posB = posA-size, posB-size
+	}
+}
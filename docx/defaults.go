@@ -0,0 +1,162 @@
+package docx
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/karitham/prosemirror"
+)
+
+// DefaultSerializer serializes documents of the schema package's default schema,
+// along with bullet_list, ordered_list and list_item nodes. Images are written as their alt text.
+var DefaultSerializer = NewSerializer(DefaultNodeSerializers(), DefaultMarkSerializers(), SerializerOptions{})
+
+// DefaultNodeSerializers returns the serializers of the default nodes, to be extended for other schemas.
+func DefaultNodeSerializers() map[prosemirror.NodeTypeName]NodeSerializer {
+	return map[prosemirror.NodeTypeName]NodeSerializer{
+		"paragraph": func(s *SerializerState, node prosemirror.Node) {
+			s.Paragraph(ParagraphProps{}, func() { s.RenderContent(node) })
+		},
+		"heading": func(s *SerializerState, node prosemirror.Node) {
+			level := 1
+			if l, ok := prosemirror.IntAttr(node.Attrs["level"]); ok {
+				level = max(1, min(l, 6))
+			}
+
+			s.Paragraph(ParagraphProps{Style: fmt.Sprintf("Heading%d", level)}, func() { s.RenderContent(node) })
+		},
+		"blockquote": func(s *SerializerState, node prosemirror.Node) {
+			s.WrapBlock("Quote", 360, func() { s.RenderContent(node) })
+		},
+		"code_block": func(s *SerializerState, node prosemirror.Node) {
+			s.Paragraph(ParagraphProps{Style: "Code"}, func() { s.Text(node.TextContent(), nil) })
+		},
+		"horizontal_rule": func(s *SerializerState, _ prosemirror.Node) {
+			s.Paragraph(ParagraphProps{BottomBorder: true}, nil)
+		},
+		"bullet_list": func(s *SerializerState, node prosemirror.Node) {
+			s.RenderList(node, false, 1)
+		},
+		"ordered_list": func(s *SerializerState, node prosemirror.Node) {
+			start := 1
+			if order, ok := prosemirror.IntAttr(node.Attrs["order"]); ok {
+				start = order
+			}
+
+			s.RenderList(node, true, start)
+		},
+		"list_item": func(s *SerializerState, node prosemirror.Node) {
+			s.RenderListItem(node)
+		},
+		"text": func(s *SerializerState, node prosemirror.Node) {
+			s.Text(node.Text, node.Marks)
+		},
+		"hard_break": func(s *SerializerState, node prosemirror.Node) {
+			s.Run(node.Marks, "<w:br/>")
+		},
+		"image": func(s *SerializerState, node prosemirror.Node) {
+			src, _ := node.Attrs["src"].(string)
+			alt, _ := node.Attrs["alt"].(string)
+
+			var img Image
+			if load := s.ser.Options.LoadImage; load != nil {
+				var err error
+				if img, err = load(src); err != nil {
+					s.Error(fmt.Errorf("failed to load image %q: %w", src, err))
+					return
+				}
+			}
+
+			if len(img.Data) == 0 {
+				s.Text(alt, node.Marks)
+				return
+			}
+
+			drawing, err := s.Drawing(img, alt)
+			if err != nil {
+				s.Error(fmt.Errorf("failed to add image %q: %w", src, err))
+				return
+			}
+
+			s.Run(node.Marks, drawing)
+		},
+	}
+}
+
+// DefaultMarkSerializers returns the serializers of the default marks, to be extended for other schemas.
+func DefaultMarkSerializers() map[prosemirror.MarkTypeName]MarkSerializer {
+	return map[prosemirror.MarkTypeName]MarkSerializer{
+		"em":     func(_ prosemirror.Mark, props *RunProps) { props.Italic = true },
+		"strong": func(_ prosemirror.Mark, props *RunProps) { props.Bold = true },
+		"code":   func(_ prosemirror.Mark, props *RunProps) { props.Font = "Courier New" },
+		"link": func(mark prosemirror.Mark, props *RunProps) {
+			props.Hyperlink, _ = mark.Attrs["href"].(string)
+			props.Style = "Hyperlink"
+		},
+	}
+}
+
+// NewParser creates a parser for the given schema, using the node and mark
+// names of the schema package's default schema, along with bullet_list,
+// ordered_list and list_item nodes.
+func NewParser(s prosemirror.Schema) Parser {
+	return Parser{
+		Schema:         s,
+		Styles:         DefaultStyles(),
+		Paragraph:      "paragraph",
+		Marks:          DefaultMarkRules(),
+		BulletList:     "bullet_list",
+		OrderedList:    "ordered_list",
+		ListItem:       "list_item",
+		OrderAttr:      "order",
+		HorizontalRule: "horizontal_rule",
+		HardBreak:      "hard_break",
+		Image:          "image",
+	}
+}
+
+// DefaultStyles returns the specs of the built-in paragraph styles of Word, to be extended for other schemas.
+func DefaultStyles() map[string]StyleSpec {
+	styles := map[string]StyleSpec{
+		"Title":            {Node: "heading", Attrs: map[string]any{"level": 1}},
+		"Subtitle":         {Node: "heading", Attrs: map[string]any{"level": 2}},
+		"Quote":            {Node: "paragraph", Wrap: "blockquote"},
+		"IntenseQuote":     {Node: "paragraph", Wrap: "blockquote"},
+		"Code":             {Node: "code_block"},
+		"HTMLPreformatted": {Node: "code_block"},
+		"SourceCode":       {Node: "code_block"},
+	}
+
+	for level := 1; level <= 6; level++ {
+		styles[fmt.Sprintf("Heading%d", level)] = StyleSpec{Node: "heading", Attrs: map[string]any{"level": level}}
+	}
+
+	return styles
+}
+
+var monospaceFonts = []string{
+	"consolas", "courier", "courier new", "dejavu sans mono", "liberation mono",
+	"lucida console", "menlo", "monaco", "roboto mono", "source code pro",
+}
+
+// DefaultMarkRules returns the rules of the default marks, to be extended for other schemas.
+// Runs in a monospace font or a code style get the code mark.
+func DefaultMarkRules() map[prosemirror.MarkTypeName]MarkRule {
+	return map[prosemirror.MarkTypeName]MarkRule{
+		"em":     func(props RunProps) (map[string]any, bool) { return nil, props.Italic },
+		"strong": func(props RunProps) (map[string]any, bool) { return nil, props.Bold },
+		"code": func(props RunProps) (map[string]any, bool) {
+			code := slices.Contains(monospaceFonts, strings.ToLower(props.Font))
+			switch normalizeStyle(props.Style) {
+			case "code", "htmlcode", "verbatimchar", "sourcecode":
+				code = true
+			}
+
+			return nil, code
+		},
+		"link": func(props RunProps) (map[string]any, bool) {
+			return map[string]any{"href": props.Hyperlink}, props.Hyperlink != ""
+		},
+	}
+}
@@ -0,0 +1,819 @@
+package docx
+
+import (
+	"archive/zip"
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/karitham/prosemirror"
+)
+
+// StyleSpec describes the node paragraphs with a style become.
+type StyleSpec struct {
+	// The type of the node.
+	Node prosemirror.NodeTypeName
+
+	// The attributes of the node.
+	Attrs map[string]any
+
+	// The type of a node wrapping consecutive paragraphs with the style, like a blockquote.
+	Wrap prosemirror.NodeTypeName
+}
+
+// MarkRule computes the attributes of a mark added to runs from their properties,
+// reporting false when the mark doesn't apply.
+type MarkRule func(props RunProps) (attrs map[string]any, ok bool)
+
+// Warning reports content of a Word file left out of a parsed document, because
+// the parser or the schema cannot represent it. Its source is the name of the
+// WordprocessingML element holding the content.
+type Warning = prosemirror.ParseWarning
+
+// Parser reads Word files into documents of a schema. Node types that are not
+// in the schema are left out, and their content kept when possible: paragraphs
+// with an unsupported style become plain paragraphs, and numbered paragraphs
+// stay paragraphs without list types. Tables are flattened to their paragraphs.
+type Parser struct {
+	Schema prosemirror.Schema
+
+	// The nodes paragraphs become, by paragraph style ID or name. Keys are
+	// matched ignoring case and spaces, and styles inherit the spec of the style
+	// they are based on. Paragraphs with other styles become Paragraph nodes.
+	Styles map[string]StyleSpec
+
+	// The type of paragraphs without a spec in Styles.
+	Paragraph prosemirror.NodeTypeName
+
+	// The marks added to runs, computed from their properties.
+	Marks map[prosemirror.MarkTypeName]MarkRule
+
+	// The types of numbered paragraphs' lists and list items.
+	BulletList, OrderedList, ListItem prosemirror.NodeTypeName
+
+	// The attribute of ordered lists holding their first number.
+	OrderAttr string
+
+	// The type of the node empty paragraphs with a bottom border become.
+	HorizontalRule prosemirror.NodeTypeName
+
+	// The types of line breaks and images. Images get `src`, `alt` and `title` attributes.
+	HardBreak, Image prosemirror.NodeTypeName
+
+	// Stores an image embedded in the file, whose name in the file is `name`,
+	// returning the source of its image node. Embedded images are left out when nil.
+	StoreImage func(img Image, name string) (src string, err error)
+}
+
+// Parse reads the Word file of the given size from r.
+func (p Parser) Parse(r io.ReaderAt, size int64) (prosemirror.Node, []Warning, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return prosemirror.Node{}, nil, fmt.Errorf("failed to open package: %w", err)
+	}
+
+	pkg := &wordPackage{zip: z}
+	docPath, err := pkg.documentPath()
+	if err != nil {
+		return prosemirror.Node{}, nil, err
+	}
+
+	var document element
+	if err := pkg.read(docPath, &document); err != nil {
+		return prosemirror.Node{}, nil, err
+	}
+
+	s := &parseState{parser: p, pkg: pkg, docPath: docPath}
+	if s.rels, err = pkg.relationships(docPath); err != nil {
+		return prosemirror.Node{}, nil, err
+	}
+
+	// styles and numbering are optional parts
+	dir := path.Dir(docPath)
+	var styles, numbering element
+	for _, r := range s.rels {
+		switch r.typ {
+		case relStyles, strictRel(relStyles):
+			err = pkg.read(path.Join(dir, r.target), &styles)
+		case relNumbering, strictRel(relNumbering):
+			err = pkg.read(path.Join(dir, r.target), &numbering)
+		}
+
+		if err != nil {
+			return prosemirror.Node{}, nil, err
+		}
+	}
+
+	s.styles = readStyles(styles)
+	s.numbering = readNumbering(numbering)
+
+	s.open(*p.Schema.TopNodeType, nil)
+	if body := document.child("body"); body != nil {
+		s.blocks(body.Children)
+	}
+	s.closeLists(-1)
+	s.closeWrap()
+
+	doc, err := s.stack[0].Node()
+	if err != nil {
+		return prosemirror.Node{}, s.warnings, fmt.Errorf("failed to create document: %w", err)
+	}
+
+	return doc, s.warnings, nil
+}
+
+// strictRel returns the Strict Office Open XML variant of a relationship type.
+func strictRel(typ string) string {
+	return "http://purl.oclc.org/ooxml/officeDocument/relationships/" + path.Base(typ)
+}
+
+// element is a generic XML element.
+type element struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []element  `xml:",any"`
+	Text     string     `xml:",chardata"`
+}
+
+// attr returns the value of the attribute with the given local name.
+func (e element) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+
+	return ""
+}
+
+// child returns the first child with the given local name.
+func (e element) child(name string) *element {
+	for i := range e.Children {
+		if e.Children[i].XMLName.Local == name {
+			return &e.Children[i]
+		}
+	}
+
+	return nil
+}
+
+// find returns the first descendant with the given local name.
+func (e element) find(name string) *element {
+	for i := range e.Children {
+		if e.Children[i].XMLName.Local == name {
+			return &e.Children[i]
+		}
+
+		if found := e.Children[i].find(name); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// on reports whether a toggle property, like `<w:b/>`, is set.
+func (e *element) on() bool {
+	if e == nil {
+		return false
+	}
+
+	switch e.attr("val") {
+	case "0", "false", "off", "none":
+		return false
+	}
+
+	return true
+}
+
+type wordPackage struct {
+	zip *zip.Reader
+}
+
+func (pkg *wordPackage) open(name string) ([]byte, error) {
+	f, err := pkg.zip.Open(strings.TrimPrefix(name, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	return data, nil
+}
+
+func (pkg *wordPackage) read(name string, v any) error {
+	data, err := pkg.open(name)
+	if err != nil {
+		return err
+	}
+
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func (pkg *wordPackage) relationships(part string) (map[string]relationship, error) {
+	var rels element
+	name := path.Join(path.Dir(part), "_rels", path.Base(part)+".rels")
+	if err := pkg.read(name, &rels); err != nil {
+		return nil, err
+	}
+
+	result := map[string]relationship{}
+	for _, r := range rels.Children {
+		result[r.attr("Id")] = relationship{
+			id:       r.attr("Id"),
+			typ:      r.attr("Type"),
+			target:   r.attr("Target"),
+			external: r.attr("TargetMode") == "External",
+		}
+	}
+
+	return result, nil
+}
+
+func (pkg *wordPackage) documentPath() (string, error) {
+	rels, err := pkg.relationships("/")
+	if err != nil {
+		return "", err
+	}
+
+	for _, r := range rels {
+		if r.typ == relOfficeDocument || r.typ == strictRel(relOfficeDocument) {
+			return strings.TrimPrefix(r.target, "/"), nil
+		}
+	}
+
+	return "", fmt.Errorf("no office document in package")
+}
+
+// style is a paragraph style of a Word file.
+type style struct {
+	name, basedOn string
+}
+
+func readStyles(styles element) map[string]style {
+	result := map[string]style{}
+	for _, s := range styles.Children {
+		if s.XMLName.Local != "style" || s.attr("type") != "paragraph" {
+			continue
+		}
+
+		st := style{}
+		if name := s.child("name"); name != nil {
+			st.name = name.attr("val")
+		}
+		if basedOn := s.child("basedOn"); basedOn != nil {
+			st.basedOn = basedOn.attr("val")
+		}
+
+		result[s.attr("styleId")] = st
+	}
+
+	return result
+}
+
+// numberingLevel is a level of a numbering definition.
+type numberingLevel struct {
+	ordered bool
+	start   int
+}
+
+func readNumbering(numbering element) map[string]map[int]numberingLevel {
+	abstracts := map[string]map[int]numberingLevel{}
+	for _, a := range numbering.Children {
+		if a.XMLName.Local != "abstractNum" {
+			continue
+		}
+
+		levels := map[int]numberingLevel{}
+		for _, lvl := range a.Children {
+			if lvl.XMLName.Local != "lvl" {
+				continue
+			}
+
+			level := numberingLevel{ordered: true, start: 1}
+			if f := lvl.child("numFmt"); f != nil {
+				level.ordered = f.attr("val") != "bullet" && f.attr("val") != "none"
+			}
+			if start := lvl.child("start"); start != nil {
+				level.start, _ = strconv.Atoi(start.attr("val"))
+			}
+
+			ilvl, _ := strconv.Atoi(lvl.attr("ilvl"))
+			levels[ilvl] = level
+		}
+
+		abstracts[a.attr("abstractNumId")] = levels
+	}
+
+	nums := map[string]map[int]numberingLevel{}
+	for _, n := range numbering.Children {
+		if n.XMLName.Local != "num" {
+			continue
+		}
+
+		levels := map[int]numberingLevel{}
+		if id := n.child("abstractNumId"); id != nil {
+			for l, level := range abstracts[id.attr("val")] {
+				levels[l] = level
+			}
+		}
+
+		for _, o := range n.Children {
+			start := o.child("startOverride")
+			if o.XMLName.Local != "lvlOverride" || start == nil {
+				continue
+			}
+
+			ilvl, _ := strconv.Atoi(o.attr("ilvl"))
+			level := levels[ilvl]
+			level.start, _ = strconv.Atoi(start.attr("val"))
+			levels[ilvl] = level
+		}
+
+		nums[n.attr("numId")] = levels
+	}
+
+	return nums
+}
+
+// normalizeStyle normalizes a style ID or name to match a key of Parser.Styles.
+func normalizeStyle(s string) string {
+	return strings.ToLower(strings.ReplaceAll(s, " ", ""))
+}
+
+// openList is a list being built, along with its current item.
+type openList struct {
+	level   int
+	ordered bool
+}
+
+type parseState struct {
+	parser    Parser
+	pkg       *wordPackage
+	docPath   string
+	rels      map[string]relationship
+	styles    map[string]style
+	numbering map[string]map[int]numberingLevel
+
+	stack    []*prosemirror.ContentBuilder
+	lists    []openList
+	wrap     prosemirror.NodeTypeName
+	warnings prosemirror.ParseWarnings
+}
+
+func (s *parseState) top() *prosemirror.ContentBuilder {
+	return s.stack[len(s.stack)-1]
+}
+
+func (s *parseState) nodeType(name prosemirror.NodeTypeName) (prosemirror.NodeType, bool) {
+	if name == "" {
+		return prosemirror.NodeType{}, false
+	}
+
+	typ, ok := s.parser.Schema.Nodes[name]
+	return typ, ok
+}
+
+func (s *parseState) open(typ prosemirror.NodeType, attrs map[string]any) {
+	s.stack = append(s.stack, prosemirror.NewContentBuilder(typ, attrs, nil))
+}
+
+func (s *parseState) close(element string) {
+	node, err := s.top().Node()
+	s.stack = s.stack[:len(s.stack)-1]
+	if err != nil {
+		s.warnings.Warn(element, "%s", err)
+		return
+	}
+
+	// consecutive code paragraphs make a single code block
+	parent := s.top()
+	if last := len(parent.Content) - 1; node.Type.Spec.Code && last >= 0 && parent.Content[last].Type.Name == node.Type.Name {
+		text := parent.Content[last].TextContent() + "\n" + node.TextContent()
+		if merged, err := node.Type.CreateAndFill(parent.Content[last].Attrs, nil, s.parser.Schema.Text(text)); err == nil {
+			parent.Content[last] = merged
+			return
+		}
+	}
+
+	s.addNode(element, node)
+}
+
+func (s *parseState) addNode(element string, node prosemirror.Node) {
+	if b := s.top(); !b.Push(node) {
+		s.warnings.Warn(element, "%s is not allowed in %s", node.Type.Name, b.Type.Name)
+	}
+}
+
+func (s *parseState) addText(text string, marks []prosemirror.Mark) {
+	if text == "" {
+		return
+	}
+
+	typ := s.top().Type
+	var allowed []prosemirror.Mark
+	for _, m := range marks {
+		if typ.AllowsMarkType(m.Type) {
+			allowed = append(allowed, m)
+		} else {
+			s.warnings.Warn("r", "mark %s is not allowed in %s", m.Type.Name, typ.Name)
+		}
+	}
+
+	s.addNode("t", s.parser.Schema.Text(text, allowed...))
+}
+
+// blocks adds block-level content, like the children of the body.
+func (s *parseState) blocks(children []element) {
+	for _, e := range children {
+		switch e.XMLName.Local {
+		case "p":
+			s.paragraph(e)
+		case "tbl":
+			s.warnings.Warn("tbl", "table flattened into paragraphs")
+			for _, row := range e.Children {
+				for _, cell := range row.Children {
+					if cell.XMLName.Local == "tc" {
+						s.blocks(cell.Children)
+					}
+				}
+			}
+		case "sdt":
+			if content := e.child("sdtContent"); content != nil {
+				s.blocks(content.Children)
+			}
+		case "customXml", "ins", "smartTag":
+			s.blocks(e.Children)
+		case "sectPr", "bookmarkStart", "bookmarkEnd", "del", "proofErr", "permStart", "permEnd":
+		default:
+			s.warnings.Warn(e.XMLName.Local, "unsupported block content")
+		}
+	}
+}
+
+// styleSpec returns the spec of a paragraph style, following the styles it is based on.
+func (s *parseState) styleSpec(id string) (StyleSpec, bool) {
+	for range 10 {
+		if id == "" {
+			break
+		}
+
+		st := s.styles[id]
+		for key, spec := range s.parser.Styles {
+			if key := normalizeStyle(key); key == normalizeStyle(id) || st.name != "" && key == normalizeStyle(st.name) {
+				return spec, true
+			}
+		}
+
+		id = st.basedOn
+	}
+
+	return StyleSpec{}, false
+}
+
+func (s *parseState) paragraph(p element) {
+	pPr := p.child("pPr")
+	var styleID, numID string
+	level, indent := 0, 0
+	border := false
+	if pPr != nil {
+		if st := pPr.child("pStyle"); st != nil {
+			styleID = st.attr("val")
+		}
+		if numPr := pPr.child("numPr"); numPr != nil {
+			if id := numPr.child("numId"); id != nil && id.attr("val") != "0" {
+				numID = id.attr("val")
+			}
+			if ilvl := numPr.child("ilvl"); ilvl != nil {
+				level, _ = strconv.Atoi(ilvl.attr("val"))
+			}
+		}
+		if bdr := pPr.child("pBdr"); bdr != nil && bdr.child("bottom").on() {
+			border = true
+		}
+		if ind := pPr.child("ind"); ind != nil {
+			indent, _ = strconv.Atoi(cmp.Or(ind.attr("left"), ind.attr("start")))
+		}
+	}
+
+	spec, ok := s.styleSpec(styleID)
+	if !ok {
+		spec = StyleSpec{Node: s.parser.Paragraph}
+	}
+
+	if spec.Wrap != s.wrap {
+		s.closeLists(-1)
+		s.closeWrap()
+		if typ, ok := s.nodeType(spec.Wrap); ok {
+			s.open(typ, nil)
+			s.wrap = spec.Wrap
+		} else if spec.Wrap != "" {
+			s.warnings.Warn("pStyle", "node type %s is not in the schema", spec.Wrap)
+		}
+	}
+
+	switch n := len(s.lists); {
+	case numID != "":
+		s.listItem(numID, level)
+	case n > 0 && indent >= listIndent(s.lists[n-1].level):
+		// indented paragraphs continue the current list item
+	default:
+		s.closeLists(-1)
+	}
+
+	if border && isEmptyParagraph(p) {
+		if typ, ok := s.nodeType(s.parser.HorizontalRule); ok {
+			node, err := typ.CreateAndFill(nil, nil)
+			if err == nil {
+				s.addNode("p", node)
+				return
+			}
+		}
+	}
+
+	typ, ok := s.nodeType(spec.Node)
+	if !ok {
+		s.warnings.Warn("pStyle", "node type %s is not in the schema", spec.Node)
+		if typ, ok = s.nodeType(s.parser.Paragraph); !ok {
+			s.warnings.Warn("p", "paragraph type %s is not in the schema", s.parser.Paragraph)
+			return
+		}
+		spec.Attrs = nil
+	}
+
+	s.open(typ, spec.Attrs)
+	s.inline(p.Children, RunProps{})
+	s.close("p")
+}
+
+func isEmptyParagraph(p element) bool {
+	for _, c := range p.Children {
+		if c.XMLName.Local != "pPr" && c.XMLName.Local != "bookmarkStart" && c.XMLName.Local != "bookmarkEnd" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *parseState) closeWrap() {
+	if s.wrap != "" {
+		s.wrap = ""
+		s.close("pStyle")
+	}
+}
+
+// closeLists closes the lists deeper than the given level, along with their items.
+func (s *parseState) closeLists(level int) {
+	for len(s.lists) > 0 && s.lists[len(s.lists)-1].level > level {
+		s.lists = s.lists[:len(s.lists)-1]
+		s.close("numPr") // item
+		s.close("numPr") // list
+	}
+}
+
+// listItem opens a list item for a numbered paragraph, in a new list when needed.
+func (s *parseState) listItem(numID string, level int) {
+	numbering := s.numbering[numID][level]
+	listName := s.parser.BulletList
+	if numbering.ordered {
+		listName = s.parser.OrderedList
+	}
+
+	listType, listOK := s.nodeType(listName)
+	itemType, itemOK := s.nodeType(s.parser.ListItem)
+	if !listOK || !itemOK {
+		s.warnings.Warn("numPr", "list numbering dropped, list types are not in the schema")
+		return
+	}
+
+	s.closeLists(level)
+	if n := len(s.lists); n > 0 && s.lists[n-1].level == level && s.lists[n-1].ordered == numbering.ordered {
+		s.close("numPr")
+		s.open(itemType, nil)
+		return
+	}
+
+	if n := len(s.lists); n > 0 && s.lists[n-1].level == level {
+		s.lists = s.lists[:n-1]
+		s.close("numPr")
+		s.close("numPr")
+	}
+
+	var attrs map[string]any
+	if numbering.ordered && s.parser.OrderAttr != "" {
+		attrs = map[string]any{s.parser.OrderAttr: max(numbering.start, 0)}
+	}
+
+	s.open(listType, attrs)
+	s.open(itemType, nil)
+	s.lists = append(s.lists, openList{level: level, ordered: numbering.ordered})
+}
+
+// inline adds the inline content of a paragraph, with the given properties
+// inherited from enclosing elements like hyperlinks.
+func (s *parseState) inline(children []element, props RunProps) {
+	for _, e := range children {
+		switch e.XMLName.Local {
+		case "r":
+			s.run(e, props)
+		case "hyperlink":
+			link := props
+			if r, ok := s.rels[e.attr("id")]; ok {
+				link.Hyperlink = r.target
+			} else if anchor := e.attr("anchor"); anchor != "" {
+				link.Hyperlink = "#" + anchor
+			}
+
+			s.inline(e.Children, link)
+		case "ins", "smartTag", "customXml", "fldSimple":
+			s.inline(e.Children, props)
+		case "sdt":
+			if content := e.child("sdtContent"); content != nil {
+				s.inline(content.Children, props)
+			}
+		case "pPr", "del", "bookmarkStart", "bookmarkEnd", "proofErr", "commentRangeStart", "commentRangeEnd", "permStart", "permEnd":
+		default:
+			s.warnings.Warn(e.XMLName.Local, "unsupported inline content")
+		}
+	}
+}
+
+// runProps reads the direct formatting of a run.
+func runProps(rPr *element, props RunProps) RunProps {
+	if rPr == nil {
+		return props
+	}
+
+	if st := rPr.child("rStyle"); st != nil {
+		props.Style = st.attr("val")
+	}
+	if fonts := rPr.child("rFonts"); fonts != nil && fonts.attr("ascii") != "" {
+		props.Font = fonts.attr("ascii")
+	}
+	if b := rPr.child("b"); b != nil {
+		props.Bold = b.on()
+	}
+	if i := rPr.child("i"); i != nil {
+		props.Italic = i.on()
+	}
+	if strike := rPr.child("strike"); strike != nil {
+		props.Strike = strike.on()
+	}
+	if u := rPr.child("u"); u != nil {
+		props.Underline = u.on()
+	}
+	if color := rPr.child("color"); color != nil && color.attr("val") != "auto" {
+		props.Color = color.attr("val")
+	}
+
+	return props
+}
+
+func (s *parseState) marks(props RunProps) []prosemirror.Mark {
+	var marks []prosemirror.Mark
+	for _, typ := range s.parser.Schema.MarkTypes() {
+		rule, ok := s.parser.Marks[typ.Name]
+		if !ok {
+			continue
+		}
+
+		if attrs, ok := rule(props); ok {
+			marks = typ.Create(attrs).AddToSet(marks)
+		}
+	}
+
+	return marks
+}
+
+func (s *parseState) run(r element, inherited RunProps) {
+	marks := s.marks(runProps(r.child("rPr"), inherited))
+
+	for _, e := range r.Children {
+		switch e.XMLName.Local {
+		case "t":
+			s.addText(e.Text, marks)
+		case "tab":
+			s.addText("\t", marks)
+		case "noBreakHyphen":
+			s.addText("‑", marks)
+		case "br", "cr":
+			if e.attr("type") == "page" || e.attr("type") == "column" {
+				continue
+			}
+
+			s.hardBreak(e.XMLName.Local, marks)
+		case "drawing", "pict":
+			s.image(e, marks)
+		case "rPr", "lastRenderedPageBreak", "fldChar", "instrText", "delText", "softHyphen":
+		default:
+			s.warnings.Warn(e.XMLName.Local, "unsupported run content")
+		}
+	}
+}
+
+func (s *parseState) hardBreak(name string, marks []prosemirror.Mark) {
+	typ, ok := s.nodeType(s.parser.HardBreak)
+	if !ok {
+		s.addText("\n", marks)
+		return
+	}
+
+	node, err := typ.CreateAndFill(nil, nil)
+	if err != nil {
+		s.warnings.Warn(name, "%s", err)
+		return
+	}
+
+	if b := s.top(); b.Type.Spec.Code || !b.Push(node.Mark(marks)) {
+		s.addText("\n", marks)
+	}
+}
+
+func (s *parseState) image(e element, marks []prosemirror.Mark) {
+	typ, ok := s.nodeType(s.parser.Image)
+	if !ok {
+		s.warnings.Warn(e.XMLName.Local, "image dropped, image type is not in the schema")
+		return
+	}
+
+	attrs := map[string]any{}
+	if docPr := e.find("docPr"); docPr != nil {
+		if alt := docPr.attr("descr"); alt != "" {
+			attrs["alt"] = alt
+		}
+		if title := docPr.attr("title"); title != "" {
+			attrs["title"] = title
+		}
+	}
+
+	var id string
+	var external bool
+	if blip := e.find("blip"); blip != nil {
+		id, external = blip.attr("embed"), false
+		if id == "" {
+			id, external = blip.attr("link"), true
+		}
+	} else if data := e.find("imagedata"); data != nil {
+		id = data.attr("id")
+	}
+
+	rel, ok := s.rels[id]
+	switch {
+	case !ok:
+		s.warnings.Warn(e.XMLName.Local, "image dropped, unsupported drawing")
+		return
+	case external || rel.external:
+		attrs["src"] = rel.target
+	case s.parser.StoreImage == nil:
+		s.warnings.Warn(e.XMLName.Local, "embedded image dropped, no image storage")
+		return
+	default:
+		name := path.Join(path.Dir(s.docPath), rel.target)
+		if strings.HasPrefix(rel.target, "/") {
+			name = rel.target
+		}
+
+		data, err := s.pkg.open(name)
+		if err != nil {
+			s.warnings.Warn(e.XMLName.Local, "image dropped, %s", err)
+			return
+		}
+
+		img := Image{Data: data, ContentType: http.DetectContentType(data)}
+		if extent := e.find("extent"); extent != nil {
+			cx, _ := strconv.Atoi(extent.attr("cx"))
+			cy, _ := strconv.Atoi(extent.attr("cy"))
+			img.Width, img.Height = cx/9525, cy/9525
+		}
+
+		src, err := s.parser.StoreImage(img, path.Base(name))
+		if err != nil {
+			s.warnings.Warn(e.XMLName.Local, "image dropped, failed to store it: %s", err)
+			return
+		}
+
+		attrs["src"] = src
+	}
+
+	node, err := typ.CreateAndFill(attrs, marks)
+	if err != nil {
+		s.warnings.Warn(e.XMLName.Local, "%s", err)
+		return
+	}
+
+	s.addNode(e.XMLName.Local, node)
+}
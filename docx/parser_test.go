@@ -0,0 +1,162 @@
+package docx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/builder"
+	"github.com/karitham/prosemirror/docx"
+	"github.com/karitham/prosemirror/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+// writePackage creates a minimal Word file with the given document body and styles.
+func writePackage(t *testing.T, body, styles string) []byte {
+	t.Helper()
+
+	const ns = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	parts := map[string]string{
+		"_rels/.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/></Relationships>`,
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://x.org" TargetMode="External"/></Relationships>`,
+		"word/document.xml": `<w:document ` + ns + `><w:body>` + body + `</w:body></w:document>`,
+		"word/styles.xml":   `<w:styles ` + ns + `>` + styles + `</w:styles>`,
+	}
+
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for name, content := range parts {
+		f, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestParser(t *testing.T) {
+	s := builder.Schema()
+	b := builder.New(s)
+	doc, para, heading, blockquote, codeBlock, hr := b.Node("doc"), b.Node("paragraph"), b.Node("heading"), b.Node("blockquote"), b.Node("code_block"), b.Node("horizontal_rule")
+	br, imageNode, ul, ol, li := b.Node("hard_break"), b.Node("image"), b.Node("bullet_list"), b.Node("ordered_list"), b.Node("list_item")
+	em, strong, code, link := b.Mark("em"), b.Mark("strong"), b.Mark("code"), b.Mark("link")
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 2, 1))); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		want := doc(
+			heading(map[string]any{"level": 2}, "Title"),
+			para(em(strong("a")), code("b"), link(map[string]any{"href": "https://x.org/?a=1&b=2"}, "c"), br(), "d\te"),
+			blockquote(para("q"), para("r")),
+			codeBlock("a\nb"),
+			hr(),
+			ul(
+				li(para("a"), para("more"), ol(map[string]any{"order": 3}, li(para("b")), li(para("c")))),
+				li(para("d")),
+			),
+			para(imageNode(map[string]any{"src": "stored/image1.png", "alt": "alt"})),
+		)
+
+		ser := docx.DefaultSerializer
+		ser.Options.LoadImage = func(string) (docx.Image, error) { return docx.Image{Data: img.Bytes()}, nil }
+
+		var buf bytes.Buffer
+		if !assert.NoError(t, ser.Serialize(&buf, want.Node)) {
+			return
+		}
+
+		parser := docx.NewParser(s)
+		var stored docx.Image
+		parser.StoreImage = func(img docx.Image, name string) (string, error) {
+			stored = img
+			return "stored/" + name, nil
+		}
+
+		got, warnings, err := parser.Parse(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Empty(t, warnings)
+		assert.Equal(t, want.String(), got.String())
+		assert.True(t, want.Eq(got), "attributes differ")
+		assert.Equal(t, docx.Image{Data: img.Bytes(), ContentType: "image/png", Width: 2, Height: 1}, stored)
+	})
+
+	t.Run("styles", func(t *testing.T) {
+		styles := `<w:style w:type="paragraph" w:styleId="Titre1"><w:name w:val="heading 1"/></w:style>` +
+			`<w:style w:type="paragraph" w:styleId="Fancy"><w:name w:val="Fancy"/><w:basedOn w:val="Titre1"/></w:style>`
+		body := `<w:p><w:pPr><w:pStyle w:val="Titre1"/></w:pPr><w:r><w:t>a</w:t></w:r></w:p>` +
+			`<w:p><w:pPr><w:pStyle w:val="Fancy"/></w:pPr><w:r><w:rPr><w:b/><w:i w:val="0"/></w:rPr><w:t xml:space="preserve">b </w:t></w:r>` +
+			`<w:hyperlink r:id="rId2"><w:r><w:t>c</w:t></w:r></w:hyperlink></w:p>` +
+			`<w:p><w:pPr><w:pStyle w:val="Unknown"/></w:pPr><w:ins><w:r><w:t>d</w:t></w:r></w:ins><w:del><w:r><w:delText>x</w:delText></w:r></w:del></w:p>`
+
+		data := writePackage(t, body, styles)
+		got, warnings, err := docx.NewParser(s).Parse(bytes.NewReader(data), int64(len(data)))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		want := doc(
+			heading(map[string]any{"level": 1}, "a"),
+			heading(map[string]any{"level": 1}, strong("b "), link(map[string]any{"href": "https://x.org"}, "c")),
+			para("d"),
+		)
+		assert.Empty(t, warnings)
+		assert.Equal(t, want.String(), got.String())
+		assert.True(t, want.Eq(got), "attributes differ")
+	})
+
+	t.Run("unsupported content", func(t *testing.T) {
+		body := `<w:p><w:pPr><w:pStyle w:val="Quote"/><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>a</w:t></w:r></w:p>` +
+			`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>b</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>c</w:t></w:r></w:p></w:tc></w:tr></w:tbl>` +
+			`<w:p><w:r><w:t>d</w:t><w:drawing/><w:footnoteReference w:id="1"/></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>e</w:t></w:r></w:p>`
+
+		spec := schema.DefaultSpec
+		spec.DontRegister = true
+		spec.Marks = map[p.MarkTypeName]p.MarkSpec{"em": schema.DefaultMarks["em"]}
+		spec.MarkOrder = []p.MarkTypeName{"em"}
+		basic := p.Must(p.NewSchema(spec))
+
+		data := writePackage(t, body, "")
+		got, warnings, err := docx.NewParser(basic).Parse(bytes.NewReader(data), int64(len(data)))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		bb := builder.New(basic)
+		para := bb.Node("paragraph")
+		want := bb.Node("doc")(bb.Node("blockquote")(para("a")), para("b"), para("c"), para("de"))
+		assert.Equal(t, want.String(), got.String())
+		assert.True(t, want.Eq(got), "attributes differ")
+		assert.Equal(t, []docx.Warning{
+			{Source: "numPr", Reason: "list numbering dropped, list types are not in the schema"},
+			{Source: "tbl", Reason: "table flattened into paragraphs"},
+			{Source: "drawing", Reason: "image dropped, unsupported drawing"},
+			{Source: "footnoteReference", Reason: "unsupported run content"},
+		}, warnings)
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		_, _, err := docx.NewParser(s).Parse(bytes.NewReader([]byte("not a zip")), 9)
+		assert.Error(t, err)
+	})
+}
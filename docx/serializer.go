@@ -0,0 +1,496 @@
+// Package docx converts documents to and from Word documents (WordprocessingML),
+// without depending on Word or any external tool.
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/gif"  // register the decoder reading the size of GIF images
+	_ "image/jpeg" // register the decoder reading the size of JPEG images
+	_ "image/png"  // register the decoder reading the size of PNG images
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/karitham/prosemirror"
+)
+
+// NodeSerializer writes a node as WordprocessingML, through the methods of the state.
+type NodeSerializer func(s *SerializerState, node prosemirror.Node)
+
+// MarkSerializer sets the properties of the runs of text having a mark.
+type MarkSerializer func(mark prosemirror.Mark, props *RunProps)
+
+// Image is an image embedded in a document.
+type Image struct {
+	Data []byte
+
+	// The MIME type of the image, detected from the data when empty.
+	// PNG, JPEG and GIF images are supported.
+	ContentType string
+
+	// The size of the image in pixels, read from the data when zero.
+	Width, Height int
+}
+
+// SerializerOptions are the options of a Serializer.
+type SerializerOptions struct {
+	// Loads the image at the given source, to embed it in the document.
+	// When nil, or when it returns an image without data, images are written as their alt text.
+	LoadImage func(src string) (Image, error)
+}
+
+// Serializer writes documents as Word files, using a serializer for each node and mark type.
+type Serializer struct {
+	Nodes   map[prosemirror.NodeTypeName]NodeSerializer
+	Marks   map[prosemirror.MarkTypeName]MarkSerializer
+	Options SerializerOptions
+}
+
+// NewSerializer creates a serializer with the given node and mark serializers.
+func NewSerializer(nodes map[prosemirror.NodeTypeName]NodeSerializer, marks map[prosemirror.MarkTypeName]MarkSerializer, opts SerializerOptions) Serializer {
+	return Serializer{Nodes: nodes, Marks: marks, Options: opts}
+}
+
+// Serialize writes the content of the given node as a .docx file to w.
+// Nodes without a serializer are an error, and marks without one are left out.
+func (ser Serializer) Serialize(w io.Writer, content prosemirror.Node) error {
+	s := &SerializerState{ser: ser}
+	s.RenderContent(content)
+	if s.err != nil {
+		return s.err
+	}
+
+	return s.writePackage(w)
+}
+
+// RunProps are the formatting properties of a run of text.
+type RunProps struct {
+	Bold, Italic, Underline, Strike bool
+
+	// The character style of the run, like "Hyperlink".
+	Style string
+
+	// The font of the run, like "Courier New".
+	Font string
+
+	// The color of the run, as a hex RGB value like "0563C1".
+	Color string
+
+	// The URL the run links to. URLs starting with "#" link to a bookmark.
+	Hyperlink string
+}
+
+func (p RunProps) xml() string {
+	// the order of the elements is fixed by the schema
+	var sb strings.Builder
+	if p.Style != "" {
+		fmt.Fprintf(&sb, `<w:rStyle w:val="%s"/>`, esc(p.Style))
+	}
+	if p.Font != "" {
+		fmt.Fprintf(&sb, `<w:rFonts w:ascii="%[1]s" w:hAnsi="%[1]s" w:cs="%[1]s"/>`, esc(p.Font))
+	}
+	if p.Bold {
+		sb.WriteString("<w:b/>")
+	}
+	if p.Italic {
+		sb.WriteString("<w:i/>")
+	}
+	if p.Strike {
+		sb.WriteString("<w:strike/>")
+	}
+	if p.Color != "" {
+		fmt.Fprintf(&sb, `<w:color w:val="%s"/>`, esc(p.Color))
+	}
+	if p.Underline {
+		sb.WriteString(`<w:u w:val="single"/>`)
+	}
+
+	if sb.Len() == 0 {
+		return ""
+	}
+
+	return "<w:rPr>" + sb.String() + "</w:rPr>"
+}
+
+// ParagraphProps are the formatting properties of a paragraph.
+type ParagraphProps struct {
+	// The paragraph style, like "Heading1". Defaults to the style of the enclosing block.
+	Style string
+
+	// Whether the paragraph has a bottom border, as used for horizontal rules.
+	BottomBorder bool
+}
+
+// listLevel is a list being serialized.
+type listLevel struct {
+	numID int
+}
+
+// num is a numbering instance, used by a single list.
+type num struct {
+	ordered bool
+	start   int
+	level   int
+}
+
+type relationship struct {
+	id, typ, target string
+	external        bool
+}
+
+type media struct {
+	name string
+	data []byte
+}
+
+// SerializerState tracks the state of a Word serialization, and is used by
+// node serializers to write their output.
+type SerializerState struct {
+	ser   Serializer
+	body  strings.Builder
+	err   error
+	rels  []relationship
+	media []media
+	nums  []num
+	links map[string]string
+
+	lists      []listLevel
+	pendingNum bool
+
+	// The paragraph style used by paragraphs without one, like "Quote" in blockquotes.
+	Style string
+
+	// The left indentation of paragraphs, in twentieths of a point.
+	Indent int
+}
+
+// Error records an error, making the serialization fail. Only the first error is kept.
+func (s *SerializerState) Error(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Write adds raw WordprocessingML to the document body.
+func (s *SerializerState) Write(xml string) {
+	s.body.WriteString(xml)
+}
+
+// Render writes a node with its serializer.
+func (s *SerializerState) Render(node prosemirror.Node) {
+	render, ok := s.ser.Nodes[node.Type.Name]
+	if !ok {
+		s.Error(fmt.Errorf("node type %q not supported by the Word serializer", node.Type.Name))
+		return
+	}
+
+	render(s, node)
+}
+
+// RenderContent writes the content of a node. Inline content is written as runs.
+func (s *SerializerState) RenderContent(parent prosemirror.Node) {
+	for _, child := range parent.Content.Content {
+		s.Render(child)
+	}
+}
+
+// WrapBlock renders the content of a block whose paragraphs use the given
+// style, and are indented by `indent` more twentieths of a point.
+func (s *SerializerState) WrapBlock(style string, indent int, f func()) {
+	oldStyle, oldIndent := s.Style, s.Indent
+	s.Style = style
+	s.Indent += indent
+	f()
+	s.Style, s.Indent = oldStyle, oldIndent
+}
+
+// Paragraph writes a paragraph, whose runs are written by `content`.
+// In list items, the first paragraph is numbered.
+func (s *SerializerState) Paragraph(props ParagraphProps, content func()) {
+	var pPr strings.Builder
+	if style := cmp.Or(props.Style, s.Style); style != "" {
+		fmt.Fprintf(&pPr, `<w:pStyle w:val="%s"/>`, esc(style))
+	}
+
+	level := len(s.lists) - 1
+	numbered := s.pendingNum
+	if numbered {
+		s.pendingNum = false
+		fmt.Fprintf(&pPr, `<w:numPr><w:ilvl w:val="%d"/><w:numId w:val="%d"/></w:numPr>`, min(level, 8), s.lists[level].numID)
+	}
+
+	if props.BottomBorder {
+		pPr.WriteString(`<w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="auto"/></w:pBdr>`)
+	}
+
+	switch {
+	case numbered && s.Indent > 0:
+		fmt.Fprintf(&pPr, `<w:ind w:left="%d" w:hanging="360"/>`, s.Indent+listIndent(level))
+	case numbered:
+	case level >= 0:
+		fmt.Fprintf(&pPr, `<w:ind w:left="%d"/>`, s.Indent+listIndent(level))
+	case s.Indent > 0:
+		fmt.Fprintf(&pPr, `<w:ind w:left="%d"/>`, s.Indent)
+	}
+
+	s.Write("<w:p>")
+	if pPr.Len() > 0 {
+		s.Write("<w:pPr>" + pPr.String() + "</w:pPr>")
+	}
+	if content != nil {
+		content()
+	}
+	s.Write("</w:p>")
+}
+
+// listIndent returns the indentation of the given list level, as set in the numbering definitions.
+func listIndent(level int) int {
+	return 720 * (level + 1)
+}
+
+// RenderList writes the items of a list, numbered from `start` when ordered.
+func (s *SerializerState) RenderList(node prosemirror.Node, ordered bool, start int) {
+	if s.pendingNum {
+		// a list item starting with a list gets an empty numbered paragraph
+		s.Paragraph(ParagraphProps{}, nil)
+	}
+
+	s.nums = append(s.nums, num{ordered: ordered, start: start, level: min(len(s.lists), 8)})
+	s.lists = append(s.lists, listLevel{numID: len(s.nums)})
+	s.RenderContent(node)
+	s.lists = s.lists[:len(s.lists)-1]
+}
+
+// RenderListItem writes the content of a list item, numbering its first paragraph.
+func (s *SerializerState) RenderListItem(node prosemirror.Node) {
+	s.pendingNum = len(s.lists) > 0
+	s.RenderContent(node)
+	if s.pendingNum {
+		s.Paragraph(ParagraphProps{}, nil)
+	}
+}
+
+func (s *SerializerState) runProps(marks []prosemirror.Mark) RunProps {
+	var props RunProps
+	for _, m := range marks {
+		if set, ok := s.ser.Marks[m.Type.Name]; ok {
+			set(m, &props)
+		}
+	}
+
+	return props
+}
+
+// Run writes a run with the properties of the given marks, holding the given
+// raw WordprocessingML, like `<w:br/>`. Runs with a hyperlink are wrapped in one.
+func (s *SerializerState) Run(marks []prosemirror.Mark, content string) {
+	props := s.runProps(marks)
+	run := "<w:r>" + props.xml() + content + "</w:r>"
+
+	switch {
+	case props.Hyperlink == "":
+		s.Write(run)
+	case strings.HasPrefix(props.Hyperlink, "#"):
+		s.Write(fmt.Sprintf(`<w:hyperlink w:anchor="%s">%s</w:hyperlink>`, esc(props.Hyperlink[1:]), run))
+	default:
+		s.Write(fmt.Sprintf(`<w:hyperlink r:id="%s" w:history="1">%s</w:hyperlink>`, s.hyperlink(props.Hyperlink), run))
+	}
+}
+
+// Text writes a run of text with the given marks. Newlines become line breaks, and tabs tab characters.
+func (s *SerializerState) Text(text string, marks []prosemirror.Mark) {
+	if text == "" {
+		return
+	}
+
+	var sb strings.Builder
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			sb.WriteString("<w:br/>")
+		}
+
+		for j, part := range strings.Split(line, "\t") {
+			if j > 0 {
+				sb.WriteString("<w:tab/>")
+			}
+			if part != "" {
+				fmt.Fprintf(&sb, `<w:t xml:space="preserve">%s</w:t>`, esc(part))
+			}
+		}
+	}
+
+	s.Run(marks, sb.String())
+}
+
+func (s *SerializerState) addRel(typ, target string, external bool) string {
+	id := "rId" + strconv.Itoa(len(s.rels)+3) // rId1 and rId2 are the styles and numbering
+	s.rels = append(s.rels, relationship{id: id, typ: typ, target: target, external: external})
+	return id
+}
+
+func (s *SerializerState) hyperlink(url string) string {
+	if id, ok := s.links[url]; ok {
+		return id
+	}
+
+	if s.links == nil {
+		s.links = map[string]string{}
+	}
+
+	id := s.addRel(relHyperlink, url, true)
+	s.links[url] = id
+	return id
+}
+
+// maxImageWidth is the width of the page's text, in EMUs.
+const maxImageWidth = 5943600
+
+// Drawing adds an image to the document, and returns the WordprocessingML of
+// the drawing displaying it, to be written in a run.
+func (s *SerializerState) Drawing(img Image, alt string) (string, error) {
+	if img.ContentType == "" {
+		img.ContentType = http.DetectContentType(img.Data)
+	}
+
+	ext, ok := imageExtensions[img.ContentType]
+	if !ok {
+		return "", fmt.Errorf("unsupported image type %q", img.ContentType)
+	}
+
+	if img.Width == 0 || img.Height == 0 {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+		if err != nil {
+			return "", fmt.Errorf("failed to read image size: %w", err)
+		}
+
+		img.Width, img.Height = cfg.Width, cfg.Height
+	}
+
+	// 9525 EMUs per pixel, at 96 DPI
+	cx, cy := img.Width*9525, img.Height*9525
+	if cx > maxImageWidth {
+		cx, cy = maxImageWidth, cy*maxImageWidth/cx
+	}
+
+	n := len(s.media) + 1
+	name := fmt.Sprintf("image%d.%s", n, ext)
+	s.media = append(s.media, media{name: name, data: img.Data})
+	id := s.addRel(relImage, "media/"+name, false)
+
+	return fmt.Sprintf(drawingXML, cx, cy, n, esc(alt), name, id), nil
+}
+
+const drawingXML = `<w:drawing><wp:inline distT="0" distB="0" distL="0" distR="0">` +
+	`<wp:extent cx="%[1]d" cy="%[2]d"/><wp:docPr id="%[3]d" name="Picture %[3]d" descr="%[4]s"/>` +
+	`<wp:cNvGraphicFramePr><a:graphicFrameLocks noChangeAspect="1"/></wp:cNvGraphicFramePr>` +
+	`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">` +
+	`<pic:pic><pic:nvPicPr><pic:cNvPr id="%[3]d" name="%[5]s"/><pic:cNvPicPr/></pic:nvPicPr>` +
+	`<pic:blipFill><a:blip r:embed="%[6]s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>` +
+	`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%[1]d" cy="%[2]d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>` +
+	`</pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing>`
+
+var imageExtensions = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpeg",
+	"image/gif":  "gif",
+}
+
+func (s *SerializerState) writePackage(w io.Writer) error {
+	z := zip.NewWriter(w)
+
+	body := s.body.String()
+	if body == "" {
+		// Word expects at least one paragraph
+		body = "<w:p/>"
+	}
+
+	var rels strings.Builder
+	for _, r := range s.rels {
+		mode := ""
+		if r.external {
+			mode = ` TargetMode="External"`
+		}
+
+		fmt.Fprintf(&rels, `<Relationship Id="%s" Type="%s" Target="%s"%s/>`, r.id, r.typ, esc(r.target), mode)
+	}
+
+	parts := []struct {
+		name    string
+		content []byte
+	}{
+		{"[Content_Types].xml", []byte(contentTypesXML)},
+		{"_rels/.rels", []byte(packageRelsXML)},
+		{"word/document.xml", []byte(fmt.Sprintf(documentXML, body))},
+		{"word/styles.xml", []byte(stylesXML)},
+		{"word/numbering.xml", []byte(s.numberingXML())},
+		{"word/_rels/document.xml.rels", []byte(fmt.Sprintf(documentRelsXML, rels.String()))},
+	}
+	for _, m := range s.media {
+		parts = append(parts, struct {
+			name    string
+			content []byte
+		}{"word/media/" + m.name, m.data})
+	}
+
+	for _, part := range parts {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", part.name, err)
+		}
+
+		if _, err := f.Write(part.content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", part.name, err)
+		}
+	}
+
+	return z.Close()
+}
+
+func (s *SerializerState) numberingXML() string {
+	var sb strings.Builder
+	sb.WriteString(xmlHeader + `<w:numbering xmlns:w="` + nsW + `">`)
+
+	for id, ordered := range []bool{false, true} {
+		fmt.Fprintf(&sb, `<w:abstractNum w:abstractNumId="%d"><w:multiLevelType w:val="hybridMultilevel"/>`, id)
+		for l := 0; l < 9; l++ {
+			format, text := "bullet", []string{"•", "◦", "▪"}[l%3]
+			if ordered {
+				format, text = []string{"decimal", "lowerLetter", "lowerRoman"}[l%3], fmt.Sprintf("%%%d.", l+1)
+			}
+
+			fmt.Fprintf(&sb, `<w:lvl w:ilvl="%d"><w:start w:val="1"/><w:numFmt w:val="%s"/><w:lvlText w:val="%s"/>`+
+				`<w:lvlJc w:val="left"/><w:pPr><w:ind w:left="%d" w:hanging="360"/></w:pPr></w:lvl>`, l, format, text, listIndent(l))
+		}
+		sb.WriteString(`</w:abstractNum>`)
+	}
+
+	for i, n := range s.nums {
+		abstract := 0
+		if n.ordered {
+			abstract = 1
+		}
+
+		fmt.Fprintf(&sb, `<w:num w:numId="%d"><w:abstractNumId w:val="%d"/>`, i+1, abstract)
+		if n.ordered {
+			// restart the numbering of every list
+			fmt.Fprintf(&sb, `<w:lvlOverride w:ilvl="%d"><w:startOverride w:val="%d"/></w:lvlOverride>`, n.level, n.start)
+		}
+		sb.WriteString(`</w:num>`)
+	}
+
+	sb.WriteString(`</w:numbering>`)
+	return sb.String()
+}
+
+// esc escapes text for use in XML content and attribute values.
+func esc(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
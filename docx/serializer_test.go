@@ -0,0 +1,193 @@
+package docx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/builder"
+	"github.com/karitham/prosemirror/docx"
+	"github.com/stretchr/testify/assert"
+)
+
+// readPackage unzips a .docx file, checking that its XML parts are well formed.
+func readPackage(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := map[string]string{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = string(content)
+
+		if strings.HasSuffix(f.Name, ".xml") || strings.HasSuffix(f.Name, ".rels") {
+			dec := xml.NewDecoder(bytes.NewReader(content))
+			for {
+				if _, err := dec.Token(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("%s is not well formed: %v", f.Name, err)
+				}
+			}
+		}
+	}
+
+	return parts
+}
+
+// body returns the content of the document's body, without the section properties.
+func body(document string) string {
+	start := strings.Index(document, "<w:body>") + len("<w:body>")
+	return document[start:strings.Index(document, "<w:sectPr>")]
+}
+
+func TestSerializer(t *testing.T) {
+	s := builder.Schema()
+	b := builder.New(s)
+	doc, para, heading, blockquote, codeBlock, hr := b.Node("doc"), b.Node("paragraph"), b.Node("heading"), b.Node("blockquote"), b.Node("code_block"), b.Node("horizontal_rule")
+	br, imageNode, ul, ol, li := b.Node("hard_break"), b.Node("image"), b.Node("bullet_list"), b.Node("ordered_list"), b.Node("list_item")
+	em, strong, code := b.Mark("em"), b.Mark("strong"), b.Mark("code")
+	link := b.Mark("link").With(map[string]any{"href": "https://x.org/?a=1&b=2"})
+
+	tests := []struct {
+		name string
+		doc  builder.Node
+		want string
+	}{
+		{
+			name: "paragraphs and headings",
+			doc:  doc(heading(map[string]any{"level": 2}, "Title"), para(s.Text("a <b> & c"))),
+			want: `<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t xml:space="preserve">Title</w:t></w:r></w:p>` +
+				`<w:p><w:r><w:t xml:space="preserve">a &lt;b&gt; &amp; c</w:t></w:r></w:p>`,
+		},
+		{
+			name: "marks",
+			doc: doc(para(
+				strong(em("a")),
+				code("b"),
+				link("c"),
+				br(),
+				"d\te",
+			)),
+			want: `<w:p><w:r><w:rPr><w:b/><w:i/></w:rPr><w:t xml:space="preserve">a</w:t></w:r>` +
+				`<w:r><w:rPr><w:rFonts w:ascii="Courier New" w:hAnsi="Courier New" w:cs="Courier New"/></w:rPr><w:t xml:space="preserve">b</w:t></w:r>` +
+				`<w:hyperlink r:id="rId3" w:history="1"><w:r><w:rPr><w:rStyle w:val="Hyperlink"/></w:rPr><w:t xml:space="preserve">c</w:t></w:r></w:hyperlink>` +
+				`<w:r><w:br/></w:r>` +
+				`<w:r><w:t xml:space="preserve">d</w:t><w:tab/><w:t xml:space="preserve">e</w:t></w:r></w:p>`,
+		},
+		{
+			name: "blocks",
+			doc: doc(
+				blockquote(para("q")),
+				codeBlock("a\nb"),
+				hr(),
+			),
+			want: `<w:p><w:pPr><w:pStyle w:val="Quote"/><w:ind w:left="360"/></w:pPr><w:r><w:t xml:space="preserve">q</w:t></w:r></w:p>` +
+				`<w:p><w:pPr><w:pStyle w:val="Code"/></w:pPr><w:r><w:t xml:space="preserve">a</w:t><w:br/><w:t xml:space="preserve">b</w:t></w:r></w:p>` +
+				`<w:p><w:pPr><w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="auto"/></w:pBdr></w:pPr></w:p>`,
+		},
+		{
+			name: "lists",
+			doc: doc(
+				ul(
+					li(para("a"), para("more"), ol(map[string]any{"order": 3}, li(para("b")))),
+				),
+			),
+			want: `<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t xml:space="preserve">a</w:t></w:r></w:p>` +
+				`<w:p><w:pPr><w:ind w:left="720"/></w:pPr><w:r><w:t xml:space="preserve">more</w:t></w:r></w:p>` +
+				`<w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="2"/></w:numPr></w:pPr><w:r><w:t xml:space="preserve">b</w:t></w:r></w:p>`,
+		},
+		{
+			name: "images without loader",
+			doc:  doc(para(imageNode(map[string]any{"src": "a.png", "alt": "an image"}))),
+			want: `<w:p><w:r><w:t xml:space="preserve">an image</w:t></w:r></w:p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if !assert.NoError(t, docx.DefaultSerializer.Serialize(&buf, tt.doc.Node)) {
+				return
+			}
+
+			parts := readPackage(t, buf.Bytes())
+			assert.Equal(t, tt.want, body(parts["word/document.xml"]))
+		})
+	}
+
+	t.Run("package", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := docx.DefaultSerializer.Serialize(&buf, doc(
+			para(link("a"), link("b")),
+			ol(map[string]any{"order": 3}, li(para("c"))),
+		).Node)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		parts := readPackage(t, buf.Bytes())
+		for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/document.xml", "word/styles.xml", "word/numbering.xml", "word/_rels/document.xml.rels"} {
+			assert.Contains(t, parts, name)
+		}
+
+		rels := parts["word/_rels/document.xml.rels"]
+		assert.Equal(t, 1, strings.Count(rels, "relationships/hyperlink"))
+		assert.Contains(t, rels, `Target="https://x.org/?a=1&amp;b=2" TargetMode="External"`)
+		assert.Contains(t, parts["word/numbering.xml"], `<w:num w:numId="1"><w:abstractNumId w:val="1"/><w:lvlOverride w:ilvl="0"><w:startOverride w:val="3"/></w:lvlOverride></w:num>`)
+	})
+
+	t.Run("images", func(t *testing.T) {
+		var img bytes.Buffer
+		if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 2, 1))); err != nil {
+			t.Fatal(err)
+		}
+
+		ser := docx.DefaultSerializer
+		ser.Options.LoadImage = func(src string) (docx.Image, error) {
+			if src != "a.png" {
+				return docx.Image{}, errors.New("not found")
+			}
+
+			return docx.Image{Data: img.Bytes()}, nil
+		}
+
+		var buf bytes.Buffer
+		if !assert.NoError(t, ser.Serialize(&buf, doc(para(imageNode(map[string]any{"src": "a.png", "alt": "alt"}))).Node)) {
+			return
+		}
+
+		parts := readPackage(t, buf.Bytes())
+		assert.Equal(t, img.String(), parts["word/media/image1.png"])
+		assert.Contains(t, parts["word/_rels/document.xml.rels"], `Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/image1.png"`)
+		assert.Contains(t, parts["word/document.xml"], `<wp:extent cx="19050" cy="9525"/><wp:docPr id="1" name="Picture 1" descr="alt"/>`)
+		assert.Contains(t, parts["word/document.xml"], `<a:blip r:embed="rId3"/>`)
+
+		err := ser.Serialize(&buf, doc(para(imageNode(map[string]any{"src": "b.png"}))).Node)
+		assert.ErrorContains(t, err, "not found")
+	})
+
+	t.Run("unknown node", func(t *testing.T) {
+		ser := docx.NewSerializer(map[p.NodeTypeName]docx.NodeSerializer{}, nil, docx.SerializerOptions{})
+		assert.Error(t, ser.Serialize(io.Discard, doc(para("a")).Node))
+	})
+}
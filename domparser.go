@@ -0,0 +1,996 @@
+package prosemirror
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// WhitespaceMode controls how whitespace is parsed from HTML.
+type WhitespaceMode string
+
+const (
+	// WhitespaceInherit uses the mode of the surrounding content, or the whitespace
+	// option of the node type being parsed.
+	WhitespaceInherit WhitespaceMode = ""
+
+	// WhitespaceCollapse collapses whitespace like HTML rendering does.
+	WhitespaceCollapse WhitespaceMode = "collapse"
+
+	// WhitespacePreserve keeps whitespace, but turns newlines into spaces.
+	WhitespacePreserve WhitespaceMode = "preserve"
+
+	// WhitespaceFull keeps whitespace, including newlines.
+	WhitespaceFull WhitespaceMode = "full"
+)
+
+// ParseRule describes how to parse an HTML element or a style property into
+// a node or a mark. Rules are used by the DOMParser, and can be set on node and
+// mark specs, in which case Node or Mark defaults to the spec's type.
+type ParseRule struct {
+	// A CSS selector describing the elements this rule matches, like "p",
+	// "img[src]", "div.note" or "h1, h2". Supports tag names, classes, ids
+	// and attribute selectors.
+	Tag string
+
+	// A style property this rule matches, like "font-weight", optionally
+	// followed by `=` and the exact value to match, like "font-style=italic".
+	// Style rules can only create marks.
+	Style string
+
+	// The precedence of this rule. Rules with a higher priority are tried first. Defaults to 50.
+	Priority *int
+
+	// The name of the node type to create when this rule matches.
+	Node NodeTypeName
+
+	// The name of the mark type to wrap the matched content in.
+	Mark MarkTypeName
+
+	// When true, the matched content is ignored.
+	Ignore bool
+
+	// When true, the matched element itself is ignored, but its content is parsed.
+	Skip bool
+
+	// When true, finding an element that matches this rule closes the current node.
+	CloseParent bool
+
+	// Attributes for the created node or mark.
+	Attrs map[string]any
+
+	// Computes the attributes of the node or mark from the matched element.
+	// Returning false makes the rule not match. Only used by tag rules.
+	GetAttrs func(node *html.Node) (map[string]any, bool)
+
+	// Computes the attributes of the mark from the value of the matched style.
+	// Returning false makes the rule not match. Only used by style rules.
+	GetStyleAttrs func(value string) (map[string]any, bool)
+
+	// Removes the marks it returns true for from the active marks,
+	// instead of adding one. Only used by style rules.
+	ClearMark func(mark Mark) bool
+
+	// Controls whether whitespace is preserved when parsing the content of the matched node.
+	PreserveWhitespace WhitespaceMode
+}
+
+func (r ParseRule) priority() int {
+	if r.Priority == nil {
+		return 50
+	}
+
+	return *r.Priority
+}
+
+// ParseOptions are the options of a parse.
+type ParseOptions struct {
+	// How whitespace is parsed at the top level.
+	PreserveWhitespace WhitespaceMode
+
+	// The node to parse the content into. Defaults to a node of the schema's top node type.
+	TopNode *Node
+
+	// When true, the content of the top node isn't completed to make it valid.
+	TopOpen bool
+}
+
+// DOMParser parses HTML into documents, using a set of parse rules.
+type DOMParser struct {
+	// The schema the parser produces nodes for.
+	Schema Schema
+
+	// The parse rules, in order of precedence.
+	Rules []ParseRule
+
+	tags           []ParseRule
+	styles         []ParseRule
+	matchedStyles  []string
+	normalizeLists bool
+}
+
+// NewDOMParser creates a parser that uses the given rules, in order of precedence.
+func NewDOMParser(s Schema, rules []ParseRule) DOMParser {
+	p := DOMParser{Schema: s, Rules: rules}
+	for _, rule := range rules {
+		switch {
+		case rule.Tag != "":
+			p.tags = append(p.tags, rule)
+		case rule.Style != "":
+			prop, _, _ := strings.Cut(rule.Style, "=")
+			if !slices.Contains(p.matchedStyles, prop) {
+				p.matchedStyles = append(p.matchedStyles, prop)
+			}
+
+			p.styles = append(p.styles, rule)
+		}
+	}
+
+	// lists are only normalized when the schema doesn't allow lists directly in lists.
+	p.normalizeLists = !slices.ContainsFunc(p.tags, func(r ParseRule) bool {
+		if !listTagRe.MatchString(r.Tag) || r.Node == "" {
+			return false
+		}
+
+		typ, ok := s.Nodes[r.Node]
+		return ok && typ.ContentMatch.matchType(typ) != nil
+	})
+
+	return p
+}
+
+var listTagRe = regexp.MustCompile(`^(ul|ol)\b`)
+
+// DOMParserFromSchema creates a parser using the ParseDOM rules of the schema's
+// mark and node specs, ordered by priority.
+func DOMParserFromSchema(s Schema) DOMParser {
+	var rules []ParseRule
+	for _, mt := range s.MarkTypes() {
+		for _, rule := range mt.Spec.ParseDOM {
+			if rule.Mark == "" && !rule.Ignore && rule.ClearMark == nil {
+				rule.Mark = mt.Name
+			}
+
+			rules = append(rules, rule)
+		}
+	}
+
+	for _, nt := range s.NodeTypes() {
+		for _, rule := range nt.Spec.ParseDOM {
+			if rule.Node == "" && !rule.Ignore && rule.Mark == "" {
+				rule.Node = nt.Name
+			}
+
+			rules = append(rules, rule)
+		}
+	}
+
+	slices.SortStableFunc(rules, func(a, b ParseRule) int {
+		return b.priority() - a.priority()
+	})
+
+	return NewDOMParser(s, rules)
+}
+
+// Parse parses an HTML document or fragment into a document.
+func (p DOMParser) Parse(r io.Reader, opts ParseOptions) (Node, error) {
+	root, err := parseHTML(r)
+	if err != nil {
+		return Node{}, err
+	}
+
+	return p.ParseDOM(root, opts)
+}
+
+// ParseSlice parses an HTML fragment into a slice, which is open on the sides
+// where the content couldn't be completed.
+func (p DOMParser) ParseSlice(r io.Reader, opts ParseOptions) (Slice, error) {
+	root, err := parseHTML(r)
+	if err != nil {
+		return Slice{}, err
+	}
+
+	return p.ParseDOMSlice(root, opts)
+}
+
+// ParseDOM parses the children of an already parsed HTML element into a document.
+func (p DOMParser) ParseDOM(root *html.Node, opts ParseOptions) (Node, error) {
+	cx := newParseContext(p, opts, false)
+	if err := cx.addAll(root, nil); err != nil {
+		return Node{}, err
+	}
+
+	_, top := cx.finish()
+	return *top, nil
+}
+
+// ParseDOMSlice parses the children of an already parsed HTML element into a slice.
+func (p DOMParser) ParseDOMSlice(root *html.Node, opts ParseOptions) (Slice, error) {
+	cx := newParseContext(p, opts, true)
+	if err := cx.addAll(root, nil); err != nil {
+		return Slice{}, err
+	}
+
+	f, _ := cx.finish()
+	return MaxOpen(f, true), nil
+}
+
+// parseHTML parses an HTML fragment, returning a div holding the parsed nodes.
+func parseHTML(r io.Reader) (*html.Node, error) {
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(r, &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+
+	return root, nil
+}
+
+func (p DOMParser) matchTag(dom *html.Node) (ParseRule, bool) {
+	for _, rule := range p.tags {
+		if !matchesSelector(dom, rule.Tag) {
+			continue
+		}
+
+		if rule.GetAttrs != nil {
+			attrs, ok := rule.GetAttrs(dom)
+			if !ok {
+				continue
+			}
+
+			rule.Attrs = attrs
+		}
+
+		return rule, true
+	}
+
+	return ParseRule{}, false
+}
+
+func (p DOMParser) matchStyle(prop, value string) (ParseRule, bool) {
+	for _, rule := range p.styles {
+		name, want, hasValue := strings.Cut(rule.Style, "=")
+		if name != prop || hasValue && want != value {
+			continue
+		}
+
+		if rule.GetStyleAttrs != nil {
+			attrs, ok := rule.GetStyleAttrs(value)
+			if !ok {
+				continue
+			}
+
+			rule.Attrs = attrs
+		}
+
+		return rule, true
+	}
+
+	return ParseRule{}, false
+}
+
+// Whitespace options of a node context.
+const (
+	optPreserveWS = 1 << iota
+	optPreserveWSFull
+	optOpenLeft
+)
+
+func wsOptionsFor(typ *NodeType, mode WhitespaceMode, base int) int {
+	switch {
+	case mode == WhitespaceCollapse:
+		return 0
+	case mode == WhitespacePreserve:
+		return optPreserveWS
+	case mode == WhitespaceFull:
+		return optPreserveWS | optPreserveWSFull
+	case typ != nil && typ.Whitespace() == "pre":
+		return optPreserveWS | optPreserveWSFull
+	default:
+		return base &^ optOpenLeft
+	}
+}
+
+var (
+	blockTags = map[string]bool{
+		"address": true, "article": true, "aside": true, "blockquote": true, "canvas": true,
+		"dd": true, "div": true, "dl": true, "fieldset": true, "figcaption": true, "figure": true,
+		"footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+		"h6": true, "header": true, "hgroup": true, "hr": true, "li": true, "noscript": true,
+		"ol": true, "output": true, "p": true, "pre": true, "section": true, "table": true,
+		"tfoot": true, "ul": true,
+	}
+
+	ignoreTags = map[string]bool{
+		"head": true, "noscript": true, "object": true, "script": true, "style": true, "title": true,
+	}
+
+	listTags = map[string]bool{"ol": true, "ul": true}
+)
+
+func newNodeContext(typ *NodeType, attrs map[string]any, marks []Mark, solid bool, match *ContentMatch, options int) *nodeContext {
+	if match == nil && typ != nil && options&optOpenLeft == 0 {
+		m := typ.ContentMatch
+		match = &m
+	}
+
+	return &nodeContext{typ: typ, attrs: attrs, marks: marks, solid: solid, match: match, options: options}
+}
+
+// nodeContext is a node being built by the parser.
+type nodeContext struct {
+	// The type of the node, nil for the top of a slice.
+	typ     *NodeType
+	attrs   map[string]any
+	marks   []Mark
+	solid   bool
+	match   *ContentMatch
+	content []Node
+	options int
+}
+
+func (cx *nodeContext) findWrapping(node Node) []NodeType {
+	if cx.match == nil {
+		if cx.typ == nil {
+			return []NodeType{}
+		}
+
+		start := cx.typ.ContentMatch
+		if fill := start.FillBefore(NewFragment(node), false, 0); fill != nil {
+			cx.match = start.matchFragment(*fill, -1, -1)
+		} else if wrap := start.FindWrapping(node.Type); wrap != nil {
+			cx.match = &start
+			return wrap
+		} else {
+			return nil
+		}
+	}
+
+	return cx.match.FindWrapping(node.Type)
+}
+
+var trailingSpaceRe = regexp.MustCompile(`[ \t\r\n\f]+$`)
+
+func (cx *nodeContext) finishContent(openEnd bool) Fragment {
+	if cx.options&optPreserveWS == 0 {
+		// strip trailing whitespace
+		if n := len(cx.content); n > 0 && cx.content[n-1].IsText() {
+			last := cx.content[n-1]
+			if m := trailingSpaceRe.FindString(last.Text); m != "" {
+				if len(m) == len(last.Text) {
+					cx.content = cx.content[:n-1]
+				} else {
+					cx.content[n-1] = last.withText(last.Text[:len(last.Text)-len(m)])
+				}
+			}
+		}
+	}
+
+	// join adjacent text nodes with the same marks
+	var joined []Node
+	for _, n := range cx.content {
+		if last := len(joined) - 1; last >= 0 && n.IsText() && joined[last].IsText() && joined[last].SameMarkup(n) {
+			joined[last] = joined[last].withText(joined[last].Text + n.Text)
+			continue
+		}
+
+		joined = append(joined, n)
+	}
+
+	content := NewFragment(joined...)
+	if !openEnd && cx.match != nil {
+		if fill := cx.match.FillBefore(Fragment{}, true, 0); fill != nil {
+			content = content.Append(*fill)
+		}
+	}
+
+	return content
+}
+
+// finish creates the node. Its attributes are computed when it is entered.
+func (cx *nodeContext) finish(openEnd bool) Node {
+	attrs := cx.attrs
+	if attrs == nil {
+		attrs = maps.Clone(cx.typ.DefaultAttrs)
+	}
+
+	return Node{Type: *cx.typ, Attrs: attrs, Marks: cx.marks, Content: cx.finishContent(openEnd)}
+}
+
+func (cx *nodeContext) inlineContext(dom *html.Node) bool {
+	if cx.typ != nil {
+		return cx.typ.InlineContent
+	}
+
+	if len(cx.content) > 0 {
+		return cx.content[0].IsInline()
+	}
+
+	return dom.Parent != nil && !blockTags[dom.Parent.Data]
+}
+
+// parseContext holds the state of a parse.
+type parseContext struct {
+	parser          DOMParser
+	opts            ParseOptions
+	isOpen          bool
+	nodes           []*nodeContext
+	open            int
+	needsBlock      bool
+	localPreserveWS bool
+}
+
+func newParseContext(p DOMParser, opts ParseOptions, isOpen bool) *parseContext {
+	topOptions := wsOptionsFor(nil, opts.PreserveWhitespace, 0)
+	if isOpen {
+		topOptions |= optOpenLeft
+	}
+
+	var top *nodeContext
+	switch {
+	case opts.TopNode != nil:
+		typ := p.Schema.Nodes[opts.TopNode.Type.Name]
+		match := typ.ContentMatch
+		top = newNodeContext(&typ, opts.TopNode.Attrs, nil, true, &match, topOptions)
+	case isOpen:
+		top = newNodeContext(nil, nil, nil, true, nil, topOptions)
+	default:
+		top = newNodeContext(p.Schema.TopNodeType, nil, nil, true, nil, topOptions)
+	}
+
+	return &parseContext{parser: p, opts: opts, isOpen: isOpen, nodes: []*nodeContext{top}}
+}
+
+func (p *parseContext) top() *nodeContext {
+	return p.nodes[p.open]
+}
+
+func (p *parseContext) addDOM(dom *html.Node, marks []Mark) error {
+	switch dom.Type {
+	case html.TextNode:
+		return p.addTextNode(dom, marks)
+	case html.ElementNode:
+		return p.addElement(dom, marks)
+	}
+
+	return nil
+}
+
+var (
+	spaceRe        = regexp.MustCompile(`[ \t\r\n\f]+`)
+	nonSpaceRe     = regexp.MustCompile(`[^ \t\r\n\f]`)
+	newlineRe      = regexp.MustCompile(`\r?\n|\r`)
+	crlfRe         = regexp.MustCompile(`\r\n?`)
+	leadingSpaceRe = regexp.MustCompile(`^[ \t\r\n\f]`)
+	endSpaceRe     = regexp.MustCompile(`[ \t\r\n\f]$`)
+)
+
+func (p *parseContext) addTextNode(dom *html.Node, marks []Mark) error {
+	value, top := dom.Data, p.top()
+	full := top.options&optPreserveWSFull != 0
+	preserve := full || p.localPreserveWS || top.options&optPreserveWS != 0
+	if !full && !top.inlineContext(dom) && !nonSpaceRe.MatchString(value) {
+		return nil
+	}
+
+	switch {
+	case !preserve:
+		value = spaceRe.ReplaceAllString(value, " ")
+
+		// If this starts with whitespace, and there is no node before it, or
+		// a hard break, or a text node that ends with whitespace, strip the
+		// leading space.
+		if leadingSpaceRe.MatchString(value) && p.open == len(p.nodes)-1 {
+			var nodeBefore *Node
+			if n := len(top.content); n > 0 {
+				nodeBefore = &top.content[n-1]
+			}
+
+			domBefore := dom.PrevSibling
+			if nodeBefore == nil ||
+				domBefore != nil && domBefore.Type == html.ElementNode && domBefore.Data == "br" ||
+				nodeBefore.IsText() && endSpaceRe.MatchString(nodeBefore.Text) {
+				value = value[1:]
+			}
+		}
+	case !full:
+		value = newlineRe.ReplaceAllString(value, " ")
+	default:
+		value = crlfRe.ReplaceAllString(value, "\n")
+	}
+
+	if value == "" {
+		return nil
+	}
+
+	p.insertNode(p.parser.Schema.Text(value), marks)
+	return nil
+}
+
+func (p *parseContext) addElement(dom *html.Node, marks []Mark) error {
+	outerWS, top := p.localPreserveWS, p.top()
+	styles := parseStyles(dom)
+	if dom.Data == "pre" || strings.Contains(styles["white-space"], "pre") {
+		p.localPreserveWS = true
+	}
+
+	defer func() { p.localPreserveWS = outerWS }()
+
+	name := dom.Data
+	if listTags[name] && p.parser.normalizeLists {
+		normalizeList(dom)
+	}
+
+	rule, ok := p.parser.matchTag(dom)
+	switch {
+	case ok && rule.Ignore || !ok && ignoreTags[name]:
+		p.ignoreFallback(dom, marks)
+	case !ok || rule.Skip || rule.CloseParent:
+		if ok && rule.CloseParent {
+			p.open = max(0, p.open-1)
+		}
+
+		sync, oldNeedsBlock := false, p.needsBlock
+		if blockTags[name] {
+			if len(top.content) > 0 && top.content[0].IsInline() && p.open > 0 {
+				p.open--
+				top = p.top()
+			}
+
+			sync = true
+			if top.typ == nil {
+				p.needsBlock = true
+			}
+		} else if dom.FirstChild == nil {
+			return p.leafFallback(dom, marks)
+		}
+
+		innerMarks, keep := marks, true
+		if !ok || !rule.Skip {
+			innerMarks, keep = p.readStyles(styles, marks)
+		}
+
+		if keep {
+			if err := p.addAll(dom, innerMarks); err != nil {
+				return err
+			}
+		}
+
+		if sync {
+			p.sync(top)
+		}
+
+		p.needsBlock = oldNeedsBlock
+	default:
+		if innerMarks, keep := p.readStyles(styles, marks); keep {
+			return p.addElementByRule(dom, rule, innerMarks)
+		}
+	}
+
+	return nil
+}
+
+// leafFallback turns br elements into newlines in inline content.
+func (p *parseContext) leafFallback(dom *html.Node, marks []Mark) error {
+	if dom.Data == "br" && p.top().typ != nil && p.top().typ.InlineContent {
+		return p.addTextNode(&html.Node{Type: html.TextNode, Data: "\n"}, marks)
+	}
+
+	return nil
+}
+
+// ignoreFallback makes ignored br elements at least create an inline context.
+func (p *parseContext) ignoreFallback(dom *html.Node, marks []Mark) {
+	if dom.Data == "br" && (p.top().typ == nil || !p.top().typ.InlineContent) {
+		p.findPlace(p.parser.Schema.Text("-"), marks)
+	}
+}
+
+// readStyles applies the style rules matching the styles of an element to the given marks.
+// It returns false when the element should be ignored.
+func (p *parseContext) readStyles(styles map[string]string, marks []Mark) ([]Mark, bool) {
+	if len(styles) == 0 {
+		return marks, true
+	}
+
+	for _, name := range p.parser.matchedStyles {
+		value := styles[name]
+		if value == "" {
+			continue
+		}
+
+		rule, ok := p.parser.matchStyle(name, value)
+		switch {
+		case !ok:
+		case rule.Ignore:
+			return nil, false
+		case rule.ClearMark != nil:
+			marks = slices.DeleteFunc(slices.Clone(marks), rule.ClearMark)
+		default:
+			markType, ok := p.parser.Schema.Marks[rule.Mark]
+			if ok {
+				marks = append(slices.Clip(marks), markType.Create(rule.Attrs))
+			}
+		}
+	}
+
+	return marks, true
+}
+
+func (p *parseContext) addElementByRule(dom *html.Node, rule ParseRule, marks []Mark) error {
+	sync := false
+	if rule.Node != "" {
+		nodeType, ok := p.parser.Schema.Nodes[rule.Node]
+		if !ok {
+			return fmt.Errorf("parse rule for %q refers to unknown node type %q", rule.Tag, rule.Node)
+		}
+
+		if nodeType.IsLeaf() {
+			node, err := nodeType.Create(rule.Attrs, nil)
+			if err != nil {
+				return fmt.Errorf("failed to create %s node: %w", nodeType.Name, err)
+			}
+
+			if !p.insertNode(node, marks) {
+				return p.leafFallback(dom, marks)
+			}
+
+			return nil
+		}
+
+		attrs, err := nodeType.computeAttrs(rule.Attrs)
+		if err != nil {
+			return fmt.Errorf("failed to create %s node: %w", nodeType.Name, err)
+		}
+
+		if inner, ok := p.enter(nodeType, attrs, marks, rule.PreserveWhitespace); ok {
+			sync, marks = true, inner
+		}
+	} else {
+		markType, ok := p.parser.Schema.Marks[rule.Mark]
+		if !ok {
+			return fmt.Errorf("parse rule for %q refers to unknown mark type %q", rule.Tag, rule.Mark)
+		}
+
+		marks = append(slices.Clip(marks), markType.Create(rule.Attrs))
+	}
+
+	startIn := p.top()
+	if err := p.addAll(dom, marks); err != nil {
+		return err
+	}
+
+	if sync && p.sync(startIn) {
+		p.open--
+	}
+
+	return nil
+}
+
+func (p *parseContext) addAll(parent *html.Node, marks []Mark) error {
+	for dom := parent.FirstChild; dom != nil; dom = dom.NextSibling {
+		if err := p.addDOM(dom, marks); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findPlace tries to find a way to fit the given node into the current context,
+// opening the needed wrapper nodes. It returns the marks that are still to be applied.
+func (p *parseContext) findPlace(node Node, marks []Mark) ([]Mark, bool) {
+	var route []NodeType
+	var sync *nodeContext
+	for depth, penalty := p.open, 0; depth >= 0; depth-- {
+		cx := p.nodes[depth]
+		found := cx.findWrapping(node)
+		if found != nil && (route == nil || len(route) > len(found)+penalty) {
+			route, sync = found, cx
+			if len(found) == 0 {
+				break
+			}
+		}
+
+		if cx.solid {
+			penalty += 2
+		}
+	}
+
+	if route == nil {
+		return nil, false
+	}
+
+	p.sync(sync)
+	for _, typ := range route {
+		marks = p.enterInner(typ, nil, marks, false, WhitespaceInherit)
+	}
+
+	return marks, true
+}
+
+func (p *parseContext) insertNode(node Node, marks []Mark) bool {
+	if node.IsInline() && p.needsBlock && p.top().typ == nil {
+		if block := p.textblockFromContext(); block != nil {
+			marks = p.enterInner(*block, nil, marks, false, WhitespaceInherit)
+		}
+	}
+
+	innerMarks, ok := p.findPlace(node, marks)
+	if !ok {
+		return false
+	}
+
+	p.closeExtra(false)
+
+	top := p.top()
+	if top.match != nil {
+		top.match = top.match.matchType(node.Type)
+	}
+
+	var nodeMarks []Mark
+	for _, m := range append(slices.Clip(innerMarks), node.Marks...) {
+		if top.typ != nil && top.typ.AllowsMarkType(m.Type) || top.typ == nil && markMayApply(m.Type, node.Type) {
+			nodeMarks = m.AddToSet(nodeMarks)
+		}
+	}
+
+	top.content = append(top.content, node.Mark(nodeMarks))
+	return true
+}
+
+// enter tries to open a node of the given type, returning the marks that are still to be applied.
+func (p *parseContext) enter(typ NodeType, attrs map[string]any, marks []Mark, ws WhitespaceMode) ([]Mark, bool) {
+	if _, ok := p.findPlace(Node{Type: typ, Attrs: attrs}, marks); !ok {
+		return nil, false
+	}
+
+	return p.enterInner(typ, attrs, marks, true, ws), true
+}
+
+func (p *parseContext) enterInner(typ NodeType, attrs map[string]any, marks []Mark, solid bool, ws WhitespaceMode) []Mark {
+	p.closeExtra(false)
+
+	top := p.top()
+	if top.match != nil {
+		top.match = top.match.matchType(typ)
+	}
+
+	typ = p.parser.Schema.Nodes[typ.Name]
+	options := wsOptionsFor(&typ, ws, top.options)
+	if top.options&optOpenLeft != 0 && len(top.content) == 0 {
+		options |= optOpenLeft
+	}
+
+	var applyMarks, rest []Mark
+	for _, m := range marks {
+		if top.typ != nil && top.typ.AllowsMarkType(m.Type) || top.typ == nil && markMayApply(m.Type, typ) {
+			applyMarks = m.AddToSet(applyMarks)
+		} else {
+			rest = append(rest, m)
+		}
+	}
+
+	p.nodes = append(p.nodes, newNodeContext(&typ, attrs, applyMarks, solid, nil, options))
+	p.open++
+	return rest
+}
+
+// closeExtra closes the nodes above the open one, adding them to their parent.
+func (p *parseContext) closeExtra(openEnd bool) {
+	for i := len(p.nodes) - 1; i > p.open; i-- {
+		p.nodes[i-1].content = append(p.nodes[i-1].content, p.nodes[i].finish(openEnd))
+	}
+
+	p.nodes = p.nodes[:p.open+1]
+}
+
+// finish closes all the nodes, returning the content of the top one, and the top node itself when it has a type.
+func (p *parseContext) finish() (Fragment, *Node) {
+	p.open = 0
+	p.closeExtra(p.isOpen)
+
+	top := p.nodes[0]
+	openEnd := p.isOpen || p.opts.TopOpen
+	if top.typ == nil {
+		return top.finishContent(openEnd), nil
+	}
+
+	n := top.finish(openEnd)
+	return n.Content, &n
+}
+
+// sync makes the given context the open one, if it's in the stack.
+func (p *parseContext) sync(to *nodeContext) bool {
+	for i := p.open; i >= 0; i-- {
+		if p.nodes[i] == to {
+			p.open = i
+			return true
+		}
+
+		if p.localPreserveWS {
+			p.nodes[i].options |= optPreserveWS
+		}
+	}
+
+	return false
+}
+
+func (p *parseContext) textblockFromContext() *NodeType {
+	for _, typ := range p.parser.Schema.NodeTypes() {
+		if typ.IsTextblock() && !typ.hasRequiredAttrs() {
+			return &typ
+		}
+	}
+
+	return nil
+}
+
+// markMayApply reports whether a mark of the given type can be applied to
+// nodes of the given type in some parent.
+func markMayApply(markType MarkType, nodeType NodeType) bool {
+	for _, parent := range nodeType.Schema.NodeTypes() {
+		if !parent.AllowsMarkType(markType) {
+			continue
+		}
+
+		var seen []*ContentMatch
+		var scan func(match *ContentMatch) bool
+		scan = func(match *ContentMatch) bool {
+			seen = append(seen, match)
+			for _, edge := range match.Next {
+				if edge.Type.Name == nodeType.Name {
+					return true
+				}
+
+				if !slices.Contains(seen, edge.Next) && scan(edge.Next) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		match := parent.ContentMatch
+		if scan(&match) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeList moves lists directly inside other lists into the preceding item.
+func normalizeList(dom *html.Node) {
+	var prevItem *html.Node
+	for child := dom.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+
+		switch {
+		case listTags[child.Data] && prevItem != nil:
+			dom.RemoveChild(child)
+			prevItem.AppendChild(child)
+			child = prevItem
+		case child.Data == "li":
+			prevItem = child
+		default:
+			prevItem = nil
+		}
+	}
+}
+
+// parseStyles parses the style attribute of an element into its properties.
+func parseStyles(dom *html.Node) map[string]string {
+	var styles map[string]string
+	for _, a := range dom.Attr {
+		if a.Namespace != "" || a.Key != "style" {
+			continue
+		}
+
+		for _, decl := range strings.Split(a.Val, ";") {
+			prop, value, ok := strings.Cut(decl, ":")
+			if !ok {
+				continue
+			}
+
+			if styles == nil {
+				styles = map[string]string{}
+			}
+
+			value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+			styles[strings.ToLower(strings.TrimSpace(prop))] = value
+		}
+	}
+
+	return styles
+}
+
+// DOMAttr returns the value of an attribute of an element, and whether it is set.
+func DOMAttr(dom *html.Node, name string) (string, bool) {
+	for _, a := range dom.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val, true
+		}
+	}
+
+	return "", false
+}
+
+// DOMStyle returns the value of a style property of an element, or an empty string.
+func DOMStyle(dom *html.Node, prop string) string {
+	return parseStyles(dom)[prop]
+}
+
+// matchesSelector reports whether an element matches a selector made of a
+// comma-separated list of compound selectors, like "a[href]" or "div.note, p#intro".
+func matchesSelector(dom *html.Node, selector string) bool {
+	for _, sel := range strings.Split(selector, ",") {
+		if matchesCompound(dom, strings.TrimSpace(sel)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesCompound(dom *html.Node, sel string) bool {
+	end := strings.IndexAny(sel, ".#[")
+	if end == -1 {
+		end = len(sel)
+	}
+
+	if tag := sel[:end]; tag != "" && tag != "*" && !strings.EqualFold(tag, dom.Data) {
+		return false
+	}
+
+	for sel = sel[end:]; sel != ""; {
+		switch sel[0] {
+		case '.', '#':
+			end := strings.IndexAny(sel[1:], ".#[")
+			if end == -1 {
+				end = len(sel) - 1
+			}
+
+			name := sel[1 : end+1]
+			if sel[0] == '.' {
+				class, _ := DOMAttr(dom, "class")
+				if !slices.Contains(strings.Fields(class), name) {
+					return false
+				}
+			} else if id, _ := DOMAttr(dom, "id"); id != name {
+				return false
+			}
+
+			sel = sel[end+1:]
+		case '[':
+			end := strings.IndexByte(sel, ']')
+			if end == -1 {
+				return false
+			}
+
+			name, want, hasValue := strings.Cut(sel[1:end], "=")
+			value, ok := DOMAttr(dom, strings.ToLower(strings.TrimSpace(name)))
+			if !ok || hasValue && value != strings.Trim(strings.TrimSpace(want), `"'`) {
+				return false
+			}
+
+			sel = sel[end+1:]
+		default:
+			return false
+		}
+	}
+
+	return true
+}
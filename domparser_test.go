@@ -0,0 +1,217 @@
+package prosemirror
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestDOMParser(t *testing.T) {
+	s := Must(NewSchema(SchemaSpec{
+		Nodes: map[NodeTypeName]NodeSpec{
+			"doc":        {Content: "block+"},
+			"paragraph":  {Content: "inline*", Group: "block", ParseDOM: []ParseRule{{Tag: "p"}}},
+			"blockquote": {Content: "block+", Group: "block", ParseDOM: []ParseRule{{Tag: "blockquote"}}},
+			"heading": {
+				Content: "inline*",
+				Group:   "block",
+				Attrs:   map[string]Attribute{"level": {Default: 1}},
+				ParseDOM: []ParseRule{
+					{Tag: "h1", Attrs: map[string]any{"level": 1}},
+					{Tag: "h2", Attrs: map[string]any{"level": 2}},
+				},
+			},
+			"code_block": {
+				Content:  "text*",
+				Group:    "block",
+				Marks:    new(string),
+				Code:     true,
+				ParseDOM: []ParseRule{{Tag: "pre", PreserveWhitespace: WhitespaceFull}},
+			},
+			"image": {
+				Inline: true,
+				Group:  "inline",
+				Attrs:  map[string]Attribute{"src": {}},
+				ParseDOM: []ParseRule{{Tag: "img[src]", GetAttrs: func(n *html.Node) (map[string]any, bool) {
+					src, _ := DOMAttr(n, "src")
+					return map[string]any{"src": src}, true
+				}}},
+			},
+			"hard_break": {Inline: true, Group: "inline", ParseDOM: []ParseRule{{Tag: "br"}}},
+			"text":       {Group: "inline"},
+		},
+		Marks: map[MarkTypeName]MarkSpec{
+			"link": {
+				Attrs: map[string]Attribute{"href": {}},
+				ParseDOM: []ParseRule{{Tag: "a[href]", GetAttrs: func(n *html.Node) (map[string]any, bool) {
+					href, _ := DOMAttr(n, "href")
+					return map[string]any{"href": href}, true
+				}}},
+			},
+			"em": {ParseDOM: []ParseRule{{Tag: "i"}, {Tag: "em"}, {Style: "font-style=italic"}}},
+			"strong": {ParseDOM: []ParseRule{
+				{Tag: "strong"},
+				{Tag: "b"},
+				{Style: "font-weight=normal", ClearMark: func(m Mark) bool { return m.Type.Name == "strong" }},
+			}},
+		},
+		NodeOrder:    []NodeTypeName{"doc", "paragraph", "blockquote", "heading", "code_block", "image", "hard_break", "text"},
+		MarkOrder:    []MarkTypeName{"link", "em", "strong"},
+		TopNode:      "doc",
+		DontRegister: true,
+	}))
+
+	node := func(name NodeTypeName, attrs map[string]any, content ...Node) Node {
+		n, err := s.Nodes[name].CreateUnchecked(attrs, nil, content...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return n
+	}
+	doc := func(content ...Node) Node { return node("doc", nil, content...) }
+	p := func(content ...Node) Node { return node("paragraph", nil, content...) }
+	em, strong := s.Mark("em", nil), s.Mark("strong", nil)
+
+	tests := []struct {
+		name string
+		html string
+		want Node
+	}{
+		{
+			name: "paragraphs",
+			html: "<p>one</p><p>two</p>",
+			want: doc(p(s.Text("one")), p(s.Text("two"))),
+		},
+		{
+			name: "collapses whitespace",
+			html: "<p>  a \n\t b  </p>\n<p> c</p>",
+			want: doc(p(s.Text("a b")), p(s.Text("c"))),
+		},
+		{
+			name: "preserves whitespace in code",
+			html: "<pre>a\n  b\r\n</pre>",
+			want: doc(node("code_block", nil, s.Text("a\n  b\n"))),
+		},
+		{
+			name: "wraps loose inline content",
+			html: "hello <b>world</b>",
+			want: doc(p(s.Text("hello "), s.Text("world", strong))),
+		},
+		{
+			name: "nests marks",
+			html: `<p><a href="x"><i>a<strong>b</strong></i></a></p>`,
+			want: doc(p(
+				s.Text("a", s.Mark("link", map[string]any{"href": "x"}), em),
+				s.Text("b", s.Mark("link", map[string]any{"href": "x"}), em, strong),
+			)),
+		},
+		{
+			name: "style rules",
+			html: `<p><span style="font-style: italic">a</span><b><span style="font-weight:normal">b</span></b></p>`,
+			want: doc(p(s.Text("a", em), s.Text("b"))),
+		},
+		{
+			name: "attributes",
+			html: `<h2>title</h2><p><img src="a.png"><img></p>`,
+			want: doc(node("heading", map[string]any{"level": 2}, s.Text("title")), p(node("image", map[string]any{"src": "a.png"}))),
+		},
+		{
+			name: "ignores scripts and comments",
+			html: "<p>a<script>alert(1)</script><!-- hi -->b</p>",
+			want: doc(p(s.Text("ab"))),
+		},
+		{
+			name: "fits content",
+			html: "<blockquote>quoted</blockquote><h1>a<p>b</p></h1>",
+			want: doc(node("blockquote", nil, p(s.Text("quoted"))), node("heading", map[string]any{"level": 1}, s.Text("a")), p(s.Text("b"))),
+		},
+		{
+			name: "unknown block elements",
+			html: "<div><div>a</div>b</div><section>c</section>",
+			want: doc(p(s.Text("a")), p(s.Text("b")), p(s.Text("c"))),
+		},
+		{
+			name: "hard breaks",
+			html: "<p>a<br> b</p>",
+			want: doc(p(s.Text("a"), node("hard_break", nil), s.Text("b"))),
+		},
+		{
+			name: "empty",
+			html: "",
+			want: doc(p()),
+		},
+	}
+
+	parser := DOMParserFromSchema(s)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.Parse(strings.NewReader(tt.html), ParseOptions{})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.want.String(), got.String())
+
+			assert.True(t, tt.want.Eq(got), "attributes differ")
+			assert.NoError(t, got.Type.CheckContent(got.Content))
+		})
+	}
+
+	t.Run("slice", func(t *testing.T) {
+		got, err := parser.ParseSlice(strings.NewReader("foo <em>bar</em>"), ParseOptions{})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		want := NewSlice(NewFragment(s.Text("foo "), s.Text("bar", em)), 0, 0)
+		assert.Equal(t, want.String(), got.String())
+		assert.True(t, want.Eq(got), "attributes differ")
+	})
+
+	t.Run("open slice", func(t *testing.T) {
+		got, err := parser.ParseSlice(strings.NewReader("<blockquote><p>a</p></blockquote><p>b</p>"), ParseOptions{})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		want := NewSlice(NewFragment(node("blockquote", nil, p(s.Text("a"))), p(s.Text("b"))), 2, 1)
+		assert.Equal(t, want.String(), got.String())
+		assert.True(t, want.Eq(got), "attributes differ")
+	})
+
+	t.Run("preserve whitespace option", func(t *testing.T) {
+		got, err := parser.Parse(strings.NewReader("<p>a  b\nc</p>"), ParseOptions{PreserveWhitespace: WhitespacePreserve})
+		if assert.NoError(t, err) {
+			assert.Equal(t, doc(p(s.Text("a  b c"))).String(), got.String())
+		}
+	})
+}
+
+func TestMatchesSelector(t *testing.T) {
+	dom := &html.Node{Type: html.ElementNode, Data: "a", Attr: []html.Attribute{
+		{Key: "href", Val: "x"},
+		{Key: "class", Val: "one two"},
+		{Key: "id", Val: "main"},
+	}}
+
+	for sel, want := range map[string]bool{
+		"a":                  true,
+		"A":                  true,
+		"p":                  false,
+		"a[href]":            true,
+		"a[title]":           false,
+		`a[href="x"]`:        true,
+		"a[href=y]":          false,
+		"a.two":              true,
+		".one.two#main":      true,
+		"a.three":            false,
+		"p, a#main":          true,
+		"*[href].one#other":  false,
+		"img[src], a[href] ": true,
+	} {
+		assert.Equal(t, want, matchesSelector(dom, sel), sel)
+	}
+}
@@ -0,0 +1,322 @@
+package prosemirror
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DOMOutputSpec is a description of a DOM structure, used by the ToDOM hooks
+// of node and mark specs. It is either an element, a text node, or the hole
+// where the content of the node or mark goes.
+type DOMOutputSpec struct {
+	// The tag name of the element. When empty, the spec is a text node.
+	Tag string
+
+	// The attributes of the element.
+	Attrs map[string]string
+
+	// The children of the element. At most one spec in the whole structure
+	// may be a hole, and it must be the only child of its parent.
+	Children []DOMOutputSpec
+
+	// The content of a text node.
+	Text string
+
+	// Whether this spec is the content hole.
+	Hole bool
+}
+
+// DOMHole is the spec marking where the content of a node or mark is placed.
+var DOMHole = DOMOutputSpec{Hole: true}
+
+// DOMElement creates the spec of an element.
+func DOMElement(tag string, attrs map[string]string, children ...DOMOutputSpec) DOMOutputSpec {
+	return DOMOutputSpec{Tag: tag, Attrs: attrs, Children: children}
+}
+
+// DOMText creates the spec of a text node.
+func DOMText(text string) DOMOutputSpec {
+	return DOMOutputSpec{Text: text}
+}
+
+// voidElements are the elements rendered without a closing tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// domNameRe matches the tag and attribute names allowed in DOM specs, which are
+// written to the HTML as they are.
+var domNameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*$`)
+
+// renderedSpec is a DOM spec rendered to HTML, split around its content hole.
+type renderedSpec struct {
+	open, close string
+	hole        bool
+}
+
+// renderSpec renders a DOM spec, returning the HTML before and after its content hole.
+func renderSpec(spec DOMOutputSpec) (renderedSpec, error) {
+	var r renderedSpec
+	var sb strings.Builder
+	if err := renderSpecTo(&sb, spec, &r, true); err != nil {
+		return renderedSpec{}, err
+	}
+
+	if r.hole {
+		r.close = sb.String()
+	} else {
+		r.open = sb.String()
+	}
+
+	return r, nil
+}
+
+func renderSpecTo(sb *strings.Builder, spec DOMOutputSpec, r *renderedSpec, top bool) error {
+	switch {
+	case spec.Hole:
+		if top {
+			return errors.New("content hole can't be the top of a DOM spec")
+		}
+
+		if r.hole {
+			return errors.New("DOM spec has more than one content hole")
+		}
+
+		r.hole = true
+		r.open = sb.String()
+		sb.Reset()
+		return nil
+	case spec.Tag == "":
+		sb.WriteString(html.EscapeString(spec.Text))
+		return nil
+	}
+
+	if !domNameRe.MatchString(spec.Tag) {
+		return fmt.Errorf("invalid tag name %q in DOM spec", spec.Tag)
+	}
+
+	sb.WriteByte('<')
+	sb.WriteString(spec.Tag)
+	keys := make([]string, 0, len(spec.Attrs))
+	for k := range spec.Attrs {
+		if !domNameRe.MatchString(k) {
+			return fmt.Errorf("invalid attribute name %q in DOM spec", k)
+		}
+
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+	for _, k := range keys {
+		sb.WriteByte(' ')
+		sb.WriteString(k)
+		sb.WriteString(`="`)
+		sb.WriteString(html.EscapeString(spec.Attrs[k]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('>')
+
+	if voidElements[spec.Tag] {
+		if len(spec.Children) > 0 {
+			return fmt.Errorf("%s elements can't have content", spec.Tag)
+		}
+
+		return nil
+	}
+
+	for _, child := range spec.Children {
+		if child.Hole && len(spec.Children) > 1 {
+			return errors.New("content hole must be the only child of its parent node")
+		}
+
+		if err := renderSpecTo(sb, child, r, false); err != nil {
+			return err
+		}
+	}
+
+	sb.WriteString("</")
+	sb.WriteString(spec.Tag)
+	sb.WriteByte('>')
+	return nil
+}
+
+// DOMSerializer describes how to serialize a document to HTML.
+// Nodes are rendered with the function for their type name, and marks likewise.
+type DOMSerializer struct {
+	// The node serialization functions.
+	Nodes map[NodeTypeName]func(node Node) DOMOutputSpec
+
+	// The mark serialization functions.
+	Marks map[MarkTypeName]func(mark Mark, inline bool) DOMOutputSpec
+}
+
+// DOMSerializerFromSchema builds a serializer using the ToDOM hooks of the schema's node and mark specs.
+// Text nodes are rendered as plain text unless their spec says otherwise.
+func DOMSerializerFromSchema(s Schema) DOMSerializer {
+	ser := DOMSerializer{
+		Nodes: map[NodeTypeName]func(Node) DOMOutputSpec{},
+		Marks: map[MarkTypeName]func(Mark, bool) DOMOutputSpec{},
+	}
+
+	for name, typ := range s.Nodes {
+		if typ.Spec.ToDOM != nil {
+			ser.Nodes[name] = typ.Spec.ToDOM
+		}
+	}
+
+	if _, ok := ser.Nodes["text"]; !ok {
+		ser.Nodes["text"] = func(n Node) DOMOutputSpec { return DOMText(n.Text) }
+	}
+
+	for name, typ := range s.Marks {
+		if typ.Spec.ToDOM != nil {
+			ser.Marks[name] = typ.Spec.ToDOM
+		}
+	}
+
+	return ser
+}
+
+// SerializeFragment writes the HTML of the content of a fragment to w.
+// Adjacent nodes sharing marks are rendered inside the same mark elements.
+func (s DOMSerializer) SerializeFragment(w io.Writer, f Fragment) error {
+	var sb strings.Builder
+	if err := s.serializeFragment(&sb, f); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// SerializeNode writes the HTML of a node to w, including its marks.
+func (s DOMSerializer) SerializeNode(w io.Writer, n Node) error {
+	var sb strings.Builder
+
+	var closing []string
+	for _, m := range n.Marks {
+		r, ok, err := s.serializeMark(m, n.IsInline())
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			sb.WriteString(r.open)
+			closing = append(closing, r.close)
+		}
+	}
+
+	if err := s.serializeNodeInner(&sb, n); err != nil {
+		return err
+	}
+
+	for i := len(closing) - 1; i >= 0; i-- {
+		sb.WriteString(closing[i])
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (s DOMSerializer) serializeFragment(sb *strings.Builder, f Fragment) error {
+	type activeMark struct {
+		mark  Mark
+		close string
+	}
+
+	var active []activeMark
+	for _, node := range f.Content {
+		if len(active) > 0 || len(node.Marks) > 0 {
+			keep, rendered := 0, 0
+			for keep < len(active) && rendered < len(node.Marks) {
+				next := node.Marks[rendered]
+				if _, ok := s.Marks[next.Type.Name]; !ok {
+					rendered++
+					continue
+				}
+
+				if !next.Eq(active[keep].mark) || !next.Type.IsSpanning() {
+					break
+				}
+
+				keep++
+				rendered++
+			}
+
+			for keep < len(active) {
+				sb.WriteString(active[len(active)-1].close)
+				active = active[:len(active)-1]
+			}
+
+			for ; rendered < len(node.Marks); rendered++ {
+				add := node.Marks[rendered]
+				r, ok, err := s.serializeMark(add, node.IsInline())
+				if err != nil {
+					return err
+				}
+
+				if ok {
+					sb.WriteString(r.open)
+					active = append(active, activeMark{mark: add, close: r.close})
+				}
+			}
+		}
+
+		if err := s.serializeNodeInner(sb, node); err != nil {
+			return err
+		}
+	}
+
+	for i := len(active) - 1; i >= 0; i-- {
+		sb.WriteString(active[i].close)
+	}
+
+	return nil
+}
+
+func (s DOMSerializer) serializeNodeInner(sb *strings.Builder, n Node) error {
+	toDOM, ok := s.Nodes[n.Type.Name]
+	if !ok {
+		return fmt.Errorf("no DOM serializer for node type %q", n.Type.Name)
+	}
+
+	r, err := renderSpec(toDOM(n))
+	if err != nil {
+		return fmt.Errorf("failed to render node type %q: %w", n.Type.Name, err)
+	}
+
+	sb.WriteString(r.open)
+	if r.hole {
+		if n.IsLeaf() {
+			return fmt.Errorf("content hole not allowed in the spec of leaf node type %q", n.Type.Name)
+		}
+
+		if err := s.serializeFragment(sb, n.Content); err != nil {
+			return err
+		}
+	}
+
+	sb.WriteString(r.close)
+	return nil
+}
+
+// serializeMark renders a mark, returning false when it has no serializer.
+func (s DOMSerializer) serializeMark(m Mark, inline bool) (renderedSpec, bool, error) {
+	toDOM, ok := s.Marks[m.Type.Name]
+	if !ok {
+		return renderedSpec{}, false, nil
+	}
+
+	r, err := renderSpec(toDOM(m, inline))
+	if err != nil {
+		return renderedSpec{}, false, fmt.Errorf("failed to render mark type %q: %w", m.Type.Name, err)
+	}
+
+	return r, true, nil
+}
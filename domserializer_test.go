@@ -0,0 +1,152 @@
+package prosemirror
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDOMSerializer(t *testing.T) {
+	hole := func(tag string) func(Node) DOMOutputSpec {
+		return func(Node) DOMOutputSpec { return DOMElement(tag, nil, DOMHole) }
+	}
+	markHole := func(tag string) func(Mark, bool) DOMOutputSpec {
+		return func(Mark, bool) DOMOutputSpec { return DOMElement(tag, nil, DOMHole) }
+	}
+
+	s := Must(NewSchema(SchemaSpec{
+		Nodes: map[NodeTypeName]NodeSpec{
+			"doc":       {Content: "block+"},
+			"paragraph": {Content: "inline*", Group: "block", ToDOM: hole("p")},
+			"code_block": {
+				Content: "text*",
+				Group:   "block",
+				Marks:   new(string),
+				ToDOM: func(Node) DOMOutputSpec {
+					return DOMElement("pre", nil, DOMElement("code", nil, DOMHole))
+				},
+			},
+			"hard_break": {Inline: true, Group: "inline", ToDOM: func(Node) DOMOutputSpec { return DOMElement("br", nil) }},
+			"text":       {Group: "inline"},
+		},
+		Marks: map[MarkTypeName]MarkSpec{
+			"link": {
+				Attrs: map[string]Attribute{"href": {}},
+				ToDOM: func(m Mark, _ bool) DOMOutputSpec {
+					return DOMElement("a", map[string]string{"href": m.Attrs["href"].(string)}, DOMHole)
+				},
+			},
+			"em":      {ToDOM: markHole("em")},
+			"strong":  {ToDOM: markHole("strong")},
+			"code":    {ToDOM: markHole("code"), Spanning: new(bool)},
+			"comment": {},
+		},
+		MarkOrder:    []MarkTypeName{"link", "em", "strong", "code", "comment"},
+		TopNode:      "doc",
+		DontRegister: true,
+	}))
+
+	em, strong := s.Mark("em", nil), s.Mark("strong", nil)
+	link := func(href string) Mark { return s.Mark("link", map[string]any{"href": href}) }
+	code, comment := s.Mark("code", nil), s.Mark("comment", nil)
+	p := func(content ...Node) Node { return s.Node("paragraph", nil, NewFragment(content...)) }
+
+	tests := []struct {
+		name string
+		doc  Node
+		want string
+	}{
+		{
+			name: "blocks",
+			doc:  s.Node("doc", nil, NewFragment(p(s.Text("one")), s.Node("code_block", nil, NewFragment(s.Text("two"))))),
+			want: "<p>one</p><pre><code>two</code></pre>",
+		},
+		{
+			name: "escapes text",
+			doc:  s.Node("doc", nil, NewFragment(p(s.Text("a < b & c")))),
+			want: "<p>a &lt; b &amp; c</p>",
+		},
+		{
+			name: "escapes attributes",
+			doc:  s.Node("doc", nil, NewFragment(p(s.Text("x", link(`/?a="b"&c`))))),
+			want: `<p><a href="/?a=&#34;b&#34;&amp;c">x</a></p>`,
+		},
+		{
+			name: "void elements",
+			doc:  s.Node("doc", nil, NewFragment(p(s.Text("a"), s.Node("hard_break", nil, Fragment{}), s.Text("b")))),
+			want: "<p>a<br>b</p>",
+		},
+		{
+			name: "nests marks",
+			doc:  s.Node("doc", nil, NewFragment(p(s.Text("a", em), s.Text("b", em, strong), s.Text("c", strong)))),
+			want: "<p><em>a<strong>b</strong></em><strong>c</strong></p>",
+		},
+		{
+			name: "joins adjacent marks across nodes",
+			doc:  s.Node("doc", nil, NewFragment(p(s.Text("a", link("x"), em), s.Node("hard_break", nil, Fragment{}, link("x")), s.Text("b", link("x"))))),
+			want: `<p><a href="x"><em>a</em><br>b</a></p>`,
+		},
+		{
+			name: "splits marks that don't span nodes",
+			doc:  s.Node("doc", nil, NewFragment(p(s.Text("a", code), s.Node("hard_break", nil, Fragment{}, code), s.Text("b", em, code)))),
+			want: "<p><code>a</code><code><br></code><em><code>b</code></em></p>",
+		},
+		{
+			name: "splits marks with different attributes",
+			doc:  s.Node("doc", nil, NewFragment(p(s.Text("a", link("x")), s.Text("b", link("y"))))),
+			want: `<p><a href="x">a</a><a href="y">b</a></p>`,
+		},
+		{
+			name: "skips marks without serializer",
+			doc:  s.Node("doc", nil, NewFragment(p(s.Text("a", em), s.Text("b", em, comment), s.Text("c", comment)))),
+			want: "<p><em>ab</em>c</p>",
+		},
+	}
+
+	ser := DOMSerializerFromSchema(s)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			if !assert.NoError(t, ser.SerializeFragment(&sb, tt.doc.Content)) {
+				return
+			}
+
+			assert.Equal(t, tt.want, sb.String())
+		})
+	}
+
+	t.Run("node with marks", func(t *testing.T) {
+		var sb strings.Builder
+		if assert.NoError(t, ser.SerializeNode(&sb, s.Text("a", em, strong))) {
+			assert.Equal(t, "<em><strong>a</strong></em>", sb.String())
+		}
+	})
+
+	t.Run("node without serializer", func(t *testing.T) {
+		assert.Error(t, ser.SerializeNode(&strings.Builder{}, s.Node("doc", nil, NewFragment(p()))))
+	})
+
+	t.Run("invalid holes", func(t *testing.T) {
+		for _, spec := range []DOMOutputSpec{
+			DOMHole,
+			DOMElement("p", nil, DOMHole, DOMText("a")),
+			DOMElement("p", nil, DOMElement("b", nil, DOMHole), DOMElement("i", nil, DOMElement("u", nil, DOMHole))),
+		} {
+			_, err := renderSpec(spec)
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("invalid names", func(t *testing.T) {
+		for _, spec := range []DOMOutputSpec{
+			DOMElement("h1 onmouseover=alert(1) x", nil, DOMHole),
+			DOMElement("p><script", nil),
+			DOMElement("a", map[string]string{`href="x" onclick`: "alert(1)"}, DOMHole),
+			DOMElement("p", nil, DOMElement("1b", nil)),
+		} {
+			_, err := renderSpec(spec)
+			assert.Error(t, err)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+// Package export writes schema specs for other ProseMirror implementations,
+// as a TypeScript module creating a prosemirror-model Schema or as a JSON spec,
+// and loads JSON specs back, so a schema can be defined once for the frontend
+// and the backend.
+//
+// Only the serializable parts of specs are exported: content expressions,
+// groups, marks, flags, attribute defaults and extra properties. Functions like
+// ToDOM and ParseDOM have no JSON form, and are taken from a Go spec by Load.
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+
+	p "github.com/karitham/prosemirror"
+)
+
+// TypeScriptOptions configures the exported TypeScript module.
+type TypeScriptOptions struct {
+	// The name of the exported schema constant. Defaults to "schema".
+	Name string
+
+	// The module Schema is imported from. Defaults to "prosemirror-model".
+	Module string
+}
+
+// JSON returns the spec as indented JSON, in the shape of a prosemirror-model
+// SchemaSpec, with the node and mark types in schema order.
+func JSON(spec p.SchemaSpec) ([]byte, error) {
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("error writing spec: %w", err)
+	}
+
+	v := jsontext.Value(b)
+	if err := v.Indent("", "  "); err != nil {
+		return nil, err
+	}
+
+	return append(v, '\n'), nil
+}
+
+// TypeScript returns a TypeScript module exporting the schema of the spec:
+//
+//	import { Schema } from "prosemirror-model";
+//
+//	export const schema = new Schema({
+//	  topNode: "doc",
+//	  nodes: { ... },
+//	  marks: { ... },
+//	});
+func TypeScript(spec p.SchemaSpec, opts TypeScriptOptions) ([]byte, error) {
+	if opts.Name == "" {
+		opts.Name = "schema"
+	}
+
+	if opts.Module == "" {
+		opts.Module = "prosemirror-model"
+	}
+
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("error writing spec: %w", err)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by prosemirror/export. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "import { Schema } from %s;\n\n", quote(opts.Module))
+	fmt.Fprintf(&out, "export const %s = new Schema(", opts.Name)
+	if err := writeTS(&out, jsontext.NewDecoder(bytes.NewReader(b)), ""); err != nil {
+		return nil, err
+	}
+	out.WriteString(");\n")
+
+	return out.Bytes(), nil
+}
+
+// Load reads a JSON spec, as written by JSON. The functions of the node and
+// mark specs, which JSON can't hold, are taken from the types of the same name
+// in base, which may be empty.
+func Load(r io.Reader, base p.SchemaSpec) (p.SchemaSpec, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return p.SchemaSpec{}, err
+	}
+
+	var spec p.SchemaSpec
+	if err := json.Unmarshal(b, &spec); err != nil {
+		return p.SchemaSpec{}, fmt.Errorf("error reading spec: %w", err)
+	}
+
+	for name, node := range spec.Nodes {
+		if from, ok := base.Nodes[name]; ok {
+			node.ToDOM = from.ToDOM
+			node.ParseDOM = from.ParseDOM
+			node.LeafText = from.LeafText
+			node.ToDebugString = from.ToDebugString
+			spec.Nodes[name] = node
+		}
+	}
+
+	for name, mark := range spec.Marks {
+		if from, ok := base.Marks[name]; ok {
+			mark.ToDOM = from.ToDOM
+			mark.ParseDOM = from.ParseDOM
+			spec.Marks[name] = mark
+		}
+	}
+
+	return spec, nil
+}
+
+// writeTS writes the next JSON value of dec as a TypeScript expression,
+// indented by two spaces per level, with identifier keys left unquoted.
+func writeTS(w *bytes.Buffer, dec *jsontext.Decoder, indent string) error {
+	kind := dec.PeekKind()
+	if kind != '{' && kind != '[' {
+		v, err := dec.ReadValue()
+		if err != nil {
+			return err
+		}
+
+		w.Write(v)
+		return nil
+	}
+
+	if _, err := dec.ReadToken(); err != nil {
+		return err
+	}
+
+	end := byte('}')
+	if kind == '[' {
+		end = ']'
+	}
+
+	if dec.PeekKind() == jsontext.Kind(end) {
+		w.WriteByte(byte(kind))
+		w.WriteByte(end)
+		_, err := dec.ReadToken()
+		return err
+	}
+
+	w.WriteByte(byte(kind))
+	w.WriteByte('\n')
+	for dec.PeekKind() != jsontext.Kind(end) {
+		w.WriteString(indent + "  ")
+		if kind == '{' {
+			name, err := dec.ReadToken()
+			if err != nil {
+				return err
+			}
+
+			w.WriteString(key(name.String()) + ": ")
+		}
+
+		if err := writeTS(w, dec, indent+"  "); err != nil {
+			return err
+		}
+		w.WriteString(",\n")
+	}
+
+	w.WriteString(indent)
+	w.WriteByte(end)
+	_, err := dec.ReadToken()
+	return err
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// key returns an object key, quoted unless it is an identifier.
+func key(name string) string {
+	if identifier.MatchString(name) {
+		return name
+	}
+
+	return quote(name)
+}
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
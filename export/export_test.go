@@ -0,0 +1,140 @@
+package export_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/export"
+	"github.com/karitham/prosemirror/schema"
+)
+
+var spec = p.SchemaSpec{
+	Nodes: map[p.NodeTypeName]p.NodeSpec{
+		"doc":       {Content: "block+"},
+		"paragraph": {Content: "inline*", Group: "block"},
+		"code_block": {Content: "text*", Group: "block", Marks: new(string), Code: true, Attrs: map[string]p.Attribute{
+			"language": {Optional: true},
+		}},
+		"table_cell": {Content: "paragraph+", Extra: map[string]any{"tableRole": "cell"}, Attrs: map[string]p.Attribute{
+			"colspan":    {Default: 1},
+			"data-align": {Default: "left"},
+		}},
+		"text":  {Group: "inline"},
+		"image": {Inline: true, Atom: true, Group: "inline", Attrs: map[string]p.Attribute{"src": {}}},
+	},
+	Marks: map[p.MarkTypeName]p.MarkSpec{
+		"em":   {},
+		"link": {Attrs: map[string]p.Attribute{"href": {}}, Inclusive: new(bool)},
+	},
+	TopNode:   "doc",
+	NodeOrder: []p.NodeTypeName{"doc", "paragraph", "code_block", "table_cell", "text", "image"},
+	MarkOrder: []p.MarkTypeName{"link", "em"},
+}
+
+func TestTypeScript(t *testing.T) {
+	got, err := export.TypeScript(spec, export.TypeScriptOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, `// Code generated by prosemirror/export. DO NOT EDIT.
+
+import { Schema } from "prosemirror-model";
+
+export const schema = new Schema({
+  topNode: "doc",
+  nodes: {
+    doc: {
+      content: "block+",
+    },
+    paragraph: {
+      content: "inline*",
+      group: "block",
+    },
+    code_block: {
+      content: "text*",
+      marks: "",
+      group: "block",
+      attrs: {
+        language: {
+          default: null,
+        },
+      },
+      code: true,
+    },
+    table_cell: {
+      content: "paragraph+",
+      attrs: {
+        colspan: {
+          default: 1,
+        },
+        "data-align": {
+          default: "left",
+        },
+      },
+      tableRole: "cell",
+    },
+    text: {
+      group: "inline",
+    },
+    image: {
+      group: "inline",
+      inline: true,
+      atom: true,
+      attrs: {
+        src: {},
+      },
+    },
+  },
+  marks: {
+    link: {
+      attrs: {
+        href: {},
+      },
+      inclusive: false,
+    },
+    em: {},
+  },
+});
+`, string(got))
+}
+
+func TestLoad(t *testing.T) {
+	b, err := export.JSON(spec)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	got, err := export.Load(bytes.NewReader(b), p.SchemaSpec{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, spec, got, "the JSON spec loads back")
+
+	// functions come from the base spec
+	b, err = export.JSON(schema.DefaultSpec)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	got, err = export.Load(bytes.NewReader(b), schema.DefaultSpec)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, schema.DefaultSpec.NodeOrder, got.NodeOrder)
+	assert.NotNil(t, got.Nodes["paragraph"].ToDOM)
+	assert.NotNil(t, got.Marks["link"].ParseDOM)
+
+	s, err := p.NewSchema(got)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, s.Nodes["heading"].DefaultAttrs["level"])
+	}
+
+	_, err = export.Load(bytes.NewReader([]byte(`{"nodes": 1}`)), p.SchemaSpec{})
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package prosemirror
 import (
 	"fmt"
 	"slices"
+	"strings"
 
 	"github.com/go-json-experiment/json"
 )
@@ -15,8 +16,18 @@ type Fragment struct {
 	Content []Node
 }
 
+// String returns a debug representation of the fragment, like <paragraph("a"), horizontal_rule>.
 func (f Fragment) String() string {
-	return fmt.Sprintf("Fragment{Size: %d, Content: %v}", f.Size, f.Content)
+	return "<" + f.stringInner() + ">"
+}
+
+func (f Fragment) stringInner() string {
+	parts := make([]string, len(f.Content))
+	for i, n := range f.Content {
+		parts[i] = n.String()
+	}
+
+	return strings.Join(parts, ", ")
 }
 
 func (f Fragment) MarshalJSON() ([]byte, error) {
@@ -50,9 +61,11 @@ func NewFragment(nodes ...Node) Fragment {
 	}
 }
 
-func (f Fragment) replaceChild(index int, n Node) Fragment {
+// ReplaceChild creates a new fragment in which the node at the given index
+// is replaced by the given node.
+func (f Fragment) ReplaceChild(index int, n Node) Fragment {
 	curr := f.Content[index]
-	if curr.eq(n) {
+	if curr.Eq(n) {
 		return f
 	}
 
@@ -68,7 +81,7 @@ func (f Fragment) Child(index int) *Node {
 	return &f.Content[index]
 }
 
-func (f Fragment) maybeChild(index int) *Node {
+func (f Fragment) MaybeChild(index int) *Node {
 	if index < 0 || index >= len(f.Content) {
 		return nil
 	}
@@ -80,6 +93,81 @@ func (f Fragment) ChildCount() int {
 	return len(f.Content)
 }
 
+// Cut cuts out the sub-fragment between the two given positions.
+// If `to` is -1, it defaults to the end of the fragment.
+func (f Fragment) Cut(from, to int) Fragment {
+	return f.cut(from, to)
+}
+
+// CutByIndex cuts out the sub-fragment between the two given child indices.
+func (f Fragment) CutByIndex(from, to int) Fragment {
+	if from == to {
+		return Fragment{}
+	}
+
+	if from == 0 && to == len(f.Content) {
+		return f
+	}
+
+	return NewFragment(slices.Clone(f.Content[from:to])...)
+}
+
+// AddToStart creates a new fragment by prepending the given node to this fragment.
+func (f Fragment) AddToStart(n Node) Fragment {
+	return NewFragment(append([]Node{n}, f.Content...)...)
+}
+
+// AddToEnd creates a new fragment by appending the given node to this fragment.
+func (f Fragment) AddToEnd(n Node) Fragment {
+	return NewFragment(append(slices.Clone(f.Content), n)...)
+}
+
+// NodesBetween invokes a callback for all descendant nodes between the given two
+// positions (relative to start of this fragment). Doesn't descend into a node
+// when the callback returns false.
+func (f Fragment) NodesBetween(from, to int, fn func(node Node, pos int, parent *Node, index int) bool) {
+	f.nodesBetween(from, to, fn, 0, nil)
+}
+
+// TextBetween extracts the text between `from` and `to`. `blockSeparator` is
+// inserted between blocks, and `leafText` is used to get the text of leaf nodes,
+// falling back to their type's LeafText when nil.
+func (f Fragment) TextBetween(from, to int, blockSeparator string, leafText func(Node) string) string {
+	var sb strings.Builder
+	first := true
+
+	f.nodesBetween(from, to, func(node Node, pos int, _ *Node, _ int) bool {
+		var nodeText string
+		switch {
+		case node.IsText():
+			nodeText = utf16Slice(node.Text, max(from, pos)-pos, to-pos)
+		case !node.IsLeaf():
+		case leafText != nil:
+			nodeText = leafText(node)
+		case node.Type.Spec.LeafText != nil:
+			nodeText = node.Type.Spec.LeafText(node)
+		}
+
+		if (node.IsBlock() && node.IsLeaf() && nodeText != "" || node.IsTextblock()) && blockSeparator != "" {
+			if first {
+				first = false
+			} else {
+				sb.WriteString(blockSeparator)
+			}
+		}
+
+		sb.WriteString(nodeText)
+		return true
+	}, 0, nil)
+
+	return sb.String()
+}
+
+// Descendants calls the given callback for every descendant node.
+func (f Fragment) Descendants(fn func(node Node, pos int, parent *Node, index int) bool) {
+	f.nodesBetween(0, f.Size, fn, 0, nil)
+}
+
 // cut removes a range of nodes from the fragment, or a range of text
 //
 // if `to` is -1, it uses the default size of the node
@@ -126,7 +214,9 @@ func (f Fragment) cut(from int, to int) Fragment {
 	}
 }
 
-func (f Fragment) append(other Fragment) Fragment {
+// Append creates a new fragment containing the combined content of this
+// fragment and the other, joining adjacent text nodes with the same marks.
+func (f Fragment) Append(other Fragment) Fragment {
 	if other.Size == 0 {
 		return f
 	}
@@ -135,12 +225,12 @@ func (f Fragment) append(other Fragment) Fragment {
 		return other
 	}
 
-	last := *f.lastChild()
-	first := *other.firstChild()
+	last := *f.LastChild()
+	first := *other.FirstChild()
 	content := slices.Clone(f.Content)
 	i := 0
 
-	if last.IsText() && last.sameMarkup(first) {
+	if last.IsText() && last.SameMarkup(first) {
 		content[len(content)-1] = last.withText(last.Text + first.Text)
 		i = 1
 	}
@@ -155,6 +245,11 @@ func (f Fragment) append(other Fragment) Fragment {
 	}
 }
 
+// Eq compares this fragment to another one.
+func (f Fragment) Eq(other Fragment) bool {
+	return slices.EqualFunc(f.Content, other.Content, Node.Eq)
+}
+
 func (f Fragment) clone() Fragment {
 	return Fragment{
 		Size:    f.Size,
@@ -194,7 +289,7 @@ func (f Fragment) findIndex(pos int) (int, int) {
 	}
 }
 
-func (f Fragment) firstChild() *Node {
+func (f Fragment) FirstChild() *Node {
 	if len(f.Content) == 0 {
 		return nil
 	}
@@ -202,7 +297,7 @@ func (f Fragment) firstChild() *Node {
 	return &f.Content[0]
 }
 
-func (f Fragment) lastChild() *Node {
+func (f Fragment) LastChild() *Node {
 	if len(f.Content) == 0 {
 		return nil
 	}
@@ -211,15 +306,11 @@ func (f Fragment) lastChild() *Node {
 }
 
 func (f Fragment) nodesBetween(from, to int, fn func(node Node, start int, parent *Node, index int) bool, nodeStart int, parent *Node) {
-	for i, pos := 0, 0; pos < to; i++ {
+	for i, pos := 0, 0; pos < to && i < len(f.Content); i++ {
 		child := f.Content[i]
 		end := pos + child.NodeSize()
 
-		if end < from {
-			continue
-		}
-
-		if fn(child, nodeStart+pos, parent, i) && child.Content.Size != 0 {
+		if end > from && fn(child, nodeStart+pos, parent, i) && child.Content.Size != 0 {
 			start := pos + 1
 			child.nodesBetween(
 				max(0, from-start),
@@ -70,3 +70,94 @@ func TestFragment_findIndex(t *testing.T) {
 		})
 	}
 }
+
+func TestFragment_FindDiff(t *testing.T) {
+	s := Must(NewSchema(SchemaSpec{
+		Nodes: map[NodeTypeName]NodeSpec{
+			"doc": {
+				Content: "block+",
+			},
+			"paragraph": {
+				Content: "inline*",
+				Group:   "block",
+			},
+			"text": {
+				Group: "inline",
+			},
+		},
+		Marks: map[MarkTypeName]MarkSpec{
+			"em": {},
+		},
+		TopNode:      "doc",
+		DontRegister: true,
+	}))
+
+	newP := func(text ...Node) Node {
+		return s.Node("paragraph", nil, NewFragment(text...))
+	}
+
+	tests := []struct {
+		name string
+		a, b Fragment
+
+		start  int
+		endA   int
+		endB   int
+		noDiff bool
+	}{
+		{
+			name:   "identical",
+			a:      NewFragment(newP(s.Text("Hello")), newP(s.Text("World"))),
+			b:      NewFragment(newP(s.Text("Hello")), newP(s.Text("World"))),
+			noDiff: true,
+		},
+		{
+			name:  "text changed in the middle",
+			a:     NewFragment(newP(s.Text("Hello World"))),
+			b:     NewFragment(newP(s.Text("Hello there World"))),
+			start: 7,
+			endA:  6,
+			endB:  12,
+		},
+		{
+			name:  "paragraph appended",
+			a:     NewFragment(newP(s.Text("one"))),
+			b:     NewFragment(newP(s.Text("one")), newP(s.Text("two"))),
+			start: 5,
+			endA:  4,
+			endB:  9,
+		},
+		{
+			name:  "mark added",
+			a:     NewFragment(newP(s.Text("ab"), s.Text("cd"))),
+			b:     NewFragment(newP(s.Text("ab"), s.Text("cd", s.Mark("em", nil)))),
+			start: 3,
+			endA:  5,
+			endB:  5,
+		},
+		{
+			name:  "surrogate pairs count as two units",
+			a:     NewFragment(newP(s.Text("😀a😀"))),
+			b:     NewFragment(newP(s.Text("😀b😀"))),
+			start: 3,
+			endA:  4,
+			endB:  4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.noDiff, tt.a.Eq(tt.b))
+
+			start, ok := tt.a.FindDiffStart(tt.b, 0)
+			if !assert.Equal(t, !tt.noDiff, ok) || tt.noDiff {
+				return
+			}
+
+			endA, endB, ok := tt.a.FindDiffEnd(tt.b, tt.a.Size, tt.b.Size)
+			assert.True(t, ok)
+			assert.Equal(t, tt.start, start)
+			assert.Equal(t, tt.endA, endA)
+			assert.Equal(t, tt.endB, endB)
+		})
+	}
+}
@@ -6,9 +6,9 @@ require (
 	github.com/davecgh/go-spew v1.1.1
 	github.com/go-json-experiment/json v0.0.0-20231102232822-2e55bd4e08b0
 	github.com/stretchr/testify v1.8.4
+	github.com/yuin/goldmark v1.7.17
+	golang.org/x/net v0.35.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-require (
-	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
-)
+require github.com/pmezard/go-difflib v1.0.0 // indirect
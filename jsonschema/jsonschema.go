@@ -0,0 +1,365 @@
+// Package jsonschema generates a JSON Schema (draft 2020-12) validating the
+// JSON documents of a schema, for services validating documents without a
+// ProseMirror implementation.
+//
+// Every node and mark type gets a definition, named node_<name> and
+// mark_<name>, with its attributes and their defaults. The content of a node
+// is checked against the node types its content expression allows, and the
+// number of children it allows, which approximates the expression: the order
+// of the children isn't checked. The marks of a node are checked against the
+// marks its parent allows, for block nodes as for inline ones.
+package jsonschema
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+
+	p "github.com/karitham/prosemirror"
+)
+
+const draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Options configures the generated JSON Schema.
+type Options struct {
+	// The $id of the JSON Schema, left out when empty.
+	ID string
+
+	// The prefix of references to definitions. Defaults to "#/$defs/", and
+	// can be changed to embed the definitions elsewhere, like the components
+	// of an OpenAPI document.
+	RefPrefix string
+}
+
+// Generate returns the JSON Schema of the documents of s, which have the
+// schema's top node type at their root. The definitions of every node and
+// mark type are under "$defs".
+func Generate(s p.Schema, opts Options) (map[string]any, error) {
+	if s.TopNodeType == nil {
+		return nil, fmt.Errorf("schema has no top node type")
+	}
+
+	if opts.RefPrefix == "" {
+		opts.RefPrefix = "#/$defs/"
+	}
+
+	g := generator{opts: opts}
+	defs := map[string]any{}
+
+	nodeNames := []any{}
+	for _, typ := range s.NodeTypes() {
+		nodeNames = append(nodeNames, string(typ.Name))
+		defs[nodeDef(typ.Name)] = g.node(s, typ)
+	}
+
+	markNames, marks := []any{}, []any{}
+	for _, typ := range s.MarkTypes() {
+		markNames = append(markNames, string(typ.Name))
+		marks = append(marks, g.ref(markDef(typ.Name)))
+		defs[markDef(typ.Name)] = g.mark(typ)
+	}
+
+	defs["node_type"] = map[string]any{"enum": nodeNames}
+	defs["mark_type"] = map[string]any{"enum": markNames}
+	defs["mark"] = anyOf(marks)
+
+	out := map[string]any{
+		"$schema": draft,
+		"$ref":    g.ref(nodeDef(s.TopNodeType.Name)),
+		"$defs":   defs,
+	}
+
+	if opts.ID != "" {
+		out["$id"] = opts.ID
+	}
+
+	return out, nil
+}
+
+func nodeDef(name p.NodeTypeName) string { return "node_" + string(name) }
+func markDef(name p.MarkTypeName) string { return "mark_" + string(name) }
+
+type generator struct {
+	opts Options
+}
+
+func (g generator) ref(def string) map[string]any {
+	return map[string]any{"$ref": g.opts.RefPrefix + def}
+}
+
+func (g generator) node(s p.Schema, typ p.NodeType) map[string]any {
+	props := map[string]any{
+		"type":  map[string]any{"const": string(typ.Name)},
+		"marks": map[string]any{"type": "array", "items": g.ref("mark")},
+	}
+	required := []string{"type"}
+
+	if typ.IsText() {
+		props["text"] = map[string]any{"type": "string", "minLength": 1}
+		required = append(required, "text")
+	}
+
+	if attrs, req := g.attrs(typ.Spec.Attrs); attrs != nil {
+		props["attrs"] = attrs
+		if req {
+			required = append(required, "attrs")
+		}
+	}
+
+	if content := g.content(s, typ); content != nil {
+		props["content"] = content
+		if content["minItems"] != nil {
+			required = append(required, "content")
+		}
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func (g generator) mark(typ p.MarkType) map[string]any {
+	props := map[string]any{
+		"type": map[string]any{"const": string(typ.Name)},
+	}
+	required := []string{"type"}
+
+	if attrs, req := g.attrs(typ.Spec.Attrs); attrs != nil {
+		props["attrs"] = attrs
+		if req {
+			required = append(required, "attrs")
+		}
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// attrs returns the schema of the attributes, and whether some are required.
+func (g generator) attrs(spec map[string]p.Attribute) (map[string]any, bool) {
+	if len(spec) == 0 {
+		return nil, false
+	}
+
+	props := map[string]any{}
+	required := []string{}
+	for name, attr := range spec {
+		prop := map[string]any{}
+		if t := jsonType(attr.Default); t != "" {
+			prop["type"] = t
+		}
+
+		if attr.Default != nil || attr.Optional {
+			prop["default"] = attr.Default
+		} else {
+			required = append(required, name)
+		}
+
+		props[name] = prop
+	}
+
+	slices.Sort(required)
+
+	out := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+
+	if len(required) > 0 {
+		out["required"] = required
+	}
+
+	return out, len(required) > 0
+}
+
+// content returns the schema of the content of a node type, or nil for leaves.
+func (g generator) content(s p.Schema, typ p.NodeType) map[string]any {
+	if typ.IsLeaf() {
+		return nil
+	}
+
+	start := typ.ContentMatch
+	types := contentTypes(&start)
+	items := []any{}
+	for _, t := range s.NodeTypes() {
+		if slices.ContainsFunc(types, func(other p.NodeType) bool { return other.Name == t.Name }) {
+			items = append(items, g.ref(nodeDef(t.Name)))
+		}
+	}
+
+	itemSchema := map[string]any{"anyOf": items}
+	if typ.Marks != nil {
+		itemSchema["properties"] = map[string]any{"marks": g.allowedMarks(s, typ)}
+	}
+
+	out := map[string]any{
+		"type":  "array",
+		"items": itemSchema,
+	}
+
+	minItems, maxItems := contentBounds(&start)
+	if minItems > 0 {
+		out["minItems"] = minItems
+	}
+
+	if maxItems >= 0 {
+		out["maxItems"] = maxItems
+	}
+
+	return out
+}
+
+// allowedMarks returns the schema of the marks of the children of a node type.
+func (g generator) allowedMarks(s p.Schema, typ p.NodeType) map[string]any {
+	if len(typ.Marks) == 0 {
+		return map[string]any{"type": "array", "maxItems": 0}
+	}
+
+	items := []any{}
+	for _, mark := range s.MarkTypes() {
+		if typ.AllowsMarkType(mark) {
+			items = append(items, g.ref(markDef(mark.Name)))
+		}
+	}
+
+	return map[string]any{"type": "array", "items": anyOf(items)}
+}
+
+// anyOf returns a schema matching any of the given ones, which is false when
+// there are none, since anyOf can't be empty.
+func anyOf(schemas []any) any {
+	if len(schemas) == 0 {
+		return false
+	}
+
+	return map[string]any{"anyOf": schemas}
+}
+
+// contentTypes returns the node types found on the edges of the automaton.
+func contentTypes(start *p.ContentMatch) []p.NodeType {
+	types := []p.NodeType{}
+	walk(start, func(m *p.ContentMatch) {
+		for i := 0; i < m.EdgeCount(); i++ {
+			t, _ := m.Edge(i)
+			types = append(types, t)
+		}
+	})
+
+	return types
+}
+
+func walk(start *p.ContentMatch, f func(m *p.ContentMatch)) {
+	seen := map[*p.ContentMatch]bool{}
+	var visit func(m *p.ContentMatch)
+	visit = func(m *p.ContentMatch) {
+		if seen[m] {
+			return
+		}
+		seen[m] = true
+
+		f(m)
+		for i := 0; i < m.EdgeCount(); i++ {
+			_, next := m.Edge(i)
+			visit(next)
+		}
+	}
+
+	visit(start)
+}
+
+// contentBounds returns the least and greatest number of children matching
+// the automaton, the greatest being -1 when unbounded.
+func contentBounds(start *p.ContentMatch) (int, int) {
+	// the least is the length of the shortest path to a valid end
+	minItems := -1
+	level, seen := []*p.ContentMatch{start}, map[*p.ContentMatch]bool{start: true}
+	for depth := 0; len(level) > 0 && minItems < 0; depth++ {
+		var next []*p.ContentMatch
+		for _, m := range level {
+			if m.ValidEnd {
+				minItems = depth
+				break
+			}
+
+			for i := 0; i < m.EdgeCount(); i++ {
+				if _, to := m.Edge(i); !seen[to] {
+					seen[to] = true
+					next = append(next, to)
+				}
+			}
+		}
+		level = next
+	}
+
+	// the greatest is the length of the longest path, when there's no cycle
+	const visiting = math.MinInt
+	longest := map[*p.ContentMatch]int{}
+	var visit func(m *p.ContentMatch) (int, bool)
+	visit = func(m *p.ContentMatch) (int, bool) {
+		if l, ok := longest[m]; ok {
+			return l, l != visiting
+		}
+		longest[m] = visiting
+
+		l := -1
+		if m.ValidEnd {
+			l = 0
+		}
+
+		for i := 0; i < m.EdgeCount(); i++ {
+			_, to := m.Edge(i)
+			n, ok := visit(to)
+			if !ok {
+				return 0, false
+			}
+
+			if n >= 0 {
+				l = max(l, n+1)
+			}
+		}
+
+		longest[m] = l
+		return l, true
+	}
+
+	maxItems, ok := visit(start)
+	if !ok {
+		maxItems = -1
+	}
+
+	return max(minItems, 0), maxItems
+}
+
+// jsonType returns the JSON type of an attribute default, or "" when unknown.
+func jsonType(v any) string {
+	if v == nil {
+		return ""
+	}
+
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+
+	return ""
+}
@@ -0,0 +1,158 @@
+package jsonschema_test
+
+import (
+	"testing"
+
+	"github.com/go-json-experiment/json"
+	"github.com/stretchr/testify/assert"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/jsonschema"
+	"github.com/karitham/prosemirror/schema"
+)
+
+func TestGenerate(t *testing.T) {
+	s := p.Must(p.NewSchema(schema.DefaultSpec))
+
+	got, err := jsonschema.Generate(s, jsonschema.Options{ID: "https://example.com/doc.json"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "https://json-schema.org/draft/2020-12/schema", got["$schema"])
+	assert.Equal(t, "https://example.com/doc.json", got["$id"])
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/node_doc"}, got["$ref"])
+
+	defs := got["$defs"].(map[string]any)
+	assert.Equal(t, map[string]any{"enum": []any{"link", "em", "strong", "code"}}, defs["mark_type"])
+	assert.Equal(t, map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"type": map[string]any{"const": "link"},
+			"attrs": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"href":  map[string]any{"default": nil},
+					"title": map[string]any{"default": nil},
+				},
+				"additionalProperties": false,
+			},
+		},
+		"required":             []string{"type"},
+		"additionalProperties": false,
+	}, defs["mark_link"])
+
+	image := defs["node_image"].(map[string]any)
+	assert.Equal(t, []string{"type", "attrs"}, image["required"])
+	assert.Equal(t, []string{"src"}, image["properties"].(map[string]any)["attrs"].(map[string]any)["required"])
+
+	heading := defs["node_heading"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "integer", "default": 1}, heading["attrs"].(map[string]any)["properties"].(map[string]any)["level"])
+
+	doc := defs["node_doc"].(map[string]any)
+	assert.Equal(t, []string{"type", "content"}, doc["required"])
+	assert.Equal(t, map[string]any{
+		"type":     "array",
+		"minItems": 1,
+		"items": map[string]any{
+			"anyOf": []any{
+				map[string]any{"$ref": "#/$defs/node_paragraph"},
+				map[string]any{"$ref": "#/$defs/node_blockquote"},
+				map[string]any{"$ref": "#/$defs/node_horizontal_rule"},
+				map[string]any{"$ref": "#/$defs/node_heading"},
+				map[string]any{"$ref": "#/$defs/node_code_block"},
+			},
+			"properties": map[string]any{"marks": map[string]any{"type": "array", "maxItems": 0}},
+		},
+	}, doc["properties"].(map[string]any)["content"])
+
+	// paragraphs allow every mark on their children, code blocks none
+	paragraph := defs["node_paragraph"].(map[string]any)["properties"].(map[string]any)["content"].(map[string]any)
+	assert.NotContains(t, paragraph["items"], "properties")
+	codeBlock := defs["node_code_block"].(map[string]any)["properties"].(map[string]any)["content"].(map[string]any)
+	assert.Equal(t, map[string]any{"marks": map[string]any{"type": "array", "maxItems": 0}}, codeBlock["items"].(map[string]any)["properties"])
+
+	text := defs["node_text"].(map[string]any)
+	assert.Equal(t, []string{"type", "text"}, text["required"])
+
+	// every node may have marks, which its parent checks, and only text nodes have text
+	for _, name := range []string{"doc", "paragraph", "heading", "horizontal_rule", "image", "text"} {
+		props := defs["node_"+name].(map[string]any)["properties"]
+		assert.Contains(t, props, "marks", name)
+		if name != "text" {
+			assert.NotContains(t, props, "text", name)
+		}
+	}
+	assert.NotContains(t, defs["node_image"].(map[string]any)["properties"], "content")
+
+	_, err = json.Marshal(got)
+	assert.NoError(t, err)
+}
+
+func TestContentBounds(t *testing.T) {
+	em := "em"
+	s := p.Must(p.NewSchema(p.SchemaSpec{
+		Nodes: map[p.NodeTypeName]p.NodeSpec{
+			"doc":       {Content: "title paragraph{1,3} (note | image)?"},
+			"title":     {Content: "text*", Marks: new(string)},
+			"paragraph": {Content: "text*", Marks: &em},
+			"note":      {Content: "paragraph+"},
+			"image":     {Inline: true},
+			"text":      {},
+		},
+		Marks:        map[p.MarkTypeName]p.MarkSpec{"em": {}, "strong": {}},
+		DontRegister: true,
+	}))
+
+	got, err := jsonschema.Generate(s, jsonschema.Options{RefPrefix: "#/components/schemas/"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defs := got["$defs"].(map[string]any)
+	content := func(name string) map[string]any {
+		return defs["node_"+name].(map[string]any)["properties"].(map[string]any)["content"].(map[string]any)
+	}
+
+	doc := content("doc")
+	assert.Equal(t, 2, doc["minItems"])
+	assert.Equal(t, 5, doc["maxItems"])
+	assert.Len(t, doc["items"].(map[string]any)["anyOf"], 4)
+
+	assert.Equal(t, 1, content("note")["minItems"])
+	assert.NotContains(t, content("note"), "maxItems")
+	assert.NotContains(t, content("title"), "minItems")
+
+	assert.Equal(t, map[string]any{
+		"type":  "array",
+		"items": map[string]any{"anyOf": []any{map[string]any{"$ref": "#/components/schemas/mark_em"}}},
+	}, content("paragraph")["items"].(map[string]any)["properties"].(map[string]any)["marks"])
+}
+
+func TestBlockMarks(t *testing.T) {
+	em := "em"
+	s := p.Must(p.NewSchema(p.SchemaSpec{
+		Nodes: map[p.NodeTypeName]p.NodeSpec{
+			"doc":       {Content: "paragraph+", Marks: &em},
+			"paragraph": {Content: "text*"},
+			"text":      {},
+		},
+		Marks:        map[p.MarkTypeName]p.MarkSpec{"em": {}, "strong": {}},
+		DontRegister: true,
+	}))
+
+	got, err := jsonschema.Generate(s, jsonschema.Options{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defs := got["$defs"].(map[string]any)
+	assert.Contains(t, defs["node_paragraph"].(map[string]any)["properties"], "marks")
+
+	// paragraphs may hold the marks the document allows
+	content := defs["node_doc"].(map[string]any)["properties"].(map[string]any)["content"].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"type":  "array",
+		"items": map[string]any{"anyOf": []any{map[string]any{"$ref": "#/$defs/mark_em"}}},
+	}, content["items"].(map[string]any)["properties"].(map[string]any)["marks"])
+}
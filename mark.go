@@ -1,6 +1,11 @@
 package prosemirror
 
-import "maps"
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
 
 type Mark struct {
 	Type  MarkType       `json:"type"`
@@ -8,5 +13,140 @@ type Mark struct {
 }
 
 func (m Mark) Eq(other Mark) bool {
-	return m.Type.Eq(other.Type) && maps.Equal(m.Attrs, other.Attrs)
+	return m.Type.Name == other.Type.Name && attrsEq(m.Attrs, other.Attrs)
+}
+
+func (m Mark) String() string {
+	if len(m.Attrs) == 0 {
+		return string(m.Type.Name)
+	}
+
+	return fmt.Sprintf("%s%v", m.Type.Name, m.Attrs)
+}
+
+// AddToSet returns a set of marks with this mark added, in rank order.
+// Marks excluded by this one are removed, and if the set already contains
+// a mark that excludes this one, it is returned unchanged.
+func (m Mark) AddToSet(set []Mark) []Mark {
+	var copied []Mark
+	placed := false
+
+	for i, other := range set {
+		if m.Eq(other) {
+			return set
+		}
+
+		switch {
+		case m.Type.Excludes(other.Type):
+			if copied == nil {
+				copied = slices.Clone(set[:i])
+			}
+		case other.Type.Excludes(m.Type):
+			return set
+		default:
+			if !placed && other.Type.Rank > m.Type.Rank {
+				if copied == nil {
+					copied = slices.Clone(set[:i])
+				}
+				copied = append(copied, m)
+				placed = true
+			}
+
+			if copied != nil {
+				copied = append(copied, other)
+			}
+		}
+	}
+
+	if copied == nil {
+		copied = slices.Clone(set)
+	}
+
+	if !placed {
+		copied = append(copied, m)
+	}
+
+	return copied
+}
+
+// RemoveFromSet removes this mark from the given set, returning a new set.
+// If this mark is not in the set, the set itself is returned.
+func (m Mark) RemoveFromSet(set []Mark) []Mark {
+	for i, other := range set {
+		if m.Eq(other) {
+			return append(set[:i:i], set[i+1:]...)
+		}
+	}
+
+	return set
+}
+
+// IsInSet tests whether this mark is in the given set of marks.
+func (m Mark) IsInSet(set []Mark) bool {
+	return slices.ContainsFunc(set, m.Eq)
+}
+
+// SameMarkSet tests whether two sets of marks are identical.
+func SameMarkSet(a, b []Mark) bool {
+	return slices.EqualFunc(a, b, Mark.Eq)
+}
+
+// MarkSetFrom creates a properly sorted mark set from the given marks.
+func MarkSetFrom(marks ...Mark) []Mark {
+	if len(marks) == 0 {
+		return nil
+	}
+
+	copied := slices.Clone(marks)
+	slices.SortStableFunc(copied, func(a, b Mark) int {
+		return a.Type.Rank - b.Type.Rank
+	})
+
+	return copied
+}
+
+func attrsEq(a, b map[string]any) bool {
+	return maps.EqualFunc(a, b, attrValueEq)
+}
+
+// attrValueEq compares attribute values, treating numbers of different
+// types as equal since JSON decoding turns every number into a float64.
+func attrValueEq(a, b any) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	return 0, false
 }
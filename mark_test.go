@@ -0,0 +1,75 @@
+package prosemirror
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func markTestSchema(t *testing.T) Schema {
+	t.Helper()
+
+	excludeAll := "_"
+	s, err := NewSchema(SchemaSpec{
+		Nodes: map[NodeTypeName]NodeSpec{
+			"doc":  {Content: "text*"},
+			"text": {},
+		},
+		Marks: map[MarkTypeName]MarkSpec{
+			"em":     {},
+			"strong": {},
+			"link":   {Attrs: map[string]Attribute{"href": {}}},
+			"code":   {Excludes: &excludeAll},
+		},
+		MarkOrder:    []MarkTypeName{"em", "strong", "link", "code"},
+		DontRegister: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestMarkAddToSet(t *testing.T) {
+	s := markTestSchema(t)
+	em, strong, code := s.Mark("em", nil), s.Mark("strong", nil), s.Mark("code", nil)
+	link := func(href string) Mark { return s.Mark("link", map[string]any{"href": href}) }
+
+	tests := []struct {
+		name string
+		mark Mark
+		set  []Mark
+		want []Mark
+	}{
+		{name: "empty set", mark: em, want: []Mark{em}},
+		{name: "already in set", mark: em, set: []Mark{em, strong}, want: []Mark{em, strong}},
+		{name: "rank order", mark: em, set: []Mark{strong}, want: []Mark{em, strong}},
+		{name: "at the end", mark: link("a"), set: []Mark{em, strong}, want: []Mark{em, strong, link("a")}},
+		{name: "replaces same type", mark: link("b"), set: []Mark{em, link("a")}, want: []Mark{em, link("b")}},
+		{name: "excluding mark", mark: code, set: []Mark{em, link("a")}, want: []Mark{code}},
+		{name: "excluded mark", mark: em, set: []Mark{code}, want: []Mark{code}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.mark.AddToSet(tc.set)
+			assert.True(t, SameMarkSet(tc.want, got), "got %v, want %v", got, tc.want)
+		})
+	}
+}
+
+func TestMarkSets(t *testing.T) {
+	s := markTestSchema(t)
+	em, strong := s.Mark("em", nil), s.Mark("strong", nil)
+
+	set := []Mark{em, strong}
+	assert.True(t, em.IsInSet(set))
+	assert.True(t, SameMarkSet([]Mark{strong}, em.RemoveFromSet(set)))
+	assert.True(t, SameMarkSet(set, s.Mark("link", map[string]any{"href": "a"}).RemoveFromSet(set)))
+	assert.True(t, SameMarkSet(set, MarkSetFrom(strong, em)))
+	assert.Nil(t, MarkSetFrom())
+
+	// JSON decoding turns numbers into float64
+	assert.True(t, s.Mark("link", map[string]any{"href": 1}).Eq(s.Mark("link", map[string]any{"href": 1.0})))
+}
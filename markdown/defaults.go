@@ -0,0 +1,262 @@
+package markdown
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+
+	"github.com/karitham/prosemirror"
+)
+
+// DefaultSerializer serializes documents of the schema package's default schema,
+// along with bullet_list, ordered_list and list_item nodes.
+var DefaultSerializer = NewSerializer(DefaultNodeSerializers(), DefaultMarkSerializers(), SerializerOptions{})
+
+var fenceRe = regexp.MustCompile("(?m)`{3,}")
+
+// DefaultNodeSerializers returns the serializers of the default nodes, to be extended for other schemas.
+func DefaultNodeSerializers() map[prosemirror.NodeTypeName]NodeSerializer {
+	return map[prosemirror.NodeTypeName]NodeSerializer{
+		"blockquote": func(s *SerializerState, node, _ prosemirror.Node, _ int) {
+			s.WrapBlock("> ", "", node, func() { s.RenderContent(node) })
+		},
+		"code_block": func(s *SerializerState, node, _ prosemirror.Node, _ int) {
+			text := node.TextContent()
+
+			// make sure the fence is longer than any backtick run in the code
+			fence := "```"
+			if runs := fenceRe.FindAllString(text, -1); len(runs) > 0 {
+				fence = slices.MaxFunc(runs, func(a, b string) int { return len(a) - len(b) }) + "`"
+			}
+
+			language, _ := node.Attrs["language"].(string)
+			s.Write(fence + language + "\n")
+			s.Text(text, false)
+			s.Write("\n")
+			s.Write(fence)
+			s.CloseBlock(node)
+		},
+		"heading": func(s *SerializerState, node, _ prosemirror.Node, _ int) {
+			s.Write(strings.Repeat("#", headingLevel(node)) + " ")
+			s.RenderInline(node, false)
+			s.CloseBlock(node)
+		},
+		"horizontal_rule": func(s *SerializerState, node, _ prosemirror.Node, _ int) {
+			markup, _ := node.Attrs["markup"].(string)
+			if markup == "" {
+				markup = "---"
+			}
+
+			s.Write(markup)
+			s.CloseBlock(node)
+		},
+		"bullet_list": func(s *SerializerState, node, _ prosemirror.Node, _ int) {
+			bullet, _ := node.Attrs["bullet"].(string)
+			if bullet == "" {
+				bullet = "*"
+			}
+
+			s.RenderList(node, "  ", func(int) string { return bullet + " " })
+		},
+		"ordered_list": func(s *SerializerState, node, _ prosemirror.Node, _ int) {
+			start := 1
+			if order, ok := prosemirror.IntAttr(node.Attrs["order"]); ok {
+				start = order
+			}
+
+			maxW := len(strconv.Itoa(start + node.ChildCount() - 1))
+			s.RenderList(node, strings.Repeat(" ", maxW+2), func(i int) string {
+				n := strconv.Itoa(start + i)
+				return strings.Repeat(" ", maxW-len(n)) + n + ". "
+			})
+		},
+		"list_item": func(s *SerializerState, node, _ prosemirror.Node, _ int) {
+			s.RenderContent(node)
+		},
+		"paragraph": func(s *SerializerState, node, _ prosemirror.Node, _ int) {
+			s.RenderInline(node, true)
+			s.CloseBlock(node)
+		},
+		"image": func(s *SerializerState, node, _ prosemirror.Node, _ int) {
+			alt, _ := node.Attrs["alt"].(string)
+			src, _ := node.Attrs["src"].(string)
+			title, _ := node.Attrs["title"].(string)
+			s.Write("![" + s.Esc(alt, false) + "](" + parenEscaper.Replace(src) + titleString(title) + ")")
+		},
+		"hard_break": func(s *SerializerState, node, parent prosemirror.Node, index int) {
+			for i := index + 1; i < parent.ChildCount(); i++ {
+				if parent.Child(i).Type.Name != node.Type.Name {
+					s.Write("\\\n")
+					return
+				}
+			}
+		},
+		"text": func(s *SerializerState, node, _ prosemirror.Node, _ int) {
+			s.Text(node.Text, !s.InAutolink)
+		},
+	}
+}
+
+// DefaultMarkSerializers returns the serializers of the default marks, to be extended for other schemas.
+func DefaultMarkSerializers() map[prosemirror.MarkTypeName]MarkSerializer {
+	noEscape := false
+	return map[prosemirror.MarkTypeName]MarkSerializer{
+		"em":     {Open: Delim("*"), Close: Delim("*"), Mixable: true, ExpelEnclosingWhitespace: true},
+		"strong": {Open: Delim("**"), Close: Delim("**"), Mixable: true, ExpelEnclosingWhitespace: true},
+		"link": {
+			Open: func(s *SerializerState, mark prosemirror.Mark, parent prosemirror.Node, index int) string {
+				s.InAutolink = isPlainURL(mark, parent, index)
+				if s.InAutolink {
+					return "<"
+				}
+
+				return "["
+			},
+			Close: func(s *SerializerState, mark prosemirror.Mark, _ prosemirror.Node, _ int) string {
+				inAutolink := s.InAutolink
+				s.InAutolink = false
+				if inAutolink {
+					return ">"
+				}
+
+				href, _ := mark.Attrs["href"].(string)
+				title, _ := mark.Attrs["title"].(string)
+				return "](" + linkEscaper.Replace(href) + titleString(title) + ")"
+			},
+		},
+		"code": {
+			Open: func(_ *SerializerState, _ prosemirror.Mark, parent prosemirror.Node, index int) string {
+				return backticksFor(*parent.Child(index), -1)
+			},
+			Close: func(_ *SerializerState, _ prosemirror.Mark, parent prosemirror.Node, index int) string {
+				return backticksFor(*parent.Child(index - 1), 1)
+			},
+			Escape: &noEscape,
+		},
+	}
+}
+
+var (
+	parenEscaper = strings.NewReplacer("(", `\(`, ")", `\)`)
+	linkEscaper  = strings.NewReplacer("(", `\(`, ")", `\)`, `"`, `\"`)
+	titleEscaper = strings.NewReplacer(`"`, `\"`)
+	backticksRe  = regexp.MustCompile("`+")
+	schemeRe     = regexp.MustCompile(`^\w+:`)
+)
+
+func titleString(title string) string {
+	if title == "" {
+		return ""
+	}
+
+	return ` "` + titleEscaper.Replace(title) + `"`
+}
+
+// backticksFor returns the delimiter of inline code holding the given node's text.
+// `side` is negative for the opening delimiter, and positive for the closing one.
+func backticksFor(node prosemirror.Node, side int) string {
+	length := 0
+	if node.IsText() {
+		for _, m := range backticksRe.FindAllString(node.Text, -1) {
+			length = max(length, len(m))
+		}
+	}
+
+	result := "`"
+	if length > 0 && side > 0 {
+		result = " `"
+	}
+
+	result += strings.Repeat("`", length)
+	if length > 0 && side < 0 {
+		result += " "
+	}
+
+	return result
+}
+
+// isPlainURL reports whether a link can be rendered as an autolink.
+func isPlainURL(link prosemirror.Mark, parent prosemirror.Node, index int) bool {
+	href, _ := link.Attrs["href"].(string)
+	if title, _ := link.Attrs["title"].(string); title != "" || !schemeRe.MatchString(href) {
+		return false
+	}
+
+	content := parent.Child(index)
+	if !content.IsText() || content.Text != href || !content.Marks[len(content.Marks)-1].Eq(link) {
+		return false
+	}
+
+	return index == parent.ChildCount()-1 || !link.IsInSet(parent.Child(index+1).Marks)
+}
+
+func headingLevel(node prosemirror.Node) int {
+	if level, ok := prosemirror.IntAttr(node.Attrs["level"]); ok {
+		return max(1, min(level, 6))
+	}
+
+	return 1
+}
+
+// DefaultParseSpecs returns the parse specs of the default schema's nodes and marks,
+// along with bullet_list, ordered_list and list_item nodes, to be extended for other schemas.
+func DefaultParseSpecs() map[string]ParseSpec {
+	return map[string]ParseSpec{
+		"blockquote": {Block: "blockquote"},
+		"paragraph":  {Block: "paragraph"},
+		"list_item":  {Block: "list_item"},
+		"bullet_list": {Block: "bullet_list", GetAttrs: func(n ast.Node, _ []byte) map[string]any {
+			return map[string]any{"tight": n.(*ast.List).IsTight}
+		}},
+		"ordered_list": {Block: "ordered_list", GetAttrs: func(n ast.Node, _ []byte) map[string]any {
+			list := n.(*ast.List)
+			return map[string]any{"order": list.Start, "tight": list.IsTight}
+		}},
+		"heading": {Block: "heading", GetAttrs: func(n ast.Node, _ []byte) map[string]any {
+			return map[string]any{"level": n.(*ast.Heading).Level}
+		}},
+		"code_block": {Block: "code_block"},
+		"fence": {Block: "code_block", GetAttrs: func(n ast.Node, source []byte) map[string]any {
+			if language := n.(*ast.FencedCodeBlock).Language(source); len(language) > 0 {
+				return map[string]any{"language": string(unescape(language))}
+			}
+
+			return nil
+		}},
+		"hr": {Node: "horizontal_rule"},
+		"image": {Node: "image", GetAttrs: func(n ast.Node, source []byte) map[string]any {
+			img := n.(*ast.Image)
+			attrs := map[string]any{"src": string(unescape(img.Destination))}
+			if alt := PlainText(img, source); alt != "" {
+				attrs["alt"] = alt
+			}
+			if len(img.Title) > 0 {
+				attrs["title"] = string(unescape(img.Title))
+			}
+
+			return attrs
+		}},
+		"hardbreak":   {Node: "hard_break"},
+		"em":          {Mark: "em"},
+		"strong":      {Mark: "strong"},
+		"code_inline": {Mark: "code"},
+		"link": {Mark: "link", GetAttrs: func(n ast.Node, source []byte) map[string]any {
+			switch n := n.(type) {
+			case *ast.AutoLink:
+				return map[string]any{"href": string(n.URL(source))}
+			case *ast.Link:
+				attrs := map[string]any{"href": string(unescape(n.Destination))}
+				if len(n.Title) > 0 {
+					attrs["title"] = string(unescape(n.Title))
+				}
+
+				return attrs
+			}
+
+			return nil
+		}},
+	}
+}
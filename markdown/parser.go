@@ -0,0 +1,362 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	"github.com/karitham/prosemirror"
+)
+
+// ParseSpec describes how a Markdown token is turned into a node or mark. One of
+// Block, Node, Mark or Ignore is set.
+type ParseSpec struct {
+	// Block is the type of the node wrapping the token's content.
+	Block prosemirror.NodeTypeName
+
+	// Node is the type of the leaf node the token is turned into.
+	Node prosemirror.NodeTypeName
+
+	// Mark is the type of the mark added to the token's content.
+	Mark prosemirror.MarkTypeName
+
+	// Attrs are the attributes of the created node or mark.
+	Attrs map[string]any
+
+	// GetAttrs computes the attributes of the created node or mark from the token's
+	// AST node. It takes precedence over Attrs.
+	GetAttrs func(n ast.Node, source []byte) map[string]any
+
+	// Whether the token and its content are left out of the document.
+	Ignore bool
+}
+
+// Warning reports a Markdown construct left out of a parsed document, because
+// the parser or the schema cannot represent it. Its source is the name of the
+// construct's token.
+type Warning = prosemirror.ParseWarning
+
+// Parser builds documents of a schema from CommonMark, using a parse spec for each token.
+//
+// Tokens are named after markdown-it's: blockquote, paragraph, heading, code_block (indented code),
+// fence, hr, bullet_list, ordered_list, list_item, html_block, em, strong, link (including
+// autolinks), image, code_inline, html_inline, hardbreak and softbreak. Soft breaks without a
+// spec become newlines. The tokens of goldmark extensions are named after their AST node kind.
+type Parser struct {
+	Schema   prosemirror.Schema
+	Markdown goldmark.Markdown
+	Tokens   map[string]ParseSpec
+
+	// Whether constructs left out of the document are an error, instead of a warning.
+	Strict bool
+}
+
+// NewParser creates a CommonMark parser for the given schema and tokens.
+func NewParser(s prosemirror.Schema, tokens map[string]ParseSpec) Parser {
+	return Parser{Schema: s, Markdown: goldmark.New(), Tokens: tokens}
+}
+
+// Parse parses a Markdown document. Constructs that cannot be represented are
+// dropped and reported as warnings, once per token and reason, unless the parser
+// is strict.
+func (p Parser) Parse(src []byte) (prosemirror.Node, []Warning, error) {
+	root := p.Markdown.Parser().Parse(text.NewReader(src))
+
+	s := &parseState{parser: p, src: src}
+	s.open(*p.Schema.TopNodeType, nil)
+	s.renderContent(root)
+
+	doc, err := s.stack[0].Node()
+	if err != nil {
+		return prosemirror.Node{}, s.warnings, fmt.Errorf("failed to create document: %w", err)
+	}
+
+	if p.Strict && len(s.warnings) > 0 {
+		return prosemirror.Node{}, s.warnings, fmt.Errorf("cannot represent markdown: %w", s.warnings[0])
+	}
+
+	return doc, s.warnings, nil
+}
+
+type parseState struct {
+	parser   Parser
+	src      []byte
+	stack    []*prosemirror.ContentBuilder
+	marks    []prosemirror.Mark
+	warnings prosemirror.ParseWarnings
+}
+
+func (s *parseState) top() *prosemirror.ContentBuilder {
+	return s.stack[len(s.stack)-1]
+}
+
+func (s *parseState) open(typ prosemirror.NodeType, attrs map[string]any) {
+	s.stack = append(s.stack, prosemirror.NewContentBuilder(typ, attrs, nil))
+}
+
+func (s *parseState) close(token string) {
+	node, err := s.top().Node()
+	s.stack = s.stack[:len(s.stack)-1]
+	if err != nil {
+		s.warnings.Warn(token, "%s", err)
+		return
+	}
+
+	s.addNode(token, node)
+}
+
+func (s *parseState) addNode(token string, node prosemirror.Node) {
+	if b := s.top(); !b.Push(node) {
+		s.warnings.Warn(token, "%s is not allowed in %s", node.Type.Name, b.Type.Name)
+	}
+}
+
+// allowedMarks returns the active marks allowed in the current node, reporting the others.
+func (s *parseState) allowedMarks(token string) []prosemirror.Mark {
+	typ := s.top().Type
+
+	var marks []prosemirror.Mark
+	for _, m := range s.marks {
+		if typ.AllowsMarkType(m.Type) {
+			marks = append(marks, m)
+		} else {
+			s.warnings.Warn(token, "mark %s is not allowed in %s", m.Type.Name, typ.Name)
+		}
+	}
+
+	return marks
+}
+
+func (s *parseState) addText(text string) {
+	if text == "" {
+		return
+	}
+
+	s.addNode("text", s.parser.Schema.Text(text, s.allowedMarks("text")...))
+}
+
+func (s *parseState) attrs(spec ParseSpec, n ast.Node) map[string]any {
+	if spec.GetAttrs != nil {
+		return spec.GetAttrs(n, s.src)
+	}
+
+	return spec.Attrs
+}
+
+// render adds an AST node to the document.
+func (s *parseState) render(n ast.Node) {
+	switch n := n.(type) {
+	case *ast.Text:
+		value := n.Segment.Value(s.src)
+		if !n.IsRaw() {
+			value = unescape(value)
+		}
+
+		s.addText(string(value))
+		switch {
+		case n.HardLineBreak():
+			s.handle("hardbreak", n)
+		case n.SoftLineBreak():
+			if _, ok := s.parser.Tokens["softbreak"]; ok {
+				s.handle("softbreak", n)
+			} else {
+				s.addText("\n")
+			}
+		}
+	case *ast.String:
+		s.addText(string(n.Value))
+	case *ast.Paragraph, *ast.TextBlock:
+		// a paragraph made only of link reference definitions is left empty.
+		if n.Lines().Len() == 0 && !n.HasChildren() {
+			return
+		}
+
+		s.handle(tokenName(n), n)
+	default:
+		s.handle(tokenName(n), n)
+	}
+}
+
+func (s *parseState) handle(token string, n ast.Node) {
+	spec, ok := s.parser.Tokens[token]
+	switch {
+	case !ok:
+		s.warnings.Warn(token, "no parse spec for token")
+	case spec.Ignore:
+	case spec.Block != "":
+		typ, ok := s.parser.Schema.Nodes[spec.Block]
+		if !ok {
+			s.warnings.Warn(token, "node type %s is not in the schema", spec.Block)
+			s.renderContent(n)
+			return
+		}
+
+		s.open(typ, s.attrs(spec, n))
+		s.renderContent(n)
+		s.close(token)
+	case spec.Node != "":
+		typ, ok := s.parser.Schema.Nodes[spec.Node]
+		if !ok {
+			s.warnings.Warn(token, "node type %s is not in the schema", spec.Node)
+			return
+		}
+
+		node, err := typ.CreateAndFill(s.attrs(spec, n), s.allowedMarks(token))
+		if err != nil {
+			s.warnings.Warn(token, "%s", err)
+			return
+		}
+
+		s.addNode(token, node)
+	case spec.Mark != "":
+		typ, ok := s.parser.Schema.Marks[spec.Mark]
+		if !ok {
+			s.warnings.Warn(token, "mark type %s is not in the schema", spec.Mark)
+			s.renderContent(n)
+			return
+		}
+
+		marks := s.marks
+		s.marks = typ.Create(s.attrs(spec, n)).AddToSet(marks)
+		s.renderContent(n)
+		s.marks = marks
+	default:
+		s.warnings.Warn(token, "empty parse spec")
+	}
+}
+
+// renderContent adds the content of an AST node to the document.
+func (s *parseState) renderContent(n ast.Node) {
+	switch n := n.(type) {
+	case *ast.CodeSpan:
+		s.addText(codeSpanText(n, s.src))
+	case *ast.AutoLink:
+		s.addText(string(n.Label(s.src)))
+	case *ast.RawHTML:
+		s.addText(segmentsText(n.Segments, s.src))
+	case *ast.CodeBlock, *ast.FencedCodeBlock:
+		s.addText(strings.TrimSuffix(segmentsText(n.Lines(), s.src), "\n"))
+	case *ast.HTMLBlock:
+		text := segmentsText(n.Lines(), s.src)
+		if n.HasClosure() {
+			text += string(n.ClosureLine.Value(s.src))
+		}
+
+		s.addText(strings.TrimSuffix(text, "\n"))
+	default:
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			s.render(c)
+		}
+	}
+}
+
+// tokenName returns the name of the token of an AST node.
+func tokenName(n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.Paragraph, *ast.TextBlock:
+		return "paragraph"
+	case *ast.Heading:
+		return "heading"
+	case *ast.Blockquote:
+		return "blockquote"
+	case *ast.CodeBlock:
+		return "code_block"
+	case *ast.FencedCodeBlock:
+		return "fence"
+	case *ast.ThematicBreak:
+		return "hr"
+	case *ast.List:
+		if n.IsOrdered() {
+			return "ordered_list"
+		}
+
+		return "bullet_list"
+	case *ast.ListItem:
+		return "list_item"
+	case *ast.HTMLBlock:
+		return "html_block"
+	case *ast.Emphasis:
+		if n.Level == 2 {
+			return "strong"
+		}
+
+		return "em"
+	case *ast.Link, *ast.AutoLink:
+		return "link"
+	case *ast.Image:
+		return "image"
+	case *ast.CodeSpan:
+		return "code_inline"
+	case *ast.RawHTML:
+		return "html_inline"
+	}
+
+	return n.Kind().String()
+}
+
+// unescape resolves the backslash escapes and character references of Markdown text.
+func unescape(b []byte) []byte {
+	return util.ResolveEntityNames(util.ResolveNumericReferences(util.UnescapePunctuations(b)))
+}
+
+// PlainText returns the text content of an AST node, such as the alt text of an image.
+func PlainText(n ast.Node, source []byte) string {
+	var sb strings.Builder
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := n.(type) {
+		case *ast.Text:
+			sb.Write(unescape(n.Segment.Value(source)))
+			if n.SoftLineBreak() {
+				sb.WriteByte('\n')
+			}
+		case *ast.String:
+			sb.Write(n.Value)
+		case *ast.CodeSpan:
+			sb.WriteString(codeSpanText(n, source))
+			return ast.WalkSkipChildren, nil
+		case *ast.AutoLink:
+			sb.Write(n.Label(source))
+		}
+
+		return ast.WalkContinue, nil
+	})
+
+	return sb.String()
+}
+
+func codeSpanText(n *ast.CodeSpan, source []byte) string {
+	var sb strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		t, ok := c.(*ast.Text)
+		if !ok {
+			continue
+		}
+
+		value := t.Segment.Value(source)
+		if v, found := strings.CutSuffix(string(value), "\n"); found {
+			sb.WriteString(v + " ")
+		} else {
+			sb.Write(value)
+		}
+	}
+
+	return sb.String()
+}
+
+func segmentsText(segments *text.Segments, source []byte) string {
+	var sb strings.Builder
+	for i := 0; i < segments.Len(); i++ {
+		segment := segments.At(i)
+		sb.Write(segment.Value(source))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,197 @@
+package markdown_test
+
+import (
+	"strings"
+	"testing"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/builder"
+	"github.com/karitham/prosemirror/markdown"
+	"github.com/karitham/prosemirror/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParser(t *testing.T) {
+	s := builder.Schema()
+	b := builder.New(s)
+	doc, para, heading, blockquote, hr := b.Node("doc"), b.Node("paragraph"), b.Node("heading"), b.Node("blockquote"), b.Node("horizontal_rule")
+	codeBlock, br, img := b.Node("code_block"), b.Node("hard_break"), b.Node("image")
+	ul, ol, li := b.Node("bullet_list"), b.Node("ordered_list"), b.Node("list_item")
+	em, strong, code, link := b.Mark("em"), b.Mark("strong"), b.Mark("code"), b.Mark("link")
+
+	tests := []struct {
+		name string
+		md   string
+		want builder.Node
+	}{
+		{
+			name: "paragraphs",
+			md:   "one\ntwo\n\nthree",
+			want: doc(para("one\ntwo"), para("three")),
+		},
+		{
+			name: "headings",
+			md:   "## Title\n\nSub\n===",
+			want: doc(heading(map[string]any{"level": 2}, "Title"), heading(map[string]any{"level": 1}, "Sub")),
+		},
+		{
+			name: "escapes and entities",
+			md:   `\# not \*a\* heading &amp; &#35;`,
+			want: doc(para("# not *a* heading & #")),
+		},
+		{
+			name: "marks",
+			md:   "a *b **c*** `x*y`",
+			want: doc(para("a ", em("b ", strong("c")), " ", code("x*y"))),
+		},
+		{
+			name: "links",
+			md:   `[here](https://x.org/a\(b\) "T") <https://x.org>`,
+			want: doc(para(
+				link(map[string]any{"href": "https://x.org/a(b)", "title": "T"}, "here"),
+				" ",
+				link(map[string]any{"href": "https://x.org"}, "https://x.org"),
+			)),
+		},
+		{
+			name: "code blocks",
+			md:   "```go\nfmt.Println(\"*\")\n```\n\n    indented\n",
+			want: doc(
+				codeBlock(map[string]any{"language": "go"}, "fmt.Println(\"*\")"),
+				codeBlock("indented"),
+			),
+		},
+		{
+			name: "blockquotes and rules",
+			md:   "> a\n>\n> b\n\n---",
+			want: doc(blockquote(para("a"), para("b")), hr()),
+		},
+		{
+			name: "hard breaks and images",
+			md:   "a\\\nb![an *image*](i.png \"T\")",
+			want: doc(para(
+				"a", br(), "b",
+				img(map[string]any{"src": "i.png", "alt": "an image", "title": "T"}),
+			)),
+		},
+		{
+			name: "lists",
+			md:   "* a\n  * nested\n* b\n\n9. nine\n\n10. ten",
+			want: doc(
+				ul(map[string]any{"tight": true},
+					li(para("a"), ul(map[string]any{"tight": true}, li(para("nested")))),
+					li(para("b")),
+				),
+				ol(map[string]any{"order": 9, "tight": false},
+					li(para("nine")),
+					li(para("ten")),
+				),
+			),
+		},
+		{
+			name: "link reference definitions",
+			md:   "[ref]: http://x\n\nhello [ref]",
+			want: doc(para("hello ", link(map[string]any{"href": "http://x"}, "ref"))),
+		},
+		{
+			name: "empty",
+			md:   "",
+			want: doc(para()),
+		},
+	}
+
+	parser := markdown.NewParser(s, markdown.DefaultParseSpecs())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := parser.Parse([]byte(tt.md))
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Empty(t, warnings)
+			assert.Equal(t, tt.want.String(), got.String())
+			assert.True(t, tt.want.Eq(got), "attributes differ")
+			assert.NoError(t, got.Type.CheckContent(got.Content))
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		md := "# Title\n\nsome *em* and **strong** `code`\n\n> quoted\n\n* a\n* b\n\n```go\nx := 1\n```"
+		got, _, err := parser.Parse([]byte(md))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var sb strings.Builder
+		if assert.NoError(t, markdown.DefaultSerializer.Serialize(&sb, got)) {
+			assert.Equal(t, md, sb.String())
+		}
+	})
+
+	t.Run("tight list", func(t *testing.T) {
+		got, _, err := parser.Parse([]byte("- a\n- b"))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var sb strings.Builder
+		if assert.NoError(t, markdown.DefaultSerializer.Serialize(&sb, got)) {
+			assert.Equal(t, "* a\n* b", sb.String())
+		}
+	})
+}
+
+func TestParserUnsupported(t *testing.T) {
+	s := p.Must(p.NewSchema(schema.DefaultSpec))
+	parser := markdown.NewParser(s, markdown.DefaultParseSpecs())
+
+	b := builder.New(s)
+	doc, para, heading, codeBlock, em := b.Node("doc"), b.Node("paragraph"), b.Node("heading"), b.Node("code_block"), b.Mark("em")
+
+	got, warnings, err := parser.Parse([]byte("* a\n* b\n\n# *title*\n\n<div>html</div>\n\nx <b>y</b>"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	want := doc(
+		para("a"),
+		para("b"),
+		heading(map[string]any{"level": 1}, em("title")),
+		para("x y"),
+	)
+	assert.Equal(t, want.String(), got.String())
+	assert.True(t, want.Eq(got), "attributes differ")
+	assert.Equal(t, []markdown.Warning{
+		{Source: "bullet_list", Reason: "node type bullet_list is not in the schema"},
+		{Source: "list_item", Reason: "node type list_item is not in the schema"},
+		{Source: "html_block", Reason: "no parse spec for token"},
+		{Source: "html_inline", Reason: "no parse spec for token"},
+	}, warnings)
+
+	t.Run("disallowed marks", func(t *testing.T) {
+		specs := markdown.DefaultParseSpecs()
+		specs["heading"] = markdown.ParseSpec{Block: "code_block"}
+
+		got, warnings, err := markdown.NewParser(s, specs).Parse([]byte("a\n\n# b *c*"))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		want := doc(para("a"), codeBlock("b c"))
+		assert.Equal(t, want.String(), got.String())
+		assert.True(t, want.Eq(got), "attributes differ")
+		assert.Equal(t, []markdown.Warning{{Source: "text", Reason: "mark em is not allowed in code_block"}}, warnings)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		strict := parser
+		strict.Strict = true
+
+		_, warnings, err := strict.Parse([]byte("a <b>b</b>"))
+		assert.Len(t, warnings, 1, "warnings are reported once per token and reason")
+		assert.EqualError(t, err, "cannot represent markdown: html_inline: no parse spec for token")
+
+		_, _, err = strict.Parse([]byte("# a"))
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,584 @@
+// Package markdown converts documents to and from Markdown, like prosemirror-markdown.
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/karitham/prosemirror"
+)
+
+// NodeSerializer renders a node to Markdown. `parent` is the node's parent, and `index` its index in it.
+type NodeSerializer func(s *SerializerState, node, parent prosemirror.Node, index int)
+
+// MarkSerializer describes how a mark is rendered to Markdown.
+type MarkSerializer struct {
+	// Open and Close return the delimiters put around the marked content.
+	// `parent` is the node holding the content, and `index` the index of the content's
+	// first node for Open, and the index after its last node for Close.
+	Open, Close func(s *SerializerState, mark prosemirror.Mark, parent prosemirror.Node, index int) string
+
+	// Whether the mark can be opened and closed in a different order than other mixable marks, like `*` and `**`.
+	Mixable bool
+
+	// Whether whitespace at the start and end of the marked content is moved outside of the mark.
+	ExpelEnclosingWhitespace bool
+
+	// Whether the marked text is escaped. Defaults to true. When false, the mark
+	// must be the innermost one, which it is when it has the highest rank.
+	Escape *bool
+}
+
+func (m MarkSerializer) escape() bool {
+	return m.Escape == nil || *m.Escape
+}
+
+// Delim returns a mark delimiter function always returning `s`.
+func Delim(s string) func(*SerializerState, prosemirror.Mark, prosemirror.Node, int) string {
+	return func(*SerializerState, prosemirror.Mark, prosemirror.Node, int) string { return s }
+}
+
+// SerializerOptions are the options of a Serializer.
+type SerializerOptions struct {
+	// Extra characters to escape in text.
+	EscapeExtraCharacters *regexp.Regexp
+
+	// The name of the hard break node type, whose marks are dropped when they
+	// end with it. Defaults to "hard_break".
+	HardBreakNodeName prosemirror.NodeTypeName
+
+	// Whether rendering a node or mark without a serializer is an error. Defaults to true.
+	// When false, such nodes render their content, and such marks are left out.
+	Strict *bool
+
+	// Whether lists without a `tight` attribute are rendered tight.
+	TightLists bool
+}
+
+// Serializer renders documents to Markdown, using a serializer for each node and mark type.
+type Serializer struct {
+	Nodes   map[prosemirror.NodeTypeName]NodeSerializer
+	Marks   map[prosemirror.MarkTypeName]MarkSerializer
+	Options SerializerOptions
+}
+
+// NewSerializer creates a serializer with the given node and mark serializers.
+func NewSerializer(nodes map[prosemirror.NodeTypeName]NodeSerializer, marks map[prosemirror.MarkTypeName]MarkSerializer, opts SerializerOptions) Serializer {
+	if opts.HardBreakNodeName == "" {
+		opts.HardBreakNodeName = "hard_break"
+	}
+
+	return Serializer{Nodes: nodes, Marks: marks, Options: opts}
+}
+
+// Serialize writes the content of the given node as Markdown to w.
+func (ser Serializer) Serialize(w io.Writer, content prosemirror.Node) error {
+	s := &SerializerState{ser: ser}
+	s.RenderContent(content)
+	if s.err != nil {
+		return s.err
+	}
+
+	_, err := io.WriteString(w, s.out.String())
+	return err
+}
+
+// SerializerState tracks the state of a Markdown serialization, and is
+// used by node and mark serializers to write their output.
+type SerializerState struct {
+	ser Serializer
+	out strings.Builder
+	err error
+
+	// The delimiter put at the start of every line, like "> " in blockquotes.
+	Delim string
+
+	// The last closed block, whose trailing blank line isn't written yet.
+	Closed *prosemirror.Node
+
+	// Whether the current link is rendered as an autolink.
+	InAutolink bool
+
+	// Whether the output is at the start of a block.
+	AtBlockStart bool
+
+	// Whether the current list is tight.
+	InTightList bool
+}
+
+// Out returns the output written so far.
+func (s *SerializerState) Out() string {
+	return s.out.String()
+}
+
+// Error records an error, making the serialization fail. Only the first error is kept.
+func (s *SerializerState) Error(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+func (s *SerializerState) strict() bool {
+	return s.ser.Options.Strict == nil || *s.ser.Options.Strict
+}
+
+func (s *SerializerState) flushClose(size int) {
+	if s.Closed == nil {
+		return
+	}
+
+	if !s.atBlank() {
+		s.out.WriteByte('\n')
+	}
+
+	if size > 1 {
+		delimMin := strings.TrimRightFunc(s.Delim, unicode.IsSpace)
+		for i := 1; i < size; i++ {
+			s.out.WriteString(delimMin)
+			s.out.WriteByte('\n')
+		}
+	}
+
+	s.Closed = nil
+}
+
+func (s *SerializerState) getMark(name prosemirror.MarkTypeName) (MarkSerializer, bool) {
+	info, ok := s.ser.Marks[name]
+	if !ok && s.strict() {
+		s.Error(fmt.Errorf("mark type %q not supported by the Markdown serializer", name))
+	}
+
+	return info, ok
+}
+
+// WrapBlock renders a block, prefixing each of its lines with `delim`.
+// `firstDelim` is used for the first line instead, when not empty.
+func (s *SerializerState) WrapBlock(delim, firstDelim string, node prosemirror.Node, f func()) {
+	old := s.Delim
+	if firstDelim == "" {
+		firstDelim = delim
+	}
+
+	s.Write(firstDelim)
+	s.Delim += delim
+	f()
+	s.Delim = old
+	s.CloseBlock(node)
+}
+
+func (s *SerializerState) atBlank() bool {
+	out := s.out.String()
+	return out == "" || strings.HasSuffix(out, "\n")
+}
+
+// EnsureNewLine starts a new line, unless the output is already at the start of one.
+func (s *SerializerState) EnsureNewLine() {
+	if !s.atBlank() {
+		s.out.WriteByte('\n')
+	}
+}
+
+// Write adds the given content to the output, closing the last block
+// and writing the line delimiter when needed.
+func (s *SerializerState) Write(content string) {
+	s.flushClose(2)
+	if s.Delim != "" && s.atBlank() {
+		s.out.WriteString(s.Delim)
+	}
+
+	s.out.WriteString(content)
+}
+
+// CloseBlock closes the given block node, which causes a blank line to be written before the next content.
+func (s *SerializerState) CloseBlock(node prosemirror.Node) {
+	s.Closed = &node
+}
+
+var bangRe = regexp.MustCompile(`(^|[^\\])!$`)
+
+// Text adds text to the output, escaping it when `escape` is true.
+func (s *SerializerState) Text(text string, escape bool) {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		s.Write("")
+
+		// escape exclamation marks in front of links
+		if !escape && strings.HasPrefix(line, "[") && bangRe.MatchString(s.out.String()) {
+			out := s.out.String()
+			s.out.Reset()
+			s.out.WriteString(out[:len(out)-1])
+			s.out.WriteString(`\!`)
+		}
+
+		if escape {
+			line = s.Esc(line, s.AtBlockStart)
+		}
+
+		s.out.WriteString(line)
+		if i != len(lines)-1 {
+			s.out.WriteByte('\n')
+		}
+	}
+}
+
+// Render renders a node using its serializer.
+func (s *SerializerState) Render(node, parent prosemirror.Node, index int) {
+	if render, ok := s.ser.Nodes[node.Type.Name]; ok {
+		render(s, node, parent, index)
+		return
+	}
+
+	if s.strict() {
+		s.Error(fmt.Errorf("node type %q not supported by the Markdown serializer", node.Type.Name))
+		return
+	}
+
+	if !node.IsLeaf() {
+		if node.InlineContent() {
+			s.RenderInline(node, true)
+		} else {
+			s.RenderContent(node)
+		}
+
+		if node.IsBlock() {
+			s.CloseBlock(node)
+		}
+	}
+}
+
+// RenderContent renders the children of a node.
+func (s *SerializerState) RenderContent(parent prosemirror.Node) {
+	for i, child := range parent.Content.Content {
+		s.Render(child, parent, i)
+	}
+}
+
+var (
+	leadingSpaceRe  = regexp.MustCompile(`^\s+`)
+	trailingSpaceRe = regexp.MustCompile(`\s+$`)
+	nonSpaceRe      = regexp.MustCompile(`\S`)
+)
+
+// RenderInline renders the inline content of a node, opening and closing marks as needed.
+// `fromBlockStart` tells whether the content is at the start of a block, where more characters are escaped.
+func (s *SerializerState) RenderInline(parent prosemirror.Node, fromBlockStart bool) {
+	s.AtBlockStart = fromBlockStart
+
+	var active []prosemirror.Mark
+	trailing := ""
+	progress := func(node *prosemirror.Node, index int) {
+		var marks []prosemirror.Mark
+		if node != nil {
+			marks = node.Marks
+		}
+
+		// Remove marks from hard breaks that are the last node inside
+		// that mark to prevent parser edge cases with new lines just
+		// before closing marks.
+		if node != nil && node.Type.Name == s.ser.Options.HardBreakNodeName {
+			var kept []prosemirror.Mark
+			for _, m := range marks {
+				if index+1 == parent.ChildCount() {
+					continue
+				}
+
+				next := parent.Child(index + 1)
+				if m.IsInSet(next.Marks) && (!next.IsText() || nonSpaceRe.MatchString(next.Text)) {
+					kept = append(kept, m)
+				}
+			}
+
+			marks = kept
+		}
+
+		leading := trailing
+		trailing = ""
+
+		// If whitespace has to be expelled from the node, adjust
+		// leading and trailing accordingly.
+		expel := func(end bool) bool {
+			for _, m := range marks {
+				info, ok := s.getMark(m.Type.Name)
+				if !ok || !info.ExpelEnclosingWhitespace {
+					continue
+				}
+
+				if !end && !m.IsInSet(active) ||
+					end && (index == parent.ChildCount()-1 || !m.IsInSet(parent.Child(index+1).Marks)) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if node != nil && node.IsText() && expel(false) {
+			if lead := leadingSpaceRe.FindString(node.Text); lead != "" {
+				leading += lead
+				node = withText(*node, node.Text[len(lead):])
+				if node == nil {
+					marks = active
+				}
+			}
+		}
+
+		if node != nil && node.IsText() && expel(true) {
+			if trail := trailingSpaceRe.FindString(node.Text); trail != "" {
+				trailing = trail
+				node = withText(*node, node.Text[:len(node.Text)-len(trail)])
+				if node == nil {
+					marks = active
+				}
+			}
+		}
+
+		// marks without a serializer are left out
+		marks = s.knownMarks(marks)
+
+		var inner *prosemirror.Mark
+		var innerInfo MarkSerializer
+		if len(marks) > 0 {
+			inner = &marks[len(marks)-1]
+			innerInfo = s.ser.Marks[inner.Type.Name]
+		}
+
+		noEsc := inner != nil && !innerInfo.escape()
+		length := len(marks)
+		if noEsc {
+			length--
+		}
+
+		// Try to reorder 'mixable' marks, such as em and strong, which
+		// in Markdown may be opened and closed in different order, so
+		// that order of the marks for the token matches the order in
+		// active.
+		marks = reorderMixable(marks, active, length, s.ser.Marks)
+
+		// Find the prefix of the mark set that didn't change
+		keep := 0
+		for keep < min(len(active), length) && marks[keep].Eq(active[keep]) {
+			keep++
+		}
+
+		// Close the marks that need to be closed
+		for keep < len(active) {
+			m := active[len(active)-1]
+			active = active[:len(active)-1]
+			s.Text(s.markString(m, false, parent, index), false)
+		}
+
+		// Output any previously expelled trailing whitespace outside the marks
+		if leading != "" {
+			s.Text(leading, true)
+		}
+
+		// Open the marks that need to be opened
+		if node != nil {
+			for len(active) < length {
+				add := marks[len(active)]
+				active = append(active, add)
+				s.Text(s.markString(add, true, parent, index), false)
+				s.AtBlockStart = false
+			}
+
+			// Render the node. Special case code marks, since their content
+			// may not be escaped.
+			if noEsc && node.IsText() {
+				s.Text(s.markString(*inner, true, parent, index)+node.Text+s.markString(*inner, false, parent, index+1), false)
+			} else {
+				s.Render(*node, parent, index)
+			}
+
+			s.AtBlockStart = false
+		}
+
+		// After the first non-empty text node is rendered, the end of output
+		// is no longer at block start.
+		if node != nil && node.IsText() && node.NodeSize() > 0 {
+			s.AtBlockStart = false
+		}
+	}
+
+	for i := range parent.Content.Content {
+		progress(&parent.Content.Content[i], i)
+	}
+
+	progress(nil, parent.ChildCount())
+	s.AtBlockStart = false
+}
+
+func (s *SerializerState) knownMarks(marks []prosemirror.Mark) []prosemirror.Mark {
+	var known []prosemirror.Mark
+	for i, m := range marks {
+		if _, ok := s.getMark(m.Type.Name); ok {
+			if known != nil {
+				known = append(known, m)
+			}
+
+			continue
+		}
+
+		if known == nil {
+			known = append([]prosemirror.Mark{}, marks[:i]...)
+		}
+	}
+
+	if known == nil {
+		return marks
+	}
+
+	return known
+}
+
+// reorderMixable reorders the first `length` marks so that the mixable ones
+// already active keep their position.
+func reorderMixable(marks, active []prosemirror.Mark, length int, infos map[prosemirror.MarkTypeName]MarkSerializer) []prosemirror.Mark {
+outer:
+	for i := 0; i < length; i++ {
+		mark := marks[i]
+		if !infos[mark.Type.Name].Mixable {
+			break
+		}
+
+		for j, other := range active {
+			if !infos[other.Type.Name].Mixable {
+				break
+			}
+
+			if !mark.Eq(other) {
+				continue
+			}
+
+			reordered := make([]prosemirror.Mark, 0, len(marks))
+			switch {
+			case i > j:
+				reordered = append(reordered, marks[:j]...)
+				reordered = append(reordered, mark)
+				reordered = append(reordered, marks[j:i]...)
+				reordered = append(reordered, marks[i+1:]...)
+			case j > i:
+				reordered = append(reordered, marks[:i]...)
+				reordered = append(reordered, marks[i+1:j]...)
+				reordered = append(reordered, mark)
+				reordered = append(reordered, marks[j:]...)
+			default:
+				continue outer
+			}
+
+			marks = reordered
+			continue outer
+		}
+	}
+
+	return marks
+}
+
+func withText(n prosemirror.Node, text string) *prosemirror.Node {
+	if text == "" {
+		return nil
+	}
+
+	n.Text = text
+	return &n
+}
+
+// RenderList renders a list node. `delim` is the indentation of the items' content,
+// and `firstDelim` returns the marker of the item at the given index.
+func (s *SerializerState) RenderList(node prosemirror.Node, delim string, firstDelim func(index int) string) {
+	switch {
+	case s.Closed != nil && s.Closed.Type.Name == node.Type.Name:
+		s.flushClose(3)
+	case s.InTightList:
+		s.flushClose(1)
+	}
+
+	isTight := s.ser.Options.TightLists
+	if tight, ok := node.Attrs["tight"].(bool); ok {
+		isTight = tight
+	}
+
+	prevTight := s.InTightList
+	s.InTightList = isTight
+	for i, child := range node.Content.Content {
+		if i > 0 && isTight {
+			s.flushClose(1)
+		}
+
+		s.WrapBlock(delim, firstDelim(i), node, func() { s.Render(child, node, i) })
+	}
+
+	s.InTightList = prevTight
+}
+
+var (
+	escapeRe       = regexp.MustCompile("[`*\\\\~\\[\\]_]")
+	lineStartRe    = regexp.MustCompile(`^(\+[ ]|[\-*>])`)
+	headingStartRe = regexp.MustCompile(`^(\s*)(#{1,6})(\s|$)`)
+	orderedStartRe = regexp.MustCompile(`^(\s*\d+)\.\s`)
+)
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// Esc escapes the Markdown syntax characters in `str`. When `startOfLine` is true,
+// characters that are only special at the start of a line are escaped too.
+func (s *SerializerState) Esc(str string, startOfLine bool) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range escapeRe.FindAllStringIndex(str, -1) {
+		i := loc[0]
+		if str[i] == '_' && i > 0 && i+1 < len(str) && isWordByte(str[i-1]) && isWordByte(str[i+1]) {
+			continue
+		}
+
+		sb.WriteString(str[last:i])
+		sb.WriteByte('\\')
+		last = i
+	}
+
+	sb.WriteString(str[last:])
+	str = sb.String()
+
+	if startOfLine {
+		str = lineStartRe.ReplaceAllString(str, `\$1`)
+		str = headingStartRe.ReplaceAllString(str, `$1\$2$3`)
+		str = orderedStartRe.ReplaceAllString(str, `$1\. `)
+	}
+
+	if s.ser.Options.EscapeExtraCharacters != nil {
+		str = s.ser.Options.EscapeExtraCharacters.ReplaceAllString(str, `\$0`)
+	}
+
+	return str
+}
+
+// Quote wraps a string in double quotes, single quotes or parentheses,
+// whichever it doesn't contain, for use as a link title.
+func (s *SerializerState) Quote(str string) string {
+	switch {
+	case !strings.Contains(str, `"`):
+		return `"` + str + `"`
+	case !strings.Contains(str, "'"):
+		return "'" + str + "'"
+	default:
+		return "(" + str + ")"
+	}
+}
+
+func (s *SerializerState) markString(mark prosemirror.Mark, open bool, parent prosemirror.Node, index int) string {
+	info := s.ser.Marks[mark.Type.Name]
+	f := info.Close
+	if open {
+		f = info.Open
+	}
+
+	if f == nil {
+		return ""
+	}
+
+	return f(s, mark, parent, index)
+}
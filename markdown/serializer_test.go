@@ -0,0 +1,119 @@
+package markdown_test
+
+import (
+	"strings"
+	"testing"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/builder"
+	"github.com/karitham/prosemirror/markdown"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerializer(t *testing.T) {
+	b := builder.New(builder.Schema())
+	doc, para, heading, blockquote, hr := b.Node("doc"), b.Node("paragraph"), b.Node("heading"), b.Node("blockquote"), b.Node("horizontal_rule")
+	codeBlock, br, img := b.Node("code_block"), b.Node("hard_break"), b.Node("image")
+	ul, ol, li := b.Node("bullet_list"), b.Node("ordered_list"), b.Node("list_item")
+	em, strong, code := b.Mark("em"), b.Mark("strong"), b.Mark("code")
+	link := func(href string) builder.MarkBuilder { return b.Mark("link").With(map[string]any{"href": href}) }
+
+	tests := []struct {
+		name string
+		doc  builder.Node
+		want string
+	}{
+		{
+			name: "paragraphs",
+			doc:  doc(para("one"), para("two")),
+			want: "one\n\ntwo",
+		},
+		{
+			name: "headings",
+			doc:  doc(heading(map[string]any{"level": 2}, "Title"), para("body")),
+			want: "## Title\n\nbody",
+		},
+		{
+			name: "escapes text",
+			doc:  doc(para("# not *a* heading_or snake_ case [x]"), para("1. item")),
+			want: "\\# not \\*a\\* heading_or snake\\_ case \\[x\\]\n\n1\\. item",
+		},
+		{
+			name: "marks",
+			doc:  doc(para("a ", em("b ", strong("c")), strong(" d"), "e")),
+			want: "a *b **c***** d**e",
+		},
+		{
+			name: "reorders mixable marks",
+			doc:  doc(para(strong("a", em("b"), "c"))),
+			want: "**a*b*c**",
+		},
+		{
+			name: "inline code",
+			doc:  doc(para("use ", code("a*b"), " or ", code("x`y"))),
+			want: "use `a*b` or `` x`y ``",
+		},
+		{
+			name: "links",
+			doc:  doc(para("see ", link("https://x.org/a(b)")("here"), " or ", link("https://x.org")("https://x.org"))),
+			want: "see [here](https://x.org/a\\(b\\)) or <https://x.org>",
+		},
+		{
+			name: "code blocks",
+			doc: doc(
+				codeBlock(map[string]any{"language": "go"}, "fmt.Println(\"*\")"),
+				codeBlock("```\nnested\n```"),
+			),
+			want: "```go\nfmt.Println(\"*\")\n```\n\n````\n```\nnested\n```\n````",
+		},
+		{
+			name: "blockquotes and rules",
+			doc:  doc(blockquote(para("a"), para("b")), hr()),
+			want: "> a\n>\n> b\n\n---",
+		},
+		{
+			name: "hard breaks and images",
+			doc: doc(para(
+				"a", br(), "b",
+				img(map[string]any{"src": "i.png", "alt": "an *image*", "title": "T"}),
+				br(),
+			)),
+			want: "a\\\nb![an \\*image\\*](i.png \"T\")",
+		},
+		{
+			name: "loose list",
+			doc:  doc(ul(li(para("a")), li(para("b")))),
+			want: "* a\n\n* b",
+		},
+		{
+			name: "tight list",
+			doc: doc(ul(map[string]any{"tight": true},
+				li(para("a"), ul(map[string]any{"tight": true}, li(para("nested")))),
+				li(para("b")),
+			)),
+			want: "* a\n  * nested\n* b",
+		},
+		{
+			name: "ordered list",
+			doc: doc(ol(map[string]any{"order": 9, "tight": true},
+				li(para("nine")),
+				li(para("ten")),
+			)),
+			want: " 9. nine\n10. ten",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			if assert.NoError(t, markdown.DefaultSerializer.Serialize(&sb, tt.doc.Node)) {
+				assert.Equal(t, tt.want, sb.String())
+			}
+		})
+	}
+
+	t.Run("unknown node", func(t *testing.T) {
+		ser := markdown.NewSerializer(map[p.NodeTypeName]markdown.NodeSerializer{}, nil, markdown.SerializerOptions{})
+		assert.Error(t, ser.Serialize(&strings.Builder{}, doc(para("a")).Node))
+	})
+}
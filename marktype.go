@@ -3,6 +3,7 @@ package prosemirror
 import (
 	"fmt"
 	"maps"
+	"strings"
 
 	"github.com/go-json-experiment/json"
 )
@@ -14,8 +15,8 @@ type MarkTypeName string
 type MarkType struct {
 	// The Name of the mark type.
 	Name MarkTypeName
-	// The rank of the mark type.
-	// Rank int
+	// The rank of the mark type, which decides the order marks are kept in.
+	Rank int
 
 	// The schema this mark type is part of.
 	Schema Schema
@@ -61,6 +62,10 @@ func (mt MarkType) Create(attrs map[string]any) Mark {
 		return *mt.Instance
 	}
 
+	if built, err := computeAttrs(mt.Attrs, attrs); err == nil {
+		attrs = built
+	}
+
 	return Mark{
 		Type:  mt,
 		Attrs: attrs,
@@ -68,23 +73,25 @@ func (mt MarkType) Create(attrs map[string]any) Mark {
 }
 
 // Remove this mark type from a mark set, if present.
-func (mt MarkType) RemoveFromSet(set []MarkType) []MarkType {
+func (mt MarkType) RemoveFromSet(set []Mark) []Mark {
 	for i, m := range set {
-		if m.Name == mt.Name {
-			return append(set[:i], set[i+1:]...)
+		if m.Type.Name == mt.Name {
+			return append(set[:i:i], set[i+1:]...)
 		}
 	}
+
 	return set
 }
 
-// Check if this mark type is in a mark set.
-func (mt MarkType) IsInSet(set []MarkType) MarkType {
-	for _, m := range set {
-		if m.Name == mt.Name {
-			return m
+// Check if a mark of this type is in a mark set.
+func (mt MarkType) IsInSet(set []Mark) *Mark {
+	for i, m := range set {
+		if m.Type.Name == mt.Name {
+			return &set[i]
 		}
 	}
-	return MarkType{}
+
+	return nil
 }
 
 // Check if this mark type excludes another type.
@@ -97,6 +104,17 @@ func (mt MarkType) Excludes(other MarkType) bool {
 	return false
 }
 
+// IsInclusive reports whether this mark should be active when the cursor
+// is positioned at its end.
+func (mt MarkType) IsInclusive() bool {
+	return mt.Spec.Inclusive == nil || *mt.Spec.Inclusive
+}
+
+// IsSpanning reports whether this mark can span multiple nodes.
+func (mt MarkType) IsSpanning() bool {
+	return mt.Spec.Spanning == nil || *mt.Spec.Spanning
+}
+
 func (m MarkType) Eq(other MarkType) bool {
 	return m.Name == other.Name &&
 		maps.Equal(m.Attrs, other.Attrs)
@@ -106,17 +124,27 @@ type MarkSpec struct {
 	// The attributes this mark can have.
 	Attrs map[string]Attribute
 
-	// Whether this mark should be active at its end.
-	Inclusive bool
+	// Whether this mark should be active at its end. Defaults to true.
+	Inclusive *bool
 
 	// Determines which other marks this can coexist with.
-	Excludes string
+	// Defaults to only excluding marks of the same type.
+	Excludes *string
 
 	// The group or groups this mark belongs to.
 	Group string
 
-	// Whether this mark can span multiple nodes.
-	Spanning bool
+	// Whether this mark can span multiple nodes, so that adjacent nodes
+	// with the mark are serialized in a single element. Defaults to true.
+	Spanning *bool
+
+	// Defines the default way marks of this type should be serialized to HTML.
+	// `inline` tells whether the marked content is inline. Marks without it
+	// are left out of the output.
+	ToDOM func(mark Mark, inline bool) DOMOutputSpec
+
+	// Rules used by DOMParserFromSchema to parse marks of this type from HTML.
+	ParseDOM []ParseRule
 
 	// Additional spec properties.
 	Extra map[string]any
@@ -128,15 +156,43 @@ func NewMarkType(s Schema, name MarkTypeName, spec MarkSpec) MarkType {
 		Schema: s,
 		Spec:   spec,
 		Attrs:  initAttrs(spec.Attrs),
-		// TODO: https://github.com/ProseMirror/prosemirror-model/blob/master/src/schema.ts#L272-L273
+		// Excluded and Instance are filled once the whole schema is compiled.
 	}
 }
 
 func compileMarkTypeSet(s Schema, spec map[MarkTypeName]MarkSpec) (map[MarkTypeName]MarkType, error) {
 	result := map[MarkTypeName]MarkType{}
-	for name, spec := range spec {
-		result[name] = NewMarkType(s, name, spec)
+	for rank, name := range specOrder(spec, s.Spec.MarkOrder) {
+		mt := NewMarkType(s, name, spec[name])
+		mt.Rank = rank
+		result[name] = mt
 	}
 
 	return result, nil
 }
+
+// gatherMarks resolves a space-separated list of mark names or groups.
+// "_" stands for every mark in the schema.
+func gatherMarks(s Schema, names string) ([]MarkType, error) {
+	found := []MarkType{}
+	for _, name := range strings.Fields(names) {
+		if mark, ok := s.Marks[MarkTypeName(name)]; ok {
+			found = append(found, mark)
+			continue
+		}
+
+		ok := false
+		for _, mark := range s.MarkTypes() {
+			if name == "_" || strings.Contains(" "+mark.Spec.Group+" ", " "+name+" ") {
+				found = append(found, mark)
+				ok = true
+			}
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("unknown mark type: %q", name)
+		}
+	}
+
+	return found, nil
+}
@@ -0,0 +1,435 @@
+// Package migrate upgrades stored documents between versions of a schema.
+//
+// Migrations rewrite documents as raw JSON trees, since documents of an old
+// version may not decode with the new schema. They are ordered by version,
+// and a Migrator runs the ones newer than the version stamped on a document,
+// then validates the result with the current schema:
+//
+//	m, err := migrate.New(s,
+//		migrate.Migration{Version: 1, Rewrite: migrate.RenameMark("code", "inline_code")},
+//		migrate.Migration{Version: 2, Rewrite: migrate.DefaultAttr("image", "alt", "")},
+//	)
+//	doc, err := m.Load(stored)
+//	stored, err = m.Save(doc)
+//
+// Documents are saved as {"version": 2, "doc": {...}}. Documents without a
+// version stamp are read as version 0.
+package migrate
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+
+	p "github.com/karitham/prosemirror"
+)
+
+// Node is a node of a document as raw JSON, in the shape of the JSON of p.Node.
+type Node struct {
+	Type    string         `json:"type"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+	Marks   []Mark         `json:"marks,omitempty"`
+	Text    string         `json:"text,omitempty"`
+	Content []Node         `json:"content,omitempty"`
+}
+
+// Mark is a mark of a document as raw JSON, in the shape of the JSON of p.Mark.
+type Mark struct {
+	Type  string         `json:"type"`
+	Attrs map[string]any `json:"attrs,omitempty"`
+}
+
+// Versioned is a document stamped with the version of the schema it was saved with.
+type Versioned struct {
+	Version int            `json:"version"`
+	Doc     jsontext.Value `json:"doc"`
+}
+
+// Rewrite rewrites a node, whose content has already been rewritten, into
+// the nodes replacing it: none removes it, and several unwrap it. Rewrites
+// must not modify the node they are given.
+type Rewrite func(n Node) ([]Node, error)
+
+// Migration upgrades documents to a version of the schema.
+type Migration struct {
+	// The version of the documents once migrated.
+	Version int
+
+	// Describes the migration in errors.
+	Description string
+
+	Rewrite Rewrite
+}
+
+// Migrator runs migrations on documents, up to the current version of a schema.
+type Migrator struct {
+	schema     p.Schema
+	migrations []Migration
+}
+
+// New returns a migrator to the given schema, whose version is the version
+// of the last migration. Migrations must be ordered by increasing version,
+// starting from 1.
+func New(schema p.Schema, migrations ...Migration) (*Migrator, error) {
+	for i, m := range migrations {
+		if m.Version <= 0 || (i > 0 && m.Version <= migrations[i-1].Version) {
+			return nil, fmt.Errorf("migration %d has version %d, versions must start from 1 and increase", i, m.Version)
+		}
+
+		if m.Rewrite == nil {
+			return nil, fmt.Errorf("migration to version %d has no rewrite", m.Version)
+		}
+	}
+
+	return &Migrator{schema: schema, migrations: slices.Clone(migrations)}, nil
+}
+
+// Version returns the current version of the schema.
+func (m *Migrator) Version() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Migrate runs the migrations newer than version on a raw document.
+func (m *Migrator) Migrate(doc Node, version int) (Node, error) {
+	if version > m.Version() {
+		return Node{}, fmt.Errorf("document version %d is newer than the schema version %d", version, m.Version())
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version <= version {
+			continue
+		}
+
+		nodes, err := rewrite(doc, migration.Rewrite, "doc")
+		if err != nil {
+			return Node{}, fmt.Errorf("error migrating to version %d%s: %w", migration.Version, describe(migration), err)
+		}
+
+		if len(nodes) != 1 {
+			return Node{}, fmt.Errorf("error migrating to version %d%s: top node rewritten to %d nodes", migration.Version, describe(migration), len(nodes))
+		}
+
+		doc = nodes[0]
+	}
+
+	return doc, nil
+}
+
+// Load reads a stored document, either stamped with its version or not, and
+// returns it migrated to the current version and validated.
+func (m *Migrator) Load(data []byte) (p.Node, error) {
+	var stored struct {
+		Versioned
+		Type *string `json:"type"`
+	}
+
+	if err := json.Unmarshal(data, &stored, json.DiscardUnknownMembers(true)); err != nil {
+		return p.Node{}, fmt.Errorf("error reading document: %w", err)
+	}
+
+	// a document without stamp
+	raw := stored.Doc
+	if stored.Type != nil {
+		raw, stored.Version = data, 0
+	}
+
+	var doc Node
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return p.Node{}, fmt.Errorf("error reading document: %w", err)
+	}
+
+	migrated, err := m.Migrate(doc, stored.Version)
+	if err != nil {
+		return p.Node{}, err
+	}
+
+	return m.Build(migrated)
+}
+
+// Save returns the document stamped with the current version.
+func (m *Migrator) Save(doc p.Node) ([]byte, error) {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(Versioned{Version: m.Version(), Doc: raw})
+}
+
+// Upgrade migrates a document of an older version of the schema, like one
+// decoded with it, to the current version.
+func (m *Migrator) Upgrade(doc p.Node, version int) (p.Node, error) {
+	raw, err := Raw(doc)
+	if err != nil {
+		return p.Node{}, err
+	}
+
+	migrated, err := m.Migrate(raw, version)
+	if err != nil {
+		return p.Node{}, err
+	}
+
+	return m.Build(migrated)
+}
+
+// Raw returns the raw JSON tree of a node.
+func Raw(n p.Node) (Node, error) {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return Node{}, err
+	}
+
+	var raw Node
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return Node{}, err
+	}
+
+	return raw, nil
+}
+
+// Build creates the document of a raw tree with the current schema, checking
+// its content, attributes and marks.
+func (m *Migrator) Build(doc Node) (p.Node, error) {
+	if top := m.schema.TopNodeType; top != nil && doc.Type != string(top.Name) {
+		return p.Node{}, fmt.Errorf("doc: top node is %s, not %s", doc.Type, top.Name)
+	}
+
+	return m.build(doc, "doc")
+}
+
+func (m *Migrator) build(n Node, path string) (p.Node, error) {
+	typ, ok := m.schema.Nodes[p.NodeTypeName(n.Type)]
+	if !ok {
+		return p.Node{}, fmt.Errorf("%s: unknown node type %q", path, n.Type)
+	}
+
+	if err := checkAttrs(typ.Attrs, n.Attrs); err != nil {
+		return p.Node{}, fmt.Errorf("%s: node %s: %w", path, n.Type, err)
+	}
+
+	marks := make([]p.Mark, 0, len(n.Marks))
+	for _, raw := range n.Marks {
+		mt, ok := m.schema.Marks[p.MarkTypeName(raw.Type)]
+		if !ok {
+			return p.Node{}, fmt.Errorf("%s: unknown mark type %q", path, raw.Type)
+		}
+
+		if err := checkAttrs(mt.Attrs, raw.Attrs); err != nil {
+			return p.Node{}, fmt.Errorf("%s: mark %s: %w", path, raw.Type, err)
+		}
+
+		marks = mt.Create(raw.Attrs).AddToSet(marks)
+	}
+
+	if typ.IsText() {
+		if n.Text == "" {
+			return p.Node{}, fmt.Errorf("%s: empty text node", path)
+		}
+
+		return m.schema.Text(n.Text, marks...), nil
+	}
+
+	content := make([]p.Node, len(n.Content))
+	for i, child := range n.Content {
+		built, err := m.build(child, fmt.Sprintf("%s.content[%d]", path, i))
+		if err != nil {
+			return p.Node{}, err
+		}
+
+		content[i] = built
+	}
+
+	node, err := typ.Create(n.Attrs, marks, content...)
+	if err != nil {
+		return p.Node{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return node, nil
+}
+
+// checkAttrs checks that the required attributes are given. Unknown attributes
+// are dropped when the node or mark is created, like NodeFromJSON does, so that
+// removing an attribute from the schema doesn't break the documents holding it.
+func checkAttrs(spec p.Attrs, attrs map[string]any) error {
+	for name, attr := range spec {
+		if attr.Default == nil && !attr.Optional && attrs[name] == nil {
+			return fmt.Errorf("no value supplied for attribute %s", name)
+		}
+	}
+
+	return nil
+}
+
+// rewrite applies a rewrite to the nodes of a tree, from its leaves up.
+func rewrite(n Node, f Rewrite, path string) ([]Node, error) {
+	if len(n.Content) > 0 {
+		content := make([]Node, 0, len(n.Content))
+		for i, child := range n.Content {
+			nodes, err := rewrite(child, f, fmt.Sprintf("%s.content[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+
+			content = append(content, nodes...)
+		}
+
+		n.Content = content
+	}
+
+	nodes, err := f(n)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nodes, nil
+}
+
+func describe(m Migration) string {
+	if m.Description == "" {
+		return ""
+	}
+
+	return " (" + m.Description + ")"
+}
+
+// Chain returns a rewrite applying the given ones in order.
+func Chain(rewrites ...Rewrite) Rewrite {
+	return func(n Node) ([]Node, error) {
+		nodes := []Node{n}
+		for _, f := range rewrites {
+			var next []Node
+			for _, n := range nodes {
+				out, err := f(n)
+				if err != nil {
+					return nil, err
+				}
+
+				next = append(next, out...)
+			}
+
+			nodes = next
+		}
+
+		return nodes, nil
+	}
+}
+
+// Nodes returns a rewrite applying f to the nodes of the given type.
+func Nodes(typ string, f func(n Node) ([]Node, error)) Rewrite {
+	return func(n Node) ([]Node, error) {
+		if n.Type != typ {
+			return []Node{n}, nil
+		}
+
+		return f(n)
+	}
+}
+
+// Marks returns a rewrite replacing the marks of every node by the marks f
+// returns for them.
+func Marks(f func(m Mark) ([]Mark, error)) Rewrite {
+	return func(n Node) ([]Node, error) {
+		if len(n.Marks) == 0 {
+			return []Node{n}, nil
+		}
+
+		marks := make([]Mark, 0, len(n.Marks))
+		for _, m := range n.Marks {
+			out, err := f(m)
+			if err != nil {
+				return nil, err
+			}
+
+			marks = append(marks, out...)
+		}
+
+		n.Marks = marks
+		return []Node{n}, nil
+	}
+}
+
+// RenameNode renames the nodes of a type.
+func RenameNode(from, to string) Rewrite {
+	return Nodes(from, func(n Node) ([]Node, error) {
+		n.Type = to
+		return []Node{n}, nil
+	})
+}
+
+// Unwrap replaces the nodes of a type by their content.
+func Unwrap(typ string) Rewrite {
+	return Nodes(typ, func(n Node) ([]Node, error) {
+		return slices.Clone(n.Content), nil
+	})
+}
+
+// RenameMark renames the marks of a type.
+func RenameMark(from, to string) Rewrite {
+	return Marks(func(m Mark) ([]Mark, error) {
+		if m.Type == from {
+			m.Type = to
+		}
+
+		return []Mark{m}, nil
+	})
+}
+
+// RemoveMark removes the marks of a type.
+func RemoveMark(typ string) Rewrite {
+	return Marks(func(m Mark) ([]Mark, error) {
+		if m.Type == typ {
+			return nil, nil
+		}
+
+		return []Mark{m}, nil
+	})
+}
+
+// DefaultAttr sets an attribute of the nodes of a type to value, when they
+// have none, like for an attribute which became required.
+func DefaultAttr(typ, name string, value any) Rewrite {
+	return Nodes(typ, func(n Node) ([]Node, error) {
+		if n.Attrs[name] == nil {
+			n.Attrs = maps.Clone(n.Attrs)
+			if n.Attrs == nil {
+				n.Attrs = map[string]any{}
+			}
+
+			n.Attrs[name] = value
+		}
+
+		return []Node{n}, nil
+	})
+}
+
+// RenameAttr renames an attribute of the nodes of a type.
+func RenameAttr(typ, from, to string) Rewrite {
+	return Nodes(typ, func(n Node) ([]Node, error) {
+		if v, ok := n.Attrs[from]; ok {
+			n.Attrs = maps.Clone(n.Attrs)
+			delete(n.Attrs, from)
+			n.Attrs[to] = v
+		}
+
+		return []Node{n}, nil
+	})
+}
+
+// RemoveAttr removes an attribute of the nodes of a type.
+func RemoveAttr(typ, name string) Rewrite {
+	return Nodes(typ, func(n Node) ([]Node, error) {
+		if _, ok := n.Attrs[name]; ok {
+			n.Attrs = maps.Clone(n.Attrs)
+			delete(n.Attrs, name)
+		}
+
+		return []Node{n}, nil
+	})
+}
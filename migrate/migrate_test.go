@@ -0,0 +1,185 @@
+package migrate_test
+
+import (
+	"testing"
+
+	"github.com/go-json-experiment/json"
+	"github.com/stretchr/testify/assert"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/migrate"
+)
+
+func schema(t *testing.T, spec string) p.Schema {
+	t.Helper()
+
+	var s p.SchemaSpec
+	if err := json.Unmarshal([]byte(spec), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	s.DontRegister = true
+	return p.Must(p.NewSchema(s))
+}
+
+// v0 of the schema had a code mark, and images without alt text.
+const v0 = `{
+	"nodes": {
+		"doc": {"content": "block+"},
+		"paragraph": {"content": "inline*", "group": "block"},
+		"section": {"content": "block+", "group": "block"},
+		"image": {"inline": true, "group": "inline", "attrs": {"src": {}, "width": {"default": null}}},
+		"text": {"group": "inline"}
+	},
+	"marks": {"em": {}, "code": {}}
+}`
+
+const v2 = `{
+	"nodes": {
+		"doc": {"content": "block+"},
+		"paragraph": {"content": "inline*", "group": "block"},
+		"image": {"inline": true, "group": "inline", "attrs": {"src": {}, "alt": {}}},
+		"text": {"group": "inline"}
+	},
+	"marks": {"em": {}, "inline_code": {}}
+}`
+
+func migrator(t *testing.T) *migrate.Migrator {
+	t.Helper()
+
+	m, err := migrate.New(schema(t, v2),
+		migrate.Migration{Version: 1, Description: "rename code", Rewrite: migrate.RenameMark("code", "inline_code")},
+		migrate.Migration{Version: 2, Rewrite: migrate.Chain(
+			migrate.Unwrap("section"),
+			migrate.DefaultAttr("image", "alt", ""),
+			migrate.RemoveAttr("image", "width"),
+		)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+		err  string
+	}{
+		{
+			name: "unversioned",
+			doc: `{"type": "doc", "content": [{"type": "section", "content": [{"type": "paragraph", "content": [
+				{"type": "text", "text": "run "},
+				{"type": "text", "text": "go", "marks": [{"type": "code"}]},
+				{"type": "image", "attrs": {"src": "a.png", "width": 10}}
+			]}]}]}`,
+			want: `doc(paragraph("run ", inline_code("go"), image))`,
+		},
+		{
+			name: "version 1",
+			doc:  `{"version": 1, "doc": {"type": "doc", "content": [{"type": "paragraph", "content": [{"type": "image", "attrs": {"src": "a.png", "alt": "a"}}]}]}}`,
+			want: `doc(paragraph(image))`,
+		},
+		{
+			name: "current version",
+			doc:  `{"version": 2, "doc": {"type": "doc", "content": [{"type": "paragraph", "content": [{"type": "text", "text": "go", "marks": [{"type": "inline_code"}]}]}]}}`,
+			want: `doc(paragraph(inline_code("go")))`,
+		},
+		{
+			name: "newer version",
+			doc:  `{"version": 3, "doc": {"type": "doc", "content": [{"type": "paragraph"}]}}`,
+			err:  "document version 3 is newer than the schema version 2",
+		},
+		{
+			name: "invalid content",
+			doc:  `{"version": 2, "doc": {"type": "doc"}}`,
+			err:  "doc: content does not match node type doc",
+		},
+		{
+			name: "unknown node type",
+			doc:  `{"version": 2, "doc": {"type": "doc", "content": [{"type": "section", "content": [{"type": "paragraph"}]}]}}`,
+			err:  `doc.content[0]: unknown node type "section"`,
+		},
+		{
+			name: "missing attribute",
+			doc:  `{"version": 2, "doc": {"type": "doc", "content": [{"type": "paragraph", "content": [{"type": "image", "attrs": {"src": "a.png"}}]}]}}`,
+			err:  "doc.content[0].content[0]: node image: no value supplied for attribute alt",
+		},
+	}
+
+	m := migrator(t)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := m.Load([]byte(tc.doc))
+			if tc.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.err)
+				}
+				return
+			}
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestLoadRemovedAttribute(t *testing.T) {
+	got, err := migrator(t).Load([]byte(`{"version": 2, "doc": {"type": "doc", "content": [{"type": "paragraph", "content": [
+		{"type": "image", "attrs": {"src": "a.png", "alt": "", "width": 1}},
+		{"type": "text", "text": "a", "marks": [{"type": "em", "attrs": {"color": "red"}}]}
+	]}]}}`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	para := got.Child(0)
+	assert.Equal(t, map[string]any{"src": "a.png", "alt": ""}, para.Child(0).Attrs)
+	assert.Empty(t, para.Child(1).Marks[0].Attrs)
+}
+
+func TestSaveUpgrade(t *testing.T) {
+	old := schema(t, v0)
+	m := migrator(t)
+
+	doc := p.Must(old.Nodes["doc"].Create(nil, nil,
+		p.Must(old.Nodes["paragraph"].Create(nil, nil, old.Text("go", old.Marks["code"].Create(nil)))),
+	))
+
+	upgraded, err := m.Upgrade(doc, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, `doc(paragraph(inline_code("go")))`, upgraded.String())
+
+	saved, err := m.Save(upgraded)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.JSONEq(t, `{"version": 2, "doc": {"type": "doc", "content": [{"type": "paragraph", "content": [{"type": "text", "text": "go", "marks": [{"type": "inline_code"}]}]}]}}`, string(saved))
+
+	loaded, err := m.Load(saved)
+	if assert.NoError(t, err) {
+		assert.True(t, loaded.Eq(upgraded))
+	}
+}
+
+func TestNew(t *testing.T) {
+	s := schema(t, v2)
+	rename := migrate.RenameNode("a", "b")
+
+	_, err := migrate.New(s, migrate.Migration{Version: 2, Rewrite: rename}, migrate.Migration{Version: 1, Rewrite: rename})
+	assert.EqualError(t, err, "migration 1 has version 1, versions must start from 1 and increase")
+
+	_, err = migrate.New(s, migrate.Migration{Version: 1})
+	assert.EqualError(t, err, "migration to version 1 has no rewrite")
+
+	m, err := migrate.New(s)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, m.Version())
+	}
+}
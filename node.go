@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"maps"
 	"slices"
-	"strings"
 
 	"github.com/go-json-experiment/json"
 )
@@ -54,43 +53,36 @@ func (n Node) Resolve(pos int) (ResolvedPos, error) {
 	return resolve(n, pos)
 }
 
-func (n Node) eq(other Node) bool {
-	if n.Type.isText() {
-		return n.sameMarkup(other) && n.Text == other.Text
+// Eq tests whether two nodes represent the same piece of document.
+func (n Node) Eq(other Node) bool {
+	if n.Type.IsText() {
+		return n.SameMarkup(other) && n.Text == other.Text
 	}
 
-	return n.Type.Eq(other.Type) &&
-		maps.Equal(n.Attrs, other.Attrs) &&
-		slices.EqualFunc(n.Marks, other.Marks, func(a, b Mark) bool {
-			return a.Eq(b)
-		}) &&
+	return n.SameMarkup(other) &&
 		n.Text == other.Text &&
-		slices.EqualFunc(n.Content.Content, other.Content.Content, func(a, b Node) bool {
-			return a.eq(b)
-		})
+		n.Content.Eq(other.Content)
 }
 
+// String returns a debug representation of the node, matching the toString of
+// prosemirror-model: the type name followed by the content in parentheses,
+// quoted text, and marks wrapped around their node, like
+// doc(paragraph("hi ", em("there"))). It can be read back with ParseDebugString.
 func (n Node) String() string {
-	b := &strings.Builder{}
-
-	b.WriteString("Node{")
-	fmt.Fprintf(b, "Type: %s", n.Type)
-
-	if len(n.Attrs) > 0 {
-		fmt.Fprintf(b, ", Attrs: %v", n.Attrs)
+	if n.Type.Spec.ToDebugString != nil {
+		return n.Type.Spec.ToDebugString(n)
 	}
-	if len(n.Marks) > 0 {
-		fmt.Fprintf(b, ", Marks: %v", n.Marks)
-	}
-	if n.Text != "" {
-		fmt.Fprintf(b, ", Text: %q", n.Text)
+
+	if n.IsText() {
+		return wrapMarks(n.Marks, quoteJS(n.Text))
 	}
+
+	name := string(n.Type.Name)
 	if n.Content.Size > 0 {
-		fmt.Fprintf(b, ", Content: %v", n.Content)
+		name += "(" + n.Content.stringInner() + ")"
 	}
 
-	b.WriteString("}")
-	return b.String()
+	return wrapMarks(n.Marks, name)
 }
 
 func (n Node) NodeSize() int {
@@ -110,27 +102,103 @@ func (n Node) Child(index int) *Node {
 }
 
 func (n Node) MaybeChild(index int) *Node {
-	return n.Content.maybeChild(index)
+	return n.Content.MaybeChild(index)
 }
 
 func (n Node) FirstChild() *Node {
-	return n.Content.firstChild()
+	return n.Content.FirstChild()
+}
+
+func (n Node) LastChild() *Node {
+	return n.Content.LastChild()
 }
 
 func (n Node) IsText() bool {
-	return n.Type.isText()
+	return n.Type.IsText()
 }
 
 func (n Node) IsLeaf() bool {
-	return n.Type.isLeaf()
+	return n.Type.IsLeaf()
 }
 
 func (n Node) IsAtom() bool {
-	return n.Type.isAtom()
+	return n.Type.IsAtom()
 }
 
 func (n Node) IsInline() bool {
-	return n.Type.isInline()
+	return n.Type.IsInline()
+}
+
+func (n Node) IsBlock() bool {
+	return n.Type.IsBlock()
+}
+
+// IsTextblock is true when this is a block (non-inline node) that has inline content.
+func (n Node) IsTextblock() bool {
+	return n.Type.IsTextblock()
+}
+
+// InlineContent is true when this node allows inline content.
+func (n Node) InlineContent() bool {
+	return n.Type.InlineContent
+}
+
+// NodeAt finds the node directly after the given position.
+func (n Node) NodeAt(pos int) *Node {
+	for node := n; ; {
+		index, offset := node.Content.findIndex(pos)
+		child := node.MaybeChild(index)
+		if child == nil {
+			return nil
+		}
+
+		if offset == pos || child.IsText() {
+			return child
+		}
+
+		pos -= offset + 1
+		node = *child
+	}
+}
+
+// ChildAfter finds the (direct) child node after the given offset, if any,
+// and returns it along with its index and offset relative to this node.
+func (n Node) ChildAfter(pos int) (*Node, int, int) {
+	index, offset := n.Content.findIndex(pos)
+	return n.MaybeChild(index), index, offset
+}
+
+// ChildBefore finds the (direct) child node before the given offset, if any,
+// and returns it along with its index and offset relative to this node.
+func (n Node) ChildBefore(pos int) (*Node, int, int) {
+	if pos == 0 {
+		return nil, 0, 0
+	}
+
+	index, offset := n.Content.findIndex(pos)
+	if offset < pos {
+		return n.Child(index), index, offset
+	}
+
+	node := n.Child(index - 1)
+	return node, index - 1, offset - node.NodeSize()
+}
+
+// RangeHasMark tests whether a given mark or mark type occurs in this
+// document between the two given positions.
+func (n Node) RangeHasMark(from, to int, typ MarkType) bool {
+	found := false
+	if to > from {
+		n.NodesBetween(from, to, func(node Node, _ int, _ *Node, _ int) bool {
+			if typ.IsInSet(node.Marks) != nil {
+				found = true
+			}
+
+			return !found
+		})
+	}
+
+	return found
 }
 
 func (n Node) WithMarks(m []Mark) Node {
@@ -139,6 +207,16 @@ func (n Node) WithMarks(m []Mark) Node {
 	return n
 }
 
+// Mark returns a copy of this node with the given set of marks instead
+// of the node's own marks.
+func (n Node) Mark(marks []Mark) Node {
+	if SameMarkSet(n.Marks, marks) {
+		return n
+	}
+
+	return n.WithMarks(marks)
+}
+
 // Cut out the part of the document between the given positions, and
 // return it as a `Slice` object.
 func (n Node) Slice(from, to int, includeParents bool) (Slice, error) {
@@ -182,7 +260,7 @@ func (n Node) close(f Fragment) (Node, error) {
 		return Node{}, err
 	}
 
-	return n.copy(f), nil
+	return n.Copy(f), nil
 }
 
 func (n Node) ChildCount() int {
@@ -204,7 +282,7 @@ func (n Node) MarshalJSON() ([]byte, error) {
 		Content Fragment       `json:"content,omitempty,omitzero"`
 	}
 
-	if n.Type.isText() {
+	if n.Type.IsText() {
 		return json.Marshal(StringNode{
 			Type:  n.Type,
 			Attrs: n.Attrs,
@@ -227,6 +305,10 @@ func (n Node) MarshalJSON() ([]byte, error) {
 // can optionally pass `start` and `end` indices into the
 // replacement fragment.
 func (n Node) canReplace(from, to int, replacement Fragment, start, end int) bool {
+	if n.IsLeaf() {
+		return from == 0 && to == 0 && replacement.ChildCount() == 0
+	}
+
 	if start == -1 {
 		start = 0
 	}
@@ -254,8 +336,55 @@ func (n Node) canReplace(from, to int, replacement Fragment, start, end int) boo
 	return true
 }
 
+// CanReplace tests whether replacing the range between `from` and `to`
+// (by child index) with the given replacement fragment would leave the
+// node's content valid.
+func (n Node) CanReplace(from, to int, replacement Fragment) bool {
+	return n.canReplace(from, to, replacement, -1, -1)
+}
+
+// CanReplaceWith tests whether replacing the range `from` to `to` (by index)
+// with a node of the given type would leave the node's content valid.
+func (n Node) CanReplaceWith(from, to int, typ NodeType, marks []Mark) bool {
+	if marks != nil && !n.Type.AllowsMarks(marks) {
+		return false
+	}
+
+	start := n.ContentMatchAt(from).matchType(typ)
+	if start == nil {
+		return false
+	}
+
+	end := start.matchFragment(n.Content, to, -1)
+	return end != nil && end.ValidEnd
+}
+
+// CanAppend tests whether the given node's content could be appended to
+// this node. If that node is empty, this will only return true if there
+// is at least one node type that can appear in both nodes (to avoid
+// merging completely incompatible nodes).
+func (n Node) CanAppend(other Node) bool {
+	if other.Content.Size > 0 {
+		return n.canReplace(n.ChildCount(), n.ChildCount(), other.Content, -1, -1)
+	}
+
+	return n.Type.compatibleContent(other.Type)
+}
+
+// ContentMatchAt gets the content match in this node at the given index.
+func (n Node) ContentMatchAt(index int) *ContentMatch {
+	return n.contentMatchAt(index)
+}
+
 func (n Node) contentMatchAt(index int) *ContentMatch {
-	return n.Type.ContentMatch.matchFragment(n.Content, 0, index)
+	match := n.Type.schemaType().ContentMatch
+	return match.matchFragment(n.Content, 0, index)
+}
+
+// Cut creates a copy of this node with only the content between the given
+// positions. If `to` is -1, it defaults to the end of the node.
+func (n Node) Cut(from int, to int) Node {
+	return n.cut(from, to)
 }
 
 func (n Node) cut(from int, to int) Node {
@@ -268,7 +397,7 @@ func (n Node) cut(from int, to int) Node {
 			return n
 		}
 
-		return n.withText(n.Text[from:to])
+		return n.withText(utf16Slice(n.Text, from, to))
 	}
 
 	if to == -1 {
@@ -279,7 +408,7 @@ func (n Node) cut(from int, to int) Node {
 		return n
 	}
 
-	return n.copy(n.Content.cut(from, to))
+	return n.Copy(n.Content.cut(from, to))
 }
 
 func (n Node) withText(s string) Node {
@@ -296,7 +425,9 @@ func (n Node) withText(s string) Node {
 	}
 }
 
-func (n Node) copy(f Fragment) Node {
+// Copy creates a new node with the same markup as this node, containing
+// the given content.
+func (n Node) Copy(f Fragment) Node {
 	return Node{
 		Type:    n.Type,
 		Text:    n.Text,
@@ -316,16 +447,27 @@ func (n Node) Clone() Node {
 	}
 }
 
-func (n Node) hasMarkup(t NodeType, attrs map[string]any, marks []Mark) bool {
+// HasMarkup checks whether this node's markup corresponds to the given
+// type, attributes, and marks.
+func (n Node) HasMarkup(t NodeType, attrs map[string]any, marks []Mark) bool {
+	if attrs == nil {
+		attrs = t.DefaultAttrs
+	}
+
+	own := n.Attrs
+	if own == nil {
+		own = n.Type.DefaultAttrs
+	}
+
 	return n.Type.Eq(t) &&
-		maps.Equal(n.Attrs, attrs) &&
-		slices.EqualFunc(n.Marks, marks, func(a, b Mark) bool {
-			return a.Eq(b)
-		})
+		attrsEq(own, attrs) &&
+		SameMarkSet(n.Marks, marks)
 }
 
-func (n Node) sameMarkup(other Node) bool {
-	return n.hasMarkup(other.Type, other.Attrs, other.Marks)
+// SameMarkup compares the markup (type, attributes, and marks) of this
+// node to those of another. Returns true if both have the same markup.
+func (n Node) SameMarkup(other Node) bool {
+	return n.HasMarkup(other.Type, other.Attrs, other.Marks)
 }
 
 // Call the given callback for every descendant node. Doesn't
@@ -334,6 +476,32 @@ func (n Node) Descendants(f func(n Node, pos int, parent *Node, index int) bool)
 	n.nodesBetween(0, n.Content.Size, f, 0)
 }
 
+// TextContent concatenates all the text nodes found in this node and its children.
+func (n Node) TextContent() string {
+	if n.IsText() {
+		return n.Text
+	}
+
+	if n.IsLeaf() && n.Type.Spec.LeafText != nil {
+		return n.Type.Spec.LeafText(n)
+	}
+
+	return n.TextBetween(0, n.Content.Size, "", nil)
+}
+
+// TextBetween gets all the text between the given positions, relative to the
+// start of this node's content. See Fragment.TextBetween.
+func (n Node) TextBetween(from, to int, blockSeparator string, leafText func(Node) string) string {
+	return n.Content.TextBetween(from, to, blockSeparator, leafText)
+}
+
+// NodesBetween invokes a callback for all descendant nodes recursively
+// between the given two positions that are relative to start of this
+// node's content. Doesn't descend into a node when the callback returns `false`.
+func (n Node) NodesBetween(from, to int, f func(node Node, pos int, parent *Node, index int) bool) {
+	n.nodesBetween(from, to, f, 0)
+}
+
 func (n Node) nodesBetween(from int, to int, f func(node Node, start int, parent *Node, index int) bool, startPos int) {
 	n.Content.nodesBetween(from, to, f, startPos, &n)
 }
@@ -1,7 +1,9 @@
 package prosemirror_test
 
 import (
+	"maps"
 	"reflect"
+	"slices"
 	"testing"
 
 	"github.com/go-json-experiment/json"
@@ -12,15 +14,15 @@ import (
 )
 
 func TestNodeCalcSize(t *testing.T) {
-	b := b.New(prosemirror.Must(prosemirror.NewSchema(schema.DefaultSpec)))
-	n := b.Doc(
-		b.PText("Crazy?"),
-		b.PText("I was crazy once."),
-		b.PText("They put me in a room."),
-		b.PText("A rubber room."),
-		b.PText("A rubber room with rats."),
-		b.PText("Rubber rats."),
-		b.PText("I hate rats."),
+	nb := b.New(prosemirror.Must(prosemirror.NewSchema(schema.DefaultSpec)))
+	n := nb.Doc(
+		nb.PText("Crazy?"),
+		nb.PText("I was crazy once."),
+		nb.PText("They put me in a room."),
+		nb.PText("A rubber room."),
+		nb.PText("A rubber room with rats."),
+		nb.PText("Rubber rats."),
+		nb.PText("I hate rats."),
 	)
 
 	if n.Content.Size != 121 {
@@ -29,7 +31,7 @@ func TestNodeCalcSize(t *testing.T) {
 }
 
 func TestNodeIndexing(t *testing.T) {
-	b := b.New(prosemirror.Must(prosemirror.NewSchema(schema.DefaultSpec)))
+	nb := b.New(prosemirror.Must(prosemirror.NewSchema(schema.DefaultSpec)))
 	type test struct {
 		name string
 		got  prosemirror.Node
@@ -39,14 +41,14 @@ func TestNodeIndexing(t *testing.T) {
 	tests := []test{{
 		name: "default doc",
 		want: 121,
-		got: b.Doc(
-			b.PText("Crazy?"),
-			b.PText("I was crazy once."),
-			b.PText("They put me in a room."),
-			b.PText("A rubber room."),
-			b.PText("A rubber room with rats."),
-			b.PText("Rubber rats."),
-			b.PText("I hate rats."),
+		got: nb.Doc(
+			nb.PText("Crazy?"),
+			nb.PText("I was crazy once."),
+			nb.PText("They put me in a room."),
+			nb.PText("A rubber room."),
+			nb.PText("A rubber room with rats."),
+			nb.PText("Rubber rats."),
+			nb.PText("I hate rats."),
 		),
 	}}
 
@@ -81,3 +83,62 @@ func toJSON(v any) string {
 	b, _ := json.Marshal(v)
 	return string(b)
 }
+
+func TestNodeTextBetween(t *testing.T) {
+	spec := schema.DefaultSpec
+	spec.Nodes = maps.Clone(schema.DefaultNodes)
+	spec.Nodes["mention"] = prosemirror.NodeSpec{
+		Inline:   true,
+		Group:    "inline",
+		Attrs:    map[string]prosemirror.Attribute{"name": {}},
+		LeafText: func(n prosemirror.Node) string { return "@" + n.Attrs["name"].(string) },
+	}
+	spec.NodeOrder = append(slices.Clone(spec.NodeOrder), "mention")
+	s := prosemirror.Must(prosemirror.NewSchema(spec))
+
+	nb := b.New(s)
+	para := nb.Node("paragraph")
+	mention := nb.Node("mention")(map[string]any{"name": "bob"})
+
+	// 1 + "a😀b" (4) + 1 + mention (1) + 1 + hard_break (1) + "c" (1) + 1 + hr (1) + 1 + "d" (1) + 1
+	doc := nb.Node("doc")(
+		para("a😀b", mention),
+		nb.Node("heading")(map[string]any{"level": 1}, nb.Node("hard_break")(), "c"),
+		nb.Node("horizontal_rule")(),
+		para("d"),
+	).Node
+
+	tests := []struct {
+		name     string
+		from, to int
+		sep      string
+		leafText func(prosemirror.Node) string
+		want     string
+	}{
+		{name: "whole document", from: 0, to: doc.Content.Size, want: "a😀b@bob\ncd"},
+		{name: "block separator", from: 0, to: doc.Content.Size, sep: "|", want: "a😀b@bob|\nc|d"},
+		{name: "utf-16 positions", from: 2, to: 4, want: "😀"},
+		{name: "partial blocks", from: 4, to: 11, sep: "|", want: "b@bob|\nc"},
+		{
+			name: "leaf text override", from: 0, to: doc.Content.Size, sep: "|",
+			leafText: func(n prosemirror.Node) string { return "<" + string(n.Type.Name) + ">" },
+			want:     "a😀b<mention>|<hard_break>c|<horizontal_rule>|d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := doc.TextBetween(tt.from, tt.to, tt.sep, tt.leafText); got != tt.want {
+				t.Errorf("TextBetween() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got, want := doc.TextContent(), "a😀b@bob\ncd"; got != want {
+		t.Errorf("TextContent() = %q, want %q", got, want)
+	}
+
+	if got, want := mention.TextContent(), "@bob"; got != want {
+		t.Errorf("TextContent() = %q, want %q", got, want)
+	}
+}
@@ -3,6 +3,7 @@ package prosemirror
 import (
 	"cmp"
 	"fmt"
+	"maps"
 	"slices"
 	"strings"
 
@@ -15,10 +16,13 @@ type Attrs map[string]Attribute
 
 type Attribute struct {
 	Default any
+
+	// Optional allows a nil Default, which would otherwise make the attribute required.
+	Optional bool
 }
 
 func (a Attribute) isRequired() bool {
-	return a.Default == nil
+	return a.Default == nil && !a.Optional
 }
 
 type NodeSpec struct {
@@ -40,14 +44,44 @@ type NodeSpec struct {
 	// The attributes this node can have.
 	Attrs map[string]Attribute
 
+	// Controls whether nodes of this type can be selected as a node
+	// selection. Defaults to true for non-text nodes.
+	Selectable *bool
+
+	// Can be used to indicate that this node contains code, which
+	// causes some commands to behave differently.
+	Code bool
+
+	// Controls the way whitespace in this node is parsed. Either
+	// "normal" or "pre". Defaults to "pre" for code nodes.
+	Whitespace string
+
 	// Determines if this is an important parent node.
-	// DefiningAsContext bool
+	DefiningAsContext bool
 
 	// Preserve defining parents when possible.
-	// DefiningForContent bool
+	DefiningForContent bool
+
+	// Shorthand for setting both DefiningAsContext and DefiningForContent.
+	Defining bool
 
 	// Blocks regular editing operations from crossing sides.
-	// Isolating bool
+	Isolating bool
+
+	// Defines the default way a node of this type should be serialized
+	// to HTML, see DOMSerializer. Nodes without it can't be serialized.
+	ToDOM func(node Node) DOMOutputSpec
+
+	// Rules used by DOMParserFromSchema to parse nodes of this type from HTML.
+	ParseDOM []ParseRule
+
+	// Defines the default way a leaf node of this type should be serialized
+	// to a string, as used by Node.TextBetween and Node.TextContent.
+	LeafText func(node Node) string
+
+	// Defines the way nodes of this type are written by Node.String,
+	// which is their type name, followed by their content in parentheses.
+	ToDebugString func(node Node) string
 
 	// Arbitrary additional properties.
 	Extra map[string]any
@@ -66,6 +100,9 @@ type NodeType struct {
 	DefaultAttrs  map[string]any
 	ContentMatch  ContentMatch
 	InlineContent bool
+
+	// The position of this type in the schema, used to order group members.
+	Rank int
 }
 
 func (n NodeType) MarshalJSON() ([]byte, error) {
@@ -104,7 +141,7 @@ func (n NodeType) Format(s fmt.State, verb rune) {
 
 // CreateNodeType creates a node of the given type with the given attributes and content.
 func (n NodeType) Create(attrs map[string]any, marks []Mark, content ...Node) (Node, error) {
-	if n.isText() {
+	if n.IsText() {
 		return Node{}, fmt.Errorf("cannot create text node through NodeType")
 	}
 
@@ -114,32 +151,129 @@ func (n NodeType) Create(attrs map[string]any, marks []Mark, content ...Node) (N
 		return Node{}, err
 	}
 
+	built, err := n.computeAttrs(attrs)
+	if err != nil {
+		return Node{}, err
+	}
+
 	return Node{
 		Type:    n,
-		Attrs:   attrs,
+		Attrs:   built,
 		Marks:   marks,
 		Content: f,
 	}, nil
 }
 
-func (n NodeType) isInline() bool {
+// CreateUnchecked is like Create, but doesn't check that the content is valid
+// for this type. This is useful when building nodes whose content is filled in later,
+// like the wrappers of a ReplaceAroundStep.
+func (n NodeType) CreateUnchecked(attrs map[string]any, marks []Mark, content ...Node) (Node, error) {
+	if n.IsText() {
+		return Node{}, fmt.Errorf("cannot create text node through NodeType")
+	}
+
+	built, err := n.computeAttrs(attrs)
+	if err != nil {
+		return Node{}, err
+	}
+
+	return Node{
+		Type:    n,
+		Attrs:   built,
+		Marks:   marks,
+		Content: NewFragment(content...),
+	}, nil
+}
+
+// CreateAndFill is like Create, but sees if it is necessary to add nodes to the
+// start or end of the given fragment to make it fit the node. If no fitting
+// wrapping can be found, an error is returned. Note that, due to the fact that
+// required nodes can always be created, this will always succeed if you pass
+// empty content.
+func (n NodeType) CreateAndFill(attrs map[string]any, marks []Mark, content ...Node) (Node, error) {
+	if n.IsText() {
+		return Node{}, fmt.Errorf("cannot create text node through NodeType")
+	}
+
+	n = n.schemaType()
+	built, err := n.computeAttrs(attrs)
+	if err != nil {
+		return Node{}, err
+	}
+
+	f := NewFragment(content...)
+	if f.Size > 0 {
+		before := n.ContentMatch.FillBefore(f, false, 0)
+		if before == nil {
+			return Node{}, fmt.Errorf("content does not fit node type %s", n.Name)
+		}
+
+		f = before.Append(f)
+	}
+
+	matched := n.ContentMatch.matchFragment(f, -1, -1)
+	if matched == nil {
+		return Node{}, fmt.Errorf("content does not fit node type %s", n.Name)
+	}
+
+	after := matched.FillBefore(Fragment{}, true, 0)
+	if after == nil {
+		return Node{}, fmt.Errorf("content does not fit node type %s", n.Name)
+	}
+
+	return Node{
+		Type:    n,
+		Attrs:   built,
+		Marks:   marks,
+		Content: f.Append(*after),
+	}, nil
+}
+
+// ValidContent returns true if the given fragment is valid content for this node type.
+func (n NodeType) ValidContent(f Fragment) bool {
+	return n.CheckContent(f) == nil
+}
+
+func (n NodeType) IsInline() bool {
 	return !n.Block
 }
 
-func (n NodeType) isTextBlock() bool {
+func (n NodeType) IsBlock() bool {
+	return n.Block
+}
+
+func (n NodeType) IsTextblock() bool {
 	return n.Block && n.InlineContent
 }
 
-func (n NodeType) isLeaf() bool {
+func (n NodeType) IsLeaf() bool {
 	return n.ContentMatch.Empty()
 }
 
-func (n NodeType) isText() bool {
+func (n NodeType) IsText() bool {
 	return n.Text
 }
 
-func (n NodeType) isAtom() bool {
-	return n.isLeaf() || n.Spec.Atom
+func (n NodeType) IsAtom() bool {
+	return n.IsLeaf() || n.Spec.Atom
+}
+
+// IsCode reports whether this type has its Code spec set.
+func (n NodeType) IsCode() bool {
+	return n.Spec.Code
+}
+
+// Whitespace returns the node type's whitespace option.
+func (n NodeType) Whitespace() string {
+	if n.Spec.Whitespace != "" {
+		return n.Spec.Whitespace
+	}
+
+	if n.Spec.Code {
+		return "pre"
+	}
+
+	return "normal"
 }
 
 func (t NodeType) hasRequiredAttrs() bool {
@@ -152,20 +286,65 @@ func (t NodeType) hasRequiredAttrs() bool {
 	return false
 }
 
+// HasRequiredAttrs tells you whether this node type has any required attributes.
+func (t NodeType) HasRequiredAttrs() bool {
+	return t.hasRequiredAttrs()
+}
+
 func (n NodeType) AllowsMarkType(markType MarkType) bool {
 	return n.Marks == nil || slices.ContainsFunc(n.Marks, func(other MarkType) bool {
 		return other.Name == markType.Name
 	})
 }
 
+// AllowsMarks tests whether the given set of marks are allowed in this node.
+func (n NodeType) AllowsMarks(marks []Mark) bool {
+	return n.validMarks(marks) == nil
+}
+
+// AllowedMarks removes the marks that are not allowed in this node from the given set.
+func (n NodeType) AllowedMarks(marks []Mark) []Mark {
+	if n.Marks == nil {
+		return marks
+	}
+
+	var copied []Mark
+	for i, m := range marks {
+		if !n.AllowsMarkType(m.Type) {
+			if copied == nil {
+				copied = slices.Clone(marks[:i])
+			}
+		} else if copied != nil {
+			copied = append(copied, m)
+		}
+	}
+
+	if copied == nil {
+		return marks
+	}
+
+	return copied
+}
+
 func (t NodeType) compatibleContent(other NodeType) bool {
-	return t.Eq(other) || t.ContentMatch.compatible(other.ContentMatch)
+	if t.Eq(other) {
+		return true
+	}
+
+	match := t.schemaType().ContentMatch
+	return match.compatible(other.schemaType().ContentMatch)
+}
+
+// CompatibleContent reports whether the content of both types share a node type.
+func (t NodeType) CompatibleContent(other NodeType) bool {
+	return t.compatibleContent(other)
 }
 
-func (t NodeType) computeAttrs(attrs map[string]any) map[string]any {
+func (t NodeType) computeAttrs(attrs map[string]any) (map[string]any, error) {
 	if attrs == nil && t.DefaultAttrs != nil {
-		return t.DefaultAttrs
+		return maps.Clone(t.DefaultAttrs), nil
 	}
+
 	return computeAttrs(t.Attrs, attrs)
 }
 
@@ -173,6 +352,18 @@ func (n NodeType) Eq(other NodeType) bool {
 	return n.Name == other.Name
 }
 
+// schemaType returns the fully compiled version of this type from its schema.
+//
+// Node types referenced by content matches are captured while the schema is
+// being built, so they don't carry their own compiled content expression.
+func (n NodeType) schemaType() NodeType {
+	if t, ok := n.Schema.Nodes[n.Name]; ok {
+		return t
+	}
+
+	return n
+}
+
 func (n NodeType) CheckContent(f Fragment) error {
 	result := n.ContentMatch.matchFragment(f, -1, -1)
 	// be as descriptive as possible
@@ -213,6 +404,10 @@ func NewNodeType(name NodeTypeName) (NodeType, error) {
 }
 
 func newNodeType(name NodeTypeName, schema Schema, spec NodeSpec) (NodeType, error) {
+	if spec.Whitespace != "" && spec.Whitespace != "normal" && spec.Whitespace != "pre" {
+		return NodeType{}, &SpecError{Path: nodePath(name, "whitespace"), Err: fmt.Errorf("unknown whitespace mode %q", spec.Whitespace)}
+	}
+
 	n := NodeType{
 		Name:          name,
 		Spec:          spec,
@@ -232,51 +427,80 @@ func newNodeType(name NodeTypeName, schema Schema, spec NodeSpec) (NodeType, err
 
 func compileNodeTypeSet(schema Schema, nodeSet map[NodeTypeName]NodeSpec) (map[NodeTypeName]NodeType, error) {
 	out := map[NodeTypeName]NodeType{}
-	for name, spec := range nodeSet {
-		nodeType, err := newNodeType(name, schema, spec)
+	for rank, name := range specOrder(nodeSet, schema.Spec.NodeOrder) {
+		nodeType, err := newNodeType(name, schema, nodeSet[name])
 		if err != nil {
 			return nil, err
 		}
 
+		nodeType.Rank = rank
 		out[name] = nodeType
 	}
 
 	topNode := cmp.Or(schema.Spec.TopNode, "doc")
 	if out[topNode].Eq(NodeType{}) {
-		return nil, fmt.Errorf("no top level node %q defined in node set", topNode)
+		return nil, &SpecError{Path: "topNode", Err: fmt.Errorf("no top level node %q defined in node set", topNode)}
 	}
 
 	if out["text"].Eq(NodeType{}) {
-		return nil, fmt.Errorf("no text node type defined in node set")
+		return nil, &SpecError{Path: "nodes", Err: fmt.Errorf("no text node type defined in node set")}
 	}
 
 	if len(out["text"].Attrs) > 0 {
-		return nil, fmt.Errorf("text node type should not have attributes")
+		return nil, &SpecError{Path: nodePath("text", "attrs"), Err: fmt.Errorf("text node type should not have attributes")}
 	}
 
 	return out, nil
 }
 
-func computeAttrs(attrs Attrs, value map[string]any) map[string]any {
+// specOrder returns the names of the given specs, those listed in order first
+// and the remaining ones sorted by name.
+func specOrder[K ~string, V any](specs map[K]V, order []K) []K {
+	names := make([]K, 0, len(specs))
+	for _, name := range order {
+		if _, ok := specs[name]; ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	rest := []K{}
+	for name := range specs {
+		if !slices.Contains(names, name) {
+			rest = append(rest, name)
+		}
+	}
+
+	slices.Sort(rest)
+	return append(names, rest...)
+}
+
+func compareNodeTypes(a, b NodeType) int {
+	return cmp.Or(cmp.Compare(a.Rank, b.Rank), cmp.Compare(a.Name, b.Name))
+}
+
+func computeAttrs(attrs Attrs, value map[string]any) (map[string]any, error) {
+	if len(attrs) == 0 {
+		return nil, nil
+	}
+
 	built := map[string]any{}
 
 	for name := range attrs {
-		given := value[name]
-		if given != nil {
+		given, ok := value[name]
+		if ok && (given != nil || attrs[name].Optional) {
 			built[name] = given
 			continue
 		}
 
 		attr := attrs[name]
-		if attr.Default == nil {
-			// Skill issue
-			panic("No value supplied for attribute " + name)
+		if attr.isRequired() {
+			return nil, fmt.Errorf("no value supplied for attribute %s", name)
 		}
 
 		built[name] = attr.Default
 	}
 
-	return built
+	return built, nil
 }
 
 // TODO: either of these is wrong
@@ -290,8 +514,16 @@ func initAttrs(attrs Attrs) Attrs {
 }
 
 func defaultAttrs(attrs Attrs) map[string]any {
+	if len(attrs) == 0 {
+		return nil
+	}
+
 	out := map[string]any{}
 	for k, v := range attrs {
+		if v.isRequired() {
+			return nil
+		}
+
 		out[k] = v.Default
 	}
 
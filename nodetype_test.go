@@ -0,0 +1,65 @@
+package prosemirror
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeTypeCreateAndFill(t *testing.T) {
+	s := contentTestSchema(t)
+
+	doc, err := s.Nodes["doc"].CreateAndFill(nil, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []NodeTypeName{"paragraph"}, childTypes(doc))
+	}
+
+	item, err := s.Nodes["list_item"].CreateAndFill(nil, nil, s.Node("horizontal_rule", nil, Fragment{}))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []NodeTypeName{"paragraph", "horizontal_rule"}, childTypes(item))
+	}
+
+	_, err = s.Nodes["paragraph"].CreateAndFill(nil, nil, s.Node("heading", nil, Fragment{}))
+	assert.EqualError(t, err, "content does not fit node type paragraph")
+}
+
+func childTypes(n Node) []NodeTypeName {
+	names := []NodeTypeName{}
+	for _, child := range n.Content.Content {
+		names = append(names, child.Type.Name)
+	}
+
+	return names
+}
+
+func TestComputeAttrs(t *testing.T) {
+	attrs := Attrs{
+		"src":   {},
+		"title": {Optional: true},
+		"width": {Default: 100},
+	}
+
+	got, err := computeAttrs(attrs, map[string]any{"src": "a.png"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]any{"src": "a.png", "title": nil, "width": 100}, got)
+	}
+
+	got, err = computeAttrs(attrs, map[string]any{"src": "a.png", "title": nil, "width": 5})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]any{"src": "a.png", "title": nil, "width": 5}, got)
+	}
+
+	_, err = computeAttrs(attrs, map[string]any{"src": nil})
+	assert.EqualError(t, err, "no value supplied for attribute src")
+}
+
+func TestNodeCanReplace(t *testing.T) {
+	s := contentTestSchema(t)
+	p := s.Node("paragraph", nil, NewFragment(s.Text("hello")))
+	doc := s.Node("doc", nil, NewFragment(p, s.Node("horizontal_rule", nil, Fragment{})))
+
+	assert.True(t, doc.CanReplace(0, 1, NewFragment(s.Node("heading", nil, Fragment{}))))
+	assert.False(t, doc.CanReplace(0, 2, Fragment{}), "doc needs at least one block")
+	assert.False(t, doc.CanReplace(0, 1, NewFragment(s.Text("hi"))))
+	assert.True(t, doc.CanReplaceWith(1, 1, s.Nodes["bullet_list"], nil))
+}
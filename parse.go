@@ -0,0 +1,99 @@
+package prosemirror
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// ContentBuilder builds a node from children added one at a time, like parsers
+// reading other formats do. It inserts the nodes the content expression of the
+// node requires before a child, and joins adjacent text with the same marks.
+type ContentBuilder struct {
+	Type    NodeType
+	Attrs   map[string]any
+	Marks   []Mark
+	Content []Node
+
+	match *ContentMatch
+}
+
+// NewContentBuilder creates a builder of a node of the given type, attributes and marks.
+func NewContentBuilder(typ NodeType, attrs map[string]any, marks []Mark) *ContentBuilder {
+	match := typ.ContentMatch
+	return &ContentBuilder{Type: typ, Attrs: attrs, Marks: marks, match: &match}
+}
+
+// Push adds a node to the content, inserting the nodes required before it.
+// It reports false when the node cannot be placed in the content.
+func (b *ContentBuilder) Push(node Node) bool {
+	if last := len(b.Content) - 1; node.IsText() && last >= 0 && b.Content[last].IsText() && SameMarkSet(b.Content[last].Marks, node.Marks) {
+		b.Content[last] = b.Content[last].withText(b.Content[last].Text + node.Text)
+		return true
+	}
+
+	if next := b.match.MatchType(node.Type); next != nil {
+		b.match = next
+		b.Content = append(b.Content, node)
+		return true
+	}
+
+	fill := b.match.FillBefore(NewFragment(node), false, 0)
+	if fill == nil {
+		return false
+	}
+
+	nodes := append(fill.Content, node)
+	b.match = b.match.MatchFragment(NewFragment(nodes...), -1, -1)
+	b.Content = append(b.Content, nodes...)
+	return true
+}
+
+// Node creates the node, filling the content required at its end.
+func (b *ContentBuilder) Node() (Node, error) {
+	return b.Type.CreateAndFill(b.Attrs, b.Marks, b.Content...)
+}
+
+// ParseWarning reports content left out of a parsed document, because the
+// parser or the schema cannot represent it.
+type ParseWarning struct {
+	// Source names the construct holding the content in the parsed format,
+	// like a token or an element.
+	Source string
+
+	// Reason describes why the content was left out.
+	Reason string
+}
+
+func (w ParseWarning) Error() string {
+	return w.Source + ": " + w.Reason
+}
+
+// ParseWarnings collects the warnings of a parse.
+type ParseWarnings []ParseWarning
+
+// Warn reports dropped content, once per source and reason.
+func (ws *ParseWarnings) Warn(source, format string, args ...any) {
+	w := ParseWarning{Source: source, Reason: fmt.Sprintf(format, args...)}
+	if !slices.Contains(*ws, w) {
+		*ws = append(*ws, w)
+	}
+}
+
+// IntAttr reads an integer attribute, which is a float64 when decoded from
+// JSON, and may be a string in formats without numbers.
+func IntAttr(v any) (int, bool) {
+	switch v := v.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	}
+
+	return 0, false
+}
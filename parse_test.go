@@ -0,0 +1,71 @@
+package prosemirror
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentBuilder(t *testing.T) {
+	s := contentTestSchema(t)
+
+	t.Run("fills required nodes", func(t *testing.T) {
+		b := NewContentBuilder(s.Nodes["list_item"], nil, nil)
+		assert.True(t, b.Push(s.Node("heading", nil, Fragment{})))
+		assert.False(t, b.Push(s.Text("a")))
+
+		node, err := b.Node()
+		if assert.NoError(t, err) {
+			assert.Equal(t, []NodeTypeName{"paragraph", "heading"}, childTypes(node))
+		}
+	})
+
+	t.Run("joins text", func(t *testing.T) {
+		b := NewContentBuilder(s.Nodes["paragraph"], nil, nil)
+		assert.True(t, b.Push(s.Text("a")))
+		assert.True(t, b.Push(s.Text("b")))
+		assert.True(t, b.Push(s.Node("image", map[string]any{"src": "x"}, Fragment{})))
+		assert.True(t, b.Push(s.Text("c")))
+		if assert.Len(t, b.Content, 3) {
+			assert.Equal(t, "ab", b.Content[0].Text)
+		}
+	})
+
+	t.Run("fills the end", func(t *testing.T) {
+		node, err := NewContentBuilder(s.Nodes["doc"], nil, nil).Node()
+		if assert.NoError(t, err) {
+			assert.Equal(t, []NodeTypeName{"paragraph"}, childTypes(node))
+		}
+	})
+}
+
+func TestParseWarnings(t *testing.T) {
+	var ws ParseWarnings
+	ws.Warn("p", "dropped %s", "a")
+	ws.Warn("p", "dropped %s", "a")
+	ws.Warn("r", "dropped %s", "a")
+
+	assert.Equal(t, ParseWarnings{{Source: "p", Reason: "dropped a"}, {Source: "r", Reason: "dropped a"}}, ws)
+	assert.EqualError(t, ws[0], "p: dropped a")
+}
+
+func TestIntAttr(t *testing.T) {
+	tests := []struct {
+		v    any
+		want int
+		ok   bool
+	}{
+		{v: 2, want: 2, ok: true},
+		{v: int64(3), want: 3, ok: true},
+		{v: 4.0, want: 4, ok: true},
+		{v: "5", want: 5, ok: true},
+		{v: "5 px"},
+		{v: nil},
+	}
+
+	for _, tc := range tests {
+		got, ok := IntAttr(tc.v)
+		assert.Equal(t, tc.ok, ok, "%v", tc.v)
+		assert.Equal(t, tc.want, got, "%v", tc.v)
+	}
+}
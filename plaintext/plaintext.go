@@ -0,0 +1,231 @@
+// Package plaintext extracts the plain text of documents, along with a map
+// converting between offsets in that text and document positions, so that
+// matches found in the text can be located in the document.
+package plaintext
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/karitham/prosemirror"
+)
+
+// Unit is the unit text offsets are counted in.
+type Unit int
+
+const (
+	// UTF16 counts offsets in UTF-16 code units, like JavaScript and document positions do.
+	UTF16 Unit = iota
+	// Runes counts offsets in Unicode code points.
+	Runes
+	// Bytes counts offsets in bytes of the UTF-8 encoded text.
+	Bytes
+)
+
+// length returns the length of s in this unit.
+func (u Unit) length(s string) int {
+	switch u {
+	case Runes:
+		return utf8.RuneCountInString(s)
+	case Bytes:
+		return len(s)
+	}
+
+	return prosemirror.UTF16Len(s)
+}
+
+// Options configure how text is extracted, like Node.TextBetween.
+type Options struct {
+	// Inserted between blocks.
+	BlockSeparator string
+
+	// Gets the text of leaf nodes, falling back to their type's LeafText when nil.
+	LeafText func(prosemirror.Node) string
+
+	// The unit of text offsets. Defaults to UTF16.
+	Unit Unit
+}
+
+// segment is a piece of the text coming from a single place of the document.
+type segment struct {
+	// The offset of the segment in the text, in the map's unit, and its length.
+	offset, length int
+
+	// The byte index of the segment in the text.
+	start int
+
+	// The document range the segment comes from. Text segments map their
+	// characters one to one, other segments, like block separators and leaf
+	// text, map as a whole.
+	pos, size int
+	text      bool
+}
+
+func (s segment) end() int {
+	return s.offset + s.length
+}
+
+// Map holds the plain text of a document, and converts between offsets in
+// that text and positions in the document.
+type Map struct {
+	// The extracted text.
+	Text string
+
+	doc      prosemirror.Node
+	unit     Unit
+	segments []segment
+}
+
+// Extract gets the text content of a document, as returned by Node.TextBetween,
+// along with its position map.
+func Extract(doc prosemirror.Node, opts Options) Map {
+	m := Map{doc: doc, unit: opts.Unit}
+
+	var sb strings.Builder
+	first := true
+	add := func(text string, pos, size int, isText bool) {
+		seg := segment{offset: m.Len(), length: opts.Unit.length(text), start: sb.Len(), pos: pos, size: size, text: isText}
+		sb.WriteString(text)
+
+		if last := len(m.segments) - 1; isText && last >= 0 {
+			if prev := m.segments[last]; prev.text && prev.pos+prev.size == pos {
+				m.segments[last].length += seg.length
+				m.segments[last].size += size
+				return
+			}
+		}
+
+		m.segments = append(m.segments, seg)
+	}
+
+	doc.Descendants(func(node prosemirror.Node, pos int, _ *prosemirror.Node, _ int) bool {
+		var nodeText string
+		switch {
+		case node.IsText():
+			nodeText = node.Text
+		case !node.IsLeaf():
+		case opts.LeafText != nil:
+			nodeText = opts.LeafText(node)
+		case node.Type.Spec.LeafText != nil:
+			nodeText = node.Type.Spec.LeafText(node)
+		}
+
+		if (node.IsBlock() && node.IsLeaf() && nodeText != "" || node.IsTextblock()) && opts.BlockSeparator != "" {
+			if first {
+				first = false
+			} else {
+				add(opts.BlockSeparator, pos, 0, false)
+			}
+		}
+
+		if nodeText != "" {
+			add(nodeText, pos, node.NodeSize(), node.IsText())
+		}
+
+		return true
+	})
+
+	m.Text = sb.String()
+	return m
+}
+
+// Len returns the length of the text, in the map's unit.
+func (m Map) Len() int {
+	if len(m.segments) == 0 {
+		return 0
+	}
+
+	return m.segments[len(m.segments)-1].end()
+}
+
+// ToPos converts an offset in the text to a document position. When the offset
+// is on the boundary of two pieces of the document, like the end of a paragraph
+// and the start of the next, assoc (-1 or 1) determines which side is used.
+// Offsets within a block separator or the text of a leaf map to its start or end,
+// depending on assoc.
+func (m Map) ToPos(offset, assoc int) (int, error) {
+	if offset < 0 || offset > m.Len() {
+		return 0, fmt.Errorf("offset %d outside of text of length %d", offset, m.Len())
+	}
+
+	if len(m.segments) == 0 {
+		return 0, nil
+	}
+
+	i := sort.Search(len(m.segments), func(i int) bool {
+		if assoc < 0 {
+			return m.segments[i].end() >= offset
+		}
+
+		return m.segments[i].end() > offset
+	})
+	seg := m.segments[min(i, len(m.segments)-1)]
+
+	switch {
+	case seg.text:
+		text := prefix(m.Text[seg.start:], offset-seg.offset, m.unit)
+		return seg.pos + prosemirror.UTF16Len(text), nil
+	case offset == seg.offset:
+		return seg.pos, nil
+	case offset == seg.end() || assoc >= 0:
+		return seg.pos + seg.size, nil
+	}
+
+	return seg.pos, nil
+}
+
+// Resolve converts an offset in the text to a resolved document position. See ToPos.
+func (m Map) Resolve(offset, assoc int) (prosemirror.ResolvedPos, error) {
+	pos, err := m.ToPos(offset, assoc)
+	if err != nil {
+		return prosemirror.ResolvedPos{}, err
+	}
+
+	return m.doc.Resolve(pos)
+}
+
+// FromPos converts a document position to an offset in the text. Positions
+// outside of the text map to the offset of the next piece of text.
+func (m Map) FromPos(pos int) (int, error) {
+	if pos < 0 || pos > m.doc.Content.Size {
+		return 0, fmt.Errorf("position %d outside of document of size %d", pos, m.doc.Content.Size)
+	}
+
+	i := sort.Search(len(m.segments), func(i int) bool { return m.segments[i].pos > pos }) - 1
+	if i < 0 {
+		return 0, nil
+	}
+
+	seg := m.segments[i]
+	switch {
+	case pos >= seg.pos+seg.size && pos > seg.pos:
+		return seg.end(), nil
+	case seg.text:
+		return seg.offset + m.unit.length(prefix(m.Text[seg.start:], pos-seg.pos, UTF16)), nil
+	}
+
+	return seg.offset, nil
+}
+
+// prefix returns the start of text up to the given offset, counted in unit.
+func prefix(text string, offset int, unit Unit) string {
+	if unit == Bytes {
+		return text[:offset]
+	}
+
+	l := 0
+	for i, r := range text {
+		if l >= offset {
+			return text[:i]
+		}
+
+		l++
+		if unit == UTF16 && r >= 0x10000 {
+			l++
+		}
+	}
+
+	return text
+}
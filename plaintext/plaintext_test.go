@@ -0,0 +1,119 @@
+package plaintext_test
+
+import (
+	"strings"
+	"testing"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/builder"
+	"github.com/karitham/prosemirror/plaintext"
+	"github.com/karitham/prosemirror/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap(t *testing.T) {
+	b := builder.New(p.Must(p.NewSchema(schema.DefaultSpec)))
+	para, br := b.Node("paragraph"), b.Node("hard_break")
+
+	// <p>a😀b<em>c</em></p><h1>d<br>e</h1><hr><p></p><p>f<img></p>
+	doc := b.Node("doc")(
+		para("a😀b", b.Mark("em")("c")),                                // 0-7
+		b.Node("heading")(map[string]any{"level": 1}, "d", br(), "e"), // 7-12
+		b.Node("horizontal_rule")(),                                   // 12-13
+		para(),                                                        // 13-15
+		para("f", b.Node("image")(map[string]any{"src": "x"})),        // 15-19
+	).Node
+
+	m := plaintext.Extract(doc, plaintext.Options{BlockSeparator: "\n\n"})
+	assert.Equal(t, doc.TextBetween(0, doc.Content.Size, "\n\n", nil), m.Text)
+	assert.Equal(t, "a😀bc\n\nd\ne\n\n\n\nf", m.Text)
+	assert.Equal(t, p.UTF16Len(m.Text), m.Len())
+
+	t.Run("to position", func(t *testing.T) {
+		for _, tt := range []struct {
+			offset, assoc, want int
+		}{
+			{offset: 0, assoc: 1, want: 1},
+			{offset: 1, assoc: 1, want: 2},
+			{offset: 3, assoc: 1, want: 4},
+			{offset: 5, assoc: -1, want: 6},
+			{offset: 5, assoc: 1, want: 7},
+			{offset: 6, assoc: 1, want: 7},
+			{offset: 7, assoc: 1, want: 8},
+			{offset: 8, assoc: -1, want: 9},
+			{offset: 8, assoc: 1, want: 9},
+			{offset: 9, assoc: 1, want: 10},
+			{offset: 10, assoc: -1, want: 11},
+			{offset: 11, assoc: 1, want: 13},
+			{offset: 14, assoc: 1, want: 16},
+			{offset: 15, assoc: -1, want: 17},
+		} {
+			got, err := m.ToPos(tt.offset, tt.assoc)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got, "offset %d assoc %d", tt.offset, tt.assoc)
+			}
+		}
+	})
+
+	t.Run("from position", func(t *testing.T) {
+		for _, tt := range []struct {
+			pos, want int
+		}{
+			{pos: 0, want: 0},
+			{pos: 1, want: 0},
+			{pos: 2, want: 1},
+			{pos: 4, want: 3},
+			{pos: 6, want: 5},
+			{pos: 7, want: 5},
+			{pos: 8, want: 7},
+			{pos: 9, want: 8},
+			{pos: 12, want: 10},
+			{pos: 14, want: 12},
+			{pos: 17, want: 15},
+			{pos: 19, want: 15},
+		} {
+			got, err := m.FromPos(tt.pos)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got, "pos %d", tt.pos)
+			}
+		}
+	})
+
+	t.Run("highlights matches", func(t *testing.T) {
+		start := p.UTF16Len(m.Text[:strings.Index(m.Text, "d\ne")])
+		from, err := m.ToPos(start, 1)
+		assert.NoError(t, err)
+		to, err := m.ToPos(start+3, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, "d\ne", doc.TextBetween(from, to, "", nil))
+
+		pos, err := m.Resolve(start, 1)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "heading", string(pos.Parent().Type.Name))
+		}
+	})
+
+	t.Run("units", func(t *testing.T) {
+		for unit, want := range map[plaintext.Unit]int{plaintext.UTF16: 2, plaintext.Runes: 1, plaintext.Bytes: 4} {
+			m := plaintext.Extract(doc, plaintext.Options{Unit: unit})
+			assert.Equal(t, "a😀bcd\nef", m.Text)
+
+			got, err := m.ToPos(1+want, 1)
+			if assert.NoError(t, err) {
+				assert.Equal(t, 4, got)
+			}
+
+			offset, err := m.FromPos(4)
+			if assert.NoError(t, err) {
+				assert.Equal(t, 1+want, offset)
+			}
+		}
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		_, err := m.ToPos(m.Len()+1, 1)
+		assert.Error(t, err)
+		_, err = m.FromPos(-1)
+		assert.Error(t, err)
+	})
+}
@@ -27,7 +27,7 @@ func replaceOuter(from, to ResolvedPos, slice Slice, depth int) (Node, error) {
 			return Node{}, err
 		}
 
-		return node.copy(node.Content.replaceChild(index, inner)), nil
+		return node.Copy(node.Content.ReplaceChild(index, inner)), nil
 
 	case slice.Content.Size == 0:
 		r, err := replaceTwoWay(from, to, depth)
@@ -47,9 +47,9 @@ func replaceOuter(from, to ResolvedPos, slice Slice, depth int) (Node, error) {
 		content := parent.Content
 
 		cut1 := content.cut(0, from.ParentOffset)
-		append1 := cut1.append(slice.Content)
+		append1 := cut1.Append(slice.Content)
 		cut2 := content.cut(to.ParentOffset, -1)
-		append2 := append1.append(cut2)
+		append2 := append1.Append(cut2)
 
 		closed, err := parent.close(append2)
 		if err != nil {
@@ -175,9 +175,9 @@ func replaceTwoWay(from, to ResolvedPos, depth int) (Fragment, error) {
 func prepareSliceForReplace(slice Slice, along ResolvedPos) (ResolvedPos, ResolvedPos) {
 	extra := along.Depth - slice.OpenStart
 	parent := along.Node(extra)
-	node := parent.copy(slice.Content)
+	node := parent.Copy(slice.Content)
 	for i := extra - 1; i >= 0; i-- {
-		node = along.Node(i).copy(NewFragment(node))
+		node = along.Node(i).Copy(NewFragment(node))
 	}
 
 	start, err := resolve(node, slice.OpenStart+extra)
@@ -195,7 +195,7 @@ func prepareSliceForReplace(slice Slice, along ResolvedPos) (ResolvedPos, Resolv
 
 func appendNode(content []Node, child Node) []Node {
 	last := len(content) - 1
-	if last >= 0 && child.IsText() && child.sameMarkup(content[last]) {
+	if last >= 0 && child.IsText() && child.SameMarkup(content[last]) {
 		content[last] = child.withText(content[last].Text + child.Text)
 		return content
 	}
@@ -222,7 +222,7 @@ func appendRange(content []Node, start *ResolvedPos, end *ResolvedPos, depth int
 		startIndex = start.Index(depth)
 		if start.Depth > depth {
 			startIndex++
-		} else if start.TextOffset() {
+		} else if start.TextOffset() != 0 {
 			content = appendNode(content, *start.NodeAfter())
 			startIndex++
 		}
@@ -232,7 +232,7 @@ func appendRange(content []Node, start *ResolvedPos, end *ResolvedPos, depth int
 		content = appendNode(content, *node.Child(i))
 	}
 
-	if end != nil && end.Depth == depth && end.TextOffset() {
+	if end != nil && end.Depth == depth && end.TextOffset() != 0 {
 		content = appendNode(content, *end.NodeBefore())
 	}
 
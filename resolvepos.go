@@ -2,6 +2,7 @@ package prosemirror
 
 import (
 	"fmt"
+	"strings"
 )
 
 // resolve resolves the position within the node's content to a position in the document.
@@ -59,8 +60,19 @@ type ResolvedPos struct {
 	OffsetPath []int
 }
 
+// String returns a debug representation of the position, with the type and
+// index of its ancestors and its offset in its parent, like paragraph_1:3.
 func (r ResolvedPos) String() string {
-	return fmt.Sprintf("ResolvedPos{Pos: %d, ParentOffset: %d, IndexPath: %v, OffsetPath: %v}", r.Pos, r.ParentOffset, r.IndexPath, r.OffsetPath)
+	var b strings.Builder
+	for i := 1; i <= r.Depth; i++ {
+		if b.Len() > 0 {
+			b.WriteString("/")
+		}
+
+		fmt.Fprintf(&b, "%s_%d", r.Node(i).Type.Name, r.Index(i-1))
+	}
+
+	return fmt.Sprintf("%s:%d", b.String(), r.ParentOffset)
 }
 
 func (r ResolvedPos) Node(depth int) Node {
@@ -76,7 +88,7 @@ func (r ResolvedPos) Index(depth int) int {
 func (r ResolvedPos) IndexAfter(depth int) int {
 	depth = r.resolveDepth(depth)
 	i := r.Index(depth)
-	if depth != r.Depth || r.TextOffset() {
+	if depth != r.Depth || r.TextOffset() != 0 {
 		i += 1
 	}
 
@@ -90,11 +102,16 @@ func (r ResolvedPos) Parent() Node {
 	return r.Node(r.Depth)
 }
 
+// Doc returns the root node in which the position was resolved.
+func (r ResolvedPos) Doc() Node {
+	return r.NodePath[0]
+}
+
 // When this position points into a text node, this returns the
 // distance between the position and the start of the text node.
 // Will be zero for positions that point between nodes.
-func (r ResolvedPos) TextOffset() bool {
-	return r.Pos-r.OffsetPath[len(r.OffsetPath)-1] != 0
+func (r ResolvedPos) TextOffset() int {
+	return r.Pos - r.OffsetPath[len(r.OffsetPath)-1]
 }
 
 // Get the node directly after the position, if any. If the position
@@ -151,10 +168,9 @@ func (r ResolvedPos) Start(depth int) int {
 		return 0
 	}
 
-	// Weirdly complex in the original impl. This actually queries the *parent* index path.
+	// Weirdly complex in the original impl. This actually queries the *parent* offset path.
 	// https://github.com/ProseMirror/prosemirror-model/blob/a37b6b3adeb548dc9822211b680ce9d31be65842/src/resolvedpos.ts#L65
-	last := len(r.OffsetPath) - 1
-	return r.OffsetPath[last-1] + 1
+	return r.OffsetPath[depth-1] + 1
 }
 
 func (r ResolvedPos) End(depth int) int {
@@ -162,6 +178,146 @@ func (r ResolvedPos) End(depth int) int {
 	return r.Start(depth) + r.Node(depth).Content.Size
 }
 
+// Before returns the (absolute) position directly before the wrapping node
+// at the given level, or, when depth is `r.Depth + 1`, the original position.
+func (r ResolvedPos) Before(depth int) int {
+	depth = r.resolveDepth(depth)
+	if depth == 0 {
+		panic("there is no position before the top-level node")
+	}
+
+	if depth == r.Depth+1 {
+		return r.Pos
+	}
+
+	return r.OffsetPath[depth-1]
+}
+
+// After returns the (absolute) position directly after the wrapping node
+// at the given level, or the original position when depth is `r.Depth + 1`.
+func (r ResolvedPos) After(depth int) int {
+	depth = r.resolveDepth(depth)
+	if depth == 0 {
+		panic("there is no position after the top-level node")
+	}
+
+	if depth == r.Depth+1 {
+		return r.Pos
+	}
+
+	return r.OffsetPath[depth-1] + r.NodePath[depth].NodeSize()
+}
+
+// PosAtIndex gets the position at the given index in the parent node at the given depth.
+func (r ResolvedPos) PosAtIndex(index, depth int) int {
+	depth = r.resolveDepth(depth)
+	node := r.NodePath[depth]
+	pos := r.Start(depth)
+	for i := 0; i < index; i++ {
+		pos += node.Child(i).NodeSize()
+	}
+
+	return pos
+}
+
+// Marks gets the marks at this position, factoring in the surrounding
+// marks' inclusive property. If the position is at the start of a
+// non-empty node, the marks of the node after it (if any) are returned.
+func (r ResolvedPos) Marks() []Mark {
+	parent := r.Parent()
+	index := r.Index(r.Depth)
+
+	if parent.Content.Size == 0 {
+		return nil
+	}
+
+	if r.TextOffset() != 0 {
+		return parent.Child(index).Marks
+	}
+
+	main, other := parent.MaybeChild(index-1), parent.MaybeChild(index)
+	if main == nil {
+		main, other = other, main
+	}
+
+	return exclusiveMarks(main.Marks, other)
+}
+
+// MarksAcross gets the marks after the current position, if any, except those
+// that are non-inclusive and not present at position `end`. This is mostly
+// useful for getting the set of marks to preserve after a deletion.
+// Returns nil if this position is at the end of its parent node or its
+// parent node isn't a textblock.
+func (r ResolvedPos) MarksAcross(end ResolvedPos) []Mark {
+	after := r.Parent().MaybeChild(r.Index(r.Depth))
+	if after == nil || !after.IsInline() {
+		return nil
+	}
+
+	return exclusiveMarks(after.Marks, end.Parent().MaybeChild(end.Index(end.Depth)))
+}
+
+// exclusiveMarks drops the non-inclusive marks that don't continue into next.
+func exclusiveMarks(marks []Mark, next *Node) []Mark {
+	for i := 0; i < len(marks); i++ {
+		if !marks[i].Type.IsInclusive() && (next == nil || !marks[i].IsInSet(next.Marks)) {
+			marks = marks[i].RemoveFromSet(marks)
+			i--
+		}
+	}
+
+	return marks
+}
+
+// BlockRange returns a range based on the place where this position and the
+// given position diverge around block content. If both point into the same
+// textblock, for example, a range around that textblock will be returned.
+// If they point into different blocks, the range around those blocks in
+// their shared ancestor is returned. You can pass in an optional predicate
+// that will be called with a parent node to see if a range into that parent
+// is acceptable.
+func (r ResolvedPos) BlockRange(other ResolvedPos, pred func(Node) bool) *NodeRange {
+	if other.Pos < r.Pos {
+		return other.BlockRange(r, pred)
+	}
+
+	d := r.Depth
+	if r.Parent().InlineContent() || r.Pos == other.Pos {
+		d--
+	}
+
+	for ; d >= 0; d-- {
+		if other.Pos <= r.End(d) && (pred == nil || pred(r.Node(d))) {
+			return &NodeRange{From: r, To: other, Depth: d}
+		}
+	}
+
+	return nil
+}
+
+// SameParent queries whether the given position shares the same parent node.
+func (r ResolvedPos) SameParent(other ResolvedPos) bool {
+	return r.Pos-r.ParentOffset == other.Pos-other.ParentOffset
+}
+
+// Max returns the greater of this and the given position.
+func (r ResolvedPos) Max(other ResolvedPos) ResolvedPos {
+	if other.Pos > r.Pos {
+		return other
+	}
+
+	return r
+}
+
+// Min returns the smaller of this and the given position.
+func (r ResolvedPos) Min(other ResolvedPos) ResolvedPos {
+	if other.Pos < r.Pos {
+		return other
+	}
+
+	return r
+}
+
 func (r ResolvedPos) resolveDepth(depth int) int {
 	if depth < 0 {
 		return r.Depth + depth
@@ -170,6 +326,42 @@ func (r ResolvedPos) resolveDepth(depth int) int {
 	return depth
 }
 
+// NodeRange represents a flat range of content, i.e. one that starts and
+// ends in the same node.
+type NodeRange struct {
+	// A resolved position along the start of the content.
+	From ResolvedPos
+	// A position along the end of the content.
+	To ResolvedPos
+	// The depth of the node that this range points into.
+	Depth int
+}
+
+// Start returns the position at the start of the range.
+func (r NodeRange) Start() int {
+	return r.From.Before(r.Depth + 1)
+}
+
+// End returns the position at the end of the range.
+func (r NodeRange) End() int {
+	return r.To.After(r.Depth + 1)
+}
+
+// Parent returns the parent node that the range points into.
+func (r NodeRange) Parent() Node {
+	return r.From.Node(r.Depth)
+}
+
+// StartIndex returns the start index of the range in the parent node.
+func (r NodeRange) StartIndex() int {
+	return r.From.Index(r.Depth)
+}
+
+// EndIndex returns the end index of the range in the parent node.
+func (r NodeRange) EndIndex() int {
+	return r.To.IndexAfter(r.Depth)
+}
+
 func joinable(before, after ResolvedPos, depth int) (*Node, error) {
 	node := before.Node(depth)
 	err := checkJoin(node, after.Node(depth))
@@ -1,8 +1,10 @@
 package prosemirror
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
-	"strings"
+	"slices"
 )
 
 type SchemaSpec struct {
@@ -18,6 +20,14 @@ type SchemaSpec struct {
 	// The name of the default top-level node for the schema.
 	TopNode NodeTypeName
 
+	// The order of the node types, which is significant for things like
+	// the default type of a group. Types that aren't listed come last, sorted by name.
+	NodeOrder []NodeTypeName
+
+	// The order of the mark types, which decides the order marks are kept in
+	// on a node. Types that aren't listed come last, sorted by name.
+	MarkOrder []MarkTypeName
+
 	// Don't register the schema in the global schema store.
 	DontRegister bool
 }
@@ -51,50 +61,129 @@ func (s Schema) Mark(typ MarkTypeName, attrs map[string]any) Mark {
 	return s.Marks[typ].Create(attrs)
 }
 
-func compileContentMatch(typ *NodeType, schema Schema, contentExprCache map[string]ContentMatch) error {
+// NodeTypes returns the node types of the schema, in schema order.
+func (s Schema) NodeTypes() []NodeType {
+	types := make([]NodeType, 0, len(s.Nodes))
+	for _, t := range s.Nodes {
+		types = append(types, t)
+	}
+
+	slices.SortFunc(types, compareNodeTypes)
+	return types
+}
+
+// MarkTypes returns the mark types of the schema, in schema order.
+func (s Schema) MarkTypes() []MarkType {
+	types := make([]MarkType, 0, len(s.Marks))
+	for _, t := range s.Marks {
+		types = append(types, t)
+	}
+
+	slices.SortFunc(types, func(a, b MarkType) int {
+		return cmp.Or(cmp.Compare(a.Rank, b.Rank), cmp.Compare(a.Name, b.Name))
+	})
+	return types
+}
+
+func compileContentMatch(typ *NodeType, schema Schema, contentExprCache map[string]*ContentMatch) error {
+	ce, ok := contentExprCache[typ.Spec.Content]
+	if !ok {
+		var err error
+		ce, err = parseContentMatch(typ.Spec.Content, schema.Nodes)
+		if err != nil {
+			return &SpecError{Path: nodePath(typ.Name, "content"), Expr: typ.Spec.Content, Err: err}
+		}
+
+		contentExprCache[typ.Spec.Content] = ce
+	}
+
+	typ.ContentMatch = *ce
+	typ.InlineContent = ce.InlineContent()
+
 	switch {
-	default:
+	case typ.Spec.Marks != nil && *typ.Spec.Marks == "_":
 		typ.Marks = nil
-	case typ.Spec.Marks == nil || *typ.Spec.Marks == "_":
-		typ.Marks = nil
-	case *typ.Spec.Marks != "":
-		typ.Marks = []MarkType{}
-		for _, markName := range strings.Split(*typ.Spec.Marks, " ") {
-			if markName == "" {
-				continue
-			}
-
-			typ.Marks = append(typ.Marks, schema.Marks[MarkTypeName(markName)])
+	case typ.Spec.Marks != nil && *typ.Spec.Marks != "":
+		marks, err := gatherMarks(schema, *typ.Spec.Marks)
+		if err != nil {
+			return &SpecError{Path: nodePath(typ.Name, "marks"), Expr: *typ.Spec.Marks, Err: err}
 		}
-	case *typ.Spec.Marks == "" || !typ.InlineContent:
+
+		typ.Marks = marks
+	case typ.Spec.Marks != nil || !typ.InlineContent:
 		typ.Marks = []MarkType{}
+	default:
+		typ.Marks = nil
 	}
 
-	if ce, ok := contentExprCache[typ.Spec.Content]; ok {
-		typ.ContentMatch = ce
-		return nil
-	}
+	return nil
+}
 
-	if typ.Spec.Content == "" {
-		return nil
-	}
+// SpecError is an error in a schema spec, with the path of the offending
+// property, like nodes.heading.content.
+type SpecError struct {
+	Path string
 
-	cm, err := parseNodespecContent(typ.Spec.Content, schema.Nodes)
-	if err != nil {
-		return fmt.Errorf("error parsing content for node %q: %w", typ, err)
+	// The content expression or list of marks the error was found in, if any.
+	Expr string
+
+	Err error
+}
+
+func (e *SpecError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+func (e *SpecError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks that the spec compiles to a schema, without registering it.
+// Errors are reported as a *SpecError when the offending property is known.
+func (s SchemaSpec) Validate() error {
+	s.DontRegister = true
+	_, err := NewSchema(s)
+	if specErr := (*SpecError)(nil); errors.As(err, &specErr) {
+		return specErr
 	}
 
-	ce := dfa(nfa(cm))
+	return err
+}
 
-	contentExprCache[typ.Spec.Content] = *ce
-	typ.ContentMatch = *ce
-	typ.InlineContent = ce.InlineContent()
-	return nil
+func nodePath(name NodeTypeName, property string) string {
+	return joinPath("nodes", string(name), property)
+}
+
+func markPath(name MarkTypeName, property string) string {
+	return joinPath("marks", string(name), property)
+}
+
+func joinPath(parts ...string) string {
+	path := ""
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+
+		if path != "" {
+			path += "."
+		}
+		path += part
+	}
+
+	return path
 }
 
+// NewSchema compiles a schema spec. Errors in the spec are reported as a
+// *SpecError.
 func NewSchema(spec SchemaSpec) (Schema, error) {
 	s := Schema{Spec: spec}
 
+	// the maps are shared by every copy of the schema held by its types,
+	// so they're created first and filled in place.
+	s.Nodes = map[NodeTypeName]NodeType{}
+	s.Marks = map[MarkTypeName]MarkType{}
+
 	nodes, err := compileNodeTypeSet(s, spec.Nodes)
 	if err != nil {
 		return Schema{}, fmt.Errorf("error compiling nodes: %w", err)
@@ -105,12 +194,17 @@ func NewSchema(spec SchemaSpec) (Schema, error) {
 		return Schema{}, fmt.Errorf("error compiling marks: %w", err)
 	}
 
-	s.Nodes = nodes
-	s.Marks = marks
+	for name, node := range nodes {
+		s.Nodes[name] = node
+	}
+
+	for name, mark := range marks {
+		s.Marks[name] = mark
+	}
 
-	contentExprCache := map[string]ContentMatch{}
+	contentExprCache := map[string]*ContentMatch{}
 	for k := range nodes {
-		node := nodes[k]
+		node := s.Nodes[k]
 		err := compileContentMatch(&node, s, contentExprCache)
 		if err != nil {
 			return Schema{}, err
@@ -119,7 +213,32 @@ func NewSchema(spec SchemaSpec) (Schema, error) {
 		nodes[k] = node
 	}
 
-	topnodeT := s.Nodes[spec.TopNode]
+	for name, node := range nodes {
+		s.Nodes[name] = node
+	}
+
+	for name, mark := range s.Marks {
+		switch {
+		case mark.Spec.Excludes == nil:
+			mark.Excluded = []MarkType{mark}
+		case *mark.Spec.Excludes == "":
+			mark.Excluded = []MarkType{}
+		default:
+			excluded, err := gatherMarks(s, *mark.Spec.Excludes)
+			if err != nil {
+				return Schema{}, &SpecError{Path: markPath(name, "excludes"), Expr: *mark.Spec.Excludes, Err: err}
+			}
+			mark.Excluded = excluded
+		}
+
+		marks[name] = mark
+	}
+
+	for name, mark := range marks {
+		s.Marks[name] = mark
+	}
+
+	topnodeT := s.Nodes[cmp.Or(spec.TopNode, "doc")]
 	s.TopNodeType = &topnodeT
 
 	if !spec.DontRegister {
@@ -0,0 +1,295 @@
+package schema
+
+import (
+	"fmt"
+
+	"golang.org/x/net/html"
+
+	p "github.com/karitham/prosemirror"
+)
+
+// The schema of a subset of the Atlassian Document Format, using its names and attributes:
+// text formatting, headings, lists, code blocks, blockquotes, rules, panels, tables,
+// mentions, emojis, statuses, dates and inline cards.
+// It shares node names with the default schema, so it isn't registered
+// in the global schema store.
+var (
+	ADFSpec = p.SchemaSpec{
+		Nodes:   ADFNodes,
+		TopNode: "doc",
+		Marks:   ADFMarks,
+
+		NodeOrder: []p.NodeTypeName{
+			"doc", "paragraph", "heading", "bulletList", "orderedList", "listItem", "codeBlock",
+			"blockquote", "rule", "panel", "table", "tableRow", "tableHeader", "tableCell",
+			"text", "hardBreak", "mention", "emoji", "status", "date", "inlineCard",
+		},
+		MarkOrder: []p.MarkTypeName{"link", "em", "strong", "strike", "underline", "subsup", "textColor", "code"},
+
+		DontRegister: true,
+	}
+
+	// ADF code can only be combined with links, formatting marks are in the "format" group.
+	ADFMarks = map[p.MarkTypeName]p.MarkSpec{
+		"link": {
+			Attrs: map[string]p.Attribute{
+				"href":  {},
+				"title": {Optional: true},
+			},
+			Inclusive: opt(false),
+			ToDOM:     DefaultMarks["link"].ToDOM,
+			ParseDOM:  DefaultMarks["link"].ParseDOM,
+		},
+		"em": {
+			Group: "format",
+			ToDOM: markDOM("em"),
+		},
+		"strong": {
+			Group: "format",
+			ToDOM: markDOM("strong"),
+		},
+		"strike": {
+			Group: "format",
+			ToDOM: markDOM("s"),
+		},
+		"underline": {
+			Group: "format",
+			ToDOM: markDOM("u"),
+		},
+		"subsup": {
+			Group: "format",
+			Attrs: map[string]p.Attribute{
+				"type": {Default: "sub"},
+			},
+			ToDOM: func(m p.Mark, _ bool) p.DOMOutputSpec {
+				return p.DOMElement(fmt.Sprint(m.Attrs["type"]), nil, p.DOMHole)
+			},
+		},
+		"textColor": {
+			Group: "format",
+			Attrs: map[string]p.Attribute{
+				"color": {},
+			},
+			ToDOM: func(m p.Mark, _ bool) p.DOMOutputSpec {
+				return p.DOMElement("span", map[string]string{"style": fmt.Sprintf("color: %v", m.Attrs["color"])}, p.DOMHole)
+			},
+		},
+		"code": {
+			Excludes: opt("code format"),
+			ToDOM:    markDOM("code"),
+		},
+	}
+
+	ADFNodes = map[p.NodeTypeName]p.NodeSpec{
+		"doc": {
+			Content: "block+",
+		},
+		"paragraph": {
+			Content: "inline*",
+			Group:   "block",
+			ToDOM:   nodeDOM("p"),
+		},
+		"heading": {
+			Content:  "inline*",
+			Group:    "block",
+			Defining: true,
+			Attrs: map[string]p.Attribute{
+				"level": {Default: 1},
+			},
+			ToDOM: DefaultNodes["heading"].ToDOM,
+		},
+		"bulletList": {
+			Content: "listItem+",
+			Group:   "block",
+			ToDOM:   nodeDOM("ul"),
+		},
+		"orderedList": {
+			Content: "listItem+",
+			Group:   "block",
+			Attrs: map[string]p.Attribute{
+				"order": {Default: 1},
+			},
+			ToDOM: func(n p.Node) p.DOMOutputSpec {
+				return p.DOMElement("ol", map[string]string{"start": fmt.Sprint(n.Attrs["order"])}, p.DOMHole)
+			},
+		},
+		"listItem": {
+			Content:  "(paragraph | codeBlock) (paragraph | bulletList | orderedList | codeBlock)*",
+			Defining: true,
+			ToDOM:    nodeDOM("li"),
+		},
+		"codeBlock": {
+			Content:  "text*",
+			Group:    "block",
+			Marks:    opt(""),
+			Code:     true,
+			Defining: true,
+			Attrs: map[string]p.Attribute{
+				"language": {Optional: true},
+			},
+			ToDOM: TiptapNodes["codeBlock"].ToDOM,
+		},
+		"blockquote": {
+			Content:  "(paragraph | bulletList | orderedList | codeBlock)+",
+			Group:    "block",
+			Defining: true,
+			ToDOM:    nodeDOM("blockquote"),
+		},
+		"rule": {
+			Group: "block",
+			ToDOM: DefaultNodes["horizontal_rule"].ToDOM,
+		},
+		"panel": {
+			Content:  "(paragraph | heading | bulletList | orderedList | codeBlock | rule)+",
+			Group:    "block",
+			Defining: true,
+			Attrs: map[string]p.Attribute{
+				"panelType": {Default: "info"},
+			},
+			ToDOM: func(n p.Node) p.DOMOutputSpec {
+				return p.DOMElement("div", map[string]string{"data-panel-type": fmt.Sprint(n.Attrs["panelType"])}, p.DOMHole)
+			},
+			ParseDOM: []p.ParseRule{{Tag: "div[data-panel-type]", GetAttrs: func(n *html.Node) (map[string]any, bool) {
+				typ, _ := p.DOMAttr(n, "data-panel-type")
+				return map[string]any{"panelType": typ}, true
+			}}},
+		},
+		"table": {
+			Content:   "tableRow+",
+			Group:     "block",
+			Isolating: true,
+			Attrs: map[string]p.Attribute{
+				"isNumberColumnEnabled": {Default: false},
+				"layout":                {Default: "default"},
+			},
+			ToDOM: func(p.Node) p.DOMOutputSpec {
+				return p.DOMElement("table", nil, p.DOMElement("tbody", nil, p.DOMHole))
+			},
+		},
+		"tableRow": {
+			Content: "(tableHeader | tableCell)+",
+			ToDOM:   nodeDOM("tr"),
+		},
+		"tableHeader": {
+			Content:   "(paragraph | heading | bulletList | orderedList | codeBlock | blockquote | rule | panel)+",
+			Isolating: true,
+			Attrs:     tableCellAttrs(),
+			ToDOM:     tableCellDOM("th"),
+		},
+		"tableCell": {
+			Content:   "(paragraph | heading | bulletList | orderedList | codeBlock | blockquote | rule | panel)+",
+			Isolating: true,
+			Attrs:     tableCellAttrs(),
+			ToDOM:     tableCellDOM("td"),
+		},
+		"text": {
+			Group: "inline",
+		},
+		"hardBreak": {
+			Inline:   true,
+			Group:    "inline",
+			ToDOM:    DefaultNodes["hard_break"].ToDOM,
+			LeafText: DefaultNodes["hard_break"].LeafText,
+		},
+		"mention": {
+			Inline: true,
+			Group:  "inline",
+			Attrs: map[string]p.Attribute{
+				"id":          {},
+				"text":        {Default: ""},
+				"accessLevel": {Optional: true},
+			},
+			ToDOM: func(n p.Node) p.DOMOutputSpec {
+				return p.DOMElement("span", map[string]string{"data-mention-id": fmt.Sprint(n.Attrs["id"])}, p.DOMText(fmt.Sprint(n.Attrs["text"])))
+			},
+			LeafText: textAttr("text"),
+		},
+		"emoji": {
+			Inline: true,
+			Group:  "inline",
+			Attrs: map[string]p.Attribute{
+				"shortName": {},
+				"id":        {Optional: true},
+				"text":      {Optional: true},
+			},
+			ToDOM: func(n p.Node) p.DOMOutputSpec {
+				return p.DOMElement("span", map[string]string{"data-emoji-short-name": fmt.Sprint(n.Attrs["shortName"])}, p.DOMText(emojiText(n)))
+			},
+			LeafText: emojiText,
+		},
+		"status": {
+			Inline: true,
+			Group:  "inline",
+			Attrs: map[string]p.Attribute{
+				"text":    {Default: ""},
+				"color":   {Default: "neutral"},
+				"localId": {Optional: true},
+			},
+			ToDOM: func(n p.Node) p.DOMOutputSpec {
+				return p.DOMElement("span", map[string]string{"data-status-color": fmt.Sprint(n.Attrs["color"])}, p.DOMText(fmt.Sprint(n.Attrs["text"])))
+			},
+			LeafText: textAttr("text"),
+		},
+		"date": {
+			Inline: true,
+			Group:  "inline",
+			Attrs: map[string]p.Attribute{
+				"timestamp": {},
+			},
+			ToDOM: func(n p.Node) p.DOMOutputSpec {
+				ts := fmt.Sprint(n.Attrs["timestamp"])
+				return p.DOMElement("time", map[string]string{"data-timestamp": ts}, p.DOMText(ts))
+			},
+		},
+		"inlineCard": {
+			Inline: true,
+			Group:  "inline",
+			Attrs: map[string]p.Attribute{
+				"url": {},
+			},
+			ToDOM: func(n p.Node) p.DOMOutputSpec {
+				url := fmt.Sprint(n.Attrs["url"])
+				return p.DOMElement("a", map[string]string{"href": url}, p.DOMText(url))
+			},
+			LeafText: textAttr("url"),
+		},
+	}
+)
+
+func tableCellAttrs() map[string]p.Attribute {
+	return map[string]p.Attribute{
+		"colspan":    {Default: 1},
+		"rowspan":    {Default: 1},
+		"colwidth":   {Optional: true},
+		"background": {Optional: true},
+	}
+}
+
+func tableCellDOM(tag string) func(p.Node) p.DOMOutputSpec {
+	return func(n p.Node) p.DOMOutputSpec {
+		attrs := map[string]string{}
+		for _, name := range []string{"colspan", "rowspan"} {
+			if v := fmt.Sprint(n.Attrs[name]); v != "1" {
+				attrs[name] = v
+			}
+		}
+
+		return p.DOMElement(tag, attrs, p.DOMHole)
+	}
+}
+
+func textAttr(name string) func(p.Node) string {
+	return func(n p.Node) string {
+		s, _ := n.Attrs[name].(string)
+		return s
+	}
+}
+
+// emojiText returns the text of an emoji, or its short name for custom emojis.
+func emojiText(n p.Node) string {
+	if text, ok := n.Attrs["text"].(string); ok && text != "" {
+		return text
+	}
+
+	return fmt.Sprint(n.Attrs["shortName"])
+}
@@ -1,21 +1,69 @@
 package schema
 
 import (
+	"fmt"
+	"regexp"
+
+	"golang.org/x/net/html"
+
 	p "github.com/karitham/prosemirror"
 )
 
 var (
+	// DefaultSpec follows prosemirror-schema-basic: the alt and title of images
+	// and the language of code blocks are optional, and headings, blockquotes
+	// and code blocks are defining. Unlike there, the href of links is optional,
+	// so that stored documents holding bare link marks keep decoding.
 	DefaultSpec = p.SchemaSpec{
 		Nodes:   DefaultNodes,
 		TopNode: "doc",
 		Marks:   DefaultMarks,
+
+		NodeOrder: []p.NodeTypeName{"doc", "paragraph", "blockquote", "horizontal_rule", "heading", "code_block", "text", "image", "hard_break"},
+		MarkOrder: []p.MarkTypeName{"link", "em", "strong", "code"},
 	}
 
 	DefaultMarks = map[p.MarkTypeName]p.MarkSpec{
-		"link":   {},
-		"em":     {},
-		"strong": {},
-		"code":   {},
+		"link": {
+			Attrs: map[string]p.Attribute{
+				"href":  {Optional: true},
+				"title": {Optional: true},
+			},
+			Inclusive: opt(false),
+			ToDOM: func(m p.Mark, _ bool) p.DOMOutputSpec {
+				return p.DOMElement("a", domAttrs(m.Attrs, "href", "title"), p.DOMHole)
+			},
+			ParseDOM: []p.ParseRule{{Tag: "a[href]", GetAttrs: getAttrs("href", "title")}},
+		},
+		"em": {
+			ToDOM: markDOM("em"),
+			ParseDOM: []p.ParseRule{
+				{Tag: "i"},
+				{Tag: "em"},
+				{Style: "font-style=italic"},
+				{Style: "font-style=normal", ClearMark: isMark("em")},
+			},
+		},
+		"strong": {
+			ToDOM: markDOM("strong"),
+			ParseDOM: []p.ParseRule{
+				{Tag: "strong"},
+				// This works around a Google Docs misbehavior where
+				// pasted content will be inexplicably wrapped in `<b>`
+				// tags with a font-weight normal.
+				{Tag: "b", GetAttrs: func(n *html.Node) (map[string]any, bool) {
+					return nil, p.DOMStyle(n, "font-weight") != "normal"
+				}},
+				{Style: "font-weight=400", ClearMark: isMark("strong")},
+				{Style: "font-weight", GetStyleAttrs: func(value string) (map[string]any, bool) {
+					return nil, boldRe.MatchString(value)
+				}},
+			},
+		},
+		"code": {
+			ToDOM:    markDOM("code"),
+			ParseDOM: []p.ParseRule{{Tag: "code"}},
+		},
 	}
 
 	DefaultNodes = map[p.NodeTypeName]p.NodeSpec{
@@ -23,34 +71,59 @@ var (
 			Content: "block+",
 		},
 		"paragraph": {
-			Content: "inline*",
-			Group:   "block",
+			Content:  "inline*",
+			Group:    "block",
+			ToDOM:    nodeDOM("p"),
+			ParseDOM: []p.ParseRule{{Tag: "p"}},
 		},
 		"blockquote": {
-			Content: "block+",
-			Group:   "block",
+			Content:  "block+",
+			Group:    "block",
+			Defining: true,
+			ToDOM:    nodeDOM("blockquote"),
+			ParseDOM: []p.ParseRule{{Tag: "blockquote"}},
 		},
 		"horizontal_rule": {
-			Group: "block",
+			Group:    "block",
+			ToDOM:    func(p.Node) p.DOMOutputSpec { return p.DOMElement("hr", nil) },
+			ParseDOM: []p.ParseRule{{Tag: "hr"}},
 		},
 		"heading": {
-			Content: "inline*",
-			Group:   "block",
+			Content:  "inline*",
+			Group:    "block",
+			Defining: true,
 			Attrs: map[string]p.Attribute{
 				"level": {
 					Default: 1,
 				},
 			},
+			ToDOM: func(n p.Node) p.DOMOutputSpec {
+				return p.DOMElement(fmt.Sprintf("h%d", headingLevel(n)), nil, p.DOMHole)
+			},
+			ParseDOM: []p.ParseRule{
+				{Tag: "h1", Attrs: map[string]any{"level": 1}},
+				{Tag: "h2", Attrs: map[string]any{"level": 2}},
+				{Tag: "h3", Attrs: map[string]any{"level": 3}},
+				{Tag: "h4", Attrs: map[string]any{"level": 4}},
+				{Tag: "h5", Attrs: map[string]any{"level": 5}},
+				{Tag: "h6", Attrs: map[string]any{"level": 6}},
+			},
 		},
 		"code_block": {
-			Content: "text*",
-			Group:   "block",
-			Marks:   opt(""),
+			Content:  "text*",
+			Group:    "block",
+			Marks:    opt(""),
+			Code:     true,
+			Defining: true,
 			Attrs: map[string]p.Attribute{
 				"language": {
-					Default: nil,
+					Optional: true,
 				},
 			},
+			ToDOM: func(p.Node) p.DOMOutputSpec {
+				return p.DOMElement("pre", nil, p.DOMElement("code", nil, p.DOMHole))
+			},
+			ParseDOM: []p.ParseRule{{Tag: "pre", PreserveWhitespace: p.WhitespaceFull}},
 		},
 		"text": {
 			Group: "inline",
@@ -59,14 +132,21 @@ var (
 			Inline: true,
 			Attrs: map[string]p.Attribute{
 				"src":   {},
-				"alt":   {},
-				"title": {},
+				"alt":   {Optional: true},
+				"title": {Optional: true},
 			},
 			Group: "inline",
+			ToDOM: func(n p.Node) p.DOMOutputSpec {
+				return p.DOMElement("img", domAttrs(n.Attrs, "src", "alt", "title"))
+			},
+			ParseDOM: []p.ParseRule{{Tag: "img[src]", GetAttrs: getAttrs("src", "alt", "title")}},
 		},
 		"hard_break": {
-			Inline: true,
-			Group:  "inline",
+			Inline:   true,
+			Group:    "inline",
+			ToDOM:    func(p.Node) p.DOMOutputSpec { return p.DOMElement("br", nil) },
+			ParseDOM: []p.ParseRule{{Tag: "br"}},
+			LeafText: func(p.Node) string { return "\n" },
 		},
 	}
 )
@@ -80,6 +160,57 @@ func init() {
 	DefaultSpec.DontRegister = true
 }
 
+// headingLevel returns the level of a heading between 1 and 6, defaulting to 1
+// when it isn't a number.
+func headingLevel(n p.Node) int {
+	level, ok := p.IntAttr(n.Attrs["level"])
+	if !ok {
+		return 1
+	}
+
+	return max(1, min(level, 6))
+}
+
+func nodeDOM(tag string) func(p.Node) p.DOMOutputSpec {
+	return func(p.Node) p.DOMOutputSpec { return p.DOMElement(tag, nil, p.DOMHole) }
+}
+
+func markDOM(tag string) func(p.Mark, bool) p.DOMOutputSpec {
+	return func(p.Mark, bool) p.DOMOutputSpec { return p.DOMElement(tag, nil, p.DOMHole) }
+}
+
+// domAttrs converts the given attributes to HTML attributes, leaving out the unset ones.
+func domAttrs(attrs map[string]any, names ...string) map[string]string {
+	out := map[string]string{}
+	for _, name := range names {
+		if v := attrs[name]; v != nil {
+			out[name] = fmt.Sprint(v)
+		}
+	}
+
+	return out
+}
+
+// getAttrs reads the given attributes from an element, leaving out the missing ones.
+func getAttrs(names ...string) func(*html.Node) (map[string]any, bool) {
+	return func(n *html.Node) (map[string]any, bool) {
+		attrs := map[string]any{}
+		for _, name := range names {
+			if v, ok := p.DOMAttr(n, name); ok {
+				attrs[name] = v
+			}
+		}
+
+		return attrs, true
+	}
+}
+
+func isMark(name p.MarkTypeName) func(p.Mark) bool {
+	return func(m p.Mark) bool { return m.Type.Name == name }
+}
+
+var boldRe = regexp.MustCompile(`^(bold(er)?|[5-9]\d{2,})$`)
+
 func opt[T any](optVal T) *T {
 	return &optVal
 }
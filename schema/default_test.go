@@ -1,8 +1,12 @@
 package schema_test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/go-json-experiment/json"
+
 	p "github.com/karitham/prosemirror"
 	"github.com/karitham/prosemirror/schema"
 	"github.com/stretchr/testify/assert"
@@ -16,3 +20,195 @@ func TestDefaultSchema(t *testing.T) {
 		assert.NoError(t, err, "error creating text node")
 	}
 }
+
+func TestDefaultSchemaBareLink(t *testing.T) {
+	s := p.Must(p.NewSchema(schema.DefaultSpec))
+
+	// stored documents may hold links without an href
+	assert.Equal(t, map[string]any{"href": nil, "title": nil}, s.Marks["link"].Create(nil).Attrs)
+}
+
+func TestDefaultSchemaToDOM(t *testing.T) {
+	s := p.Must(p.NewSchema(schema.DefaultSpec))
+
+	doc := s.Node("doc", nil, p.NewFragment(
+		s.Node("heading", map[string]any{"level": 2}, p.NewFragment(s.Text("Title"))),
+		s.Node("paragraph", nil, p.NewFragment(
+			s.Text("see "),
+			s.Text("here", s.Mark("link", map[string]any{"href": "https://example.com"}), s.Mark("strong", nil)),
+			s.Node("image", map[string]any{"src": "a.png"}, p.Fragment{}),
+		)),
+		s.Node("horizontal_rule", nil, p.Fragment{}),
+	))
+
+	var sb strings.Builder
+	if assert.NoError(t, p.DOMSerializerFromSchema(s).SerializeFragment(&sb, doc.Content)) {
+		assert.Equal(t, `<h2>Title</h2><p>see <a href="https://example.com"><strong>here</strong></a><img src="a.png"></p><hr>`, sb.String())
+	}
+}
+
+func TestDefaultSchemaHeadingLevel(t *testing.T) {
+	s := p.Must(p.NewSchema(schema.DefaultSpec))
+	ser := p.DOMSerializerFromSchema(s)
+
+	tests := []struct {
+		level any
+		want  string
+	}{
+		{level: 3, want: "<h3>a</h3>"},
+		{level: 2.0, want: "<h2>a</h2>"},
+		{level: "4", want: "<h4>a</h4>"},
+		{level: 0, want: "<h1>a</h1>"},
+		{level: 9, want: "<h6>a</h6>"},
+		{level: "1 onmouseover=alert(1) x", want: "<h1>a</h1>"},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprint(tc.level), func(t *testing.T) {
+			heading := s.Node("heading", map[string]any{"level": tc.level}, p.NewFragment(s.Text("a")))
+
+			var sb strings.Builder
+			if assert.NoError(t, ser.SerializeNode(&sb, heading)) {
+				assert.Equal(t, tc.want, sb.String())
+			}
+		})
+	}
+}
+
+func TestDefaultSchemaParseDOM(t *testing.T) {
+	s := p.Must(p.NewSchema(schema.DefaultSpec))
+
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "round trip",
+			html: `<h2>Title</h2><p>see <a href="https://example.com"><strong>here</strong></a><img src="a.png"></p><hr><pre><code>a
+  b</code></pre>`,
+			want: `<h2>Title</h2><p>see <a href="https://example.com"><strong>here</strong></a><img src="a.png"></p><hr><pre><code>a
+  b</code></pre>`,
+		},
+		{
+			name: "google docs bold",
+			html: `<b style="font-weight:normal"><p>plain <span style="font-weight:700">bold</span> <span style="font-style:italic">it</span></p></b>`,
+			want: `<p>plain <strong>bold</strong> <em>it</em></p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := p.DOMParserFromSchema(s).Parse(strings.NewReader(tt.html), p.ParseOptions{})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var sb strings.Builder
+			if assert.NoError(t, p.DOMSerializerFromSchema(s).SerializeFragment(&sb, doc.Content)) {
+				assert.Equal(t, tt.want, sb.String())
+			}
+		})
+	}
+}
+
+func TestTiptapSchema(t *testing.T) {
+	s := p.Must(p.NewSchema(schema.TiptapSpec))
+	match := s.Nodes["doc"].ContentMatch
+	assert.Equal(t, "paragraph", string(match.DefaultType().Name))
+
+	html := `<h2>Title</h2><p><strong>a</strong><em>b</em><s>c</s><code>d</code><br></p>` +
+		`<ol start="3"><li><p>x</p><ul><li><p>y</p></li></ul></li></ol><pre><code class="language-go">a</code></pre><hr>`
+	doc, err := p.DOMParserFromSchema(s).Parse(strings.NewReader(html), p.ParseOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 3, doc.Child(2).Attrs["start"])
+	assert.Equal(t, "go", doc.Child(3).Attrs["language"])
+
+	var sb strings.Builder
+	if assert.NoError(t, p.DOMSerializerFromSchema(s).SerializeFragment(&sb, doc.Content)) {
+		assert.Equal(t, html, sb.String())
+	}
+}
+
+func TestAddListNodes(t *testing.T) {
+	spec := schema.AddListNodes(schema.DefaultSpec, "paragraph block*", "block")
+	assert.NotContains(t, schema.DefaultSpec.Nodes, p.NodeTypeName("list_item"), "the original spec should be left unchanged")
+
+	s := p.Must(p.NewSchema(spec))
+	assert.Equal(t, "block", s.Nodes["bullet_list"].Spec.Group)
+
+	html := `<ol start="3"><li><p>x</p><ul><li><p>y</p></li></ul></li></ol><ol><li><p>z</p></li></ol>`
+	doc, err := p.DOMParserFromSchema(s).Parse(strings.NewReader(html), p.ParseOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 3, doc.Child(0).Attrs["order"])
+	assert.Equal(t, 1, doc.Child(1).Attrs["order"])
+
+	var sb strings.Builder
+	if assert.NoError(t, p.DOMSerializerFromSchema(s).SerializeFragment(&sb, doc.Content)) {
+		assert.Equal(t, html, sb.String())
+	}
+}
+
+func TestListNodesTight(t *testing.T) {
+	s := p.Must(p.NewSchema(schema.AddListNodes(schema.DefaultSpec, "paragraph block*", "block")))
+
+	html := `<ul data-tight="true"><li><p>a</p></li></ul><ol data-tight="true" start="2"><li><p>b</p></li></ol><ul><li><p>c</p></li></ul>`
+	doc, err := p.DOMParserFromSchema(s).Parse(strings.NewReader(html), p.ParseOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, true, doc.Child(0).Attrs["tight"])
+	assert.Equal(t, true, doc.Child(1).Attrs["tight"])
+	assert.Equal(t, false, doc.Child(2).Attrs["tight"])
+
+	var sb strings.Builder
+	if assert.NoError(t, p.DOMSerializerFromSchema(s).SerializeFragment(&sb, doc.Content)) {
+		assert.Equal(t, html, sb.String())
+	}
+}
+
+func TestAddListNodesJSON(t *testing.T) {
+	spec := schema.AddListNodes(schema.DefaultSpec, "paragraph block*", "block")
+	assert.True(t, spec.DontRegister, "the spec should keep the choice of not registering its schema")
+
+	s := p.Must(p.NewSchema(spec))
+	p.RegisterSchema(s)
+
+	var doc p.Node
+	err := json.Unmarshal([]byte(`{"type":"doc","content":[{"type":"ordered_list","attrs":{"order":2},"content":[{"type":"list_item","content":[{"type":"paragraph","content":[{"type":"text","text":"x"}]}]}]}]}`), &doc)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	list := doc.Child(0)
+	assert.True(t, list.Type.Eq(s.Nodes["ordered_list"]))
+	assert.Equal(t, p.NodeTypeName("list_item"), list.Child(0).Type.Name)
+	assert.NoError(t, s.Nodes["doc"].CheckContent(doc.Content))
+}
+
+func TestAddTableNodes(t *testing.T) {
+	s := p.Must(p.NewSchema(schema.AddTableNodes(schema.DefaultSpec, "block+", "block")))
+
+	html := `<table><tbody><tr><th colspan="2" data-colwidth="100,200"><p>a</p></th></tr>` +
+		`<tr><td><p>b</p></td><td rowspan="2"><p>c</p></td></tr><tr><td><p>d</p></td></tr></tbody></table>`
+	doc, err := p.DOMParserFromSchema(s).Parse(strings.NewReader(html), p.ParseOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	header := doc.Child(0).Child(0).Child(0)
+	assert.Equal(t, "table_header", string(header.Type.Name))
+	assert.Equal(t, []int{100, 200}, header.Attrs["colwidth"])
+
+	var sb strings.Builder
+	if assert.NoError(t, p.DOMSerializerFromSchema(s).SerializeFragment(&sb, doc.Content)) {
+		assert.Equal(t, html, sb.String())
+	}
+}
@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	"golang.org/x/net/html"
+
+	p "github.com/karitham/prosemirror"
+)
+
+var (
+	// OrderedList is the spec of an ordered list. It has an `order` attribute, which
+	// determines the number at which the list starts counting, and defaults to 1, and
+	// a `tight` attribute, telling whether the list is rendered without blank lines
+	// between its items in Markdown. Its content and group are set by AddListNodes.
+	OrderedList = p.NodeSpec{
+		Attrs: map[string]p.Attribute{
+			"order": {Default: 1},
+			"tight": {Default: false},
+		},
+		ToDOM: func(n p.Node) p.DOMOutputSpec {
+			attrs := tightDOM(n)
+			if order := fmt.Sprint(n.Attrs["order"]); order != "1" {
+				attrs["start"] = order
+			}
+
+			return p.DOMElement("ol", attrs, p.DOMHole)
+		},
+		ParseDOM: []p.ParseRule{{Tag: "ol", GetAttrs: func(n *html.Node) (map[string]any, bool) {
+			order := 1
+			if v, ok := p.DOMAttr(n, "start"); ok {
+				if start, err := strconv.Atoi(v); err == nil {
+					order = start
+				}
+			}
+
+			_, tight := p.DOMAttr(n, "data-tight")
+			return map[string]any{"order": order, "tight": tight}, true
+		}}},
+	}
+
+	// BulletList is the spec of a bullet list. Like OrderedList, it has a `tight`
+	// attribute. Its content and group are set by AddListNodes.
+	BulletList = p.NodeSpec{
+		Attrs: map[string]p.Attribute{
+			"tight": {Default: false},
+		},
+		ToDOM: func(n p.Node) p.DOMOutputSpec {
+			return p.DOMElement("ul", tightDOM(n), p.DOMHole)
+		},
+		ParseDOM: []p.ParseRule{{Tag: "ul", GetAttrs: func(n *html.Node) (map[string]any, bool) {
+			_, tight := p.DOMAttr(n, "data-tight")
+			return map[string]any{"tight": tight}, true
+		}}},
+	}
+
+	// ListItem is the spec of a list item. Its content is set by AddListNodes.
+	ListItem = p.NodeSpec{
+		Defining: true,
+		ToDOM:    nodeDOM("li"),
+		ParseDOM: []p.ParseRule{{Tag: "li"}},
+	}
+)
+
+// tightDOM returns the HTML attributes of a list, marking tight lists with data-tight.
+func tightDOM(n p.Node) map[string]string {
+	attrs := map[string]string{}
+	if tight, _ := n.Attrs["tight"].(bool); tight {
+		attrs["data-tight"] = "true"
+	}
+
+	return attrs
+}
+
+// AddListNodes returns a copy of the schema spec with the list nodes added, as
+// ordered_list, bullet_list and list_item. itemContent is the content expression
+// of list items, and listGroup the group of the lists, like "block".
+func AddListNodes(spec p.SchemaSpec, itemContent, listGroup string) p.SchemaSpec {
+	nodes := maps.Clone(spec.Nodes)
+
+	orderedList := OrderedList
+	orderedList.Content, orderedList.Group = "list_item+", listGroup
+	nodes["ordered_list"] = orderedList
+
+	bulletList := BulletList
+	bulletList.Content, bulletList.Group = "list_item+", listGroup
+	nodes["bullet_list"] = bulletList
+
+	listItem := ListItem
+	listItem.Content = itemContent
+	nodes["list_item"] = listItem
+
+	spec.Nodes = nodes
+	spec.NodeOrder = append(slices.Clone(spec.NodeOrder), "ordered_list", "bullet_list", "list_item")
+	return spec
+}
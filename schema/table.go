@@ -0,0 +1,155 @@
+package schema
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	p "github.com/karitham/prosemirror"
+)
+
+var (
+	// Table is the spec of a table. Its group is set by AddTableNodes.
+	//
+	// Table nodes have a "tableRole" in their spec's Extra, one of "table",
+	// "row", "cell" and "header_cell", which is how the tables package finds them.
+	Table = p.NodeSpec{
+		Content:   "table_row+",
+		Isolating: true,
+		ToDOM: func(p.Node) p.DOMOutputSpec {
+			return p.DOMElement("table", nil, p.DOMElement("tbody", nil, p.DOMHole))
+		},
+		ParseDOM: []p.ParseRule{{Tag: "table"}},
+		Extra:    map[string]any{"tableRole": "table"},
+	}
+
+	// TableRow is the spec of a table row.
+	TableRow = p.NodeSpec{
+		Content:  "(table_cell | table_header)*",
+		ToDOM:    nodeDOM("tr"),
+		ParseDOM: []p.ParseRule{{Tag: "tr"}},
+		Extra:    map[string]any{"tableRole": "row"},
+	}
+
+	// TableCell is the spec of a table cell. It has `colspan` and `rowspan`
+	// attributes, defaulting to 1, and a `colwidth` attribute, holding the
+	// width in pixels of each column it spans, or nil. Its content is set
+	// by AddTableNodes.
+	TableCell = p.NodeSpec{
+		Isolating: true,
+		Attrs:     cellAttrs(),
+		ToDOM:     cellDOM("td"),
+		ParseDOM:  []p.ParseRule{{Tag: "td", GetAttrs: parseCellAttrs}},
+		Extra:     map[string]any{"tableRole": "cell"},
+	}
+
+	// TableHeader is the spec of a table header cell, with the attributes of TableCell.
+	TableHeader = p.NodeSpec{
+		Isolating: true,
+		Attrs:     cellAttrs(),
+		ToDOM:     cellDOM("th"),
+		ParseDOM:  []p.ParseRule{{Tag: "th", GetAttrs: parseCellAttrs}},
+		Extra:     map[string]any{"tableRole": "header_cell"},
+	}
+)
+
+// AddTableNodes returns a copy of the schema spec with the table nodes added, as
+// table, table_row, table_cell and table_header. cellContent is the content
+// expression of cells, and tableGroup the group of tables, like "block".
+func AddTableNodes(spec p.SchemaSpec, cellContent, tableGroup string) p.SchemaSpec {
+	nodes := maps.Clone(spec.Nodes)
+
+	table := Table
+	table.Group = tableGroup
+	nodes["table"] = table
+	nodes["table_row"] = TableRow
+
+	cell := TableCell
+	cell.Content = cellContent
+	nodes["table_cell"] = cell
+
+	header := TableHeader
+	header.Content = cellContent
+	nodes["table_header"] = header
+
+	spec.Nodes = nodes
+	spec.NodeOrder = append(slices.Clone(spec.NodeOrder), "table", "table_row", "table_cell", "table_header")
+	return spec
+}
+
+func cellAttrs() map[string]p.Attribute {
+	return map[string]p.Attribute{
+		"colspan":  {Default: 1},
+		"rowspan":  {Default: 1},
+		"colwidth": {Optional: true},
+	}
+}
+
+var colwidthRe = regexp.MustCompile(`^\d+(,\d+)*$`)
+
+func parseCellAttrs(n *html.Node) (map[string]any, bool) {
+	span := func(name string) int {
+		v, _ := p.DOMAttr(n, name)
+		if i, err := strconv.Atoi(v); err == nil {
+			return i
+		}
+
+		return 1
+	}
+
+	colspan := span("colspan")
+	attrs := map[string]any{"colspan": colspan, "rowspan": span("rowspan"), "colwidth": nil}
+	if v, ok := p.DOMAttr(n, "data-colwidth"); ok && colwidthRe.MatchString(v) {
+		var widths []int
+		for _, w := range strings.Split(v, ",") {
+			i, _ := strconv.Atoi(w)
+			widths = append(widths, i)
+		}
+
+		if len(widths) == colspan {
+			attrs["colwidth"] = widths
+		}
+	}
+
+	return attrs, true
+}
+
+func cellDOM(tag string) func(p.Node) p.DOMOutputSpec {
+	return func(n p.Node) p.DOMOutputSpec {
+		attrs := map[string]string{}
+		for _, name := range []string{"colspan", "rowspan"} {
+			if v := fmt.Sprint(n.Attrs[name]); v != "1" {
+				attrs[name] = v
+			}
+		}
+
+		if widths := colwidths(n.Attrs["colwidth"]); len(widths) > 0 {
+			attrs["data-colwidth"] = strings.Join(widths, ",")
+		}
+
+		return p.DOMElement(tag, attrs, p.DOMHole)
+	}
+}
+
+// colwidths formats the widths of a colwidth attribute, which holds ints,
+// or numbers of any kind when decoded from JSON.
+func colwidths(v any) []string {
+	var widths []string
+	switch v := v.(type) {
+	case []int:
+		for _, w := range v {
+			widths = append(widths, strconv.Itoa(w))
+		}
+	case []any:
+		for _, w := range v {
+			widths = append(widths, fmt.Sprint(w))
+		}
+	}
+
+	return widths
+}
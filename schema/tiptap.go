@@ -0,0 +1,182 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	p "github.com/karitham/prosemirror"
+)
+
+// The schema of Tiptap's StarterKit, using Tiptap's names, attributes and HTML.
+// It shares node names with the default schema, so it isn't registered
+// in the global schema store.
+var (
+	TiptapSpec = p.SchemaSpec{
+		Nodes:   TiptapNodes,
+		TopNode: "doc",
+		Marks:   TiptapMarks,
+
+		// Tiptap orders the paragraph first, making it the default block
+		NodeOrder: []p.NodeTypeName{
+			"paragraph", "blockquote", "bulletList", "codeBlock", "doc", "hardBreak",
+			"heading", "horizontalRule", "listItem", "orderedList", "text",
+		},
+		MarkOrder: []p.MarkTypeName{"bold", "code", "italic", "strike"},
+
+		DontRegister: true,
+	}
+
+	TiptapMarks = map[p.MarkTypeName]p.MarkSpec{
+		"bold": {
+			ToDOM: markDOM("strong"),
+			ParseDOM: []p.ParseRule{
+				{Tag: "strong"},
+				{Tag: "b", GetAttrs: func(n *html.Node) (map[string]any, bool) {
+					return nil, p.DOMStyle(n, "font-weight") != "normal"
+				}},
+				{Style: "font-weight=400", ClearMark: isMark("bold")},
+				{Style: "font-weight", GetStyleAttrs: func(value string) (map[string]any, bool) {
+					return nil, boldRe.MatchString(value)
+				}},
+			},
+		},
+		"code": {
+			Excludes: opt("_"),
+			ToDOM:    markDOM("code"),
+			ParseDOM: []p.ParseRule{{Tag: "code"}},
+		},
+		"italic": {
+			ToDOM: markDOM("em"),
+			ParseDOM: []p.ParseRule{
+				{Tag: "i"},
+				{Tag: "em"},
+				{Style: "font-style=italic"},
+				{Style: "font-style=normal", ClearMark: isMark("italic")},
+			},
+		},
+		"strike": {
+			ToDOM: markDOM("s"),
+			ParseDOM: []p.ParseRule{
+				{Tag: "s"},
+				{Tag: "del"},
+				{Tag: "strike"},
+				{Style: "text-decoration=line-through"},
+				{Style: "text-decoration-line=line-through"},
+			},
+		},
+	}
+
+	TiptapNodes = map[p.NodeTypeName]p.NodeSpec{
+		"doc": {
+			Content: "block+",
+		},
+		"paragraph": {
+			Content:  "inline*",
+			Group:    "block",
+			ToDOM:    nodeDOM("p"),
+			ParseDOM: []p.ParseRule{{Tag: "p"}},
+		},
+		"text": {
+			Group: "inline",
+		},
+		"blockquote": {
+			Content:  "block+",
+			Group:    "block",
+			Defining: true,
+			ToDOM:    nodeDOM("blockquote"),
+			ParseDOM: []p.ParseRule{{Tag: "blockquote"}},
+		},
+		"bulletList": {
+			Content:  "listItem+",
+			Group:    "block list",
+			ToDOM:    nodeDOM("ul"),
+			ParseDOM: []p.ParseRule{{Tag: "ul"}},
+		},
+		"orderedList": {
+			Content: "listItem+",
+			Group:   "block list",
+			Attrs: map[string]p.Attribute{
+				"start": {Default: 1},
+			},
+			ToDOM: func(n p.Node) p.DOMOutputSpec {
+				if start, ok := n.Attrs["start"]; ok && fmt.Sprint(start) != "1" {
+					return p.DOMElement("ol", map[string]string{"start": fmt.Sprint(start)}, p.DOMHole)
+				}
+
+				return p.DOMElement("ol", nil, p.DOMHole)
+			},
+			ParseDOM: []p.ParseRule{{Tag: "ol", GetAttrs: func(n *html.Node) (map[string]any, bool) {
+				start := 1
+				if v, ok := p.DOMAttr(n, "start"); ok {
+					if s, err := strconv.Atoi(v); err == nil {
+						start = s
+					}
+				}
+
+				return map[string]any{"start": start}, true
+			}}},
+		},
+		"listItem": {
+			Content:  "paragraph block*",
+			Defining: true,
+			ToDOM:    nodeDOM("li"),
+			ParseDOM: []p.ParseRule{{Tag: "li"}},
+		},
+		"codeBlock": {
+			Content:  "text*",
+			Group:    "block",
+			Marks:    opt(""),
+			Code:     true,
+			Defining: true,
+			Attrs: map[string]p.Attribute{
+				"language": {Optional: true},
+			},
+			ToDOM: func(n p.Node) p.DOMOutputSpec {
+				var attrs map[string]string
+				if lang, ok := n.Attrs["language"].(string); ok && lang != "" {
+					attrs = map[string]string{"class": "language-" + lang}
+				}
+
+				return p.DOMElement("pre", nil, p.DOMElement("code", attrs, p.DOMHole))
+			},
+			ParseDOM: []p.ParseRule{{Tag: "pre", PreserveWhitespace: p.WhitespaceFull, GetAttrs: func(n *html.Node) (map[string]any, bool) {
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					class, _ := p.DOMAttr(c, "class")
+					for _, name := range strings.Fields(class) {
+						if lang, ok := strings.CutPrefix(name, "language-"); ok {
+							return map[string]any{"language": lang}, true
+						}
+					}
+				}
+
+				return nil, true
+			}}},
+		},
+		"heading": {
+			Content:  "inline*",
+			Group:    "block",
+			Defining: true,
+			Attrs: map[string]p.Attribute{
+				"level": {Default: 1},
+			},
+			ToDOM:    DefaultNodes["heading"].ToDOM,
+			ParseDOM: DefaultNodes["heading"].ParseDOM,
+		},
+		"horizontalRule": {
+			Group:    "block",
+			ToDOM:    DefaultNodes["horizontal_rule"].ToDOM,
+			ParseDOM: []p.ParseRule{{Tag: "hr"}},
+		},
+		"hardBreak": {
+			Inline:     true,
+			Group:      "inline",
+			Selectable: opt(false),
+			ToDOM:      DefaultNodes["hard_break"].ToDOM,
+			ParseDOM:   []p.ParseRule{{Tag: "br"}},
+			LeafText:   DefaultNodes["hard_break"].LeafText,
+		},
+	}
+)
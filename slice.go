@@ -9,7 +9,72 @@ type Slice struct {
 }
 
 func (s Slice) String() string {
-	return fmt.Sprintf("Slice{Content: %v, OpenStart: %d, OpenEnd: %d}", s.Content, s.OpenStart, s.OpenEnd)
+	return fmt.Sprintf("%s(%d,%d)", s.Content, s.OpenStart, s.OpenEnd)
+}
+
+// NewSlice creates a slice from a fragment and its open depths.
+func NewSlice(content Fragment, openStart, openEnd int) Slice {
+	return Slice{Content: content, OpenStart: openStart, OpenEnd: openEnd}
+}
+
+// MaxOpen creates a slice from a fragment by taking the maximum possible
+// open value on both side of the fragment.
+func MaxOpen(f Fragment, openIsolating bool) Slice {
+	openStart, openEnd := 0, 0
+	for n := f.FirstChild(); n != nil && !n.IsLeaf() && (openIsolating || !n.Type.Spec.Isolating); n = n.FirstChild() {
+		openStart++
+	}
+
+	for n := f.LastChild(); n != nil && !n.IsLeaf() && (openIsolating || !n.Type.Spec.Isolating); n = n.LastChild() {
+		openEnd++
+	}
+
+	return Slice{Content: f, OpenStart: openStart, OpenEnd: openEnd}
+}
+
+// Size is the size this slice would add when inserted into a document.
+func (s Slice) Size() int {
+	return s.Content.Size - s.OpenStart - s.OpenEnd
+}
+
+// Eq tests whether this slice is equal to another slice.
+func (s Slice) Eq(other Slice) bool {
+	return s.Content.Eq(other.Content) && s.OpenStart == other.OpenStart && s.OpenEnd == other.OpenEnd
+}
+
+// RemoveBetween removes the flat range between the given positions of the slice.
+func (s Slice) RemoveBetween(from, to int) (Slice, error) {
+	content, err := removeRange(s.Content, from+s.OpenStart, to+s.OpenStart)
+	if err != nil {
+		return Slice{}, err
+	}
+
+	return Slice{Content: content, OpenStart: s.OpenStart, OpenEnd: s.OpenEnd}, nil
+}
+
+func removeRange(content Fragment, from, to int) (Fragment, error) {
+	index, offset := content.findIndex(from)
+	child := content.MaybeChild(index)
+	indexTo, offsetTo := content.findIndex(to)
+
+	if offset == from || child.IsText() {
+		if offsetTo != to && !content.Child(indexTo).IsText() {
+			return Fragment{}, fmt.Errorf("removing non-flat range")
+		}
+
+		return content.cut(0, from).Append(content.cut(to, -1)), nil
+	}
+
+	if index != indexTo {
+		return Fragment{}, fmt.Errorf("removing non-flat range")
+	}
+
+	inner, err := removeRange(child.Content, from-offset-1, to-offset-1)
+	if err != nil {
+		return Fragment{}, err
+	}
+
+	return content.ReplaceChild(index, child.Copy(inner)), nil
 }
 
 // insertAt(pos: number, fragment: Fragment) {
@@ -32,22 +97,22 @@ func (s Slice) InsertAt(pos int, frag Fragment) *Slice {
 
 func insertInto(content Fragment, dist int, insert Fragment, parent *Node) *Fragment {
 	index, offset := content.findIndex(dist)
-	child := content.maybeChild(index)
+	child := content.MaybeChild(index)
 	if offset == dist || (child != nil && child.IsText()) {
 		if parent != nil && !parent.canReplace(index, index, insert, -1, -1) {
 			return nil
 		}
 
 		c := content.cut(0, dist)
-		c = c.append(insert)
+		c = c.Append(insert)
 		subc := content.cut(dist, -1)
-		c = c.append(subc)
+		c = c.Append(subc)
 		return &c
 	}
 
 	inner := insertInto(child.Content, dist-offset-1, insert, nil)
 	if inner != nil {
-		r := content.replaceChild(index, child.copy(*inner))
+		r := content.ReplaceChild(index, child.Copy(*inner))
 		return &r
 	}
 
@@ -0,0 +1,309 @@
+package prosemirror
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// MarshalJSON writes the serializable properties of a schema spec in the shape
+// used by prosemirror-model, with the node and mark types in schema order.
+func (s SchemaSpec) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := jsontext.NewEncoder(&buf)
+	if err := enc.WriteToken(jsontext.ObjectStart); err != nil {
+		return nil, err
+	}
+
+	if s.TopNode != "" {
+		if err := writeMember(enc, "topNode", s.TopNode); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := enc.WriteToken(jsontext.String("nodes")); err != nil {
+		return nil, err
+	}
+	if err := marshalOrdered(enc, s.Nodes, s.NodeOrder); err != nil {
+		return nil, fmt.Errorf("error writing nodes: %w", err)
+	}
+
+	if err := enc.WriteToken(jsontext.String("marks")); err != nil {
+		return nil, err
+	}
+	if err := marshalOrdered(enc, s.Marks, s.MarkOrder); err != nil {
+		return nil, fmt.Errorf("error writing marks: %w", err)
+	}
+
+	if err := enc.WriteToken(jsontext.ObjectEnd); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
+
+// UnmarshalJSON reads a schema spec in the shape used by prosemirror-model,
+// `{"topNode": ..., "nodes": {...}, "marks": {...}}`. The order of the node
+// and mark types is the order of their keys. The spec is validated, see Validate.
+func (s *SchemaSpec) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		TopNode NodeTypeName   `json:"topNode"`
+		Nodes   jsontext.Value `json:"nodes"`
+		Marks   jsontext.Value `json:"marks"`
+	}
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	nodes, nodeOrder, err := unmarshalOrdered[NodeTypeName, NodeSpec](raw.Nodes, "nodes")
+	if err != nil {
+		return err
+	}
+
+	marks, markOrder, err := unmarshalOrdered[MarkTypeName, MarkSpec](raw.Marks, "marks")
+	if err != nil {
+		return err
+	}
+
+	spec := SchemaSpec{
+		Nodes:     nodes,
+		Marks:     marks,
+		TopNode:   raw.TopNode,
+		NodeOrder: nodeOrder,
+		MarkOrder: markOrder,
+	}
+
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+
+	*s = spec
+	return nil
+}
+
+// MarshalJSON writes the serializable properties of a node spec, leaving out
+// the unset ones and the functions. Extra properties are written along them.
+func (n NodeSpec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Content            string               `json:"content,omitempty"`
+		Marks              *string              `json:"marks,omitzero"`
+		Group              string               `json:"group,omitempty"`
+		Inline             bool                 `json:"inline,omitzero"`
+		Atom               bool                 `json:"atom,omitzero"`
+		Attrs              map[string]Attribute `json:"attrs,omitempty"`
+		Selectable         *bool                `json:"selectable,omitzero"`
+		Code               bool                 `json:"code,omitzero"`
+		Whitespace         string               `json:"whitespace,omitempty"`
+		DefiningAsContext  bool                 `json:"definingAsContext,omitzero"`
+		DefiningForContent bool                 `json:"definingForContent,omitzero"`
+		Defining           bool                 `json:"defining,omitzero"`
+		Isolating          bool                 `json:"isolating,omitzero"`
+		Extra              map[string]any       `json:",unknown"`
+	}{
+		Content:            n.Content,
+		Marks:              n.Marks,
+		Group:              n.Group,
+		Inline:             n.Inline,
+		Atom:               n.Atom,
+		Attrs:              n.Attrs,
+		Selectable:         n.Selectable,
+		Code:               n.Code,
+		Whitespace:         n.Whitespace,
+		DefiningAsContext:  n.DefiningAsContext,
+		DefiningForContent: n.DefiningForContent,
+		Defining:           n.Defining,
+		Isolating:          n.Isolating,
+		Extra:              n.Extra,
+	}, json.Deterministic(true))
+}
+
+// UnmarshalJSON reads the serializable properties of a node spec.
+// Unknown properties are kept in Extra.
+func (n *NodeSpec) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Content            string               `json:"content"`
+		Marks              *string              `json:"marks"`
+		Group              string               `json:"group"`
+		Inline             bool                 `json:"inline"`
+		Atom               bool                 `json:"atom"`
+		Attrs              map[string]Attribute `json:"attrs"`
+		Selectable         *bool                `json:"selectable"`
+		Code               bool                 `json:"code"`
+		Whitespace         string               `json:"whitespace"`
+		DefiningAsContext  bool                 `json:"definingAsContext"`
+		DefiningForContent bool                 `json:"definingForContent"`
+		Defining           bool                 `json:"defining"`
+		Isolating          bool                 `json:"isolating"`
+		Extra              map[string]any       `json:",unknown"`
+	}
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	*n = NodeSpec{
+		Content:            raw.Content,
+		Marks:              raw.Marks,
+		Group:              raw.Group,
+		Inline:             raw.Inline,
+		Atom:               raw.Atom,
+		Attrs:              raw.Attrs,
+		Selectable:         raw.Selectable,
+		Code:               raw.Code,
+		Whitespace:         raw.Whitespace,
+		DefiningAsContext:  raw.DefiningAsContext,
+		DefiningForContent: raw.DefiningForContent,
+		Defining:           raw.Defining,
+		Isolating:          raw.Isolating,
+		Extra:              raw.Extra,
+	}
+
+	return nil
+}
+
+// MarshalJSON writes the serializable properties of a mark spec, leaving out
+// the unset ones and the functions. Extra properties are written along them.
+func (m MarkSpec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Attrs     map[string]Attribute `json:"attrs,omitempty"`
+		Inclusive *bool                `json:"inclusive,omitzero"`
+		Excludes  *string              `json:"excludes,omitzero"`
+		Group     string               `json:"group,omitempty"`
+		Spanning  *bool                `json:"spanning,omitzero"`
+		Extra     map[string]any       `json:",unknown"`
+	}{
+		Attrs:     m.Attrs,
+		Inclusive: m.Inclusive,
+		Excludes:  m.Excludes,
+		Group:     m.Group,
+		Spanning:  m.Spanning,
+		Extra:     m.Extra,
+	}, json.Deterministic(true))
+}
+
+// UnmarshalJSON reads the serializable properties of a mark spec.
+// Unknown properties are kept in Extra.
+func (m *MarkSpec) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Attrs     map[string]Attribute `json:"attrs"`
+		Inclusive *bool                `json:"inclusive"`
+		Excludes  *string              `json:"excludes"`
+		Group     string               `json:"group"`
+		Spanning  *bool                `json:"spanning"`
+		Extra     map[string]any       `json:",unknown"`
+	}
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	*m = MarkSpec{
+		Attrs:     raw.Attrs,
+		Inclusive: raw.Inclusive,
+		Excludes:  raw.Excludes,
+		Group:     raw.Group,
+		Spanning:  raw.Spanning,
+		Extra:     raw.Extra,
+	}
+
+	return nil
+}
+
+// MarshalJSON writes an attribute spec, `{"default": ...}`, without a default
+// for required attributes.
+func (a Attribute) MarshalJSON() ([]byte, error) {
+	if a.isRequired() {
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(map[string]any{"default": a.Default})
+}
+
+// UnmarshalJSON reads an attribute spec, `{"default": ...}`. Attributes without
+// a default are required, and a null default makes them optional.
+// Whole numbers are read as ints, like the defaults of specs written in Go.
+func (a *Attribute) UnmarshalJSON(b []byte) error {
+	var raw map[string]any
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	def, ok := raw["default"]
+	if f, isFloat := def.(float64); isFloat && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		def = int(f)
+	}
+
+	*a = Attribute{Default: def, Optional: ok && def == nil}
+	return nil
+}
+
+// unmarshalOrdered reads a JSON object into a map, along with the order of its keys.
+// Errors are reported as a *SpecError, with the path of the object's members.
+func unmarshalOrdered[K ~string, V any](b jsontext.Value, path string) (map[K]V, []K, error) {
+	if len(b) == 0 || b.Kind() == 'n' {
+		return nil, nil, nil
+	}
+
+	dec := jsontext.NewDecoder(bytes.NewReader(b))
+	if tok, err := dec.ReadToken(); err != nil {
+		return nil, nil, &SpecError{Path: path, Err: err}
+	} else if tok.Kind() != '{' {
+		return nil, nil, &SpecError{Path: path, Err: fmt.Errorf("expected an object, got %s", tok.Kind())}
+	}
+
+	values, order := map[K]V{}, []K{}
+	for dec.PeekKind() != '}' {
+		tok, err := dec.ReadToken()
+		if err != nil {
+			return nil, nil, &SpecError{Path: path, Err: err}
+		}
+
+		name := K(tok.String())
+		value, err := dec.ReadValue()
+		if err != nil {
+			return nil, nil, &SpecError{Path: joinPath(path, string(name)), Err: err}
+		}
+
+		var v V
+		if err := json.Unmarshal(value, &v); err != nil {
+			return nil, nil, &SpecError{Path: joinPath(path, string(name)), Err: err}
+		}
+
+		values[name] = v
+		order = append(order, name)
+	}
+
+	return values, order, nil
+}
+
+// marshalOrdered writes a map as a JSON object, with its keys in schema order.
+func marshalOrdered[K ~string, V any](enc *jsontext.Encoder, values map[K]V, order []K) error {
+	if err := enc.WriteToken(jsontext.ObjectStart); err != nil {
+		return err
+	}
+
+	for _, name := range specOrder(values, order) {
+		if err := writeMember(enc, name, values[name]); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return enc.WriteToken(jsontext.ObjectEnd)
+}
+
+func writeMember[K ~string](enc *jsontext.Encoder, name K, value any) error {
+	b, err := json.Marshal(value, json.Deterministic(true))
+	if err != nil {
+		return err
+	}
+
+	if err := enc.WriteToken(jsontext.String(string(name))); err != nil {
+		return err
+	}
+
+	return enc.WriteValue(b)
+}
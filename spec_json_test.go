@@ -0,0 +1,178 @@
+package prosemirror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-json-experiment/json"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSchemaSpecUnmarshalJSON(t *testing.T) {
+	const spec = `{
+		"topNode": "doc",
+		"nodes": {
+			"doc": {"content": "block+"},
+			"paragraph": {"content": "inline*", "group": "block", "tableRole": "cell"},
+			"heading": {"content": "text*", "group": "block", "marks": "", "defining": true, "attrs": {"level": {"default": 1}}},
+			"text": {"group": "inline"},
+			"image": {"inline": true, "group": "inline", "atom": true, "attrs": {"src": {}, "alt": {"default": null}, "scale": {"default": 0.5}}}
+		},
+		"marks": {
+			"strong": {},
+			"em": {"inclusive": false, "excludes": "strong"}
+		}
+	}`
+
+	var got SchemaSpec
+	if !assert.NoError(t, json.Unmarshal([]byte(spec), &got)) {
+		return
+	}
+
+	assert.Equal(t, NodeTypeName("doc"), got.TopNode)
+	assert.Equal(t, []NodeTypeName{"doc", "paragraph", "heading", "text", "image"}, got.NodeOrder)
+	assert.Equal(t, []MarkTypeName{"strong", "em"}, got.MarkOrder)
+
+	assert.Equal(t, map[string]any{"tableRole": "cell"}, got.Nodes["paragraph"].Extra)
+	assert.Equal(t, "", *got.Nodes["heading"].Marks)
+	assert.Nil(t, got.Nodes["paragraph"].Marks)
+	assert.True(t, got.Nodes["heading"].Defining)
+	assert.Equal(t, map[string]Attribute{"level": {Default: 1}}, got.Nodes["heading"].Attrs)
+	assert.Equal(t, map[string]Attribute{
+		"src":   {},
+		"alt":   {Optional: true},
+		"scale": {Default: 0.5},
+	}, got.Nodes["image"].Attrs)
+	assert.False(t, *got.Marks["em"].Inclusive)
+	assert.Equal(t, "strong", *got.Marks["em"].Excludes)
+
+	got.DontRegister = true
+	s, err := NewSchema(got)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "paragraph", string(s.NodeTypes()[1].Name))
+		assert.Equal(t, "em", string(s.MarkTypes()[1].Name))
+	}
+
+	assert.Error(t, json.Unmarshal([]byte(`{"nodes": []}`), &got))
+	assert.Error(t, json.Unmarshal([]byte(`{"nodes": {"doc": {"content": 1}}}`), &got))
+}
+
+func TestSchemaSpecYAML(t *testing.T) {
+	const spec = `
+topNode: doc
+nodes:
+  doc:
+    content: block+
+  paragraph:
+    content: inline*
+    group: block
+  heading:
+    content: text*
+    group: block
+    attrs:
+      level: {default: 1}
+  text:
+    group: inline
+marks:
+  strong: {}
+  link:
+    attrs:
+      href: {}
+      title: {default: null}
+`
+
+	var got SchemaSpec
+	if !assert.NoError(t, yaml.Unmarshal([]byte(spec), &got)) {
+		return
+	}
+
+	assert.Equal(t, []NodeTypeName{"doc", "paragraph", "heading", "text"}, got.NodeOrder)
+	assert.Equal(t, []MarkTypeName{"strong", "link"}, got.MarkOrder)
+	assert.Equal(t, map[string]Attribute{"level": {Default: 1}}, got.Nodes["heading"].Attrs)
+	assert.Equal(t, map[string]Attribute{"href": {}, "title": {Optional: true}}, got.Marks["link"].Attrs)
+
+	b, err := yaml.Marshal(got)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var back SchemaSpec
+	if assert.NoError(t, yaml.Unmarshal(b, &back), string(b)) {
+		assert.Equal(t, got, back)
+	}
+}
+
+func TestSpecError(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		path string
+		expr string
+		line int
+	}{
+		{
+			name: "content expression",
+			yaml: "nodes:\n  doc:\n    content: paragraph+\n  paragraph:\n    content: inline* (\n  text: {}\n",
+			path: "nodes.paragraph.content",
+			expr: "inline* (",
+			line: 5,
+		},
+		{
+			name: "unknown type in content",
+			yaml: "nodes:\n  doc:\n    content: block+\n  text: {}\n",
+			path: "nodes.doc.content",
+			expr: "block+",
+			line: 3,
+		},
+		{
+			name: "marks",
+			yaml: "nodes:\n  doc:\n    content: text*\n    marks: bold\n  text: {}\n",
+			path: "nodes.doc.marks",
+			expr: "bold",
+			line: 4,
+		},
+		{
+			name: "excludes",
+			yaml: "nodes:\n  doc: {content: text*}\n  text: {}\nmarks:\n  em:\n    excludes: strong\n",
+			path: "marks.em.excludes",
+			expr: "strong",
+			line: 6,
+		},
+		{
+			name: "whitespace",
+			yaml: "nodes:\n  doc:\n    content: text*\n    whitespace: keep\n  text: {}\n",
+			path: "nodes.doc.whitespace",
+			line: 4,
+		},
+		{
+			name: "top node",
+			yaml: "topNode: page\nnodes:\n  doc: {}\n  text: {}\n",
+			path: "topNode",
+			line: 1,
+		},
+		{
+			name: "malformed node spec",
+			yaml: "nodes:\n  doc:\n    content: [block]\n  text: {}\n",
+			path: "nodes.doc",
+			line: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var spec SchemaSpec
+			err := yaml.Unmarshal([]byte(tc.yaml), &spec)
+
+			var specErr *SpecError
+			if !assert.True(t, errors.As(err, &specErr), "got %v", err) {
+				return
+			}
+
+			assert.Equal(t, tc.path, specErr.Path)
+			assert.Equal(t, tc.expr, specErr.Expr)
+			assert.Contains(t, err.Error(), fmt.Sprintf("line %d: %s", tc.line, tc.path))
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package prosemirror
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+	"gopkg.in/yaml.v3"
+)
+
+// Schema specs are read from YAML in the same shape as their JSON, by
+// converting the YAML to JSON first. Errors in a spec read from YAML are
+// reported with the line of the offending property.
+
+// UnmarshalYAML reads a schema spec in the shape of its JSON.
+func (s *SchemaSpec) UnmarshalYAML(value *yaml.Node) error {
+	return unmarshalYAML(value, s)
+}
+
+// MarshalYAML writes a schema spec in the shape of its JSON.
+func (s SchemaSpec) MarshalYAML() (any, error) {
+	return marshalYAML(s)
+}
+
+// UnmarshalYAML reads a node spec in the shape of its JSON.
+func (n *NodeSpec) UnmarshalYAML(value *yaml.Node) error {
+	return unmarshalYAML(value, n)
+}
+
+// MarshalYAML writes a node spec in the shape of its JSON.
+func (n NodeSpec) MarshalYAML() (any, error) {
+	return marshalYAML(n)
+}
+
+// UnmarshalYAML reads a mark spec in the shape of its JSON.
+func (m *MarkSpec) UnmarshalYAML(value *yaml.Node) error {
+	return unmarshalYAML(value, m)
+}
+
+// MarshalYAML writes a mark spec in the shape of its JSON.
+func (m MarkSpec) MarshalYAML() (any, error) {
+	return marshalYAML(m)
+}
+
+func unmarshalYAML(value *yaml.Node, v any) error {
+	var buf bytes.Buffer
+	if err := yamlToJSON(jsontext.NewEncoder(&buf), value); err != nil {
+		return err
+	}
+
+	err := json.Unmarshal(buf.Bytes(), v)
+	if specErr := (*SpecError)(nil); errors.As(err, &specErr) {
+		if line := yamlLine(value, specErr.Path); line > 0 {
+			return fmt.Errorf("line %d: %w", line, specErr)
+		}
+
+		return specErr
+	}
+
+	return err
+}
+
+func marshalYAML(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is YAML, which only needs its flow style and quotes removed
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	var clear func(n *yaml.Node)
+	clear = func(n *yaml.Node) {
+		n.Style = 0
+		for _, c := range n.Content {
+			clear(c)
+		}
+	}
+	clear(&doc)
+
+	return doc.Content[0], nil
+}
+
+// yamlToJSON writes a YAML node as JSON, keeping the order of mappings.
+func yamlToJSON(enc *jsontext.Encoder, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return enc.WriteToken(jsontext.Null)
+		}
+
+		return yamlToJSON(enc, n.Content[0])
+	case yaml.AliasNode:
+		return yamlToJSON(enc, n.Alias)
+	case yaml.MappingNode:
+		if err := enc.WriteToken(jsontext.ObjectStart); err != nil {
+			return err
+		}
+
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: mapping keys must be scalars", key.Line)
+			}
+
+			if err := enc.WriteToken(jsontext.String(key.Value)); err != nil {
+				return fmt.Errorf("line %d: %w", key.Line, err)
+			}
+
+			if err := yamlToJSON(enc, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+
+		return enc.WriteToken(jsontext.ObjectEnd)
+	case yaml.SequenceNode:
+		if err := enc.WriteToken(jsontext.ArrayStart); err != nil {
+			return err
+		}
+
+		for _, c := range n.Content {
+			if err := yamlToJSON(enc, c); err != nil {
+				return err
+			}
+		}
+
+		return enc.WriteToken(jsontext.ArrayEnd)
+	default:
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return err
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+
+		return enc.WriteValue(b)
+	}
+}
+
+// yamlLine returns the line of the property at the given dotted path of a spec,
+// or of its closest parent found.
+func yamlLine(n *yaml.Node, path string) int {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+
+	line := n.Line
+	for _, part := range strings.Split(path, ".") {
+		if n.Kind == yaml.AliasNode {
+			n = n.Alias
+		}
+
+		if n.Kind != yaml.MappingNode {
+			return line
+		}
+
+		found := false
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == part {
+				line, n, found = n.Content[i].Line, n.Content[i+1], true
+				break
+			}
+		}
+
+		if !found {
+			return line
+		}
+	}
+
+	return line
+}
@@ -0,0 +1,366 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/transform"
+)
+
+// Selection is the interface implemented by the selection types.
+// A selection has an anchor, which stays in place, and a head, which moves.
+type Selection interface {
+	// Anchor is the resolved anchor of the selection (the side that stays in
+	// place when the selection is modified).
+	Anchor() prosemirror.ResolvedPos
+
+	// Head is the resolved head of the selection (the side that moves when
+	// the selection is modified).
+	Head() prosemirror.ResolvedPos
+
+	// From is the lower bound of the selection.
+	From() prosemirror.ResolvedPos
+
+	// To is the upper bound of the selection.
+	To() prosemirror.ResolvedPos
+
+	// Empty indicates whether the selection contains any content.
+	Empty() bool
+
+	// Eq tests whether the selection is the same as another selection.
+	Eq(other Selection) bool
+
+	// Map this selection through a mappable thing. `doc` should be the new
+	// document to which we are mapping.
+	Map(doc prosemirror.Node, mapping transform.Mappable) Selection
+}
+
+// Content gets the content of this selection as a slice.
+func Content(sel Selection) (prosemirror.Slice, error) {
+	return sel.From().Doc().Slice(sel.From().Pos, sel.To().Pos, true)
+}
+
+// resolve resolves a position that is known to be valid, such as one
+// produced by mapping through the steps that produced the document.
+func resolve(doc prosemirror.Node, pos int) prosemirror.ResolvedPos {
+	rp, err := doc.Resolve(pos)
+	if err != nil {
+		panic(fmt.Sprintf("failed to resolve position %d: %v", pos, err))
+	}
+
+	return rp
+}
+
+// TextSelection represents a text cursor or a range of text.
+// Its endpoints always point into textblocks.
+type TextSelection struct {
+	anchor, head prosemirror.ResolvedPos
+}
+
+// NewTextSelection constructs a text selection between the given points.
+func NewTextSelection(anchor, head prosemirror.ResolvedPos) *TextSelection {
+	return &TextSelection{anchor: anchor, head: head}
+}
+
+// NewCursor constructs an empty text selection at the given point.
+func NewCursor(pos prosemirror.ResolvedPos) *TextSelection {
+	return NewTextSelection(pos, pos)
+}
+
+// CreateTextSelection creates a text selection from non-resolved positions.
+func CreateTextSelection(doc prosemirror.Node, anchor, head int) (*TextSelection, error) {
+	rAnchor, err := doc.Resolve(anchor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve anchor: %w", err)
+	}
+
+	rHead, err := doc.Resolve(head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve head: %w", err)
+	}
+
+	return NewTextSelection(rAnchor, rHead), nil
+}
+
+// TextSelectionBetween returns a text selection that spans the given
+// positions or, if they aren't text positions, find a text selection near them.
+// `bias` determines whether the method searches forward (default) or backwards
+// (negative number) first. Will fall back to calling SelectionNear when the
+// document doesn't contain a valid text position.
+func TextSelectionBetween(anchor, head prosemirror.ResolvedPos, bias int) Selection {
+	dPos := anchor.Pos - head.Pos
+	if bias == 0 || dPos != 0 {
+		bias = 1
+		if dPos < 0 {
+			bias = -1
+		}
+	}
+
+	if !head.Parent().InlineContent() {
+		found := FindSelectionFrom(head, bias, true)
+		if found == nil {
+			found = FindSelectionFrom(head, -bias, true)
+		}
+
+		if found == nil {
+			return SelectionNear(head, bias)
+		}
+
+		head = found.Head()
+	}
+
+	if !anchor.Parent().InlineContent() {
+		if dPos == 0 {
+			anchor = head
+		} else {
+			found := FindSelectionFrom(anchor, -bias, true)
+			if found == nil {
+				found = FindSelectionFrom(anchor, bias, true)
+			}
+
+			anchor = found.Anchor()
+			if (anchor.Pos < head.Pos) != (dPos < 0) {
+				anchor = head
+			}
+		}
+	}
+
+	return NewTextSelection(anchor, head)
+}
+
+func (s *TextSelection) Anchor() prosemirror.ResolvedPos { return s.anchor }
+func (s *TextSelection) Head() prosemirror.ResolvedPos   { return s.head }
+func (s *TextSelection) From() prosemirror.ResolvedPos   { return s.anchor.Min(s.head) }
+func (s *TextSelection) To() prosemirror.ResolvedPos     { return s.anchor.Max(s.head) }
+func (s *TextSelection) Empty() bool                     { return s.anchor.Pos == s.head.Pos }
+
+// Cursor returns a resolved position if this is a cursor selection (an empty
+// text selection), and nil otherwise.
+func (s *TextSelection) Cursor() *prosemirror.ResolvedPos {
+	if !s.Empty() {
+		return nil
+	}
+
+	return &s.head
+}
+
+func (s *TextSelection) Eq(other Selection) bool {
+	o, ok := other.(*TextSelection)
+	return ok && o.anchor.Pos == s.anchor.Pos && o.head.Pos == s.head.Pos
+}
+
+func (s *TextSelection) Map(doc prosemirror.Node, mapping transform.Mappable) Selection {
+	head := resolve(doc, mapping.Map(s.head.Pos, 1))
+	if !head.Parent().InlineContent() {
+		return SelectionNear(head, 1)
+	}
+
+	anchor := resolve(doc, mapping.Map(s.anchor.Pos, 1))
+	if !anchor.Parent().InlineContent() {
+		anchor = head
+	}
+
+	return NewTextSelection(anchor, head)
+}
+
+func (s *TextSelection) String() string {
+	return fmt.Sprintf("TextSelection{Anchor: %d, Head: %d}", s.anchor.Pos, s.head.Pos)
+}
+
+// NodeSelection is a selection that points at a single node. All nodes marked
+// selectable can be the target of a node selection. In such a selection, `from`
+// and `to` point directly before and after the selected node, `anchor` equals
+// `from`, and `head` equals `to`.
+type NodeSelection struct {
+	from, to prosemirror.ResolvedPos
+
+	// The selected node.
+	Node prosemirror.Node
+}
+
+// NewNodeSelection creates a node selection. Does not verify the validity of its argument.
+func NewNodeSelection(pos prosemirror.ResolvedPos) *NodeSelection {
+	node := pos.NodeAfter()
+	return &NodeSelection{
+		from: pos,
+		to:   resolve(pos.Doc(), pos.Pos+node.NodeSize()),
+		Node: *node,
+	}
+}
+
+// CreateNodeSelection creates a node selection from a non-resolved position.
+func CreateNodeSelection(doc prosemirror.Node, from int) (*NodeSelection, error) {
+	rp, err := doc.Resolve(from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve position: %w", err)
+	}
+
+	if rp.NodeAfter() == nil {
+		return nil, fmt.Errorf("no node after position %d", from)
+	}
+
+	return NewNodeSelection(rp), nil
+}
+
+// IsSelectable determines whether the given node may be selected as a node selection.
+func IsSelectable(node prosemirror.Node) bool {
+	return !node.IsText() && (node.Type.Spec.Selectable == nil || *node.Type.Spec.Selectable)
+}
+
+func (s *NodeSelection) Anchor() prosemirror.ResolvedPos { return s.from }
+func (s *NodeSelection) Head() prosemirror.ResolvedPos   { return s.to }
+func (s *NodeSelection) From() prosemirror.ResolvedPos   { return s.from }
+func (s *NodeSelection) To() prosemirror.ResolvedPos     { return s.to }
+func (s *NodeSelection) Empty() bool                     { return false }
+
+func (s *NodeSelection) Eq(other Selection) bool {
+	o, ok := other.(*NodeSelection)
+	return ok && o.from.Pos == s.from.Pos
+}
+
+func (s *NodeSelection) Map(doc prosemirror.Node, mapping transform.Mappable) Selection {
+	result := mapping.MapResult(s.from.Pos, 1)
+	pos := resolve(doc, result.Pos)
+	if result.Deleted() || pos.NodeAfter() == nil {
+		return SelectionNear(pos, 1)
+	}
+
+	return NewNodeSelection(pos)
+}
+
+func (s *NodeSelection) String() string {
+	return fmt.Sprintf("NodeSelection{From: %d, To: %d}", s.from.Pos, s.to.Pos)
+}
+
+// AllSelection is a selection type that represents selecting the whole document
+// (which can not necessarily be expressed with a text selection, when there are
+// for example leaf block nodes at the start or end of the document).
+type AllSelection struct {
+	from, to prosemirror.ResolvedPos
+}
+
+// NewAllSelection creates an all-selection over the given document.
+func NewAllSelection(doc prosemirror.Node) *AllSelection {
+	return &AllSelection{from: resolve(doc, 0), to: resolve(doc, doc.Content.Size)}
+}
+
+func (s *AllSelection) Anchor() prosemirror.ResolvedPos { return s.from }
+func (s *AllSelection) Head() prosemirror.ResolvedPos   { return s.to }
+func (s *AllSelection) From() prosemirror.ResolvedPos   { return s.from }
+func (s *AllSelection) To() prosemirror.ResolvedPos     { return s.to }
+func (s *AllSelection) Empty() bool                     { return s.from.Pos == s.to.Pos }
+
+func (s *AllSelection) Eq(other Selection) bool {
+	_, ok := other.(*AllSelection)
+	return ok
+}
+
+func (s *AllSelection) Map(doc prosemirror.Node, _ transform.Mappable) Selection {
+	return NewAllSelection(doc)
+}
+
+func (s *AllSelection) String() string {
+	return "AllSelection"
+}
+
+// FindSelectionFrom finds a valid cursor or leaf node selection starting at
+// the given position and searching back if `dir` is negative, and forward if
+// positive. When `textOnly` is true, only consider cursor selections.
+// Will return nil when no valid selection position is found.
+func FindSelectionFrom(pos prosemirror.ResolvedPos, dir int, textOnly bool) Selection {
+	if pos.Parent().InlineContent() {
+		return NewCursor(pos)
+	}
+
+	doc := pos.Doc()
+	if found := findSelectionIn(doc, pos.Parent(), pos.Pos, pos.Index(pos.Depth), dir, textOnly); found != nil {
+		return found
+	}
+
+	for depth := pos.Depth - 1; depth >= 0; depth-- {
+		var found Selection
+		if dir < 0 {
+			found = findSelectionIn(doc, pos.Node(depth), pos.Before(depth+1), pos.Index(depth), dir, textOnly)
+		} else {
+			found = findSelectionIn(doc, pos.Node(depth), pos.After(depth+1), pos.Index(depth)+1, dir, textOnly)
+		}
+
+		if found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// SelectionNear finds a valid cursor or leaf node selection near the given
+// position. Searches forward first by default, but if `bias` is negative,
+// it will search backwards first.
+func SelectionNear(pos prosemirror.ResolvedPos, bias int) Selection {
+	if found := FindSelectionFrom(pos, bias, false); found != nil {
+		return found
+	}
+
+	if found := FindSelectionFrom(pos, -bias, false); found != nil {
+		return found
+	}
+
+	return NewAllSelection(pos.Doc())
+}
+
+// SelectionAtStart finds the cursor or leaf node selection closest to the
+// start of the given document. Will return an AllSelection if no valid
+// position exists.
+func SelectionAtStart(doc prosemirror.Node) Selection {
+	if found := findSelectionIn(doc, doc, 0, 0, 1, false); found != nil {
+		return found
+	}
+
+	return NewAllSelection(doc)
+}
+
+// SelectionAtEnd finds the cursor or leaf node selection closest to the end
+// of the given document.
+func SelectionAtEnd(doc prosemirror.Node) Selection {
+	if found := findSelectionIn(doc, doc, doc.Content.Size, doc.ChildCount(), -1, false); found != nil {
+		return found
+	}
+
+	return NewAllSelection(doc)
+}
+
+func findSelectionIn(doc, node prosemirror.Node, pos, index, dir int, text bool) Selection {
+	if node.InlineContent() {
+		return NewCursor(resolve(doc, pos))
+	}
+
+	i := index
+	if dir < 0 {
+		i--
+	}
+
+	for ; (dir > 0 && i < node.ChildCount()) || (dir < 0 && i >= 0); i += dir {
+		child := node.Child(i)
+		if !child.IsAtom() {
+			childIndex := 0
+			if dir < 0 {
+				childIndex = child.ChildCount()
+			}
+
+			if inner := findSelectionIn(doc, *child, pos+dir, childIndex, dir, text); inner != nil {
+				return inner
+			}
+		} else if !text && IsSelectable(*child) {
+			at := pos
+			if dir < 0 {
+				at -= child.NodeSize()
+			}
+
+			return NewNodeSelection(resolve(doc, at))
+		}
+
+		pos += child.NodeSize() * dir
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package state_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-json-experiment/json"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/karitham/prosemirror"
+	_ "github.com/karitham/prosemirror/schema"
+	"github.com/karitham/prosemirror/state"
+	"github.com/karitham/prosemirror/transform"
+)
+
+// testDoc is hr, p("one"), p("two"), hr: the paragraphs hold the text
+// positions 2 to 5 and 7 to 10.
+const testDoc = `{"type":"doc","content":[
+	{"type":"horizontal_rule"},
+	{"type":"paragraph","content":[{"type":"text","text":"one"}]},
+	{"type":"paragraph","content":[{"type":"text","text":"two"}]},
+	{"type":"horizontal_rule"}
+]}`
+
+func fromJSON(t *testing.T, s string) prosemirror.Node {
+	t.Helper()
+
+	var n prosemirror.Node
+	if err := json.Unmarshal([]byte(s), &n); err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func resolve(t *testing.T, doc prosemirror.Node, pos int) prosemirror.ResolvedPos {
+	t.Helper()
+
+	rp, err := doc.Resolve(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rp
+}
+
+func TestFindSelection(t *testing.T) {
+	doc := fromJSON(t, testDoc)
+
+	tests := []struct {
+		name string
+		sel  state.Selection
+		want string
+	}{
+		{name: "at start", sel: state.SelectionAtStart(doc), want: "NodeSelection{From: 0, To: 1}"},
+		{name: "at end", sel: state.SelectionAtEnd(doc), want: "NodeSelection{From: 11, To: 12}"},
+		{name: "text forward", sel: state.FindSelectionFrom(resolve(t, doc, 1), 1, true), want: "TextSelection{Anchor: 2, Head: 2}"},
+		{name: "text backward", sel: state.FindSelectionFrom(resolve(t, doc, 6), -1, true), want: "TextSelection{Anchor: 5, Head: 5}"},
+		{name: "near leaf", sel: state.SelectionNear(resolve(t, doc, 11), 1), want: "NodeSelection{From: 11, To: 12}"},
+		{name: "between blocks", sel: state.TextSelectionBetween(resolve(t, doc, 1), resolve(t, doc, 11), 0), want: "TextSelection{Anchor: 2, Head: 10}"},
+		{name: "all", sel: state.NewAllSelection(doc), want: "AllSelection"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, fmt.Sprint(tc.sel))
+		})
+	}
+}
+
+func TestSelectionMap(t *testing.T) {
+	doc := fromJSON(t, testDoc)
+
+	text, err := state.CreateTextSelection(doc, 3, 8)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	node, err := state.CreateNodeSelection(doc, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tests := []struct {
+		name string
+		sel  state.Selection
+		edit func(tr *transform.Transform) error
+		want string
+	}{
+		{
+			name: "text through insertion",
+			sel:  text,
+			edit: func(tr *transform.Transform) error { return tr.Insert(2, tr.Doc.Type.Schema.Text("ab")) },
+			want: "TextSelection{Anchor: 5, Head: 10}",
+		},
+		{
+			name: "text through deletion",
+			sel:  text,
+			edit: func(tr *transform.Transform) error { return tr.Delete(4, 9) },
+			want: "TextSelection{Anchor: 3, Head: 4}",
+		},
+		{
+			name: "deleted node",
+			sel:  node,
+			edit: func(tr *transform.Transform) error { return tr.Delete(0, 1) },
+			want: "TextSelection{Anchor: 1, Head: 1}",
+		},
+		{
+			name: "moved node",
+			sel:  node,
+			edit: func(tr *transform.Transform) error {
+				return tr.Insert(0, tr.Doc.Type.Schema.Node("horizontal_rule", nil, prosemirror.Fragment{}))
+			},
+			want: "NodeSelection{From: 1, To: 2}",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := transform.New(doc)
+			if !assert.NoError(t, tc.edit(tr)) {
+				return
+			}
+
+			assert.Equal(t, tc.want, fmt.Sprint(tc.sel.Map(tr.Doc, tr.Mapping)))
+		})
+	}
+}
+
+func TestSelectionContent(t *testing.T) {
+	doc := fromJSON(t, testDoc)
+
+	sel, err := state.CreateTextSelection(doc, 3, 8)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	content, err := state.Content(sel)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, content.OpenStart)
+		assert.Equal(t, 1, content.OpenEnd)
+		assert.Equal(t, 5, content.Size())
+	}
+}
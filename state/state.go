@@ -0,0 +1,66 @@
+// Package state implements the editor state: a document, a selection inside it,
+// and transactions that update both.
+package state
+
+import (
+	"github.com/karitham/prosemirror"
+)
+
+// EditorState is the state of a ProseMirror editor. It is immutable:
+// updating it is done by applying a Transaction, which creates a new state.
+type EditorState struct {
+	// The current document.
+	Doc prosemirror.Node
+
+	// The selection.
+	Selection Selection
+
+	// A set of marks to apply to the next input. Will be nil when
+	// no explicit marks have been set.
+	StoredMarks []prosemirror.Mark
+}
+
+// Config holds the options used to create an editor state.
+type Config struct {
+	// The starting document.
+	Doc prosemirror.Node
+
+	// A valid selection in the document. Defaults to the start of the document.
+	Selection Selection
+
+	// The initial set of stored marks.
+	StoredMarks []prosemirror.Mark
+}
+
+// Create a new state.
+func Create(config Config) *EditorState {
+	sel := config.Selection
+	if sel == nil {
+		sel = SelectionAtStart(config.Doc)
+	}
+
+	return &EditorState{
+		Doc:         config.Doc,
+		Selection:   sel,
+		StoredMarks: config.StoredMarks,
+	}
+}
+
+// Schema returns the schema of the state's document.
+func (s *EditorState) Schema() prosemirror.Schema {
+	return s.Doc.Type.Schema
+}
+
+// Tr starts a transaction from this state.
+func (s *EditorState) Tr() *Transaction {
+	return newTransaction(s)
+}
+
+// Apply the given transaction to produce a new state.
+func (s *EditorState) Apply(tr *Transaction) *EditorState {
+	return &EditorState{
+		Doc:         tr.Doc,
+		Selection:   tr.Selection(),
+		StoredMarks: tr.StoredMarks(),
+	}
+}
@@ -0,0 +1,290 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/transform"
+)
+
+// Transaction is a transform that also tracks the selection and stored marks.
+// The state is updated by applying a transaction to it, which produces a new state.
+type Transaction struct {
+	*transform.Transform
+
+	curSelection    Selection
+	curSelectionFor int
+	selectionSet    bool
+
+	storedMarks    []prosemirror.Mark
+	storedMarksFor int
+	storedMarksSet bool
+
+	meta map[string]any
+}
+
+func newTransaction(state *EditorState) *Transaction {
+	return &Transaction{
+		Transform:    transform.New(state.Doc),
+		curSelection: state.Selection,
+		storedMarks:  state.StoredMarks,
+	}
+}
+
+// Selection returns the transaction's current selection. This defaults to the
+// editor selection mapped through the steps in the transaction, but can be
+// overwritten with SetSelection.
+func (tr *Transaction) Selection() Selection {
+	if tr.curSelectionFor < len(tr.Steps) {
+		tr.curSelection = tr.curSelection.Map(tr.Doc, tr.Mapping.Slice(tr.curSelectionFor, -1))
+		tr.curSelectionFor = len(tr.Steps)
+	}
+
+	return tr.curSelection
+}
+
+// SetSelection updates the transaction's current selection. Will determine the
+// selection that the editor gets when the transaction is applied.
+func (tr *Transaction) SetSelection(sel Selection) *Transaction {
+	tr.curSelection = sel
+	tr.curSelectionFor = len(tr.Steps)
+	tr.selectionSet = true
+	tr.storedMarks = nil
+	tr.storedMarksSet = false
+	return tr
+}
+
+// SelectionSet reports whether the selection was explicitly updated by this transaction.
+func (tr *Transaction) SelectionSet() bool {
+	return tr.selectionSet
+}
+
+// StoredMarks returns the stored marks set by this transaction, if any.
+// Stored marks are cleared by any step that changes the document after they were set.
+func (tr *Transaction) StoredMarks() []prosemirror.Mark {
+	if tr.storedMarksFor < len(tr.Steps) {
+		return nil
+	}
+
+	return tr.storedMarks
+}
+
+// StoredMarksSet reports whether the stored marks were explicitly set for this transaction.
+func (tr *Transaction) StoredMarksSet() bool {
+	return tr.storedMarksSet && tr.storedMarksFor == len(tr.Steps)
+}
+
+// SetStoredMarks sets the current stored marks.
+func (tr *Transaction) SetStoredMarks(marks []prosemirror.Mark) *Transaction {
+	tr.storedMarks = marks
+	tr.storedMarksFor = len(tr.Steps)
+	tr.storedMarksSet = true
+	return tr
+}
+
+// EnsureMarks makes sure the current stored marks or, if that is nil, the marks
+// at the selection, match the given set of marks. Does nothing if this is already the case.
+func (tr *Transaction) EnsureMarks(marks []prosemirror.Mark) *Transaction {
+	current := tr.StoredMarks()
+	if current == nil {
+		current = tr.Selection().From().Marks()
+	}
+
+	if !prosemirror.SameMarkSet(current, marks) {
+		tr.SetStoredMarks(marks)
+	}
+
+	return tr
+}
+
+// AddStoredMark adds a mark to the set of stored marks.
+func (tr *Transaction) AddStoredMark(mark prosemirror.Mark) *Transaction {
+	return tr.EnsureMarks(mark.AddToSet(tr.marksAtHead()))
+}
+
+// RemoveStoredMark removes a mark from the set of stored marks.
+func (tr *Transaction) RemoveStoredMark(mark prosemirror.Mark) *Transaction {
+	return tr.EnsureMarks(mark.RemoveFromSet(tr.marksAtHead()))
+}
+
+// RemoveStoredMarkType removes all marks of the given type from the set of stored marks.
+func (tr *Transaction) RemoveStoredMarkType(typ prosemirror.MarkType) *Transaction {
+	return tr.EnsureMarks(typ.RemoveFromSet(tr.marksAtHead()))
+}
+
+func (tr *Transaction) marksAtHead() []prosemirror.Mark {
+	if marks := tr.StoredMarks(); marks != nil {
+		return marks
+	}
+
+	return tr.Selection().Head().Marks()
+}
+
+// ReplaceSelection replaces the current selection with the given slice.
+func (tr *Transaction) ReplaceSelection(slice prosemirror.Slice) error {
+	sel := tr.Selection()
+
+	if _, ok := sel.(*AllSelection); ok && slice.Content.Size == 0 {
+		if err := tr.Delete(0, tr.Doc.Content.Size); err != nil {
+			return err
+		}
+
+		if start := SelectionAtStart(tr.Doc); !start.Eq(tr.Selection()) {
+			tr.SetSelection(start)
+		}
+
+		return nil
+	}
+
+	lastNode := slice.Content.LastChild()
+	var lastParent *prosemirror.Node
+	for i := 0; i < slice.OpenEnd && lastNode != nil; i++ {
+		lastParent = lastNode
+		lastNode = lastNode.LastChild()
+	}
+
+	mapFrom := len(tr.Steps)
+	if err := tr.ReplaceRange(sel.From().Pos, sel.To().Pos, slice); err != nil {
+		return err
+	}
+
+	bias := 1
+	if (lastNode != nil && lastNode.IsInline()) || (lastNode == nil && lastParent != nil && lastParent.IsTextblock()) {
+		bias = -1
+	}
+
+	tr.selectionToInsertionEnd(mapFrom, bias)
+
+	if ts, ok := sel.(*TextSelection); ok && slice.Content.Size == 0 {
+		if marks := ts.From().MarksAcross(ts.To()); marks != nil {
+			tr.EnsureMarks(marks)
+		}
+	}
+
+	return nil
+}
+
+// ReplaceSelectionWith replaces the selection with the given node. When
+// `inheritMarks` is true and the content is inline, it inherits the marks
+// from the place where it is inserted.
+func (tr *Transaction) ReplaceSelectionWith(node prosemirror.Node, inheritMarks bool) error {
+	sel := tr.Selection()
+	if inheritMarks {
+		marks := tr.StoredMarks()
+		if marks == nil {
+			if sel.Empty() {
+				marks = sel.From().Marks()
+			} else {
+				marks = sel.From().MarksAcross(sel.To())
+			}
+		}
+
+		node = node.Mark(marks)
+	}
+
+	mapFrom := len(tr.Steps)
+	if err := tr.ReplaceRangeWith(sel.From().Pos, sel.To().Pos, node); err != nil {
+		return err
+	}
+
+	bias := 1
+	if node.IsInline() {
+		bias = -1
+	}
+
+	tr.selectionToInsertionEnd(mapFrom, bias)
+	return nil
+}
+
+// DeleteSelection deletes the selection.
+func (tr *Transaction) DeleteSelection() error {
+	return tr.ReplaceSelection(prosemirror.Slice{})
+}
+
+// InsertText replaces the selection with a text node containing the given string.
+// The inserted text inherits the stored marks or the marks at the selection.
+func (tr *Transaction) InsertText(text string) error {
+	if text == "" {
+		return tr.DeleteSelection()
+	}
+
+	return tr.ReplaceSelectionWith(tr.Doc.Type.Schema.Text(text), true)
+}
+
+// InsertTextAt replaces the range between `from` and `to` with a text node
+// containing the given string. When no stored marks are set, the text takes
+// the marks of the replaced range.
+func (tr *Transaction) InsertTextAt(text string, from, to int) error {
+	if text == "" {
+		return tr.DeleteRange(from, to)
+	}
+
+	marks := tr.StoredMarks()
+	if marks == nil {
+		rFrom, err := tr.Doc.Resolve(from)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %d: %w", from, err)
+		}
+
+		if to == from {
+			marks = rFrom.Marks()
+		} else {
+			rTo, err := tr.Doc.Resolve(to)
+			if err != nil {
+				return fmt.Errorf("failed to resolve %d: %w", to, err)
+			}
+
+			marks = rFrom.MarksAcross(rTo)
+		}
+	}
+
+	if err := tr.ReplaceRangeWith(from, to, tr.Doc.Type.Schema.Text(text, marks...)); err != nil {
+		return err
+	}
+
+	if sel := tr.Selection(); !sel.Empty() {
+		tr.SetSelection(SelectionNear(sel.To(), 1))
+	}
+
+	return nil
+}
+
+// SetMeta stores a metadata property in this transaction, which can be read by
+// the code consuming it.
+func (tr *Transaction) SetMeta(key string, value any) *Transaction {
+	if tr.meta == nil {
+		tr.meta = map[string]any{}
+	}
+
+	tr.meta[key] = value
+	return tr
+}
+
+// GetMeta retrieves a metadata property for a given key.
+func (tr *Transaction) GetMeta(key string) any {
+	return tr.meta[key]
+}
+
+// selectionToInsertionEnd places the selection at the end of the content
+// inserted by the last replace step, if one was added since startLen.
+func (tr *Transaction) selectionToInsertionEnd(startLen int, bias int) {
+	last := len(tr.Steps) - 1
+	if last < startLen {
+		return
+	}
+
+	switch tr.Steps[last].Impl.(type) {
+	case *transform.ReplaceStep, *transform.ReplaceAroundStep:
+	default:
+		return
+	}
+
+	end := -1
+	tr.Mapping.Maps[last].ForEach(func(_, _, _, newTo int) {
+		if end < 0 {
+			end = newTo
+		}
+	})
+
+	tr.SetSelection(SelectionNear(resolve(tr.Doc, end), bias))
+}
@@ -0,0 +1,131 @@
+package state_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-json-experiment/json"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/state"
+)
+
+func TestTransaction(t *testing.T) {
+	tests := []struct {
+		name    string
+		sel     func(doc prosemirror.Node) state.Selection
+		edit    func(t *testing.T, tr *state.Transaction) error
+		wantDoc string
+		wantSel string
+	}{
+		{
+			name: "insert text with stored marks",
+			sel:  textSelection(3, 3),
+			edit: func(t *testing.T, tr *state.Transaction) error {
+				tr.AddStoredMark(tr.Doc.Type.Schema.Mark("em", nil))
+				return tr.InsertText("X")
+			},
+			wantDoc: `[{"type":"horizontal_rule"},{"type":"paragraph","content":[{"type":"text","text":"o"},{"type":"text","marks":[{"type":"em"}],"text":"X"},{"type":"text","text":"ne"}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]},{"type":"horizontal_rule"}]`,
+			wantSel: "TextSelection{Anchor: 4, Head: 4}",
+		},
+		{
+			name: "delete selection across paragraphs",
+			sel:  textSelection(3, 8),
+			edit: func(t *testing.T, tr *state.Transaction) error {
+				return tr.DeleteSelection()
+			},
+			wantDoc: `[{"type":"horizontal_rule"},{"type":"paragraph","content":[{"type":"text","text":"owo"}]},{"type":"horizontal_rule"}]`,
+			wantSel: "TextSelection{Anchor: 3, Head: 3}",
+		},
+		{
+			name: "replace node selection",
+			sel: func(doc prosemirror.Node) state.Selection {
+				return state.SelectionAtEnd(doc)
+			},
+			edit: func(t *testing.T, tr *state.Transaction) error {
+				return tr.ReplaceSelectionWith(tr.Doc.Type.Schema.Node("paragraph", nil, prosemirror.NewFragment(tr.Doc.Type.Schema.Text("three"))), false)
+			},
+			wantDoc: `[{"type":"horizontal_rule"},{"type":"paragraph","content":[{"type":"text","text":"one"}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]},{"type":"paragraph","content":[{"type":"text","text":"three"}]}]`,
+			wantSel: "TextSelection{Anchor: 17, Head: 17}",
+		},
+		{
+			name: "insert text at a range",
+			sel:  textSelection(7, 7),
+			edit: func(t *testing.T, tr *state.Transaction) error {
+				return tr.InsertTextAt("ONE", 2, 5)
+			},
+			wantDoc: `[{"type":"horizontal_rule"},{"type":"paragraph","content":[{"type":"text","text":"ONE"}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]},{"type":"horizontal_rule"}]`,
+			wantSel: "TextSelection{Anchor: 7, Head: 7}",
+		},
+		{
+			name: "delete everything",
+			sel: func(doc prosemirror.Node) state.Selection {
+				return state.NewAllSelection(doc)
+			},
+			edit: func(t *testing.T, tr *state.Transaction) error {
+				return tr.DeleteSelection()
+			},
+			wantDoc: `[{"type":"paragraph"}]`,
+			wantSel: "TextSelection{Anchor: 1, Head: 1}",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := fromJSON(t, testDoc)
+			s := state.Create(state.Config{Doc: doc, Selection: tc.sel(doc)})
+
+			tr := s.Tr()
+			if !assert.NoError(t, tc.edit(t, tr)) {
+				return
+			}
+
+			next := s.Apply(tr)
+			got, err := json.Marshal(next.Doc.Content)
+			if assert.NoError(t, err) {
+				assert.JSONEq(t, tc.wantDoc, string(got))
+			}
+
+			assert.Equal(t, tc.wantSel, fmt.Sprint(next.Selection))
+			assert.Nil(t, next.StoredMarks, "stored marks are cleared by steps")
+		})
+	}
+}
+
+func textSelection(anchor, head int) func(doc prosemirror.Node) state.Selection {
+	return func(doc prosemirror.Node) state.Selection {
+		sel, err := state.CreateTextSelection(doc, anchor, head)
+		if err != nil {
+			panic(err)
+		}
+
+		return sel
+	}
+}
+
+func TestStoredMarks(t *testing.T) {
+	doc := fromJSON(t, testDoc)
+	s := state.Create(state.Config{Doc: doc, Selection: textSelection(3, 3)(doc)})
+	em := doc.Type.Schema.Mark("em", nil)
+
+	tr := s.Tr()
+	tr.AddStoredMark(em)
+	assert.True(t, tr.StoredMarksSet())
+	assert.True(t, prosemirror.SameMarkSet([]prosemirror.Mark{em}, tr.StoredMarks()))
+	assert.True(t, prosemirror.SameMarkSet([]prosemirror.Mark{em}, s.Apply(tr).StoredMarks))
+
+	// marks already at the selection aren't stored
+	tr = s.Tr()
+	tr.RemoveStoredMark(em)
+	assert.False(t, tr.StoredMarksSet())
+
+	tr = s.Tr().SetStoredMarks([]prosemirror.Mark{em})
+	tr.SetSelection(state.SelectionAtStart(doc))
+	assert.Nil(t, tr.StoredMarks(), "setting the selection clears stored marks")
+	assert.True(t, tr.SelectionSet())
+
+	tr.SetMeta("origin", "test")
+	assert.Equal(t, "test", tr.GetMeta("origin"))
+	assert.Nil(t, tr.GetMeta("missing"))
+}
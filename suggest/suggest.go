@@ -0,0 +1,301 @@
+// Package suggest implements a suggestion (track changes) mode on top of transforms.
+//
+// In suggestion mode, deleted content is kept in the document with a deletion mark,
+// and inserted content is marked with an insertion mark. Both marks carry the author
+// and the time of the change. Suggestions can then be accepted or rejected, which
+// turns them into ordinary edits.
+//
+// Every change is made of ordinary steps, so any client can replay them. The
+// document's schema must define the insertion and deletion marks, see MarkSpecs.
+package suggest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/transform"
+)
+
+const (
+	// InsertionMark is the name of the mark type wrapping suggested insertions.
+	InsertionMark prosemirror.MarkTypeName = "insertion"
+
+	// DeletionMark is the name of the mark type wrapping suggested deletions.
+	DeletionMark prosemirror.MarkTypeName = "deletion"
+)
+
+// MarkSpecs returns the specs of the insertion and deletion marks, to be added to a schema.
+func MarkSpecs() map[prosemirror.MarkTypeName]prosemirror.MarkSpec {
+	inclusive := false
+	spec := prosemirror.MarkSpec{
+		Attrs: map[string]prosemirror.Attribute{
+			"author": {Optional: true},
+			"time":   {Optional: true},
+		},
+		Inclusive: &inclusive,
+	}
+
+	return map[prosemirror.MarkTypeName]prosemirror.MarkSpec{
+		InsertionMark: spec,
+		DeletionMark:  spec,
+	}
+}
+
+// Transform wraps a transform so that its edits are recorded as suggestions.
+// Methods that aren't overridden, like mark or structure changes, apply directly.
+type Transform struct {
+	*transform.Transform
+
+	// The author recorded on the suggestions.
+	Author string
+
+	// Now returns the time recorded on the suggestions. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// New creates a suggestion transform that starts with the given document.
+func New(doc prosemirror.Node, author string) *Transform {
+	return Wrap(transform.New(doc), author)
+}
+
+// Wrap makes the edits made through the returned transform suggestions.
+// Steps are added to the given transform.
+func Wrap(tr *transform.Transform, author string) *Transform {
+	return &Transform{Transform: tr, Author: author, Now: time.Now}
+}
+
+func (tr *Transform) attrs() map[string]any {
+	now := time.Now
+	if tr.Now != nil {
+		now = tr.Now
+	}
+
+	return map[string]any{
+		"author": tr.Author,
+		"time":   now().UnixMilli(),
+	}
+}
+
+func (tr *Transform) markType(name prosemirror.MarkTypeName) (prosemirror.MarkType, error) {
+	mt, ok := tr.Doc.Type.Schema.Marks[name]
+	if !ok {
+		return prosemirror.MarkType{}, fmt.Errorf("schema has no %q mark", name)
+	}
+
+	return mt, nil
+}
+
+// Delete suggests deleting the inline content between `from` and `to`. Content
+// that is itself a suggested insertion is removed, the rest is marked as deleted.
+// The block structure is left as is. It fails, without changing the document, when
+// the content is in a node that doesn't allow the deletion mark, like a code block.
+func (tr *Transform) Delete(from, to int) error {
+	deletion, err := tr.markType(DeletionMark)
+	if err != nil {
+		return err
+	}
+
+	insertion, err := tr.markType(InsertionMark)
+	if err != nil {
+		return err
+	}
+
+	mark := deletion.Create(tr.attrs())
+
+	var remove, keep [][2]int
+	for _, r := range inlineRanges(tr.Doc, from, to) {
+		switch {
+		case insertion.IsInSet(r.marks) != nil:
+			remove = appendRange(remove, r.from, r.to)
+		case !r.parent.Type.AllowsMarkType(deletion):
+			return fmt.Errorf("cannot suggest deleting content of %s, which doesn't allow %q marks", r.parent.Type.Name, DeletionMark)
+		case deletion.IsInSet(r.marks) == nil:
+			keep = appendRange(keep, r.from, r.to)
+		}
+	}
+
+	for _, r := range keep {
+		if err := tr.AddMark(r[0], r[1], mark); err != nil {
+			return err
+		}
+	}
+
+	for i := len(remove) - 1; i >= 0; i-- {
+		if err := tr.Transform.Delete(remove[i][0], remove[i][1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Replace suggests replacing the content between `from` and `to` with the given slice:
+// the existing content is suggested for deletion, and the slice is inserted after it.
+// It fails, without changing the document, when the content can't carry the suggestion
+// marks, like the text of a code block.
+func (tr *Transform) Replace(from, to int, slice prosemirror.Slice) error {
+	var content prosemirror.Fragment
+	if slice.Size() > 0 {
+		insertion, err := tr.markType(InsertionMark)
+		if err != nil {
+			return err
+		}
+
+		rTo, err := tr.Doc.Resolve(to)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %d: %w", to, err)
+		}
+
+		if parent := rTo.Parent(); parent.InlineContent() && !parent.Type.AllowsMarkType(insertion) {
+			return fmt.Errorf("cannot suggest inserting into %s, which doesn't allow %q marks", parent.Type.Name, InsertionMark)
+		}
+
+		content, err = markInline(slice.Content, insertion.Create(tr.attrs()))
+		if err != nil {
+			return err
+		}
+	}
+
+	start := len(tr.Steps)
+	if from < to {
+		if err := tr.Delete(from, to); err != nil {
+			return err
+		}
+	}
+
+	if slice.Size() == 0 {
+		return nil
+	}
+
+	pos := tr.Mapping.Slice(start, -1).Map(to, -1)
+	return tr.Transform.Replace(pos, pos, prosemirror.NewSlice(content, slice.OpenStart, slice.OpenEnd))
+}
+
+// ReplaceWith suggests replacing the given range with the given content.
+func (tr *Transform) ReplaceWith(from, to int, content ...prosemirror.Node) error {
+	return tr.Replace(from, to, prosemirror.NewSlice(prosemirror.NewFragment(content...), 0, 0))
+}
+
+// Insert suggests inserting the given content at the given position.
+func (tr *Transform) Insert(pos int, content ...prosemirror.Node) error {
+	return tr.ReplaceWith(pos, pos, content...)
+}
+
+// InsertText suggests inserting the given text at the given position, with the given marks.
+func (tr *Transform) InsertText(pos int, text string, marks ...prosemirror.Mark) error {
+	return tr.Insert(pos, tr.Doc.Type.Schema.Text(text, marks...))
+}
+
+// markInline adds the given mark to the inline content of the fragment.
+// It fails when the content is in a node that doesn't allow the mark.
+func markInline(f prosemirror.Fragment, mark prosemirror.Mark) (prosemirror.Fragment, error) {
+	content := make([]prosemirror.Node, len(f.Content))
+	for i, child := range f.Content {
+		switch {
+		case child.IsInline():
+			child = child.Mark(mark.AddToSet(child.Marks))
+		case child.InlineContent() && !child.Type.AllowsMarkType(mark.Type):
+			return prosemirror.Fragment{}, fmt.Errorf("cannot suggest inserting %s, which doesn't allow %q marks", child.Type.Name, mark.Type.Name)
+		default:
+			inner, err := markInline(child.Content, mark)
+			if err != nil {
+				return prosemirror.Fragment{}, err
+			}
+
+			child = child.Copy(inner)
+		}
+
+		content[i] = child
+	}
+
+	return prosemirror.NewFragment(content...), nil
+}
+
+// Accept accepts the suggestions between `from` and `to`: suggested deletions are
+// removed from the document, and suggested insertions lose their mark.
+func Accept(tr *transform.Transform, from, to int) error {
+	return resolve(tr, from, to, DeletionMark, InsertionMark)
+}
+
+// Reject rejects the suggestions between `from` and `to`: suggested insertions are
+// removed from the document, and suggested deletions lose their mark.
+func Reject(tr *transform.Transform, from, to int) error {
+	return resolve(tr, from, to, InsertionMark, DeletionMark)
+}
+
+// resolve deletes the content marked with `drop` and removes the `keep` mark
+// from the content between `from` and `to`.
+func resolve(tr *transform.Transform, from, to int, drop, keep prosemirror.MarkTypeName) error {
+	schema := tr.Doc.Type.Schema
+
+	dropType, ok := schema.Marks[drop]
+	if !ok {
+		return fmt.Errorf("schema has no %q mark", drop)
+	}
+
+	keepType, ok := schema.Marks[keep]
+	if !ok {
+		return fmt.Errorf("schema has no %q mark", keep)
+	}
+
+	var remove [][2]int
+	unmark := false
+	for _, r := range inlineRanges(tr.Doc, from, to) {
+		if dropType.IsInSet(r.marks) != nil {
+			remove = appendRange(remove, r.from, r.to)
+		} else if keepType.IsInSet(r.marks) != nil {
+			unmark = true
+		}
+	}
+
+	if unmark {
+		if err := tr.RemoveMarkType(from, to, keepType); err != nil {
+			return err
+		}
+	}
+
+	for i := len(remove) - 1; i >= 0; i-- {
+		if err := tr.Delete(remove[i][0], remove[i][1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inlineRange is a piece of inline content, with the marks it has.
+type inlineRange struct {
+	from, to int
+	marks    []prosemirror.Mark
+	parent   prosemirror.Node
+}
+
+// inlineRanges lists the inline nodes between `from` and `to`, clipped to the range.
+func inlineRanges(doc prosemirror.Node, from, to int) []inlineRange {
+	var ranges []inlineRange
+	doc.NodesBetween(from, to, func(node prosemirror.Node, pos int, parent *prosemirror.Node, _ int) bool {
+		if !node.IsInline() {
+			return true
+		}
+
+		start, end := max(pos, from), min(pos+node.NodeSize(), to)
+		if start < end {
+			ranges = append(ranges, inlineRange{from: start, to: end, marks: node.Marks, parent: *parent})
+		}
+
+		return false
+	})
+
+	return ranges
+}
+
+// appendRange adds a range to a sorted list of ranges, joining it with the last one when they touch.
+func appendRange(ranges [][2]int, from, to int) [][2]int {
+	if n := len(ranges); n > 0 && ranges[n-1][1] == from {
+		ranges[n-1][1] = to
+		return ranges
+	}
+
+	return append(ranges, [2]int{from, to})
+}
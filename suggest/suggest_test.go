@@ -0,0 +1,189 @@
+package suggest_test
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/go-json-experiment/json"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/schema"
+	"github.com/karitham/prosemirror/suggest"
+	"github.com/karitham/prosemirror/transform"
+)
+
+func newSchema(t *testing.T) prosemirror.Schema {
+	t.Helper()
+
+	spec := schema.DefaultSpec
+	spec.Marks = maps.Clone(schema.DefaultMarks)
+	maps.Copy(spec.Marks, suggest.MarkSpecs())
+	spec.DontRegister = true
+
+	s, err := prosemirror.NewSchema(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func toJSON(t *testing.T, n prosemirror.Node) string {
+	t.Helper()
+
+	b, err := json.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func TestSuggest(t *testing.T) {
+	s := newSchema(t)
+	now := func() time.Time { return time.UnixMilli(1000) }
+	p := func(content ...prosemirror.Node) prosemirror.Node {
+		return s.Node("paragraph", nil, prosemirror.NewFragment(content...))
+	}
+	doc := func(content ...prosemirror.Node) prosemirror.Node {
+		return s.Node("doc", nil, prosemirror.NewFragment(content...))
+	}
+
+	ins := s.Mark("insertion", map[string]any{"author": "alice", "time": int64(1000)})
+	del := s.Mark("deletion", map[string]any{"author": "alice", "time": int64(1000)})
+
+	type tt struct {
+		name  string
+		doc   prosemirror.Node
+		edit  func(tr *suggest.Transform) error
+		want  prosemirror.Node
+		check func(t *testing.T, doc prosemirror.Node)
+	}
+
+	tests := []tt{
+		{
+			name: "insert text",
+			doc:  doc(p(s.Text("hello world"))),
+			edit: func(tr *suggest.Transform) error { return tr.InsertText(6, " big") },
+			want: doc(p(s.Text("hello"), s.Text(" big", ins), s.Text(" world"))),
+		},
+		{
+			name: "delete text",
+			doc:  doc(p(s.Text("hello world"))),
+			edit: func(tr *suggest.Transform) error { return tr.Delete(6, 12) },
+			want: doc(p(s.Text("hello"), s.Text(" world", del))),
+		},
+		{
+			name: "replace text",
+			doc:  doc(p(s.Text("hello world"))),
+			edit: func(tr *suggest.Transform) error {
+				return tr.ReplaceWith(7, 12, s.Text("there"))
+			},
+			want: doc(p(s.Text("hello "), s.Text("world", del), s.Text("there", ins))),
+		},
+		{
+			name: "deleting a suggested insertion removes it",
+			doc:  doc(p(s.Text("hello"), s.Text(" big", ins), s.Text(" world"))),
+			edit: func(tr *suggest.Transform) error { return tr.Delete(4, 14) },
+			want: doc(p(s.Text("hel"), s.Text("lo wor", del), s.Text("ld"))),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := suggest.New(tc.doc, "alice")
+			tr.Now = now
+
+			if !assert.NoError(t, tc.edit(tr)) {
+				return
+			}
+
+			assert.JSONEq(t, toJSON(t, tc.want), toJSON(t, tr.Doc))
+		})
+	}
+}
+
+func TestSuggestCodeBlock(t *testing.T) {
+	s := newSchema(t)
+	doc := s.Node("doc", nil, prosemirror.NewFragment(
+		s.Node("code_block", nil, prosemirror.NewFragment(s.Text("let x"))),
+	))
+
+	tests := []struct {
+		name string
+		edit func(tr *suggest.Transform) error
+	}{
+		{
+			name: "delete",
+			edit: func(tr *suggest.Transform) error { return tr.Delete(1, 4) },
+		},
+		{
+			name: "insert text",
+			edit: func(tr *suggest.Transform) error { return tr.InsertText(4, " y") },
+		},
+		{
+			name: "replace",
+			edit: func(tr *suggest.Transform) error { return tr.ReplaceWith(5, 6, s.Text("y")) },
+		},
+		{
+			name: "insert code block",
+			edit: func(tr *suggest.Transform) error {
+				return tr.Insert(0, s.Node("code_block", nil, prosemirror.NewFragment(s.Text("y"))))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := suggest.New(doc, "alice")
+
+			assert.Error(t, tc.edit(tr))
+			assert.Empty(t, tr.Steps)
+			assert.JSONEq(t, toJSON(t, doc), toJSON(t, tr.Doc))
+		})
+	}
+}
+
+func TestAcceptReject(t *testing.T) {
+	s := newSchema(t)
+	p := func(content ...prosemirror.Node) prosemirror.Node {
+		return s.Node("paragraph", nil, prosemirror.NewFragment(content...))
+	}
+	doc := func(content ...prosemirror.Node) prosemirror.Node {
+		return s.Node("doc", nil, prosemirror.NewFragment(content...))
+	}
+
+	ins := s.Mark("insertion", map[string]any{"author": "alice"})
+	del := s.Mark("deletion", map[string]any{"author": "alice"})
+	suggested := doc(p(s.Text("hello "), s.Text("world", del), s.Text("there", ins), s.Text("!")))
+
+	tests := []struct {
+		name    string
+		resolve func(tr *transform.Transform, from, to int) error
+		want    prosemirror.Node
+	}{
+		{
+			name:    "accept",
+			resolve: suggest.Accept,
+			want:    doc(p(s.Text("hello there!"))),
+		},
+		{
+			name:    "reject",
+			resolve: suggest.Reject,
+			want:    doc(p(s.Text("hello world!"))),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := transform.New(suggested)
+			if !assert.NoError(t, tc.resolve(tr, 0, suggested.Content.Size)) {
+				return
+			}
+
+			assert.JSONEq(t, toJSON(t, tc.want), toJSON(t, tr.Doc))
+		})
+	}
+}
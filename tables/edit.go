@@ -0,0 +1,377 @@
+package tables
+
+import (
+	"fmt"
+
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/transform"
+)
+
+// The operations below take a TableRect of the current document of the
+// transform, computed with TableAt or CellAt.
+
+// AddColumn adds a column to the table at index col, copying the type of
+// the cells of a neighbouring column, unless it is a header column.
+func AddColumn(tr *transform.Transform, r TableRect, col int) error {
+	m, table := r.Map, r.Table
+	refColumn, useRef := 0, true
+	if col > 0 {
+		refColumn = -1
+	}
+
+	if columnIsHeader(m, table, col+refColumn) {
+		refColumn, useRef = 0, col != 0 && col != m.Width
+	}
+
+	mapStart := len(tr.Mapping.Maps)
+	mapPos := func(pos int) int { return tr.Mapping.Slice(mapStart, -1).Map(pos, 1) }
+	for row := 0; row < m.Height; row++ {
+		index := row*m.Width + col
+
+		// this position falls inside a col-spanning cell
+		if col > 0 && col < m.Width && m.Map[index-1] == m.Map[index] {
+			pos := m.Map[index]
+			cell := table.NodeAt(pos)
+			colCount, err := m.ColCount(pos)
+			if err != nil {
+				return err
+			}
+
+			if err := tr.SetNodeMarkup(mapPos(r.TableStart+pos), nil, addColSpan(*cell, col-colCount, 1), nil); err != nil {
+				return err
+			}
+
+			row += readCellAttrs(*cell).rowspan - 1
+			continue
+		}
+
+		typ := TableNodeTypes(table.Type.Schema)["cell"]
+		if useRef {
+			typ = table.NodeAt(m.Map[index+refColumn]).Type
+		}
+
+		node, err := typ.CreateAndFill(nil, nil)
+		if err != nil {
+			return err
+		}
+
+		if err := tr.Insert(mapPos(r.TableStart+m.PositionAt(row, col, table)), node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveColumn removes the column at index col from the table, shrinking
+// the cells spanning over it.
+func RemoveColumn(tr *transform.Transform, r TableRect, col int) error {
+	m, table := r.Map, r.Table
+	mapStart := len(tr.Mapping.Maps)
+	mapPos := func(pos int) int { return tr.Mapping.Slice(mapStart, -1).Map(pos, 1) }
+	for row := 0; row < m.Height; {
+		index := row*m.Width + col
+		pos := m.Map[index]
+		cell := table.NodeAt(pos)
+
+		// this is part of a col-spanning cell
+		if (col > 0 && m.Map[index-1] == pos) || (col < m.Width-1 && m.Map[index+1] == pos) {
+			colCount, err := m.ColCount(pos)
+			if err != nil {
+				return err
+			}
+
+			if err := tr.SetNodeMarkup(mapPos(r.TableStart+pos), nil, removeColSpan(*cell, col-colCount, 1), nil); err != nil {
+				return err
+			}
+		} else {
+			start := mapPos(r.TableStart + pos)
+			if err := tr.Delete(start, start+cell.NodeSize()); err != nil {
+				return err
+			}
+		}
+
+		row += readCellAttrs(*cell).rowspan
+	}
+
+	return nil
+}
+
+// AddRow adds a row to the table at index row, copying the type of the cells
+// of a neighbouring row, unless it is a header row.
+func AddRow(tr *transform.Transform, r TableRect, row int) error {
+	m, table := r.Map, r.Table
+	types := TableNodeTypes(table.Type.Schema)
+
+	rowPos := r.TableStart
+	for i := 0; i < row; i++ {
+		rowPos += table.Child(i).NodeSize()
+	}
+
+	refRow, useRef := 0, true
+	if row > 0 {
+		refRow = -1
+	}
+
+	if rowIsHeader(m, table, row+refRow) {
+		refRow, useRef = 0, row != 0 && row != m.Height
+	}
+
+	var cells []prosemirror.Node
+	for col, index := 0, m.Width*row; col < m.Width; col, index = col+1, index+1 {
+		// covered by a rowspan cell
+		if row > 0 && row < m.Height && m.Map[index] == m.Map[index-m.Width] {
+			pos := m.Map[index]
+			cell := table.NodeAt(pos)
+			attrs := readCellAttrs(*cell)
+			if err := tr.SetNodeMarkup(r.TableStart+pos, nil, withAttrs(*cell, map[string]any{"rowspan": attrs.rowspan + 1}), nil); err != nil {
+				return err
+			}
+
+			col += attrs.colspan - 1
+			index += attrs.colspan - 1
+			continue
+		}
+
+		typ := types["cell"]
+		if useRef {
+			typ = table.NodeAt(m.Map[index+refRow*m.Width]).Type
+		}
+
+		if node, err := typ.CreateAndFill(nil, nil); err == nil {
+			cells = append(cells, node)
+		}
+	}
+
+	node, err := types["row"].Create(nil, nil, cells...)
+	if err != nil {
+		return err
+	}
+
+	return tr.Insert(rowPos, node)
+}
+
+// RemoveRow removes the row at index row from the table, shrinking the
+// cells spanning over it.
+func RemoveRow(tr *transform.Transform, r TableRect, row int) error {
+	m, table := r.Map, r.Table
+
+	rowPos := 0
+	for i := 0; i < row; i++ {
+		rowPos += table.Child(i).NodeSize()
+	}
+	nextRow := rowPos + table.Child(row).NodeSize()
+
+	mapStart := len(tr.Mapping.Maps)
+	mapPos := func(pos int) int { return tr.Mapping.Slice(mapStart, -1).Map(pos, 1) }
+	if err := tr.Delete(r.TableStart+rowPos, r.TableStart+nextRow); err != nil {
+		return err
+	}
+
+	seen := map[int]bool{}
+	for col, index := 0, row*m.Width; col < m.Width; col, index = col+1, index+1 {
+		pos := m.Map[index]
+		if seen[pos] {
+			continue
+		}
+		seen[pos] = true
+
+		cell := table.NodeAt(pos)
+		attrs := readCellAttrs(*cell)
+		switch {
+		case row > 0 && pos == m.Map[index-m.Width]:
+			// this cell starts in the row above, reduce its rowspan
+			if err := tr.SetNodeMarkup(mapPos(r.TableStart+pos), nil, withAttrs(*cell, map[string]any{"rowspan": attrs.rowspan - 1}), nil); err != nil {
+				return err
+			}
+		case row+1 < m.Height && pos == m.Map[index+m.Width]:
+			// this cell continues in the row below, move it down
+			cp, err := cell.Type.Create(withAttrs(*cell, map[string]any{"rowspan": attrs.rowspan - 1}), nil, cell.Content.Content...)
+			if err != nil {
+				return err
+			}
+
+			if err := tr.Insert(mapPos(r.TableStart+m.PositionAt(row+1, col, table)), cp); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+
+		col += attrs.colspan - 1
+		index += attrs.colspan - 1
+	}
+
+	return nil
+}
+
+// MergeCells merges the cells of the rectangle into its top left cell,
+// appending the content of the non-empty ones. It fails when cells
+// cross the sides of the rectangle.
+func MergeCells(tr *transform.Transform, r TableRect) error {
+	m := r.Map
+	if cellsOverlapRectangle(m, r.Rect) {
+		return fmt.Errorf("cells overlap the sides of the rectangle")
+	}
+
+	var content prosemirror.Fragment
+	var merged *prosemirror.Node
+	mergedPos := 0
+	mapStart := len(tr.Mapping.Maps)
+	seen := map[int]bool{}
+	for row := r.Top; row < r.Bottom; row++ {
+		for col := r.Left; col < r.Right; col++ {
+			cellPos := m.Map[row*m.Width+col]
+			cell := r.Table.NodeAt(cellPos)
+			if seen[cellPos] || cell == nil {
+				continue
+			}
+			seen[cellPos] = true
+
+			if merged == nil {
+				merged, mergedPos = cell, cellPos
+				continue
+			}
+
+			if !isEmpty(*cell) {
+				content = content.Append(cell.Content)
+			}
+
+			mapped := tr.Mapping.Slice(mapStart, -1).Map(cellPos+r.TableStart, 1)
+			if err := tr.Delete(mapped, mapped+cell.NodeSize()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if merged == nil {
+		return nil
+	}
+
+	attrs := readCellAttrs(*merged)
+	cp := *merged
+	cp.Attrs = addColSpan(*merged, attrs.colspan, r.Right-r.Left-attrs.colspan)
+	if err := tr.SetNodeMarkup(mergedPos+r.TableStart, nil, withAttrs(cp, map[string]any{"rowspan": r.Bottom - r.Top}), nil); err != nil {
+		return err
+	}
+
+	if content.Size > 0 {
+		end := mergedPos + 1 + merged.Content.Size
+		start := end
+		if isEmpty(*merged) {
+			start = mergedPos + 1
+		}
+
+		if err := tr.ReplaceWith(start+r.TableStart, end+r.TableStart, content.Content...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SplitCell splits the cell at the given position, spanning several rows
+// or columns, into cells of a single row and column.
+func SplitCell(tr *transform.Transform, cellPos int) error {
+	r, err := CellAt(tr.Doc, cellPos)
+	if err != nil {
+		return err
+	}
+
+	cell := tr.Doc.NodeAt(cellPos)
+	attrs := readCellAttrs(*cell)
+	if attrs.colspan == 1 && attrs.rowspan == 1 {
+		return fmt.Errorf("cell at %d doesn't span several rows or columns", cellPos)
+	}
+
+	cellAttrs := make([]map[string]any, r.Right-r.Left)
+	for i := range cellAttrs {
+		changes := map[string]any{"colspan": 1, "rowspan": 1}
+		if attrs.colwidth != nil {
+			changes["colwidth"] = nil
+			if i < len(attrs.colwidth) && attrs.colwidth[i] != 0 {
+				changes["colwidth"] = []int{attrs.colwidth[i]}
+			}
+		}
+
+		cellAttrs[i] = withAttrs(*cell, changes)
+	}
+
+	mapStart := len(tr.Mapping.Maps)
+	for row := r.Top; row < r.Bottom; row++ {
+		pos := r.Map.PositionAt(row, r.Left, r.Table)
+		if row == r.Top {
+			pos += cell.NodeSize()
+		}
+
+		for col, i := r.Left, 0; col < r.Right; col, i = col+1, i+1 {
+			if col == r.Left && row == r.Top {
+				continue
+			}
+
+			node, err := cell.Type.CreateAndFill(cellAttrs[i], nil)
+			if err != nil {
+				return err
+			}
+
+			if err := tr.Insert(tr.Mapping.Slice(mapStart, -1).Map(pos+r.TableStart, 1), node); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tr.SetNodeMarkup(cellPos, nil, cellAttrs[0], nil)
+}
+
+func columnIsHeader(m TableMap, table prosemirror.Node, col int) bool {
+	header := TableNodeTypes(table.Type.Schema)["header_cell"]
+	for row := 0; row < m.Height; row++ {
+		if cell := table.NodeAt(m.Map[col+row*m.Width]); cell == nil || !cell.Type.Eq(header) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func rowIsHeader(m TableMap, table prosemirror.Node, row int) bool {
+	header := TableNodeTypes(table.Type.Schema)["header_cell"]
+	for col := 0; col < m.Width; col++ {
+		if cell := table.NodeAt(m.Map[col+row*m.Width]); cell == nil || !cell.Type.Eq(header) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isEmpty reports whether a cell holds a single empty textblock.
+func isEmpty(cell prosemirror.Node) bool {
+	return cell.ChildCount() == 1 && cell.Child(0).IsTextblock() && cell.Child(0).ChildCount() == 0
+}
+
+// cellsOverlapRectangle reports whether cells cross the sides of the rectangle.
+func cellsOverlapRectangle(m TableMap, r Rect) bool {
+	indexTop := r.Top*m.Width + r.Left
+	indexLeft, indexRight := indexTop, indexTop+(r.Right-r.Left-1)
+	indexBottom := (r.Bottom-1)*m.Width + r.Left
+	for i := r.Top; i < r.Bottom; i++ {
+		if (r.Left > 0 && m.Map[indexLeft] == m.Map[indexLeft-1]) || (r.Right < m.Width && m.Map[indexRight] == m.Map[indexRight+1]) {
+			return true
+		}
+
+		indexLeft += m.Width
+		indexRight += m.Width
+	}
+
+	for i := r.Left; i < r.Right; i++ {
+		if (r.Top > 0 && m.Map[indexTop] == m.Map[indexTop-m.Width]) || (r.Bottom < m.Height && m.Map[indexBottom] == m.Map[indexBottom+m.Width]) {
+			return true
+		}
+
+		indexTop++
+		indexBottom++
+	}
+
+	return false
+}
@@ -0,0 +1,190 @@
+package tables_test
+
+import (
+	"testing"
+
+	"github.com/karitham/prosemirror/builder"
+	"github.com/karitham/prosemirror/tables"
+	"github.com/karitham/prosemirror/transform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEdit(t *testing.T) {
+	// a b
+	// c d
+	simple := doc(table(tr(td(para("a")), td(para("b"))), tr(td(para("c")), td(para("d")))))
+
+	// a a b
+	// a a c
+	// d e c
+	spans := doc(table(
+		tr(td(map[string]any{"colspan": 2, "rowspan": 2}, para("a")), td(para("b"))),
+		tr(td(map[string]any{"rowspan": 2}, para("c"))),
+		tr(td(para("d")), td(para("e"))),
+	))
+
+	whole := func(f func(tf *transform.Transform, r tables.TableRect) error) func(tf *transform.Transform) error {
+		return func(tf *transform.Transform) error {
+			r, err := tables.TableAt(tf.Doc, 0)
+			if err != nil {
+				return err
+			}
+
+			return f(tf, r)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		doc     builder.Node
+		edit    func(tf *transform.Transform) error
+		want    builder.Node
+		wantErr bool
+	}{
+		{
+			name: "add row",
+			doc:  simple,
+			edit: whole(func(tf *transform.Transform, r tables.TableRect) error { return tables.AddRow(tf, r, 1) }),
+			want: doc(table(tr(td(para("a")), td(para("b"))), tr(td(para()), td(para())), tr(td(para("c")), td(para("d"))))),
+		},
+		{
+			name: "add row after a header row",
+			doc:  doc(table(tr(th(para("a")), th(para("b"))), tr(td(para("c")), td(para("d"))))),
+			edit: whole(func(tf *transform.Transform, r tables.TableRect) error { return tables.AddRow(tf, r, 1) }),
+			want: doc(table(tr(th(para("a")), th(para("b"))), tr(td(para()), td(para())), tr(td(para("c")), td(para("d"))))),
+		},
+		{
+			name: "add row through a rowspan",
+			doc:  spans,
+			edit: whole(func(tf *transform.Transform, r tables.TableRect) error { return tables.AddRow(tf, r, 1) }),
+			want: doc(table(
+				tr(td(map[string]any{"colspan": 2, "rowspan": 3}, para("a")), td(para("b"))),
+				tr(td(para())),
+				tr(td(map[string]any{"rowspan": 2}, para("c"))),
+				tr(td(para("d")), td(para("e"))),
+			)),
+		},
+		{
+			name: "remove row",
+			doc:  simple,
+			edit: whole(func(tf *transform.Transform, r tables.TableRect) error { return tables.RemoveRow(tf, r, 0) }),
+			want: doc(table(tr(td(para("c")), td(para("d"))))),
+		},
+		{
+			name: "remove row with spanning cells",
+			doc:  spans,
+			edit: whole(func(tf *transform.Transform, r tables.TableRect) error { return tables.RemoveRow(tf, r, 1) }),
+			want: doc(table(
+				tr(td(map[string]any{"colspan": 2}, para("a")), td(para("b"))),
+				tr(td(para("d")), td(para("e")), td(para("c"))),
+			)),
+		},
+		{
+			name: "add column",
+			doc:  simple,
+			edit: whole(func(tf *transform.Transform, r tables.TableRect) error { return tables.AddColumn(tf, r, 2) }),
+			want: doc(table(tr(td(para("a")), td(para("b")), td(para())), tr(td(para("c")), td(para("d")), td(para())))),
+		},
+		{
+			name: "add column through a colspan",
+			doc:  spans,
+			edit: whole(func(tf *transform.Transform, r tables.TableRect) error { return tables.AddColumn(tf, r, 1) }),
+			want: doc(table(
+				tr(td(map[string]any{"colspan": 3, "rowspan": 2}, para("a")), td(para("b"))),
+				tr(td(map[string]any{"rowspan": 2}, para("c"))),
+				tr(td(para("d")), td(para()), td(para("e"))),
+			)),
+		},
+		{
+			name: "remove column",
+			doc:  doc(table(tr(td(map[string]any{"colspan": 2, "colwidth": []int{100, 200}}, para("a")), td(para("b"))), tr(td(map[string]any{"colwidth": []int{100}}, para("c")), td(para("d")), td(para("e"))))),
+			edit: whole(func(tf *transform.Transform, r tables.TableRect) error { return tables.RemoveColumn(tf, r, 1) }),
+			want: doc(table(tr(td(map[string]any{"colwidth": []int{100}}, para("a")), td(para("b"))), tr(td(map[string]any{"colwidth": []int{100}}, para("c")), td(para("e"))))),
+		},
+		{
+			name: "merge cells",
+			doc:  simple,
+			edit: func(tf *transform.Transform) error {
+				r, err := tables.TableAt(tf.Doc, 0)
+				if err != nil {
+					return err
+				}
+
+				r.Rect = tables.Rect{Left: 0, Top: 0, Right: 2, Bottom: 1}
+				return tables.MergeCells(tf, r)
+			},
+			want: doc(table(tr(td(map[string]any{"colspan": 2}, para("a"), para("b"))), tr(td(para("c")), td(para("d"))))),
+		},
+		{
+			name: "merge overlapping cells",
+			doc:  spans,
+			edit: func(tf *transform.Transform) error {
+				r, err := tables.TableAt(tf.Doc, 0)
+				if err != nil {
+					return err
+				}
+
+				r.Rect = tables.Rect{Left: 1, Top: 0, Right: 3, Bottom: 1}
+				return tables.MergeCells(tf, r)
+			},
+			wantErr: true,
+		},
+		{
+			name: "split cell",
+			doc:  spans,
+			edit: func(tf *transform.Transform) error { return tables.SplitCell(tf, 2) },
+			want: doc(table(
+				tr(td(para("a")), td(para()), td(para("b"))),
+				tr(td(para()), td(para()), td(map[string]any{"rowspan": 2}, para("c"))),
+				tr(td(para("d")), td(para("e"))),
+			)),
+		},
+		{
+			name:    "split single cell",
+			doc:     simple,
+			edit:    func(tf *transform.Transform) error { return tables.SplitCell(tf, 2) },
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tf := transform.New(tc.doc.Node)
+			err := tc.edit(tf)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+			assertDoc(t, tc.want.Node, tf.Doc)
+
+			m, err := tables.NewTableMap(*tf.Doc.Child(0))
+			if assert.NoError(t, err) {
+				assert.Empty(t, m.Problems)
+			}
+
+			// the steps replay on the original document
+			replayed := tc.doc.Node
+			for _, step := range tf.Steps {
+				if replayed, err = step.Apply(replayed); !assert.NoError(t, err) {
+					return
+				}
+			}
+			assertDoc(t, tc.want.Node, replayed)
+		})
+	}
+
+	t.Run("cell at", func(t *testing.T) {
+		r, err := tables.CellAt(spans.Node, 14)
+		if assert.NoError(t, err) {
+			assert.Equal(t, tables.Rect{Left: 2, Top: 1, Right: 3, Bottom: 3}, r.Rect)
+			assert.Equal(t, 1, r.TableStart)
+		}
+
+		_, err = tables.CellAt(spans.Node, 3)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,134 @@
+package tables
+
+import (
+	"fmt"
+
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/transform"
+)
+
+// FixTables adds steps to the transform repairing the problems of the tables
+// in its document, making sure every table is a rectangle of cells without
+// overlaps. It reports whether any table was changed.
+func FixTables(tr *transform.Transform) (bool, error) {
+	type found struct {
+		node prosemirror.Node
+		pos  int
+	}
+
+	var tables []found
+	tr.Doc.Descendants(func(n prosemirror.Node, pos int, _ *prosemirror.Node, _ int) bool {
+		if TableRole(n.Type) == "table" {
+			tables = append(tables, found{n, pos})
+		}
+
+		return true
+	})
+
+	doc, mapStart, changed := tr.Doc, len(tr.Mapping.Maps), false
+	for _, t := range tables {
+		fixed, err := fixTable(tr, doc, t.node, t.pos, mapStart)
+		if err != nil {
+			return changed, fmt.Errorf("failed to fix table at %d: %w", t.pos, err)
+		}
+
+		changed = changed || fixed
+	}
+
+	return changed, nil
+}
+
+// fixTable repairs a table of doc, which is the document of the transform
+// when its mapping had mapStart maps.
+func fixTable(tr *transform.Transform, doc, table prosemirror.Node, tablePos, mapStart int) (bool, error) {
+	m, err := NewTableMap(table)
+	if err != nil {
+		return false, err
+	}
+
+	if len(m.Problems) == 0 {
+		return false, nil
+	}
+
+	mapPos := func(pos int) int {
+		return tr.Mapping.Slice(mapStart, -1).Map(pos, 1)
+	}
+
+	mustAdd := make([]int, m.Height)
+	for _, prob := range m.Problems {
+		var cell *prosemirror.Node
+		if prob.Type != ProblemMissing && prob.Type != ProblemZeroSized {
+			if cell = table.NodeAt(prob.Pos); cell == nil {
+				continue
+			}
+		}
+
+		switch prob.Type {
+		case ProblemCollision:
+			attrs := readCellAttrs(*cell)
+			for j := 0; j < attrs.rowspan && prob.Row+j < m.Height; j++ {
+				mustAdd[prob.Row+j] += prob.N
+			}
+
+			err = tr.SetNodeMarkup(mapPos(tablePos+1+prob.Pos), nil, removeColSpan(*cell, attrs.colspan-prob.N, prob.N), nil)
+		case ProblemMissing:
+			mustAdd[prob.Row] += prob.N
+		case ProblemOverlongRowspan:
+			attrs := readCellAttrs(*cell)
+			err = tr.SetNodeMarkup(mapPos(tablePos+1+prob.Pos), nil, withAttrs(*cell, map[string]any{"rowspan": attrs.rowspan - prob.N}), nil)
+		case ProblemColwidthMismatch:
+			err = tr.SetNodeMarkup(mapPos(tablePos+1+prob.Pos), nil, withAttrs(*cell, map[string]any{"colwidth": prob.Colwidth}), nil)
+		case ProblemZeroSized:
+			pos := mapPos(tablePos)
+			err = tr.Delete(pos, pos+table.NodeSize())
+		}
+
+		if err != nil {
+			return false, err
+		}
+	}
+
+	first, last := -1, -1
+	for i, add := range mustAdd {
+		if add > 0 {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+
+	// Add the missing cells, at the start of the rows when it looks like a
+	// "bite" was taken out of the table, and at their end otherwise.
+	types := TableNodeTypes(doc.Type.Schema)
+	for i, pos := 0, tablePos+1; i < m.Height; i++ {
+		row := table.Child(i)
+		end := pos + row.NodeSize()
+		if add := mustAdd[i]; add > 0 {
+			role := "cell"
+			if c := row.FirstChild(); c != nil {
+				role = TableRole(c.Type)
+			}
+
+			var nodes []prosemirror.Node
+			for j := 0; j < add; j++ {
+				if node, err := types[role].CreateAndFill(nil, nil); err == nil {
+					nodes = append(nodes, node)
+				}
+			}
+
+			side := end - 1
+			if (i == 0 || first == i-1) && last == i {
+				side = pos + 1
+			}
+
+			if err := tr.Insert(mapPos(side), nodes...); err != nil {
+				return false, err
+			}
+		}
+
+		pos = end
+	}
+
+	return true, nil
+}
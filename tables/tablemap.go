@@ -0,0 +1,383 @@
+// Package tables works with tables of cells spanning several rows and columns,
+// modeled on prosemirror-tables.
+//
+// Table nodes are found by the "tableRole" of their spec's Extra, like the
+// nodes added by schema.AddTableNodes. A TableMap lays the cells of a table on
+// a grid and reports its problems, which FixTables repairs, and the table
+// operations add steps to transforms, so they can be replayed by clients.
+package tables
+
+import (
+	"fmt"
+
+	"github.com/karitham/prosemirror"
+)
+
+// Rect is a rectangle of cells in a table, from its top left cell
+// to its bottom right one, excluded.
+type Rect struct {
+	Left, Top, Right, Bottom int
+}
+
+// ProblemType is the type of a problem found in a table.
+type ProblemType string
+
+const (
+	// ProblemCollision is a cell overlapping another one, because of a rowspan above it.
+	ProblemCollision ProblemType = "collision"
+
+	// ProblemMissing is a row with fewer cells than the table width.
+	ProblemMissing ProblemType = "missing"
+
+	// ProblemOverlongRowspan is a cell spanning rows past the end of the table.
+	ProblemOverlongRowspan ProblemType = "overlong_rowspan"
+
+	// ProblemColwidthMismatch is a cell with column widths that disagree with the other cells of its columns.
+	ProblemColwidthMismatch ProblemType = "colwidth_mismatch"
+
+	// ProblemZeroSized is a table without rows or columns.
+	ProblemZeroSized ProblemType = "zero_sized"
+)
+
+// Problem is a problem found in a table by NewTableMap.
+type Problem struct {
+	Type ProblemType
+
+	// The position of the cell with the problem, relative to the start of the table,
+	// for all problems but missing cells and zero sized tables.
+	Pos int
+
+	// The row of the problem, for collisions and missing cells.
+	Row int
+
+	// The number of columns, for collisions, of cells, for missing cells,
+	// or of rows, for overlong rowspans, in excess or missing.
+	N int
+
+	// The column widths the cell should have, for colwidth mismatches.
+	Colwidth []int
+}
+
+// TableMap describes the structure of a table, with the positions of
+// the cells covering each slot of its grid.
+type TableMap struct {
+	// The number of columns.
+	Width int
+
+	// The number of rows.
+	Height int
+
+	// A Width * Height array with the start position of the cell covering
+	// each slot of the table, relative to the start of the table.
+	Map []int
+
+	// The problems found in the table, if any.
+	Problems []Problem
+}
+
+// NewTableMap computes the map of a table node.
+func NewTableMap(table prosemirror.Node) (TableMap, error) {
+	if TableRole(table.Type) != "table" {
+		return TableMap{}, fmt.Errorf("not a table node: %s", table.Type.Name)
+	}
+
+	width, height := findWidth(table), table.ChildCount()
+	m := TableMap{Width: width, Height: height, Map: make([]int, width*height)}
+	mapPos := 0
+	colWidths := make([]colWidth, width)
+
+	for row, pos := 0, 0; row < height; row++ {
+		rowNode := table.Child(row)
+		pos++
+
+		for i := 0; ; i++ {
+			for mapPos < len(m.Map) && m.Map[mapPos] != 0 {
+				mapPos++
+			}
+
+			if i == rowNode.ChildCount() {
+				break
+			}
+
+			cell := rowNode.Child(i)
+			attrs := readCellAttrs(*cell)
+			for h := 0; h < attrs.rowspan; h++ {
+				if h+row >= height {
+					m.Problems = append(m.Problems, Problem{Type: ProblemOverlongRowspan, Pos: pos, N: attrs.rowspan - h})
+					break
+				}
+
+				start := mapPos + h*width
+				for w := 0; w < attrs.colspan; w++ {
+					if start+w < len(m.Map) && m.Map[start+w] == 0 {
+						m.Map[start+w] = pos
+					} else {
+						m.Problems = append(m.Problems, Problem{Type: ProblemCollision, Row: row, Pos: pos, N: attrs.colspan - w})
+					}
+
+					if w < len(attrs.colwidth) && attrs.colwidth[w] != 0 {
+						colWidths[(start+w)%width].add(attrs.colwidth[w])
+					}
+				}
+			}
+
+			mapPos += attrs.colspan
+			pos += cell.NodeSize()
+		}
+
+		missing := 0
+		for expected := (row + 1) * width; mapPos < expected; mapPos++ {
+			if m.Map[mapPos] == 0 {
+				missing++
+			}
+		}
+
+		if missing > 0 {
+			m.Problems = append(m.Problems, Problem{Type: ProblemMissing, Row: row, N: missing})
+		}
+
+		pos++
+	}
+
+	if width == 0 || height == 0 {
+		m.Problems = append(m.Problems, Problem{Type: ProblemZeroSized})
+	}
+
+	for _, w := range colWidths {
+		if w.width != 0 && w.count < height {
+			if err := m.findBadColWidths(colWidths, table); err != nil {
+				return TableMap{}, err
+			}
+
+			break
+		}
+	}
+
+	return m, nil
+}
+
+// colWidth is the width most cells of a column agree on.
+type colWidth struct {
+	width int
+	count int
+}
+
+func (c *colWidth) add(width int) {
+	switch {
+	case c.width == 0 || (c.width != width && c.count == 1):
+		c.width, c.count = width, 1
+	case c.width == width:
+		c.count++
+	}
+}
+
+// findWidth returns the number of columns of the widest row of a table.
+func findWidth(table prosemirror.Node) int {
+	width, hasRowSpan := -1, false
+	for row := 0; row < table.ChildCount(); row++ {
+		rowNode, rowWidth := table.Child(row), 0
+		if hasRowSpan {
+			for j := 0; j < row; j++ {
+				prevRow := table.Child(j)
+				for i := 0; i < prevRow.ChildCount(); i++ {
+					if attrs := readCellAttrs(*prevRow.Child(i)); j+attrs.rowspan > row {
+						rowWidth += attrs.colspan
+					}
+				}
+			}
+		}
+
+		for i := 0; i < rowNode.ChildCount(); i++ {
+			attrs := readCellAttrs(*rowNode.Child(i))
+			rowWidth += attrs.colspan
+			if attrs.rowspan > 1 {
+				hasRowSpan = true
+			}
+		}
+
+		width = max(width, rowWidth)
+	}
+
+	return max(width, 0)
+}
+
+// findBadColWidths reports the cells with column widths that disagree with
+// the widths of their columns.
+func (m *TableMap) findBadColWidths(colWidths []colWidth, table prosemirror.Node) error {
+	var problems []Problem
+	seen := map[int]bool{}
+	for i, pos := range m.Map {
+		if seen[pos] {
+			continue
+		}
+		seen[pos] = true
+
+		node := table.NodeAt(pos)
+		if node == nil {
+			return fmt.Errorf("no cell with offset %d found", pos)
+		}
+
+		attrs := readCellAttrs(*node)
+		var updated []int
+		for j := 0; j < attrs.colspan; j++ {
+			w := colWidths[(i+j)%m.Width].width
+			if w == 0 || (j < len(attrs.colwidth) && attrs.colwidth[j] == w) {
+				continue
+			}
+
+			if updated == nil {
+				updated = attrs.freshColwidth()
+			}
+			updated[j] = w
+		}
+
+		if updated != nil {
+			problems = append(problems, Problem{Type: ProblemColwidthMismatch, Pos: pos, Colwidth: updated})
+		}
+	}
+
+	m.Problems = append(problems, m.Problems...)
+	return nil
+}
+
+// FindCell returns the rectangle covered by the cell at the given position.
+func (m TableMap) FindCell(pos int) (Rect, error) {
+	for i, cur := range m.Map {
+		if cur != pos {
+			continue
+		}
+
+		left, top := i%m.Width, i/m.Width
+		right, bottom := left+1, top+1
+		for j := 1; right < m.Width && m.Map[i+j] == cur; j++ {
+			right++
+		}
+
+		for j := 1; bottom < m.Height && m.Map[i+m.Width*j] == cur; j++ {
+			bottom++
+		}
+
+		return Rect{Left: left, Top: top, Right: right, Bottom: bottom}, nil
+	}
+
+	return Rect{}, fmt.Errorf("no cell with offset %d found", pos)
+}
+
+// ColCount returns the left-most column of the cell at the given position.
+func (m TableMap) ColCount(pos int) (int, error) {
+	for i, cur := range m.Map {
+		if cur == pos {
+			return i % m.Width, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no cell with offset %d found", pos)
+}
+
+// Axis is the direction in which NextCell moves.
+type Axis int
+
+const (
+	Horizontal Axis = iota
+	Vertical
+)
+
+// NextCell returns the position of the next cell in the given direction, dir
+// being negative to move left or up, and reports false when there is none.
+func (m TableMap) NextCell(pos int, axis Axis, dir int) (int, bool, error) {
+	r, err := m.FindCell(pos)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if axis == Horizontal {
+		if (dir < 0 && r.Left == 0) || (dir >= 0 && r.Right == m.Width) {
+			return 0, false, nil
+		}
+
+		col := r.Right
+		if dir < 0 {
+			col = r.Left - 1
+		}
+
+		return m.Map[r.Top*m.Width+col], true, nil
+	}
+
+	if (dir < 0 && r.Top == 0) || (dir >= 0 && r.Bottom == m.Height) {
+		return 0, false, nil
+	}
+
+	row := r.Bottom
+	if dir < 0 {
+		row = r.Top - 1
+	}
+
+	return m.Map[r.Left+m.Width*row], true, nil
+}
+
+// RectBetween returns the smallest rectangle covering the cells at the two positions.
+func (m TableMap) RectBetween(a, b int) (Rect, error) {
+	ra, err := m.FindCell(a)
+	if err != nil {
+		return Rect{}, err
+	}
+
+	rb, err := m.FindCell(b)
+	if err != nil {
+		return Rect{}, err
+	}
+
+	return Rect{
+		Left:   min(ra.Left, rb.Left),
+		Top:    min(ra.Top, rb.Top),
+		Right:  max(ra.Right, rb.Right),
+		Bottom: max(ra.Bottom, rb.Bottom),
+	}, nil
+}
+
+// CellsInRect returns the positions of the cells starting in the given rectangle.
+func (m TableMap) CellsInRect(r Rect) []int {
+	var result []int
+	seen := map[int]bool{}
+	for row := r.Top; row < r.Bottom; row++ {
+		for col := r.Left; col < r.Right; col++ {
+			index := row*m.Width + col
+			pos := m.Map[index]
+			if seen[pos] {
+				continue
+			}
+			seen[pos] = true
+
+			if (col == r.Left && col > 0 && m.Map[index-1] == pos) || (row == r.Top && row > 0 && m.Map[index-m.Width] == pos) {
+				continue
+			}
+
+			result = append(result, pos)
+		}
+	}
+
+	return result
+}
+
+// PositionAt returns the position at which a cell at the given row and column
+// would start, relative to the start of the table, skipping cells spanning
+// from previous rows.
+func (m TableMap) PositionAt(row, col int, table prosemirror.Node) int {
+	for i, rowStart := 0, 0; ; i++ {
+		rowEnd := rowStart + table.Child(i).NodeSize()
+		if i == row {
+			index, rowEndIndex := col+row*m.Width, (row+1)*m.Width
+			for index < rowEndIndex && m.Map[index] < rowStart {
+				index++
+			}
+
+			if index == rowEndIndex {
+				return rowEnd - 1
+			}
+
+			return m.Map[index]
+		}
+
+		rowStart = rowEnd
+	}
+}
@@ -0,0 +1,157 @@
+package tables_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	p "github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/builder"
+	"github.com/karitham/prosemirror/schema"
+	"github.com/karitham/prosemirror/tables"
+	"github.com/karitham/prosemirror/transform"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	s = p.Must(p.NewSchema(schema.AddTableNodes(schema.DefaultSpec, "block+", "block")))
+	b = builder.New(s)
+
+	doc, table, tr, para = b.Node("doc"), b.Node("table"), b.Node("table_row"), b.Node("paragraph")
+	td, th               = b.Node("table_cell"), b.Node("table_header")
+)
+
+func assertDoc(t *testing.T, want, got p.Node) {
+	t.Helper()
+
+	w, err := json.Marshal(want)
+	assert.NoError(t, err)
+	g, err := json.Marshal(got)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(w), string(g))
+}
+
+func TestTableMap(t *testing.T) {
+	t.Run("simple", func(t *testing.T) {
+		m, err := tables.NewTableMap(table(tr(td(para("a")), td(para("b"))), tr(td(para("c")), td(para("d")))).Node)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, tables.TableMap{Width: 2, Height: 2, Map: []int{1, 6, 13, 18}}, m)
+	})
+
+	t.Run("spans", func(t *testing.T) {
+		// a a b
+		// a a c
+		// d e c
+		tbl := table(
+			tr(td(map[string]any{"colspan": 2, "rowspan": 2}, para("a")), td(para("b"))),
+			tr(td(map[string]any{"rowspan": 2}, para("c"))),
+			tr(td(para("d")), td(para("e"))),
+		)
+
+		m, err := tables.NewTableMap(tbl.Node)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Empty(t, m.Problems)
+		assert.Equal(t, []int{1, 1, 6, 1, 1, 13, 20, 25, 13}, m.Map)
+
+		r, err := m.FindCell(1)
+		if assert.NoError(t, err) {
+			assert.Equal(t, tables.Rect{Left: 0, Top: 0, Right: 2, Bottom: 2}, r)
+		}
+
+		next, ok, err := m.NextCell(1, tables.Horizontal, 1)
+		if assert.NoError(t, err) && assert.True(t, ok) {
+			assert.Equal(t, 6, next)
+		}
+
+		_, ok, err = m.NextCell(13, tables.Vertical, 1)
+		if assert.NoError(t, err) {
+			assert.False(t, ok)
+		}
+
+		r, err = m.RectBetween(20, 6)
+		if assert.NoError(t, err) {
+			assert.Equal(t, tables.Rect{Left: 0, Top: 0, Right: 3, Bottom: 3}, r)
+		}
+
+		assert.Equal(t, []int{13, 25}, m.CellsInRect(tables.Rect{Left: 1, Top: 1, Right: 3, Bottom: 3}))
+		assert.Equal(t, 13, m.PositionAt(1, 0, tbl.Node))
+		assert.Equal(t, 20, m.PositionAt(2, 0, tbl.Node))
+
+		_, err = m.FindCell(2)
+		assert.Error(t, err)
+	})
+
+	t.Run("problems", func(t *testing.T) {
+		m, err := tables.NewTableMap(table(
+			tr(td(para("a")), td(map[string]any{"rowspan": 3}, para("b"))),
+			tr(td(para("c")), td(para("d"))),
+		).Node)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, []tables.Problem{
+			{Type: tables.ProblemOverlongRowspan, Pos: 6, N: 1},
+			{Type: tables.ProblemMissing, Row: 0, N: 1},
+		}, m.Problems)
+
+		m, err = tables.NewTableMap(table(
+			tr(td(map[string]any{"colwidth": []int{100}}, para("a")), td(para("b"))),
+			tr(td(map[string]any{"colwidth": []int{100}}, para("c"))),
+			tr(td(map[string]any{"colwidth": []int{200}}, para("e")), td(para("f"))),
+		).Node)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, []tables.Problem{
+			{Type: tables.ProblemColwidthMismatch, Pos: 20, Colwidth: []int{100}},
+			{Type: tables.ProblemMissing, Row: 1, N: 1},
+		}, m.Problems)
+
+		_, err = tables.NewTableMap(para("a").Node)
+		assert.Error(t, err)
+	})
+}
+
+func TestFixTables(t *testing.T) {
+	before := doc(
+		table(
+			tr(td(para("a")), td(para("b"))),
+			tr(td(para("c"))),
+		),
+		table(
+			tr(td(para("a")), td(map[string]any{"rowspan": 3}, para("b"))),
+			tr(td(para("c")), td(para("d"))),
+		),
+	)
+
+	tf := transform.New(before.Node)
+	changed, err := tables.FixTables(tf)
+	if !assert.NoError(t, err) || !assert.True(t, changed) {
+		return
+	}
+
+	want := doc(
+		table(
+			tr(td(para("a")), td(para("b"))),
+			tr(td(para("c")), td(para())),
+		),
+		// cells missing from the first row are added at its start
+		table(
+			tr(td(para()), td(para("a")), td(map[string]any{"rowspan": 2}, para("b"))),
+			tr(td(para("c")), td(para("d"))),
+		),
+	)
+	assertDoc(t, want.Node, tf.Doc)
+
+	changed, err = tables.FixTables(transform.New(tf.Doc))
+	if assert.NoError(t, err) {
+		assert.False(t, changed)
+	}
+}
@@ -0,0 +1,186 @@
+package tables
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/karitham/prosemirror"
+)
+
+// TableRole returns the table role of a node type, from the "tableRole" of its
+// spec's Extra: "table", "row", "cell" or "header_cell", or "" for other nodes.
+func TableRole(typ prosemirror.NodeType) string {
+	role, _ := typ.Spec.Extra["tableRole"].(string)
+	return role
+}
+
+// TableNodeTypes returns the node types of a schema with a table role, by role.
+func TableNodeTypes(s prosemirror.Schema) map[string]prosemirror.NodeType {
+	types := map[string]prosemirror.NodeType{}
+	for _, typ := range s.Nodes {
+		if role := TableRole(typ); role != "" {
+			types[role] = typ
+		}
+	}
+
+	return types
+}
+
+// cellAttrs are the attributes of a cell used to lay out tables.
+type cellAttrs struct {
+	colspan  int
+	rowspan  int
+	colwidth []int
+}
+
+func readCellAttrs(cell prosemirror.Node) cellAttrs {
+	return cellAttrs{
+		colspan:  intAttr(cell.Attrs["colspan"]),
+		rowspan:  intAttr(cell.Attrs["rowspan"]),
+		colwidth: intsAttr(cell.Attrs["colwidth"]),
+	}
+}
+
+// freshColwidth returns a copy of the column widths of a cell, with zeros
+// for cells without widths.
+func (a cellAttrs) freshColwidth() []int {
+	if a.colwidth != nil {
+		return slices.Clone(a.colwidth)
+	}
+
+	return make([]int, a.colspan)
+}
+
+// intAttr reads a span attribute, which is decoded as a float64 from JSON.
+func intAttr(v any) int {
+	if n, ok := prosemirror.IntAttr(v); ok {
+		return n
+	}
+
+	return 1
+}
+
+// intsAttr reads a colwidth attribute.
+func intsAttr(v any) []int {
+	switch v := v.(type) {
+	case []int:
+		return v
+	case []any:
+		ints := make([]int, len(v))
+		for i, w := range v {
+			if f, ok := w.(float64); ok {
+				ints[i] = int(f)
+			} else if n, ok := w.(int); ok {
+				ints[i] = n
+			}
+		}
+
+		return ints
+	}
+
+	return nil
+}
+
+// withAttrs returns a copy of the attributes of a cell with the given ones changed.
+func withAttrs(cell prosemirror.Node, changes map[string]any) map[string]any {
+	attrs := maps.Clone(cell.Attrs)
+	if attrs == nil {
+		attrs = map[string]any{}
+	}
+
+	maps.Copy(attrs, changes)
+	return attrs
+}
+
+// colwidthValue returns the colwidth attribute holding the given widths,
+// which is nil without any width.
+func colwidthValue(widths []int) any {
+	if !slices.ContainsFunc(widths, func(w int) bool { return w > 0 }) {
+		return nil
+	}
+
+	return widths
+}
+
+// removeColSpan returns the attributes of a cell with n columns removed at pos.
+func removeColSpan(cell prosemirror.Node, pos, n int) map[string]any {
+	a := readCellAttrs(cell)
+	changes := map[string]any{"colspan": a.colspan - n}
+	if a.colwidth != nil {
+		widths := slices.Clone(a.colwidth)
+		changes["colwidth"] = colwidthValue(slices.Delete(widths, pos, min(pos+n, len(widths))))
+	}
+
+	return withAttrs(cell, changes)
+}
+
+// addColSpan returns the attributes of a cell with n columns added at pos.
+func addColSpan(cell prosemirror.Node, pos, n int) map[string]any {
+	a := readCellAttrs(cell)
+	changes := map[string]any{"colspan": a.colspan + n}
+	if a.colwidth != nil {
+		widths := slices.Clone(a.colwidth)
+		changes["colwidth"] = slices.Insert(widths, min(pos, len(widths)), make([]int, n)...)
+	}
+
+	return withAttrs(cell, changes)
+}
+
+// TableRect is a rectangle of cells in a table of a document.
+type TableRect struct {
+	Rect
+
+	// The map of the table.
+	Map TableMap
+
+	// The table node.
+	Table prosemirror.Node
+
+	// The position of the start of the table content in the document.
+	TableStart int
+}
+
+// TableAt returns the rectangle covering the whole table at the given position of a document.
+func TableAt(doc prosemirror.Node, tablePos int) (TableRect, error) {
+	table := doc.NodeAt(tablePos)
+	if table == nil || TableRole(table.Type) != "table" {
+		return TableRect{}, fmt.Errorf("no table at position %d", tablePos)
+	}
+
+	m, err := NewTableMap(*table)
+	if err != nil {
+		return TableRect{}, err
+	}
+
+	return TableRect{
+		Rect:       Rect{Right: m.Width, Bottom: m.Height},
+		Map:        m,
+		Table:      *table,
+		TableStart: tablePos + 1,
+	}, nil
+}
+
+// CellAt returns the rectangle covered by the cell at the given position of a document.
+func CellAt(doc prosemirror.Node, cellPos int) (TableRect, error) {
+	rp, err := doc.Resolve(cellPos)
+	if err != nil {
+		return TableRect{}, err
+	}
+
+	if rp.Depth < 2 || TableRole(rp.Node(rp.Depth-1).Type) != "table" {
+		return TableRect{}, fmt.Errorf("no cell at position %d", cellPos)
+	}
+
+	r, err := TableAt(doc, rp.Before(rp.Depth-1))
+	if err != nil {
+		return TableRect{}, err
+	}
+
+	r.Rect, err = r.Map.FindCell(cellPos - r.TableStart)
+	if err != nil {
+		return TableRect{}, err
+	}
+
+	return r, nil
+}
@@ -0,0 +1,180 @@
+package transform
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/go-json-experiment/json"
+
+	"github.com/karitham/prosemirror"
+)
+
+var (
+	_ Applier = (*AttrStep)(nil)
+	_ Applier = (*DocAttrStep)(nil)
+)
+
+func init() {
+	RegisterTransformer("attr", func() Applier {
+		return new(AttrStep)
+	})
+	RegisterTransformer("docAttr", func() Applier {
+		return new(DocAttrStep)
+	})
+}
+
+// AttrStep updates an attribute in a specific node.
+type AttrStep struct {
+	Type  string `json:"stepType"`
+	Pos   int    `json:"pos"`
+	Attr  string `json:"attr"`
+	Value any    `json:"value"`
+}
+
+// NewAttrStep creates a step that sets the attribute `attr` of the node at `pos` to `value`.
+func NewAttrStep(pos int, attr string, value any) *AttrStep {
+	return &AttrStep{
+		Type:  "attr",
+		Pos:   pos,
+		Attr:  attr,
+		Value: value,
+	}
+}
+
+func (s *AttrStep) String() string {
+	return fmt.Sprintf("AttrStep{Pos: %d, Attr: %s, Value: %v}", s.Pos, s.Attr, s.Value)
+}
+
+func (s *AttrStep) UnmarshalJSON(data []byte) error {
+	type a AttrStep
+	aux := a{}
+
+	if err := json.Unmarshal(data, &aux, json.RejectUnknownMembers(true)); err != nil {
+		return fmt.Errorf("failed to decode attr step (%s): %w", string(data), err)
+	}
+
+	*s = AttrStep(aux)
+	return nil
+}
+
+func (s *AttrStep) MarshalJSON() ([]byte, error) {
+	type a AttrStep
+	aux := a(*s)
+
+	return json.Marshal(aux)
+}
+
+func (s *AttrStep) Apply(doc prosemirror.Node) (prosemirror.Node, error) {
+	node := doc.NodeAt(s.Pos)
+	if node == nil {
+		return prosemirror.Node{}, fmt.Errorf("no node at attribute step's position %d", s.Pos)
+	}
+
+	attrs := maps.Clone(node.Attrs)
+	if attrs == nil {
+		attrs = map[string]any{}
+	}
+
+	attrs[s.Attr] = s.Value
+
+	updated, err := node.Type.CreateUnchecked(attrs, node.Marks)
+	if err != nil {
+		return prosemirror.Node{}, fmt.Errorf("failed to create updated node: %w", err)
+	}
+
+	openEnd := 1
+	if node.IsLeaf() {
+		openEnd = 0
+	}
+
+	return doc.Replace(s.Pos, s.Pos+1, prosemirror.NewSlice(prosemirror.NewFragment(updated), 0, openEnd))
+}
+
+func (s *AttrStep) GetMap() StepMap {
+	return EmptyStepMap
+}
+
+func (s *AttrStep) Invert(doc prosemirror.Node) (Applier, error) {
+	node := doc.NodeAt(s.Pos)
+	if node == nil {
+		return nil, fmt.Errorf("no node at attribute step's position %d", s.Pos)
+	}
+
+	return NewAttrStep(s.Pos, s.Attr, node.Attrs[s.Attr]), nil
+}
+
+func (s *AttrStep) Map(m Mappable) Applier {
+	pos := m.MapResult(s.Pos, 1)
+	if pos.DeletedAfter() {
+		return nil
+	}
+
+	return NewAttrStep(pos.Pos, s.Attr, s.Value)
+}
+
+// DocAttrStep updates an attribute in the doc node.
+type DocAttrStep struct {
+	Type  string `json:"stepType"`
+	Attr  string `json:"attr"`
+	Value any    `json:"value"`
+}
+
+// NewDocAttrStep creates a step that sets the attribute `attr` of the document to `value`.
+func NewDocAttrStep(attr string, value any) *DocAttrStep {
+	return &DocAttrStep{
+		Type:  "docAttr",
+		Attr:  attr,
+		Value: value,
+	}
+}
+
+func (s *DocAttrStep) String() string {
+	return fmt.Sprintf("DocAttrStep{Attr: %s, Value: %v}", s.Attr, s.Value)
+}
+
+func (s *DocAttrStep) UnmarshalJSON(data []byte) error {
+	type a DocAttrStep
+	aux := a{}
+
+	if err := json.Unmarshal(data, &aux, json.RejectUnknownMembers(true)); err != nil {
+		return fmt.Errorf("failed to decode doc attr step (%s): %w", string(data), err)
+	}
+
+	*s = DocAttrStep(aux)
+	return nil
+}
+
+func (s *DocAttrStep) MarshalJSON() ([]byte, error) {
+	type a DocAttrStep
+	aux := a(*s)
+
+	return json.Marshal(aux)
+}
+
+func (s *DocAttrStep) Apply(doc prosemirror.Node) (prosemirror.Node, error) {
+	attrs := maps.Clone(doc.Attrs)
+	if attrs == nil {
+		attrs = map[string]any{}
+	}
+
+	attrs[s.Attr] = s.Value
+
+	updated, err := doc.Type.CreateUnchecked(attrs, doc.Marks, doc.Content.Content...)
+	if err != nil {
+		return prosemirror.Node{}, fmt.Errorf("failed to create updated document: %w", err)
+	}
+
+	return updated, nil
+}
+
+func (s *DocAttrStep) GetMap() StepMap {
+	return EmptyStepMap
+}
+
+func (s *DocAttrStep) Invert(doc prosemirror.Node) (Applier, error) {
+	return NewDocAttrStep(s.Attr, doc.Attrs[s.Attr]), nil
+}
+
+func (s *DocAttrStep) Map(Mappable) Applier {
+	return s
+}
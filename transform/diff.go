@@ -0,0 +1,360 @@
+package transform
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"github.com/go-json-experiment/json"
+
+	"github.com/karitham/prosemirror"
+)
+
+// maxDiffCells bounds the size of the table used to align two sequences.
+// Larger changes fall back to replacing the whole changed range.
+const maxDiffCells = 1 << 22
+
+// Diff computes the steps that transform the document `a` into the document `b`.
+//
+// Unchanged nodes are left alone. Nodes whose attributes changed produce AttrSteps,
+// textblocks are diffed character by character, producing ReplaceSteps for the changed
+// text and MarkSteps for the changed marks, and other changed content is replaced as a whole.
+// Steps are produced from the end of the document to its start, so that the position of
+// every step is also a position in `a`.
+func Diff(a, b prosemirror.Node) ([]Step, error) {
+	if !a.Type.Eq(b.Type) {
+		return nil, fmt.Errorf("cannot diff documents of different types %s and %s", a.Type.Name, b.Type.Name)
+	}
+
+	tr := New(a)
+	if _, changed := a.Content.FindDiffStart(b.Content, 0); changed {
+		if err := diffContent(tr, a, b, 0); err != nil {
+			// the fine-grained diff went through a state the schema doesn't allow,
+			// replace the whole content instead.
+			tr = New(a)
+			if err := tr.Step(NewReplaceStep(0, a.Content.Size, prosemirror.NewSlice(b.Content, 0, 0), false)); err != nil {
+				return nil, fmt.Errorf("failed to replace document content: %w", err)
+			}
+		}
+	}
+
+	for _, attr := range changedAttrs(a, b) {
+		if err := tr.SetDocAttribute(attr, attrsOf(b)[attr]); err != nil {
+			return nil, err
+		}
+	}
+
+	return tr.Steps, nil
+}
+
+// diffNode diffs the node `a`, found at `pos` in the transform's document, with the node `b`.
+func diffNode(tr *Transform, a, b prosemirror.Node, pos int) error {
+	if a.Eq(b) {
+		return nil
+	}
+
+	replace := func() error {
+		return tr.Step(NewReplaceStep(pos, pos+a.NodeSize(), prosemirror.NewSlice(prosemirror.NewFragment(b), 0, 0), false))
+	}
+
+	switch {
+	case a.IsText() || b.IsText() || a.IsLeaf() != b.IsLeaf():
+		return replace()
+	case a.Type.Eq(b.Type) && prosemirror.SameMarkSet(a.Marks, b.Marks):
+		for _, attr := range changedAttrs(a, b) {
+			if err := tr.SetNodeAttribute(pos, attr, attrsOf(b)[attr]); err != nil {
+				return err
+			}
+		}
+	case a.InlineContent() != b.InlineContent():
+		return replace()
+	default:
+		if err := tr.SetNodeMarkup(pos, &b.Type, b.Attrs, b.Marks); err != nil {
+			return replace()
+		}
+	}
+
+	if a.IsLeaf() {
+		return nil
+	}
+
+	return diffContent(tr, a, b, pos+1)
+}
+
+// diffContent diffs the content of `a`, starting at `start` in the transform's document,
+// with the content of `b`.
+func diffContent(tr *Transform, a, b prosemirror.Node, start int) error {
+	if a.InlineContent() {
+		return diffInline(tr, a.Content, b.Content, start)
+	}
+
+	return diffBlocks(tr, a.Content, b.Content, start)
+}
+
+// diffBlocks aligns the children of two block fragments, diffs the children that
+// kept their place, and replaces the ones that didn't.
+func diffBlocks(tr *Transform, a, b prosemirror.Fragment, start int) error {
+	offsets := childOffsets(a)
+	gaps := diffSequences(a.Content, b.Content, prosemirror.Node.Eq)
+
+	for k := len(gaps) - 1; k >= 0; k-- {
+		g := gaps[k]
+		if g.aTo-g.aFrom == g.bTo-g.bFrom && sameTypes(a.Content[g.aFrom:g.aTo], b.Content[g.bFrom:g.bTo]) {
+			for i := g.aTo - 1; i >= g.aFrom; i-- {
+				if err := diffNode(tr, a.Content[i], b.Content[g.bFrom+i-g.aFrom], start+offsets[i]); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		slice := prosemirror.NewSlice(b.CutByIndex(g.bFrom, g.bTo), 0, 0)
+		if err := tr.Step(NewReplaceStep(start+offsets[g.aFrom], start+offsets[g.aTo], slice, false)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inlineUnit is a single character of text, or a single inline node.
+type inlineUnit struct {
+	char  rune
+	node  *prosemirror.Node
+	size  int
+	marks []prosemirror.Mark
+}
+
+func inlineUnits(f prosemirror.Fragment) []inlineUnit {
+	var units []inlineUnit
+	for i := range f.Content {
+		child := &f.Content[i]
+		if !child.IsText() {
+			units = append(units, inlineUnit{node: child, size: child.NodeSize(), marks: child.Marks})
+			continue
+		}
+
+		for _, r := range child.Text {
+			units = append(units, inlineUnit{char: r, size: prosemirror.UTF16Len(string(r)), marks: child.Marks})
+		}
+	}
+
+	return units
+}
+
+// unitEq compares two inline units, ignoring their marks.
+func unitEq(a, b inlineUnit) bool {
+	if a.node == nil || b.node == nil {
+		return a.node == nil && b.node == nil && a.char == b.char
+	}
+
+	return a.node.Mark(nil).Eq(b.node.Mark(nil))
+}
+
+// diffInline diffs the inline content of a textblock: the text is replaced where
+// it changed, then the marks of the whole content are updated.
+func diffInline(tr *Transform, a, b prosemirror.Fragment, start int) error {
+	aUnits, bUnits := inlineUnits(a), inlineUnits(b)
+	aOffsets, bOffsets := unitOffsets(aUnits), unitOffsets(bUnits)
+
+	gaps := diffSequences(aUnits, bUnits, unitEq)
+	for k := len(gaps) - 1; k >= 0; k-- {
+		g := gaps[k]
+		slice := prosemirror.NewSlice(b.Cut(bOffsets[g.bFrom], bOffsets[g.bTo]), 0, 0)
+		if err := tr.Step(NewReplaceStep(start+aOffsets[g.aFrom], start+aOffsets[g.aTo], slice, false)); err != nil {
+			return err
+		}
+	}
+
+	// the text now matches, so positions in `b` are positions in the current content.
+	parent := tr.Doc.NodeAt(start - 1)
+	if parent == nil {
+		return fmt.Errorf("no textblock at position %d", start-1)
+	}
+
+	current := inlineUnits(parent.Content)
+	if len(current) != len(bUnits) {
+		return fmt.Errorf("inline content at %d does not match after replacing text", start)
+	}
+
+	for _, remove := range []bool{true, false} {
+		for _, mark := range distinctMarks(current, bUnits) {
+			from := -1
+			for i := 0; i <= len(bUnits); i++ {
+				differs := false
+				if i < len(bUnits) {
+					if remove {
+						differs = mark.IsInSet(current[i].marks) && !mark.IsInSet(bUnits[i].marks)
+					} else {
+						differs = !mark.IsInSet(current[i].marks) && mark.IsInSet(bUnits[i].marks)
+					}
+				}
+
+				switch {
+				case differs && from < 0:
+					from = i
+				case !differs && from >= 0:
+					var err error
+					if remove {
+						err = tr.RemoveMark(start+bOffsets[from], start+bOffsets[i], mark)
+					} else {
+						err = tr.AddMark(start+bOffsets[from], start+bOffsets[i], mark)
+					}
+
+					if err != nil {
+						return err
+					}
+
+					from = -1
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func distinctMarks(sets ...[]inlineUnit) []prosemirror.Mark {
+	var marks []prosemirror.Mark
+	for _, units := range sets {
+		for _, u := range units {
+			for _, m := range u.marks {
+				if !m.IsInSet(marks) {
+					marks = append(marks, m)
+				}
+			}
+		}
+	}
+
+	return marks
+}
+
+// gap is a range of `a` that has to be replaced by a range of `b`.
+type gap struct {
+	aFrom, aTo int
+	bFrom, bTo int
+}
+
+// diffSequences returns the ranges in which the two sequences differ, in order.
+// Elements outside the returned gaps are equal and kept in place.
+func diffSequences[T any](a, b []T, eq func(T, T) bool) []gap {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && eq(a[prefix], b[prefix]) {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && eq(a[len(a)-1-suffix], b[len(b)-1-suffix]) {
+		suffix++
+	}
+
+	aMid, bMid := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(aMid) == 0 && len(bMid) == 0 {
+		return nil
+	}
+
+	whole := []gap{{prefix, len(a) - suffix, prefix, len(b) - suffix}}
+	if len(aMid) == 0 || len(bMid) == 0 || len(aMid)*len(bMid) > maxDiffCells {
+		return whole
+	}
+
+	// longest common subsequence table of the suffixes of both middles.
+	n, m := len(aMid), len(bMid)
+	table := make([]int, (n+1)*(m+1))
+	at := func(i, j int) *int { return &table[i*(m+1)+j] }
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if eq(aMid[i], bMid[j]) {
+				*at(i, j) = *at(i+1, j+1) + 1
+			} else {
+				*at(i, j) = max(*at(i+1, j), *at(i, j+1))
+			}
+		}
+	}
+
+	var gaps []gap
+	i, j, gi, gj := 0, 0, 0, 0
+	flush := func() {
+		if gi < i || gj < j {
+			gaps = append(gaps, gap{prefix + gi, prefix + i, prefix + gj, prefix + j})
+		}
+	}
+
+	for i < n && j < m {
+		switch {
+		case eq(aMid[i], bMid[j]):
+			flush()
+			i, j = i+1, j+1
+			gi, gj = i, j
+		case *at(i+1, j) >= *at(i, j+1):
+			i++
+		default:
+			j++
+		}
+	}
+
+	i, j = n, m
+	flush()
+
+	return gaps
+}
+
+func childOffsets(f prosemirror.Fragment) []int {
+	offsets := make([]int, 0, len(f.Content)+1)
+	pos := 0
+	for _, child := range f.Content {
+		offsets = append(offsets, pos)
+		pos += child.NodeSize()
+	}
+
+	return append(offsets, pos)
+}
+
+func unitOffsets(units []inlineUnit) []int {
+	offsets := make([]int, 0, len(units)+1)
+	pos := 0
+	for _, u := range units {
+		offsets = append(offsets, pos)
+		pos += u.size
+	}
+
+	return append(offsets, pos)
+}
+
+func sameTypes(a, b []prosemirror.Node) bool {
+	return slices.EqualFunc(a, b, func(a, b prosemirror.Node) bool {
+		return a.Type.Eq(b.Type)
+	})
+}
+
+func attrsOf(n prosemirror.Node) map[string]any {
+	if n.Attrs == nil {
+		return n.Type.DefaultAttrs
+	}
+
+	return n.Attrs
+}
+
+// changedAttrs returns the sorted names of the attributes that differ between the two nodes.
+func changedAttrs(a, b prosemirror.Node) []string {
+	aAttrs, bAttrs := attrsOf(a), attrsOf(b)
+
+	var changed []string
+	for k, v := range bAttrs {
+		if old, ok := aAttrs[k]; !ok || !valueEq(old, v) {
+			changed = append(changed, k)
+		}
+	}
+
+	slices.Sort(changed)
+	return changed
+}
+
+// valueEq compares attribute values by their JSON encoding, so that numbers
+// decoded from JSON compare equal to the same numbers set from Go.
+func valueEq(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
@@ -0,0 +1,107 @@
+package transform_test
+
+import (
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/transform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	type test struct {
+		name  string
+		a     string
+		b     string
+		steps []string
+	}
+
+	tests := []test{
+		{
+			name:  "same document",
+			a:     `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"Hello"}]}]}`,
+			b:     `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"Hello"}]}]}`,
+			steps: nil,
+		},
+		{
+			name: "text changed in a paragraph",
+			a:    `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"Hello World"}]}]}`,
+			b:    `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"Hello there World!"}]}]}`,
+			steps: []string{
+				`{"stepType":"replace","from":12,"to":12,"slice":{"content":[{"type":"text","text":"!"}]}}`,
+				`{"stepType":"replace","from":7,"to":7,"slice":{"content":[{"type":"text","text":"there "}]}}`,
+			},
+		},
+		{
+			name: "mark added",
+			a:    `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"Hello World"}]}]}`,
+			b:    `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"Hello "},{"type":"text","marks":[{"type":"em"}],"text":"World"}]}]}`,
+			steps: []string{
+				`{"stepType":"addMark","from":7,"to":12,"mark":{"type":"em"}}`,
+			},
+		},
+		{
+			name: "heading level changed",
+			a:    `{"type":"doc","content":[{"type":"heading","attrs":{"level":1},"content":[{"type":"text","text":"Title"}]},{"type":"paragraph"}]}`,
+			b:    `{"type":"doc","content":[{"type":"heading","attrs":{"level":3},"content":[{"type":"text","text":"Title"}]},{"type":"paragraph"}]}`,
+			steps: []string{
+				`{"stepType":"attr","pos":0,"attr":"level","value":3}`,
+			},
+		},
+		{
+			name: "paragraph inserted",
+			a:    `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]},{"type":"paragraph","content":[{"type":"text","text":"three"}]}]}`,
+			b:    `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]},{"type":"paragraph","content":[{"type":"text","text":"three"}]}]}`,
+			steps: []string{
+				`{"stepType":"replace","from":5,"to":5,"slice":{"content":[{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}}`,
+			},
+		},
+		{
+			name: "paragraph turned into a code block",
+			a:    `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"x = 1"}]}]}`,
+			b:    `{"type":"doc","content":[{"type":"code_block","content":[{"type":"text","text":"x = 2"}]}]}`,
+		},
+		{
+			name: "emoji edit",
+			a:    `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"a😀b"}]}]}`,
+			b:    `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"a😀c"}]}]}`,
+			steps: []string{
+				`{"stepType":"replace","from":4,"to":5,"slice":{"content":[{"type":"text","text":"c"}]}}`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := fromJSON[prosemirror.Node](tc.a)
+			b := fromJSON[prosemirror.Node](tc.b)
+
+			steps, err := transform.Diff(a, b)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			if tc.steps != nil || len(steps) == 0 {
+				var want []transform.Step
+				for _, s := range tc.steps {
+					want = append(want, fromJSON[transform.Step](s))
+				}
+
+				if !assert.Equal(t, spew.Sdump(want), spew.Sdump(steps)) {
+					return
+				}
+			}
+
+			doc := a
+			for _, s := range steps {
+				doc, err = s.Apply(doc)
+				if !assert.NoError(t, err) {
+					return
+				}
+			}
+
+			assert.Equal(t, spew.Sdump(b), spew.Sdump(doc))
+		})
+	}
+}
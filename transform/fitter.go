@@ -0,0 +1,851 @@
+package transform
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/karitham/prosemirror"
+)
+
+// ReplaceStepFor creates a step that replaces the range between `from` and `to`
+// with the given slice, fitting the slice into the document structure.
+// Returns nil when the replacement would be a no-op, and an error when no
+// valid step could be found.
+func ReplaceStepFor(doc prosemirror.Node, from, to int, slice prosemirror.Slice) (Applier, error) {
+	if from == to && slice.Size() == 0 {
+		return nil, nil
+	}
+
+	rFrom, err := doc.Resolve(from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %d: %w", from, err)
+	}
+
+	rTo, err := doc.Resolve(to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %d: %w", to, err)
+	}
+
+	if fitsTrivially(rFrom, rTo, slice) {
+		return NewReplaceStep(from, to, slice, false), nil
+	}
+
+	step, err := newFitter(rFrom, rTo, slice).fit()
+	if err != nil {
+		return nil, err
+	}
+
+	if step == nil {
+		return nil, nil
+	}
+
+	return step, nil
+}
+
+// fitsTrivially reports whether a slice can be placed at the given range without any fitting.
+func fitsTrivially(from, to prosemirror.ResolvedPos, slice prosemirror.Slice) bool {
+	return slice.OpenStart == 0 && slice.OpenEnd == 0 && from.Start(from.Depth) == to.Start(to.Depth) &&
+		from.Parent().CanReplace(from.Index(from.Depth), to.Index(to.Depth), slice.Content)
+}
+
+type frontierEntry struct {
+	typ   prosemirror.NodeType
+	match *prosemirror.ContentMatch
+}
+
+type fittable struct {
+	sliceDepth    int
+	frontierDepth int
+	parent        *prosemirror.Node
+	inject        *prosemirror.Fragment
+	wrap          []prosemirror.NodeType
+}
+
+type closeLevel struct {
+	depth int
+	fit   prosemirror.Fragment
+	move  prosemirror.ResolvedPos
+}
+
+// fitter is used to find a valid way to place a slice into a document.
+// It tracks the open nodes along the start of the placed content (the frontier),
+// places the unplaced nodes of the slice into it one by one, opening or dropping
+// nodes as needed, and closes everything off against the end of the replaced range.
+type fitter struct {
+	from     prosemirror.ResolvedPos
+	to       prosemirror.ResolvedPos
+	unplaced prosemirror.Slice
+
+	frontier []frontierEntry
+	placed   prosemirror.Fragment
+}
+
+func newFitter(from, to prosemirror.ResolvedPos, unplaced prosemirror.Slice) *fitter {
+	f := &fitter{from: from, to: to, unplaced: unplaced}
+
+	for i := 0; i <= from.Depth; i++ {
+		node := from.Node(i)
+		f.frontier = append(f.frontier, frontierEntry{
+			typ:   node.Type,
+			match: node.ContentMatchAt(from.IndexAfter(i)),
+		})
+	}
+
+	for i := from.Depth; i > 0; i-- {
+		f.placed = prosemirror.NewFragment(from.Node(i).Copy(f.placed))
+	}
+
+	return f
+}
+
+func (f *fitter) depth() int {
+	return len(f.frontier) - 1
+}
+
+func (f *fitter) fit() (Applier, error) {
+	for f.unplaced.Size() > 0 {
+		if fit := f.findFittable(); fit != nil {
+			if err := f.placeNodes(*fit); err != nil {
+				return nil, err
+			}
+		} else if !f.openMore() {
+			f.dropNode()
+		}
+	}
+
+	// When there's inline content directly after the frontier _and_
+	// directly after `f.to`, we must generate a ReplaceAround step that
+	// pulls that content into the node after the frontier. That means the
+	// fitting must be done to the end of the textblock node after `f.to`,
+	// not `f.to` itself.
+	moveInline := f.mustMoveInline()
+	placedSize := f.placed.Size - f.depth() - f.from.Depth
+
+	target := f.to
+	if moveInline >= 0 {
+		var err error
+		target, err = f.from.Doc().Resolve(moveInline)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	to, ok, err := f.close(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("no valid way to fit the slice between %d and %d", f.from.Pos, f.to.Pos)
+	}
+
+	// If closing to `to` succeeded, create a step
+	content, openStart, openEnd := f.placed, f.from.Depth, to.Depth
+	// Normalize by dropping open parent nodes
+	for openStart > 0 && openEnd > 0 && content.ChildCount() == 1 {
+		content = content.FirstChild().Content
+		openStart--
+		openEnd--
+	}
+
+	slice := prosemirror.NewSlice(content, openStart, openEnd)
+	if moveInline >= 0 {
+		return NewReplaceAroundStep(f.from.Pos, moveInline, f.to.Pos, f.to.End(f.to.Depth), slice, placedSize, false), nil
+	}
+
+	// Don't generate no-op steps
+	if slice.Size() > 0 || f.from.Pos != f.to.Pos {
+		return NewReplaceStep(f.from.Pos, to.Pos, slice, false), nil
+	}
+
+	return nil, nil
+}
+
+// findFittable finds a position on the start spine of `f.unplaced` that has
+// content that can be moved somewhere on the frontier. Returns nil if no such
+// place exists.
+func (f *fitter) findFittable() *fittable {
+	startDepth := f.unplaced.OpenStart
+	cur, openEnd := f.unplaced.Content, f.unplaced.OpenEnd
+	for d := 0; d < startDepth; d++ {
+		node := cur.FirstChild()
+		if cur.ChildCount() > 1 {
+			openEnd = 0
+		}
+
+		if node.Type.Spec.Isolating && openEnd <= d {
+			startDepth = d
+			break
+		}
+
+		cur = node.Content
+	}
+
+	// Only try wrapping nodes (pass 2) after finding a place without
+	// wrapping failed.
+	for pass := 1; pass <= 2; pass++ {
+		sliceDepth := f.unplaced.OpenStart
+		if pass == 1 {
+			sliceDepth = startDepth
+		}
+
+		for ; sliceDepth >= 0; sliceDepth-- {
+			var fragment prosemirror.Fragment
+			var parent *prosemirror.Node
+			if sliceDepth > 0 {
+				parent = contentAt(f.unplaced.Content, sliceDepth-1).FirstChild()
+				fragment = parent.Content
+			} else {
+				fragment = f.unplaced.Content
+			}
+
+			first := fragment.FirstChild()
+			for frontierDepth := f.depth(); frontierDepth >= 0; frontierDepth-- {
+				typ, match := f.frontier[frontierDepth].typ, f.frontier[frontierDepth].match
+
+				if pass == 1 {
+					if first != nil {
+						if match.MatchType(first.Type) != nil {
+							return &fittable{sliceDepth: sliceDepth, frontierDepth: frontierDepth, parent: parent}
+						}
+
+						if inject := match.FillBefore(prosemirror.NewFragment(*first), false, 0); inject != nil {
+							return &fittable{sliceDepth: sliceDepth, frontierDepth: frontierDepth, parent: parent, inject: inject}
+						}
+					} else if parent != nil && typ.CompatibleContent(parent.Type) {
+						return &fittable{sliceDepth: sliceDepth, frontierDepth: frontierDepth, parent: parent}
+					}
+				} else if first != nil {
+					if wrap := match.FindWrapping(first.Type); wrap != nil {
+						return &fittable{sliceDepth: sliceDepth, frontierDepth: frontierDepth, parent: parent, wrap: wrap}
+					}
+				}
+
+				// Don't continue looking further up if the parent node
+				// would fit here.
+				if parent != nil && match.MatchType(parent.Type) != nil {
+					break
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (f *fitter) openMore() bool {
+	content, openStart, openEnd := f.unplaced.Content, f.unplaced.OpenStart, f.unplaced.OpenEnd
+
+	inner := contentAt(content, openStart)
+	if inner.ChildCount() == 0 || inner.FirstChild().IsLeaf() {
+		return false
+	}
+
+	newOpenEnd := 0
+	if inner.Size+openStart >= content.Size-openEnd {
+		newOpenEnd = openStart + 1
+	}
+
+	f.unplaced = prosemirror.NewSlice(content, openStart+1, max(openEnd, newOpenEnd))
+	return true
+}
+
+func (f *fitter) dropNode() {
+	content, openStart, openEnd := f.unplaced.Content, f.unplaced.OpenStart, f.unplaced.OpenEnd
+
+	inner := contentAt(content, openStart)
+	if inner.ChildCount() <= 1 && openStart > 0 {
+		openAtEnd := content.Size-openStart <= openStart+inner.Size
+		if openAtEnd {
+			openEnd = openStart - 1
+		}
+
+		f.unplaced = prosemirror.NewSlice(dropFromFragment(content, openStart-1, 1), openStart-1, openEnd)
+	} else {
+		f.unplaced = prosemirror.NewSlice(dropFromFragment(content, openStart, 1), openStart, openEnd)
+	}
+}
+
+// placeNodes moves content from the unplaced slice at `sliceDepth` to the
+// frontier node at `frontierDepth`. Closes and opens frontier nodes as needed.
+func (f *fitter) placeNodes(fit fittable) error {
+	for f.depth() > fit.frontierDepth {
+		if err := f.closeFrontierNode(); err != nil {
+			return err
+		}
+	}
+
+	for _, w := range fit.wrap {
+		if err := f.openFrontierNode(w, nil, prosemirror.Fragment{}); err != nil {
+			return err
+		}
+	}
+
+	slice := f.unplaced
+	fragment := slice.Content
+	if fit.parent != nil {
+		fragment = fit.parent.Content
+	}
+
+	openStart := slice.OpenStart - fit.sliceDepth
+	taken, add := 0, []prosemirror.Node{}
+	match, typ := f.frontier[fit.frontierDepth].match, f.frontier[fit.frontierDepth].typ
+	if fit.inject != nil {
+		add = append(add, fit.inject.Content...)
+		match = match.MatchFragment(*fit.inject, -1, -1)
+	}
+
+	// Computes the amount of (end) open nodes at the end of the
+	// fragment. When 0, the parent is open, but no more. When
+	// negative, nothing is open.
+	openEndCount := (fragment.Size + fit.sliceDepth) - (slice.Content.Size - slice.OpenEnd)
+
+	// Scan over the fragment, fitting as many child nodes as
+	// possible.
+	for taken < fragment.ChildCount() {
+		next := fragment.Child(taken)
+		matches := match.MatchType(next.Type)
+		if matches == nil {
+			break
+		}
+
+		taken++
+		// Drop empty open nodes
+		if taken > 1 || openStart == 0 || next.Content.Size > 0 {
+			match = matches
+
+			nodeOpenStart, nodeOpenEnd := 0, -1
+			if taken == 1 {
+				nodeOpenStart = openStart
+			}
+
+			if taken == fragment.ChildCount() {
+				nodeOpenEnd = openEndCount
+			}
+
+			node, err := closeNodeStart(next.Mark(typ.AllowedMarks(next.Marks)), nodeOpenStart, nodeOpenEnd)
+			if err != nil {
+				return err
+			}
+
+			add = append(add, node)
+		}
+	}
+
+	toEnd := taken == fragment.ChildCount()
+	if !toEnd {
+		openEndCount = -1
+	}
+
+	f.placed = addToFragment(f.placed, fit.frontierDepth, prosemirror.NewFragment(add...))
+	f.frontier[fit.frontierDepth].match = match
+
+	// If the parent types match, and the entire node was moved, and
+	// it's not open, close this frontier node right away.
+	if toEnd && openEndCount < 0 && fit.parent != nil && fit.parent.Type.Eq(f.frontier[f.depth()].typ) && len(f.frontier) > 1 {
+		if err := f.closeFrontierNode(); err != nil {
+			return err
+		}
+	}
+
+	// Add new frontier nodes for any open nodes at the end.
+	cur := fragment
+	for i := 0; i < openEndCount; i++ {
+		node := cur.LastChild()
+		f.frontier = append(f.frontier, frontierEntry{typ: node.Type, match: node.ContentMatchAt(node.ChildCount())})
+		cur = node.Content
+	}
+
+	// Update `f.unplaced`. Drop the entire node from which we
+	// placed it (if we placed the entire node), or shift the content
+	// in it to remove the content that was moved.
+	switch {
+	case !toEnd:
+		f.unplaced = prosemirror.NewSlice(dropFromFragment(slice.Content, fit.sliceDepth, taken), slice.OpenStart, slice.OpenEnd)
+	case fit.sliceDepth == 0:
+		f.unplaced = prosemirror.Slice{}
+	default:
+		openEnd := fit.sliceDepth - 1
+		if openEndCount < 0 {
+			openEnd = slice.OpenEnd
+		}
+
+		f.unplaced = prosemirror.NewSlice(dropFromFragment(slice.Content, fit.sliceDepth-1, 1), fit.sliceDepth-1, openEnd)
+	}
+
+	return nil
+}
+
+func (f *fitter) mustMoveInline() int {
+	if !f.to.Parent().IsTextblock() {
+		return -1
+	}
+
+	top := f.frontier[f.depth()]
+	if !top.typ.IsTextblock() || contentAfterFits(f.to, f.to.Depth, top.typ, top.match, false) == nil {
+		return -1
+	}
+
+	if f.to.Depth == f.depth() {
+		if level := f.findCloseLevel(f.to); level != nil && level.depth == f.depth() {
+			return -1
+		}
+	}
+
+	depth := f.to.Depth
+	after := f.to.After(depth)
+	for depth > 1 {
+		depth--
+		if after != f.to.End(depth) {
+			break
+		}
+
+		after++
+	}
+
+	return after
+}
+
+func (f *fitter) findCloseLevel(to prosemirror.ResolvedPos) *closeLevel {
+scan:
+	for i := min(f.depth(), to.Depth); i >= 0; i-- {
+		match, typ := f.frontier[i].match, f.frontier[i].typ
+		dropInner := i < to.Depth && to.End(i+1) == to.Pos+(to.Depth-(i+1))
+
+		fit := contentAfterFits(to, i, typ, match, dropInner)
+		if fit == nil {
+			continue
+		}
+
+		for d := i - 1; d >= 0; d-- {
+			matches := contentAfterFits(to, d, f.frontier[d].typ, f.frontier[d].match, true)
+			if matches == nil || matches.ChildCount() > 0 {
+				continue scan
+			}
+		}
+
+		move := to
+		if dropInner {
+			var err error
+			move, err = to.Doc().Resolve(to.After(i + 1))
+			if err != nil {
+				continue
+			}
+		}
+
+		return &closeLevel{depth: i, fit: *fit, move: move}
+	}
+
+	return nil
+}
+
+func (f *fitter) close(to prosemirror.ResolvedPos) (prosemirror.ResolvedPos, bool, error) {
+	level := f.findCloseLevel(to)
+	if level == nil {
+		return prosemirror.ResolvedPos{}, false, nil
+	}
+
+	for f.depth() > level.depth {
+		if err := f.closeFrontierNode(); err != nil {
+			return prosemirror.ResolvedPos{}, false, err
+		}
+	}
+
+	if level.fit.ChildCount() > 0 {
+		f.placed = addToFragment(f.placed, level.depth, level.fit)
+	}
+
+	to = level.move
+	for d := level.depth + 1; d <= to.Depth; d++ {
+		node := to.Node(d)
+		add := node.Type.ContentMatch.FillBefore(node.Content, true, to.Index(d))
+		if add == nil {
+			return prosemirror.ResolvedPos{}, false, nil
+		}
+
+		if err := f.openFrontierNode(node.Type, node.Attrs, *add); err != nil {
+			return prosemirror.ResolvedPos{}, false, err
+		}
+	}
+
+	return to, true, nil
+}
+
+func (f *fitter) openFrontierNode(typ prosemirror.NodeType, attrs map[string]any, content prosemirror.Fragment) error {
+	top := &f.frontier[f.depth()]
+	top.match = top.match.MatchType(typ)
+
+	node, err := typ.CreateUnchecked(attrs, nil, content.Content...)
+	if err != nil {
+		return fmt.Errorf("failed to open %s node: %w", typ.Name, err)
+	}
+
+	f.placed = addToFragment(f.placed, f.depth(), prosemirror.NewFragment(node))
+	f.frontier = append(f.frontier, frontierEntry{typ: typ, match: &typ.ContentMatch})
+	return nil
+}
+
+func (f *fitter) closeFrontierNode() error {
+	open := f.frontier[len(f.frontier)-1]
+	f.frontier = f.frontier[:len(f.frontier)-1]
+
+	add := open.match.FillBefore(prosemirror.Fragment{}, true, 0)
+	if add == nil {
+		return fmt.Errorf("can't close %s node", open.typ.Name)
+	}
+
+	if add.ChildCount() > 0 {
+		f.placed = addToFragment(f.placed, len(f.frontier), *add)
+	}
+
+	return nil
+}
+
+func dropFromFragment(fragment prosemirror.Fragment, depth, count int) prosemirror.Fragment {
+	if depth == 0 {
+		return fragment.CutByIndex(count, fragment.ChildCount())
+	}
+
+	first := fragment.FirstChild()
+	return fragment.ReplaceChild(0, first.Copy(dropFromFragment(first.Content, depth-1, count)))
+}
+
+func addToFragment(fragment prosemirror.Fragment, depth int, content prosemirror.Fragment) prosemirror.Fragment {
+	if depth == 0 {
+		return fragment.Append(content)
+	}
+
+	last := fragment.LastChild()
+	return fragment.ReplaceChild(fragment.ChildCount()-1, last.Copy(addToFragment(last.Content, depth-1, content)))
+}
+
+func contentAt(fragment prosemirror.Fragment, depth int) prosemirror.Fragment {
+	for i := 0; i < depth; i++ {
+		fragment = fragment.FirstChild().Content
+	}
+
+	return fragment
+}
+
+func closeNodeStart(node prosemirror.Node, openStart, openEnd int) (prosemirror.Node, error) {
+	if openStart <= 0 {
+		return node, nil
+	}
+
+	frag := node.Content
+	if openStart > 1 {
+		childOpenEnd := 0
+		if frag.ChildCount() == 1 {
+			childOpenEnd = openEnd - 1
+		}
+
+		first, err := closeNodeStart(*frag.FirstChild(), openStart-1, childOpenEnd)
+		if err != nil {
+			return prosemirror.Node{}, err
+		}
+
+		frag = frag.ReplaceChild(0, first)
+	}
+
+	match := node.Type.ContentMatch
+	before := match.FillBefore(frag, false, 0)
+	if before == nil {
+		return prosemirror.Node{}, fmt.Errorf("can't close the start of %s node", node.Type.Name)
+	}
+
+	frag = before.Append(frag)
+	if openEnd <= 0 {
+		end := match.MatchFragment(frag, -1, -1)
+		if end == nil {
+			return prosemirror.Node{}, fmt.Errorf("can't close the end of %s node", node.Type.Name)
+		}
+
+		after := end.FillBefore(prosemirror.Fragment{}, true, 0)
+		if after == nil {
+			return prosemirror.Node{}, fmt.Errorf("can't close the end of %s node", node.Type.Name)
+		}
+
+		frag = frag.Append(*after)
+	}
+
+	return node.Copy(frag), nil
+}
+
+func contentAfterFits(to prosemirror.ResolvedPos, depth int, typ prosemirror.NodeType, match *prosemirror.ContentMatch, open bool) *prosemirror.Fragment {
+	node := to.Node(depth)
+	index := to.Index(depth)
+	if open {
+		index = to.IndexAfter(depth)
+	}
+
+	if index == node.ChildCount() && !typ.CompatibleContent(node.Type) {
+		return nil
+	}
+
+	fit := match.FillBefore(node.Content, true, index)
+	if fit == nil || invalidMarks(typ, node.Content, index) {
+		return nil
+	}
+
+	return fit
+}
+
+func invalidMarks(typ prosemirror.NodeType, fragment prosemirror.Fragment, start int) bool {
+	for i := start; i < fragment.ChildCount(); i++ {
+		if !typ.AllowsMarks(fragment.Child(i).Marks) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func definesContent(typ prosemirror.NodeType) bool {
+	return typ.Spec.Defining || typ.Spec.DefiningForContent
+}
+
+func replaceRange(tr *Transform, from, to int, slice prosemirror.Slice) error {
+	if slice.Size() == 0 {
+		return tr.DeleteRange(from, to)
+	}
+
+	rFrom, err := tr.Doc.Resolve(from)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %d: %w", from, err)
+	}
+
+	rTo, err := tr.Doc.Resolve(to)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %d: %w", to, err)
+	}
+
+	if fitsTrivially(rFrom, rTo, slice) {
+		return tr.Step(NewReplaceStep(from, to, slice, false))
+	}
+
+	targetDepths := coveredDepths(rFrom, rTo)
+	// Can't replace the whole document, so remove 0 if it's present
+	if len(targetDepths) > 0 && targetDepths[len(targetDepths)-1] == 0 {
+		targetDepths = targetDepths[:len(targetDepths)-1]
+	}
+
+	// Negative numbers represent not expansion over the whole node at
+	// that depth, but replacing from $from.before(-D) to $to.pos.
+	preferredTarget := -(rFrom.Depth + 1)
+	targetDepths = append([]int{preferredTarget}, targetDepths...)
+
+	// This loop picks a preferred target depth, if one of the covering
+	// depths is not outside of a defining node, and adds negative
+	// depths for any depth that has $from at its start and does not
+	// cross a defining node.
+	for d, pos := rFrom.Depth, rFrom.Pos-1; d > 0; d, pos = d-1, pos-1 {
+		spec := rFrom.Node(d).Type.Spec
+		if spec.Defining || spec.DefiningAsContext || spec.Isolating {
+			break
+		}
+
+		if slices.Contains(targetDepths, d) {
+			preferredTarget = d
+		} else if rFrom.Before(d) == pos {
+			targetDepths = slices.Insert(targetDepths, 1, -d)
+		}
+	}
+
+	// Try to fit each possible depth of the slice into each possible
+	// target depth, starting with the preferred depths.
+	preferredTargetIndex := slices.Index(targetDepths, preferredTarget)
+
+	leftNodes, preferredDepth := []prosemirror.Node{}, slice.OpenStart
+	for content, i := slice.Content, 0; ; i++ {
+		node := content.FirstChild()
+		leftNodes = append(leftNodes, *node)
+		if i == slice.OpenStart {
+			break
+		}
+
+		content = node.Content
+	}
+
+	// Back up preferredDepth to cover defining textblocks directly
+	// above it, possibly skipping a non-defining textblock.
+	for d := preferredDepth - 1; d >= 0; d-- {
+		leftNode := leftNodes[d]
+		def := definesContent(leftNode.Type)
+		if def && !leftNode.SameMarkup(rFrom.Node(abs(preferredTarget)-1)) {
+			preferredDepth = d
+		} else if def || !leftNode.Type.IsTextblock() {
+			break
+		}
+	}
+
+	for j := slice.OpenStart; j >= 0; j-- {
+		openDepth := (j + preferredDepth + 1) % (slice.OpenStart + 1)
+		if openDepth >= len(leftNodes) {
+			continue
+		}
+
+		insert := leftNodes[openDepth]
+		for i := range targetDepths {
+			// Loop over possible expansion levels, starting with the
+			// preferred one
+			targetDepth := targetDepths[(i+preferredTargetIndex)%len(targetDepths)]
+			expand := true
+			if targetDepth < 0 {
+				expand = false
+				targetDepth = -targetDepth
+			}
+
+			parent, index := rFrom.Node(targetDepth-1), rFrom.Index(targetDepth-1)
+			if parent.CanReplaceWith(index, index, insert.Type, insert.Marks) {
+				end := to
+				if expand {
+					end = rTo.After(targetDepth)
+				}
+
+				content, err := closeFragment(slice.Content, 0, slice.OpenStart, openDepth, nil)
+				if err != nil {
+					return err
+				}
+
+				return tr.Replace(rFrom.Before(targetDepth), end, prosemirror.NewSlice(content, openDepth, slice.OpenEnd))
+			}
+		}
+	}
+
+	startSteps := len(tr.Steps)
+	for i := len(targetDepths) - 1; i >= 0; i-- {
+		if err := tr.Replace(from, to, slice); err != nil {
+			return err
+		}
+
+		if len(tr.Steps) > startSteps {
+			break
+		}
+
+		depth := targetDepths[i]
+		if depth < 0 {
+			continue
+		}
+
+		from, to = rFrom.Before(depth), rTo.After(depth)
+	}
+
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+func closeFragment(fragment prosemirror.Fragment, depth, oldOpen, newOpen int, parent *prosemirror.Node) (prosemirror.Fragment, error) {
+	if depth < oldOpen {
+		first := fragment.FirstChild()
+		content, err := closeFragment(first.Content, depth+1, oldOpen, newOpen, first)
+		if err != nil {
+			return prosemirror.Fragment{}, err
+		}
+
+		fragment = fragment.ReplaceChild(0, first.Copy(content))
+	}
+
+	if depth > newOpen {
+		match := parent.ContentMatchAt(0)
+		before := match.FillBefore(fragment, false, 0)
+		if before == nil {
+			return prosemirror.Fragment{}, fmt.Errorf("can't close %s node", parent.Type.Name)
+		}
+
+		start := before.Append(fragment)
+
+		end := match.MatchFragment(start, -1, -1)
+		if end == nil {
+			return prosemirror.Fragment{}, fmt.Errorf("can't close %s node", parent.Type.Name)
+		}
+
+		after := end.FillBefore(prosemirror.Fragment{}, true, 0)
+		if after == nil {
+			return prosemirror.Fragment{}, fmt.Errorf("can't close %s node", parent.Type.Name)
+		}
+
+		fragment = start.Append(*after)
+	}
+
+	return fragment, nil
+}
+
+func replaceRangeWith(tr *Transform, from, to int, node prosemirror.Node) error {
+	if !node.IsInline() && from == to {
+		rp, err := tr.Doc.Resolve(from)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %d: %w", from, err)
+		}
+
+		if rp.Parent().Content.Size > 0 {
+			if point := InsertPoint(tr.Doc, from, node.Type); point >= 0 {
+				from, to = point, point
+			}
+		}
+	}
+
+	return tr.ReplaceRange(from, to, prosemirror.NewSlice(prosemirror.NewFragment(node), 0, 0))
+}
+
+func deleteRange(tr *Transform, from, to int) error {
+	rFrom, err := tr.Doc.Resolve(from)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %d: %w", from, err)
+	}
+
+	rTo, err := tr.Doc.Resolve(to)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %d: %w", to, err)
+	}
+
+	covered := coveredDepths(rFrom, rTo)
+	for i, depth := range covered {
+		last := i == len(covered)-1
+		if (last && depth == 0) || rFrom.Node(depth).Type.ContentMatch.ValidEnd {
+			return tr.Delete(rFrom.Start(depth), rTo.End(depth))
+		}
+
+		if depth > 0 && (last || rFrom.Node(depth-1).CanReplace(rFrom.Index(depth-1), rTo.IndexAfter(depth-1), prosemirror.Fragment{})) {
+			return tr.Delete(rFrom.Before(depth), rTo.After(depth))
+		}
+	}
+
+	for d := 1; d <= rFrom.Depth && d <= rTo.Depth; d++ {
+		if from-rFrom.Start(d) == rFrom.Depth-d && to > rFrom.End(d) && rTo.End(d)-to != rTo.Depth-d &&
+			rFrom.Start(d-1) == rTo.Start(d-1) && rFrom.Node(d-1).CanReplace(rFrom.Index(d-1), rTo.Index(d-1), prosemirror.Fragment{}) {
+			return tr.Delete(rFrom.Before(d), to)
+		}
+	}
+
+	return tr.Delete(from, to)
+}
+
+// coveredDepths returns an array of all depths for which from - to spans the
+// whole content of the nodes at that depth.
+func coveredDepths(from, to prosemirror.ResolvedPos) []int {
+	result := []int{}
+	for d := min(from.Depth, to.Depth); d >= 0; d-- {
+		start := from.Start(d)
+		if start < from.Pos-(from.Depth-d) ||
+			to.End(d) > to.Pos+(to.Depth-d) ||
+			from.Node(d).Type.Spec.Isolating ||
+			to.Node(d).Type.Spec.Isolating {
+			break
+		}
+
+		if start == to.Start(d) ||
+			(d == from.Depth && d == to.Depth && from.Parent().InlineContent() && to.Parent().InlineContent() &&
+				d > 0 && to.Start(d-1) == start-1) {
+			result = append(result, d)
+		}
+	}
+
+	return result
+}
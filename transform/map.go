@@ -0,0 +1,404 @@
+package transform
+
+import "fmt"
+
+// Mappable is implemented by objects that can map positions through them,
+// like StepMap and Mapping.
+type Mappable interface {
+	// Map a position through this object. When given, assoc (should be -1 or 1)
+	// determines with which side the position is associated, which determines
+	// in which direction to move when a chunk of content is inserted at the
+	// mapped position.
+	Map(pos int, assoc int) int
+
+	// MapResult maps a position, and returns an object containing additional
+	// information about the mapping.
+	MapResult(pos int, assoc int) MapResult
+}
+
+// There are several things that positions can be mapped through.
+// Such objects conform to this interface.
+var (
+	_ Mappable = StepMap{}
+	_ Mappable = (*Mapping)(nil)
+)
+
+// Recovery values encode a range index and an offset. They are
+// represented as numbers, because tons of them will be created when
+// mapping, for example, a large number of decorations. The number's
+// lower 16 bits provide the index, the remaining bits the offset.
+const (
+	lower16  = 0xffff
+	factor16 = 1 << 16
+)
+
+func makeRecover(index, offset int) int {
+	return index + offset*factor16
+}
+
+func recoverIndex(value int) int {
+	return value & lower16
+}
+
+func recoverOffset(value int) int {
+	return (value - (value & lower16)) / factor16
+}
+
+const (
+	delBefore = 1
+	delAfter  = 2
+	delAcross = 4
+	delSide   = 8
+)
+
+// MapResult is an object representing a mapped position with extra information.
+type MapResult struct {
+	// The mapped version of the position.
+	Pos int
+
+	delInfo int
+	// A recovery value, or -1 when the position can't be recovered.
+	recover int
+}
+
+// Deleted tells you whether the position was deleted, that is, whether the
+// step removed the token on the side queried (via the `assoc`) argument from
+// the document.
+func (r MapResult) Deleted() bool {
+	return r.delInfo&delSide > 0
+}
+
+// DeletedBefore tells you whether the token before the mapped position was deleted.
+func (r MapResult) DeletedBefore() bool {
+	return r.delInfo&(delBefore|delAcross) > 0
+}
+
+// DeletedAfter is true when the token after the mapped position was deleted.
+func (r MapResult) DeletedAfter() bool {
+	return r.delInfo&(delAfter|delAcross) > 0
+}
+
+// DeletedAcross tells whether any of the steps mapped through deletes across
+// the position (including both the token before and after the position).
+func (r MapResult) DeletedAcross() bool {
+	return r.delInfo&delAcross > 0
+}
+
+// StepMap is a map describing the deletions and insertions made by a step,
+// which can be used to find the correspondence between positions in the
+// pre-step version of a document and the same position in the post-step version.
+type StepMap struct {
+	// Ranges are triples of [start, oldSize, newSize].
+	Ranges   []int
+	Inverted bool
+}
+
+// NewStepMap creates a position map. The modifications to the document are
+// represented as an array of numbers, in which each group of three
+// represents a modified chunk as `[start, oldSize, newSize]`.
+func NewStepMap(ranges []int) StepMap {
+	return StepMap{Ranges: ranges}
+}
+
+// EmptyStepMap is a StepMap that contains no changed ranges.
+var EmptyStepMap = StepMap{}
+
+// OffsetStepMap creates a map that moves all positions by offset `n` (which may be negative).
+// This can be useful when applying steps meant for a sub-document to a larger document, or vice-versa.
+func OffsetStepMap(n int) StepMap {
+	switch {
+	case n == 0:
+		return EmptyStepMap
+	case n < 0:
+		return NewStepMap([]int{0, -n, 0})
+	default:
+		return NewStepMap([]int{0, 0, n})
+	}
+}
+
+func (m StepMap) String() string {
+	prefix := ""
+	if m.Inverted {
+		prefix = "-"
+	}
+
+	return fmt.Sprintf("%s%v", prefix, m.Ranges)
+}
+
+// Recover the position a recovery value was created for.
+func (m StepMap) Recover(value int) int {
+	diff, index := 0, recoverIndex(value)
+	if !m.Inverted {
+		for i := 0; i < index; i++ {
+			diff += m.Ranges[i*3+2] - m.Ranges[i*3+1]
+		}
+	}
+
+	return m.Ranges[index*3] + diff + recoverOffset(value)
+}
+
+func (m StepMap) MapResult(pos int, assoc int) MapResult {
+	return m.mapPos(pos, assoc)
+}
+
+func (m StepMap) Map(pos int, assoc int) int {
+	return m.mapPos(pos, assoc).Pos
+}
+
+func (m StepMap) indexes() (int, int) {
+	if m.Inverted {
+		return 2, 1
+	}
+
+	return 1, 2
+}
+
+func (m StepMap) mapPos(pos int, assoc int) MapResult {
+	diff := 0
+	oldIndex, newIndex := m.indexes()
+
+	for i := 0; i < len(m.Ranges); i += 3 {
+		start := m.Ranges[i]
+		if m.Inverted {
+			start -= diff
+		}
+
+		if start > pos {
+			break
+		}
+
+		oldSize, newSize := m.Ranges[i+oldIndex], m.Ranges[i+newIndex]
+		end := start + oldSize
+		if pos <= end {
+			side := assoc
+			switch {
+			case oldSize == 0:
+			case pos == start:
+				side = -1
+			case pos == end:
+				side = 1
+			}
+
+			result := start + diff
+			if side >= 0 {
+				result += newSize
+			}
+
+			recover := makeRecover(i/3, pos-start)
+			if (assoc < 0 && pos == start) || (assoc >= 0 && pos == end) {
+				recover = -1
+			}
+
+			del := delAcross
+			switch pos {
+			case start:
+				del = delAfter
+			case end:
+				del = delBefore
+			}
+
+			if (assoc < 0 && pos != start) || (assoc >= 0 && pos != end) {
+				del |= delSide
+			}
+
+			return MapResult{Pos: result, delInfo: del, recover: recover}
+		}
+
+		diff += newSize - oldSize
+	}
+
+	return MapResult{Pos: pos + diff, recover: -1}
+}
+
+// Touches reports whether the range identified by the recovery value
+// touches the given position.
+func (m StepMap) Touches(pos int, recover int) bool {
+	diff, index := 0, recoverIndex(recover)
+	oldIndex, newIndex := m.indexes()
+
+	for i := 0; i < len(m.Ranges); i += 3 {
+		start := m.Ranges[i]
+		if m.Inverted {
+			start -= diff
+		}
+
+		if start > pos {
+			break
+		}
+
+		oldSize := m.Ranges[i+oldIndex]
+		end := start + oldSize
+		if pos <= end && i == index*3 {
+			return true
+		}
+
+		diff += m.Ranges[i+newIndex] - oldSize
+	}
+
+	return false
+}
+
+// ForEach calls the given function on each of the changed ranges included in this map.
+func (m StepMap) ForEach(f func(oldStart, oldEnd, newStart, newEnd int)) {
+	oldIndex, newIndex := m.indexes()
+	diff := 0
+	for i := 0; i < len(m.Ranges); i += 3 {
+		start := m.Ranges[i]
+		oldStart, newStart := start, start
+		if m.Inverted {
+			oldStart -= diff
+		} else {
+			newStart += diff
+		}
+
+		oldSize, newSize := m.Ranges[i+oldIndex], m.Ranges[i+newIndex]
+		f(oldStart, oldStart+oldSize, newStart, newStart+newSize)
+		diff += newSize - oldSize
+	}
+}
+
+// Invert creates an inverted version of this map. The result can be used to
+// map positions in the post-step document to the pre-step document.
+func (m StepMap) Invert() StepMap {
+	return StepMap{Ranges: m.Ranges, Inverted: !m.Inverted}
+}
+
+// Mapping is a pipeline of step maps. It has special provisions for losslessly
+// handling mapping positions through a series of steps in which some steps
+// are inverted versions of earlier steps. (This comes up when ‘rebasing’
+// steps for collaboration or history management.)
+type Mapping struct {
+	// The step maps in this mapping.
+	Maps []StepMap
+
+	// pairs of indices of maps that mirror each other.
+	mirror []int
+
+	// The starting position in the maps array, used when Map or MapResult is called.
+	From int
+	// The end position in the maps array, -1 meaning the end.
+	To int
+}
+
+// NewMapping creates a new mapping with the given position maps.
+func NewMapping(maps ...StepMap) *Mapping {
+	return &Mapping{Maps: maps, To: -1}
+}
+
+func (m *Mapping) to() int {
+	if m.To < 0 {
+		return len(m.Maps)
+	}
+
+	return m.To
+}
+
+// Slice creates a mapping that maps only through a part of this one.
+// `to` may be -1 to go up to the end.
+func (m *Mapping) Slice(from, to int) *Mapping {
+	return &Mapping{Maps: m.Maps, mirror: m.mirror, From: from, To: to}
+}
+
+// AppendMap adds a step map to the end of this mapping. If mirrors is
+// not -1, it should be the index of the step map that is the mirror image of this one.
+func (m *Mapping) AppendMap(sm StepMap, mirrors int) {
+	m.Maps = append(m.Maps, sm)
+	m.To = len(m.Maps)
+
+	if mirrors >= 0 {
+		m.SetMirror(len(m.Maps)-1, mirrors)
+	}
+}
+
+// AppendMapping adds all the step maps in a given mapping to this one
+// (preserving mirroring information).
+func (m *Mapping) AppendMapping(other *Mapping) {
+	startSize := len(m.Maps)
+	for i, sm := range other.Maps {
+		mirr := other.GetMirror(i)
+		if mirr >= 0 && mirr < i {
+			m.AppendMap(sm, startSize+mirr)
+		} else {
+			m.AppendMap(sm, -1)
+		}
+	}
+}
+
+// GetMirror finds the offset of the step map that mirrors the map at the
+// given offset, in this mapping (as per the second argument to AppendMap).
+// Returns -1 when there is none.
+func (m *Mapping) GetMirror(n int) int {
+	for i := 0; i < len(m.mirror); i++ {
+		if m.mirror[i] == n {
+			if i%2 == 1 {
+				return m.mirror[i-1]
+			}
+			return m.mirror[i+1]
+		}
+	}
+
+	return -1
+}
+
+func (m *Mapping) SetMirror(n, mirror int) {
+	m.mirror = append(m.mirror, n, mirror)
+}
+
+// AppendMappingInverted appends the inverse of the given mapping to this one.
+func (m *Mapping) AppendMappingInverted(other *Mapping) {
+	totalSize := len(m.Maps) + len(other.Maps)
+	for i := len(other.Maps) - 1; i >= 0; i-- {
+		mirr := other.GetMirror(i)
+		if mirr >= 0 && mirr > i {
+			m.AppendMap(other.Maps[i].Invert(), totalSize-mirr-1)
+		} else {
+			m.AppendMap(other.Maps[i].Invert(), -1)
+		}
+	}
+}
+
+// Invert creates an inverted version of this mapping.
+func (m *Mapping) Invert() *Mapping {
+	inverse := NewMapping()
+	inverse.AppendMappingInverted(m)
+	return inverse
+}
+
+// Map a position through this mapping.
+func (m *Mapping) Map(pos int, assoc int) int {
+	if len(m.mirror) > 0 {
+		return m.mapPos(pos, assoc).Pos
+	}
+
+	for i := m.From; i < m.to(); i++ {
+		pos = m.Maps[i].Map(pos, assoc)
+	}
+
+	return pos
+}
+
+// MapResult maps a position through this mapping, returning a mapping result.
+func (m *Mapping) MapResult(pos int, assoc int) MapResult {
+	return m.mapPos(pos, assoc)
+}
+
+func (m *Mapping) mapPos(pos int, assoc int) MapResult {
+	delInfo := 0
+	for i := m.From; i < m.to(); i++ {
+		sm := m.Maps[i]
+		result := sm.MapResult(pos, assoc)
+		if result.recover >= 0 {
+			corr := m.GetMirror(i)
+			if corr >= 0 && corr > i && corr < m.to() {
+				i = corr
+				pos = m.Maps[corr].Recover(result.recover)
+				continue
+			}
+		}
+
+		delInfo |= result.delInfo
+		pos = result.Pos
+	}
+
+	return MapResult{Pos: pos, delInfo: delInfo, recover: -1}
+}
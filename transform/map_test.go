@@ -0,0 +1,138 @@
+package transform_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/karitham/prosemirror/transform"
+)
+
+// mapping builds a mapping of the given step maps, mirroring each pair of
+// indices in mirrors.
+func mapping(maps [][]int, mirrors ...[2]int) *transform.Mapping {
+	m := transform.NewMapping()
+	for _, ranges := range maps {
+		m.AppendMap(transform.NewStepMap(ranges), -1)
+	}
+
+	for _, pair := range mirrors {
+		m.SetMirror(pair[0], pair[1])
+	}
+
+	return m
+}
+
+func TestMapping(t *testing.T) {
+	type pos struct {
+		from, to, assoc int
+		// the inverted mapping can't recover the position
+		lossy bool
+	}
+
+	tests := []struct {
+		name    string
+		mapping *transform.Mapping
+		pos     []pos
+	}{
+		{
+			name:    "insertion",
+			mapping: mapping([][]int{{2, 0, 4}}),
+			pos:     []pos{{0, 0, 1, false}, {2, 6, 1, false}, {2, 2, -1, false}, {3, 7, 1, false}},
+		},
+		{
+			name:    "deletion",
+			mapping: mapping([][]int{{2, 4, 0}}),
+			pos:     []pos{{0, 0, 1, false}, {2, 2, -1, false}, {3, 2, 1, true}, {6, 2, 1, false}, {6, 2, -1, true}, {7, 3, 1, false}},
+		},
+		{
+			name:    "replace",
+			mapping: mapping([][]int{{2, 4, 4}}),
+			pos:     []pos{{0, 0, 1, false}, {2, 2, 1, false}, {4, 6, 1, true}, {4, 2, -1, true}, {6, 6, -1, false}, {8, 8, 1, false}},
+		},
+		{
+			name:    "mirrored delete-insert",
+			mapping: mapping([][]int{{2, 4, 0}, {2, 0, 4}}, [2]int{0, 1}),
+			pos:     []pos{{0, 0, 1, false}, {2, 2, 1, false}, {4, 4, 1, false}, {6, 6, 1, false}, {7, 7, 1, false}},
+		},
+		{
+			name:    "mirrored insert-delete",
+			mapping: mapping([][]int{{2, 0, 4}, {2, 4, 0}}, [2]int{0, 1}),
+			pos:     []pos{{0, 0, 1, false}, {2, 2, 1, false}, {3, 3, 1, false}},
+		},
+		{
+			name:    "delete-insert with an insert in between",
+			mapping: mapping([][]int{{2, 4, 0}, {1, 0, 1}, {3, 0, 4}}, [2]int{0, 2}),
+			pos:     []pos{{0, 0, 1, false}, {1, 2, 1, false}, {4, 5, 1, false}, {6, 7, 1, false}, {7, 8, 1, false}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			inverted := tc.mapping.Invert()
+			for _, p := range tc.pos {
+				assert.Equal(t, p.to, tc.mapping.Map(p.from, p.assoc), "map %d (assoc %d)", p.from, p.assoc)
+				if !p.lossy {
+					assert.Equal(t, p.from, inverted.Map(p.to, p.assoc), "inverted map %d (assoc %d)", p.to, p.assoc)
+				}
+			}
+		})
+	}
+}
+
+func TestMapResultDeleted(t *testing.T) {
+	tests := []struct {
+		maps  [][]int
+		pos   int
+		assoc int
+		// d for deleted, b for deleted before, a for deleted after, x for deleted across
+		want string
+	}{
+		{maps: [][]int{{0, 2, 0}}, pos: 2, assoc: -1, want: "db"},
+		{maps: [][]int{{0, 2, 0}}, pos: 2, assoc: 1, want: "b"},
+		{maps: [][]int{{0, 2, 2}}, pos: 2, assoc: -1, want: "db"},
+		{maps: [][]int{{0, 1, 0}, {0, 1, 0}}, pos: 2, assoc: -1, want: "db"},
+		{maps: [][]int{{0, 1, 0}}, pos: 2, assoc: -1, want: ""},
+		{maps: [][]int{{2, 2, 0}}, pos: 2, assoc: -1, want: "a"},
+		{maps: [][]int{{2, 2, 0}}, pos: 2, assoc: 1, want: "da"},
+		{maps: [][]int{{2, 2, 2}}, pos: 2, assoc: 1, want: "da"},
+		{maps: [][]int{{2, 1, 0}, {2, 1, 0}}, pos: 2, assoc: 1, want: "da"},
+		{maps: [][]int{{3, 2, 0}, {2, 1, 0}}, pos: 2, assoc: 1, want: "da"},
+		{maps: [][]int{{0, 4, 0}}, pos: 2, assoc: -1, want: "dbax"},
+		{maps: [][]int{{0, 4, 0}}, pos: 2, assoc: 1, want: "dbax"},
+		{maps: [][]int{{0, 1, 0}, {4, 1, 0}, {0, 3, 0}}, pos: 2, assoc: 1, want: "dbax"},
+		{maps: [][]int{{4, 1, 0}, {0, 1, 0}}, pos: 2, assoc: -1, want: ""},
+		{maps: [][]int{{2, 1, 0}, {0, 2, 0}}, pos: 2, assoc: -1, want: "dba"},
+		{maps: [][]int{{2, 1, 0}, {0, 1, 0}}, pos: 2, assoc: -1, want: "a"},
+		{maps: [][]int{{3, 1, 0}, {0, 2, 0}}, pos: 2, assoc: -1, want: "db"},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%v %d %d", tc.maps, tc.pos, tc.assoc), func(t *testing.T) {
+			r := mapping(tc.maps).MapResult(tc.pos, tc.assoc)
+
+			got := ""
+			for _, flag := range []struct {
+				set  bool
+				name string
+			}{{r.Deleted(), "d"}, {r.DeletedBefore(), "b"}, {r.DeletedAfter(), "a"}, {r.DeletedAcross(), "x"}} {
+				if flag.set {
+					got += flag.name
+				}
+			}
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestStepMapForEach(t *testing.T) {
+	var got [][4]int
+	transform.NewStepMap([]int{2, 1, 3, 10, 2, 0}).ForEach(func(oldStart, oldEnd, newStart, newEnd int) {
+		got = append(got, [4]int{oldStart, oldEnd, newStart, newEnd})
+	})
+
+	assert.Equal(t, [][4]int{{2, 3, 2, 5}, {10, 12, 12, 12}}, got)
+	assert.Equal(t, 7, transform.OffsetStepMap(4).Map(3, 1))
+}
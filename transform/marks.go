@@ -2,7 +2,7 @@ package transform
 
 import (
 	"fmt"
-	"slices"
+	"regexp"
 
 	"github.com/go-json-experiment/json"
 
@@ -92,6 +92,13 @@ func mapFragments(f prosemirror.Fragment, fn func(node, parent prosemirror.Node,
 			child = fn(child, parent, i)
 		}
 
+		// adjacent text nodes that end up with the same marks are joined
+		if last := len(content) - 1; last >= 0 && child.IsText() && content[last].IsText() &&
+			prosemirror.SameMarkSet(child.Marks, content[last].Marks) {
+			content[last] = child.Type.Schema.Text(content[last].Text+child.Text, child.Marks...)
+			continue
+		}
+
 		content = append(content, child)
 	}
 
@@ -99,56 +106,239 @@ func mapFragments(f prosemirror.Fragment, fn func(node, parent prosemirror.Node,
 }
 
 func (s *MarkStep) Apply(doc prosemirror.Node) (prosemirror.Node, error) {
+	oldSlice, err := doc.Slice(s.From, s.To, false)
+	if err != nil {
+		return doc, fmt.Errorf("failed to slice document: %w", err)
+	}
+
+	var content prosemirror.Fragment
 	if s.remove {
-		return s.removeMark(doc)
+		content = mapFragments(oldSlice.Content, func(node, parent prosemirror.Node, i int) prosemirror.Node {
+			return node.Mark(s.Mark.RemoveFromSet(node.Marks))
+		}, doc)
+	} else {
+		from, err := doc.Resolve(s.From)
+		if err != nil {
+			return doc, fmt.Errorf("failed to resolve document: %w", err)
+		}
+
+		content = mapFragments(oldSlice.Content, func(node, parent prosemirror.Node, i int) prosemirror.Node {
+			if !node.IsAtom() || !parent.Type.AllowsMarkType(s.Mark.Type) {
+				return node
+			}
+
+			return node.Mark(s.Mark.AddToSet(node.Marks))
+		}, from.Node(from.SharedDepth(s.To)))
 	}
 
-	return s.addMark(doc)
+	return doc.Replace(s.From, s.To, prosemirror.NewSlice(content, oldSlice.OpenStart, oldSlice.OpenEnd))
 }
 
-func (s *MarkStep) addMark(doc prosemirror.Node) (prosemirror.Node, error) {
-	oldSlice, err := doc.Slice(s.From, s.To, false)
-	if err != nil {
-		return doc, fmt.Errorf("failed to slice document: %w", err)
+func (s *MarkStep) GetMap() StepMap {
+	return EmptyStepMap
+}
+
+func (s *MarkStep) Invert(prosemirror.Node) (Applier, error) {
+	if s.remove {
+		return NewAddMarkStep(s.From, s.To, s.Mark), nil
 	}
 
-	from, err := doc.Resolve(s.From)
-	if err != nil {
-		return doc, fmt.Errorf("failed to resolve document: %w", err)
+	return NewRemoveMarkStep(s.From, s.To, s.Mark), nil
+}
+
+func (s *MarkStep) Map(m Mappable) Applier {
+	from, to := m.MapResult(s.From, 1), m.MapResult(s.To, -1)
+	if (from.Deleted() && to.Deleted()) || from.Pos >= to.Pos {
+		return nil
+	}
+
+	return s.with(from.Pos, to.Pos)
+}
+
+func (s *MarkStep) Merge(other Applier) Applier {
+	o, ok := unwrap(other).(*MarkStep)
+	if !ok || o.remove != s.remove || !o.Mark.Eq(s.Mark) || s.From > o.To || s.To < o.From {
+		return nil
+	}
+
+	return s.with(min(s.From, o.From), max(s.To, o.To))
+}
+
+// IsRemove reports whether this step removes its mark rather than adding it.
+func (s *MarkStep) IsRemove() bool {
+	return s.remove
+}
+
+func (s *MarkStep) with(from, to int) *MarkStep {
+	if s.remove {
+		return NewRemoveMarkStep(from, to, s.Mark)
 	}
 
-	parent := from.Node(from.SharedDepth(s.To))
+	return NewAddMarkStep(from, to, s.Mark)
+}
+
+func addMark(tr *Transform, from, to int, mark prosemirror.Mark) error {
+	var removed, added []*MarkStep
+	var removing, adding *MarkStep
+
+	tr.Doc.NodesBetween(from, to, func(node prosemirror.Node, pos int, parent *prosemirror.Node, _ int) bool {
+		if !node.IsInline() {
+			return true
+		}
+
+		marks := node.Marks
+		if mark.IsInSet(marks) || !parent.Type.AllowsMarkType(mark.Type) {
+			return true
+		}
+
+		start, end := max(pos, from), min(pos+node.NodeSize(), to)
+		newSet := mark.AddToSet(marks)
+
+		for _, m := range marks {
+			if m.IsInSet(newSet) {
+				continue
+			}
 
-	slice := mapFragments(oldSlice.Content, func(node prosemirror.Node, parent prosemirror.Node, i int) prosemirror.Node {
-		if !node.IsAtom() || !parent.Type.AllowsMarkType(s.Mark.Type) {
-			return node
+			if removing != nil && removing.To == start && removing.Mark.Eq(m) {
+				removing.To = end
+			} else {
+				removing = NewRemoveMarkStep(start, end, m)
+				removed = append(removed, removing)
+			}
 		}
 
-		return node.WithMarks(append(node.Marks, s.Mark))
-	}, parent)
+		if adding != nil && adding.To == start {
+			adding.To = end
+		} else {
+			adding = NewAddMarkStep(start, end, mark)
+			added = append(added, adding)
+		}
 
-	return doc.Replace(s.From, s.To, prosemirror.Slice{
-		Content:   slice,
-		OpenStart: oldSlice.OpenStart,
-		OpenEnd:   oldSlice.OpenEnd,
+		return true
 	})
+
+	for _, step := range append(removed, added...) {
+		if err := tr.Step(step); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (s *MarkStep) removeMark(doc prosemirror.Node) (prosemirror.Node, error) {
-	oldSlice, err := doc.Slice(s.From, s.To, false)
-	if err != nil {
-		return doc, fmt.Errorf("failed to slice document: %w", err)
+// removeMark removes, from every inline node between `from` and `to`, the
+// marks that `toRemove` picks out of that node's mark set.
+func removeMark(tr *Transform, from, to int, toRemove func([]prosemirror.Mark) []prosemirror.Mark) error {
+	type matched struct {
+		style    prosemirror.Mark
+		from, to int
+		step     int
 	}
 
-	slice := mapFragments(oldSlice.Content, func(node, parent prosemirror.Node, i int) prosemirror.Node {
-		return node.WithMarks(slices.DeleteFunc(node.Marks, func(m prosemirror.Mark) bool {
-			return m.Eq(s.Mark)
-		}))
-	}, doc)
+	var matches []*matched
+	step := 0
+
+	tr.Doc.NodesBetween(from, to, func(node prosemirror.Node, pos int, _ *prosemirror.Node, _ int) bool {
+		if !node.IsInline() {
+			return true
+		}
+
+		step++
+		end := min(pos+node.NodeSize(), to)
+		for _, style := range toRemove(node.Marks) {
+			var found *matched
+			for _, m := range matches {
+				if m.step == step-1 && style.Eq(m.style) {
+					found = m
+				}
+			}
+
+			if found != nil {
+				found.to = end
+				found.step = step
+			} else {
+				matches = append(matches, &matched{style: style, from: max(pos, from), to: end, step: step})
+			}
+		}
 
-	return doc.Replace(s.From, s.To, prosemirror.Slice{
-		Content:   slice,
-		OpenStart: oldSlice.OpenStart,
-		OpenEnd:   oldSlice.OpenEnd,
+		return true
 	})
+
+	for _, m := range matches {
+		if err := tr.Step(NewRemoveMarkStep(m.from, m.to, m.style)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var newlineRe = regexp.MustCompile(`\r?\n|\r`)
+
+func clearIncompatible(tr *Transform, pos int, parentType prosemirror.NodeType, match *prosemirror.ContentMatch) error {
+	if match == nil {
+		match = &parentType.ContentMatch
+	}
+
+	node := tr.Doc.NodeAt(pos)
+	if node == nil {
+		return fmt.Errorf("no node at position %d", pos)
+	}
+
+	var replSteps []Applier
+	cur := pos + 1
+	for i := 0; i < node.ChildCount(); i++ {
+		child := node.Child(i)
+		end := cur + child.NodeSize()
+
+		allowed := match.MatchType(child.Type)
+		if allowed == nil {
+			replSteps = append(replSteps, NewReplaceStep(cur, end, prosemirror.Slice{}, false))
+			cur = end
+			continue
+		}
+
+		match = allowed
+		for _, m := range child.Marks {
+			if !parentType.AllowsMarkType(m.Type) {
+				if err := tr.Step(NewRemoveMarkStep(cur, end, m)); err != nil {
+					return err
+				}
+			}
+		}
+
+		if child.IsText() && parentType.Whitespace() != "pre" {
+			var slice *prosemirror.Slice
+			for _, loc := range newlineRe.FindAllStringIndex(child.Text, -1) {
+				if slice == nil {
+					s := prosemirror.NewSlice(prosemirror.NewFragment(parentType.Schema.Text(" ", parentType.AllowedMarks(child.Marks)...)), 0, 0)
+					slice = &s
+				}
+
+				start := cur + prosemirror.UTF16Len(child.Text[:loc[0]])
+				replSteps = append(replSteps, NewReplaceStep(start, start+loc[1]-loc[0], *slice, false))
+			}
+		}
+
+		cur = end
+	}
+
+	if !match.ValidEnd {
+		fill := match.FillBefore(prosemirror.Fragment{}, true, 0)
+		if fill == nil {
+			return fmt.Errorf("can't fill the end of %s node", parentType.Name)
+		}
+
+		if err := tr.Replace(cur, cur, prosemirror.NewSlice(*fill, 0, 0)); err != nil {
+			return err
+		}
+	}
+
+	for i := len(replSteps) - 1; i >= 0; i-- {
+		if err := tr.Step(replSteps[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
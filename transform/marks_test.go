@@ -51,7 +51,7 @@ func TestMark(t *testing.T) {
 				return
 			}
 
-			if !assert.Equal(t, tc.want, got) {
+			if !assertNodeEq(t, tc.want, got) {
 				return
 			}
 		})
@@ -14,12 +14,32 @@ func init() {
 	})
 }
 
+// ReplaceStep replaces a part of the document with a slice of new content.
 type ReplaceStep struct {
 	BaseStep
 	Slice     prosemirror.Slice `json:"slice"`
 	Structure bool              `json:"structure,omitempty"`
 }
 
+// NewReplaceStep creates a step that replaces the content between `from` and `to`
+// with the given slice. When structure is true, the step will fail if the range
+// it replaces contains content, which is used by steps that only move structure around.
+func NewReplaceStep(from, to int, slice prosemirror.Slice, structure bool) *ReplaceStep {
+	return &ReplaceStep{
+		BaseStep: BaseStep{
+			Type: "replace",
+			From: from,
+			To:   to,
+		},
+		Slice:     slice,
+		Structure: structure,
+	}
+}
+
+func (s *ReplaceStep) String() string {
+	return fmt.Sprintf("ReplaceStep{From: %d, To: %d, Slice: %v, Structure: %t}", s.From, s.To, s.Slice, s.Structure)
+}
+
 func (s *ReplaceStep) UnmarshalJSON(data []byte) error {
 	type a ReplaceStep
 	aux := a{}
@@ -40,6 +60,10 @@ func (s *ReplaceStep) MarshalJSON() ([]byte, error) {
 }
 
 func (s *ReplaceStep) Apply(doc prosemirror.Node) (prosemirror.Node, error) {
+	if s.Structure && contentBetween(doc, s.From, s.To) {
+		return prosemirror.Node{}, fmt.Errorf("structure replace would overwrite content")
+	}
+
 	doc, err := doc.Replace(s.From, s.To, s.Slice)
 	if err != nil {
 		return prosemirror.Node{}, fmt.Errorf("failed to replace: %w", err)
@@ -47,3 +71,51 @@ func (s *ReplaceStep) Apply(doc prosemirror.Node) (prosemirror.Node, error) {
 
 	return doc, nil
 }
+
+func (s *ReplaceStep) GetMap() StepMap {
+	return NewStepMap([]int{s.From, s.To - s.From, s.Slice.Size()})
+}
+
+func (s *ReplaceStep) Invert(doc prosemirror.Node) (Applier, error) {
+	slice, err := doc.Slice(s.From, s.To, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to slice document: %w", err)
+	}
+
+	return NewReplaceStep(s.From, s.From+s.Slice.Size(), slice, false), nil
+}
+
+func (s *ReplaceStep) Map(m Mappable) Applier {
+	from, to := m.MapResult(s.From, 1), m.MapResult(s.To, -1)
+	if from.DeletedAcross() && to.DeletedAcross() {
+		return nil
+	}
+
+	return NewReplaceStep(from.Pos, max(from.Pos, to.Pos), s.Slice, s.Structure)
+}
+
+func (s *ReplaceStep) Merge(other Applier) Applier {
+	o, ok := unwrap(other).(*ReplaceStep)
+	if !ok || o.Structure || s.Structure {
+		return nil
+	}
+
+	switch {
+	case s.From+s.Slice.Size() == o.From && s.Slice.OpenEnd == 0 && o.Slice.OpenStart == 0:
+		slice := prosemirror.Slice{}
+		if s.Slice.Size()+o.Slice.Size() != 0 {
+			slice = prosemirror.NewSlice(s.Slice.Content.Append(o.Slice.Content), s.Slice.OpenStart, o.Slice.OpenEnd)
+		}
+
+		return NewReplaceStep(s.From, s.To+(o.To-o.From), slice, s.Structure)
+	case o.To == s.From && s.Slice.OpenStart == 0 && o.Slice.OpenEnd == 0:
+		slice := prosemirror.Slice{}
+		if s.Slice.Size()+o.Slice.Size() != 0 {
+			slice = prosemirror.NewSlice(o.Slice.Content.Append(s.Slice.Content), o.Slice.OpenStart, s.Slice.OpenEnd)
+		}
+
+		return NewReplaceStep(o.From, s.To, slice, s.Structure)
+	}
+
+	return nil
+}
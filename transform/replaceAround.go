@@ -14,6 +14,8 @@ func init() {
 	})
 }
 
+// ReplaceAroundStep replaces a part of the document with a slice of content, but
+// preserves a range of the replaced content by moving it into the slice.
 type ReplaceAroundStep struct {
 	BaseStep
 	GapFrom   int               `json:"gapFrom"`
@@ -23,6 +25,28 @@ type ReplaceAroundStep struct {
 	Structure bool              `json:"structure"`
 }
 
+// NewReplaceAroundStep creates a replace-around step with the given range and gap.
+// `insert` should be the point in the slice into which the content of the gap should be moved.
+func NewReplaceAroundStep(from, to, gapFrom, gapTo int, slice prosemirror.Slice, insert int, structure bool) *ReplaceAroundStep {
+	return &ReplaceAroundStep{
+		BaseStep: BaseStep{
+			Type: "replaceAround",
+			From: from,
+			To:   to,
+		},
+		GapFrom:   gapFrom,
+		GapTo:     gapTo,
+		Insert:    insert,
+		Slice:     slice,
+		Structure: structure,
+	}
+}
+
+func (s *ReplaceAroundStep) String() string {
+	return fmt.Sprintf("ReplaceAroundStep{From: %d, To: %d, GapFrom: %d, GapTo: %d, Insert: %d, Slice: %v, Structure: %t}",
+		s.From, s.To, s.GapFrom, s.GapTo, s.Insert, s.Slice, s.Structure)
+}
+
 func (s *ReplaceAroundStep) UnmarshalJSON(data []byte) error {
 	type a ReplaceAroundStep
 	aux := a{}
@@ -58,12 +82,59 @@ func (s *ReplaceAroundStep) Apply(doc prosemirror.Node) (prosemirror.Node, error
 
 	inserted := s.Slice.InsertAt(s.Insert, gap.Content)
 	if inserted == nil {
-		return prosemirror.Node{}, fmt.Errorf("failed to insert slice")
+		return prosemirror.Node{}, fmt.Errorf("content does not fit in gap")
 	}
 
 	return doc.Replace(s.From, s.To, *inserted)
 }
 
+func (s *ReplaceAroundStep) GetMap() StepMap {
+	return NewStepMap([]int{
+		s.From, s.GapFrom - s.From, s.Insert,
+		s.GapTo, s.To - s.GapTo, s.Slice.Size() - s.Insert,
+	})
+}
+
+func (s *ReplaceAroundStep) Invert(doc prosemirror.Node) (Applier, error) {
+	gap := s.GapTo - s.GapFrom
+
+	slice, err := doc.Slice(s.From, s.To, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to slice document: %w", err)
+	}
+
+	slice, err = slice.RemoveBetween(s.GapFrom-s.From, s.GapTo-s.From)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove gap: %w", err)
+	}
+
+	return NewReplaceAroundStep(
+		s.From, s.From+s.Slice.Size()+gap,
+		s.From+s.Insert, s.From+s.Insert+gap,
+		slice, s.GapFrom-s.From, s.Structure,
+	), nil
+}
+
+func (s *ReplaceAroundStep) Map(m Mappable) Applier {
+	from, to := m.MapResult(s.From, 1), m.MapResult(s.To, -1)
+
+	gapFrom := from.Pos
+	if s.From != s.GapFrom {
+		gapFrom = m.Map(s.GapFrom, -1)
+	}
+
+	gapTo := to.Pos
+	if s.To != s.GapTo {
+		gapTo = m.Map(s.GapTo, 1)
+	}
+
+	if (from.DeletedAcross() && to.DeletedAcross()) || gapFrom < from.Pos || gapTo > to.Pos {
+		return nil
+	}
+
+	return NewReplaceAroundStep(from.Pos, to.Pos, gapFrom, gapTo, s.Slice, s.Insert, s.Structure)
+}
+
 func contentBetween(doc prosemirror.Node, from, to int) bool {
 	fromNode, _ := doc.Resolve(from)
 	dist := to - from
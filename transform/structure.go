@@ -0,0 +1,450 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/karitham/prosemirror"
+)
+
+// Wrapper is a node type with its attributes, used to describe nodes to wrap
+// content in or to create when splitting.
+type Wrapper struct {
+	Type  prosemirror.NodeType
+	Attrs map[string]any
+}
+
+func canCut(node prosemirror.Node, start, end int) bool {
+	return (start == 0 || node.CanReplace(start, node.ChildCount(), prosemirror.Fragment{})) &&
+		(end == node.ChildCount() || node.CanReplace(0, end, prosemirror.Fragment{}))
+}
+
+// LiftTarget tries to find a target depth to which the content in the given
+// range can be lifted. Will not go across isolating parent nodes.
+// Returns -1 when no valid target exists.
+func LiftTarget(r prosemirror.NodeRange) int {
+	parent := r.Parent()
+	content := parent.Content.CutByIndex(r.StartIndex(), r.EndIndex())
+
+	for depth := r.Depth; ; depth-- {
+		node := r.From.Node(depth)
+		index, endIndex := r.From.Index(depth), r.To.IndexAfter(depth)
+		if depth < r.Depth && node.CanReplace(index, endIndex, content) {
+			return depth
+		}
+
+		if depth == 0 || node.Type.Spec.Isolating || !canCut(node, index, endIndex) {
+			break
+		}
+	}
+
+	return -1
+}
+
+func lift(tr *Transform, r prosemirror.NodeRange, target int) error {
+	from, to, depth := r.From, r.To, r.Depth
+
+	gapStart, gapEnd := from.Before(depth+1), to.After(depth+1)
+	start, end := gapStart, gapEnd
+
+	before, openStart := prosemirror.Fragment{}, 0
+	splitting := false
+	for d := depth; d > target; d-- {
+		if splitting || from.Index(d) > 0 {
+			splitting = true
+			before = prosemirror.NewFragment(from.Node(d).Copy(before))
+			openStart++
+		} else {
+			start--
+		}
+	}
+
+	after, openEnd := prosemirror.Fragment{}, 0
+	splitting = false
+	for d := depth; d > target; d-- {
+		if splitting || to.After(d+1) < to.End(d) {
+			splitting = true
+			after = prosemirror.NewFragment(to.Node(d).Copy(after))
+			openEnd++
+		} else {
+			end++
+		}
+	}
+
+	return tr.Step(NewReplaceAroundStep(
+		start, end, gapStart, gapEnd,
+		prosemirror.NewSlice(before.Append(after), openStart, openEnd),
+		before.Size-openStart, true,
+	))
+}
+
+// FindWrapping tries to find a valid way to wrap the content in the given range in a
+// node of the given type. May introduce extra nodes around and inside the wrapper
+// node, if necessary. Returns nil if no valid wrapping could be found.
+// When `innerRange` is given, that range's content is used as the content to
+// fit into the wrapping, instead of the content of `r`.
+func FindWrapping(r prosemirror.NodeRange, typ prosemirror.NodeType, attrs map[string]any, innerRange *prosemirror.NodeRange) []Wrapper {
+	if innerRange == nil {
+		innerRange = &r
+	}
+
+	around := findWrappingOutside(r, typ)
+	if around == nil {
+		return nil
+	}
+
+	inner := findWrappingInside(*innerRange, typ)
+	if inner == nil {
+		return nil
+	}
+
+	wrappers := make([]Wrapper, 0, len(around)+len(inner)+1)
+	for _, t := range around {
+		wrappers = append(wrappers, Wrapper{Type: t})
+	}
+
+	wrappers = append(wrappers, Wrapper{Type: typ, Attrs: attrs})
+	for _, t := range inner {
+		wrappers = append(wrappers, Wrapper{Type: t})
+	}
+
+	return wrappers
+}
+
+func findWrappingOutside(r prosemirror.NodeRange, typ prosemirror.NodeType) []prosemirror.NodeType {
+	parent, startIndex, endIndex := r.Parent(), r.StartIndex(), r.EndIndex()
+
+	around := parent.ContentMatchAt(startIndex).FindWrapping(typ)
+	if around == nil {
+		return nil
+	}
+
+	outer := typ
+	if len(around) > 0 {
+		outer = around[0]
+	}
+
+	if !parent.CanReplaceWith(startIndex, endIndex, outer, nil) {
+		return nil
+	}
+
+	return around
+}
+
+func findWrappingInside(r prosemirror.NodeRange, typ prosemirror.NodeType) []prosemirror.NodeType {
+	parent, startIndex, endIndex := r.Parent(), r.StartIndex(), r.EndIndex()
+
+	inner := parent.Child(startIndex)
+	inside := typ.ContentMatch.FindWrapping(inner.Type)
+	if inside == nil {
+		return nil
+	}
+
+	lastType := typ
+	if len(inside) > 0 {
+		lastType = inside[len(inside)-1]
+	}
+
+	innerMatch := &lastType.ContentMatch
+	for i := startIndex; innerMatch != nil && i < endIndex; i++ {
+		innerMatch = innerMatch.MatchType(parent.Child(i).Type)
+	}
+
+	if innerMatch == nil || !innerMatch.ValidEnd {
+		return nil
+	}
+
+	return inside
+}
+
+func wrap(tr *Transform, r prosemirror.NodeRange, wrappers []Wrapper) error {
+	content := prosemirror.Fragment{}
+	for i := len(wrappers) - 1; i >= 0; i-- {
+		if content.Size > 0 {
+			match := wrappers[i].Type.ContentMatch.MatchFragment(content, -1, -1)
+			if match == nil || !match.ValidEnd {
+				return fmt.Errorf("wrapper type given to Transform.Wrap does not form valid content of its parent wrapper")
+			}
+		}
+
+		node, err := wrappers[i].Type.CreateUnchecked(wrappers[i].Attrs, nil, content.Content...)
+		if err != nil {
+			return fmt.Errorf("failed to create wrapper %s: %w", wrappers[i].Type.Name, err)
+		}
+
+		content = prosemirror.NewFragment(node)
+	}
+
+	start, end := r.Start(), r.End()
+	return tr.Step(NewReplaceAroundStep(start, end, start, end, prosemirror.NewSlice(content, 0, 0), len(wrappers), true))
+}
+
+func setBlockType(tr *Transform, from, to int, typ prosemirror.NodeType, attrs map[string]any) error {
+	if !typ.IsTextblock() {
+		return fmt.Errorf("type given to SetBlockType should be a textblock")
+	}
+
+	var err error
+	mapFrom := len(tr.Steps)
+	tr.Doc.NodesBetween(from, to, func(node prosemirror.Node, pos int, _ *prosemirror.Node, _ int) bool {
+		if err != nil {
+			return false
+		}
+
+		if !node.IsTextblock() || node.HasMarkup(typ, attrs, node.Marks) || !canChangeType(tr.Doc, tr.Mapping.Slice(mapFrom, -1).Map(pos, 1), typ) {
+			return true
+		}
+
+		if err = tr.ClearIncompatible(tr.Mapping.Slice(mapFrom, -1).Map(pos, 1), typ, nil); err != nil {
+			return false
+		}
+
+		mapping := tr.Mapping.Slice(mapFrom, -1)
+		startM, endM := mapping.Map(pos, 1), mapping.Map(pos+node.NodeSize(), 1)
+
+		var wrapper prosemirror.Node
+		wrapper, err = typ.CreateUnchecked(attrs, node.Marks)
+		if err != nil {
+			return false
+		}
+
+		err = tr.Step(NewReplaceAroundStep(startM, endM, startM+1, endM-1,
+			prosemirror.NewSlice(prosemirror.NewFragment(wrapper), 0, 0), 1, true))
+		return false
+	})
+
+	return err
+}
+
+func canChangeType(doc prosemirror.Node, pos int, typ prosemirror.NodeType) bool {
+	rp, err := doc.Resolve(pos)
+	if err != nil {
+		return false
+	}
+
+	index := rp.Index(rp.Depth)
+	return rp.Parent().CanReplaceWith(index, index+1, typ, nil)
+}
+
+func setNodeMarkup(tr *Transform, pos int, typ *prosemirror.NodeType, attrs map[string]any, marks []prosemirror.Mark) error {
+	node := tr.Doc.NodeAt(pos)
+	if node == nil {
+		return fmt.Errorf("no node at position %d", pos)
+	}
+
+	if typ == nil {
+		typ = &node.Type
+	}
+
+	if marks == nil {
+		marks = node.Marks
+	}
+
+	if node.IsLeaf() {
+		newNode, err := typ.CreateUnchecked(attrs, marks)
+		if err != nil {
+			return fmt.Errorf("failed to create node: %w", err)
+		}
+
+		return tr.ReplaceWith(pos, pos+node.NodeSize(), newNode)
+	}
+
+	if !typ.ValidContent(node.Content) {
+		return fmt.Errorf("invalid content for node type %s", typ.Name)
+	}
+
+	newNode, err := typ.CreateUnchecked(attrs, marks)
+	if err != nil {
+		return fmt.Errorf("failed to create node: %w", err)
+	}
+
+	return tr.Step(NewReplaceAroundStep(pos, pos+node.NodeSize(), pos+1, pos+node.NodeSize()-1,
+		prosemirror.NewSlice(prosemirror.NewFragment(newNode), 0, 0), 1, true))
+}
+
+// CanSplit checks whether splitting at the given position is allowed.
+// `typesAfter` may hold the types (and attributes) of the nodes after the
+// split, innermost last; a nil entry means the original node's type is kept.
+func CanSplit(doc prosemirror.Node, pos int, depth int, typesAfter []*Wrapper) bool {
+	rp, err := doc.Resolve(pos)
+	if err != nil {
+		return false
+	}
+
+	base := rp.Depth - depth
+	if base < 0 {
+		return false
+	}
+
+	typeAfter := func(i int, node prosemirror.Node) prosemirror.NodeType {
+		if i >= 0 && i < len(typesAfter) && typesAfter[i] != nil {
+			return typesAfter[i].Type
+		}
+
+		return node.Type
+	}
+
+	parent := rp.Parent()
+	innerType := typeAfter(len(typesAfter)-1, parent)
+	if parent.Type.Spec.Isolating ||
+		!parent.CanReplace(rp.Index(rp.Depth), parent.ChildCount(), prosemirror.Fragment{}) ||
+		!innerType.ValidContent(parent.Content.CutByIndex(rp.Index(rp.Depth), parent.ChildCount())) {
+		return false
+	}
+
+	for d, i := rp.Depth-1, depth-2; d > base; d, i = d-1, i-1 {
+		node, index := rp.Node(d), rp.Index(d)
+		if node.Type.Spec.Isolating {
+			return false
+		}
+
+		rest := node.Content.CutByIndex(index, node.ChildCount())
+		if i+1 >= 0 && i+1 < len(typesAfter) && typesAfter[i+1] != nil {
+			override, err := typesAfter[i+1].Type.CreateUnchecked(typesAfter[i+1].Attrs, nil)
+			if err != nil {
+				return false
+			}
+
+			rest = rest.ReplaceChild(0, override)
+		}
+
+		if !node.CanReplace(index+1, node.ChildCount(), prosemirror.Fragment{}) || !typeAfter(i, node).ValidContent(rest) {
+			return false
+		}
+	}
+
+	index := rp.IndexAfter(base)
+	return rp.Node(base).CanReplaceWith(index, index, typeAfter(0, rp.Node(base+1)), nil)
+}
+
+func split(tr *Transform, pos int, depth int, typesAfter []*Wrapper) error {
+	rp, err := tr.Doc.Resolve(pos)
+	if err != nil {
+		return fmt.Errorf("failed to resolve position: %w", err)
+	}
+
+	before, after := prosemirror.Fragment{}, prosemirror.Fragment{}
+	for d, e, i := rp.Depth, rp.Depth-depth, depth-1; d > e; d, i = d-1, i-1 {
+		before = prosemirror.NewFragment(rp.Node(d).Copy(before))
+
+		if i >= 0 && i < len(typesAfter) && typesAfter[i] != nil {
+			node, err := typesAfter[i].Type.CreateUnchecked(typesAfter[i].Attrs, nil, after.Content...)
+			if err != nil {
+				return fmt.Errorf("failed to create node after split: %w", err)
+			}
+
+			after = prosemirror.NewFragment(node)
+		} else {
+			after = prosemirror.NewFragment(rp.Node(d).Copy(after))
+		}
+	}
+
+	return tr.Step(NewReplaceStep(pos, pos, prosemirror.NewSlice(before.Append(after), depth, depth), true))
+}
+
+// CanJoin tests whether the blocks before and after a given position can be joined.
+func CanJoin(doc prosemirror.Node, pos int) bool {
+	rp, err := doc.Resolve(pos)
+	if err != nil {
+		return false
+	}
+
+	index := rp.Index(rp.Depth)
+	return joinable(rp.NodeBefore(), rp.NodeAfter()) && rp.Parent().CanReplace(index, index+1, prosemirror.Fragment{})
+}
+
+func joinable(a, b *prosemirror.Node) bool {
+	return a != nil && b != nil && !a.IsLeaf() && a.CanAppend(*b)
+}
+
+// JoinPoint finds an ancestor of the given position that can be joined to the
+// block before it (or after it if `dir` is positive). Returns the joinable
+// point, or -1 if there is none.
+func JoinPoint(doc prosemirror.Node, pos int, dir int) int {
+	rp, err := doc.Resolve(pos)
+	if err != nil {
+		return -1
+	}
+
+	for d := rp.Depth; ; d-- {
+		var before, after *prosemirror.Node
+		index := rp.Index(d)
+
+		switch {
+		case d == rp.Depth:
+			before, after = rp.NodeBefore(), rp.NodeAfter()
+		case dir > 0:
+			node := rp.Node(d + 1)
+			before = &node
+			index++
+			after = rp.Node(d).MaybeChild(index)
+		default:
+			node := rp.Node(d + 1)
+			before = rp.Node(d).MaybeChild(index - 1)
+			after = &node
+		}
+
+		if before != nil && !before.IsTextblock() && joinable(before, after) &&
+			rp.Node(d).CanReplace(index, index+1, prosemirror.Fragment{}) {
+			return pos
+		}
+
+		if d == 0 {
+			break
+		}
+
+		if dir < 0 {
+			pos = rp.Before(d)
+		} else {
+			pos = rp.After(d)
+		}
+	}
+
+	return -1
+}
+
+func join(tr *Transform, pos int, depth int) error {
+	return tr.Step(NewReplaceStep(pos-depth, pos+depth, prosemirror.Slice{}, true))
+}
+
+// InsertPoint tries to find a point where a node of the given type can be inserted
+// near `pos`, by searching up the node hierarchy when `pos` itself isn't a valid
+// place but is at the start or end of a node. Returns -1 if no position was found.
+func InsertPoint(doc prosemirror.Node, pos int, typ prosemirror.NodeType) int {
+	rp, err := doc.Resolve(pos)
+	if err != nil {
+		return -1
+	}
+
+	index := rp.Index(rp.Depth)
+	if rp.Parent().CanReplaceWith(index, index, typ, nil) {
+		return pos
+	}
+
+	if rp.ParentOffset == 0 {
+		for d := rp.Depth - 1; d >= 0; d-- {
+			index := rp.Index(d)
+			if rp.Node(d).CanReplaceWith(index, index, typ, nil) {
+				return rp.Before(d + 1)
+			}
+
+			if index > 0 {
+				return -1
+			}
+		}
+	}
+
+	if rp.ParentOffset == rp.Parent().Content.Size {
+		for d := rp.Depth - 1; d >= 0; d-- {
+			index := rp.IndexAfter(d)
+			if rp.Node(d).CanReplaceWith(index, index, typ, nil) {
+				return rp.After(d + 1)
+			}
+
+			if index < rp.Node(d).ChildCount() {
+				return -1
+			}
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,164 @@
+package transform_test
+
+import (
+	"testing"
+
+	"github.com/go-json-experiment/json"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/karitham/prosemirror"
+	"github.com/karitham/prosemirror/transform"
+)
+
+func blockRange(t *testing.T, doc prosemirror.Node, from, to int) prosemirror.NodeRange {
+	t.Helper()
+
+	start, err := doc.Resolve(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	end, err := doc.Resolve(to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := start.BlockRange(end, nil)
+	if r == nil {
+		t.Fatalf("no block range between %d and %d", from, to)
+	}
+
+	return *r
+}
+
+func TestStructure(t *testing.T) {
+	const (
+		twoParagraphs = `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}`
+		quoted        = `{"type":"doc","content":[{"type":"blockquote","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}]}`
+	)
+
+	tests := []struct {
+		name string
+		doc  string
+		op   func(t *testing.T, tr *transform.Transform) error
+		want string
+	}{
+		{
+			name: "split paragraph",
+			doc:  `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"onetwo"}]}]}`,
+			op: func(t *testing.T, tr *transform.Transform) error {
+				assert.True(t, transform.CanSplit(tr.Doc, 4, 1, nil))
+				return tr.Split(4, 1, nil)
+			},
+			want: twoParagraphs,
+		},
+		{
+			name: "split into heading",
+			doc:  `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"onetwo"}]}]}`,
+			op: func(t *testing.T, tr *transform.Transform) error {
+				return tr.Split(4, 1, []*transform.Wrapper{{Type: tr.Doc.Type.Schema.Nodes["heading"], Attrs: map[string]any{"level": 2}}})
+			},
+			want: `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]},{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"two"}]}]}`,
+		},
+		{
+			name: "join paragraphs",
+			doc:  twoParagraphs,
+			op: func(t *testing.T, tr *transform.Transform) error {
+				assert.True(t, transform.CanJoin(tr.Doc, 5))
+				return tr.Join(5, 1)
+			},
+			want: `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"onetwo"}]}]}`,
+		},
+		{
+			name: "wrap in blockquote",
+			doc:  twoParagraphs,
+			op: func(t *testing.T, tr *transform.Transform) error {
+				r := blockRange(t, tr.Doc, 1, 8)
+				wrappers := transform.FindWrapping(r, tr.Doc.Type.Schema.Nodes["blockquote"], nil, nil)
+				if !assert.NotNil(t, wrappers) {
+					return nil
+				}
+
+				return tr.Wrap(r, wrappers)
+			},
+			want: quoted,
+		},
+		{
+			name: "lift out of blockquote",
+			doc:  quoted,
+			op: func(t *testing.T, tr *transform.Transform) error {
+				r := blockRange(t, tr.Doc, 2, 9)
+				target := transform.LiftTarget(r)
+				assert.Equal(t, 0, target)
+				return tr.Lift(r, target)
+			},
+			want: twoParagraphs,
+		},
+		{
+			name: "lift the last paragraph",
+			doc:  quoted,
+			op: func(t *testing.T, tr *transform.Transform) error {
+				r := blockRange(t, tr.Doc, 9, 9)
+				return tr.Lift(r, transform.LiftTarget(r))
+			},
+			want: `{"type":"doc","content":[{"type":"blockquote","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}`,
+		},
+		{
+			name: "set block type",
+			doc:  twoParagraphs,
+			op: func(t *testing.T, tr *transform.Transform) error {
+				return tr.SetBlockType(1, 8, tr.Doc.Type.Schema.Nodes["code_block"], nil)
+			},
+			want: `{"type":"doc","content":[{"type":"code_block","attrs":{"language":null},"content":[{"type":"text","text":"one"}]},{"type":"code_block","attrs":{"language":null},"content":[{"type":"text","text":"two"}]}]}`,
+		},
+		{
+			name: "delete across paragraphs",
+			doc:  twoParagraphs,
+			op: func(t *testing.T, tr *transform.Transform) error {
+				return tr.Delete(3, 7)
+			},
+			want: `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"onwo"}]}]}`,
+		},
+		{
+			name: "replace with fitted slice",
+			doc:  twoParagraphs,
+			op: func(t *testing.T, tr *transform.Transform) error {
+				// a closed paragraph pasted inside text is split around
+				slice := prosemirror.NewSlice(prosemirror.NewFragment(tr.Doc.Content.Child(1).Copy(tr.Doc.Content.Child(1).Content)), 0, 0)
+				return tr.Replace(2, 2, slice)
+			},
+			want: `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"o"}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]},{"type":"paragraph","content":[{"type":"text","text":"ne"}]},{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}`,
+		},
+		{
+			name: "invalid wrap",
+			doc:  twoParagraphs,
+			op: func(t *testing.T, tr *transform.Transform) error {
+				r := blockRange(t, tr.Doc, 1, 1)
+				assert.Nil(t, transform.FindWrapping(r, tr.Doc.Type.Schema.Nodes["paragraph"], nil, nil))
+				return nil
+			},
+			want: twoParagraphs,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := fromJSON[prosemirror.Node](tc.doc)
+			tr := transform.New(doc)
+			if !assert.NoError(t, tc.op(t, tr)) {
+				return
+			}
+
+			got, err := json.Marshal(tr.Doc)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.JSONEq(t, tc.want, string(got))
+			assert.NoError(t, tr.Doc.Type.CheckContent(tr.Doc.Content))
+
+			// the steps map positions consistently with the documents
+			assert.Equal(t, tr.Doc.Content.Size, tr.Mapping.Map(doc.Content.Size, 1))
+		})
+	}
+}
@@ -15,10 +15,30 @@ import (
 // the step type.
 type Applier interface {
 	Apply(prosemirror.Node) (prosemirror.Node, error)
+
+	// GetMap returns the position map that represents the changes made by this step,
+	// mapping positions in the old document to positions in the new one.
+	GetMap() StepMap
+
+	// Invert creates an inverted version of this step, which undoes it when applied
+	// to the document produced by the step. It takes the document the step was applied to.
+	Invert(prosemirror.Node) (Applier, error)
+
+	// Map this step through a mappable thing, returning either a version of that step
+	// with its positions adjusted, or nil if the step was entirely deleted by the mapping.
+	Map(Mappable) Applier
+
 	json.UnmarshalerV1
 	json.MarshalerV1
 }
 
+// Merger is implemented by steps that can be merged with a step that directly follows them.
+type Merger interface {
+	// Merge tries to merge this step with another one, to be applied directly after it.
+	// Returns the merged step when possible, nil if the steps can't be merged.
+	Merge(other Applier) Applier
+}
+
 // Step is an abstract type that gets implemented by the various step types
 // that we have. This is so that we can unmarshal the step without knowing
 // what type it is, and then we can use the type to apply the step.
@@ -61,6 +81,32 @@ func (s *Step) Apply(n prosemirror.Node) (prosemirror.Node, error) {
 	return s.Impl.Apply(n)
 }
 
+func (s *Step) GetMap() StepMap {
+	return s.Impl.GetMap()
+}
+
+func (s *Step) Invert(doc prosemirror.Node) (Applier, error) {
+	return s.Impl.Invert(doc)
+}
+
+func (s *Step) Map(m Mappable) Applier {
+	return s.Impl.Map(m)
+}
+
+// Merge merges the wrapped step with another one, if it implements Merger.
+func (s *Step) Merge(other Applier) Applier {
+	m, ok := s.Impl.(Merger)
+	if !ok {
+		return nil
+	}
+
+	return m.Merge(other)
+}
+
+func (s *Step) String() string {
+	return fmt.Sprint(s.Impl)
+}
+
 type BaseStep struct {
 	Type string `json:"stepType"`
 	From int    `json:"from"`
@@ -68,10 +114,24 @@ type BaseStep struct {
 }
 
 // NewStep returns a new step from the given applier.
+// Steps that are already wrapped are returned as is.
 func NewStep(a Applier) Step {
+	if s, ok := a.(*Step); ok {
+		return *s
+	}
+
 	return Step{Impl: a}
 }
 
+// unwrap returns the concrete step behind a Step wrapper.
+func unwrap(a Applier) Applier {
+	if s, ok := a.(*Step); ok {
+		return s.Impl
+	}
+
+	return a
+}
+
 // RegisterTransformer registers a new step type.
 func RegisterTransformer(name string, f func() Applier) {
 	if _, ok := transformers[name]; ok {
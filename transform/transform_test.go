@@ -33,6 +33,12 @@ func TestTransforms(t *testing.T) {
 			want: `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"Hello World!?"}]}]}`,
 			step: `{"stepType":"replace","from":13,"to":13,"slice":{"content":[{"type":"text","text":"?"}]}}`,
 		},
+		{
+			name: "set heading level",
+			doc:  `{"type":"doc","content":[{"type":"heading","attrs":{"level":1},"content":[{"type":"text","text":"Title"}]},{"type":"paragraph"}]}`,
+			want: `{"type":"doc","content":[{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Title"}]},{"type":"paragraph"}]}`,
+			step: `{"stepType":"attr","pos":0,"attr":"level","value":2}`,
+		},
 	}
 
 	for _, tc := range tests {
@@ -61,3 +67,17 @@ func fromJSON[T any](s string) T {
 	_ = json.Unmarshal([]byte(s), &v)
 	return v
 }
+
+// assertNodeEq compares nodes with Node.Eq, since nodes whose specs hold funcs
+// are never deeply equal, and shows their JSON when they differ.
+func assertNodeEq(t *testing.T, want, got prosemirror.Node) bool {
+	t.Helper()
+
+	if want.Eq(got) {
+		return true
+	}
+
+	wantJSON, _ := json.Marshal(want)
+	gotJSON, _ := json.Marshal(got)
+	return assert.Equal(t, string(wantJSON), string(gotJSON))
+}
@@ -0,0 +1,220 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/karitham/prosemirror"
+)
+
+// Transform is an abstraction for building up and tracking an array of steps
+// representing a document transformation.
+//
+// Most transforming methods return an error when the resulting step would
+// produce an invalid document, in which case the transform is left untouched.
+type Transform struct {
+	// The current document (the result of applying the steps in the transform).
+	Doc prosemirror.Node
+
+	// The steps in this transform.
+	Steps []Step
+
+	// The documents before each of the steps.
+	Docs []prosemirror.Node
+
+	// A mapping with the maps for each of the steps in this transform.
+	Mapping *Mapping
+}
+
+// New creates a transform that starts with the given document.
+func New(doc prosemirror.Node) *Transform {
+	return &Transform{
+		Doc:     doc,
+		Mapping: NewMapping(),
+	}
+}
+
+// Before returns the starting document.
+func (tr *Transform) Before() prosemirror.Node {
+	if len(tr.Docs) > 0 {
+		return tr.Docs[0]
+	}
+
+	return tr.Doc
+}
+
+// Step applies a new step in this transform, saving the result.
+// Returns an error when the step fails.
+func (tr *Transform) Step(step Applier) error {
+	doc, err := step.Apply(tr.Doc)
+	if err != nil {
+		return fmt.Errorf("failed to apply step %v: %w", step, err)
+	}
+
+	tr.addStep(step, doc)
+	return nil
+}
+
+func (tr *Transform) addStep(step Applier, doc prosemirror.Node) {
+	tr.Docs = append(tr.Docs, tr.Doc)
+	tr.Steps = append(tr.Steps, NewStep(step))
+	tr.Mapping.AppendMap(step.GetMap(), -1)
+	tr.Doc = doc
+}
+
+// DocChanged is true when the document has been changed (when there are any steps).
+func (tr *Transform) DocChanged() bool {
+	return len(tr.Steps) > 0
+}
+
+// Replace the part of the document between `from` and `to` with the
+// given slice.
+func (tr *Transform) Replace(from, to int, slice prosemirror.Slice) error {
+	step, err := ReplaceStepFor(tr.Doc, from, to, slice)
+	if err != nil {
+		return err
+	}
+
+	if step == nil {
+		return nil
+	}
+
+	return tr.Step(step)
+}
+
+// ReplaceWith replaces the given range with the given content.
+func (tr *Transform) ReplaceWith(from, to int, content ...prosemirror.Node) error {
+	return tr.Replace(from, to, prosemirror.NewSlice(prosemirror.NewFragment(content...), 0, 0))
+}
+
+// Delete the content between the given positions.
+func (tr *Transform) Delete(from, to int) error {
+	return tr.Replace(from, to, prosemirror.Slice{})
+}
+
+// Insert the given content at the given position.
+func (tr *Transform) Insert(pos int, content ...prosemirror.Node) error {
+	return tr.ReplaceWith(pos, pos, content...)
+}
+
+// ReplaceRange replaces a range of the document with a given slice, using
+// `from`, `to`, and the slice's OpenStart property as hints, rather than
+// fixed start and end points. This method may grow the replaced area or
+// close open nodes in the slice in order to get a fit that is more in line
+// with WYSIWYG expectations, by dropping fully covered parent nodes of the
+// replaced region when they are marked non-defining as context, or
+// including an open parent node from the slice that is marked as defining
+// its content.
+func (tr *Transform) ReplaceRange(from, to int, slice prosemirror.Slice) error {
+	return replaceRange(tr, from, to, slice)
+}
+
+// ReplaceRangeWith replaces the given range with a node, but use `from` and
+// `to` as hints, rather than precise positions. When from and to are the same
+// and are at the start or end of a parent node in which the given node
+// doesn't fit, this method may move them out towards a parent that does allow
+// the given node to be placed. When the given range completely covers a parent
+// node, this method may completely replace that parent node.
+func (tr *Transform) ReplaceRangeWith(from, to int, node prosemirror.Node) error {
+	return replaceRangeWith(tr, from, to, node)
+}
+
+// DeleteRange deletes the given range, expanding it to cover fully covered
+// parent nodes until a valid replace is found.
+func (tr *Transform) DeleteRange(from, to int) error {
+	return deleteRange(tr, from, to)
+}
+
+// Lift splits the content in the given range off from its parent, if there
+// is sibling content before or after it, and moves it up the tree to the
+// depth specified by `target`. You'll probably want to use LiftTarget to
+// compute `target`, to make sure the lift is valid.
+func (tr *Transform) Lift(r prosemirror.NodeRange, target int) error {
+	return lift(tr, r, target)
+}
+
+// Join the blocks around the given position. If depth is 2, their last and
+// first siblings are also joined, and so on.
+func (tr *Transform) Join(pos int, depth int) error {
+	return join(tr, pos, depth)
+}
+
+// Wrap the given range in the given set of wrappers. The wrappers are assumed
+// to be valid in this position, and should probably be computed with FindWrapping.
+func (tr *Transform) Wrap(r prosemirror.NodeRange, wrappers []Wrapper) error {
+	return wrap(tr, r, wrappers)
+}
+
+// SetBlockType sets the type of all textblocks (partly) between `from` and `to`
+// to the given node type with the given attributes.
+func (tr *Transform) SetBlockType(from, to int, typ prosemirror.NodeType, attrs map[string]any) error {
+	return setBlockType(tr, from, to, typ, attrs)
+}
+
+// SetNodeMarkup changes the type, attributes, and/or marks of the node at `pos`.
+// When `typ` is nil, the existing node type is preserved, and when marks are nil,
+// the node's marks are kept.
+func (tr *Transform) SetNodeMarkup(pos int, typ *prosemirror.NodeType, attrs map[string]any, marks []prosemirror.Mark) error {
+	return setNodeMarkup(tr, pos, typ, attrs, marks)
+}
+
+// SetNodeAttribute sets a single attribute on a given node to a new value.
+func (tr *Transform) SetNodeAttribute(pos int, attr string, value any) error {
+	return tr.Step(NewAttrStep(pos, attr, value))
+}
+
+// SetDocAttribute sets a single attribute on the document to a new value.
+func (tr *Transform) SetDocAttribute(attr string, value any) error {
+	return tr.Step(NewDocAttrStep(attr, value))
+}
+
+// Split the node at the given position, and optionally, if `depth` is greater
+// than one, any number of nodes above that. By default, the parts split off
+// will inherit the node type of the original node. This can be changed by
+// passing an array of types and attributes to use after the split.
+func (tr *Transform) Split(pos int, depth int, typesAfter []*Wrapper) error {
+	return split(tr, pos, depth, typesAfter)
+}
+
+// AddMark adds the given mark to the inline content between `from` and `to`.
+func (tr *Transform) AddMark(from, to int, mark prosemirror.Mark) error {
+	return addMark(tr, from, to, mark)
+}
+
+// RemoveMark removes the given mark from the inline content between `from` and `to`.
+func (tr *Transform) RemoveMark(from, to int, mark prosemirror.Mark) error {
+	return removeMark(tr, from, to, func(marks []prosemirror.Mark) []prosemirror.Mark {
+		if mark.IsInSet(marks) {
+			return []prosemirror.Mark{mark}
+		}
+
+		return nil
+	})
+}
+
+// RemoveMarkType removes all marks of the given type from the inline content
+// between `from` and `to`.
+func (tr *Transform) RemoveMarkType(from, to int, typ prosemirror.MarkType) error {
+	return removeMark(tr, from, to, func(marks []prosemirror.Mark) []prosemirror.Mark {
+		var found []prosemirror.Mark
+		for m := typ.IsInSet(marks); m != nil; m = typ.IsInSet(marks) {
+			found = append(found, *m)
+			marks = m.RemoveFromSet(marks)
+		}
+
+		return found
+	})
+}
+
+// RemoveAllMarks removes every mark from the inline content between `from` and `to`.
+func (tr *Transform) RemoveAllMarks(from, to int) error {
+	return removeMark(tr, from, to, func(marks []prosemirror.Mark) []prosemirror.Mark {
+		return marks
+	})
+}
+
+// ClearIncompatible removes all marks and nodes from the content of the node at
+// `pos` that don't match the given new parent node type. Accepts an optional
+// starting content match as third argument.
+func (tr *Transform) ClearIncompatible(pos int, parentType prosemirror.NodeType, match *prosemirror.ContentMatch) error {
+	return clearIncompatible(tr, pos, parentType, match)
+}
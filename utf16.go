@@ -18,3 +18,38 @@ func utf16Len(s string) int {
 
 	return l
 }
+
+// UTF16Len returns the length of a string in utf-16 code units, which is
+// the unit document positions are counted in.
+func UTF16Len(s string) int {
+	return utf16Len(s)
+}
+
+// utf16Slice returns the part of s between the given utf-16 code unit offsets.
+// Offsets falling in the middle of a surrogate pair are rounded up, past the pair.
+func utf16Slice(s string, from, to int) string {
+	start, end := len(s), len(s)
+	l := 0
+	for i, r := range s {
+		if l >= from && start == len(s) {
+			start = i
+		}
+
+		if l >= to {
+			end = i
+			break
+		}
+
+		if r >= 0x10000 {
+			l += 2
+			continue
+		}
+		l++
+	}
+
+	if start > end {
+		start = end
+	}
+
+	return s[start:end]
+}
@@ -3,6 +3,8 @@ package prosemirror
 import (
 	"testing"
 	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
 )
 
 // FuzzUtf16Len tests the utf16Len function for correctness by comparing
@@ -18,3 +20,22 @@ func FuzzUtf16Len(f *testing.F) {
 		}
 	})
 }
+
+func TestUtf16Slice(t *testing.T) {
+	tests := []struct {
+		s        string
+		from, to int
+		want     string
+	}{
+		{s: "abc", from: 1, to: 2, want: "b"},
+		{s: "a😀b", from: 1, to: 3, want: "😀"},
+		{s: "a😀b", from: 0, to: 2, want: "a😀"},
+		{s: "a😀b", from: 2, to: 4, want: "b"},
+		{s: "a😀b", from: 2, to: 2, want: ""},
+		{s: "a😀b", from: 3, to: 10, want: "b"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, utf16Slice(tc.s, tc.from, tc.to), "utf16Slice(%q, %d, %d)", tc.s, tc.from, tc.to)
+	}
+}